@@ -11,7 +11,12 @@ import (
 	"gophermart-loyalty/internal/config"
 	"gophermart-loyalty/internal/handlers"
 	"gophermart-loyalty/internal/integrations"
+	"gophermart-loyalty/internal/jobs"
+	"gophermart-loyalty/internal/jwks"
 	"gophermart-loyalty/internal/logger"
+	appmw "gophermart-loyalty/internal/middleware"
+	"gophermart-loyalty/internal/notify"
+	"gophermart-loyalty/internal/passhash"
 	"gophermart-loyalty/internal/repo"
 	"gophermart-loyalty/internal/usecases"
 )
@@ -39,26 +44,59 @@ func (a *App) Start(ctx context.Context) error {
 		return err
 	}
 
+	// Создаем отправителя уведомлений
+	var sender usecases.Sender = notify.NewLogSender(a.log)
+	if a.cfg.Notify.File != "" {
+		sender = notify.NewFileSender(a.cfg.Notify.File)
+	}
+
+	// Создаем хэшер паролей
+	hasher, err := passhash.New(&a.cfg.Password)
+	if err != nil {
+		return err
+	}
+
 	// Создаем юзкейсы
-	useCases := usecases.NewUseCases(repository, a.log)
+	useCases := usecases.NewUseCases(repository, hasher, sender, a.log)
+
+	// Загружаем ключи подписи токенов
+	keys, err := jwks.NewKeySet(&a.cfg.Auth)
+	if err != nil {
+		return err
+	}
+
+	// Адрес клиента берется из заголовков прокси, только если запрос пришел от доверенного прокси
+	trustedProxies, err := appmw.ParseTrustedProxies(a.cfg.TrustedProxies)
+	if err != nil {
+		return err
+	}
 
 	// Создаём сервер
-	h := handlers.NewHandlers(&a.cfg.Auth, useCases, a.log)
+	h := handlers.NewHandlers(&a.cfg.Auth, &a.cfg.Balance, &a.cfg.Idempotency, keys, useCases, a.log)
 	r := chi.NewRouter()
-	r.Use(middleware.RealIP)
+	r.Use(appmw.RealIP(trustedProxies))
 	r.Use(middleware.RequestID)
 	r.Use(middleware.Logger)
 	r.Use(middleware.Recoverer)
 	r.Mount("/api/user", h.InitRoutes())
+	r.Mount("/api/admin", h.InitAdminRoutes())
+	r.Mount("/.well-known", h.InitWellKnownRoutes())
 	a.server = &http.Server{
 		Addr:    a.cfg.RunAddress,
 		Handler: r,
 	}
 
 	// Запускаем интеграции
-	integrations.NewIntegrationAccrual(&a.cfg.IntegrationAccrual, useCases, a.log).Start(ctx)
+	integrations.NewIntegrationAccrual(&a.cfg.IntegrationAccrual, &a.cfg.Balance.Hold, useCases, a.log).Start(ctx)
 	integrations.NewIntegrationShopStub(useCases, a.log).Start(ctx)
 
+	// Запускаем фоновые задачи
+	jobs.NewDataExportJob(&a.cfg.DataExport, useCases, a.log).Start(ctx)
+	jobs.NewReconcileJob(&a.cfg.Reconcile, useCases, a.log).Start(ctx)
+	jobs.NewExpirationJob(&a.cfg.Balance.Expiration, useCases, a.log).Start(ctx)
+	jobs.NewHoldReleaseJob(&a.cfg.Balance.Hold, useCases, a.log).Start(ctx)
+	jobs.NewIdempotencyCleanupJob(&a.cfg.Idempotency, useCases, a.log).Start(ctx)
+
 	// Горутина для остановки HTTP-сервера
 	serverStopped := make(chan struct{})
 	go func() {
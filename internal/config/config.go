@@ -5,9 +5,11 @@ import (
 	"fmt"
 	"net"
 	"os"
+	"strings"
 	"time"
 
 	"github.com/caarlos0/env/v6"
+	"github.com/shopspring/decimal"
 	"golang.org/x/sync/errgroup"
 )
 
@@ -19,9 +21,46 @@ type DB struct {
 
 // Auth - конфигурация авторизации.
 type Auth struct {
-	SigningKey string        `env:"AUTH_SECRET"` // SigningKey - ключ для подписи токена
-	SigningAlg string        // SigningAlg - алгоритм подписи JWT-токена
-	TTL        time.Duration `env:"AUTH_TTL"` // TTL - время жизни авторизационного токена
+	SigningKey     string        `env:"AUTH_SECRET"`                          // SigningKey - ключ для подписи токена алгоритмами HS*
+	SigningKeyID   string        `env:"AUTH_SECRET_ID"`                       // SigningKeyID - идентификатор ключа AUTH_SECRET в заголовке "kid" токена
+	SigningAlg     string        `env:"AUTH_SIGNING_ALG"`                     // SigningAlg - алгоритм подписи JWT-токена
+	SigningKeyFile string        `env:"AUTH_SIGNING_KEY_FILE"`                // SigningKeyFile - PEM-файл с закрытым ключом для алгоритмов RS*, ES*, EdDSA
+	PrevKeyFiles   []string      `env:"AUTH_PREV_KEY_FILES" envSeparator:","` // PrevKeyFiles - PEM-файлы с предыдущими ключами подписи
+	RotationWindow time.Duration `env:"AUTH_ROTATION_WINDOW"`                 // RotationWindow - время, в течение которого принимаются предыдущие ключи
+	RotatedAt      time.Time     `env:"AUTH_ROTATED_AT"`                      // RotatedAt - время смены ключа подписи, от которого отсчитывается RotationWindow
+	TTL            time.Duration `env:"AUTH_TTL"`                             // TTL - время жизни авторизационного токена
+	RefreshTTL     time.Duration `env:"AUTH_REFRESH_TTL"`                     // RefreshTTL - время жизни refresh-токена
+	ResetTTL       time.Duration `env:"AUTH_RESET_TTL"`                       // ResetTTL - время жизни токена сброса пароля
+	ChallengeTTL   time.Duration `env:"AUTH_2FA_CHALLENGE_TTL"`               // ChallengeTTL - время на ввод кода двухфакторной аутентификации при входе
+	TOTPIssuer     string        `env:"AUTH_TOTP_ISSUER"`                     // TOTPIssuer - название сервиса в приложении-аутентификаторе
+	LoginReuse     time.Duration `env:"AUTH_LOGIN_REUSE_GRACE"`               // LoginReuse - время после удаления учетной записи, в течение которого ее логин нельзя занять
+	Throttle       LoginThrottle // Throttle - защита от перебора паролей
+}
+
+// LoginThrottle - конфигурация защиты от перебора паролей.
+// Нулевые значения отключают соответствующее ограничение.
+type LoginThrottle struct {
+	FreeAttempts  int           `env:"LOGIN_FREE_ATTEMPTS"`   // FreeAttempts - число неудачных попыток входа без задержки
+	BaseDelay     time.Duration `env:"LOGIN_BASE_DELAY"`      // BaseDelay - начальная задержка, удваивается с каждой следующей неудачной попыткой
+	MaxDelay      time.Duration `env:"LOGIN_MAX_DELAY"`       // MaxDelay - максимальная задержка между попытками входа
+	MaxFailures   int           `env:"LOGIN_MAX_FAILURES"`    // MaxFailures - число неудачных попыток входа по логину, после которого учетная запись блокируется
+	IPMaxFailures int           `env:"LOGIN_IP_MAX_FAILURES"` // IPMaxFailures - число неудачных попыток входа с IP-адреса, после которого адрес блокируется
+	Lockout       time.Duration `env:"LOGIN_LOCKOUT"`         // Lockout - время блокировки учетной записи или IP-адреса
+	Window        time.Duration `env:"LOGIN_FAILURE_WINDOW"`  // Window - время, после которого счетчик неудачных попыток сбрасывается
+}
+
+// Password - конфигурация хэширования паролей.
+type Password struct {
+	Alg           string `env:"PASSWORD_HASH_ALG"`       // Alg - алгоритм хэширования новых паролей: argon2id или bcrypt
+	BcryptCost    int    `env:"PASSWORD_BCRYPT_COST"`    // BcryptCost - стоимость bcrypt
+	Argon2Time    uint32 `env:"PASSWORD_ARGON2_TIME"`    // Argon2Time - число итераций argon2id
+	Argon2Memory  uint32 `env:"PASSWORD_ARGON2_MEMORY"`  // Argon2Memory - объем памяти argon2id в КиБ
+	Argon2Threads uint8  `env:"PASSWORD_ARGON2_THREADS"` // Argon2Threads - число потоков argon2id
+}
+
+// Notify - конфигурация отправки уведомлений пользователям.
+type Notify struct {
+	File string `env:"NOTIFY_FILE"` // File - файл для записи уведомлений, если не задан - уведомления пишутся в лог
 }
 
 // IntegrationAccrual - конфигурация интеграции с системой расчёта начислений.
@@ -31,11 +70,82 @@ type IntegrationAccrual struct {
 	Timeout      time.Duration `env:"ACCRUAL_SYSTEM_TIMEOUT"`       // Timeout - таймаут запросов к системе расчёта начислений
 }
 
+// DataExport - конфигурация выгрузки персональных данных.
+type DataExport struct {
+	PollInterval time.Duration `env:"EXPORT_POLL_INTERVAL"` // PollInterval - интервал проверки очереди выгрузок
+	TTL          time.Duration `env:"EXPORT_TTL"`           // TTL - время хранения готовой выгрузки
+}
+
+// Idempotency - конфигурация ключей идемпотентности запросов.
+// Ответ на запрос с ключом идемпотентности хранится TTL и повторяется на запросы с тем же ключом.
+type Idempotency struct {
+	TTL             time.Duration `env:"IDEMPOTENCY_KEY_TTL"`          // TTL - время хранения ключа и записанного ответа, нулевое значение отключает ключи
+	CleanupInterval time.Duration `env:"IDEMPOTENCY_CLEANUP_INTERVAL"` // CleanupInterval - интервал удаления истекших ключей
+}
+
+// Balance - конфигурация правил начисления и списания баллов.
+type Balance struct {
+	Expiration Expiration // Expiration - сгорание баллов
+	Transfer   Transfer   // Transfer - переводы баллов между пользователями
+	Hold       Hold       // Hold - удержание начисленных баллов
+	Withdrawal Withdrawal // Withdrawal - правила списания баллов
+}
+
+// Expiration - конфигурация сгорания баллов.
+// Нулевой срок жизни означает, что баллы, начисленные операциями этого типа, не сгорают.
+type Expiration struct {
+	OrderAccrual time.Duration `env:"POINTS_LIFETIME_ORDER_ACCRUAL"`   // OrderAccrual - срок жизни баллов, начисленных за заказы
+	PromoAccrual time.Duration `env:"POINTS_LIFETIME_PROMO_ACCRUAL"`   // PromoAccrual - срок жизни баллов, начисленных по промо-кодам
+	PollInterval time.Duration `env:"POINTS_EXPIRATION_POLL_INTERVAL"` // PollInterval - интервал проверки баллов, срок жизни которых истек
+}
+
+// Hold - конфигурация удержания начисленных баллов.
+// Удержанные баллы учитываются в балансе, но не могут быть потрачены до окончания срока удержания.
+// Нулевой срок удержания означает, что баллы, начисленные операциями этого типа, доступны сразу.
+type Hold struct {
+	OrderAccrual time.Duration `env:"POINTS_HOLD_ORDER_ACCRUAL"` // OrderAccrual - срок удержания баллов, начисленных за заказы
+	PromoAccrual time.Duration `env:"POINTS_HOLD_PROMO_ACCRUAL"` // PromoAccrual - срок удержания баллов, начисленных по промо-кодам
+	PollInterval time.Duration `env:"POINTS_HOLD_POLL_INTERVAL"` // PollInterval - интервал проверки баллов, срок удержания которых истек
+}
+
+// Withdrawal - правила списания баллов в счет оплаты заказов.
+// Суточное ограничение действует на списания за последние сутки, месячное - на списания с начала календарного месяца.
+// Нулевые значения отключают соответствующее правило, кроме точности.
+type Withdrawal struct {
+	MinAmount     decimal.Decimal `env:"WITHDRAWAL_MIN_AMOUNT"`      // MinAmount - минимальная сумма списания
+	DailyAmount   decimal.Decimal `env:"WITHDRAWAL_DAILY_AMOUNT"`    // DailyAmount - максимальная сумма списаний за сутки
+	MonthlyAmount decimal.Decimal `env:"WITHDRAWAL_MONTHLY_AMOUNT"`  // MonthlyAmount - максимальная сумма списаний за месяц
+	MaxOrderShare decimal.Decimal `env:"WITHDRAWAL_MAX_ORDER_SHARE"` // MaxOrderShare - максимальная доля суммы заказа, например 0.5
+	Precision     int32           `env:"WITHDRAWAL_PRECISION"`       // Precision - допустимое число знаков после запятой в сумме списания
+}
+
+// Transfer - конфигурация переводов баллов между пользователями.
+// Ограничения действуют на переводы, отправленные пользователем за последние сутки.
+// Нулевые значения отключают соответствующее ограничение.
+type Transfer struct {
+	DailyAmount decimal.Decimal `env:"TRANSFER_DAILY_AMOUNT"` // DailyAmount - максимальная сумма переводов за сутки
+	DailyCount  int             `env:"TRANSFER_DAILY_COUNT"`  // DailyCount - максимальное количество переводов за сутки
+}
+
+// Reconcile - конфигурация сверки балансов пользователей с журналом проводок.
+type Reconcile struct {
+	Interval time.Duration `env:"RECONCILE_INTERVAL"` // Interval - интервал сверки, нулевое значение отключает сверку
+	Repair   bool          `env:"RECONCILE_REPAIR"`   // Repair - исправлять расходящиеся балансы
+}
+
 type Config struct {
-	DB                 DB     // DB - конфигурация подключения к базе данных
-	Auth               Auth   // Auth - конфигурация авторизации
-	IntegrationAccrual        // IntegrationAccrual - конфигурация интеграции с системой расчёта начислений
-	RunAddress         string `env:"RUN_ADDRESS"` // RunAddress - адрес и порт запуска сервиса
+	DB                 DB          // DB - конфигурация подключения к базе данных
+	Auth               Auth        // Auth - конфигурация авторизации
+	Password           Password    // Password - конфигурация хэширования паролей
+	IntegrationAccrual             // IntegrationAccrual - конфигурация интеграции с системой расчёта начислений
+	Notify             Notify      // Notify - конфигурация отправки уведомлений пользователям
+	DataExport         DataExport  // DataExport - конфигурация выгрузки персональных данных
+	Reconcile          Reconcile   // Reconcile - конфигурация сверки балансов
+	Balance            Balance     // Balance - конфигурация правил начисления и списания баллов
+	Idempotency        Idempotency // Idempotency - конфигурация ключей идемпотентности запросов
+	RunAddress         string      `env:"RUN_ADDRESS"` // RunAddress - адрес и порт запуска сервиса
+	// TrustedProxies - IP-адреса и подсети доверенных прокси, от которых принимаются заголовки X-Forwarded-For и X-Real-IP
+	TrustedProxies []string `env:"TRUSTED_PROXIES" envSeparator:","`
 }
 
 // NewFromCLI - конфигурационная функция, которая считывает конфигурацию приложения из переменных окружения.
@@ -77,13 +187,55 @@ func fromCLI(cfg *Config, arguments ...string) (*Config, error) {
 // NewFromEnv - конфигурационная функция, которая читывает конфигурацию приложения из переменных окружения.
 //
 // Переменные окружения:
-//    RUN_ADDRESS                  - адрес и порт запуска сервиса
-//    DATABASE_URI                 - адрес подключения к базе данных
-//    ACCRUAL_SYSTEM_ADDRESS       - адрес системы расчёта начислений
-//    ACCRUAL_SYSTEM_TIMEOUT       - таймаут запросов к системе расчёта начислений
-//    ACCRUAL_SYSTEM_POLL_INTERVAL - интервал опроса системы расчёта начислений
-//    AUTH_TTL                     - время жизни авторизационного токена
-//    AUTH_SECRET                  - секретный ключ для подписи авторизационного токена
+//    RUN_ADDRESS                     - адрес и порт запуска сервиса
+//    DATABASE_URI                    - адрес подключения к базе данных
+//    TRUSTED_PROXIES                 - IP-адреса и подсети доверенных прокси через запятую
+//    ACCRUAL_SYSTEM_ADDRESS          - адрес системы расчёта начислений
+//    ACCRUAL_SYSTEM_TIMEOUT          - таймаут запросов к системе расчёта начислений
+//    ACCRUAL_SYSTEM_POLL_INTERVAL    - интервал опроса системы расчёта начислений
+//    AUTH_TTL                        - время жизни авторизационного токена
+//    AUTH_REFRESH_TTL                - время жизни refresh-токена
+//    AUTH_RESET_TTL                  - время жизни токена сброса пароля
+//    AUTH_SECRET                     - секретный ключ для подписи авторизационного токена
+//    AUTH_SIGNING_ALG                - алгоритм подписи авторизационного токена
+//    AUTH_SIGNING_KEY_FILE           - PEM-файл с закрытым ключом для подписи авторизационного токена
+//    AUTH_PREV_KEY_FILES             - PEM-файлы с предыдущими ключами подписи через запятую
+//    AUTH_ROTATION_WINDOW            - время, в течение которого принимаются предыдущие ключи подписи
+//    AUTH_2FA_CHALLENGE_TTL          - время на ввод кода двухфакторной аутентификации при входе
+//    AUTH_TOTP_ISSUER                - название сервиса в приложении-аутентификаторе
+//    AUTH_LOGIN_REUSE_GRACE          - время после удаления учетной записи, в течение которого ее логин нельзя занять
+//    LOGIN_FREE_ATTEMPTS             - число неудачных попыток входа без задержки
+//    LOGIN_BASE_DELAY                - начальная задержка между неудачными попытками входа
+//    LOGIN_MAX_DELAY                 - максимальная задержка между неудачными попытками входа
+//    LOGIN_MAX_FAILURES              - число неудачных попыток входа до блокировки учетной записи
+//    LOGIN_IP_MAX_FAILURES           - число неудачных попыток входа до блокировки IP-адреса
+//    LOGIN_LOCKOUT                   - время блокировки учетной записи или IP-адреса
+//    LOGIN_FAILURE_WINDOW            - время, после которого счетчик неудачных попыток входа сбрасывается
+//    PASSWORD_HASH_ALG               - алгоритм хэширования новых паролей: argon2id или bcrypt
+//    PASSWORD_BCRYPT_COST            - стоимость bcrypt
+//    PASSWORD_ARGON2_TIME            - число итераций argon2id
+//    PASSWORD_ARGON2_MEMORY          - объем памяти argon2id в КиБ
+//    PASSWORD_ARGON2_THREADS         - число потоков argon2id
+//    NOTIFY_FILE                     - файл для записи уведомлений пользователям
+//    EXPORT_POLL_INTERVAL            - интервал проверки очереди выгрузок персональных данных
+//    EXPORT_TTL                      - время хранения готовой выгрузки персональных данных
+//    RECONCILE_INTERVAL              - интервал сверки балансов пользователей с журналом проводок
+//    RECONCILE_REPAIR                - исправлять расходящиеся балансы пользователей
+//    POINTS_LIFETIME_ORDER_ACCRUAL   - срок жизни баллов, начисленных за заказы
+//    POINTS_LIFETIME_PROMO_ACCRUAL   - срок жизни баллов, начисленных по промо-кодам
+//    POINTS_EXPIRATION_POLL_INTERVAL - интервал проверки баллов, срок жизни которых истек
+//    TRANSFER_DAILY_AMOUNT           - максимальная сумма переводов баллов пользователя за сутки
+//    TRANSFER_DAILY_COUNT            - максимальное количество переводов баллов пользователя за сутки
+//    POINTS_HOLD_ORDER_ACCRUAL       - срок удержания баллов, начисленных за заказы
+//    POINTS_HOLD_PROMO_ACCRUAL       - срок удержания баллов, начисленных по промо-кодам
+//    POINTS_HOLD_POLL_INTERVAL       - интервал проверки баллов, срок удержания которых истек
+//    WITHDRAWAL_MIN_AMOUNT           - минимальная сумма списания баллов
+//    WITHDRAWAL_DAILY_AMOUNT         - максимальная сумма списаний баллов пользователя за сутки
+//    WITHDRAWAL_MONTHLY_AMOUNT       - максимальная сумма списаний баллов пользователя за календарный месяц
+//    WITHDRAWAL_MAX_ORDER_SHARE      - максимальная доля суммы заказа, которую можно оплатить баллами
+//    WITHDRAWAL_PRECISION            - допустимое число знаков после запятой в сумме списания
+//    IDEMPOTENCY_KEY_TTL             - время хранения ключа идемпотентности и ответа на запрос
+//    IDEMPOTENCY_CLEANUP_INTERVAL    - интервал удаления истекших ключей идемпотентности
 //
 // Если какие-либо переменные окружения не заданы, то используются значения переданные в cfg.
 func NewFromEnv(cfg *Config) (*Config, error) {
@@ -101,7 +253,7 @@ func NewFromEnv(cfg *Config) (*Config, error) {
 // validate - проверяет конфигурацию на валидность
 func (c *Config) validate() error {
 	g := &errgroup.Group{}
-	g.Go(c.validateAuthSecret)
+	g.Go(c.validateAuthKey)
 	g.Go(c.validateServerAddr)
 	return g.Wait()
 }
@@ -118,10 +270,21 @@ func (c *Config) validateServerAddr() error {
 	return nil
 }
 
-// validateAuthSecret - проверяет чтобы ключ авторизации был не пустым.
-func (c *Config) validateAuthSecret() error {
-	if len(c.Auth.SigningKey) == 0 {
-		return fmt.Errorf("auth secret not set")
+// validateAuthKey - проверяет чтобы ключ авторизации был задан:
+// секретный ключ для алгоритмов HS*, файл с закрытым ключом для остальных алгоритмов.
+// Если заданы предыдущие ключи, должно быть задано время смены ключа.
+func (c *Config) validateAuthKey() error {
+	if len(c.Auth.PrevKeyFiles) > 0 && c.Auth.RotatedAt.IsZero() {
+		return fmt.Errorf("auth key rotation time not set")
+	}
+	if strings.HasPrefix(c.Auth.SigningAlg, "HS") {
+		if len(c.Auth.SigningKey) == 0 {
+			return fmt.Errorf("auth secret not set")
+		}
+		return nil
+	}
+	if len(c.Auth.SigningKeyFile) == 0 {
+		return fmt.Errorf("auth signing key file not set")
 	}
 	return nil
 }
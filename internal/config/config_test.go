@@ -57,4 +57,41 @@ func (suite *configSuite) TestConfig() {
 		suite.Equal("http://localhost:5001", cfg.IntegrationAccrual.Address)
 		suite.Equal(2*time.Hour, cfg.Auth.TTL)
 	})
+
+	suite.Run("asymmetric signing keys from env", func() {
+		os.Clearenv()
+		cfg, err := Compose(NewDefault)
+		suite.NoError(err)
+
+		_ = os.Setenv("AUTH_SIGNING_ALG", "ES256")
+		cfg, err = NewFromEnv(cfg)
+		suite.Error(err, "signing key file is required for ES256")
+
+		_ = os.Setenv("AUTH_SIGNING_KEY_FILE", "/keys/current.pem")
+		_ = os.Setenv("AUTH_PREV_KEY_FILES", "/keys/prev1.pem,/keys/prev2.pem")
+		_ = os.Setenv("AUTH_ROTATION_WINDOW", "30m")
+		_, err = Compose(NewDefault, NewFromEnv)
+		suite.Error(err, "rotation time is required for previous keys")
+
+		_ = os.Setenv("AUTH_ROTATED_AT", "2024-01-02T03:04:05Z")
+		cfg, err = Compose(NewDefault, NewFromEnv)
+		suite.NoError(err)
+		suite.Equal(time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC), cfg.Auth.RotatedAt)
+		suite.Equal("ES256", cfg.Auth.SigningAlg)
+		suite.Equal("/keys/current.pem", cfg.Auth.SigningKeyFile)
+		suite.Equal([]string{"/keys/prev1.pem", "/keys/prev2.pem"}, cfg.Auth.PrevKeyFiles)
+		suite.Equal(30*time.Minute, cfg.Auth.RotationWindow)
+	})
+
+	suite.Run("trusted proxies from env", func() {
+		os.Clearenv()
+		cfg, err := Compose(NewDefault, NewFromEnv)
+		suite.NoError(err)
+		suite.Empty(cfg.TrustedProxies)
+
+		_ = os.Setenv("TRUSTED_PROXIES", "10.0.0.0/8,127.0.0.1")
+		cfg, err = Compose(NewDefault, NewFromEnv)
+		suite.NoError(err)
+		suite.Equal([]string{"10.0.0.0/8", "127.0.0.1"}, cfg.TrustedProxies)
+	})
 }
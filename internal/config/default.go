@@ -2,6 +2,8 @@ package config
 
 import (
 	"time"
+
+	"github.com/shopspring/decimal"
 )
 
 // NewDefault - конфигурационная функция, возвращает конфигурацию по умолчанию.
@@ -13,17 +15,67 @@ func NewDefault(_ *Config) (*Config, error) {
 
 	cfg := Config{
 		DB: DB{
-			RequiredVersion: 1,
+			RequiredVersion: 22,
 		},
 		Auth: Auth{
-			SigningAlg: "HS512",
-			TTL:        30 * 24 * time.Hour,
-			SigningKey: randomSecret,
+			SigningAlg:     "HS512",
+			RotationWindow: time.Hour,
+			TTL:            15 * time.Minute,
+			RefreshTTL:     30 * 24 * time.Hour,
+			ResetTTL:       time.Hour,
+			ChallengeTTL:   5 * time.Minute,
+			TOTPIssuer:     "Gophermart",
+			LoginReuse:     90 * 24 * time.Hour,
+			SigningKey:     randomSecret,
+			Throttle: LoginThrottle{
+				FreeAttempts:  3,
+				BaseDelay:     time.Second,
+				MaxDelay:      30 * time.Second,
+				MaxFailures:   10,
+				IPMaxFailures: 100,
+				Lockout:       15 * time.Minute,
+				Window:        15 * time.Minute,
+			},
+		},
+		Password: Password{
+			Alg:           "argon2id",
+			BcryptCost:    12,
+			Argon2Time:    3,
+			Argon2Memory:  64 * 1024,
+			Argon2Threads: 2,
 		},
 		IntegrationAccrual: IntegrationAccrual{
 			PollInterval: 500 * time.Millisecond,
 			Timeout:      1000 * time.Millisecond,
 		},
+		DataExport: DataExport{
+			PollInterval: time.Second,
+			TTL:          24 * time.Hour,
+		},
+		Reconcile: Reconcile{
+			Interval: time.Hour,
+		},
+		Balance: Balance{
+			Expiration: Expiration{
+				OrderAccrual: 365 * 24 * time.Hour,
+				PromoAccrual: 90 * 24 * time.Hour,
+				PollInterval: time.Hour,
+			},
+			Transfer: Transfer{
+				DailyAmount: decimal.NewFromInt(10000),
+				DailyCount:  10,
+			},
+			Hold: Hold{
+				PollInterval: time.Minute,
+			},
+			Withdrawal: Withdrawal{
+				Precision: 2,
+			},
+		},
+		Idempotency: Idempotency{
+			TTL:             24 * time.Hour,
+			CleanupInterval: 10 * time.Minute,
+		},
 		RunAddress: "0.0.0.0:8080",
 	}
 
@@ -17,6 +17,16 @@ var (
 	ErrUnauthorized = NewError(1002, 401, "Unauthorized")
 	// ErrBadRequest - неверный запрос
 	ErrBadRequest = NewError(1003, 400, "Bad request")
+	// ErrForbidden - недостаточно прав
+	ErrForbidden = NewError(1004, 403, "Forbidden")
+	// ErrIdempotencyKeyInvalid - недопустимый ключ идемпотентности
+	ErrIdempotencyKeyInvalid = NewError(1005, 400, "Invalid idempotency key")
+	// ErrIdempotencyKeyReused - ключ идемпотентности уже использовался для другого запроса
+	ErrIdempotencyKeyReused = NewError(1006, 422, "Idempotency key already used for another request")
+	// ErrIdempotencyKeyInProgress - запрос с тем же ключом идемпотентности еще выполняется
+	ErrIdempotencyKeyInProgress = NewError(1007, 409, "Request with the same idempotency key is in progress")
+	// ErrListQueryInvalid - неверные параметры выборки списка: limit, cursor, status, type, from, to
+	ErrListQueryInvalid = NewError(1008, 400, "Invalid list query parameters")
 
 	// === Ошибки пользователя (1100-1199) ===
 
@@ -38,6 +48,27 @@ var (
 	// ErrUserWithdrawnNegative - общая сумма списаний не может быть отрицательной
 	ErrUserWithdrawnNegative = NewError(1106, 500, "Withdrawn amount cannot be negative")
 
+	// ErrUserRoleInvalid - недопустимая роль пользователя
+	ErrUserRoleInvalid = NewError(1107, 400, "Invalid role")
+
+	// ErrDataExportFormatInvalid - формат выгрузки персональных данных не поддерживается
+	ErrDataExportFormatInvalid = NewError(1108, 400, "Invalid export format")
+
+	// ErrDataExportInProgress - выгрузка персональных данных пользователя уже формируется
+	ErrDataExportInProgress = NewError(1109, 409, "Export already in progress")
+
+	// ErrUserBlocked - учетная запись пользователя заблокирована
+	ErrUserBlocked = NewError(1110, 403, "User blocked")
+
+	// ErrBalanceAsOfInvalid - неверный момент времени для расчета баланса
+	ErrBalanceAsOfInvalid = NewError(1111, 400, "Invalid balance date")
+
+	// ErrStatementPeriodInvalid - неверный месяц выписки
+	ErrStatementPeriodInvalid = NewError(1112, 400, "Invalid statement period")
+
+	// ErrStatementFormatInvalid - формат выписки не поддерживается
+	ErrStatementFormatInvalid = NewError(1113, 400, "Invalid statement format")
+
 	// === Ошибки операций (1200-1299) ===
 
 	// ErrOperationAttrsInvalid - аттрибуты операции должны соответствовать типу операции
@@ -61,6 +92,42 @@ var (
 	// ErrOperationPromoUsed - пользователь может воспользоваться промо-кампанией не более 1 раза
 	ErrOperationPromoUsed = NewError(1206, 409, "Promo already used")
 
+	// ErrOperationUserBlocked - заблокированный пользователь не может списывать баллы
+	ErrOperationUserBlocked = NewError(1207, 403, "Withdrawals are not allowed for blocked user")
+
+	// ErrTransferSelf - нельзя переводить баллы самому себе
+	ErrTransferSelf = NewError(1208, 400, "Cannot transfer points to yourself")
+
+	// ErrTransferRecipientNotFound - получатель перевода не найден или удален
+	ErrTransferRecipientNotFound = NewError(1209, 404, "Transfer recipient not found")
+
+	// ErrTransferDailyAmountExceeded - превышена сумма переводов за сутки
+	ErrTransferDailyAmountExceeded = NewError(1210, 422, "Daily transfer amount limit exceeded")
+
+	// ErrTransferDailyCountExceeded - превышено количество переводов за сутки
+	ErrTransferDailyCountExceeded = NewError(1211, 422, "Daily transfer count limit exceeded")
+
+	// ErrOperationStatusTransitInvalid - операция не может перейти из текущего статуса в новый
+	ErrOperationStatusTransitInvalid = NewError(1212, 409, "Operation status transition is not allowed")
+
+	// ErrWithdrawalNotCancelable - отменить можно только списание, которое еще не принято в обработку
+	ErrWithdrawalNotCancelable = NewError(1213, 409, "Withdrawal can not be canceled")
+
+	// ErrWithdrawalBelowMinimum - сумма списания меньше минимальной
+	ErrWithdrawalBelowMinimum = NewError(1214, 422, "Withdrawal sum is below the minimum")
+
+	// ErrWithdrawalDailyAmountExceeded - превышена сумма списаний за сутки
+	ErrWithdrawalDailyAmountExceeded = NewError(1215, 422, "Daily withdrawal amount limit exceeded")
+
+	// ErrWithdrawalMonthlyAmountExceeded - превышена сумма списаний за месяц
+	ErrWithdrawalMonthlyAmountExceeded = NewError(1216, 422, "Monthly withdrawal amount limit exceeded")
+
+	// ErrWithdrawalOrderShareExceeded - сумма списания превышает допустимую долю суммы заказа
+	ErrWithdrawalOrderShareExceeded = NewError(1217, 422, "Withdrawal exceeds the allowed share of the order total")
+
+	// ErrWithdrawalPrecisionInvalid - сумма списания содержит больше знаков после запятой, чем допускается
+	ErrWithdrawalPrecisionInvalid = NewError(1218, 422, "Withdrawal sum has too many decimal places")
+
 	// === Ошибки создания промо-кампаний (1300-1399) ===
 
 	// ErrPromoAlreadyExists - промо-кампания с таким кодом уже существует
@@ -79,6 +146,41 @@ var (
 
 	// ErrIntegrationRequestFailed - ошибка при запросе к внешнему сервису
 	ErrIntegrationRequestFailed = NewError(1401, 500, "Request failed")
+
+	// === Ошибки авторизации (1500-1599) ===
+
+	// ErrSessionInvalid - сессия не найдена, истекла или отозвана
+	ErrSessionInvalid = NewError(1500, 401, "Invalid session")
+
+	// ErrPasswordResetTokenInvalid - токен сброса пароля не найден, истек или уже использован
+	ErrPasswordResetTokenInvalid = NewError(1501, 400, "Invalid password reset token")
+
+	// ErrLoginThrottled - слишком много неудачных попыток входа, следующая попытка возможна позже
+	ErrLoginThrottled = NewError(1502, 429, "Too many login attempts")
+
+	// ErrUserLocked - учетная запись временно заблокирована из-за неудачных попыток входа
+	ErrUserLocked = NewError(1503, 423, "User temporarily locked")
+
+	// ErrAccessTokenNameInvalid - название персонального токена пустое или слишком длинное
+	ErrAccessTokenNameInvalid = NewError(1504, 400, "Invalid access token name")
+
+	// ErrAccessTokenScopeInvalid - области доступа персонального токена не заданы или не поддерживаются
+	ErrAccessTokenScopeInvalid = NewError(1505, 400, "Invalid access token scope")
+
+	// ErrAccessTokenExpiryInvalid - время истечения персонального токена в прошлом
+	ErrAccessTokenExpiryInvalid = NewError(1506, 400, "Invalid access token expiry")
+
+	// ErrTOTPAlreadyEnabled - двухфакторная аутентификация уже включена
+	ErrTOTPAlreadyEnabled = NewError(1507, 409, "Two-factor authentication already enabled")
+
+	// ErrTOTPNotEnrolled - двухфакторная аутентификация не подключалась или не включена
+	ErrTOTPNotEnrolled = NewError(1508, 409, "Two-factor authentication not enrolled")
+
+	// ErrTOTPCodeInvalid - неверный или уже использованный код двухфакторной аутентификации
+	ErrTOTPCodeInvalid = NewError(1509, 401, "Invalid two-factor authentication code")
+
+	// ErrLoginChallengeInvalid - незавершенный вход не найден, истек или превышено число попыток ввода кода
+	ErrLoginChallengeInvalid = NewError(1510, 401, "Invalid login challenge")
 )
 
 // Error - ошибка приложения
@@ -11,6 +11,7 @@ var (
 	ErrResponseInternal     = NewErrResponse(ErrInternal)
 	ErrResponseBadRequest   = NewErrResponse(ErrBadRequest)
 	ErrResponseUnauthorized = NewErrResponse(ErrUnauthorized)
+	ErrResponseForbidden    = NewErrResponse(ErrForbidden)
 )
 
 // ErrResponse - render.Renderer для ответов с ошибками.
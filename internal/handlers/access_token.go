@@ -0,0 +1,150 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+
+	"gophermart-loyalty/internal/errs"
+	"gophermart-loyalty/internal/middleware"
+)
+
+// accessTokenCreate - создание персонального токена доступа.
+// Токен возвращается только в ответе на этот запрос, в БД хранится только его хэш.
+// Если expires_at не задано, токен бессрочный.
+// Формат запроса:
+//    POST /api/user/tokens HTTP/1.1
+//    Content-Type: application/json
+//    Authorization: Bearer <token>
+//
+//    {
+//        "name": "import script",
+//        "scopes": ["orders:write", "balance:read"],
+//        "expires_at": "2021-12-31T23:59:59+03:00"
+//    }
+//
+// Возможные коды ответа:
+//    201 — токен успешно создан
+//    400 — неверный формат запроса, название, области доступа или время истечения токена
+//    401 — пользователь не авторизован
+//    403 — запрос выполнен с персональным токеном
+//    500 — внутренняя ошибка сервера
+//
+// Формат ответа:
+//    HTTP/1.1 201 Created
+//    Content-Type: application/json
+//
+//    {
+//        "id": 1,
+//        "name": "import script",
+//        "scopes": ["orders:write", "balance:read"],
+//        "expires_at": "2021-12-31T23:59:59+03:00",
+//        "last_used_at": null,
+//        "created_at": "2021-01-01T12:00:00+03:00",
+//        "token": "gmp_<token>"
+//    }
+func (h *Handlers) accessTokenCreate(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		_ = render.Render(w, r, errs.ErrResponseUnauthorized)
+		return
+	}
+
+	data := &AccessTokenCreateRequest{}
+	if err := render.Bind(r, data); err != nil {
+		_ = render.Render(w, r, errs.ErrResponseBadRequest)
+		return
+	}
+
+	t, token, err := h.useCases.AccessTokenCreate(r.Context(), userID, data.Name, data.Scopes, data.ExpiresAt)
+	if err != nil {
+		_ = render.Render(w, r, errs.NewErrResponse(err))
+		return
+	}
+
+	render.Status(r, http.StatusCreated)
+	_ = render.Render(w, r, newAccessTokenResponse(t, token))
+}
+
+// accessTokenList - получение списка неотозванных персональных токенов доступа пользователя.
+// Формат запроса:
+//    GET /api/user/tokens HTTP/1.1
+//    Content-Length: 0
+//    Authorization: Bearer <token>
+//
+// Возможные коды ответа:
+//    200 — успешная обработка запроса
+//    204 — нет данных для ответа
+//    401 — пользователь не авторизован
+//    403 — запрос выполнен с персональным токеном
+//    500 — внутренняя ошибка сервера
+//
+// Формат ответа:
+//    HTTP/1.1 200 OK
+//    Content-Type: application/json
+//
+//    [
+//        {
+//            "id": 1,
+//            "name": "import script",
+//            "scopes": ["orders:write", "balance:read"],
+//            "expires_at": null,
+//            "last_used_at": "2021-01-02T12:00:00+03:00",
+//            "created_at": "2021-01-01T12:00:00+03:00"
+//        }
+//    ]
+func (h *Handlers) accessTokenList(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		_ = render.Render(w, r, errs.ErrResponseUnauthorized)
+		return
+	}
+
+	tokens, err := h.useCases.AccessTokenList(r.Context(), userID)
+	if err != nil {
+		_ = render.Render(w, r, errs.NewErrResponse(err))
+		return
+	}
+
+	if len(tokens) == 0 {
+		render.NoContent(w, r)
+		return
+	}
+
+	_ = render.RenderList(w, r, newAccessTokenListResponse(tokens))
+}
+
+// accessTokenRevoke - отзыв персонального токена доступа.
+// Формат запроса:
+//    DELETE /api/user/tokens/{id} HTTP/1.1
+//    Content-Length: 0
+//    Authorization: Bearer <token>
+//
+// Возможные коды ответа:
+//    200 — токен успешно отозван
+//    400 — неверный формат запроса
+//    401 — пользователь не авторизован
+//    403 — запрос выполнен с персональным токеном
+//    404 — токен не найден или уже отозван
+//    500 — внутренняя ошибка сервера
+func (h *Handlers) accessTokenRevoke(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		_ = render.Render(w, r, errs.ErrResponseUnauthorized)
+		return
+	}
+
+	tokenID, err := strconv.ParseUint(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		_ = render.Render(w, r, errs.ErrResponseBadRequest)
+		return
+	}
+
+	if err = h.useCases.AccessTokenRevoke(r.Context(), userID, tokenID); err != nil {
+		_ = render.Render(w, r, errs.NewErrResponse(err))
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
@@ -0,0 +1,144 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+
+	"gophermart-loyalty/internal/errs"
+	"gophermart-loyalty/internal/models"
+)
+
+func (suite *handlersSuite) TestAccessTokenCreate() {
+	expiresAt := time.Now().Add(24 * time.Hour).Truncate(time.Second)
+	reqBody := `{"name":"import script","scopes":["orders:write","balance:read"],"expires_at":"` +
+		expiresAt.Format(time.RFC3339) + `"}`
+
+	suite.Run("success", func() {
+		suite.repo.On("AccessTokenCreate", mock.Anything, mock.MatchedBy(func(t *models.AccessToken) bool {
+			return t.UserID == 1 && t.Name == "import script" && len(t.Scopes) == 2 && len(t.TokenHash) == 64
+		}), mock.MatchedBy(func(ttl int64) bool {
+			return ttl > 86000 && ttl <= 86400
+		})).Return(nil).Run(func(args mock.Arguments) {
+			t := args.Get(1).(*models.AccessToken)
+			t.ID = 7
+			t.ExpiresAt = &expiresAt
+			t.CreatedAt = time.Now()
+		}).Once()
+
+		token := suite.validJWTToken(1)
+		res := suite.httpJSONRequest(http.MethodPost, "/tokens", reqBody, token)
+		defer res.Body.Close()
+		suite.Equal(http.StatusCreated, res.StatusCode)
+		resJSON := suite.parseJSON(res.Body)
+		suite.Equal(7., resJSON["id"])
+		suite.Equal("import script", resJSON["name"])
+		suite.Equal([]interface{}{"orders:write", "balance:read"}, resJSON["scopes"])
+		suite.Equal(expiresAt.Format(time.RFC3339), resJSON["expires_at"])
+		suite.Nil(resJSON["last_used_at"])
+		suite.True(strings.HasPrefix(resJSON["token"].(string), models.AccessTokenPrefix))
+	})
+
+	suite.Run("invalid scope", func() {
+		token := suite.validJWTToken(1)
+		res := suite.httpJSONRequest(http.MethodPost, "/tokens", `{"name":"test","scopes":["admin"]}`, token)
+		defer res.Body.Close()
+		suite.Equal(http.StatusBadRequest, res.StatusCode)
+		resJSON := suite.parseJSON(res.Body)
+		suite.Equal(1505., resJSON["code"])
+	})
+
+	suite.Run("invalid request body", func() {
+		token := suite.validJWTToken(1)
+		res := suite.httpJSONRequest(http.MethodPost, "/tokens", "invalid", token)
+		defer res.Body.Close()
+		suite.Equal(http.StatusBadRequest, res.StatusCode)
+	})
+
+	suite.Run("access token can not create tokens", func() {
+		token := suite.validAccessToken(1, models.ScopeOrdersWrite)
+		res := suite.httpJSONRequest(http.MethodPost, "/tokens", reqBody, token)
+		defer res.Body.Close()
+		suite.Equal(http.StatusForbidden, res.StatusCode)
+	})
+}
+
+func (suite *handlersSuite) TestAccessTokenList() {
+	suite.Run("success", func() {
+		lastUsed := time.Now()
+		suite.repo.On("AccessTokenGetByUser", mock.Anything, uint64(1)).
+			Return([]*models.AccessToken{
+				{ID: 2, UserID: 1, Name: "second", Scopes: []models.AccessTokenScope{models.ScopeBalanceRead}, LastUsedAt: &lastUsed},
+				{ID: 1, UserID: 1, Name: "first", Scopes: []models.AccessTokenScope{models.ScopeOrdersRead}},
+			}, nil).Once()
+		token := suite.validJWTToken(1)
+		res := suite.httpJSONRequest(http.MethodGet, "/tokens", "", token)
+		defer res.Body.Close()
+		suite.Equal(http.StatusOK, res.StatusCode)
+		list := suite.parseJSONList(res.Body)
+		suite.Require().Len(list, 2)
+		suite.Equal("second", list[0]["name"])
+		suite.Equal(lastUsed.Format(time.RFC3339), list[0]["last_used_at"])
+		suite.NotContains(list[0], "token")
+	})
+
+	suite.Run("no tokens", func() {
+		suite.repo.On("AccessTokenGetByUser", mock.Anything, uint64(1)).Return(nil, nil).Once()
+		token := suite.validJWTToken(1)
+		res := suite.httpJSONRequest(http.MethodGet, "/tokens", "", token)
+		defer res.Body.Close()
+		suite.Equal(http.StatusNoContent, res.StatusCode)
+	})
+}
+
+func (suite *handlersSuite) TestAccessTokenRevoke() {
+	suite.Run("success", func() {
+		suite.repo.On("AccessTokenRevoke", mock.Anything, uint64(1), uint64(7)).Return(nil).Once()
+		token := suite.validJWTToken(1)
+		res := suite.httpJSONRequest(http.MethodDelete, "/tokens/7", "", token)
+		defer res.Body.Close()
+		suite.Equal(http.StatusOK, res.StatusCode)
+	})
+
+	suite.Run("not found", func() {
+		suite.repo.On("AccessTokenRevoke", mock.Anything, uint64(1), uint64(8)).Return(errs.ErrNotFound).Once()
+		token := suite.validJWTToken(1)
+		res := suite.httpJSONRequest(http.MethodDelete, "/tokens/8", "", token)
+		defer res.Body.Close()
+		suite.Equal(http.StatusNotFound, res.StatusCode)
+	})
+
+	suite.Run("invalid id", func() {
+		token := suite.validJWTToken(1)
+		res := suite.httpJSONRequest(http.MethodDelete, "/tokens/abc", "", token)
+		defer res.Body.Close()
+		suite.Equal(http.StatusBadRequest, res.StatusCode)
+	})
+}
+
+func (suite *handlersSuite) TestAccessTokenScopes() {
+	suite.Run("allowed scope", func() {
+		suite.repo.On("UserGetByID", mock.Anything, uint64(1)).
+			Return(&models.User{ID: 1}, nil).Once()
+		token := suite.validAccessToken(1, models.ScopeBalanceRead)
+		res := suite.httpJSONRequest(http.MethodGet, "/balance", "", token)
+		defer res.Body.Close()
+		suite.Equal(http.StatusOK, res.StatusCode)
+	})
+
+	suite.Run("missing scope", func() {
+		token := suite.validAccessToken(1, models.ScopeBalanceRead)
+		res := suite.httpPlainTextRequest(http.MethodPost, "/orders", "12345678903", token)
+		defer res.Body.Close()
+		suite.Equal(http.StatusForbidden, res.StatusCode)
+	})
+
+	suite.Run("revoked token", func() {
+		suite.repo.On("AccessTokenUse", mock.Anything, mock.Anything).Return(nil, errs.ErrNotFound).Once()
+		res := suite.httpJSONRequest(http.MethodGet, "/balance", "", models.AccessTokenPrefix+"revoked")
+		defer res.Body.Close()
+		suite.Equal(http.StatusUnauthorized, res.StatusCode)
+	})
+}
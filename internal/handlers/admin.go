@@ -0,0 +1,195 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+
+	"gophermart-loyalty/internal/errs"
+	"gophermart-loyalty/internal/middleware"
+	"gophermart-loyalty/internal/models"
+)
+
+// adminUserRoleSet - установка роли пользователя.
+// Доступно только администраторам.
+// Ранее выпущенные токены пользователя становятся недействительными,
+// новые токены, полученные по refresh-токену, содержат новую роль.
+// Формат запроса:
+//    PUT /api/admin/users/{id}/role HTTP/1.1
+//    Content-Type: application/json
+//    Authorization: Bearer <token>
+//
+//    {
+//        "role": "support"
+//    }
+//
+// Возможные коды ответа:
+//    200 — роль успешно установлена
+//    400 — неверный формат запроса или недопустимая роль
+//    401 — пользователь не авторизован
+//    403 — недостаточно прав
+//    404 — пользователь не найден
+//    500 — внутренняя ошибка сервера
+func (h *Handlers) adminUserRoleSet(w http.ResponseWriter, r *http.Request) {
+	userID, err := strconv.ParseUint(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		_ = render.Render(w, r, errs.ErrResponseBadRequest)
+		return
+	}
+
+	data := &UserRoleRequest{}
+	if err = render.Bind(r, data); err != nil {
+		_ = render.Render(w, r, errs.ErrResponseBadRequest)
+		return
+	}
+
+	if err = h.useCases.UserSetRole(r.Context(), userID, data.Role); err != nil {
+		_ = render.Render(w, r, errs.NewErrResponse(err))
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+// adminUserBlock - блокировка учетной записи пользователя.
+// Доступно только администраторам.
+// Заблокированный пользователь не может войти, его токены перестают действовать,
+// а списания баллов отклоняются. Начисления по ранее загруженным заказам продолжают обрабатываться.
+// Формат запроса:
+//    POST /api/admin/users/{id}/block HTTP/1.1
+//    Content-Type: application/json
+//    Authorization: Bearer <token>
+//
+//    {
+//        "reason": "chargeback fraud, ticket FRAUD-123"
+//    }
+//
+// Возможные коды ответа:
+//    200 — учетная запись заблокирована
+//    400 — неверный формат запроса или не указана причина
+//    401 — пользователь не авторизован
+//    403 — недостаточно прав или попытка заблокировать свою учетную запись
+//    404 — пользователь не найден или удален
+//    500 — внутренняя ошибка сервера
+func (h *Handlers) adminUserBlock(w http.ResponseWriter, r *http.Request) {
+	h.adminUserStatusSet(w, r, models.UserBlocked)
+}
+
+// adminUserUnblock - разблокировка учетной записи пользователя.
+// Доступно только администраторам.
+// Формат запроса:
+//    POST /api/admin/users/{id}/unblock HTTP/1.1
+//    Content-Type: application/json
+//    Authorization: Bearer <token>
+//
+//    {
+//        "reason": "investigation closed"
+//    }
+//
+// Возможные коды ответа:
+//    200 — учетная запись разблокирована
+//    400 — неверный формат запроса или не указана причина
+//    401 — пользователь не авторизован
+//    403 — недостаточно прав
+//    404 — пользователь не найден или удален
+//    500 — внутренняя ошибка сервера
+func (h *Handlers) adminUserUnblock(w http.ResponseWriter, r *http.Request) {
+	h.adminUserStatusSet(w, r, models.UserActive)
+}
+
+// adminUserStatusSet - установка статуса учетной записи пользователя от имени текущего сотрудника.
+func (h *Handlers) adminUserStatusSet(w http.ResponseWriter, r *http.Request, status models.UserStatus) {
+	staffID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		_ = render.Render(w, r, errs.ErrResponseUnauthorized)
+		return
+	}
+
+	userID, err := strconv.ParseUint(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		_ = render.Render(w, r, errs.ErrResponseBadRequest)
+		return
+	}
+
+	data := &UserStatusRequest{}
+	if err = render.Bind(r, data); err != nil {
+		_ = render.Render(w, r, errs.ErrResponseBadRequest)
+		return
+	}
+
+	if err = h.useCases.UserSetStatus(r.Context(), staffID, userID, status, data.Reason); err != nil {
+		_ = render.Render(w, r, errs.NewErrResponse(err))
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+// adminAdjustmentCreate - корректировка баланса пользователя от имени текущего сотрудника.
+// Доступно только администраторам.
+// Положительная сумма зачисляет баллы, отрицательная - списывает. Корректировка отображается в истории операций
+// пользователя с описанием description, а причина, номер обращения и id сотрудника сохраняются в журнале корректировок.
+// Формат запроса:
+//    POST /api/admin/users/{id}/adjustments HTTP/1.1
+//    Content-Type: application/json
+//    Authorization: Bearer <token>
+//
+//    {
+//        "amount": -150,
+//        "reason": "duplicate accrual for order 12345678903",
+//        "ticket": "SUP-4521",
+//        "description": "Исправление ошибочного начисления"
+//    }
+//
+// Возможные коды ответа:
+//    201 — корректировка создана
+//    400 — неверный формат запроса, нулевая сумма, не указаны причина или номер обращения
+//    401 — пользователь не авторизован
+//    402 — на счету пользователя недостаточно средств для списания
+//    403 — недостаточно прав или попытка скорректировать свой баланс
+//    404 — пользователь не найден или удален
+//    500 — внутренняя ошибка сервера
+//
+// Формат ответа:
+//    HTTP/1.1 201 Created
+//    Content-Type: application/json
+//
+//    {
+//        "id": 42,
+//        "amount": -150,
+//        "description": "Исправление ошибочного начисления",
+//        "processed_at": "2020-01-01T00:00:00Z"
+//    }
+func (h *Handlers) adminAdjustmentCreate(w http.ResponseWriter, r *http.Request) {
+	staffID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		_ = render.Render(w, r, errs.ErrResponseUnauthorized)
+		return
+	}
+
+	userID, err := strconv.ParseUint(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		_ = render.Render(w, r, errs.ErrResponseBadRequest)
+		return
+	}
+
+	data := &AdjustmentCreateRequest{}
+	if err = render.Bind(r, data); err != nil {
+		_ = render.Render(w, r, errs.ErrResponseBadRequest)
+		return
+	}
+
+	op, err := h.useCases.BalanceAdjust(r.Context(), staffID, userID, data.Amount, data.Reason, data.Ticket, data.Description)
+	if err != nil {
+		_ = render.Render(w, r, errs.NewErrResponse(err))
+		return
+	}
+
+	render.Status(r, http.StatusCreated)
+	_ = render.Render(w, r, &AdjustmentResponse{
+		ID:          op.ID,
+		Amount:      op.Amount,
+		Description: op.Description,
+		ProcessedAt: op.UpdatedAt.Format(timeFmt),
+	})
+}
@@ -0,0 +1,250 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/stretchr/testify/mock"
+
+	"gophermart-loyalty/internal/errs"
+	"gophermart-loyalty/internal/middleware"
+	"gophermart-loyalty/internal/models"
+)
+
+func (suite *handlersSuite) TestAdminUserRoleSet() {
+	suite.Run("success", func() {
+		token := suite.validJWTTokenWithRole(1, models.RoleAdmin)
+		suite.repo.On("UserSetRole", mock.Anything, uint64(2), models.RoleSupport).
+			Return(nil).Once()
+
+		res := suite.httpJSONRequest(http.MethodPut, "/admin/users/2/role", `{"role":"support"}`, token)
+		defer res.Body.Close()
+		suite.Equal(http.StatusOK, res.StatusCode)
+	})
+
+	suite.Run("user not found", func() {
+		token := suite.validJWTTokenWithRole(1, models.RoleAdmin)
+		suite.repo.On("UserSetRole", mock.Anything, uint64(1000), models.RoleAdmin).
+			Return(errs.ErrNotFound).Once()
+
+		res := suite.httpJSONRequest(http.MethodPut, "/admin/users/1000/role", `{"role":"admin"}`, token)
+		defer res.Body.Close()
+		suite.Equal(http.StatusNotFound, res.StatusCode)
+	})
+
+	suite.Run("invalid role", func() {
+		token := suite.validJWTTokenWithRole(1, models.RoleAdmin)
+		res := suite.httpJSONRequest(http.MethodPut, "/admin/users/2/role", `{"role":"superuser"}`, token)
+		defer res.Body.Close()
+		suite.Equal(http.StatusBadRequest, res.StatusCode)
+		resJSON := suite.parseJSON(res.Body)
+		suite.Equal(1107., resJSON["code"])
+	})
+
+	suite.Run("invalid user id", func() {
+		token := suite.validJWTTokenWithRole(1, models.RoleAdmin)
+		res := suite.httpJSONRequest(http.MethodPut, "/admin/users/abc/role", `{"role":"admin"}`, token)
+		defer res.Body.Close()
+		suite.Equal(http.StatusBadRequest, res.StatusCode)
+	})
+
+	suite.Run("forbidden for support", func() {
+		token := suite.validJWTTokenWithRole(1, models.RoleSupport)
+		res := suite.httpJSONRequest(http.MethodPut, "/admin/users/2/role", `{"role":"admin"}`, token)
+		defer res.Body.Close()
+		suite.Equal(http.StatusForbidden, res.StatusCode)
+		resJSON := suite.parseJSON(res.Body)
+		suite.Equal(1004., resJSON["code"])
+	})
+
+	suite.Run("unauthorized", func() {
+		res := suite.httpJSONRequest(http.MethodPut, "/admin/users/2/role", `{"role":"admin"}`, "invalid token")
+		defer res.Body.Close()
+		suite.Equal(http.StatusUnauthorized, res.StatusCode)
+	})
+}
+
+func (suite *handlersSuite) TestAdminUserBlock() {
+	suite.Run("block", func() {
+		token := suite.validJWTTokenWithRole(1, models.RoleAdmin)
+		suite.repo.On("UserSetStatus", mock.Anything, mock.MatchedBy(func(c *models.UserStatusChange) bool {
+			return c.UserID == 2 && c.Status == models.UserBlocked && c.Reason == "fraud" && c.ChangedBy == 1
+		})).Return(nil).Once()
+
+		res := suite.httpJSONRequest(http.MethodPost, "/admin/users/2/block", `{"reason":" fraud "}`, token)
+		defer res.Body.Close()
+		suite.Equal(http.StatusOK, res.StatusCode)
+	})
+
+	suite.Run("unblock", func() {
+		token := suite.validJWTTokenWithRole(1, models.RoleAdmin)
+		suite.repo.On("UserSetStatus", mock.Anything, mock.MatchedBy(func(c *models.UserStatusChange) bool {
+			return c.UserID == 2 && c.Status == models.UserActive && c.ChangedBy == 1
+		})).Return(nil).Once()
+
+		res := suite.httpJSONRequest(http.MethodPost, "/admin/users/2/unblock", `{"reason":"investigation closed"}`, token)
+		defer res.Body.Close()
+		suite.Equal(http.StatusOK, res.StatusCode)
+	})
+
+	suite.Run("reason required", func() {
+		token := suite.validJWTTokenWithRole(1, models.RoleAdmin)
+		res := suite.httpJSONRequest(http.MethodPost, "/admin/users/2/block", `{"reason":""}`, token)
+		defer res.Body.Close()
+		suite.Equal(http.StatusBadRequest, res.StatusCode)
+	})
+
+	suite.Run("can not block yourself", func() {
+		token := suite.validJWTTokenWithRole(1, models.RoleAdmin)
+		res := suite.httpJSONRequest(http.MethodPost, "/admin/users/1/block", `{"reason":"test"}`, token)
+		defer res.Body.Close()
+		suite.Equal(http.StatusForbidden, res.StatusCode)
+	})
+
+	suite.Run("user not found", func() {
+		token := suite.validJWTTokenWithRole(1, models.RoleAdmin)
+		suite.repo.On("UserSetStatus", mock.Anything, mock.Anything).Return(errs.ErrNotFound).Once()
+
+		res := suite.httpJSONRequest(http.MethodPost, "/admin/users/1000/block", `{"reason":"fraud"}`, token)
+		defer res.Body.Close()
+		suite.Equal(http.StatusNotFound, res.StatusCode)
+	})
+
+	suite.Run("forbidden for support", func() {
+		token := suite.validJWTTokenWithRole(1, models.RoleSupport)
+		res := suite.httpJSONRequest(http.MethodPost, "/admin/users/2/block", `{"reason":"fraud"}`, token)
+		defer res.Body.Close()
+		suite.Equal(http.StatusForbidden, res.StatusCode)
+	})
+}
+
+func (suite *handlersSuite) TestAdminAdjustmentCreate() {
+	suite.Run("success", func() {
+		token := suite.validJWTTokenWithRole(1, models.RoleAdmin)
+		suite.repo.On("OperationAdjust", mock.Anything,
+			mock.MatchedBy(func(op *models.Operation) bool {
+				return op.UserID == 2 && op.Type == models.BalanceAdjustment && op.Status == models.StatusProcessed &&
+					op.Amount.String() == "-150" && op.Description == "Исправление ошибочного начисления"
+			}),
+			mock.MatchedBy(func(adj *models.Adjustment) bool {
+				return adj.StaffID == 1 && adj.Reason == "duplicate accrual" && adj.Ticket == "SUP-1"
+			}),
+		).Return(func(_ context.Context, op *models.Operation, _ *models.Adjustment) error {
+			op.ID = 42
+			op.UpdatedAt = time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+			return nil
+		}).Once()
+
+		reqBody := `{"amount":-150,"reason":"duplicate accrual","ticket":"SUP-1","description":"Исправление ошибочного начисления"}`
+		res := suite.httpJSONRequest(http.MethodPost, "/admin/users/2/adjustments", reqBody, token)
+		defer res.Body.Close()
+		suite.Equal(http.StatusCreated, res.StatusCode)
+		resJSON := suite.parseJSON(res.Body)
+		suite.Equal(42., resJSON["id"])
+		suite.Equal(-150., resJSON["amount"])
+		suite.Equal("2020-01-01T00:00:00Z", resJSON["processed_at"])
+	})
+
+	suite.Run("default description", func() {
+		token := suite.validJWTTokenWithRole(1, models.RoleAdmin)
+		suite.repo.On("OperationAdjust", mock.Anything,
+			mock.MatchedBy(func(op *models.Operation) bool {
+				return op.Description == "Корректировка баланса службой поддержки"
+			}),
+			mock.Anything,
+		).Return(nil).Once()
+
+		res := suite.httpJSONRequest(http.MethodPost, "/admin/users/2/adjustments", `{"amount":100,"reason":"lost accrual","ticket":"SUP-2"}`, token)
+		defer res.Body.Close()
+		suite.Equal(http.StatusCreated, res.StatusCode)
+	})
+
+	suite.Run("replayed with idempotency key", func() {
+		token := suite.validJWTTokenWithRole(1, models.RoleAdmin)
+		suite.repo.On("IdempotencyKeyReserve", mock.Anything, mock.Anything, int64(24*3600)).
+			Return(func(_ context.Context, k *models.IdempotencyKey, _ int64) *models.IdempotencyKey {
+				return &models.IdempotencyKey{UserID: k.UserID, Key: k.Key, Fingerprint: k.Fingerprint, StatusCode: http.StatusCreated}
+			}, nil).Once()
+
+		res := suite.httpIdempotentJSONRequest(http.MethodPost, "/admin/users/2/adjustments", `{"amount":100,"reason":"lost accrual","ticket":"SUP-2"}`, token, "key-1")
+		defer res.Body.Close()
+		suite.Equal(http.StatusCreated, res.StatusCode)
+		suite.Equal("true", res.Header.Get(middleware.IdempotentReplayedHeader))
+	})
+
+	suite.Run("reason required", func() {
+		token := suite.validJWTTokenWithRole(1, models.RoleAdmin)
+		res := suite.httpJSONRequest(http.MethodPost, "/admin/users/2/adjustments", `{"amount":100,"reason":"","ticket":"SUP-2"}`, token)
+		defer res.Body.Close()
+		suite.Equal(http.StatusBadRequest, res.StatusCode)
+	})
+
+	suite.Run("ticket required", func() {
+		token := suite.validJWTTokenWithRole(1, models.RoleAdmin)
+		res := suite.httpJSONRequest(http.MethodPost, "/admin/users/2/adjustments", `{"amount":100,"reason":"lost accrual"}`, token)
+		defer res.Body.Close()
+		suite.Equal(http.StatusBadRequest, res.StatusCode)
+	})
+
+	suite.Run("zero amount", func() {
+		token := suite.validJWTTokenWithRole(1, models.RoleAdmin)
+		res := suite.httpJSONRequest(http.MethodPost, "/admin/users/2/adjustments", `{"amount":0,"reason":"test","ticket":"SUP-3"}`, token)
+		defer res.Body.Close()
+		suite.Equal(http.StatusBadRequest, res.StatusCode)
+		resJSON := suite.parseJSON(res.Body)
+		suite.Equal(1201., resJSON["code"])
+	})
+
+	suite.Run("can not adjust own balance", func() {
+		token := suite.validJWTTokenWithRole(1, models.RoleAdmin)
+		res := suite.httpJSONRequest(http.MethodPost, "/admin/users/1/adjustments", `{"amount":100,"reason":"test","ticket":"SUP-4"}`, token)
+		defer res.Body.Close()
+		suite.Equal(http.StatusForbidden, res.StatusCode)
+	})
+
+	suite.Run("insufficient funds", func() {
+		token := suite.validJWTTokenWithRole(1, models.RoleAdmin)
+		suite.repo.On("OperationAdjust", mock.Anything, mock.Anything, mock.Anything).
+			Return(errs.ErrUserBalanceNegative).Once()
+
+		res := suite.httpJSONRequest(http.MethodPost, "/admin/users/2/adjustments", `{"amount":-1000,"reason":"test","ticket":"SUP-5"}`, token)
+		defer res.Body.Close()
+		suite.Equal(http.StatusPaymentRequired, res.StatusCode)
+	})
+
+	suite.Run("user not found", func() {
+		token := suite.validJWTTokenWithRole(1, models.RoleAdmin)
+		suite.repo.On("OperationAdjust", mock.Anything, mock.Anything, mock.Anything).
+			Return(errs.ErrNotFound).Once()
+
+		res := suite.httpJSONRequest(http.MethodPost, "/admin/users/1000/adjustments", `{"amount":100,"reason":"test","ticket":"SUP-6"}`, token)
+		defer res.Body.Close()
+		suite.Equal(http.StatusNotFound, res.StatusCode)
+	})
+
+	suite.Run("forbidden for support", func() {
+		token := suite.validJWTTokenWithRole(1, models.RoleSupport)
+		res := suite.httpJSONRequest(http.MethodPost, "/admin/users/2/adjustments", `{"amount":100,"reason":"test","ticket":"SUP-7"}`, token)
+		defer res.Body.Close()
+		suite.Equal(http.StatusForbidden, res.StatusCode)
+	})
+}
+
+func (suite *handlersSuite) TestBlockedUserToken() {
+	suite.repo.On("UserGetByID", mock.Anything, uint64(1)).
+		Return(&models.User{ID: 1, Status: models.UserBlocked}, nil).Once()
+	claims := jwt.MapClaims{
+		"sub": 1,
+		"jti": "1",
+		"iat": time.Now().Unix(),
+		"nbf": time.Now().Unix(),
+		"exp": time.Now().Add(time.Hour).Unix(),
+	}
+	token := suite.generateJWTToken(claims, suite.cfg.SigningAlg, suite.cfg.SigningKey)
+
+	res := suite.httpRequest(http.MethodGet, "/balance", "", "", token)
+	defer res.Body.Close()
+	suite.Equal(http.StatusUnauthorized, res.StatusCode)
+}
@@ -27,8 +27,31 @@ import (
 //
 //    {
 //    	"current": 500.5,
-//    	"withdrawn": 42
+//    	"withdrawn": 42,
+//    	"pending": 100
 //    }
+//
+// current — баллы, которые можно потратить, pending — начисленные баллы, которые удерживаются
+// до окончания срока возврата заказа и пока не могут быть потрачены.
+//
+// С параметром as_of (RFC3339 или YYYY-MM-DD) возвращается баланс на этот момент по сумме проводок,
+// записанных до него:
+//    GET /api/user/balance?as_of=2020-02-01 HTTP/1.1
+//
+// Дополнительные коды ответа:
+//    400 — неверный или будущий момент as_of
+//
+// Формат ответа:
+//    HTTP/1.1 200 OK
+//    Content-Type: application/json
+//
+//    {
+//    	"balance": 600.5,
+//    	"withdrawn": 42,
+//    	"as_of": "2020-02-01T00:00:00Z"
+//    }
+//
+// balance включает удержанные на тот момент баллы.
 func (h *Handlers) balanceGet(w http.ResponseWriter, r *http.Request) {
 	// Получаем пользователя из контекста
 	userID, ok := middleware.GetUserID(r.Context())
@@ -37,6 +60,22 @@ func (h *Handlers) balanceGet(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Баланс на момент времени
+	if r.URL.Query().Has("as_of") {
+		asOf, err := decodeQueryTime(r.URL.Query().Get("as_of"))
+		if err != nil || asOf == nil {
+			_ = render.Render(w, r, errs.NewErrResponse(errs.ErrBalanceAsOfInvalid))
+			return
+		}
+		b, err := h.useCases.UserBalanceAsOf(r.Context(), userID, *asOf)
+		if err != nil {
+			_ = render.Render(w, r, errs.NewErrResponse(err))
+			return
+		}
+		_ = render.Render(w, r, newBalanceAsOfResponse(b))
+		return
+	}
+
 	// Запрашиваем пользователя
 	user, err := h.useCases.UserGetByID(r.Context(), userID)
 	if errors.Is(err, errs.ErrNotFound) {
@@ -51,21 +90,26 @@ func (h *Handlers) balanceGet(w http.ResponseWriter, r *http.Request) {
 
 	// Отправляем ответ
 	_ = render.Render(w, r, &BalanceResponse{
-		Current:   user.Balance,
+		Current:   user.Available(),
 		Withdrawn: user.Withdrawn,
+		Pending:   user.Pending,
 	})
 }
 
 // balanceHistoryGet - запрос истории операций по балансу пользователя.
 // В ответе отображается только список тех операций, которые были изменяют баланс пользователя.
 // Формат запроса:
-//    GET /api/user/balance/history HTTP/1.1
+//    GET /api/user/balance/history?limit=20&cursor=<cursor>&type=order_accrual&from=2020-01-01 HTTP/1.1
 //    Content-Length: 0
 //    Authorization: Bearer <token>
 //
+// Все параметры необязательны (см. decodeOperationQuery), период задается по времени обработки операции.
+// Если задан limit и есть следующая страница, ответ содержит заголовки X-Next-Cursor и Link с rel="next".
+//
 // Возможные коды ответа:
 //    200 — успешная обработка запроса
 //	  204 — история операций пуста
+//    400 — неверные параметры выборки
 //    401 — пользователь не авторизован
 //    500 — внутренняя ошибка сервера
 //
@@ -100,8 +144,14 @@ func (h *Handlers) balanceHistoryGet(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	q, err := decodeOperationQuery(r, true)
+	if err != nil {
+		_ = render.Render(w, r, errs.NewErrResponse(err))
+		return
+	}
+
 	// Запрашиваем историю операций пользователя
-	history, err := h.useCases.UserBalanceHistoryGetByID(r.Context(), userID)
+	history, next, err := h.useCases.UserBalanceHistoryGetByID(r.Context(), userID, q)
 	if err != nil {
 		_ = render.Render(w, r, errs.NewErrResponse(err))
 		return
@@ -114,5 +164,60 @@ func (h *Handlers) balanceHistoryGet(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Отправляем ответ
+	setNextPage(w, r, next)
 	_ = render.RenderList(w, r, newBalanceHistoryResponse(history))
 }
+
+// balanceExpirationsGet - запрос предстоящего сгорания баллов пользователя.
+// Баллы расходуются в порядке начисления, поэтому в ответе указаны только неизрасходованные остатки начислений.
+// Формат запроса:
+//    GET /api/user/balance/expirations HTTP/1.1
+//    Content-Length: 0
+//    Authorization: Bearer <token>
+//
+// Возможные коды ответа:
+//    200 — успешная обработка запроса
+//    204 — нет баллов, которые сгорят
+//    401 — пользователь не авторизован
+//    500 — внутренняя ошибка сервера
+//
+// Формат ответа:
+//    HTTP/1.1 200 OK
+//    Content-Type: application/json
+//
+//    [
+//        {
+//            "amount": 100,
+//            "type": "promo_accrual",
+//            "accrued_at": "2020-01-01T00:00:00Z",
+//            "expires_at": "2020-03-31T00:00:00Z"
+//        },
+//        {
+//            "amount": 250.5,
+//            "type": "order_accrual",
+//            "accrued_at": "2020-01-02T00:00:00Z",
+//            "expires_at": "2021-01-01T00:00:00Z"
+//        }
+//    ]
+func (h *Handlers) balanceExpirationsGet(w http.ResponseWriter, r *http.Request) {
+	// Получаем пользователя из контекста
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		_ = render.Render(w, r, errs.ErrResponseUnauthorized)
+		return
+	}
+
+	lots, err := h.useCases.PointsExpiringGet(r.Context(), userID, &h.balance.Expiration)
+	if err != nil {
+		_ = render.Render(w, r, errs.NewErrResponse(err))
+		return
+	}
+
+	// Если сгорать нечему, возвращаем 204 No Content
+	if len(lots) == 0 {
+		render.NoContent(w, r)
+		return
+	}
+
+	_ = render.RenderList(w, r, newBalanceExpirationsResponse(lots))
+}
@@ -13,6 +13,7 @@ import (
 
 func (suite *handlersSuite) TestBalanceGet() {
 	suite.Run("success", func() {
+		token := suite.validJWTToken(1)
 		suite.repo.On("UserGetByID", mock.Anything, uint64(1)).
 			Return(&models.User{
 				ID:        1,
@@ -21,20 +22,64 @@ func (suite *handlersSuite) TestBalanceGet() {
 				Withdrawn: decimal.NewFromFloat(20.2),
 			}, nil).Once()
 
-		token := suite.validJWTToken(1)
 		res := suite.httpJSONRequest(http.MethodGet, "/balance", "", token)
 		defer res.Body.Close()
 		suite.Equal(http.StatusOK, res.StatusCode)
 		resJSON := suite.parseJSON(res.Body)
 		suite.Equal(100.34, resJSON["current"])
 		suite.Equal(20.2, resJSON["withdrawn"])
+		suite.Equal(0., resJSON["pending"])
 	})
 
+	suite.Run("pending points", func() {
+		token := suite.validJWTToken(1)
+		suite.repo.On("UserGetByID", mock.Anything, uint64(1)).
+			Return(&models.User{
+				ID:        1,
+				Login:     "test",
+				Balance:   decimal.NewFromInt(150),
+				Withdrawn: decimal.NewFromInt(20),
+				Pending:   decimal.NewFromInt(100),
+			}, nil).Once()
+
+		res := suite.httpJSONRequest(http.MethodGet, "/balance", "", token)
+		defer res.Body.Close()
+		suite.Equal(http.StatusOK, res.StatusCode)
+		resJSON := suite.parseJSON(res.Body)
+		suite.Equal(50., resJSON["current"])
+		suite.Equal(100., resJSON["pending"])
+	})
+
+	suite.Run("as of date", func() {
+		token := suite.validJWTToken(1)
+		asOf := time.Date(2022, 10, 1, 0, 0, 0, 0, time.UTC)
+		suite.repo.On("UserBalanceAsOf", mock.Anything, uint64(1), asOf).
+			Return(&models.BalanceSnapshot{AsOf: asOf, Balance: decimal.NewFromInt(150), Withdrawn: decimal.NewFromInt(20)}, nil).Once()
+
+		res := suite.httpJSONRequest(http.MethodGet, "/balance?as_of=2022-10-01", "", token)
+		defer res.Body.Close()
+		suite.Equal(http.StatusOK, res.StatusCode)
+		resJSON := suite.parseJSON(res.Body)
+		suite.Equal(150., resJSON["balance"])
+		suite.Equal(20., resJSON["withdrawn"])
+		suite.Equal("2022-10-01T00:00:00Z", resJSON["as_of"])
+	})
+
+	for _, asOf := range []string{"", "yesterday", "2999-01-01"} {
+		suite.Run("invalid as_of "+asOf, func() {
+			token := suite.validJWTToken(1)
+			res := suite.httpJSONRequest(http.MethodGet, "/balance?as_of="+asOf, "", token)
+			defer res.Body.Close()
+			suite.Equal(http.StatusBadRequest, res.StatusCode)
+			suite.Equal(1111., suite.parseJSON(res.Body)["code"])
+		})
+	}
+
 	suite.Run("non existing user", func() {
+		token := suite.validJWTToken(100)
 		suite.repo.On("UserGetByID", mock.Anything, uint64(100)).
 			Return(nil, errs.ErrNotFound).Once()
 
-		token := suite.validJWTToken(100)
 		res := suite.httpJSONRequest(http.MethodGet, "/balance", "", token)
 		defer res.Body.Close()
 		suite.Equal(http.StatusInternalServerError, res.StatusCode)
@@ -88,7 +133,7 @@ func (suite *handlersSuite) TestBalanceHistoryGet() {
 	}
 
 	suite.Run("success", func() {
-		suite.repo.On("UserBalanceHistoryGetByID", mock.Anything, uint64(1)).
+		suite.repo.On("UserBalanceHistoryGetByID", mock.Anything, uint64(1), mock.Anything).
 			Return(data, nil).Once()
 
 		token := suite.validJWTToken(1)
@@ -104,8 +149,23 @@ func (suite *handlersSuite) TestBalanceHistoryGet() {
 		suite.False(ok)
 	})
 
+	suite.Run("filtered by type", func() {
+		t := models.PromoAccrual
+		to := time.Date(2022, 1, 2, 0, 0, 0, 0, time.UTC)
+		suite.repo.On("UserBalanceHistoryGetByID", mock.Anything, uint64(1), &models.OperationQuery{Type: &t, To: &to}).
+			Return(data[2:], nil).Once()
+
+		token := suite.validJWTToken(1)
+		res := suite.httpJSONRequest(http.MethodGet, "/balance/history?type=promo_accrual&to=2022-01-02T03:00:00%2B03:00", "", token)
+		defer res.Body.Close()
+		suite.Equal(http.StatusOK, res.StatusCode)
+		suite.Empty(res.Header.Get("Link"))
+		resJSON := suite.parseJSONList(res.Body)
+		suite.Equal(1, len(resJSON))
+	})
+
 	suite.Run("empty", func() {
-		suite.repo.On("UserBalanceHistoryGetByID", mock.Anything, uint64(1)).
+		suite.repo.On("UserBalanceHistoryGetByID", mock.Anything, uint64(1), mock.Anything).
 			Return(nil, errs.ErrNotFound).Once()
 
 		token := suite.validJWTToken(1)
@@ -121,7 +181,7 @@ func (suite *handlersSuite) TestBalanceHistoryGet() {
 	})
 
 	suite.Run("internal error", func() {
-		suite.repo.On("UserBalanceHistoryGetByID", mock.Anything, uint64(1)).
+		suite.repo.On("UserBalanceHistoryGetByID", mock.Anything, uint64(1), mock.Anything).
 			Return(nil, errs.ErrInternal).Once()
 
 		token := suite.validJWTToken(1)
@@ -130,3 +190,40 @@ func (suite *handlersSuite) TestBalanceHistoryGet() {
 		suite.Equal(http.StatusInternalServerError, res.StatusCode)
 	})
 }
+
+func (suite *handlersSuite) TestBalanceExpirationsGet() {
+	accruedAt := time.Now().Add(-24 * time.Hour).UTC().Truncate(time.Second)
+
+	suite.Run("success", func() {
+		token := suite.validJWTToken(1)
+		suite.repo.On("UserBalanceHistoryGetByID", mock.Anything, uint64(1), mock.Anything).Return([]*models.Operation{
+			{ID: 1, Type: models.PromoAccrual, Status: models.StatusProcessed, Amount: decimal.NewFromInt(10), CreatedAt: accruedAt, UpdatedAt: accruedAt},
+			{ID: 2, Type: models.OrderAccrual, Status: models.StatusProcessed, Amount: decimal.NewFromInt(100), CreatedAt: accruedAt, UpdatedAt: accruedAt},
+		}, nil).Once()
+
+		res := suite.httpJSONRequest(http.MethodGet, "/balance/expirations", "", token)
+		defer res.Body.Close()
+		suite.Equal(http.StatusOK, res.StatusCode)
+		resJSON := suite.parseJSONList(res.Body)
+		suite.Require().Len(resJSON, 2)
+		suite.Equal(10., resJSON[0]["amount"])
+		suite.Equal("promo_accrual", resJSON[0]["type"])
+		suite.Equal(accruedAt.Add(suite.balance.Expiration.PromoAccrual).Format(time.RFC3339), resJSON[0]["expires_at"])
+		suite.Equal(100., resJSON[1]["amount"])
+	})
+
+	suite.Run("no expirations", func() {
+		token := suite.validJWTToken(1)
+		suite.repo.On("UserBalanceHistoryGetByID", mock.Anything, uint64(1), mock.Anything).Return(nil, errs.ErrNotFound).Once()
+
+		res := suite.httpJSONRequest(http.MethodGet, "/balance/expirations", "", token)
+		defer res.Body.Close()
+		suite.Equal(http.StatusNoContent, res.StatusCode)
+	})
+
+	suite.Run("unauthorized", func() {
+		res := suite.httpJSONRequest(http.MethodGet, "/balance/expirations", "", "invalid token")
+		defer res.Body.Close()
+		suite.Equal(http.StatusUnauthorized, res.StatusCode)
+	})
+}
@@ -32,19 +32,261 @@ func (req *LoginRequest) Bind(_ *http.Request) error {
 
 // LoginResponse - ответ на запрос аутентификации пользователя Handlers.login.
 type LoginResponse struct {
-	AccessToken string `json:"access_token"`
-	TokenType   string `json:"token_type"`
-	ExpiresIn   int64  `json:"expires_in"`
+	AccessToken  string `json:"access_token"`
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int64  `json:"expires_in"`
+	RefreshToken string `json:"refresh_token"`
 }
 
 func (res *LoginResponse) Render(_ http.ResponseWriter, _ *http.Request) error {
 	return nil
 }
 
+// TokenRefreshRequest - запрос на обновление токенов Handlers.tokenRefresh.
+type TokenRefreshRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+func (req *TokenRefreshRequest) Bind(_ *http.Request) error {
+	return nil
+}
+
+// PasswordChangeRequest - запрос на смену пароля Handlers.passwordChange.
+type PasswordChangeRequest struct {
+	OldPassword string `json:"old_password"`
+	NewPassword string `json:"new_password"`
+}
+
+func (req *PasswordChangeRequest) Bind(_ *http.Request) error {
+	return nil
+}
+
+// PasswordResetRequest - запрос на сброс пароля Handlers.passwordReset.
+type PasswordResetRequest struct {
+	Login string `json:"login"`
+}
+
+func (req *PasswordResetRequest) Bind(_ *http.Request) error {
+	return nil
+}
+
+// PasswordResetConfirmRequest - запрос на установку нового пароля Handlers.passwordResetConfirm.
+type PasswordResetConfirmRequest struct {
+	Token    string `json:"token"`
+	Password string `json:"password"`
+}
+
+func (req *PasswordResetConfirmRequest) Bind(_ *http.Request) error {
+	return nil
+}
+
+// UserDeleteRequest - запрос на удаление учетной записи Handlers.userDelete.
+type UserDeleteRequest struct {
+	Password string `json:"password"`
+}
+
+func (req *UserDeleteRequest) Bind(_ *http.Request) error {
+	return nil
+}
+
+// UserRoleRequest - запрос на установку роли пользователя Handlers.adminUserRoleSet.
+type UserRoleRequest struct {
+	Role models.UserRole `json:"role"`
+}
+
+func (req *UserRoleRequest) Bind(_ *http.Request) error {
+	return nil
+}
+
+// UserStatusRequest - запрос на блокировку или разблокировку пользователя
+// Handlers.adminUserBlock и Handlers.adminUserUnblock.
+type UserStatusRequest struct {
+	Reason string `json:"reason"`
+}
+
+func (req *UserStatusRequest) Bind(_ *http.Request) error {
+	return nil
+}
+
+// AdjustmentCreateRequest - запрос на корректировку баланса пользователя Handlers.adminAdjustmentCreate.
+type AdjustmentCreateRequest struct {
+	Amount      decimal.Decimal `json:"amount"`
+	Reason      string          `json:"reason"`
+	Ticket      string          `json:"ticket"`
+	Description string          `json:"description"`
+}
+
+func (req *AdjustmentCreateRequest) Bind(_ *http.Request) error {
+	return nil
+}
+
+// AdjustmentResponse - ответ на запрос корректировки баланса пользователя Handlers.adminAdjustmentCreate.
+type AdjustmentResponse struct {
+	ID          uint64          `json:"id"`
+	Amount      decimal.Decimal `json:"amount"`
+	Description string          `json:"description"`
+	ProcessedAt string          `json:"processed_at"`
+}
+
+func (res *AdjustmentResponse) Render(_ http.ResponseWriter, _ *http.Request) error {
+	return nil
+}
+
+// AccessTokenCreateRequest - запрос на создание персонального токена доступа Handlers.accessTokenCreate.
+type AccessTokenCreateRequest struct {
+	Name      string                    `json:"name"`
+	Scopes    []models.AccessTokenScope `json:"scopes"`
+	ExpiresAt *time.Time                `json:"expires_at,omitempty"`
+}
+
+func (req *AccessTokenCreateRequest) Bind(_ *http.Request) error {
+	return nil
+}
+
+// AccessTokenResponse - персональный токен доступа в ответах Handlers.accessTokenCreate и Handlers.accessTokenList.
+// Сам токен передается только в ответе на создание токена.
+type AccessTokenResponse struct {
+	ID         uint64                    `json:"id"`
+	Name       string                    `json:"name"`
+	Scopes     []models.AccessTokenScope `json:"scopes"`
+	ExpiresAt  *string                   `json:"expires_at"`
+	LastUsedAt *string                   `json:"last_used_at"`
+	CreatedAt  string                    `json:"created_at"`
+	Token      string                    `json:"token,omitempty"`
+}
+
+func (res *AccessTokenResponse) Render(_ http.ResponseWriter, _ *http.Request) error {
+	return nil
+}
+
+func newAccessTokenResponse(t *models.AccessToken, token string) *AccessTokenResponse {
+	res := &AccessTokenResponse{
+		ID:        t.ID,
+		Name:      t.Name,
+		Scopes:    t.Scopes,
+		CreatedAt: t.CreatedAt.Format(timeFmt),
+		Token:     token,
+	}
+	if t.ExpiresAt != nil {
+		s := t.ExpiresAt.Format(timeFmt)
+		res.ExpiresAt = &s
+	}
+	if t.LastUsedAt != nil {
+		s := t.LastUsedAt.Format(timeFmt)
+		res.LastUsedAt = &s
+	}
+	return res
+}
+
+func newAccessTokenListResponse(tokens []*models.AccessToken) []render.Renderer {
+	list := make([]render.Renderer, len(tokens))
+	for i, t := range tokens {
+		list[i] = newAccessTokenResponse(t, "")
+	}
+	return list
+}
+
+// LoginChallengeResponse - ответ на запрос аутентификации Handlers.login,
+// если у пользователя включена двухфакторная аутентификация.
+type LoginChallengeResponse struct {
+	MFARequired bool   `json:"mfa_required"`
+	Challenge   string `json:"challenge"`
+	ExpiresIn   int64  `json:"expires_in"`
+}
+
+func (res *LoginChallengeResponse) Render(_ http.ResponseWriter, _ *http.Request) error {
+	return nil
+}
+
+// LoginTOTPRequest - запрос на завершение входа кодом второго фактора Handlers.loginTOTP.
+type LoginTOTPRequest struct {
+	Challenge string `json:"challenge"`
+	Code      string `json:"code"`
+}
+
+func (req *LoginTOTPRequest) Bind(_ *http.Request) error {
+	return nil
+}
+
+// TOTPEnrollResponse - ответ на запрос подключения двухфакторной аутентификации Handlers.totpEnroll.
+type TOTPEnrollResponse struct {
+	Secret string `json:"secret"`
+	URI    string `json:"uri"`
+}
+
+func (res *TOTPEnrollResponse) Render(_ http.ResponseWriter, _ *http.Request) error {
+	return nil
+}
+
+// TOTPConfirmRequest - запрос на включение двухфакторной аутентификации Handlers.totpConfirm.
+type TOTPConfirmRequest struct {
+	Code string `json:"code"`
+}
+
+func (req *TOTPConfirmRequest) Bind(_ *http.Request) error {
+	return nil
+}
+
+// TOTPConfirmResponse - ответ на запрос включения двухфакторной аутентификации Handlers.totpConfirm.
+type TOTPConfirmResponse struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+func (res *TOTPConfirmResponse) Render(_ http.ResponseWriter, _ *http.Request) error {
+	return nil
+}
+
+// TOTPDisableRequest - запрос на отключение двухфакторной аутентификации Handlers.totpDisable.
+type TOTPDisableRequest struct {
+	Password string `json:"password"`
+}
+
+func (req *TOTPDisableRequest) Bind(_ *http.Request) error {
+	return nil
+}
+
+// DataExportCreateRequest - запрос на выгрузку персональных данных Handlers.dataExportCreate.
+type DataExportCreateRequest struct {
+	Format models.DataExportFormat `json:"format"`
+}
+
+func (req *DataExportCreateRequest) Bind(_ *http.Request) error {
+	return nil
+}
+
+// DataExportResponse - статус выгрузки персональных данных в ответах Handlers.dataExportCreate и Handlers.dataExportGet.
+type DataExportResponse struct {
+	ID        uint64                  `json:"id"`
+	Format    models.DataExportFormat `json:"format"`
+	Status    models.DataExportStatus `json:"status"`
+	CreatedAt string                  `json:"created_at"`
+	ExpiresAt *string                 `json:"expires_at,omitempty"`
+}
+
+func (res *DataExportResponse) Render(_ http.ResponseWriter, _ *http.Request) error {
+	return nil
+}
+
+func newDataExportResponse(e *models.DataExport) *DataExportResponse {
+	res := &DataExportResponse{
+		ID:        e.ID,
+		Format:    e.Format,
+		Status:    e.Status,
+		CreatedAt: e.CreatedAt.Format(timeFmt),
+	}
+	if e.ExpiresAt != nil {
+		expiresAt := e.ExpiresAt.Format(timeFmt)
+		res.ExpiresAt = &expiresAt
+	}
+	return res
+}
+
 // BalanceResponse - ответ на запрос баланса пользователя Handlers.balanceGet.
+// Current - доступные баллы, Pending - удержанные баллы, которые станут доступны после окончания удержания.
 type BalanceResponse struct {
 	Current   decimal.Decimal `json:"current"`
 	Withdrawn decimal.Decimal `json:"withdrawn"`
+	Pending   decimal.Decimal `json:"pending"`
 }
 
 func (b *BalanceResponse) Render(_ http.ResponseWriter, _ *http.Request) error {
@@ -76,14 +318,124 @@ func newBalanceHistoryResponse(ops []*models.Operation) []render.Renderer {
 	return list
 }
 
+// BalanceAsOfResponse - ответ на запрос баланса пользователя на момент времени Handlers.balanceGet.
+// Balance включает удержанные баллы.
+type BalanceAsOfResponse struct {
+	Balance   decimal.Decimal `json:"balance"`
+	Withdrawn decimal.Decimal `json:"withdrawn"`
+	AsOf      string          `json:"as_of"`
+}
+
+func (b *BalanceAsOfResponse) Render(_ http.ResponseWriter, _ *http.Request) error {
+	return nil
+}
+
+func newBalanceAsOfResponse(b *models.BalanceSnapshot) *BalanceAsOfResponse {
+	return &BalanceAsOfResponse{
+		Balance:   b.Balance,
+		Withdrawn: b.Withdrawn,
+		AsOf:      b.AsOf.Format(timeFmt),
+	}
+}
+
+// StatementResponse - ответ на запрос выписки за месяц Handlers.statementGet.
+type StatementResponse struct {
+	Period      string                      `json:"period"`
+	From        string                      `json:"from"`
+	To          string                      `json:"to"`
+	Opening     decimal.Decimal             `json:"opening_balance"`
+	Accruals    decimal.Decimal             `json:"accruals"`
+	Withdrawals decimal.Decimal             `json:"withdrawals"`
+	Expirations decimal.Decimal             `json:"expirations"`
+	Transfers   decimal.Decimal             `json:"transfers"`
+	Adjustments decimal.Decimal             `json:"adjustments"`
+	Closing     decimal.Decimal             `json:"closing_balance"`
+	Postings    []*StatementPostingResponse `json:"postings"`
+}
+
+// StatementPostingResponse - проводка в выписке за месяц с операцией, по которой она записана.
+type StatementPostingResponse struct {
+	Type        models.OperationType `json:"type"`
+	Amount      decimal.Decimal      `json:"amount"` // изменение баланса проводкой
+	OrderNumber *string              `json:"number,omitempty"`
+	Description string               `json:"description"`
+	PostedAt    string               `json:"posted_at"`
+}
+
+func (s *StatementResponse) Render(_ http.ResponseWriter, _ *http.Request) error {
+	return nil
+}
+
+func newStatementResponse(s *models.Statement) *StatementResponse {
+	res := &StatementResponse{
+		Period:      s.From.Format(statementPeriodFmt),
+		From:        s.From.Format(timeFmt),
+		To:          s.To.Format(timeFmt),
+		Opening:     s.Opening,
+		Accruals:    s.Accruals,
+		Withdrawals: s.Withdrawals,
+		Expirations: s.Expirations,
+		Transfers:   s.Transfers,
+		Adjustments: s.Adjustments,
+		Closing:     s.Closing,
+		Postings:    make([]*StatementPostingResponse, len(s.Postings)),
+	}
+	for i, p := range s.Postings {
+		res.Postings[i] = &StatementPostingResponse{
+			Type:        p.Operation.Type,
+			Amount:      p.Amount,
+			OrderNumber: p.Operation.OrderNumber,
+			Description: p.Operation.Description,
+			PostedAt:    p.CreatedAt.Format(timeFmt),
+		}
+	}
+	return res
+}
+
+// BalanceExpirationResponse - ответ на запрос предстоящего сгорания баллов Handlers.balanceExpirationsGet.
+type BalanceExpirationResponse struct {
+	Amount    decimal.Decimal      `json:"amount"`
+	Type      models.OperationType `json:"type"`
+	AccruedAt string               `json:"accrued_at"`
+	ExpiresAt string               `json:"expires_at"`
+}
+
+func (b *BalanceExpirationResponse) Render(_ http.ResponseWriter, _ *http.Request) error {
+	return nil
+}
+
+func newBalanceExpirationsResponse(lots []*models.PointsLot) []render.Renderer {
+	list := make([]render.Renderer, len(lots))
+	for i, l := range lots {
+		list[i] = &BalanceExpirationResponse{
+			Amount:    l.Remaining,
+			Type:      l.Type,
+			AccruedAt: l.AccruedAt.Format(timeFmt),
+			ExpiresAt: l.ExpiresAt.Format(timeFmt),
+		}
+	}
+	return list
+}
+
 // OrderWithdrawalCreateRequest - запрос на создание операции списания бонусов Handlers.orderWithdrawalCreate.
+// OrderTotal - сумма заказа, если она известна, для проверки доли заказа, оплачиваемой баллами.
 type OrderWithdrawalCreateRequest struct {
-	OrderNumber string          `json:"order"`
-	Amount      decimal.Decimal `json:"sum"`
+	OrderNumber string           `json:"order"`
+	Amount      decimal.Decimal  `json:"sum"`
+	OrderTotal  *decimal.Decimal `json:"order_total,omitempty"`
 }
 
 func (o *OrderWithdrawalCreateRequest) Bind(_ *http.Request) error {
-	o.Amount = o.Amount.Neg()
+	return nil
+}
+
+// TransferCreateRequest - запрос на перевод баллов другому пользователю Handlers.transferCreate.
+type TransferCreateRequest struct {
+	Login  string          `json:"login"`
+	Amount decimal.Decimal `json:"sum"`
+}
+
+func (t *TransferCreateRequest) Bind(_ *http.Request) error {
 	return nil
 }
 
@@ -112,6 +464,126 @@ func newOrderAccrualListResponse(ops []*models.Operation) []render.Renderer {
 	return list
 }
 
+// OrderStatusChangeResponse - ответ на запрос истории изменения статуса заказа Handlers.orderStatusHistoryGet.
+type OrderStatusChangeResponse struct {
+	OldStatus models.OperationStatus `json:"old_status"`
+	Status    models.OperationStatus `json:"status"`
+	Amount    decimal.Decimal        `json:"accrual"`
+	Source    string                 `json:"source"`
+	ChangedAt string                 `json:"changed_at"`
+}
+
+func (o *OrderStatusChangeResponse) Render(_ http.ResponseWriter, _ *http.Request) error {
+	return nil
+}
+
+func newOrderStatusHistoryResponse(history []*models.OperationStatusChange) []render.Renderer {
+	list := make([]render.Renderer, len(history))
+	for i, c := range history {
+		list[i] = &OrderStatusChangeResponse{
+			OldStatus: c.OldStatus,
+			Status:    c.NewStatus,
+			Amount:    c.Amount,
+			Source:    c.Source,
+			ChangedAt: c.CreatedAt.Format(timeFmt),
+		}
+	}
+	return list
+}
+
+// OrderAccrualResponse - ответ на запрос начисления бонусов по заказу Handlers.orderAccrualGet.
+type OrderAccrualResponse struct {
+	OrderNumber *string                      `json:"number"`
+	Status      models.OperationStatus       `json:"status"`
+	Amount      decimal.Decimal              `json:"accrual,omitempty"`
+	CreatedAt   string                       `json:"uploaded_at"`
+	CheckedAt   *string                      `json:"checked_at,omitempty"` // время последнего опроса системы расчета начислений
+	HoldUntil   *string                      `json:"hold_until,omitempty"`
+	History     []*OrderStatusChangeResponse `json:"history,omitempty"`
+}
+
+func (o *OrderAccrualResponse) Render(_ http.ResponseWriter, _ *http.Request) error {
+	return nil
+}
+
+func newOrderAccrualResponse(op *models.Operation, history []*models.OperationStatusChange) *OrderAccrualResponse {
+	res := &OrderAccrualResponse{
+		OrderNumber: op.OrderNumber,
+		Status:      op.Status,
+		Amount:      op.Amount,
+		CreatedAt:   op.CreatedAt.Format(timeFmt),
+	}
+	if op.LastCheckedAt != nil {
+		checkedAt := op.LastCheckedAt.Format(timeFmt)
+		res.CheckedAt = &checkedAt
+	}
+	if op.HoldUntil != nil {
+		holdUntil := op.HoldUntil.Format(timeFmt)
+		res.HoldUntil = &holdUntil
+	}
+	for _, c := range history {
+		res.History = append(res.History, &OrderStatusChangeResponse{
+			OldStatus: c.OldStatus,
+			Status:    c.NewStatus,
+			Amount:    c.Amount,
+			Source:    c.Source,
+			ChangedAt: c.CreatedAt.Format(timeFmt),
+		})
+	}
+	return res
+}
+
+// WithdrawalStatusChangeResponse - запись истории изменения статуса списания в ответе Handlers.orderWithdrawalGet.
+type WithdrawalStatusChangeResponse struct {
+	OldStatus models.OperationStatus `json:"old_status"`
+	Status    models.OperationStatus `json:"status"`
+	Source    string                 `json:"source"`
+	ChangedAt string                 `json:"changed_at"`
+}
+
+// OrderWithdrawalResponse - ответ на запрос списания бонусов по заказу Handlers.orderWithdrawalGet.
+type OrderWithdrawalResponse struct {
+	OrderNumber *string                           `json:"order"`
+	Status      models.OperationStatus            `json:"status"`
+	Amount      decimal.Decimal                   `json:"sum"`
+	CreatedAt   string                            `json:"created_at"`
+	ProcessedAt *string                           `json:"processed_at,omitempty"` // только для списаний в конечном статусе
+	History     []*WithdrawalStatusChangeResponse `json:"history,omitempty"`
+}
+
+func (o *OrderWithdrawalResponse) Render(_ http.ResponseWriter, _ *http.Request) error {
+	o.Amount = o.Amount.Neg() // меняем знак
+	return nil
+}
+
+func newOrderWithdrawalResponse(op *models.Operation, history []*models.OperationStatusChange) *OrderWithdrawalResponse {
+	res := &OrderWithdrawalResponse{
+		OrderNumber: op.OrderNumber,
+		Status:      op.Status,
+		Amount:      op.Amount,
+		CreatedAt:   op.CreatedAt.Format(timeFmt),
+		ProcessedAt: processedAt(op),
+	}
+	for _, c := range history {
+		res.History = append(res.History, &WithdrawalStatusChangeResponse{
+			OldStatus: c.OldStatus,
+			Status:    c.NewStatus,
+			Source:    c.Source,
+			ChangedAt: c.CreatedAt.Format(timeFmt),
+		})
+	}
+	return res
+}
+
+// processedAt - возвращает время завершения обработки операции или nil, если операция еще не в конечном статусе.
+func processedAt(op *models.Operation) *string {
+	if !op.Status.Final() {
+		return nil
+	}
+	s := op.UpdatedAt.Format(timeFmt)
+	return &s
+}
+
 // OrderWithdrawalListResponse - ответ на запрос истории списаний бонусов Handlers.orderWithdrawalList.
 type OrderWithdrawalListResponse struct {
 	OrderNumber *string                `json:"order"`
@@ -0,0 +1,137 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+
+	"gophermart-loyalty/internal/errs"
+	"gophermart-loyalty/internal/middleware"
+	"gophermart-loyalty/internal/models"
+)
+
+// dataExportCreate - запрос выгрузки персональных данных.
+// Выгрузка формируется в фоне, ее статус и файл доступны по адресу из заголовка Location.
+// Тело запроса необязательно, формат по умолчанию - json.
+// Формат запроса:
+//    POST /api/user/export HTTP/1.1
+//    Content-Type: application/json
+//    Authorization: Bearer <token>
+//
+//    {
+//        "format": "zip"
+//    }
+//
+// Возможные коды ответа:
+//    202 — выгрузка поставлена в очередь
+//    400 — неверный формат запроса или неподдерживаемый формат выгрузки
+//    401 — пользователь не авторизован
+//    403 — запрос выполнен с персональным токеном
+//    409 — предыдущая выгрузка пользователя еще формируется
+//    500 — внутренняя ошибка сервера
+//
+// Формат ответа:
+//    HTTP/1.1 202 Accepted
+//    Content-Type: application/json
+//    Location: /api/user/export/1
+//
+//    {
+//        "id": 1,
+//        "format": "zip",
+//        "status": "NEW",
+//        "created_at": "2021-01-01T12:00:00+03:00"
+//    }
+func (h *Handlers) dataExportCreate(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		_ = render.Render(w, r, errs.ErrResponseUnauthorized)
+		return
+	}
+
+	// Тело запроса необязательно
+	data := &DataExportCreateRequest{}
+	if r.ContentLength != 0 {
+		if err := render.Bind(r, data); err != nil && !errors.Is(err, io.EOF) {
+			_ = render.Render(w, r, errs.ErrResponseBadRequest)
+			return
+		}
+	}
+
+	e, err := h.useCases.DataExportCreate(r.Context(), userID, data.Format)
+	if err != nil {
+		_ = render.Render(w, r, errs.NewErrResponse(err))
+		return
+	}
+
+	w.Header().Set("Location", fmt.Sprintf("/api/user/export/%d", e.ID))
+	render.Status(r, http.StatusAccepted)
+	_ = render.Render(w, r, newDataExportResponse(e))
+}
+
+// dataExportGet - получение статуса и файла выгрузки персональных данных.
+// Пока выгрузка формируется, возвращается ее статус. Готовая выгрузка возвращается файлом.
+// Формат запроса:
+//    GET /api/user/export/{id} HTTP/1.1
+//    Content-Length: 0
+//    Authorization: Bearer <token>
+//
+// Возможные коды ответа:
+//    200 — файл выгрузки или статус FAILED, если выгрузку сформировать не удалось
+//    202 — выгрузка еще формируется
+//    400 — неверный формат запроса
+//    401 — пользователь не авторизован
+//    403 — запрос выполнен с персональным токеном
+//    404 — выгрузка не найдена или истекло время ее хранения
+//    500 — внутренняя ошибка сервера
+//
+// Формат ответа для готовой выгрузки:
+//    HTTP/1.1 200 OK
+//    Content-Type: application/zip
+//    Content-Disposition: attachment; filename="gophermart-export-1.zip"
+//
+//    <содержимое файла>
+//
+// Формат ответа для остальных статусов совпадает с ответом POST /api/user/export.
+func (h *Handlers) dataExportGet(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		_ = render.Render(w, r, errs.ErrResponseUnauthorized)
+		return
+	}
+
+	exportID, err := strconv.ParseUint(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		_ = render.Render(w, r, errs.ErrResponseBadRequest)
+		return
+	}
+
+	e, err := h.useCases.DataExportGet(r.Context(), userID, exportID)
+	if err != nil {
+		_ = render.Render(w, r, errs.NewErrResponse(err))
+		return
+	}
+
+	switch e.Status {
+	case models.DataExportNew:
+		render.Status(r, http.StatusAccepted)
+		_ = render.Render(w, r, newDataExportResponse(e))
+	case models.DataExportReady:
+		contentType := "application/json"
+		if e.Format == models.DataExportZIP {
+			contentType = "application/zip"
+		}
+		w.Header().Set("Content-Type", contentType)
+		w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="gophermart-export-%d.%s"`, e.ID, e.Format))
+		w.Header().Set("Content-Length", strconv.Itoa(len(e.Payload)))
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write(e.Payload)
+	default:
+		render.Status(r, http.StatusOK)
+		_ = render.Render(w, r, newDataExportResponse(e))
+	}
+}
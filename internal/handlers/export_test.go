@@ -0,0 +1,136 @@
+package handlers
+
+import (
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+
+	"gophermart-loyalty/internal/errs"
+	"gophermart-loyalty/internal/models"
+)
+
+func (suite *handlersSuite) TestDataExportCreate() {
+	suite.Run("success", func() {
+		token := suite.validJWTToken(1)
+		suite.repo.On("DataExportCreate", mock.Anything, mock.MatchedBy(func(e *models.DataExport) bool {
+			return e.UserID == 1 && e.Format == models.DataExportZIP
+		})).Run(func(args mock.Arguments) {
+			e := args.Get(1).(*models.DataExport)
+			e.ID = 7
+			e.Status = models.DataExportNew
+		}).Return(nil).Once()
+
+		res := suite.httpJSONRequest(http.MethodPost, "/export", `{"format":"zip"}`, token)
+		defer res.Body.Close()
+		suite.Equal(http.StatusAccepted, res.StatusCode)
+		suite.Equal("/api/user/export/7", res.Header.Get("Location"))
+		resJSON := suite.parseJSON(res.Body)
+		suite.Equal("NEW", resJSON["status"])
+	})
+
+	suite.Run("empty body", func() {
+		token := suite.validJWTToken(1)
+		suite.repo.On("DataExportCreate", mock.Anything, mock.MatchedBy(func(e *models.DataExport) bool {
+			return e.Format == models.DataExportJSON
+		})).Return(nil).Once()
+
+		res := suite.httpRequest(http.MethodPost, "/export", "", "", token)
+		defer res.Body.Close()
+		suite.Equal(http.StatusAccepted, res.StatusCode)
+	})
+
+	suite.Run("invalid format", func() {
+		token := suite.validJWTToken(1)
+		res := suite.httpJSONRequest(http.MethodPost, "/export", `{"format":"xml"}`, token)
+		defer res.Body.Close()
+		suite.Equal(http.StatusBadRequest, res.StatusCode)
+		resJSON := suite.parseJSON(res.Body)
+		suite.Equal(1108., resJSON["code"])
+	})
+
+	suite.Run("in progress", func() {
+		token := suite.validJWTToken(1)
+		suite.repo.On("DataExportCreate", mock.Anything, mock.Anything).Return(errs.ErrDataExportInProgress).Once()
+
+		res := suite.httpJSONRequest(http.MethodPost, "/export", `{}`, token)
+		defer res.Body.Close()
+		suite.Equal(http.StatusConflict, res.StatusCode)
+		resJSON := suite.parseJSON(res.Body)
+		suite.Equal(1109., resJSON["code"])
+	})
+
+	suite.Run("access token can not export", func() {
+		token := suite.validAccessToken(1, models.ScopeBalanceRead)
+		res := suite.httpJSONRequest(http.MethodPost, "/export", `{}`, token)
+		defer res.Body.Close()
+		suite.Equal(http.StatusForbidden, res.StatusCode)
+	})
+}
+
+func (suite *handlersSuite) TestDataExportGet() {
+	expiresAt := time.Now().Add(time.Hour)
+
+	suite.Run("in progress", func() {
+		token := suite.validJWTToken(1)
+		suite.repo.On("DataExportGet", mock.Anything, uint64(1), uint64(7)).
+			Return(&models.DataExport{ID: 7, UserID: 1, Format: models.DataExportJSON, Status: models.DataExportNew}, nil).Once()
+
+		res := suite.httpRequest(http.MethodGet, "/export/7", "", "", token)
+		defer res.Body.Close()
+		suite.Equal(http.StatusAccepted, res.StatusCode)
+		resJSON := suite.parseJSON(res.Body)
+		suite.Equal("NEW", resJSON["status"])
+	})
+
+	suite.Run("ready", func() {
+		token := suite.validJWTToken(1)
+		suite.repo.On("DataExportGet", mock.Anything, uint64(1), uint64(7)).
+			Return(&models.DataExport{
+				ID:        7,
+				UserID:    1,
+				Format:    models.DataExportZIP,
+				Status:    models.DataExportReady,
+				Payload:   []byte("PK"),
+				ExpiresAt: &expiresAt,
+			}, nil).Once()
+
+		res := suite.httpRequest(http.MethodGet, "/export/7", "", "", token)
+		defer res.Body.Close()
+		suite.Equal(http.StatusOK, res.StatusCode)
+		suite.Equal("application/zip", res.Header.Get("Content-Type"))
+		suite.Equal(`attachment; filename="gophermart-export-7.zip"`, res.Header.Get("Content-Disposition"))
+		body, err := io.ReadAll(res.Body)
+		suite.NoError(err)
+		suite.Equal("PK", string(body))
+	})
+
+	suite.Run("failed", func() {
+		token := suite.validJWTToken(1)
+		suite.repo.On("DataExportGet", mock.Anything, uint64(1), uint64(7)).
+			Return(&models.DataExport{ID: 7, UserID: 1, Format: models.DataExportJSON, Status: models.DataExportFailed, ExpiresAt: &expiresAt}, nil).Once()
+
+		res := suite.httpRequest(http.MethodGet, "/export/7", "", "", token)
+		defer res.Body.Close()
+		suite.Equal(http.StatusOK, res.StatusCode)
+		resJSON := suite.parseJSON(res.Body)
+		suite.Equal("FAILED", resJSON["status"])
+	})
+
+	suite.Run("not found", func() {
+		token := suite.validJWTToken(1)
+		suite.repo.On("DataExportGet", mock.Anything, uint64(1), uint64(8)).Return(nil, errs.ErrNotFound).Once()
+
+		res := suite.httpRequest(http.MethodGet, "/export/8", "", "", token)
+		defer res.Body.Close()
+		suite.Equal(http.StatusNotFound, res.StatusCode)
+	})
+
+	suite.Run("invalid id", func() {
+		token := suite.validJWTToken(1)
+		res := suite.httpRequest(http.MethodGet, "/export/abc", "", "", token)
+		defer res.Body.Close()
+		suite.Equal(http.StatusBadRequest, res.StatusCode)
+	})
+}
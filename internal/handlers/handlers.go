@@ -7,8 +7,10 @@ import (
 	"github.com/shopspring/decimal"
 
 	"gophermart-loyalty/internal/config"
+	"gophermart-loyalty/internal/jwks"
 	"gophermart-loyalty/internal/logger"
 	"gophermart-loyalty/internal/middleware"
+	"gophermart-loyalty/internal/models"
 	"gophermart-loyalty/internal/usecases"
 )
 
@@ -20,16 +22,22 @@ func init() {
 
 // Handlers - HTTP-хандлеры для API
 type Handlers struct {
-	cfg      *config.Auth
-	log      logger.Log
-	useCases *usecases.UseCases
+	cfg         *config.Auth
+	balance     *config.Balance
+	idempotency *config.Idempotency
+	keys        *jwks.KeySet
+	log         logger.Log
+	useCases    *usecases.UseCases
 }
 
-func NewHandlers(c *config.Auth, u *usecases.UseCases, log logger.Log) *Handlers {
+func NewHandlers(c *config.Auth, b *config.Balance, i *config.Idempotency, keys *jwks.KeySet, u *usecases.UseCases, log logger.Log) *Handlers {
 	return &Handlers{
-		useCases: u,
-		cfg:      c,
-		log:      log,
+		useCases:    u,
+		cfg:         c,
+		balance:     b,
+		idempotency: i,
+		keys:        keys,
+		log:         log,
 	}
 }
 
@@ -37,18 +45,76 @@ func (h *Handlers) InitRoutes() chi.Router {
 	r := chi.NewRouter()
 	r.Post("/register", h.register)
 	r.Post("/login", h.login)
+	r.Post("/login/2fa", h.loginTOTP)
+	r.Post("/token/refresh", h.tokenRefresh)
+	r.Post("/password/reset", h.passwordReset)
+	r.Post("/password/reset/confirm", h.passwordResetConfirm)
+
+	// Изменяющие запросы авторизованных пользователей можно повторять с заголовком Idempotency-Key.
+	// Запросы, ответ на которые содержит секреты (токены доступа, секрет TOTP, коды восстановления),
+	// не записываются.
+	idempotent := middleware.Idempotency(h.useCases, h.idempotency.TTL)
 
 	// Доступны только авторизованным пользователям
 	r.Group(func(r chi.Router) {
-		r.Use(middleware.Auth(h.cfg.SigningAlg, h.cfg.SigningKey))
-		r.Post("/orders", h.orderAccrualCreate)
-		r.Get("/orders", h.orderAccrualList)
-		r.Post("/balance/withdraw", h.orderWithdrawalCreate)
-		r.Get("/withdrawals", h.orderWithdrawalList)
-		r.Post("/promos", h.promoAccrualCreate)
-		r.Get("/balance", h.balanceGet)
-		r.Get("/balance/history", h.balanceHistoryGet)
+		r.Use(middleware.Auth(h.keys.Keyfunc, h.useCases))
+		r.With(middleware.RequireScope(models.ScopeOrdersWrite), idempotent).Post("/orders", h.orderAccrualCreate)
+		r.With(middleware.RequireScope(models.ScopeOrdersRead)).Get("/orders", h.orderAccrualList)
+		r.With(middleware.RequireScope(models.ScopeOrdersRead)).Get("/orders/{number}", h.orderAccrualGet)
+		r.With(middleware.RequireScope(models.ScopeOrdersRead)).Get("/orders/{number}/history", h.orderStatusHistoryGet)
+		r.With(middleware.RequireScope(models.ScopeWithdrawalsWrite), idempotent).Post("/balance/withdraw", h.orderWithdrawalCreate)
+		r.With(middleware.RequireScope(models.ScopeTransfersWrite), idempotent).Post("/balance/transfer", h.transferCreate)
+		r.With(middleware.RequireScope(models.ScopeWithdrawalsRead)).Get("/withdrawals", h.orderWithdrawalList)
+		r.With(middleware.RequireScope(models.ScopeWithdrawalsRead)).Get("/withdrawals/{order}", h.orderWithdrawalGet)
+		r.With(middleware.RequireScope(models.ScopeWithdrawalsWrite)).Delete("/withdrawals/{order}", h.orderWithdrawalCancel)
+		r.With(middleware.RequireScope(models.ScopePromosWrite), idempotent).Post("/promos", h.promoAccrualCreate)
+		r.With(middleware.RequireScope(models.ScopeBalanceRead)).Get("/balance", h.balanceGet)
+		r.With(middleware.RequireScope(models.ScopeBalanceRead)).Get("/balance/history", h.balanceHistoryGet)
+		r.With(middleware.RequireScope(models.ScopeBalanceRead)).Get("/balance/expirations", h.balanceExpirationsGet)
+		r.With(middleware.RequireScope(models.ScopeBalanceRead)).Get("/statements/{month}", h.statementGet)
+
+		// Доступны только с токеном сессии, но не с персональным токеном доступа
+		r.Group(func(r chi.Router) {
+			r.Use(middleware.RequireSession)
+			r.Delete("/", h.userDelete)
+			r.With(idempotent).Post("/logout", h.logout)
+			r.With(idempotent).Post("/sessions/revoke-all", h.sessionsRevokeAll)
+			r.With(idempotent).Post("/password", h.passwordChange)
+			r.With(idempotent).Post("/tokens", h.accessTokenCreate)
+			r.Get("/tokens", h.accessTokenList)
+			r.Delete("/tokens/{id}", h.accessTokenRevoke)
+			r.With(idempotent).Post("/2fa/totp", h.totpEnroll)
+			r.With(idempotent).Post("/2fa/totp/confirm", h.totpConfirm)
+			r.Delete("/2fa/totp", h.totpDisable)
+			r.With(idempotent).Post("/export", h.dataExportCreate)
+			r.Get("/export/{id}", h.dataExportGet)
+		})
+	})
+
+	return r
+}
+
+// InitAdminRoutes - маршруты для сотрудников
+func (h *Handlers) InitAdminRoutes() chi.Router {
+	r := chi.NewRouter()
+	r.Use(middleware.Auth(h.keys.Keyfunc, h.useCases))
+	idempotent := middleware.Idempotency(h.useCases, h.idempotency.TTL)
+
+	// Доступны только администраторам
+	r.Group(func(r chi.Router) {
+		r.Use(middleware.RequireRole(models.RoleAdmin))
+		r.Put("/users/{id}/role", h.adminUserRoleSet)
+		r.Post("/users/{id}/block", h.adminUserBlock)
+		r.Post("/users/{id}/unblock", h.adminUserUnblock)
+		r.With(idempotent).Post("/users/{id}/adjustments", h.adminAdjustmentCreate)
 	})
 
 	return r
 }
+
+// InitWellKnownRoutes - маршруты, публикуемые в /.well-known
+func (h *Handlers) InitWellKnownRoutes() chi.Router {
+	r := chi.NewRouter()
+	r.Get("/jwks.json", h.jwks)
+	return r
+}
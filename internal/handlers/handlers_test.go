@@ -2,22 +2,30 @@ package handlers
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
 	"net/http/httptest"
+	"strconv"
 	"testing"
 	"time"
 
 	"github.com/golang-jwt/jwt/v4"
 	"github.com/rs/zerolog"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/suite"
 	"golang.org/x/crypto/bcrypt"
 
 	"gophermart-loyalty/internal/config"
+	"gophermart-loyalty/internal/jwks"
 	"gophermart-loyalty/internal/logger"
+	"gophermart-loyalty/internal/middleware"
 	"gophermart-loyalty/internal/mocks"
+	"gophermart-loyalty/internal/models"
+	"gophermart-loyalty/internal/passhash"
 	"gophermart-loyalty/internal/usecases"
 )
 
@@ -27,28 +35,79 @@ func TestHandlersSuite(t *testing.T) {
 
 type handlersSuite struct {
 	suite.Suite
-	log        logger.Log
-	repo       *mocks.Repo
-	useCases   *usecases.UseCases
-	handlers   *Handlers
-	cfg        *config.Auth
-	testServer *httptest.Server
+	log         logger.Log
+	repo        *mocks.Repo
+	sender      *mocks.Sender
+	useCases    *usecases.UseCases
+	handlers    *Handlers
+	cfg         *config.Auth
+	balance     *config.Balance
+	idempotency *config.Idempotency
+	testServer  *httptest.Server
 }
 
 func (suite *handlersSuite) SetupSuite() {
 	suite.log = logger.NewLogger(zerolog.DebugLevel)
 	suite.cfg = &config.Auth{
-		SigningAlg: "HS256",
-		TTL:        60 * time.Second,
-		SigningKey: "test123456789012345678901234567890",
+		SigningAlg:   "HS256",
+		TTL:          60 * time.Second,
+		RefreshTTL:   3600 * time.Second,
+		ResetTTL:     600 * time.Second,
+		ChallengeTTL: 300 * time.Second,
+		SigningKey:   "test123456789012345678901234567890",
+		SigningKeyID: "test",
+		TOTPIssuer:   "Gophermart",
+		LoginReuse:   24 * time.Hour,
+		Throttle: config.LoginThrottle{
+			FreeAttempts:  3,
+			BaseDelay:     time.Second,
+			MaxDelay:      30 * time.Second,
+			MaxFailures:   10,
+			IPMaxFailures: 100,
+			Lockout:       15 * time.Minute,
+			Window:        15 * time.Minute,
+		},
 	}
+	suite.balance = &config.Balance{
+		Expiration: config.Expiration{
+			OrderAccrual: 365 * 24 * time.Hour,
+			PromoAccrual: 90 * 24 * time.Hour,
+		},
+		Transfer: config.Transfer{
+			DailyAmount: decimal.NewFromInt(1000),
+			DailyCount:  10,
+		},
+		Withdrawal: config.Withdrawal{
+			MinAmount:     decimal.NewFromInt(10),
+			MaxOrderShare: decimal.RequireFromString("0.5"),
+			Precision:     2,
+		},
+	}
+	suite.idempotency = &config.Idempotency{TTL: 24 * time.Hour}
 }
 
 func (suite *handlersSuite) SetupTest() {
 	suite.repo = mocks.NewRepo(suite.T())
-	suite.useCases = usecases.NewUseCases(suite.repo, suite.log)
-	suite.handlers = NewHandlers(suite.cfg, suite.useCases, suite.log)
+	// Сессии, на которые ссылаются токены validJWTToken, всегда активны
+	suite.repo.On("SessionGetByID", mock.Anything, mock.Anything).
+		Return(func(_ context.Context, id uint64) *models.Session {
+			return &models.Session{ID: id, UserID: id}
+		}, nil).Maybe()
+	suite.sender = mocks.NewSender(suite.T())
+	hasher, err := passhash.New(&config.Password{
+		Alg:           passhash.AlgBcrypt,
+		BcryptCost:    bcrypt.DefaultCost,
+		Argon2Time:    1,
+		Argon2Memory:  64,
+		Argon2Threads: 1,
+	})
+	suite.Require().NoError(err)
+	suite.useCases = usecases.NewUseCases(suite.repo, hasher, suite.sender, suite.log)
+	keys, err := jwks.NewKeySet(suite.cfg)
+	suite.Require().NoError(err)
+	suite.handlers = NewHandlers(suite.cfg, suite.balance, suite.idempotency, keys, suite.useCases, suite.log)
 	r := suite.handlers.InitRoutes()
+	r.Mount("/admin", suite.handlers.InitAdminRoutes())
 
 	suite.testServer = httptest.NewServer(r)
 }
@@ -74,6 +133,18 @@ func (suite *handlersSuite) httpJSONRequest(method, url, body, token string) *ht
 	return suite.httpRequest(method, url, "application/json", body, token)
 }
 
+func (suite *handlersSuite) httpIdempotentJSONRequest(method, url, body, token, key string) *http.Response {
+	url = fmt.Sprintf("%s%s", suite.testServer.URL, url)
+	req, err := http.NewRequest(method, url, bytes.NewBuffer([]byte(body)))
+	suite.NoError(err)
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(middleware.IdempotencyKeyHeader, key)
+	res, err := http.DefaultClient.Do(req)
+	suite.NoError(err)
+	return res
+}
+
 func (suite *handlersSuite) httpPlainTextRequest(method, url, body, token string) *http.Response {
 	return suite.httpRequest(method, url, "text/plain", body, token)
 }
@@ -97,16 +168,57 @@ func (suite *handlersSuite) getBody(body io.Reader) []byte {
 	return buf.Bytes()
 }
 
+// validJWTToken - возвращает валидный токен пользователя userID.
+// id сессии в токене совпадает с id пользователя.
+// Регистрирует однократный вызов UserGetByID для проверки токена в middleware.Auth,
+// поэтому токен должен быть получен до регистрации вызовов UserGetByID в тесте.
 func (suite *handlersSuite) validJWTToken(userID uint64) string {
+	return suite.validJWTTokenWithRole(userID, models.RoleUser)
+}
+
+// validJWTTokenWithRole - возвращает валидный токен пользователя userID с ролью role.
+// Аналогичен validJWTToken.
+func (suite *handlersSuite) validJWTTokenWithRole(userID uint64, role models.UserRole) string {
+	suite.repo.On("UserGetByID", mock.Anything, userID).
+		Return(&models.User{ID: userID, Role: role}, nil).Once()
+
 	claims := jwt.MapClaims{
-		"sub": userID,
-		"iat": time.Now().Unix(),
-		"nbf": time.Now().Unix(),
-		"exp": time.Now().Add(1 * time.Hour).Unix(),
+		"sub":  userID,
+		"jti":  strconv.FormatUint(userID, 10),
+		"iat":  time.Now().Unix(),
+		"nbf":  time.Now().Unix(),
+		"exp":  time.Now().Add(1 * time.Hour).Unix(),
+		"role": role,
 	}
 	return suite.generateJWTToken(claims, suite.handlers.cfg.SigningAlg, suite.handlers.cfg.SigningKey)
 }
 
+// loginAttempt - регистрирует однократные вызовы учета попытки входа по логину login с адреса 127.0.0.1
+// с числом попыток failures. Если released, попытка не оказывается неудачной и исключается из счетчиков.
+func (suite *handlersSuite) loginAttempt(login string, failures int, released bool) {
+	for _, key := range []string{"login:" + login, "ip:127.0.0.1"} {
+		suite.repo.On("LoginAttemptRegister", mock.Anything, key, int64(900)).
+			Return(&models.LoginFailure{Key: key, Failures: failures, LastFailedAt: time.Now()}, nil).Once()
+		if released {
+			suite.repo.On("LoginAttemptRelease", mock.Anything, key).Return(nil).Once()
+		}
+	}
+}
+
+// loginAttemptBlocked - регистрирует однократный вызов учета попытки входа по заблокированному ключу key.
+func (suite *handlersSuite) loginAttemptBlocked(key string, failures int) {
+	suite.repo.On("LoginAttemptRegister", mock.Anything, key, int64(900)).
+		Return(&models.LoginFailure{Key: key, Failures: failures, BlockedUntil: time.Now().Add(time.Hour)}, nil).Once()
+}
+
+// validAccessToken - возвращает валидный персональный токен пользователя userID с областями доступа scopes.
+// Регистрирует однократный вызов AccessTokenUse для проверки токена в middleware.Auth.
+func (suite *handlersSuite) validAccessToken(userID uint64, scopes ...models.AccessTokenScope) string {
+	suite.repo.On("AccessTokenUse", mock.Anything, mock.Anything).
+		Return(&models.AccessToken{ID: 1, UserID: userID, Scopes: scopes}, nil).Once()
+	return models.AccessTokenPrefix + "test"
+}
+
 func (suite *handlersSuite) generateJWTToken(claims jwt.Claims, alg, key string) string {
 	signingMethod := jwt.GetSigningMethod(alg)
 	suite.Require().NotNil(signingMethod)
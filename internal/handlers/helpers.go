@@ -1,12 +1,23 @@
 package handlers
 
 import (
+	"fmt"
 	"io/ioutil"
 	"net/http"
+	"strconv"
+	"time"
 
 	"github.com/go-chi/render"
 
 	"gophermart-loyalty/internal/errs"
+	"gophermart-loyalty/internal/models"
+)
+
+const (
+	// listMaxLimit - максимальное число элементов на странице списка
+	listMaxLimit = 1000
+	// nextCursorHeader - заголовок ответа с курсором следующей страницы списка
+	nextCursorHeader = "X-Next-Cursor"
 )
 
 func decodePlainText(r *http.Request) (string, error) {
@@ -20,3 +31,79 @@ func decodePlainText(r *http.Request) (string, error) {
 	}
 	return string(data), nil
 }
+
+// decodeOperationQuery - разбирает параметры выборки списка операций из строки запроса:
+//    limit    - число операций на странице, от 1 до listMaxLimit, без него возвращаются все операции
+//    cursor   - курсор следующей страницы из заголовка X-Next-Cursor предыдущего ответа
+//    status   - статус операции
+//    type     - тип операции, только если withType
+//    from, to - начало (включительно) и конец (не включая) периода в формате RFC3339 или YYYY-MM-DD
+// При неверных параметрах возвращает errs.ErrListQueryInvalid.
+func decodeOperationQuery(r *http.Request, withType bool) (*models.OperationQuery, error) {
+	values := r.URL.Query()
+	q := &models.OperationQuery{}
+	var err error
+
+	if v := values.Get("limit"); v != "" {
+		if q.Limit, err = strconv.Atoi(v); err != nil || q.Limit < 1 || q.Limit > listMaxLimit {
+			return nil, errs.ErrListQueryInvalid
+		}
+	}
+	if v := values.Get("cursor"); v != "" {
+		if q.After, err = models.ParseOperationCursor(v); err != nil {
+			return nil, errs.ErrListQueryInvalid
+		}
+	}
+	if v := values.Get("status"); v != "" {
+		status := models.OperationStatus(v)
+		if !status.Valid() {
+			return nil, errs.ErrListQueryInvalid
+		}
+		q.Status = &status
+	}
+	if v := values.Get("type"); v != "" {
+		t := models.OperationType(v)
+		if !withType || !t.Valid() {
+			return nil, errs.ErrListQueryInvalid
+		}
+		q.Type = &t
+	}
+	if q.From, err = decodeQueryTime(values.Get("from")); err != nil {
+		return nil, err
+	}
+	if q.To, err = decodeQueryTime(values.Get("to")); err != nil {
+		return nil, err
+	}
+	if q.From != nil && q.To != nil && !q.From.Before(*q.To) {
+		return nil, errs.ErrListQueryInvalid
+	}
+	return q, nil
+}
+
+// decodeQueryTime - разбирает время в формате RFC3339 или дату в формате YYYY-MM-DD (полночь UTC).
+// Для пустой строки возвращает nil.
+func decodeQueryTime(v string) (*time.Time, error) {
+	if v == "" {
+		return nil, nil
+	}
+	for _, layout := range []string{time.RFC3339, "2006-01-02"} {
+		if t, err := time.Parse(layout, v); err == nil {
+			t = t.UTC()
+			return &t, nil
+		}
+	}
+	return nil, errs.ErrListQueryInvalid
+}
+
+// setNextPage - добавляет в ответ курсор и ссылку на следующую страницу списка, если она есть.
+// Тело ответа не меняется, поэтому клиенты, не использующие постраничную выборку, получают прежний массив.
+func setNextPage(w http.ResponseWriter, r *http.Request, next *models.OperationCursor) {
+	if next == nil {
+		return
+	}
+	cursor := next.String()
+	values := r.URL.Query()
+	values.Set("cursor", cursor)
+	w.Header().Set(nextCursorHeader, cursor)
+	w.Header().Set("Link", fmt.Sprintf(`<%s?%s>; rel="next"`, r.URL.Path, values.Encode()))
+}
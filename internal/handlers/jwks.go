@@ -0,0 +1,38 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/go-chi/render"
+)
+
+// jwks - публичные ключи для проверки подписи авторизационных токенов.
+// Ключ, которым подписан токен, определяется по заголовку "kid" токена.
+// При подписи токенов симметричным ключом (HS256, HS384, HS512) список ключей пуст.
+// Формат запроса:
+//    GET /.well-known/jwks.json HTTP/1.1
+//
+// Возможные коды ответа:
+//    200 — успешная обработка запроса
+//
+// Формат ответа:
+//    HTTP/1.1 200 OK
+//    Content-Type: application/json
+//
+//    {
+//        "keys": [
+//            {
+//                "kty": "EC",
+//                "kid": "<key id>",
+//                "use": "sig",
+//                "alg": "ES256",
+//                "crv": "P-256",
+//                "x": "<x>",
+//                "y": "<y>"
+//            }
+//        ]
+//    }
+func (h *Handlers) jwks(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	render.JSON(w, r, h.keys.JWKS())
+}
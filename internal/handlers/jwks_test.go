@@ -0,0 +1,21 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+)
+
+func (suite *handlersSuite) TestJWKS() {
+	suite.Run("symmetric keys are not published", func() {
+		req := httptest.NewRequest(http.MethodGet, "/jwks.json", nil)
+		rec := httptest.NewRecorder()
+		suite.handlers.InitWellKnownRoutes().ServeHTTP(rec, req)
+
+		suite.Equal(http.StatusOK, rec.Code)
+		var resJSON map[string][]interface{}
+		suite.NoError(json.Unmarshal(rec.Body.Bytes(), &resJSON))
+		suite.Contains(resJSON, "keys")
+		suite.Empty(resJSON["keys"])
+	})
+}
@@ -1,13 +1,15 @@
 package handlers
 
 import (
-	"fmt"
+	"net"
 	"net/http"
+	"strconv"
 
 	"github.com/go-chi/render"
 	"github.com/golang-jwt/jwt/v4"
 
 	"gophermart-loyalty/internal/errs"
+	"gophermart-loyalty/internal/models"
 )
 
 // login - аутентификация пользователя.
@@ -20,10 +22,17 @@ import (
 //        "password": "<password>"
 //    }
 //
+// Если у пользователя включена двухфакторная аутентификация, токены не выдаются.
+// Вместо них возвращается токен незавершенного входа, который вместе с кодом
+// второго фактора передается в POST /api/user/login/2fa.
+//
 // Возможные коды ответа:
 //    200 — пользователь успешно аутентифицирован
+//    202 — пароль верный, требуется код второго фактора
 //    400 — неверный формат запроса
 //    401 — неверная пара логин/пароль
+//    423 — учетная запись временно заблокирована после неудачных попыток входа
+//    429 — слишком много неудачных попыток входа, попытку нужно повторить позже
 //    500 — внутренняя ошибка сервера
 //
 // Формат ответа:
@@ -33,7 +42,17 @@ import (
 //    {
 //        "access_token": "<token>",
 //        "token_type": "Bearer",
-//        "expires_in": 3600
+//        "expires_in": 900,
+//        "refresh_token": "<refresh token>"
+//    }
+//
+//    HTTP/1.1 202 Accepted
+//    Content-Type: application/json
+//
+//    {
+//        "mfa_required": true,
+//        "challenge": "<challenge token>",
+//        "expires_in": 300
 //    }
 func (h *Handlers) login(w http.ResponseWriter, r *http.Request) {
 	data := &LoginRequest{}
@@ -43,16 +62,66 @@ func (h *Handlers) login(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Ищем пользователя по логину и паролю
-	user, err := h.useCases.UserCheckLoginPass(r.Context(), data.Login, data.Password)
+	// Ищем пользователя по логину и паролю с учетом неудачных попыток входа
+	user, mfa, err := h.useCases.UserLogin(r.Context(), data.Login, data.Password, clientIP(r), &h.cfg.Throttle)
 	if err != nil {
 		h.log.Debug().Err(err).Msg("failed to check login and password")
 		_ = render.Render(w, r, errs.NewErrResponse(err))
 		return
 	}
 
+	// Если включена двухфакторная аутентификация, то вход завершается после ввода кода
+	if mfa {
+		h.renderLoginChallenge(w, r, user)
+		return
+	}
+
+	h.createSession(w, r, user)
+}
+
+// createSession - создает сессию пользователя user и отправляет токены в ответе
+func (h *Handlers) createSession(w http.ResponseWriter, r *http.Request, user *models.User) {
+	session, refreshToken, err := h.useCases.SessionCreate(r.Context(), user.ID, h.cfg.RefreshTTL)
+	if err != nil {
+		_ = render.Render(w, r, errs.NewErrResponse(err))
+		return
+	}
+
+	// Отправляем токены в ответе
+	h.renderLoginResponse(w, r, user, session, refreshToken)
+}
+
+// renderLoginChallenge - создает незавершенный вход пользователя user и отправляет его токен в ответе
+func (h *Handlers) renderLoginChallenge(w http.ResponseWriter, r *http.Request, user *models.User) {
+	_, challenge, err := h.useCases.LoginChallengeCreate(r.Context(), user.ID, h.cfg.ChallengeTTL)
+	if err != nil {
+		_ = render.Render(w, r, errs.NewErrResponse(err))
+		return
+	}
+
+	render.Status(r, http.StatusAccepted)
+	_ = render.Render(w, r, &LoginChallengeResponse{
+		MFARequired: true,
+		Challenge:   challenge,
+		ExpiresIn:   int64(h.cfg.ChallengeTTL.Seconds()),
+	})
+}
+
+// clientIP - возвращает IP-адрес клиента.
+// Адрес из заголовков X-Real-IP и X-Forwarded-For подставляется в RemoteAddr middleware.RealIP,
+// только если запрос пришел от доверенного прокси, поэтому подмена заголовков не меняет адрес.
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// renderLoginResponse - генерирует токен JWT пользователя user для сессии и отправляет его в ответе вместе с refresh-токеном
+func (h *Handlers) renderLoginResponse(w http.ResponseWriter, r *http.Request, user *models.User, session *models.Session, refreshToken string) {
 	// Генерируем токен
-	token, err := h.generateJWTToken(user.ID)
+	token, err := h.generateJWTToken(user, session.ID)
 	if err != nil {
 		h.log.Debug().Err(err).Msg("failed to generate token")
 		_ = render.Render(w, r, errs.ErrResponseInternal)
@@ -67,31 +136,28 @@ func (h *Handlers) login(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Authorization", token)
 
 	// Отправляем токен в ответе
+	render.Status(r, http.StatusOK)
 	_ = render.Render(w, r, &LoginResponse{
-		AccessToken: token,
-		TokenType:   "Bearer",
-		ExpiresIn:   int64(h.cfg.TTL.Seconds()),
+		AccessToken:  token,
+		TokenType:    "Bearer",
+		ExpiresIn:    int64(h.cfg.TTL.Seconds()),
+		RefreshToken: refreshToken,
 	})
 }
 
-// generateJWTToken - генерирует токен JWT для пользователя
-func (h *Handlers) generateJWTToken(userID uint64) (string, error) {
+// generateJWTToken - генерирует токен JWT для пользователя в рамках сессии sessionID
+func (h *Handlers) generateJWTToken(user *models.User, sessionID uint64) (string, error) {
 	// Создаем claims
 	now := jwt.TimeFunc()
 	claims := jwt.MapClaims{
-		"sub": userID,                    // subject
-		"nbf": now.Unix(),                // not before
-		"iat": now.Unix(),                // issued at
-		"exp": now.Add(h.cfg.TTL).Unix(), // expires at
-	}
-
-	// Создаем токен
-	signingMethod := jwt.GetSigningMethod(h.cfg.SigningAlg)
-	if signingMethod == nil {
-		return "", fmt.Errorf("unknown signing method: %s", h.cfg.SigningAlg)
+		"sub":  user.ID,                           // subject
+		"jti":  strconv.FormatUint(sessionID, 10), // JWT ID
+		"nbf":  now.Unix(),                        // not before
+		"iat":  now.Unix(),                        // issued at
+		"exp":  now.Add(h.cfg.TTL).Unix(),         // expires at
+		"role": user.Role,                         // роль пользователя
 	}
 
-	token := jwt.NewWithClaims(signingMethod, claims)
-	// Подписываем токен
-	return token.SignedString([]byte(h.cfg.SigningKey))
+	// Подписываем токен текущим ключом
+	return h.keys.Sign(claims)
 }
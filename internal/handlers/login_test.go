@@ -2,11 +2,17 @@ package handlers
 
 import (
 	"net/http"
+	"net/http/httptest"
+	"strings"
+	"time"
 
+	"github.com/go-chi/chi/v5"
 	"github.com/golang-jwt/jwt/v4"
 	"github.com/stretchr/testify/mock"
 
 	"gophermart-loyalty/internal/errs"
+	"gophermart-loyalty/internal/jwks"
+	"gophermart-loyalty/internal/middleware"
 	"gophermart-loyalty/internal/models"
 )
 
@@ -15,8 +21,15 @@ func (suite *handlersSuite) TestLogin() {
 	passHash := suite.passHash("test")
 
 	suite.Run("success", func() {
+		suite.loginAttempt("test", 1, true)
 		suite.repo.On("UserGetByLogin", mock.Anything, "test").
 			Return(&models.User{ID: 1, Login: "test", PassHash: passHash}, nil).Once()
+		suite.repo.On("LoginFailureReset", mock.Anything, "login:test").Return(nil).Once()
+		suite.repo.On("TOTPGet", mock.Anything, uint64(1)).Return(nil, errs.ErrNotFound).Once()
+		suite.repo.On("SessionCreate", mock.Anything, mock.Anything, int64(3600)).
+			Return(nil).Run(func(args mock.Arguments) {
+			args.Get(1).(*models.Session).ID = 5
+		}).Once()
 
 		res := suite.httpJSONRequest(http.MethodPost, "/login", reqBody, "")
 		defer res.Body.Close()
@@ -27,6 +40,7 @@ func (suite *handlersSuite) TestLogin() {
 		suite.Equal("Bearer", resJSON["token_type"])
 		suite.NotEmpty(resJSON["access_token"])
 		suite.Equal(60., resJSON["expires_in"])
+		suite.NotEmpty(resJSON["refresh_token"])
 
 		// проверяем токен
 		token, err := jwt.Parse(resJSON["access_token"].(string), func(token *jwt.Token) (interface{}, error) {
@@ -36,11 +50,37 @@ func (suite *handlersSuite) TestLogin() {
 		suite.True(token.Valid)
 		suite.Equal("HS256", token.Header["alg"])
 		suite.Equal(1., token.Claims.(jwt.MapClaims)["sub"])
+		suite.Equal("5", token.Claims.(jwt.MapClaims)["jti"])
+		suite.Equal("test", token.Header["kid"])
+	})
+
+	suite.Run("two-factor authentication required", func() {
+		suite.loginAttempt("test", 1, true)
+		suite.repo.On("UserGetByLogin", mock.Anything, "test").
+			Return(&models.User{ID: 1, Login: "test", PassHash: passHash}, nil).Once()
+		suite.repo.On("TOTPGet", mock.Anything, uint64(1)).
+			Return(&models.TOTP{UserID: 1, Secret: "JBSWY3DPEHPK3PXP", ConfirmedAt: &time.Time{}}, nil).Once()
+		suite.repo.On("LoginChallengeCreate", mock.Anything, mock.Anything, int64(300)).Return(nil).Once()
+
+		res := suite.httpJSONRequest(http.MethodPost, "/login", reqBody, "")
+		defer res.Body.Close()
+		suite.Equal(http.StatusAccepted, res.StatusCode)
+		suite.Empty(res.Header.Get("Authorization"))
+		resJSON := suite.parseJSON(res.Body)
+		suite.Equal(true, resJSON["mfa_required"])
+		suite.NotEmpty(resJSON["challenge"])
+		suite.Equal(300., resJSON["expires_in"])
+		suite.Nil(resJSON["access_token"])
 	})
 
 	suite.Run("invalid login or password", func() {
+		suite.loginAttempt("test", 1, false)
 		suite.repo.On("UserGetByLogin", mock.Anything, "test").
 			Return(nil, errs.ErrNotFound).Once()
+		suite.repo.On("LoginFailureBlock", mock.Anything, "login:test", int64(0)).
+			Return(&models.LoginFailure{Key: "login:test", Failures: 1}, nil).Once()
+		suite.repo.On("LoginFailureBlock", mock.Anything, "ip:127.0.0.1", int64(0)).
+			Return(&models.LoginFailure{Key: "ip:127.0.0.1", Failures: 1}, nil).Once()
 
 		res := suite.httpJSONRequest(http.MethodPost, "/login", reqBody, "")
 		defer res.Body.Close()
@@ -49,6 +89,64 @@ func (suite *handlersSuite) TestLogin() {
 		suite.Equal(1103., resJSON["code"])
 	})
 
+	suite.Run("login throttled", func() {
+		suite.loginAttemptBlocked("login:test", 4)
+
+		res := suite.httpJSONRequest(http.MethodPost, "/login", reqBody, "")
+		defer res.Body.Close()
+		suite.Equal(http.StatusTooManyRequests, res.StatusCode)
+		resJSON := suite.parseJSON(res.Body)
+		suite.Equal(1502., resJSON["code"])
+	})
+
+	suite.Run("spoofed X-Forwarded-For does not reset IP bucket", func() {
+		// Сервер без доверенных прокси: адрес клиента берется из соединения
+		r := chi.NewRouter()
+		r.Use(middleware.RealIP(nil))
+		r.Mount("/", suite.handlers.InitRoutes())
+		server := httptest.NewServer(r)
+		defer server.Close()
+
+		for _, xff := range []string{"198.51.100.1", "198.51.100.2", "203.0.113.7, 198.51.100.3"} {
+			suite.repo.On("LoginAttemptRegister", mock.Anything, "login:test", int64(900)).
+				Return(&models.LoginFailure{Key: "login:test", Failures: 1, LastFailedAt: time.Now()}, nil).Once()
+			suite.loginAttemptBlocked("ip:127.0.0.1", 100)
+			suite.repo.On("LoginAttemptRelease", mock.Anything, "login:test").Return(nil).Once()
+
+			req, err := http.NewRequest(http.MethodPost, server.URL+"/login", strings.NewReader(reqBody))
+			suite.Require().NoError(err)
+			req.Header.Set("Content-Type", "application/json")
+			req.Header.Set("X-Forwarded-For", xff)
+			req.Header.Set("X-Real-IP", xff)
+			res, err := http.DefaultClient.Do(req)
+			suite.Require().NoError(err)
+			suite.Equal(http.StatusTooManyRequests, res.StatusCode)
+			_ = res.Body.Close()
+		}
+	})
+
+	suite.Run("user locked", func() {
+		suite.loginAttemptBlocked("login:test", 10)
+
+		res := suite.httpJSONRequest(http.MethodPost, "/login", reqBody, "")
+		defer res.Body.Close()
+		suite.Equal(http.StatusLocked, res.StatusCode)
+		resJSON := suite.parseJSON(res.Body)
+		suite.Equal(1503., resJSON["code"])
+	})
+
+	suite.Run("user blocked", func() {
+		suite.loginAttempt("test", 1, true)
+		suite.repo.On("UserGetByLogin", mock.Anything, "test").
+			Return(&models.User{ID: 1, Login: "test", PassHash: passHash, Status: models.UserBlocked}, nil).Once()
+
+		res := suite.httpJSONRequest(http.MethodPost, "/login", reqBody, "")
+		defer res.Body.Close()
+		suite.Equal(http.StatusForbidden, res.StatusCode)
+		resJSON := suite.parseJSON(res.Body)
+		suite.Equal(1110., resJSON["code"])
+	})
+
 	suite.Run("invalid request body", func() {
 		res := suite.httpJSONRequest(http.MethodPost, "/login", "invalid", "")
 		defer res.Body.Close()
@@ -57,14 +155,35 @@ func (suite *handlersSuite) TestLogin() {
 		suite.Equal(1003., resJSON["code"])
 	})
 
+	suite.Run("session creation error", func() {
+		suite.loginAttempt("test", 1, true)
+		suite.repo.On("LoginFailureReset", mock.Anything, "login:test").Return(nil).Once()
+		suite.repo.On("UserGetByLogin", mock.Anything, "test").
+			Return(&models.User{ID: 1, Login: "test", PassHash: passHash}, nil).Once()
+		suite.repo.On("TOTPGet", mock.Anything, uint64(1)).Return(nil, errs.ErrNotFound).Once()
+		suite.repo.On("SessionCreate", mock.Anything, mock.Anything, mock.Anything).
+			Return(errs.ErrInternal).Once()
+
+		res := suite.httpJSONRequest(http.MethodPost, "/login", reqBody, "")
+		defer res.Body.Close()
+		suite.Equal(http.StatusInternalServerError, res.StatusCode)
+		resJSON := suite.parseJSON(res.Body)
+		suite.Equal(1000., resJSON["code"])
+	})
+
 	suite.Run("token generation error", func() {
+		suite.loginAttempt("test", 1, true)
+		suite.repo.On("LoginFailureReset", mock.Anything, "login:test").Return(nil).Once()
 		suite.repo.On("UserGetByLogin", mock.Anything, "test").
 			Return(&models.User{ID: 1, Login: "test", PassHash: passHash}, nil).Once()
+		suite.repo.On("TOTPGet", mock.Anything, uint64(1)).Return(nil, errs.ErrNotFound).Once()
+		suite.repo.On("SessionCreate", mock.Anything, mock.Anything, mock.Anything).
+			Return(nil).Once()
 
-		// временно подменяем алгоритм подписи токена
-		m := suite.handlers.cfg.SigningAlg
-		suite.cfg.SigningAlg = ""
-		defer func() { suite.handlers.cfg.SigningAlg = m }()
+		// временно подменяем ключи подписи токена пустым набором
+		keys := suite.handlers.keys
+		suite.handlers.keys = &jwks.KeySet{}
+		defer func() { suite.handlers.keys = keys }()
 
 		res := suite.httpJSONRequest(http.MethodPost, "/login", reqBody, "")
 		defer res.Body.Close()
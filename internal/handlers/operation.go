@@ -4,6 +4,7 @@ import (
 	"errors"
 	"net/http"
 
+	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/render"
 
 	"gophermart-loyalty/internal/errs"
@@ -69,16 +70,20 @@ func (h *Handlers) orderAccrualCreate(w http.ResponseWriter, r *http.Request) {
 //
 //    {
 //	   "order": "2377225624",
-//     "sum": 751
+//     "sum": 751,
+//     "order_total": 2000
 //    }
 //
+// Поле order_total необязательное: если сумма заказа указана, проверяется доля заказа, оплачиваемая баллами.
+//
 // Возможные коды ответа:
 //    200 — успешная обработка запроса
 //	  400 — неверный формат запроса
 //    401 — пользователь не авторизован
 //    402 — на счету недостаточно средств
+//    403 — учетная запись пользователя заблокирована
 //	  409 — номер заказа уже был загружен другим пользователем
-//    422 — неверный номер заказа
+//    422 — неверный номер заказа или списание нарушает правила списания
 //    500 — внутренняя ошибка сервера
 func (h *Handlers) orderWithdrawalCreate(w http.ResponseWriter, r *http.Request) {
 	// Получаем пользователя из контекста
@@ -96,14 +101,93 @@ func (h *Handlers) orderWithdrawalCreate(w http.ResponseWriter, r *http.Request)
 	}
 
 	// Создаем модель операции
-	op, err := h.useCases.OrderWithdrawalPrepare(r.Context(), userID, data.OrderNumber, data.Amount)
+	op, err := h.useCases.OrderWithdrawalPrepare(r.Context(), userID, data.OrderNumber, data.Amount, data.OrderTotal, &h.balance.Withdrawal)
 	if err != nil {
 		_ = render.Render(w, r, errs.NewErrResponse(err))
 		return
 	}
 
 	// Сохраняем операцию
-	if err = h.useCases.OperationCreate(r.Context(), op); err != nil {
+	if err = h.useCases.OrderWithdrawalCreate(r.Context(), op, &h.balance.Withdrawal); err != nil {
+		_ = render.Render(w, r, errs.NewErrResponse(err))
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+// orderWithdrawalCancel - отмена списания бонусов, которое еще не принято в обработку магазином.
+// Списанные баллы возвращаются на счет пользователя.
+// Формат запроса:
+//    DELETE /api/user/withdrawals/{order} HTTP/1.1
+//    Content-Length: 0
+//
+// Возможные коды ответа:
+//    200 — списание отменено
+//    401 — пользователь не авторизован
+//    404 — списание по заказу не найдено
+//    409 — списание уже принято в обработку, обработано или отменено
+//    422 — неверный номер заказа
+//    500 — внутренняя ошибка сервера
+func (h *Handlers) orderWithdrawalCancel(w http.ResponseWriter, r *http.Request) {
+	// Получаем пользователя из контекста
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		_ = render.Render(w, r, errs.ErrResponseUnauthorized)
+		return
+	}
+
+	if _, err := h.useCases.OrderWithdrawalCancel(r.Context(), userID, chi.URLParam(r, "order")); err != nil {
+		_ = render.Render(w, r, errs.NewErrResponse(err))
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+// transferCreate - перевод баллов другому пользователю по логину.
+// Баллы списываются у отправителя и зачисляются получателю одновременно,
+// перевод отображается в истории операций обоих пользователей.
+// Формат запроса:
+//    POST /api/user/balance/transfer HTTP/1.1
+//    Content-Type: application/json
+//
+//    {
+//     "login": "bob",
+//     "sum": 100
+//    }
+//
+// Возможные коды ответа:
+//    200 — успешная обработка запроса
+//    400 — неверный формат запроса или перевод самому себе
+//    401 — пользователь не авторизован
+//    402 — на счету недостаточно средств
+//    403 — учетная запись пользователя заблокирована
+//    404 — получатель не найден
+//    422 — превышена сумма или количество переводов за сутки
+//    500 — внутренняя ошибка сервера
+func (h *Handlers) transferCreate(w http.ResponseWriter, r *http.Request) {
+	// Получаем пользователя из контекста
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		_ = render.Render(w, r, errs.ErrResponseUnauthorized)
+		return
+	}
+
+	// Получаем данные из запроса
+	data := &TransferCreateRequest{}
+	if err := render.Bind(r, data); err != nil {
+		_ = render.Render(w, r, errs.ErrResponseBadRequest)
+		return
+	}
+
+	// Создаем модели операций списания и зачисления
+	debit, credit, err := h.useCases.TransferPrepare(r.Context(), userID, data.Login, data.Amount)
+	if err != nil {
+		_ = render.Render(w, r, errs.NewErrResponse(err))
+		return
+	}
+
+	// Переводим баллы
+	if err = h.useCases.TransferCreate(r.Context(), debit, credit, &h.balance.Transfer); err != nil {
 		_ = render.Render(w, r, errs.NewErrResponse(err))
 		return
 	}
@@ -144,6 +228,7 @@ func (h *Handlers) promoAccrualCreate(w http.ResponseWriter, r *http.Request) {
 		_ = render.Render(w, r, errs.NewErrResponse(err))
 		return
 	}
+	h.useCases.PointsHold(op, &h.balance.Hold)
 
 	// Сохраняем операцию
 	if err = h.useCases.OperationCreate(r.Context(), op); err != nil {
@@ -153,14 +238,18 @@ func (h *Handlers) promoAccrualCreate(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusOK)
 }
 
-// orderAccrualList - получение списка загруженных номеров заказов.
+// orderAccrualList - получение списка загруженных номеров заказов по убыванию времени загрузки.
 // Формат запроса:
-//    GET /api/user/orders HTTP/1.1
+//    GET /api/user/orders?limit=20&cursor=<cursor>&status=PROCESSED&from=2020-12-01&to=2021-01-01 HTTP/1.1
 //    Content-Length: 0
 //
+// Все параметры необязательны (см. decodeOperationQuery), период задается по времени загрузки.
+// Если задан limit и есть следующая страница, ответ содержит заголовки X-Next-Cursor и Link с rel="next".
+//
 // Возможные коды ответа:
 //    200 — успешная обработка запроса
 //    204 — нет данных для ответа.
+//    400 — неверные параметры выборки
 //    401 — пользователь не авторизован.
 //    500 — внутренняя ошибка сервера.
 //
@@ -194,8 +283,14 @@ func (h *Handlers) orderAccrualList(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	q, err := decodeOperationQuery(r, false)
+	if err != nil {
+		_ = render.Render(w, r, errs.NewErrResponse(err))
+		return
+	}
+
 	// получаем список операций начисления бонусов
-	operations, err := h.useCases.OperationGetByType(r.Context(), userID, models.OrderAccrual)
+	operations, next, err := h.useCases.OperationGetByType(r.Context(), userID, models.OrderAccrual, q)
 	if err != nil {
 		_ = render.Render(w, r, errs.NewErrResponse(err))
 		return
@@ -206,10 +301,163 @@ func (h *Handlers) orderAccrualList(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	setNextPage(w, r, next)
 	_ = render.RenderList(w, r, newOrderAccrualListResponse(operations))
 
 }
 
+// orderAccrualGet - получение начисления баллов по загруженному заказу.
+// Формат запроса:
+//    GET /api/user/orders/{number} HTTP/1.1
+//    Content-Length: 0
+//
+// Возможные коды ответа:
+//    200 — успешная обработка запроса
+//    401 — пользователь не авторизован
+//    404 — заказ не загружался пользователем
+//    422 — неверный номер заказа
+//    500 — внутренняя ошибка сервера
+//
+// Формат ответа:
+//    200 OK HTTP/1.1
+//    Content-Type: application/json
+//
+//    {
+//        "number": "9278923470",
+//        "status": "PROCESSED",
+//        "accrual": 500,
+//        "uploaded_at": "2020-12-10T15:12:01+03:00",
+//        "checked_at": "2020-12-10T15:15:45+03:00",
+//        "history": [
+//            {
+//                "old_status": "NEW",
+//                "status": "PROCESSED",
+//                "accrual": 500,
+//                "source": "accrual",
+//                "changed_at": "2020-12-10T15:15:45+03:00"
+//            }
+//        ]
+//    }
+func (h *Handlers) orderAccrualGet(w http.ResponseWriter, r *http.Request) {
+	// Получаем пользователя из контекста
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		_ = render.Render(w, r, errs.ErrResponseUnauthorized)
+		return
+	}
+
+	op, history, err := h.useCases.OrderOperationGet(r.Context(), userID, models.OrderAccrual, chi.URLParam(r, "number"))
+	if err != nil {
+		_ = render.Render(w, r, errs.NewErrResponse(err))
+		return
+	}
+
+	_ = render.Render(w, r, newOrderAccrualResponse(op, history))
+}
+
+// orderStatusHistoryGet - получение истории изменения статуса загруженного заказа.
+// Формат запроса:
+//    GET /api/user/orders/{number}/history HTTP/1.1
+//    Content-Length: 0
+//
+// Возможные коды ответа:
+//    200 — успешная обработка запроса
+//    204 — статус заказа еще не изменялся
+//    401 — пользователь не авторизован
+//    404 — заказ не загружался пользователем
+//    422 — неверный номер заказа
+//    500 — внутренняя ошибка сервера
+//
+// Формат ответа:
+//    200 OK HTTP/1.1
+//    Content-Type: application/json
+//
+//    [
+//        {
+//            "old_status": "NEW",
+//            "status": "PROCESSING",
+//            "accrual": 0,
+//            "source": "accrual",
+//            "changed_at": "2020-12-10T15:12:01+03:00"
+//        },
+//        {
+//            "old_status": "PROCESSING",
+//            "status": "PROCESSED",
+//            "accrual": 500,
+//            "source": "accrual",
+//            "changed_at": "2020-12-10T15:15:45+03:00"
+//        }
+//    ]
+func (h *Handlers) orderStatusHistoryGet(w http.ResponseWriter, r *http.Request) {
+	// Получаем пользователя из контекста
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		_ = render.Render(w, r, errs.ErrResponseUnauthorized)
+		return
+	}
+
+	history, err := h.useCases.OrderStatusHistoryGet(r.Context(), userID, chi.URLParam(r, "number"))
+	if err != nil {
+		_ = render.Render(w, r, errs.NewErrResponse(err))
+		return
+	}
+
+	if len(history) == 0 {
+		render.NoContent(w, r)
+		return
+	}
+
+	_ = render.RenderList(w, r, newOrderStatusHistoryResponse(history))
+}
+
+// orderWithdrawalGet - получение списания баллов по заказу.
+// Формат запроса:
+//    GET /api/user/withdrawals/{order} HTTP/1.1
+//    Content-Length: 0
+//
+// Возможные коды ответа:
+//    200 — успешная обработка запроса
+//    401 — пользователь не авторизован
+//    404 — списание по заказу не найдено
+//    422 — неверный номер заказа
+//    500 — внутренняя ошибка сервера
+//
+// Формат ответа:
+//    200 OK HTTP/1.1
+//    Content-Type: application/json
+//
+//    {
+//        "order": "2377225624",
+//        "status": "PROCESSED",
+//        "sum": 500,
+//        "created_at": "2020-12-09T16:08:57+03:00",
+//        "processed_at": "2020-12-09T16:09:57+03:00",
+//        "history": [
+//            {
+//                "old_status": "NEW",
+//                "status": "PROCESSED",
+//                "source": "shop",
+//                "changed_at": "2020-12-09T16:09:57+03:00"
+//            }
+//        ]
+//    }
+func (h *Handlers) orderWithdrawalGet(w http.ResponseWriter, r *http.Request) {
+	// Получаем пользователя из контекста
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		_ = render.Render(w, r, errs.ErrResponseUnauthorized)
+		return
+	}
+
+	op, history, err := h.useCases.OrderOperationGet(r.Context(), userID, models.OrderWithdrawal, chi.URLParam(r, "order"))
+	if err != nil {
+		_ = render.Render(w, r, errs.NewErrResponse(err))
+		return
+	}
+
+	_ = render.Render(w, r, newOrderWithdrawalResponse(op, history))
+}
+
 func NewOrderWithdrawalListResponse(ops []*models.Operation) []render.Renderer {
 	list := make([]render.Renderer, len(ops))
 	for i, op := range ops {
@@ -223,14 +471,18 @@ func NewOrderWithdrawalListResponse(ops []*models.Operation) []render.Renderer {
 	return list
 }
 
-// orderWithdrawalList - получение информации о выводе средств.
+// orderWithdrawalList - получение информации о выводе средств по убыванию времени создания списания.
 // Формат запроса:
-//    GET /api/user/withdrawals HTTP/1.1
+//    GET /api/user/withdrawals?limit=20&cursor=<cursor>&status=PROCESSED&from=2020-12-01&to=2021-01-01 HTTP/1.1
 //    Content-Length: 0
 //
+// Все параметры необязательны (см. decodeOperationQuery), период задается по времени создания списания.
+// Если задан limit и есть следующая страница, ответ содержит заголовки X-Next-Cursor и Link с rel="next".
+//
 // Возможные коды ответа:
 //    200 — успешная обработка запроса
 //    204 — нет ни одного списания
+//    400 — неверные параметры выборки
 //    401 — пользователь не авторизован
 //    500 — внутренняя ошибка сервера
 //
@@ -253,8 +505,14 @@ func (h *Handlers) orderWithdrawalList(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	q, err := decodeOperationQuery(r, false)
+	if err != nil {
+		_ = render.Render(w, r, errs.NewErrResponse(err))
+		return
+	}
+
 	// Получаем список операций
-	ops, err := h.useCases.OperationGetByType(r.Context(), userID, models.OrderWithdrawal)
+	ops, next, err := h.useCases.OperationGetByType(r.Context(), userID, models.OrderWithdrawal, q)
 	if err != nil {
 		_ = render.Render(w, r, errs.NewErrResponse(err))
 		return
@@ -264,5 +522,6 @@ func (h *Handlers) orderWithdrawalList(w http.ResponseWriter, r *http.Request) {
 		render.NoContent(w, r)
 		return
 	}
+	setNextPage(w, r, next)
 	_ = render.RenderList(w, r, NewOrderWithdrawalListResponse(ops))
 }
@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"context"
 	"net/http"
 	"time"
 
@@ -8,6 +9,7 @@ import (
 	"github.com/stretchr/testify/mock"
 
 	"gophermart-loyalty/internal/errs"
+	"gophermart-loyalty/internal/middleware"
 	"gophermart-loyalty/internal/models"
 )
 
@@ -73,10 +75,178 @@ func (suite *handlersSuite) TestOrderAccrualCreate() {
 	})
 }
 
+func (suite *handlersSuite) TestOrderAccrualGet() {
+	uploadedAt := time.Now().Add(-time.Hour).UTC().Truncate(time.Second)
+	checkedAt := time.Now().UTC().Truncate(time.Second)
+
+	suite.Run("success", func() {
+		suite.repo.On("OperationGetByOrder", mock.Anything, uint64(1), models.OrderAccrual, "12345678903").
+			Return(&models.Operation{
+				ID: 1, UserID: 1, Type: models.OrderAccrual, Status: models.StatusProcessing,
+				OrderNumber: strPtr("12345678903"), CreatedAt: uploadedAt, UpdatedAt: uploadedAt, LastCheckedAt: &checkedAt,
+			}, nil).Once()
+		suite.repo.On("OperationStatusHistoryGetByOrder", mock.Anything, uint64(1), models.OrderAccrual, "12345678903").
+			Return([]*models.OperationStatusChange{
+				{ID: 1, OperationID: 1, OldStatus: models.StatusNew, NewStatus: models.StatusProcessing, Source: models.StatusSourceAccrual, CreatedAt: uploadedAt},
+			}, nil).Once()
+		token := suite.validJWTToken(1)
+		res := suite.httpJSONRequest(http.MethodGet, "/orders/12345678903", "", token)
+		defer res.Body.Close()
+		suite.Equal(http.StatusOK, res.StatusCode)
+		resJSON := suite.parseJSON(res.Body)
+		suite.Equal("12345678903", resJSON["number"])
+		suite.Equal("PROCESSING", resJSON["status"])
+		suite.Equal(uploadedAt.Format(time.RFC3339), resJSON["uploaded_at"])
+		suite.Equal(checkedAt.Format(time.RFC3339), resJSON["checked_at"])
+		suite.Require().Len(resJSON["history"], 1)
+	})
+
+	suite.Run("no history", func() {
+		suite.repo.On("OperationGetByOrder", mock.Anything, uint64(1), models.OrderAccrual, "12345678903").
+			Return(&models.Operation{
+				ID: 1, UserID: 1, Type: models.OrderAccrual, Status: models.StatusNew,
+				OrderNumber: strPtr("12345678903"), CreatedAt: uploadedAt, UpdatedAt: uploadedAt,
+			}, nil).Once()
+		suite.repo.On("OperationStatusHistoryGetByOrder", mock.Anything, uint64(1), models.OrderAccrual, "12345678903").
+			Return(nil, nil).Once()
+		token := suite.validJWTToken(1)
+		res := suite.httpJSONRequest(http.MethodGet, "/orders/12345678903", "", token)
+		defer res.Body.Close()
+		suite.Equal(http.StatusOK, res.StatusCode)
+		resJSON := suite.parseJSON(res.Body)
+		suite.NotContains(resJSON, "history")
+		suite.NotContains(resJSON, "checked_at")
+	})
+
+	suite.Run("order of another user", func() {
+		suite.repo.On("OperationGetByOrder", mock.Anything, uint64(2), models.OrderAccrual, "12345678903").
+			Return(nil, errs.ErrNotFound).Once()
+		token := suite.validJWTToken(2)
+		res := suite.httpJSONRequest(http.MethodGet, "/orders/12345678903", "", token)
+		defer res.Body.Close()
+		suite.Equal(http.StatusNotFound, res.StatusCode)
+	})
+
+	suite.Run("invalid order number", func() {
+		token := suite.validJWTToken(1)
+		res := suite.httpJSONRequest(http.MethodGet, "/orders/12345678904", "", token)
+		defer res.Body.Close()
+		suite.Equal(http.StatusUnprocessableEntity, res.StatusCode)
+	})
+}
+
+func (suite *handlersSuite) TestOrderWithdrawalGet() {
+	createdAt := time.Now().Add(-time.Hour).UTC().Truncate(time.Second)
+	processedAt := time.Now().UTC().Truncate(time.Second)
+
+	suite.Run("success", func() {
+		suite.repo.On("OperationGetByOrder", mock.Anything, uint64(1), models.OrderWithdrawal, "12345678903").
+			Return(&models.Operation{
+				ID: 1, UserID: 1, Type: models.OrderWithdrawal, Status: models.StatusProcessed, Amount: decimal.NewFromInt(-100),
+				OrderNumber: strPtr("12345678903"), CreatedAt: createdAt, UpdatedAt: processedAt,
+			}, nil).Once()
+		suite.repo.On("OperationStatusHistoryGetByOrder", mock.Anything, uint64(1), models.OrderWithdrawal, "12345678903").
+			Return([]*models.OperationStatusChange{
+				{ID: 1, OperationID: 1, OldStatus: models.StatusNew, NewStatus: models.StatusProcessed, Amount: decimal.NewFromInt(-100), Source: models.StatusSourceShop, CreatedAt: processedAt},
+			}, nil).Once()
+		token := suite.validJWTToken(1)
+		res := suite.httpJSONRequest(http.MethodGet, "/withdrawals/12345678903", "", token)
+		defer res.Body.Close()
+		suite.Equal(http.StatusOK, res.StatusCode)
+		resJSON := suite.parseJSON(res.Body)
+		suite.Equal("12345678903", resJSON["order"])
+		suite.Equal("PROCESSED", resJSON["status"])
+		suite.Equal(100., resJSON["sum"])
+		suite.Equal(createdAt.Format(time.RFC3339), resJSON["created_at"])
+		suite.Equal(processedAt.Format(time.RFC3339), resJSON["processed_at"])
+		history, ok := resJSON["history"].([]interface{})
+		suite.Require().True(ok)
+		suite.Require().Len(history, 1)
+		suite.Equal("shop", history[0].(map[string]interface{})["source"])
+	})
+
+	suite.Run("not processed yet", func() {
+		suite.repo.On("OperationGetByOrder", mock.Anything, uint64(1), models.OrderWithdrawal, "12345678903").
+			Return(&models.Operation{
+				ID: 1, UserID: 1, Type: models.OrderWithdrawal, Status: models.StatusNew, Amount: decimal.NewFromInt(-100),
+				OrderNumber: strPtr("12345678903"), CreatedAt: createdAt, UpdatedAt: processedAt,
+			}, nil).Once()
+		suite.repo.On("OperationStatusHistoryGetByOrder", mock.Anything, uint64(1), models.OrderWithdrawal, "12345678903").
+			Return(nil, nil).Once()
+		token := suite.validJWTToken(1)
+		res := suite.httpJSONRequest(http.MethodGet, "/withdrawals/12345678903", "", token)
+		defer res.Body.Close()
+		suite.Equal(http.StatusOK, res.StatusCode)
+		resJSON := suite.parseJSON(res.Body)
+		suite.Equal("NEW", resJSON["status"])
+		suite.NotContains(resJSON, "processed_at")
+	})
+
+	suite.Run("withdrawal of another user", func() {
+		suite.repo.On("OperationGetByOrder", mock.Anything, uint64(2), models.OrderWithdrawal, "12345678903").
+			Return(nil, errs.ErrNotFound).Once()
+		token := suite.validJWTToken(2)
+		res := suite.httpJSONRequest(http.MethodGet, "/withdrawals/12345678903", "", token)
+		defer res.Body.Close()
+		suite.Equal(http.StatusNotFound, res.StatusCode)
+	})
+}
+
+func (suite *handlersSuite) TestOrderStatusHistoryGet() {
+	changedAt := time.Now().UTC().Truncate(time.Second)
+
+	suite.Run("success", func() {
+		suite.repo.On("OperationStatusHistoryGetByOrder", mock.Anything, uint64(1), models.OrderAccrual, "12345678903").
+			Return([]*models.OperationStatusChange{
+				{ID: 1, OperationID: 1, OldStatus: models.StatusNew, NewStatus: models.StatusProcessing, Source: models.StatusSourceAccrual, CreatedAt: changedAt},
+				{ID: 2, OperationID: 1, OldStatus: models.StatusProcessing, NewStatus: models.StatusProcessed, Amount: decimal.NewFromInt(500), Source: models.StatusSourceAccrual, CreatedAt: changedAt},
+			}, nil).Once()
+		token := suite.validJWTToken(1)
+		res := suite.httpJSONRequest(http.MethodGet, "/orders/12345678903/history", "", token)
+		defer res.Body.Close()
+		suite.Equal(http.StatusOK, res.StatusCode)
+		resJSON := suite.parseJSONList(res.Body)
+		suite.Require().Len(resJSON, 2)
+		suite.Equal("NEW", resJSON[0]["old_status"])
+		suite.Equal("PROCESSING", resJSON[0]["status"])
+		suite.Equal("accrual", resJSON[0]["source"])
+		suite.Equal(changedAt.Format(time.RFC3339), resJSON[0]["changed_at"])
+		suite.Equal("PROCESSED", resJSON[1]["status"])
+		suite.Equal(500., resJSON[1]["accrual"])
+	})
+
+	suite.Run("no changes", func() {
+		suite.repo.On("OperationStatusHistoryGetByOrder", mock.Anything, uint64(1), models.OrderAccrual, "12345678903").
+			Return(nil, nil).Once()
+		token := suite.validJWTToken(1)
+		res := suite.httpJSONRequest(http.MethodGet, "/orders/12345678903/history", "", token)
+		defer res.Body.Close()
+		suite.Equal(http.StatusNoContent, res.StatusCode)
+	})
+
+	suite.Run("order not found", func() {
+		suite.repo.On("OperationStatusHistoryGetByOrder", mock.Anything, uint64(2), models.OrderAccrual, "12345678903").
+			Return(nil, errs.ErrNotFound).Once()
+		token := suite.validJWTToken(2)
+		res := suite.httpJSONRequest(http.MethodGet, "/orders/12345678903/history", "", token)
+		defer res.Body.Close()
+		suite.Equal(http.StatusNotFound, res.StatusCode)
+	})
+
+	suite.Run("invalid order number", func() {
+		token := suite.validJWTToken(1)
+		res := suite.httpJSONRequest(http.MethodGet, "/orders/12345678904/history", "", token)
+		defer res.Body.Close()
+		suite.Equal(http.StatusUnprocessableEntity, res.StatusCode)
+		resJSON := suite.parseJSON(res.Body)
+		suite.Equal(1203., resJSON["code"])
+	})
+}
+
 func (suite *handlersSuite) TestOrderWithdrawalCreate() {
 	suite.Run("success", func() {
 		reqBody := `{"order":"12345678903","sum":100}`
-		suite.repo.On("OperationCreate", mock.Anything, mock.Anything).
+		suite.repo.On("OperationWithdraw", mock.Anything, mock.Anything, mock.Anything).
 			Return(nil).Once()
 		token := suite.validJWTToken(1)
 		res := suite.httpJSONRequest("POST", "/balance/withdraw", reqBody, token)
@@ -86,6 +256,47 @@ func (suite *handlersSuite) TestOrderWithdrawalCreate() {
 		suite.Equal(0, len(resBody))
 	})
 
+	suite.Run("success with idempotency key", func() {
+		reqBody := `{"order":"12345678903","sum":100}`
+		suite.repo.On("IdempotencyKeyReserve", mock.Anything, mock.Anything, int64(24*3600)).
+			Return(nil, nil).Once()
+		suite.repo.On("OperationWithdraw", mock.Anything, mock.Anything, mock.Anything).
+			Return(nil).Once()
+		suite.repo.On("IdempotencyKeyComplete", mock.Anything, mock.MatchedBy(func(k *models.IdempotencyKey) bool {
+			return k.UserID == 1 && k.Key == "key-1" && k.StatusCode == http.StatusOK
+		})).Return(nil).Once()
+		token := suite.validJWTToken(1)
+		res := suite.httpIdempotentJSONRequest("POST", "/balance/withdraw", reqBody, token, "key-1")
+		defer res.Body.Close()
+		suite.Equal(http.StatusOK, res.StatusCode)
+		suite.Empty(res.Header.Get(middleware.IdempotentReplayedHeader))
+	})
+
+	suite.Run("replayed with idempotency key", func() {
+		reqBody := `{"order":"12345678903","sum":100}`
+		suite.repo.On("IdempotencyKeyReserve", mock.Anything, mock.Anything, int64(24*3600)).
+			Return(func(_ context.Context, k *models.IdempotencyKey, _ int64) *models.IdempotencyKey {
+				return &models.IdempotencyKey{UserID: k.UserID, Key: k.Key, Fingerprint: k.Fingerprint, StatusCode: http.StatusOK}
+			}, nil).Once()
+		token := suite.validJWTToken(1)
+		res := suite.httpIdempotentJSONRequest("POST", "/balance/withdraw", reqBody, token, "key-1")
+		defer res.Body.Close()
+		suite.Equal(http.StatusOK, res.StatusCode)
+		suite.Equal("true", res.Header.Get(middleware.IdempotentReplayedHeader))
+	})
+
+	suite.Run("idempotency key reused", func() {
+		reqBody := `{"order":"12345678903","sum":200}`
+		suite.repo.On("IdempotencyKeyReserve", mock.Anything, mock.Anything, int64(24*3600)).
+			Return(&models.IdempotencyKey{UserID: 1, Key: "key-1", Fingerprint: "other", StatusCode: http.StatusOK}, nil).Once()
+		token := suite.validJWTToken(1)
+		res := suite.httpIdempotentJSONRequest("POST", "/balance/withdraw", reqBody, token, "key-1")
+		defer res.Body.Close()
+		suite.Equal(http.StatusUnprocessableEntity, res.StatusCode)
+		resJSON := suite.parseJSON(res.Body)
+		suite.Equal(1006., resJSON["code"])
+	})
+
 	suite.Run("invalid order number", func() {
 		reqBody := `{"order":"invalid","sum":100}`
 		token := suite.validJWTToken(1)
@@ -106,9 +317,41 @@ func (suite *handlersSuite) TestOrderWithdrawalCreate() {
 		suite.Equal(1003., resJSON["code"])
 	})
 
+	suite.Run("below minimum", func() {
+		reqBody := `{"order":"12345678903","sum":1}`
+		token := suite.validJWTToken(1)
+		res := suite.httpJSONRequest("POST", "/balance/withdraw", reqBody, token)
+		defer res.Body.Close()
+		suite.Equal(http.StatusUnprocessableEntity, res.StatusCode)
+		resJSON := suite.parseJSON(res.Body)
+		suite.Equal(1214., resJSON["code"])
+	})
+
+	suite.Run("order share exceeded", func() {
+		reqBody := `{"order":"12345678903","sum":100,"order_total":150}`
+		token := suite.validJWTToken(1)
+		res := suite.httpJSONRequest("POST", "/balance/withdraw", reqBody, token)
+		defer res.Body.Close()
+		suite.Equal(http.StatusUnprocessableEntity, res.StatusCode)
+		resJSON := suite.parseJSON(res.Body)
+		suite.Equal(1217., resJSON["code"])
+	})
+
+	suite.Run("daily amount exceeded", func() {
+		reqBody := `{"order":"12345678903","sum":100}`
+		suite.repo.On("OperationWithdraw", mock.Anything, mock.Anything, mock.Anything).
+			Return(errs.ErrWithdrawalDailyAmountExceeded).Once()
+		token := suite.validJWTToken(1)
+		res := suite.httpJSONRequest("POST", "/balance/withdraw", reqBody, token)
+		defer res.Body.Close()
+		suite.Equal(http.StatusUnprocessableEntity, res.StatusCode)
+		resJSON := suite.parseJSON(res.Body)
+		suite.Equal(1215., resJSON["code"])
+	})
+
 	suite.Run("insufficient funds", func() {
 		reqBody := `{"order":"12345678903","sum":100}`
-		suite.repo.On("OperationCreate", mock.Anything, mock.Anything).
+		suite.repo.On("OperationWithdraw", mock.Anything, mock.Anything, mock.Anything).
 			Return(errs.ErrUserBalanceNegative).Once()
 		token := suite.validJWTToken(1)
 		res := suite.httpJSONRequest("POST", "/balance/withdraw", reqBody, token)
@@ -120,7 +363,7 @@ func (suite *handlersSuite) TestOrderWithdrawalCreate() {
 
 	suite.Run("order number belongs to another user", func() {
 		reqBody := `{"order":"12345678903","sum":100}`
-		suite.repo.On("OperationCreate", mock.Anything, mock.Anything).
+		suite.repo.On("OperationWithdraw", mock.Anything, mock.Anything, mock.Anything).
 			Return(errs.ErrOperationOrderNotBelongs).Once()
 		token := suite.validJWTToken(2)
 		res := suite.httpJSONRequest("POST", "/balance/withdraw", reqBody, token)
@@ -132,7 +375,7 @@ func (suite *handlersSuite) TestOrderWithdrawalCreate() {
 
 	suite.Run("internal error", func() {
 		reqBody := `{"order":"12345678903","sum":100}`
-		suite.repo.On("OperationCreate", mock.Anything, mock.Anything).
+		suite.repo.On("OperationWithdraw", mock.Anything, mock.Anything, mock.Anything).
 			Return(errs.ErrInternal).Once()
 		token := suite.validJWTToken(1)
 		res := suite.httpJSONRequest("POST", "/balance/withdraw", reqBody, token)
@@ -143,6 +386,126 @@ func (suite *handlersSuite) TestOrderWithdrawalCreate() {
 	})
 }
 
+func (suite *handlersSuite) TestOrderWithdrawalCancel() {
+	suite.Run("success", func() {
+		suite.repo.On("OperationUpdateByOrder", mock.Anything, uint64(1), models.OrderWithdrawal, "12345678903", models.StatusSourceUser, mock.Anything).
+			Return(&models.Operation{ID: 1, Status: models.StatusCanceled}, nil).Once()
+		token := suite.validJWTToken(1)
+		res := suite.httpJSONRequest(http.MethodDelete, "/withdrawals/12345678903", "", token)
+		defer res.Body.Close()
+		suite.Equal(http.StatusOK, res.StatusCode)
+	})
+
+	suite.Run("not cancelable", func() {
+		suite.repo.On("OperationUpdateByOrder", mock.Anything, uint64(1), models.OrderWithdrawal, "12345678903", models.StatusSourceUser, mock.Anything).
+			Return(nil, errs.ErrWithdrawalNotCancelable).Once()
+		token := suite.validJWTToken(1)
+		res := suite.httpJSONRequest(http.MethodDelete, "/withdrawals/12345678903", "", token)
+		defer res.Body.Close()
+		suite.Equal(http.StatusConflict, res.StatusCode)
+		resJSON := suite.parseJSON(res.Body)
+		suite.Equal(1213., resJSON["code"])
+	})
+
+	suite.Run("not found", func() {
+		suite.repo.On("OperationUpdateByOrder", mock.Anything, uint64(1), models.OrderWithdrawal, "12345678903", models.StatusSourceUser, mock.Anything).
+			Return(nil, errs.ErrNotFound).Once()
+		token := suite.validJWTToken(1)
+		res := suite.httpJSONRequest(http.MethodDelete, "/withdrawals/12345678903", "", token)
+		defer res.Body.Close()
+		suite.Equal(http.StatusNotFound, res.StatusCode)
+	})
+
+	suite.Run("invalid order number", func() {
+		token := suite.validJWTToken(1)
+		res := suite.httpJSONRequest(http.MethodDelete, "/withdrawals/12345678904", "", token)
+		defer res.Body.Close()
+		suite.Equal(http.StatusUnprocessableEntity, res.StatusCode)
+	})
+}
+
+func (suite *handlersSuite) TestTransferCreate() {
+	suite.Run("success", func() {
+		reqBody := `{"login":"user2","sum":100}`
+		suite.repo.On("UserGetByID", mock.Anything, uint64(1)).
+			Return(&models.User{ID: 1, Login: "user1"}, nil).Once()
+		suite.repo.On("UserGetByLogin", mock.Anything, "user2").
+			Return(&models.User{ID: 2, Login: "user2"}, nil).Once()
+		suite.repo.On("OperationTransfer", mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+			Return(nil).Once()
+		token := suite.validJWTToken(1)
+		res := suite.httpJSONRequest("POST", "/balance/transfer", reqBody, token)
+		defer res.Body.Close()
+		suite.Equal(http.StatusOK, res.StatusCode)
+		resBody := suite.getBody(res.Body)
+		suite.Equal(0, len(resBody))
+	})
+
+	suite.Run("recipient not found", func() {
+		reqBody := `{"login":"nobody","sum":100}`
+		suite.repo.On("UserGetByID", mock.Anything, uint64(1)).
+			Return(&models.User{ID: 1, Login: "user1"}, nil).Once()
+		suite.repo.On("UserGetByLogin", mock.Anything, "nobody").
+			Return(nil, errs.ErrNotFound).Once()
+		token := suite.validJWTToken(1)
+		res := suite.httpJSONRequest("POST", "/balance/transfer", reqBody, token)
+		defer res.Body.Close()
+		suite.Equal(http.StatusNotFound, res.StatusCode)
+		resJSON := suite.parseJSON(res.Body)
+		suite.Equal(1209., resJSON["code"])
+	})
+
+	suite.Run("transfer to self", func() {
+		reqBody := `{"login":"user1","sum":100}`
+		suite.repo.On("UserGetByID", mock.Anything, uint64(1)).
+			Return(&models.User{ID: 1, Login: "user1"}, nil).Once()
+		suite.repo.On("UserGetByLogin", mock.Anything, "user1").
+			Return(&models.User{ID: 1, Login: "user1"}, nil).Once()
+		token := suite.validJWTToken(1)
+		res := suite.httpJSONRequest("POST", "/balance/transfer", reqBody, token)
+		defer res.Body.Close()
+		suite.Equal(http.StatusBadRequest, res.StatusCode)
+		resJSON := suite.parseJSON(res.Body)
+		suite.Equal(1208., resJSON["code"])
+	})
+
+	suite.Run("invalid amount", func() {
+		reqBody := `{"login":"user2","sum":-100}`
+		token := suite.validJWTToken(1)
+		res := suite.httpJSONRequest("POST", "/balance/transfer", reqBody, token)
+		defer res.Body.Close()
+		suite.Equal(http.StatusBadRequest, res.StatusCode)
+		resJSON := suite.parseJSON(res.Body)
+		suite.Equal(1201., resJSON["code"])
+	})
+
+	suite.Run("daily limit exceeded", func() {
+		reqBody := `{"login":"user2","sum":100}`
+		suite.repo.On("UserGetByID", mock.Anything, uint64(1)).
+			Return(&models.User{ID: 1, Login: "user1"}, nil).Once()
+		suite.repo.On("UserGetByLogin", mock.Anything, "user2").
+			Return(&models.User{ID: 2, Login: "user2"}, nil).Once()
+		suite.repo.On("OperationTransfer", mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+			Return(errs.ErrTransferDailyAmountExceeded).Once()
+		token := suite.validJWTToken(1)
+		res := suite.httpJSONRequest("POST", "/balance/transfer", reqBody, token)
+		defer res.Body.Close()
+		suite.Equal(http.StatusUnprocessableEntity, res.StatusCode)
+		resJSON := suite.parseJSON(res.Body)
+		suite.Equal(1210., resJSON["code"])
+	})
+
+	suite.Run("bad request", func() {
+		reqBody := `{"notjson`
+		token := suite.validJWTToken(1)
+		res := suite.httpJSONRequest("POST", "/balance/transfer", reqBody, token)
+		defer res.Body.Close()
+		suite.Equal(http.StatusBadRequest, res.StatusCode)
+		resJSON := suite.parseJSON(res.Body)
+		suite.Equal(1003., resJSON["code"])
+	})
+}
+
 func (suite *handlersSuite) TestPromoAccrualCreate() {
 	suite.Run("success", func() {
 		suite.repo.On("PromoGetByCode", mock.Anything, "WELCOME2022").
@@ -231,7 +594,7 @@ func (suite *handlersSuite) TestPromoAccrualCreate() {
 
 func (suite *handlersSuite) TestOrderAccrualList() {
 	suite.Run("success", func() {
-		suite.repo.On("OperationGetByType", mock.Anything, uint64(1), models.OrderAccrual).
+		suite.repo.On("OperationGetByType", mock.Anything, uint64(1), models.OrderAccrual, mock.Anything).
 			Return([]*models.Operation{
 				{
 					ID:          1,
@@ -261,8 +624,42 @@ func (suite *handlersSuite) TestOrderAccrualList() {
 		suite.Equal(2, len(resJSON))
 	})
 
+	suite.Run("next page", func() {
+		created := time.Date(2022, 10, 1, 12, 0, 0, 0, time.UTC)
+		from := time.Date(2022, 9, 1, 0, 0, 0, 0, time.UTC)
+		status := models.StatusProcessed
+		suite.repo.On("OperationGetByType", mock.Anything, uint64(1), models.OrderAccrual,
+			&models.OperationQuery{Limit: 2, Status: &status, From: &from}).
+			Return([]*models.Operation{
+				{ID: 3, UserID: 1, OrderNumber: strPtr("12345678903"), Type: models.OrderAccrual, Status: status, CreatedAt: created},
+				{ID: 2, UserID: 1, OrderNumber: strPtr("12345678902"), Type: models.OrderAccrual, Status: status, CreatedAt: created},
+			}, nil).Once()
+
+		token := suite.validJWTToken(1)
+		res := suite.httpPlainTextRequest("GET", "/orders?limit=1&status=PROCESSED&from=2022-09-01", "", token)
+		defer res.Body.Close()
+		suite.Equal(http.StatusOK, res.StatusCode)
+		cursor := (&models.OperationCursor{Time: created, ID: 3}).String()
+		suite.Equal(cursor, res.Header.Get("X-Next-Cursor"))
+		suite.Equal(`</orders?cursor=`+cursor+`&from=2022-09-01&limit=1&status=PROCESSED>; rel="next"`, res.Header.Get("Link"))
+		resJSON := suite.parseJSONList(res.Body)
+		suite.Require().Len(resJSON, 1)
+		suite.Equal("12345678903", resJSON[0]["number"])
+	})
+
+	for _, query := range []string{"limit=0", "limit=1001", "cursor=invalid", "status=UNKNOWN", "type=order_accrual", "from=yesterday", "from=2022-10-01&to=2022-09-01"} {
+		suite.Run("invalid query "+query, func() {
+			token := suite.validJWTToken(1)
+			res := suite.httpPlainTextRequest("GET", "/orders?"+query, "", token)
+			defer res.Body.Close()
+			suite.Equal(http.StatusBadRequest, res.StatusCode)
+			resJSON := suite.parseJSON(res.Body)
+			suite.Equal(1008., resJSON["code"])
+		})
+	}
+
 	suite.Run("no content", func() {
-		suite.repo.On("OperationGetByType", mock.Anything, uint64(1), models.OrderAccrual).
+		suite.repo.On("OperationGetByType", mock.Anything, uint64(1), models.OrderAccrual, mock.Anything).
 			Return(nil, errs.ErrNotFound).Once()
 
 		token := suite.validJWTToken(1)
@@ -272,7 +669,7 @@ func (suite *handlersSuite) TestOrderAccrualList() {
 	})
 
 	suite.Run("internal error", func() {
-		suite.repo.On("OperationGetByType", mock.Anything, uint64(1), models.OrderAccrual).
+		suite.repo.On("OperationGetByType", mock.Anything, uint64(1), models.OrderAccrual, mock.Anything).
 			Return(nil, errs.ErrInternal).Once()
 
 		token := suite.validJWTToken(1)
@@ -286,7 +683,7 @@ func (suite *handlersSuite) TestOrderAccrualList() {
 
 func (suite *handlersSuite) TestOrderWithdrawalList() {
 	suite.Run("success", func() {
-		suite.repo.On("OperationGetByType", mock.Anything, uint64(1), models.OrderWithdrawal).
+		suite.repo.On("OperationGetByType", mock.Anything, uint64(1), models.OrderWithdrawal, mock.Anything).
 			Return([]*models.Operation{
 				{
 					ID:          1,
@@ -318,7 +715,7 @@ func (suite *handlersSuite) TestOrderWithdrawalList() {
 	})
 
 	suite.Run("no content", func() {
-		suite.repo.On("OperationGetByType", mock.Anything, uint64(1), models.OrderWithdrawal).
+		suite.repo.On("OperationGetByType", mock.Anything, uint64(1), models.OrderWithdrawal, mock.Anything).
 			Return(nil, errs.ErrNotFound).Once()
 
 		token := suite.validJWTToken(1)
@@ -328,7 +725,7 @@ func (suite *handlersSuite) TestOrderWithdrawalList() {
 	})
 
 	suite.Run("internal error", func() {
-		suite.repo.On("OperationGetByType", mock.Anything, uint64(1), models.OrderWithdrawal).
+		suite.repo.On("OperationGetByType", mock.Anything, uint64(1), models.OrderWithdrawal, mock.Anything).
 			Return(nil, errs.ErrInternal).Once()
 
 		token := suite.validJWTToken(1)
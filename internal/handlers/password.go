@@ -0,0 +1,109 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/go-chi/render"
+
+	"gophermart-loyalty/internal/errs"
+	"gophermart-loyalty/internal/middleware"
+)
+
+// passwordChange - смена пароля авторизованного пользователя.
+// Формат запроса:
+//    POST /api/user/password HTTP/1.1
+//    Content-Type: application/json
+//    Authorization: Bearer <token>
+//
+//    {
+//        "old_password": "<old password>",
+//        "new_password": "<new password>"
+//    }
+//
+// Возможные коды ответа:
+//    200 — пароль успешно изменен
+//    400 — неверный формат запроса или недопустимый новый пароль
+//    401 — пользователь не авторизован или неверный старый пароль
+//    500 — внутренняя ошибка сервера
+func (h *Handlers) passwordChange(w http.ResponseWriter, r *http.Request) {
+	// Получаем пользователя из контекста
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		_ = render.Render(w, r, errs.ErrResponseUnauthorized)
+		return
+	}
+
+	data := &PasswordChangeRequest{}
+	if err := render.Bind(r, data); err != nil {
+		_ = render.Render(w, r, errs.ErrResponseBadRequest)
+		return
+	}
+
+	// Меняем пароль
+	if err := h.useCases.UserPasswordChange(r.Context(), userID, data.OldPassword, data.NewPassword); err != nil {
+		_ = render.Render(w, r, errs.NewErrResponse(err))
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+// passwordReset - запрос на сброс пароля.
+// Пользователю отправляется одноразовый токен сброса пароля с ограниченным сроком действия.
+// Ответ не зависит от того, существует ли пользователь с указанным логином.
+// Формат запроса:
+//    POST /api/user/password/reset HTTP/1.1
+//    Content-Type: application/json
+//
+//    {
+//        "login": "<login>"
+//    }
+//
+// Возможные коды ответа:
+//    202 — запрос принят
+//    400 — неверный формат запроса
+//    500 — внутренняя ошибка сервера
+func (h *Handlers) passwordReset(w http.ResponseWriter, r *http.Request) {
+	data := &PasswordResetRequest{}
+	if err := render.Bind(r, data); err != nil {
+		_ = render.Render(w, r, errs.ErrResponseBadRequest)
+		return
+	}
+
+	// Выпускаем и отправляем токен сброса пароля
+	if err := h.useCases.PasswordResetRequest(r.Context(), data.Login, h.cfg.ResetTTL); err != nil {
+		_ = render.Render(w, r, errs.NewErrResponse(err))
+		return
+	}
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// passwordResetConfirm - установка нового пароля по токену сброса пароля.
+// После выполнения запроса все сессии и ранее выпущенные токены пользователя становятся недействительными.
+// Формат запроса:
+//    POST /api/user/password/reset/confirm HTTP/1.1
+//    Content-Type: application/json
+//
+//    {
+//        "token": "<reset token>",
+//        "password": "<new password>"
+//    }
+//
+// Возможные коды ответа:
+//    200 — пароль успешно изменен
+//    400 — неверный формат запроса, недопустимый пароль или
+//          токен сброса пароля недействителен, истек или уже был использован
+//    500 — внутренняя ошибка сервера
+func (h *Handlers) passwordResetConfirm(w http.ResponseWriter, r *http.Request) {
+	data := &PasswordResetConfirmRequest{}
+	if err := render.Bind(r, data); err != nil {
+		_ = render.Render(w, r, errs.ErrResponseBadRequest)
+		return
+	}
+
+	// Устанавливаем новый пароль
+	if err := h.useCases.PasswordResetConfirm(r.Context(), data.Token, data.Password); err != nil {
+		_ = render.Render(w, r, errs.NewErrResponse(err))
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
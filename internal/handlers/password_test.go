@@ -0,0 +1,118 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/stretchr/testify/mock"
+
+	"gophermart-loyalty/internal/errs"
+	"gophermart-loyalty/internal/models"
+)
+
+func (suite *handlersSuite) TestPasswordChange() {
+	passHash := suite.passHash("old-password")
+	user := &models.User{ID: 1, Login: "test", PassHash: passHash}
+
+	suite.Run("success", func() {
+		token := suite.validJWTToken(1)
+		suite.repo.On("UserGetByID", mock.Anything, uint64(1)).Return(user, nil).Once()
+		suite.repo.On("UserGetByLogin", mock.Anything, "test").Return(user, nil).Once()
+		suite.repo.On("UserUpdatePassHash", mock.Anything, uint64(1), mock.Anything).Return(nil).Once()
+
+		res := suite.httpJSONRequest(http.MethodPost, "/password",
+			`{"old_password":"old-password","new_password":"new-password"}`, token)
+		defer res.Body.Close()
+		suite.Equal(http.StatusOK, res.StatusCode)
+	})
+
+	suite.Run("wrong old password", func() {
+		token := suite.validJWTToken(1)
+		suite.repo.On("UserGetByID", mock.Anything, uint64(1)).Return(user, nil).Once()
+		suite.repo.On("UserGetByLogin", mock.Anything, "test").Return(user, nil).Once()
+
+		res := suite.httpJSONRequest(http.MethodPost, "/password",
+			`{"old_password":"wrong-password","new_password":"new-password"}`, token)
+		defer res.Body.Close()
+		suite.Equal(http.StatusUnauthorized, res.StatusCode)
+		resJSON := suite.parseJSON(res.Body)
+		suite.Equal(1103., resJSON["code"])
+	})
+
+	suite.Run("invalid new password", func() {
+		token := suite.validJWTToken(1)
+		res := suite.httpJSONRequest(http.MethodPost, "/password",
+			`{"old_password":"old-password","new_password":"1"}`, token)
+		defer res.Body.Close()
+		suite.Equal(http.StatusBadRequest, res.StatusCode)
+		resJSON := suite.parseJSON(res.Body)
+		suite.Equal(1102., resJSON["code"])
+	})
+
+	suite.Run("unauthorized", func() {
+		res := suite.httpJSONRequest(http.MethodPost, "/password",
+			`{"old_password":"old-password","new_password":"new-password"}`, "invalid token")
+		defer res.Body.Close()
+		suite.Equal(http.StatusUnauthorized, res.StatusCode)
+	})
+}
+
+func (suite *handlersSuite) TestPasswordReset() {
+	suite.Run("success", func() {
+		user := &models.User{ID: 1, Login: "test"}
+		suite.repo.On("UserGetByLogin", mock.Anything, "test").Return(user, nil).Once()
+		suite.repo.On("PasswordResetCreate", mock.Anything, mock.Anything, int64(600)).Return(nil).Once()
+		suite.sender.On("Send", mock.Anything, user, mock.Anything, mock.Anything).Return(nil).Once()
+
+		res := suite.httpJSONRequest(http.MethodPost, "/password/reset", `{"login":"test"}`, "")
+		defer res.Body.Close()
+		suite.Equal(http.StatusAccepted, res.StatusCode)
+	})
+
+	suite.Run("unknown user", func() {
+		suite.repo.On("UserGetByLogin", mock.Anything, "unknown").Return(nil, errs.ErrNotFound).Once()
+
+		res := suite.httpJSONRequest(http.MethodPost, "/password/reset", `{"login":"unknown"}`, "")
+		defer res.Body.Close()
+		suite.Equal(http.StatusAccepted, res.StatusCode)
+	})
+
+	suite.Run("invalid request body", func() {
+		res := suite.httpJSONRequest(http.MethodPost, "/password/reset", "invalid", "")
+		defer res.Body.Close()
+		suite.Equal(http.StatusBadRequest, res.StatusCode)
+	})
+}
+
+func (suite *handlersSuite) TestPasswordResetConfirm() {
+	suite.Run("success", func() {
+		suite.repo.On("PasswordResetConsume", mock.Anything, mock.Anything, mock.Anything).
+			Return(uint64(1), nil).Once()
+
+		res := suite.httpJSONRequest(http.MethodPost, "/password/reset/confirm",
+			`{"token":"reset-token","password":"new-password"}`, "")
+		defer res.Body.Close()
+		suite.Equal(http.StatusOK, res.StatusCode)
+	})
+
+	suite.Run("invalid token", func() {
+		suite.repo.On("PasswordResetConsume", mock.Anything, mock.Anything, mock.Anything).
+			Return(uint64(0), errs.ErrNotFound).Once()
+
+		res := suite.httpJSONRequest(http.MethodPost, "/password/reset/confirm",
+			`{"token":"used-token","password":"new-password"}`, "")
+		defer res.Body.Close()
+		suite.Equal(http.StatusBadRequest, res.StatusCode)
+		resJSON := suite.parseJSON(res.Body)
+		suite.Equal(1501., resJSON["code"])
+	})
+
+	suite.Run("invalid password", func() {
+		res := suite.httpJSONRequest(http.MethodPost, "/password/reset/confirm",
+			`{"token":"reset-token","password":"`+strings.Repeat("1", 513)+`"}`, "")
+		defer res.Body.Close()
+		suite.Equal(http.StatusBadRequest, res.StatusCode)
+		resJSON := suite.parseJSON(res.Body)
+		suite.Equal(1102., resJSON["code"])
+	})
+}
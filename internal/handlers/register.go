@@ -21,7 +21,7 @@ import (
 // Возможные коды ответа:
 //    200 — пользователь успешно зарегистрирован и аутентифицирован
 //    400 — неверный формат запроса
-//    409 — логин уже занят
+//    409 — логин уже занят или принадлежал недавно удаленной учетной записи
 //    500 — внутренняя ошибка сервера
 //
 // Формат ответа:
@@ -31,7 +31,8 @@ import (
 //    {
 //        "access_token": "<token>",
 //        "token_type": "Bearer",
-//        "expires_in": 3600
+//        "expires_in": 900,
+//        "refresh_token": "<refresh token>"
 //    }
 func (h *Handlers) register(w http.ResponseWriter, r *http.Request) {
 	data := &RegisterRequest{}
@@ -46,26 +47,13 @@ func (h *Handlers) register(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Генерируем токен
-	token, err := h.generateJWTToken(user.ID)
+	// Создаем сессию
+	session, refreshToken, err := h.useCases.SessionCreate(r.Context(), user.ID, h.cfg.RefreshTTL)
 	if err != nil {
-		h.log.Debug().Err(err).Msg("failed to generate token")
-		_ = render.Render(w, r, errs.ErrResponseInternal)
+		_ = render.Render(w, r, errs.NewErrResponse(err))
 		return
 	}
 
-	// !!! ВНИМАНИЕ !!!
-	// Передача заголовка Authorization в HTTP-ответе — это нестандартная реализация,
-	// не описанная в спецификации HTTP или в каком-либо протоколе авторизации.
-	// Заголовок Authorization должен использоваться только в HTTP-запросах.
-	// Тем не менее автотесты явно требуют наличие заголовка Authorization в ответе.
-	w.Header().Set("Authorization", token)
-
-	// Отправляем токен в ответе
-	render.Status(r, http.StatusOK)
-	_ = render.Render(w, r, &LoginResponse{
-		AccessToken: token,
-		TokenType:   "Bearer",
-		ExpiresIn:   int64(h.cfg.TTL.Seconds()),
-	})
+	// Отправляем токены в ответе
+	h.renderLoginResponse(w, r, user, session, refreshToken)
 }
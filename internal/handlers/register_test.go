@@ -14,6 +14,8 @@ func (suite *handlersSuite) TestRegister() {
 	suite.Run("success", func() {
 		suite.repo.On("UserCreate", mock.Anything, mock.Anything).
 			Return(nil).Once()
+		suite.repo.On("SessionCreate", mock.Anything, mock.Anything, int64(3600)).
+			Return(nil).Once()
 
 		res := suite.httpJSONRequest("POST", "/register", reqBody, "")
 		defer res.Body.Close()
@@ -22,6 +24,7 @@ func (suite *handlersSuite) TestRegister() {
 		suite.Equal("Bearer", resJSON["token_type"])
 		suite.NotEmpty(resJSON["access_token"])
 		suite.Equal(60., resJSON["expires_in"])
+		suite.NotEmpty(resJSON["refresh_token"])
 	})
 
 	suite.Run("failed to bind request", func() {
@@ -0,0 +1,69 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/go-chi/render"
+
+	"gophermart-loyalty/internal/errs"
+	"gophermart-loyalty/internal/middleware"
+)
+
+// logout - завершение текущей сессии пользователя.
+// После выполнения запроса access-токен и refresh-токен текущей сессии становятся недействительными.
+// Формат запроса:
+//    POST /api/user/logout HTTP/1.1
+//    Content-Length: 0
+//    Authorization: Bearer <token>
+//
+// Возможные коды ответа:
+//    200 — сессия успешно завершена
+//    401 — пользователь не авторизован
+//    500 — внутренняя ошибка сервера
+func (h *Handlers) logout(w http.ResponseWriter, r *http.Request) {
+	// Получаем пользователя и сессию из контекста
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		_ = render.Render(w, r, errs.ErrResponseUnauthorized)
+		return
+	}
+	sessionID, ok := middleware.GetSessionID(r.Context())
+	if !ok {
+		_ = render.Render(w, r, errs.ErrResponseUnauthorized)
+		return
+	}
+
+	// Отзываем сессию
+	if err := h.useCases.SessionRevoke(r.Context(), userID, sessionID); err != nil {
+		_ = render.Render(w, r, errs.NewErrResponse(err))
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+// sessionsRevokeAll - завершение всех сессий пользователя.
+// После выполнения запроса все ранее выпущенные токены пользователя, включая текущий, становятся недействительными.
+// Формат запроса:
+//    POST /api/user/sessions/revoke-all HTTP/1.1
+//    Content-Length: 0
+//    Authorization: Bearer <token>
+//
+// Возможные коды ответа:
+//    200 — все сессии успешно завершены
+//    401 — пользователь не авторизован
+//    500 — внутренняя ошибка сервера
+func (h *Handlers) sessionsRevokeAll(w http.ResponseWriter, r *http.Request) {
+	// Получаем пользователя из контекста
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		_ = render.Render(w, r, errs.ErrResponseUnauthorized)
+		return
+	}
+
+	// Отзываем все сессии пользователя
+	if err := h.useCases.SessionRevokeAll(r.Context(), userID); err != nil {
+		_ = render.Render(w, r, errs.NewErrResponse(err))
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
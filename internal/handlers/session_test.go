@@ -0,0 +1,63 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/stretchr/testify/mock"
+
+	"gophermart-loyalty/internal/errs"
+)
+
+func (suite *handlersSuite) TestLogout() {
+	suite.Run("success", func() {
+		suite.repo.On("SessionRevoke", mock.Anything, uint64(1), uint64(1)).
+			Return(nil).Once()
+		token := suite.validJWTToken(1)
+		res := suite.httpJSONRequest(http.MethodPost, "/logout", "", token)
+		defer res.Body.Close()
+		suite.Equal(http.StatusOK, res.StatusCode)
+	})
+
+	suite.Run("session already revoked", func() {
+		suite.repo.On("SessionRevoke", mock.Anything, uint64(1), uint64(1)).
+			Return(errs.ErrNotFound).Once()
+		token := suite.validJWTToken(1)
+		res := suite.httpJSONRequest(http.MethodPost, "/logout", "", token)
+		defer res.Body.Close()
+		suite.Equal(http.StatusUnauthorized, res.StatusCode)
+		resJSON := suite.parseJSON(res.Body)
+		suite.Equal(1500., resJSON["code"])
+	})
+
+	suite.Run("unauthorized", func() {
+		res := suite.httpJSONRequest(http.MethodPost, "/logout", "", "invalid token")
+		defer res.Body.Close()
+		suite.Equal(http.StatusUnauthorized, res.StatusCode)
+	})
+}
+
+func (suite *handlersSuite) TestSessionsRevokeAll() {
+	suite.Run("success", func() {
+		suite.repo.On("SessionRevokeAll", mock.Anything, uint64(1)).
+			Return(nil).Once()
+		token := suite.validJWTToken(1)
+		res := suite.httpJSONRequest(http.MethodPost, "/sessions/revoke-all", "", token)
+		defer res.Body.Close()
+		suite.Equal(http.StatusOK, res.StatusCode)
+	})
+
+	suite.Run("internal error", func() {
+		suite.repo.On("SessionRevokeAll", mock.Anything, uint64(1)).
+			Return(errs.ErrInternal).Once()
+		token := suite.validJWTToken(1)
+		res := suite.httpJSONRequest(http.MethodPost, "/sessions/revoke-all", "", token)
+		defer res.Body.Close()
+		suite.Equal(http.StatusInternalServerError, res.StatusCode)
+	})
+
+	suite.Run("unauthorized", func() {
+		res := suite.httpJSONRequest(http.MethodPost, "/sessions/revoke-all", "", "invalid token")
+		defer res.Body.Close()
+		suite.Equal(http.StatusUnauthorized, res.StatusCode)
+	})
+}
@@ -0,0 +1,196 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"html/template"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+	"github.com/shopspring/decimal"
+
+	"gophermart-loyalty/internal/errs"
+	"gophermart-loyalty/internal/middleware"
+	"gophermart-loyalty/internal/models"
+)
+
+// statementPeriodFmt - формат месяца выписки
+const statementPeriodFmt = "2006-01"
+
+// statementGet - получение выписки по накопительному счету за календарный месяц (UTC).
+// Формат запроса:
+//    GET /api/user/statements/{yyyy-mm}?format=json HTTP/1.1
+//    Content-Length: 0
+//    Authorization: Bearer <token>
+//
+// format — json (по умолчанию), csv или html (страница для печати).
+//
+// Возможные коды ответа:
+//    200 — успешная обработка запроса
+//    400 — неверный или еще не начавшийся месяц, неподдерживаемый формат
+//    401 — пользователь не авторизован
+//    500 — внутренняя ошибка сервера
+//
+// Формат ответа:
+//    HTTP/1.1 200 OK
+//    Content-Type: application/json
+//
+//    {
+//        "period": "2020-01",
+//        "from": "2020-01-01T00:00:00Z",
+//        "to": "2020-02-01T00:00:00Z",
+//        "opening_balance": 100,
+//        "accruals": 500.5,
+//        "withdrawals": 300,
+//        "expirations": 0,
+//        "transfers": 0,
+//        "adjustments": 0,
+//        "closing_balance": 300.5,
+//        "postings": [
+//            {
+//                "type": "order_accrual",
+//                "amount": 500.5,
+//                "number": "9278923470",
+//                "description": "Начисление баллов за заказ 9278923470",
+//                "posted_at": "2020-01-02T00:00:00Z"
+//            },
+//            {
+//                "type": "order_withdrawal",
+//                "amount": -300,
+//                "number": "12345678903",
+//                "description": "Списание баллов за заказ 12345678903",
+//                "posted_at": "2020-01-03T00:00:00Z"
+//            }
+//        ]
+//    }
+//
+// Выписка строится по журналу проводок, поэтому выписка за закрытый месяц не меняется.
+// Выписка в CSV возвращается файлом с колонками date, kind, number, description, amount:
+// входящий остаток, проводки в порядке записи, итоги по видам операций и исходящий остаток.
+func (h *Handlers) statementGet(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		_ = render.Render(w, r, errs.ErrResponseUnauthorized)
+		return
+	}
+
+	month, err := time.Parse(statementPeriodFmt, chi.URLParam(r, "month"))
+	if err != nil {
+		_ = render.Render(w, r, errs.NewErrResponse(errs.ErrStatementPeriodInvalid))
+		return
+	}
+	format := models.StatementFormat(r.URL.Query().Get("format"))
+	if format == "" {
+		format = models.StatementJSON
+	}
+	if !format.Valid() {
+		_ = render.Render(w, r, errs.NewErrResponse(errs.ErrStatementFormatInvalid))
+		return
+	}
+
+	s, err := h.useCases.StatementGet(r.Context(), userID, month)
+	if err != nil {
+		_ = render.Render(w, r, errs.NewErrResponse(err))
+		return
+	}
+
+	var (
+		buf                      bytes.Buffer
+		contentType, disposition string
+	)
+	switch format {
+	case models.StatementCSV:
+		contentType = "text/csv; charset=utf-8"
+		disposition = fmt.Sprintf(`attachment; filename="gophermart-statement-%s.csv"`, s.From.Format(statementPeriodFmt))
+		err = writeStatementCSV(&buf, s)
+	case models.StatementHTML:
+		contentType = "text/html; charset=utf-8"
+		err = statementHTML.Execute(&buf, newStatementResponse(s))
+	default:
+		_ = render.Render(w, r, newStatementResponse(s))
+		return
+	}
+	if err != nil {
+		_ = render.Render(w, r, errs.ErrResponseInternal)
+		return
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	if disposition != "" {
+		w.Header().Set("Content-Disposition", disposition)
+	}
+	w.Header().Set("Content-Length", strconv.Itoa(buf.Len()))
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(buf.Bytes())
+}
+
+// writeStatementCSV - записывает выписку в CSV: входящий остаток, проводки, итоги и исходящий остаток.
+func writeStatementCSV(buf *bytes.Buffer, s *models.Statement) error {
+	cw := csv.NewWriter(buf)
+	_ = cw.Write([]string{"date", "kind", "number", "description", "amount"})
+	_ = cw.Write([]string{s.From.Format(timeFmt), "opening_balance", "", "Входящий остаток", s.Opening.String()})
+	for _, p := range s.Postings {
+		number := ""
+		if p.Operation.OrderNumber != nil {
+			number = *p.Operation.OrderNumber
+		}
+		_ = cw.Write([]string{p.CreatedAt.Format(timeFmt), string(p.Operation.Type), number, p.Operation.Description, p.Amount.String()})
+	}
+	for _, total := range []struct {
+		kind, description string
+		amount            decimal.Decimal
+	}{
+		{"accruals", "Начислено", s.Accruals},
+		{"withdrawals", "Списано", s.Withdrawals},
+		{"expirations", "Сгорело", s.Expirations},
+		{"transfers", "Сальдо переводов", s.Transfers},
+		{"adjustments", "Сальдо корректировок", s.Adjustments},
+	} {
+		_ = cw.Write([]string{"", total.kind, "", total.description, total.amount.String()})
+	}
+	_ = cw.Write([]string{s.To.Format(timeFmt), "closing_balance", "", "Исходящий остаток", s.Closing.String()})
+	cw.Flush()
+	return cw.Error()
+}
+
+// statementHTML - страница выписки для печати
+var statementHTML = template.Must(template.New("statement").Parse(`<!DOCTYPE html>
+<html lang="ru">
+<head>
+<meta charset="utf-8">
+<title>Выписка по накопительному счету за {{.Period}}</title>
+<style>
+body { font-family: sans-serif; margin: 2em; }
+table { border-collapse: collapse; width: 100%; margin-bottom: 2em; }
+th, td { border: 1px solid #999; padding: 4px 8px; text-align: left; }
+td.amount { text-align: right; white-space: nowrap; }
+@media print { body { margin: 0; } }
+</style>
+</head>
+<body>
+<h1>Выписка по накопительному счету за {{.Period}}</h1>
+<p>Период: с {{.From}} по {{.To}} (не включая)</p>
+<table>
+<tr><th>Входящий остаток</th><td class="amount">{{.Opening}}</td></tr>
+<tr><th>Начислено</th><td class="amount">{{.Accruals}}</td></tr>
+<tr><th>Списано</th><td class="amount">{{.Withdrawals}}</td></tr>
+<tr><th>Сгорело</th><td class="amount">{{.Expirations}}</td></tr>
+<tr><th>Сальдо переводов</th><td class="amount">{{.Transfers}}</td></tr>
+<tr><th>Сальдо корректировок</th><td class="amount">{{.Adjustments}}</td></tr>
+<tr><th>Исходящий остаток</th><td class="amount">{{.Closing}}</td></tr>
+</table>
+<table>
+<tr><th>Дата</th><th>Заказ</th><th>Описание</th><th>Сумма</th></tr>
+{{- range .Postings}}
+<tr><td>{{.PostedAt}}</td><td>{{with .OrderNumber}}{{.}}{{end}}</td><td>{{.Description}}</td><td class="amount">{{.Amount}}</td></tr>
+{{- else}}
+<tr><td colspan="4">Проводок за период не было</td></tr>
+{{- end}}
+</table>
+</body>
+</html>
+`))
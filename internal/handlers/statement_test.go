@@ -0,0 +1,129 @@
+package handlers
+
+import (
+	"encoding/csv"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/mock"
+
+	"gophermart-loyalty/internal/errs"
+	"gophermart-loyalty/internal/models"
+)
+
+func (suite *handlersSuite) TestStatementGet() {
+	from := time.Date(2022, 10, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2022, 11, 1, 0, 0, 0, 0, time.UTC)
+	// mockStatement - настраивает репозиторий на выписку за октябрь 2022 года
+	mockStatement := func() {
+		suite.repo.On("UserBalanceAsOf", mock.Anything, uint64(1), from).
+			Return(&models.BalanceSnapshot{AsOf: from, Balance: decimal.NewFromInt(100)}, nil).Once()
+		suite.repo.On("UserLedgerPostingsGet", mock.Anything, uint64(1), from, to).
+			Return([]*models.LedgerPosting{
+				{
+					ID: 1, Account: models.LedgerAccrualSource, Amount: decimal.NewFromInt(500), CreatedAt: from.Add(24 * time.Hour),
+					Operation: &models.Operation{
+						ID: 1, UserID: 1, Type: models.OrderAccrual, Status: models.StatusProcessed, Amount: decimal.NewFromInt(500),
+						OrderNumber: strPtr("9278923470"), Description: "Начисление баллов за заказ 9278923470",
+					},
+				},
+				{
+					ID: 2, Account: models.LedgerWithdrawalSink, Amount: decimal.NewFromInt(-300), CreatedAt: from.Add(48 * time.Hour),
+					Operation: &models.Operation{
+						ID: 2, UserID: 1, Type: models.OrderWithdrawal, Status: models.StatusNew, Amount: decimal.NewFromInt(-300),
+						OrderNumber: strPtr("12345678903"), Description: "Списание баллов за заказ 12345678903",
+					},
+				},
+			}, nil).Once()
+	}
+
+	suite.Run("json", func() {
+		mockStatement()
+		token := suite.validJWTToken(1)
+		res := suite.httpJSONRequest(http.MethodGet, "/statements/2022-10", "", token)
+		defer res.Body.Close()
+		suite.Equal(http.StatusOK, res.StatusCode)
+		resJSON := suite.parseJSON(res.Body)
+		suite.Equal("2022-10", resJSON["period"])
+		suite.Equal("2022-10-01T00:00:00Z", resJSON["from"])
+		suite.Equal("2022-11-01T00:00:00Z", resJSON["to"])
+		suite.Equal(100., resJSON["opening_balance"])
+		suite.Equal(500., resJSON["accruals"])
+		suite.Equal(300., resJSON["withdrawals"])
+		suite.Equal(0., resJSON["expirations"])
+		suite.Equal(300., resJSON["closing_balance"])
+		postings, ok := resJSON["postings"].([]interface{})
+		suite.Require().True(ok)
+		suite.Require().Len(postings, 2)
+		suite.Equal("order_accrual", postings[0].(map[string]interface{})["type"])
+		// проводка по списанию записывается при создании списания, до завершения его обработки
+		suite.Equal("2022-10-03T00:00:00Z", postings[1].(map[string]interface{})["posted_at"])
+	})
+
+	suite.Run("csv", func() {
+		mockStatement()
+		token := suite.validJWTToken(1)
+		res := suite.httpJSONRequest(http.MethodGet, "/statements/2022-10?format=csv", "", token)
+		defer res.Body.Close()
+		suite.Equal(http.StatusOK, res.StatusCode)
+		suite.Equal("text/csv; charset=utf-8", res.Header.Get("Content-Type"))
+		suite.Equal(`attachment; filename="gophermart-statement-2022-10.csv"`, res.Header.Get("Content-Disposition"))
+		records, err := csv.NewReader(res.Body).ReadAll()
+		suite.Require().NoError(err)
+		suite.Require().Len(records, 10)
+		suite.Equal([]string{"date", "kind", "number", "description", "amount"}, records[0])
+		suite.Equal([]string{"2022-10-01T00:00:00Z", "opening_balance", "", "Входящий остаток", "100"}, records[1])
+		suite.Equal("9278923470", records[2][2])
+		suite.Equal("-300", records[3][4])
+		suite.Equal("2022-10-03T00:00:00Z", records[3][0])
+		suite.Equal([]string{"2022-11-01T00:00:00Z", "closing_balance", "", "Исходящий остаток", "300"}, records[9])
+	})
+
+	suite.Run("html", func() {
+		mockStatement()
+		token := suite.validJWTToken(1)
+		res := suite.httpJSONRequest(http.MethodGet, "/statements/2022-10?format=html", "", token)
+		defer res.Body.Close()
+		suite.Equal(http.StatusOK, res.StatusCode)
+		suite.Equal("text/html; charset=utf-8", res.Header.Get("Content-Type"))
+		body, err := io.ReadAll(res.Body)
+		suite.Require().NoError(err)
+		suite.True(strings.Contains(string(body), "Выписка по накопительному счету за 2022-10"))
+		suite.True(strings.Contains(string(body), "Списание баллов за заказ 12345678903"))
+	})
+
+	suite.Run("invalid month", func() {
+		token := suite.validJWTToken(1)
+		res := suite.httpJSONRequest(http.MethodGet, "/statements/2022-13", "", token)
+		defer res.Body.Close()
+		suite.Equal(http.StatusBadRequest, res.StatusCode)
+		suite.Equal(1112., suite.parseJSON(res.Body)["code"])
+	})
+
+	suite.Run("future month", func() {
+		token := suite.validJWTToken(1)
+		res := suite.httpJSONRequest(http.MethodGet, "/statements/2999-01", "", token)
+		defer res.Body.Close()
+		suite.Equal(http.StatusBadRequest, res.StatusCode)
+		suite.Equal(1112., suite.parseJSON(res.Body)["code"])
+	})
+
+	suite.Run("invalid format", func() {
+		token := suite.validJWTToken(1)
+		res := suite.httpJSONRequest(http.MethodGet, "/statements/2022-10?format=pdf", "", token)
+		defer res.Body.Close()
+		suite.Equal(http.StatusBadRequest, res.StatusCode)
+		suite.Equal(1113., suite.parseJSON(res.Body)["code"])
+	})
+
+	suite.Run("internal error", func() {
+		suite.repo.On("UserBalanceAsOf", mock.Anything, uint64(1), from).Return(nil, errs.ErrInternal).Once()
+		token := suite.validJWTToken(1)
+		res := suite.httpJSONRequest(http.MethodGet, "/statements/2022-10", "", token)
+		defer res.Body.Close()
+		suite.Equal(http.StatusInternalServerError, res.StatusCode)
+	})
+}
@@ -0,0 +1,61 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/go-chi/render"
+
+	"gophermart-loyalty/internal/errs"
+)
+
+// tokenRefresh - обмен refresh-токена на новую пару токенов.
+// Использованный refresh-токен становится недействительным.
+// Формат запроса:
+//    POST /api/user/token/refresh HTTP/1.1
+//    Content-Type: application/json
+//
+//    {
+//        "refresh_token": "<refresh token>"
+//    }
+//
+// Возможные коды ответа:
+//    200 — токены успешно обновлены
+//    400 — неверный формат запроса
+//    401 — refresh-токен недействителен, истек или уже был использован
+//    500 — внутренняя ошибка сервера
+//
+// Формат ответа:
+//    HTTP/1.1 200 OK
+//    Content-Type: application/json
+//
+//    {
+//        "access_token": "<token>",
+//        "token_type": "Bearer",
+//        "expires_in": 900,
+//        "refresh_token": "<refresh token>"
+//    }
+func (h *Handlers) tokenRefresh(w http.ResponseWriter, r *http.Request) {
+	data := &TokenRefreshRequest{}
+	if err := render.Bind(r, data); err != nil {
+		_ = render.Render(w, r, errs.ErrResponseBadRequest)
+		return
+	}
+
+	// Обновляем сессию
+	session, refreshToken, err := h.useCases.SessionRefresh(r.Context(), data.RefreshToken, h.cfg.RefreshTTL)
+	if err != nil {
+		h.log.Debug().Err(err).Msg("failed to refresh session")
+		_ = render.Render(w, r, errs.NewErrResponse(err))
+		return
+	}
+
+	// Получаем пользователя, чтобы токен содержал его актуальную роль
+	user, err := h.useCases.UserGetByID(r.Context(), session.UserID)
+	if err != nil {
+		_ = render.Render(w, r, errs.NewErrResponse(err))
+		return
+	}
+
+	// Отправляем токены в ответе
+	h.renderLoginResponse(w, r, user, session, refreshToken)
+}
@@ -0,0 +1,60 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/stretchr/testify/mock"
+
+	"gophermart-loyalty/internal/errs"
+	"gophermart-loyalty/internal/models"
+)
+
+func (suite *handlersSuite) TestTokenRefresh() {
+	suite.Run("success", func() {
+		suite.repo.On("SessionRotate", mock.Anything, mock.Anything, mock.Anything, int64(3600)).
+			Return(&models.Session{ID: 5, UserID: 1}, nil).Once()
+		suite.repo.On("UserGetByID", mock.Anything, uint64(1)).
+			Return(&models.User{ID: 1, Role: models.RoleSupport}, nil).Once()
+
+		res := suite.httpJSONRequest(http.MethodPost, "/token/refresh", `{"refresh_token":"old"}`, "")
+		defer res.Body.Close()
+		suite.Equal(http.StatusOK, res.StatusCode)
+		resJSON := suite.parseJSON(res.Body)
+		suite.Equal("Bearer", resJSON["token_type"])
+		suite.NotEmpty(resJSON["refresh_token"])
+		suite.NotEqual("old", resJSON["refresh_token"])
+
+		// проверяем токен
+		token, err := jwt.Parse(resJSON["access_token"].(string), func(token *jwt.Token) (interface{}, error) {
+			return []byte(suite.cfg.SigningKey), nil
+		})
+		suite.NoError(err)
+		suite.Equal(1., token.Claims.(jwt.MapClaims)["sub"])
+		suite.Equal("5", token.Claims.(jwt.MapClaims)["jti"])
+		suite.Equal("support", token.Claims.(jwt.MapClaims)["role"])
+	})
+
+	suite.Run("invalid refresh token", func() {
+		suite.repo.On("SessionRotate", mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+			Return(nil, errs.ErrNotFound).Once()
+
+		res := suite.httpJSONRequest(http.MethodPost, "/token/refresh", `{"refresh_token":"used"}`, "")
+		defer res.Body.Close()
+		suite.Equal(http.StatusUnauthorized, res.StatusCode)
+		resJSON := suite.parseJSON(res.Body)
+		suite.Equal(1500., resJSON["code"])
+	})
+
+	suite.Run("empty refresh token", func() {
+		res := suite.httpJSONRequest(http.MethodPost, "/token/refresh", `{}`, "")
+		defer res.Body.Close()
+		suite.Equal(http.StatusUnauthorized, res.StatusCode)
+	})
+
+	suite.Run("invalid request body", func() {
+		res := suite.httpJSONRequest(http.MethodPost, "/token/refresh", "invalid", "")
+		defer res.Body.Close()
+		suite.Equal(http.StatusBadRequest, res.StatusCode)
+	})
+}
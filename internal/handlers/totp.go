@@ -0,0 +1,173 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/go-chi/render"
+
+	"gophermart-loyalty/internal/errs"
+	"gophermart-loyalty/internal/middleware"
+)
+
+// loginTOTP - завершение входа кодом второго фактора.
+// Вместо кода из приложения-аутентификатора можно передать неиспользованный код восстановления.
+// Формат запроса:
+//    POST /api/user/login/2fa HTTP/1.1
+//    Content-Type: application/json
+//
+//    {
+//        "challenge": "<challenge token>",
+//        "code": "123456"
+//    }
+//
+// Возможные коды ответа:
+//    200 — пользователь успешно аутентифицирован
+//    400 — неверный формат запроса
+//    401 — неверный код или незавершенный вход не найден, истек или превышено число попыток
+//    423 — учетная запись временно заблокирована после неудачных попыток входа
+//    429 — слишком много неудачных попыток входа, попытку нужно повторить позже
+//    500 — внутренняя ошибка сервера
+//
+// Формат ответа совпадает с ответом POST /api/user/login.
+func (h *Handlers) loginTOTP(w http.ResponseWriter, r *http.Request) {
+	data := &LoginTOTPRequest{}
+	if err := render.Bind(r, data); err != nil {
+		_ = render.Render(w, r, errs.ErrResponseBadRequest)
+		return
+	}
+
+	user, err := h.useCases.LoginChallengeVerify(r.Context(), data.Challenge, data.Code, clientIP(r), &h.cfg.Throttle)
+	if err != nil {
+		h.log.Debug().Err(err).Msg("failed to verify second factor")
+		_ = render.Render(w, r, errs.NewErrResponse(err))
+		return
+	}
+
+	h.createSession(w, r, user)
+}
+
+// totpEnroll - подключение двухфакторной аутентификации.
+// Генерирует новый секрет, который нужно добавить в приложение-аутентификатор
+// и подтвердить кодом в POST /api/user/2fa/totp/confirm. До подтверждения вход выполняется без кода.
+// Формат запроса:
+//    POST /api/user/2fa/totp HTTP/1.1
+//    Content-Length: 0
+//    Authorization: Bearer <token>
+//
+// Возможные коды ответа:
+//    200 — секрет успешно создан
+//    401 — пользователь не авторизован
+//    403 — запрос выполнен с персональным токеном
+//    409 — двухфакторная аутентификация уже включена
+//    500 — внутренняя ошибка сервера
+//
+// Формат ответа:
+//    HTTP/1.1 200 OK
+//    Content-Type: application/json
+//
+//    {
+//        "secret": "JBSWY3DPEHPK3PXP",
+//        "uri": "otpauth://totp/Gophermart:user?algorithm=SHA1&digits=6&issuer=Gophermart&period=30&secret=JBSWY3DPEHPK3PXP"
+//    }
+func (h *Handlers) totpEnroll(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		_ = render.Render(w, r, errs.ErrResponseUnauthorized)
+		return
+	}
+
+	secret, uri, err := h.useCases.TOTPEnroll(r.Context(), userID, h.cfg.TOTPIssuer)
+	if err != nil {
+		_ = render.Render(w, r, errs.NewErrResponse(err))
+		return
+	}
+
+	render.Status(r, http.StatusOK)
+	_ = render.Render(w, r, &TOTPEnrollResponse{Secret: secret, URI: uri})
+}
+
+// totpConfirm - включение двухфакторной аутентификации.
+// Коды восстановления возвращаются только в ответе на этот запрос, в БД хранятся только их хэши.
+// Формат запроса:
+//    POST /api/user/2fa/totp/confirm HTTP/1.1
+//    Content-Type: application/json
+//    Authorization: Bearer <token>
+//
+//    {
+//        "code": "123456"
+//    }
+//
+// Возможные коды ответа:
+//    200 — двухфакторная аутентификация включена
+//    400 — неверный формат запроса
+//    401 — пользователь не авторизован или неверный код
+//    403 — запрос выполнен с персональным токеном
+//    409 — двухфакторная аутентификация уже включена или не подключалась
+//    500 — внутренняя ошибка сервера
+//
+// Формат ответа:
+//    HTTP/1.1 200 OK
+//    Content-Type: application/json
+//
+//    {
+//        "recovery_codes": ["abcd-efgh", ...]
+//    }
+func (h *Handlers) totpConfirm(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		_ = render.Render(w, r, errs.ErrResponseUnauthorized)
+		return
+	}
+
+	data := &TOTPConfirmRequest{}
+	if err := render.Bind(r, data); err != nil {
+		_ = render.Render(w, r, errs.ErrResponseBadRequest)
+		return
+	}
+
+	codes, err := h.useCases.TOTPConfirm(r.Context(), userID, data.Code)
+	if err != nil {
+		_ = render.Render(w, r, errs.NewErrResponse(err))
+		return
+	}
+
+	render.Status(r, http.StatusOK)
+	_ = render.Render(w, r, &TOTPConfirmResponse{RecoveryCodes: codes})
+}
+
+// totpDisable - отключение двухфакторной аутентификации.
+// Формат запроса:
+//    DELETE /api/user/2fa/totp HTTP/1.1
+//    Content-Type: application/json
+//    Authorization: Bearer <token>
+//
+//    {
+//        "password": "<password>"
+//    }
+//
+// Возможные коды ответа:
+//    200 — двухфакторная аутентификация отключена
+//    400 — неверный формат запроса
+//    401 — пользователь не авторизован или неверный пароль
+//    403 — запрос выполнен с персональным токеном
+//    409 — двухфакторная аутентификация не подключалась
+//    500 — внутренняя ошибка сервера
+func (h *Handlers) totpDisable(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		_ = render.Render(w, r, errs.ErrResponseUnauthorized)
+		return
+	}
+
+	data := &TOTPDisableRequest{}
+	if err := render.Bind(r, data); err != nil {
+		_ = render.Render(w, r, errs.ErrResponseBadRequest)
+		return
+	}
+
+	if err := h.useCases.TOTPDisable(r.Context(), userID, data.Password); err != nil {
+		_ = render.Render(w, r, errs.NewErrResponse(err))
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
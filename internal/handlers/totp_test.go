@@ -0,0 +1,213 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+
+	"gophermart-loyalty/internal/errs"
+	"gophermart-loyalty/internal/models"
+	"gophermart-loyalty/pkg/totp"
+)
+
+const testTOTPSecret = "JBSWY3DPEHPK3PXP"
+
+func (suite *handlersSuite) TestLoginTOTP() {
+	enabled := &models.TOTP{UserID: 1, Secret: testTOTPSecret, ConfirmedAt: &time.Time{}}
+	challenge := &models.LoginChallenge{ID: 3, UserID: 1}
+
+	suite.Run("success", func() {
+		step := totp.Step(time.Now())
+		code, err := totp.Code(testTOTPSecret, step)
+		suite.Require().NoError(err)
+
+		suite.repo.On("LoginChallengeGet", mock.Anything, mock.Anything, 5).Return(challenge, nil).Once()
+		suite.repo.On("UserGetByID", mock.Anything, uint64(1)).Return(&models.User{ID: 1, Login: "test"}, nil).Once()
+		suite.loginAttempt("test", 1, true)
+		suite.repo.On("TOTPGet", mock.Anything, uint64(1)).Return(enabled, nil).Once()
+		suite.repo.On("TOTPUseStep", mock.Anything, uint64(1), step).Return(nil).Once()
+		suite.repo.On("LoginChallengeConsume", mock.Anything, uint64(3)).Return(nil).Once()
+		suite.repo.On("LoginFailureReset", mock.Anything, "login:test").Return(nil).Once()
+		suite.repo.On("SessionCreate", mock.Anything, mock.Anything, int64(3600)).Return(nil).Once()
+
+		res := suite.httpJSONRequest(http.MethodPost, "/login/2fa", `{"challenge":"abc","code":"`+code+`"}`, "")
+		defer res.Body.Close()
+		suite.Equal(http.StatusOK, res.StatusCode)
+		resJSON := suite.parseJSON(res.Body)
+		suite.NotEmpty(resJSON["access_token"])
+		suite.NotEmpty(resJSON["refresh_token"])
+	})
+
+	suite.Run("recovery code", func() {
+		suite.repo.On("LoginChallengeGet", mock.Anything, mock.Anything, 5).Return(challenge, nil).Once()
+		suite.repo.On("UserGetByID", mock.Anything, uint64(1)).Return(&models.User{ID: 1, Login: "test"}, nil).Once()
+		suite.loginAttempt("test", 1, true)
+		suite.repo.On("TOTPGet", mock.Anything, uint64(1)).Return(enabled, nil).Once()
+		suite.repo.On("TOTPRecoveryCodeUse", mock.Anything, uint64(1), mock.Anything).Return(nil).Once()
+		suite.repo.On("LoginChallengeConsume", mock.Anything, uint64(3)).Return(nil).Once()
+		suite.repo.On("LoginFailureReset", mock.Anything, "login:test").Return(nil).Once()
+		suite.repo.On("SessionCreate", mock.Anything, mock.Anything, int64(3600)).Return(nil).Once()
+
+		res := suite.httpJSONRequest(http.MethodPost, "/login/2fa", `{"challenge":"abc","code":"abcd-efgh"}`, "")
+		defer res.Body.Close()
+		suite.Equal(http.StatusOK, res.StatusCode)
+	})
+
+	suite.Run("invalid code", func() {
+		suite.repo.On("LoginChallengeGet", mock.Anything, mock.Anything, 5).Return(challenge, nil).Once()
+		suite.repo.On("UserGetByID", mock.Anything, uint64(1)).Return(&models.User{ID: 1, Login: "test"}, nil).Once()
+		suite.loginAttempt("test", 1, false)
+		suite.repo.On("TOTPGet", mock.Anything, uint64(1)).Return(enabled, nil).Once()
+		suite.repo.On("TOTPRecoveryCodeUse", mock.Anything, uint64(1), mock.Anything).Return(errs.ErrNotFound).Once()
+		suite.repo.On("LoginChallengeFail", mock.Anything, uint64(3)).Return(nil).Once()
+		suite.repo.On("LoginFailureBlock", mock.Anything, "login:test", int64(0)).
+			Return(&models.LoginFailure{Key: "login:test", Failures: 1}, nil).Once()
+		suite.repo.On("LoginFailureBlock", mock.Anything, "ip:127.0.0.1", int64(0)).
+			Return(&models.LoginFailure{Key: "ip:127.0.0.1", Failures: 1}, nil).Once()
+
+		res := suite.httpJSONRequest(http.MethodPost, "/login/2fa", `{"challenge":"abc","code":"wrong-code"}`, "")
+		defer res.Body.Close()
+		suite.Equal(http.StatusUnauthorized, res.StatusCode)
+		resJSON := suite.parseJSON(res.Body)
+		suite.Equal(1509., resJSON["code"])
+	})
+
+	suite.Run("login locked after failed codes", func() {
+		suite.repo.On("LoginChallengeGet", mock.Anything, mock.Anything, 5).Return(challenge, nil).Once()
+		suite.repo.On("UserGetByID", mock.Anything, uint64(1)).Return(&models.User{ID: 1, Login: "test"}, nil).Once()
+		suite.loginAttemptBlocked("login:test", 10)
+
+		res := suite.httpJSONRequest(http.MethodPost, "/login/2fa", `{"challenge":"abc","code":"123456"}`, "")
+		defer res.Body.Close()
+		suite.Equal(http.StatusLocked, res.StatusCode)
+	})
+
+	suite.Run("invalid challenge", func() {
+		suite.repo.On("LoginChallengeGet", mock.Anything, mock.Anything, 5).Return(nil, errs.ErrNotFound).Once()
+
+		res := suite.httpJSONRequest(http.MethodPost, "/login/2fa", `{"challenge":"abc","code":"123456"}`, "")
+		defer res.Body.Close()
+		suite.Equal(http.StatusUnauthorized, res.StatusCode)
+		resJSON := suite.parseJSON(res.Body)
+		suite.Equal(1510., resJSON["code"])
+	})
+
+	suite.Run("invalid request body", func() {
+		res := suite.httpJSONRequest(http.MethodPost, "/login/2fa", "invalid", "")
+		defer res.Body.Close()
+		suite.Equal(http.StatusBadRequest, res.StatusCode)
+	})
+}
+
+func (suite *handlersSuite) TestTOTPEnroll() {
+	suite.Run("success", func() {
+		token := suite.validJWTToken(1)
+		suite.repo.On("UserGetByID", mock.Anything, uint64(1)).Return(&models.User{ID: 1, Login: "test"}, nil).Once()
+		suite.repo.On("TOTPEnroll", mock.Anything, mock.MatchedBy(func(t *models.TOTP) bool {
+			return t.UserID == 1 && t.Secret != ""
+		})).Return(nil).Once()
+
+		res := suite.httpRequest(http.MethodPost, "/2fa/totp", "", "", token)
+		defer res.Body.Close()
+		suite.Equal(http.StatusOK, res.StatusCode)
+		resJSON := suite.parseJSON(res.Body)
+		suite.NotEmpty(resJSON["secret"])
+		suite.True(strings.HasPrefix(resJSON["uri"].(string), "otpauth://totp/Gophermart:test?"))
+	})
+
+	suite.Run("already enabled", func() {
+		token := suite.validJWTToken(1)
+		suite.repo.On("UserGetByID", mock.Anything, uint64(1)).Return(&models.User{ID: 1, Login: "test"}, nil).Once()
+		suite.repo.On("TOTPEnroll", mock.Anything, mock.Anything).Return(errs.ErrNotFound).Once()
+
+		res := suite.httpRequest(http.MethodPost, "/2fa/totp", "", "", token)
+		defer res.Body.Close()
+		suite.Equal(http.StatusConflict, res.StatusCode)
+		resJSON := suite.parseJSON(res.Body)
+		suite.Equal(1507., resJSON["code"])
+	})
+
+	suite.Run("access token can not enroll", func() {
+		token := suite.validAccessToken(1, models.ScopeOrdersRead)
+		res := suite.httpRequest(http.MethodPost, "/2fa/totp", "", "", token)
+		defer res.Body.Close()
+		suite.Equal(http.StatusForbidden, res.StatusCode)
+	})
+}
+
+func (suite *handlersSuite) TestTOTPConfirm() {
+	pending := &models.TOTP{UserID: 1, Secret: testTOTPSecret}
+
+	suite.Run("success", func() {
+		step := totp.Step(time.Now())
+		code, err := totp.Code(testTOTPSecret, step)
+		suite.Require().NoError(err)
+
+		token := suite.validJWTToken(1)
+		suite.repo.On("TOTPGet", mock.Anything, uint64(1)).Return(pending, nil).Once()
+		suite.repo.On("TOTPConfirm", mock.Anything, uint64(1), step, mock.MatchedBy(func(hashes []string) bool {
+			return len(hashes) == 10
+		})).Return(nil).Once()
+
+		res := suite.httpJSONRequest(http.MethodPost, "/2fa/totp/confirm", `{"code":"`+code+`"}`, token)
+		defer res.Body.Close()
+		suite.Equal(http.StatusOK, res.StatusCode)
+		resJSON := suite.parseJSON(res.Body)
+		suite.Len(resJSON["recovery_codes"], 10)
+	})
+
+	suite.Run("invalid code", func() {
+		token := suite.validJWTToken(1)
+		suite.repo.On("TOTPGet", mock.Anything, uint64(1)).Return(pending, nil).Once()
+
+		res := suite.httpJSONRequest(http.MethodPost, "/2fa/totp/confirm", `{"code":"12345"}`, token)
+		defer res.Body.Close()
+		suite.Equal(http.StatusUnauthorized, res.StatusCode)
+		resJSON := suite.parseJSON(res.Body)
+		suite.Equal(1509., resJSON["code"])
+	})
+
+	suite.Run("not enrolled", func() {
+		token := suite.validJWTToken(1)
+		suite.repo.On("TOTPGet", mock.Anything, uint64(1)).Return(nil, errs.ErrNotFound).Once()
+
+		res := suite.httpJSONRequest(http.MethodPost, "/2fa/totp/confirm", `{"code":"123456"}`, token)
+		defer res.Body.Close()
+		suite.Equal(http.StatusConflict, res.StatusCode)
+		resJSON := suite.parseJSON(res.Body)
+		suite.Equal(1508., resJSON["code"])
+	})
+}
+
+func (suite *handlersSuite) TestTOTPDisable() {
+	passHash := suite.passHash("test")
+
+	suite.Run("success", func() {
+		token := suite.validJWTToken(1)
+		suite.repo.On("UserGetByID", mock.Anything, uint64(1)).
+			Return(&models.User{ID: 1, Login: "test", PassHash: passHash}, nil).Once()
+		suite.repo.On("UserGetByLogin", mock.Anything, "test").
+			Return(&models.User{ID: 1, Login: "test", PassHash: passHash}, nil).Once()
+		suite.repo.On("TOTPDelete", mock.Anything, uint64(1)).Return(nil).Once()
+
+		res := suite.httpJSONRequest(http.MethodDelete, "/2fa/totp", `{"password":"test"}`, token)
+		defer res.Body.Close()
+		suite.Equal(http.StatusOK, res.StatusCode)
+	})
+
+	suite.Run("wrong password", func() {
+		token := suite.validJWTToken(1)
+		suite.repo.On("UserGetByID", mock.Anything, uint64(1)).
+			Return(&models.User{ID: 1, Login: "test", PassHash: passHash}, nil).Once()
+		suite.repo.On("UserGetByLogin", mock.Anything, "test").
+			Return(&models.User{ID: 1, Login: "test", PassHash: passHash}, nil).Once()
+
+		res := suite.httpJSONRequest(http.MethodDelete, "/2fa/totp", `{"password":"wrong"}`, token)
+		defer res.Body.Close()
+		suite.Equal(http.StatusUnauthorized, res.StatusCode)
+		resJSON := suite.parseJSON(res.Body)
+		suite.Equal(1103., resJSON["code"])
+	})
+}
@@ -0,0 +1,49 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/go-chi/render"
+
+	"gophermart-loyalty/internal/errs"
+	"gophermart-loyalty/internal/middleware"
+)
+
+// userDelete - удаление учетной записи пользователя.
+// Остаток баланса списывается операцией закрытия счета, незавершенные операции отменяются,
+// операции сохраняются для учета, а логин и учетные данные обезличиваются.
+// Все сессии и токены пользователя становятся недействительными.
+// Формат запроса:
+//    DELETE /api/user HTTP/1.1
+//    Content-Type: application/json
+//    Authorization: Bearer <token>
+//
+//    {
+//        "password": "<password>"
+//    }
+//
+// Возможные коды ответа:
+//    200 — учетная запись удалена
+//    400 — неверный формат запроса
+//    401 — пользователь не авторизован или неверный пароль
+//    403 — запрос выполнен с персональным токеном
+//    500 — внутренняя ошибка сервера
+func (h *Handlers) userDelete(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserID(r.Context())
+	if !ok {
+		_ = render.Render(w, r, errs.ErrResponseUnauthorized)
+		return
+	}
+
+	data := &UserDeleteRequest{}
+	if err := render.Bind(r, data); err != nil {
+		_ = render.Render(w, r, errs.ErrResponseBadRequest)
+		return
+	}
+
+	if err := h.useCases.UserDelete(r.Context(), userID, data.Password, h.cfg.LoginReuse); err != nil {
+		_ = render.Render(w, r, errs.NewErrResponse(err))
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
@@ -0,0 +1,56 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/mock"
+
+	"gophermart-loyalty/internal/models"
+)
+
+func (suite *handlersSuite) TestUserDelete() {
+	passHash := suite.passHash("test")
+
+	suite.Run("success", func() {
+		token := suite.validJWTToken(1)
+		suite.repo.On("UserGetByID", mock.Anything, uint64(1)).
+			Return(&models.User{ID: 1, Login: "test", PassHash: passHash}, nil).Once()
+		suite.repo.On("UserGetByLogin", mock.Anything, "test").
+			Return(&models.User{ID: 1, Login: "test", PassHash: passHash}, nil).Once()
+		suite.repo.On("UserDelete", mock.Anything, uint64(1), mock.Anything, int64(86400)).
+			Return(&models.Operation{ID: 7, Type: models.AccountClosure, Amount: decimal.NewFromInt(-10)}, nil).Once()
+
+		res := suite.httpJSONRequest(http.MethodDelete, "/", `{"password":"test"}`, token)
+		defer res.Body.Close()
+		suite.Equal(http.StatusOK, res.StatusCode)
+	})
+
+	suite.Run("wrong password", func() {
+		token := suite.validJWTToken(1)
+		suite.repo.On("UserGetByID", mock.Anything, uint64(1)).
+			Return(&models.User{ID: 1, Login: "test", PassHash: passHash}, nil).Once()
+		suite.repo.On("UserGetByLogin", mock.Anything, "test").
+			Return(&models.User{ID: 1, Login: "test", PassHash: passHash}, nil).Once()
+
+		res := suite.httpJSONRequest(http.MethodDelete, "/", `{"password":"wrong"}`, token)
+		defer res.Body.Close()
+		suite.Equal(http.StatusUnauthorized, res.StatusCode)
+		resJSON := suite.parseJSON(res.Body)
+		suite.Equal(1103., resJSON["code"])
+	})
+
+	suite.Run("invalid request body", func() {
+		token := suite.validJWTToken(1)
+		res := suite.httpJSONRequest(http.MethodDelete, "/", "invalid", token)
+		defer res.Body.Close()
+		suite.Equal(http.StatusBadRequest, res.StatusCode)
+	})
+
+	suite.Run("access token can not delete account", func() {
+		token := suite.validAccessToken(1, models.ScopeOrdersRead)
+		res := suite.httpJSONRequest(http.MethodDelete, "/", `{"password":"test"}`, token)
+		defer res.Body.Close()
+		suite.Equal(http.StatusForbidden, res.StatusCode)
+	})
+}
@@ -25,23 +25,28 @@ const (
 	AccrualRunning
 )
 
+// accrualStatusRegistered - статус системы начисления, которого нет среди статусов операций
+const accrualStatusRegistered models.OperationStatus = "REGISTERED"
+
 // IntegrationAccrual - интеграция с системой начисления бонусов
 type IntegrationAccrual struct {
 	status       int
 	useCases     *usecases.UseCases
 	log          logger.Log
 	client       *integrationAccrualClient
+	hold         *config.Hold // hold - удержание начисленных баллов
 	mu           sync.Mutex
 	pollInterval time.Duration // pollInterval - тайминг между запросами к системе начисления
 	retryAfter   time.Duration // retryAfter - тайминг ожидания после получения ошибки TooManyRequests
 	timingCh     chan struct{} // timingCh - сигнал об изменении таймингов после получения ошибки TooManyRequests
 }
 
-func NewIntegrationAccrual(c *config.IntegrationAccrual, u *usecases.UseCases, log logger.Log) *IntegrationAccrual {
+func NewIntegrationAccrual(c *config.IntegrationAccrual, hold *config.Hold, u *usecases.UseCases, log logger.Log) *IntegrationAccrual {
 	return &IntegrationAccrual{
 		status:       AccrualStopped,
 		useCases:     u,
 		log:          log,
+		hold:         hold,
 		pollInterval: c.PollInterval,
 		retryAfter:   0,
 		timingCh:     make(chan struct{}),
@@ -82,7 +87,7 @@ func (a *IntegrationAccrual) poll(ctx context.Context) {
 
 // updateFurther - запрашивает необработанные операции по начислению баллов и обновляет их статусы
 func (a *IntegrationAccrual) updateFurther(ctx context.Context) error {
-	op, err := a.useCases.OperationUpdateFurther(ctx, models.OrderAccrual, a.updateCallback)
+	op, err := a.useCases.OperationUpdateFurther(ctx, models.OrderAccrual, models.StatusSourceAccrual, a.updateCallback)
 	if errors.Is(err, errs.ErrNotFound) {
 		a.log.Debug().Msg("accrual operation: nothing to update")
 		return nil
@@ -115,9 +120,14 @@ func (a *IntegrationAccrual) updateCallback(ctx context.Context, op *models.Oper
 	}
 
 	a.log.Info().Uint64("operation_id", op.ID).Msg("accrual operation request success")
-	// Обновляем данные операции
+	// Обновляем данные операции. Статус REGISTERED системы начисления означает,
+	// что заказ принят, но начисление еще не рассчитано
 	op.Status = res.Status
+	if res.Status == accrualStatusRegistered {
+		op.Status = models.StatusProcessing
+	}
 	op.Amount = res.Amount
+	a.useCases.PointsHold(op, a.hold)
 	return nil
 }
 
@@ -60,6 +60,19 @@ func (suite *accrualSuite) TestSuccessfulRequest() {
 	time.Sleep(testPollInterval + 100*time.Millisecond)
 }
 
+func (suite *accrualSuite) TestRegisteredStatus() {
+	suite.testHandler = suite.handlers["registered"]
+	op := &models.Operation{ID: 1, Status: models.StatusNew, OrderNumber: strPtr("2377225624")}
+	suite.repo.On("OperationUpdateFurther", mock.Anything, models.OrderAccrual, models.StatusSourceAccrual, mock.Anything).
+		Return(op, nil).Once().
+		Run(func(args mock.Arguments) {
+			suite.NoError(args.Get(3).(repo.UpdateFunc)(suite.ctx(), op))
+		})
+	suite.NoError(suite.accrual.updateFurther(suite.ctx()))
+	// статус REGISTERED системы начисления соответствует статусу PROCESSING операции
+	suite.Equal(models.StatusProcessing, op.Status)
+}
+
 func (suite *accrualSuite) TestFailedRequest() {
 	suite.testHandler = suite.handlers["failed"]
 	suite.mockCalls["success"]().Once()
@@ -118,6 +131,11 @@ func (suite *accrualSuite) SetupSuite() {
 			w.WriteHeader(http.StatusOK)
 			_, _ = w.Write([]byte(`{"order": "2377225624", "status": "PROCESSING"}`))
 		},
+		"registered": func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusOK)
+			_, _ = w.Write([]byte(`{"order": "2377225624", "status": "REGISTERED"}`))
+		},
 		"failed": func(w http.ResponseWriter, r *http.Request) {
 			w.Header().Set("Content-Type", "application/json")
 			w.WriteHeader(http.StatusBadRequest)
@@ -140,11 +158,11 @@ func (suite *accrualSuite) SetupSuite() {
 	suite.mockCalls = map[string]func() *mock.Call{
 		"success": func() *mock.Call {
 			c := suite.repo.
-				On("OperationUpdateFurther", mock.Anything, models.OrderAccrual, mock.Anything).
+				On("OperationUpdateFurther", mock.Anything, models.OrderAccrual, models.StatusSourceAccrual, mock.Anything).
 				Return(&models.Operation{ID: 1}, nil)
 			c.RunFn = func(args mock.Arguments) {
 				ctx := args.Get(0).(context.Context)
-				updateFunc := args.Get(2).(repo.UpdateFunc)
+				updateFunc := args.Get(3).(repo.UpdateFunc)
 				err := updateFunc(ctx, &models.Operation{ID: 1, OrderNumber: strPtr("2377225624")})
 				if err != nil {
 					c.ReturnArguments = mock.Arguments{nil, err}
@@ -156,12 +174,12 @@ func (suite *accrualSuite) SetupSuite() {
 		},
 		"no_operations_to_update": func() *mock.Call {
 			return suite.repo.
-				On("OperationUpdateFurther", mock.Anything, models.OrderAccrual, mock.Anything).
+				On("OperationUpdateFurther", mock.Anything, models.OrderAccrual, models.StatusSourceAccrual, mock.Anything).
 				Return(nil, errs.ErrNotFound)
 		},
 		"failed": func() *mock.Call {
 			return suite.repo.
-				On("OperationUpdateFurther", mock.Anything, models.OrderAccrual, mock.Anything).
+				On("OperationUpdateFurther", mock.Anything, models.OrderAccrual, models.StatusSourceAccrual, mock.Anything).
 				Return(nil, errs.ErrInternal)
 		},
 	}
@@ -176,13 +194,13 @@ func (suite *accrualSuite) SetupTest() {
 	suite.testServer = httptest.NewServer(mux)
 
 	suite.repo = mocks.NewRepo(suite.T())
-	suite.useCases = usecases.NewUseCases(suite.repo, suite.log)
+	suite.useCases = usecases.NewUseCases(suite.repo, nil, mocks.NewSender(suite.T()), suite.log)
 	cfg := &config.IntegrationAccrual{
 		Address:      suite.testServer.URL,
 		PollInterval: testPollInterval,
 		Timeout:      testTimeout,
 	}
-	suite.accrual = NewIntegrationAccrual(cfg, suite.useCases, suite.log)
+	suite.accrual = NewIntegrationAccrual(cfg, &config.Hold{}, suite.useCases, suite.log)
 }
 
 func (suite *accrualSuite) TearDownTest() {
@@ -67,7 +67,7 @@ func (s *IntegrationShopStub) poll(ctx context.Context) {
 
 // updateFurther - запрашивает необработанные операции по списанию баллов и обновляет их статусы
 func (s *IntegrationShopStub) updateFurther(ctx context.Context) {
-	op, err := s.useCases.OperationUpdateFurther(ctx, models.OrderWithdrawal, func(ctx context.Context, op *models.Operation) error {
+	op, err := s.useCases.OperationUpdateFurther(ctx, models.OrderWithdrawal, models.StatusSourceShop, func(ctx context.Context, op *models.Operation) error {
 		if op.OrderNumber == nil {
 			s.log.Error().Uint64("operation_id", op.ID).Msg("order number is nil")
 			return errs.ErrInternal
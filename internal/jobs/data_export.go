@@ -0,0 +1,97 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"gophermart-loyalty/internal/config"
+	"gophermart-loyalty/internal/errs"
+	"gophermart-loyalty/internal/logger"
+	"gophermart-loyalty/internal/usecases"
+)
+
+const (
+	DataExportStopped = iota
+	DataExportRunning
+)
+
+// cleanupInterval - интервал удаления истекших выгрузок
+const cleanupInterval = time.Minute
+
+// DataExportJob - фоновое формирование выгрузок персональных данных.
+//
+// Формирует выгрузки из очереди по одной за каждую проверку очереди
+// и удаляет выгрузки, время хранения которых истекло.
+type DataExportJob struct {
+	status   int
+	cfg      *config.DataExport
+	useCases *usecases.UseCases
+	log      logger.Log
+}
+
+func NewDataExportJob(cfg *config.DataExport, u *usecases.UseCases, log logger.Log) *DataExportJob {
+	return &DataExportJob{
+		status:   DataExportStopped,
+		cfg:      cfg,
+		useCases: u,
+		log:      log,
+	}
+}
+
+// Start - запускает формирование выгрузок.
+func (j *DataExportJob) Start(ctx context.Context) {
+	go j.poll(ctx)
+	j.status = DataExportRunning
+}
+
+// Status - возвращает статус формирования выгрузок.
+func (j *DataExportJob) Status() int {
+	return j.status
+}
+
+// poll - цикл проверки очереди выгрузок
+func (j *DataExportJob) poll(ctx context.Context) {
+	j.log.Info().Msg("data export job started")
+	lastCleanup := time.Time{}
+	for {
+		select {
+		case <-ctx.Done():
+			j.log.Info().Msg("data export job stopped")
+			j.status = DataExportStopped
+			return
+		case <-time.After(j.cfg.PollInterval):
+			go j.processFurther(ctx)
+			if time.Since(lastCleanup) > cleanupInterval {
+				lastCleanup = time.Now()
+				go j.deleteExpired(ctx)
+			}
+		}
+	}
+}
+
+// processFurther - формирует самую старую выгрузку в очереди
+func (j *DataExportJob) processFurther(ctx context.Context) {
+	e, err := j.useCases.DataExportProcessFurther(ctx, j.cfg.TTL)
+	if errors.Is(err, errs.ErrNotFound) {
+		j.log.Debug().Msg("data export: nothing to process")
+		return
+	}
+	if err != nil {
+		j.log.Error().Err(err).Msg("data export processing failed")
+		return
+	}
+	j.log.Info().Uint64("export_id", e.ID).Str("status", string(e.Status)).Msg("data export processed")
+}
+
+// deleteExpired - удаляет истекшие выгрузки
+func (j *DataExportJob) deleteExpired(ctx context.Context) {
+	n, err := j.useCases.DataExportDeleteExpired(ctx)
+	if err != nil {
+		j.log.Error().Err(err).Msg("failed to delete expired data exports")
+		return
+	}
+	if n > 0 {
+		j.log.Info().Int64("count", n).Msg("expired data exports deleted")
+	}
+}
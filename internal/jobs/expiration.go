@@ -0,0 +1,74 @@
+package jobs
+
+import (
+	"context"
+	"time"
+
+	"gophermart-loyalty/internal/config"
+	"gophermart-loyalty/internal/logger"
+	"gophermart-loyalty/internal/usecases"
+)
+
+const (
+	ExpirationStopped = iota
+	ExpirationRunning
+)
+
+// ExpirationJob - периодическое сгорание баллов, срок жизни которых истек.
+type ExpirationJob struct {
+	status   int
+	cfg      *config.Expiration
+	useCases *usecases.UseCases
+	log      logger.Log
+}
+
+func NewExpirationJob(cfg *config.Expiration, u *usecases.UseCases, log logger.Log) *ExpirationJob {
+	return &ExpirationJob{
+		status:   ExpirationStopped,
+		cfg:      cfg,
+		useCases: u,
+		log:      log,
+	}
+}
+
+// Start - запускает периодическое сгорание баллов. Если интервал проверки не задан, сгорание не запускается.
+func (j *ExpirationJob) Start(ctx context.Context) {
+	if j.cfg.PollInterval <= 0 {
+		j.log.Info().Msg("points expiration job disabled")
+		return
+	}
+	go j.poll(ctx)
+	j.status = ExpirationRunning
+}
+
+// Status - возвращает статус периодического сгорания баллов.
+func (j *ExpirationJob) Status() int {
+	return j.status
+}
+
+// poll - цикл периодического сгорания баллов
+func (j *ExpirationJob) poll(ctx context.Context) {
+	j.log.Info().Msg("points expiration job started")
+	for {
+		select {
+		case <-ctx.Done():
+			j.log.Info().Msg("points expiration job stopped")
+			j.status = ExpirationStopped
+			return
+		case <-time.After(j.cfg.PollInterval):
+			j.expire(ctx)
+		}
+	}
+}
+
+// expire - сжигает баллы всех пользователей, срок жизни которых истек
+func (j *ExpirationJob) expire(ctx context.Context) {
+	ops, err := j.useCases.PointsExpire(ctx, j.cfg)
+	if err != nil {
+		j.log.Error().Err(err).Msg("points expiration failed")
+		return
+	}
+	if len(ops) > 0 {
+		j.log.Info().Int("count", len(ops)).Msg("points expired")
+	}
+}
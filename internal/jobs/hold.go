@@ -0,0 +1,74 @@
+package jobs
+
+import (
+	"context"
+	"time"
+
+	"gophermart-loyalty/internal/config"
+	"gophermart-loyalty/internal/logger"
+	"gophermart-loyalty/internal/usecases"
+)
+
+const (
+	HoldReleaseStopped = iota
+	HoldReleaseRunning
+)
+
+// HoldReleaseJob - периодическое снятие удержания с начисленных баллов, срок удержания которых истек.
+type HoldReleaseJob struct {
+	status   int
+	cfg      *config.Hold
+	useCases *usecases.UseCases
+	log      logger.Log
+}
+
+func NewHoldReleaseJob(cfg *config.Hold, u *usecases.UseCases, log logger.Log) *HoldReleaseJob {
+	return &HoldReleaseJob{
+		status:   HoldReleaseStopped,
+		cfg:      cfg,
+		useCases: u,
+		log:      log,
+	}
+}
+
+// Start - запускает периодическое снятие удержания. Если интервал проверки не задан, снятие не запускается.
+func (j *HoldReleaseJob) Start(ctx context.Context) {
+	if j.cfg.PollInterval <= 0 {
+		j.log.Info().Msg("points release job disabled")
+		return
+	}
+	go j.poll(ctx)
+	j.status = HoldReleaseRunning
+}
+
+// Status - возвращает статус периодического снятия удержания.
+func (j *HoldReleaseJob) Status() int {
+	return j.status
+}
+
+// poll - цикл периодического снятия удержания
+func (j *HoldReleaseJob) poll(ctx context.Context) {
+	j.log.Info().Msg("points release job started")
+	for {
+		select {
+		case <-ctx.Done():
+			j.log.Info().Msg("points release job stopped")
+			j.status = HoldReleaseStopped
+			return
+		case <-time.After(j.cfg.PollInterval):
+			j.release(ctx)
+		}
+	}
+}
+
+// release - снимает удержание с начислений всех пользователей, срок удержания которых истек
+func (j *HoldReleaseJob) release(ctx context.Context) {
+	released, err := j.useCases.PointsRelease(ctx)
+	if err != nil {
+		j.log.Error().Err(err).Msg("points release failed")
+		return
+	}
+	if released.IsPositive() {
+		j.log.Info().Str("amount", released.String()).Msg("points released")
+	}
+}
@@ -0,0 +1,74 @@
+package jobs
+
+import (
+	"context"
+	"time"
+
+	"gophermart-loyalty/internal/config"
+	"gophermart-loyalty/internal/logger"
+	"gophermart-loyalty/internal/usecases"
+)
+
+const (
+	IdempotencyCleanupStopped = iota
+	IdempotencyCleanupRunning
+)
+
+// IdempotencyCleanupJob - периодическое удаление истекших ключей идемпотентности.
+type IdempotencyCleanupJob struct {
+	status   int
+	cfg      *config.Idempotency
+	useCases *usecases.UseCases
+	log      logger.Log
+}
+
+func NewIdempotencyCleanupJob(cfg *config.Idempotency, u *usecases.UseCases, log logger.Log) *IdempotencyCleanupJob {
+	return &IdempotencyCleanupJob{
+		status:   IdempotencyCleanupStopped,
+		cfg:      cfg,
+		useCases: u,
+		log:      log,
+	}
+}
+
+// Start - запускает периодическое удаление ключей. Если интервал удаления не задан, удаление не запускается.
+func (j *IdempotencyCleanupJob) Start(ctx context.Context) {
+	if j.cfg.CleanupInterval <= 0 {
+		j.log.Info().Msg("idempotency keys cleanup job disabled")
+		return
+	}
+	go j.poll(ctx)
+	j.status = IdempotencyCleanupRunning
+}
+
+// Status - возвращает статус периодического удаления ключей.
+func (j *IdempotencyCleanupJob) Status() int {
+	return j.status
+}
+
+// poll - цикл периодического удаления ключей
+func (j *IdempotencyCleanupJob) poll(ctx context.Context) {
+	j.log.Info().Msg("idempotency keys cleanup job started")
+	for {
+		select {
+		case <-ctx.Done():
+			j.log.Info().Msg("idempotency keys cleanup job stopped")
+			j.status = IdempotencyCleanupStopped
+			return
+		case <-time.After(j.cfg.CleanupInterval):
+			j.cleanup(ctx)
+		}
+	}
+}
+
+// cleanup - удаляет истекшие ключи идемпотентности всех пользователей
+func (j *IdempotencyCleanupJob) cleanup(ctx context.Context) {
+	deleted, err := j.useCases.IdempotencyKeyDeleteExpired(ctx)
+	if err != nil {
+		j.log.Error().Err(err).Msg("idempotency keys cleanup failed")
+		return
+	}
+	if deleted > 0 {
+		j.log.Info().Int64("count", deleted).Msg("expired idempotency keys deleted")
+	}
+}
@@ -0,0 +1,86 @@
+package jobs
+
+import (
+	"context"
+	"time"
+
+	"gophermart-loyalty/internal/config"
+	"gophermart-loyalty/internal/logger"
+	"gophermart-loyalty/internal/usecases"
+)
+
+const (
+	ReconcileStopped = iota
+	ReconcileRunning
+)
+
+// ReconcileJob - периодическая сверка балансов пользователей с журналом проводок.
+//
+// Расхождения записываются в лог и, если это разрешено конфигурацией, исправляются.
+type ReconcileJob struct {
+	status   int
+	cfg      *config.Reconcile
+	useCases *usecases.UseCases
+	log      logger.Log
+}
+
+func NewReconcileJob(cfg *config.Reconcile, u *usecases.UseCases, log logger.Log) *ReconcileJob {
+	return &ReconcileJob{
+		status:   ReconcileStopped,
+		cfg:      cfg,
+		useCases: u,
+		log:      log,
+	}
+}
+
+// Start - запускает периодическую сверку. Если интервал сверки не задан, сверка не запускается.
+func (j *ReconcileJob) Start(ctx context.Context) {
+	if j.cfg.Interval <= 0 {
+		j.log.Info().Msg("reconcile job disabled")
+		return
+	}
+	go j.poll(ctx)
+	j.status = ReconcileRunning
+}
+
+// Status - возвращает статус периодической сверки.
+func (j *ReconcileJob) Status() int {
+	return j.status
+}
+
+// poll - цикл периодической сверки
+func (j *ReconcileJob) poll(ctx context.Context) {
+	j.log.Info().Msg("reconcile job started")
+	for {
+		select {
+		case <-ctx.Done():
+			j.log.Info().Msg("reconcile job stopped")
+			j.status = ReconcileStopped
+			return
+		case <-time.After(j.cfg.Interval):
+			j.reconcile(ctx)
+		}
+	}
+}
+
+// reconcile - сверяет балансы всех пользователей
+func (j *ReconcileJob) reconcile(ctx context.Context) {
+	started := time.Now()
+	drifts, err := j.useCases.BalanceReconcile(ctx, j.cfg.Repair)
+	if err != nil {
+		j.log.Error().Err(err).Msg("balance reconciliation failed")
+		return
+	}
+	failed := 0
+	for _, c := range drifts {
+		if c.RepairErr != nil {
+			failed++
+		}
+	}
+	j.log.Info().
+		Int("drifts", len(drifts)).
+		Int("repair_failed", failed).
+		Bool("repair", j.cfg.Repair).
+		Dur("duration", time.Since(started)).
+		Msg("balance reconciliation finished")
+}
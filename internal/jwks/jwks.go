@@ -0,0 +1,134 @@
+// Package jwks - ключи подписи JWT-токенов.
+//
+// Токены подписываются текущим ключом и содержат его идентификатор в заголовке "kid".
+// Предыдущие ключи принимаются при проверке токенов в течение окна ротации после смены ключа,
+// чтобы токены, выпущенные до смены ключа, продолжали действовать до истечения.
+// Окно ротации отсчитывается от заданного в конфигурации времени смены ключа, а не от запуска приложения,
+// поэтому перезапуск и новые экземпляры приложения не продлевают прием предыдущих ключей.
+// Публичные ключи асимметричных алгоритмов публикуются в формате JWK Set (RFC 7517).
+package jwks
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+
+	"gophermart-loyalty/internal/config"
+)
+
+// KeySet - набор ключей подписи JWT-токенов.
+type KeySet struct {
+	current       *key      // текущий ключ, которым подписываются токены
+	previous      []*key    // предыдущие ключи
+	previousUntil time.Time // время, до которого принимаются предыдущие ключи
+}
+
+// Set - набор публичных ключей в формате JSON Web Key Set.
+type Set struct {
+	Keys []JWK `json:"keys"`
+}
+
+// NewKeySet - создает набор ключей по конфигурации.
+//
+// Для алгоритмов HS256, HS384, HS512 используется секретный ключ cfg.SigningKey с идентификатором cfg.SigningKeyID.
+// Для алгоритмов RS256, RS384, RS512, ES256, ES384, ES512, EdDSA закрытый ключ загружается из PEM-файла cfg.SigningKeyFile.
+// Предыдущие ключи загружаются из PEM-файлов cfg.PrevKeyFiles и принимаются в течение cfg.RotationWindow
+// с момента смены ключа cfg.RotatedAt.
+func NewKeySet(cfg *config.Auth) (*KeySet, error) {
+	if len(cfg.PrevKeyFiles) > 0 && cfg.RotatedAt.IsZero() {
+		return nil, fmt.Errorf("key rotation time not set")
+	}
+	ks := &KeySet{previousUntil: cfg.RotatedAt.Add(cfg.RotationWindow)}
+
+	var err error
+	if _, ok := jwt.GetSigningMethod(cfg.SigningAlg).(*jwt.SigningMethodHMAC); ok {
+		ks.current, err = newHMACKey(cfg.SigningAlg, cfg.SigningKey, cfg.SigningKeyID)
+	} else {
+		ks.current, err = loadKey(cfg.SigningKeyFile, cfg.SigningAlg, true)
+		if err == nil && ks.current.signKey == nil {
+			err = fmt.Errorf("%s: private key required", cfg.SigningKeyFile)
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	for _, path := range cfg.PrevKeyFiles {
+		// Если предыдущий ключ того же типа, что и текущий, считаем, что алгоритм подписи не менялся,
+		// иначе алгоритм подписи определяется по типу ключа
+		k, err := loadKey(path, cfg.SigningAlg, false)
+		if err != nil {
+			return nil, err
+		}
+		if k.id != ks.current.id {
+			ks.previous = append(ks.previous, k)
+		}
+	}
+	return ks, nil
+}
+
+// Sign - подписывает токен с claims текущим ключом.
+// Если у ключа нет идентификатора, заголовок "kid" не передается.
+func (ks *KeySet) Sign(claims jwt.Claims) (string, error) {
+	if ks.current == nil {
+		return "", fmt.Errorf("signing key not set")
+	}
+	token := jwt.NewWithClaims(ks.current.method, claims)
+	if ks.current.id != "" {
+		token.Header["kid"] = ks.current.id
+	}
+	return token.SignedString(ks.current.signKey)
+}
+
+// Keyfunc - возвращает ключ для проверки подписи токена по заголовку "kid".
+// Токены без "kid" проверяются текущим ключом.
+// Алгоритм подписи токена должен совпадать с алгоритмом ключа.
+func (ks *KeySet) Keyfunc(token *jwt.Token) (interface{}, error) {
+	k := ks.current
+	if kid, ok := token.Header["kid"]; ok {
+		if k, ok = ks.lookup(kid); !ok {
+			return nil, fmt.Errorf("unknown key id: %v", kid)
+		}
+	}
+	if k == nil {
+		return nil, fmt.Errorf("signing key not set")
+	}
+	if token.Method.Alg() != k.method.Alg() {
+		return nil, fmt.Errorf("unsupported signing method: %v, want %v", token.Method.Alg(), k.method.Alg())
+	}
+	return k.verifyKey, nil
+}
+
+// JWKS - возвращает действующие публичные ключи: текущий ключ и предыдущие ключи в течение окна ротации.
+// Симметричные ключи не публикуются.
+func (ks *KeySet) JWKS() *Set {
+	set := &Set{Keys: []JWK{}}
+	if ks.current != nil && ks.current.jwk != nil {
+		set.Keys = append(set.Keys, *ks.current.jwk)
+	}
+	if time.Now().Before(ks.previousUntil) {
+		for _, k := range ks.previous {
+			if k.jwk != nil {
+				set.Keys = append(set.Keys, *k.jwk)
+			}
+		}
+	}
+	return set
+}
+
+// lookup - возвращает действующий ключ по kid.
+func (ks *KeySet) lookup(kid interface{}) (*key, bool) {
+	if ks.current != nil && kid == ks.current.id {
+		return ks.current, true
+	}
+	if !time.Now().Before(ks.previousUntil) {
+		return nil, false
+	}
+	for _, k := range ks.previous {
+		if kid == k.id {
+			return k, true
+		}
+	}
+	return nil, false
+}
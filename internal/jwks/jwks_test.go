@@ -0,0 +1,245 @@
+package jwks
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/stretchr/testify/suite"
+
+	"gophermart-loyalty/internal/config"
+)
+
+func TestKeySetSuite(t *testing.T) {
+	suite.Run(t, new(keySetSuite))
+}
+
+type keySetSuite struct {
+	suite.Suite
+	dir string
+}
+
+func (suite *keySetSuite) SetupTest() {
+	suite.dir = suite.T().TempDir()
+}
+
+// writeKey - сохраняет закрытый ключ в PEM-файл PKCS #8 и возвращает путь к файлу.
+func (suite *keySetSuite) writeKey(name string, k crypto.PrivateKey) string {
+	der, err := x509.MarshalPKCS8PrivateKey(k)
+	suite.Require().NoError(err)
+	path := filepath.Join(suite.dir, name)
+	suite.Require().NoError(os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), 0600))
+	return path
+}
+
+// writePublicKey - сохраняет публичный ключ в PEM-файл PKIX и возвращает путь к файлу.
+func (suite *keySetSuite) writePublicKey(name string, k crypto.PublicKey) string {
+	der, err := x509.MarshalPKIXPublicKey(k)
+	suite.Require().NoError(err)
+	path := filepath.Join(suite.dir, name)
+	suite.Require().NoError(os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}), 0600))
+	return path
+}
+
+func (suite *keySetSuite) rsaKey() *rsa.PrivateKey {
+	k, err := rsa.GenerateKey(rand.Reader, 2048)
+	suite.Require().NoError(err)
+	return k
+}
+
+func (suite *keySetSuite) ecKey() *ecdsa.PrivateKey {
+	k, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	suite.Require().NoError(err)
+	return k
+}
+
+func (suite *keySetSuite) edKey() ed25519.PrivateKey {
+	_, k, err := ed25519.GenerateKey(rand.Reader)
+	suite.Require().NoError(err)
+	return k
+}
+
+// parse - проверяет подпись токена набором ключей ks.
+func (suite *keySetSuite) parse(ks *KeySet, token string) error {
+	_, err := jwt.Parse(token, ks.Keyfunc)
+	return err
+}
+
+func (suite *keySetSuite) claims() jwt.MapClaims {
+	return jwt.MapClaims{"sub": 1, "exp": time.Now().Add(time.Hour).Unix()}
+}
+
+func (suite *keySetSuite) TestSignAndVerify() {
+	tests := []struct {
+		alg string
+		key crypto.PrivateKey
+		kty string
+	}{
+		{alg: "RS256", key: suite.rsaKey(), kty: "RSA"},
+		{alg: "ES256", key: suite.ecKey(), kty: "EC"},
+		{alg: "EdDSA", key: suite.edKey(), kty: "OKP"},
+	}
+	for _, tt := range tests {
+		suite.Run(tt.alg, func() {
+			ks, err := NewKeySet(&config.Auth{SigningAlg: tt.alg, SigningKeyFile: suite.writeKey(tt.alg+".pem", tt.key)})
+			suite.Require().NoError(err)
+
+			token, err := ks.Sign(suite.claims())
+			suite.Require().NoError(err)
+			suite.NoError(suite.parse(ks, token))
+
+			// токен содержит kid текущего ключа, который опубликован в JWKS
+			parsed, _, err := new(jwt.Parser).ParseUnverified(token, jwt.MapClaims{})
+			suite.Require().NoError(err)
+			suite.Equal(tt.alg, parsed.Method.Alg())
+			set := ks.JWKS()
+			suite.Require().Len(set.Keys, 1)
+			suite.Equal(parsed.Header["kid"], set.Keys[0].Kid)
+			suite.Equal(tt.kty, set.Keys[0].Kty)
+			suite.Equal(tt.alg, set.Keys[0].Alg)
+			suite.Equal("sig", set.Keys[0].Use)
+		})
+	}
+}
+
+func (suite *keySetSuite) TestHMAC() {
+	ks, err := NewKeySet(&config.Auth{SigningAlg: "HS256", SigningKey: "secret"})
+	suite.Require().NoError(err)
+
+	token, err := ks.Sign(suite.claims())
+	suite.Require().NoError(err)
+	suite.NoError(suite.parse(ks, token))
+
+	suite.Run("token without kid", func() {
+		token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, suite.claims()).SignedString([]byte("secret"))
+		suite.Require().NoError(err)
+		suite.NoError(suite.parse(ks, token))
+	})
+
+	suite.Run("another algorithm", func() {
+		token, err := jwt.NewWithClaims(jwt.SigningMethodHS384, suite.claims()).SignedString([]byte("secret"))
+		suite.Require().NoError(err)
+		suite.Error(suite.parse(ks, token))
+	})
+
+	suite.Run("symmetric keys are not published", func() {
+		suite.Empty(ks.JWKS().Keys)
+	})
+
+	suite.Run("kid is not derived from secret", func() {
+		parsed, _, err := new(jwt.Parser).ParseUnverified(token, jwt.MapClaims{})
+		suite.Require().NoError(err)
+		suite.NotContains(parsed.Header, "kid")
+
+		ks, err := NewKeySet(&config.Auth{SigningAlg: "HS256", SigningKey: "secret", SigningKeyID: "2024-01"})
+		suite.Require().NoError(err)
+		token, err := ks.Sign(suite.claims())
+		suite.Require().NoError(err)
+		parsed, _, err = new(jwt.Parser).ParseUnverified(token, jwt.MapClaims{})
+		suite.Require().NoError(err)
+		suite.Equal("2024-01", parsed.Header["kid"])
+		suite.NoError(suite.parse(ks, token))
+	})
+}
+
+func (suite *keySetSuite) TestRotation() {
+	oldKey := suite.rsaKey()
+	oldKeys, err := NewKeySet(&config.Auth{SigningAlg: "RS512", SigningKeyFile: suite.writeKey("old.pem", oldKey)})
+	suite.Require().NoError(err)
+	oldToken, err := oldKeys.Sign(suite.claims())
+	suite.Require().NoError(err)
+
+	newKeyFile := suite.writeKey("new.pem", suite.ecKey())
+	// предыдущий ключ может быть задан публичным ключом
+	prevKeyFile := suite.writePublicKey("old.pub", &oldKey.PublicKey)
+
+	suite.Run("previous key is accepted during rotation window", func() {
+		ks, err := NewKeySet(&config.Auth{
+			SigningAlg:     "ES256",
+			SigningKeyFile: newKeyFile,
+			PrevKeyFiles:   []string{prevKeyFile},
+			RotationWindow: time.Hour,
+			RotatedAt:      time.Now(),
+		})
+		suite.Require().NoError(err)
+		// ключ другого типа проверяется алгоритмом по умолчанию для этого типа
+		suite.Error(suite.parse(ks, oldToken))
+
+		ks, err = NewKeySet(&config.Auth{
+			SigningAlg:     "RS512",
+			SigningKeyFile: suite.writeKey("new-rsa.pem", suite.rsaKey()),
+			PrevKeyFiles:   []string{prevKeyFile},
+			RotationWindow: time.Hour,
+			RotatedAt:      time.Now(),
+		})
+		suite.Require().NoError(err)
+		suite.NoError(suite.parse(ks, oldToken))
+		suite.Len(ks.JWKS().Keys, 2)
+
+		newToken, err := ks.Sign(suite.claims())
+		suite.Require().NoError(err)
+		suite.NoError(suite.parse(ks, newToken))
+		suite.Error(suite.parse(oldKeys, newToken))
+	})
+
+	suite.Run("previous key is rejected after rotation window", func() {
+		// окно ротации отсчитывается от смены ключа, а не от создания набора ключей при запуске
+		ks, err := NewKeySet(&config.Auth{
+			SigningAlg:     "RS512",
+			SigningKeyFile: suite.writeKey("new-rsa.pem", suite.rsaKey()),
+			PrevKeyFiles:   []string{prevKeyFile},
+			RotationWindow: time.Hour,
+			RotatedAt:      time.Now().Add(-2 * time.Hour),
+		})
+		suite.Require().NoError(err)
+		suite.Error(suite.parse(ks, oldToken))
+		suite.Len(ks.JWKS().Keys, 1)
+	})
+
+	suite.Run("rotation time required", func() {
+		_, err := NewKeySet(&config.Auth{
+			SigningAlg:     "RS512",
+			SigningKeyFile: suite.writeKey("new-rsa.pem", suite.rsaKey()),
+			PrevKeyFiles:   []string{prevKeyFile},
+			RotationWindow: time.Hour,
+		})
+		suite.Error(err)
+	})
+}
+
+func (suite *keySetSuite) TestNewKeySetErrors() {
+	suite.Run("algorithm does not match key", func() {
+		_, err := NewKeySet(&config.Auth{SigningAlg: "ES384", SigningKeyFile: suite.writeKey("ec.pem", suite.ecKey())})
+		suite.Error(err)
+	})
+
+	suite.Run("public key can not sign", func() {
+		_, err := NewKeySet(&config.Auth{SigningAlg: "EdDSA", SigningKeyFile: suite.writePublicKey("ed.pub", suite.edKey().Public())})
+		suite.Error(err)
+	})
+
+	suite.Run("key file not found", func() {
+		_, err := NewKeySet(&config.Auth{SigningAlg: "RS256", SigningKeyFile: filepath.Join(suite.dir, "missing.pem")})
+		suite.Error(err)
+	})
+
+	suite.Run("unsupported algorithm", func() {
+		_, err := NewKeySet(&config.Auth{SigningAlg: "none", SigningKeyFile: suite.writeKey("rsa.pem", suite.rsaKey())})
+		suite.Error(err)
+	})
+
+	suite.Run("empty secret", func() {
+		_, err := NewKeySet(&config.Auth{SigningAlg: "HS256"})
+		suite.Error(err)
+	})
+}
@@ -0,0 +1,190 @@
+package jwks
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"os"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// JWK - публичный ключ в формате JSON Web Key (RFC 7517).
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	Crv string `json:"crv,omitempty"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	X   string `json:"x,omitempty"`
+	Y   string `json:"y,omitempty"`
+}
+
+// key - ключ подписи JWT.
+type key struct {
+	id        string            // идентификатор ключа, передается в заголовке "kid" токена
+	method    jwt.SigningMethod // алгоритм подписи
+	signKey   interface{}       // ключ для подписи, nil если известен только публичный ключ
+	verifyKey interface{}       // ключ для проверки подписи
+	jwk       *JWK              // публичный ключ для публикации, nil для симметричных ключей
+}
+
+// newHMACKey - создает симметричный ключ для алгоритмов HS256, HS384, HS512 с идентификатором id.
+// Идентификатор задается в конфигурации, а не вычисляется по секрету: заголовок "kid" токена виден всем,
+// кто получил токен, и не должен раскрывать сведения о секрете.
+func newHMACKey(alg, secret, id string) (*key, error) {
+	method, ok := jwt.GetSigningMethod(alg).(*jwt.SigningMethodHMAC)
+	if !ok {
+		return nil, fmt.Errorf("unsupported signing method: %s", alg)
+	}
+	if secret == "" {
+		return nil, fmt.Errorf("empty signing key")
+	}
+	k := []byte(secret)
+	return &key{
+		id:        id,
+		method:    method,
+		signKey:   k,
+		verifyKey: k,
+	}, nil
+}
+
+// loadKey - загружает ключ из PEM-файла.
+// Файл может содержать закрытый ключ (PKCS #1, PKCS #8, SEC 1) или публичный ключ (PKIX, PKCS #1).
+// Если strict, алгоритм подписи alg должен соответствовать типу ключа,
+// иначе для несоответствующего alg алгоритм подписи определяется по типу ключа.
+func loadKey(path, alg string, strict bool) (*key, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	parsed, err := parsePEM(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	k, err := newAsymmetricKey(parsed, alg, strict)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return k, nil
+}
+
+// parsePEM - разбирает первый PEM-блок с ключом.
+func parsePEM(data []byte) (interface{}, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("no PEM data found")
+	}
+	switch block.Type {
+	case "PRIVATE KEY":
+		return x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "RSA PRIVATE KEY":
+		return x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "EC PRIVATE KEY":
+		return x509.ParseECPrivateKey(block.Bytes)
+	case "PUBLIC KEY":
+		return x509.ParsePKIXPublicKey(block.Bytes)
+	case "RSA PUBLIC KEY":
+		return x509.ParsePKCS1PublicKey(block.Bytes)
+	}
+	return nil, fmt.Errorf("unsupported PEM block type: %s", block.Type)
+}
+
+// newAsymmetricKey - создает ключ для алгоритмов RS*, ES*, EdDSA.
+// Если strict, алгоритм подписи alg должен соответствовать типу ключа,
+// иначе для несоответствующего alg алгоритм подписи определяется по типу ключа.
+func newAsymmetricKey(parsed interface{}, alg string, strict bool) (*key, error) {
+	k := &key{}
+	var pub interface{}
+	switch p := parsed.(type) {
+	case *rsa.PrivateKey:
+		k.signKey, pub = p, &p.PublicKey
+	case *ecdsa.PrivateKey:
+		k.signKey, pub = p, &p.PublicKey
+	case ed25519.PrivateKey:
+		k.signKey, pub = p, p.Public()
+	default:
+		pub = parsed
+	}
+	k.verifyKey = pub
+
+	switch p := pub.(type) {
+	case *rsa.PublicKey:
+		if _, ok := jwt.GetSigningMethod(alg).(*jwt.SigningMethodRSA); !ok {
+			if strict {
+				return nil, fmt.Errorf("signing method %s does not match RSA key", alg)
+			}
+			alg = jwt.SigningMethodRS256.Alg()
+		}
+		k.jwk = &JWK{
+			Kty: "RSA",
+			N:   b64(p.N.Bytes()),
+			E:   b64(big.NewInt(int64(p.E)).Bytes()),
+		}
+		k.id = thumbprint(fmt.Sprintf(`{"e":"%s","kty":"RSA","n":"%s"}`, k.jwk.E, k.jwk.N))
+	case *ecdsa.PublicKey:
+		curveAlg := map[elliptic.Curve]string{
+			elliptic.P256(): jwt.SigningMethodES256.Alg(),
+			elliptic.P384(): jwt.SigningMethodES384.Alg(),
+			elliptic.P521(): jwt.SigningMethodES512.Alg(),
+		}[p.Curve]
+		if curveAlg == "" {
+			return nil, fmt.Errorf("unsupported elliptic curve")
+		}
+		if alg != curveAlg {
+			if strict {
+				return nil, fmt.Errorf("signing method %s does not match %s key", alg, p.Curve.Params().Name)
+			}
+			alg = curveAlg
+		}
+		size := (p.Curve.Params().BitSize + 7) / 8
+		k.jwk = &JWK{
+			Kty: "EC",
+			Crv: p.Curve.Params().Name,
+			X:   b64(p.X.FillBytes(make([]byte, size))),
+			Y:   b64(p.Y.FillBytes(make([]byte, size))),
+		}
+		k.id = thumbprint(fmt.Sprintf(`{"crv":"%s","kty":"EC","x":"%s","y":"%s"}`, k.jwk.Crv, k.jwk.X, k.jwk.Y))
+	case ed25519.PublicKey:
+		if alg != jwt.SigningMethodEdDSA.Alg() {
+			if strict {
+				return nil, fmt.Errorf("signing method %s does not match Ed25519 key", alg)
+			}
+			alg = jwt.SigningMethodEdDSA.Alg()
+		}
+		k.jwk = &JWK{
+			Kty: "OKP",
+			Crv: "Ed25519",
+			X:   b64(p),
+		}
+		k.id = thumbprint(fmt.Sprintf(`{"crv":"Ed25519","kty":"OKP","x":"%s"}`, k.jwk.X))
+	default:
+		return nil, fmt.Errorf("unsupported key type %T", pub)
+	}
+
+	k.method = jwt.GetSigningMethod(alg)
+	k.jwk.Kid = k.id
+	k.jwk.Use = "sig"
+	k.jwk.Alg = alg
+	return k, nil
+}
+
+// thumbprint - возвращает отпечаток ключа по RFC 7638.
+// members - обязательные поля JWK в лексикографическом порядке без пробелов.
+func thumbprint(members string) string {
+	sum := sha256.Sum256([]byte(members))
+	return b64(sum[:])
+}
+
+func b64(b []byte) string {
+	return base64.RawURLEncoding.EncodeToString(b)
+}
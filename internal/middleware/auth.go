@@ -4,109 +4,186 @@ import (
 	"context"
 	"fmt"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/go-chi/render"
 	"github.com/golang-jwt/jwt/v4"
 	"github.com/golang-jwt/jwt/v4/request"
 
 	"gophermart-loyalty/internal/errs"
+	"gophermart-loyalty/internal/models"
 )
 
 type contextKey struct {
 	name string
 }
 
-var ctxUserID = &contextKey{"user_id"}
+var (
+	ctxUserID      = &contextKey{"user_id"}
+	ctxSessionID   = &contextKey{"session_id"}
+	ctxUserRole    = &contextKey{"user_role"}
+	ctxAccessToken = &contextKey{"access_token"}
+)
+
+// SessionValidator - проверяет, что сессия, на которую ссылается токен, действительна
+// и что токен, выпущенный в момент issuedAt, не был отозван.
+type SessionValidator interface {
+	SessionValidate(ctx context.Context, userID, sessionID uint64, issuedAt time.Time) error
+}
+
+// AccessTokenValidator - проверяет персональный токен доступа.
+type AccessTokenValidator interface {
+	AccessTokenValidate(ctx context.Context, token string) (*models.AccessToken, error)
+}
+
+// Validator - проверяет сессии JWT-токенов и персональные токены доступа.
+type Validator interface {
+	SessionValidator
+	AccessTokenValidator
+}
 
 // Auth  - middleware для проверки авторизации.
 // Формат заголовка запроса:
-//    Authorization: Bearer <JWT token>
+//    Authorization: Bearer <token>
 // ...либо
-//    Authorization: <JWT token>
-func Auth(alg, key string) func(next http.Handler) http.Handler {
-	a := newAuthorizator(alg, key)
+//    Authorization: <token>
+//
+// В качестве токена принимается JWT-токен или персональный токен доступа с префиксом models.AccessTokenPrefix.
+//
+// Ключ для проверки подписи JWT-токена и допустимый алгоритм подписи определяет keyFunc.
+// Роль пользователя передается в поле "role" токена, если поле отсутствует - пользователь имеет роль models.RoleUser.
+// Токен должен содержать в поле "jti" id сессии. Сессия и время выпуска токена "iat" проверяются
+// при помощи v при каждом запросе, поэтому отозванные токены перестают действовать сразу.
+//
+// Персональный токен проверяется при помощи v при каждом запросе. Запрос с персональным токеном
+// выполняется с ролью models.RoleUser без сессии, области доступа токена проверяет RequireScope.
+func Auth(keyFunc jwt.Keyfunc, v Validator) func(next http.Handler) http.Handler {
+	a := newAuthorizator(keyFunc, v)
 	return a.handler
 }
 
 // authorizator - хранит конфигурацию для авторизации
 type authorizator struct {
-	alg string
-	key string
+	keyFunc   jwt.Keyfunc
+	validator Validator
 }
 
-func newAuthorizator(alg, key string) *authorizator {
-	return &authorizator{alg: alg, key: key}
+func newAuthorizator(keyFunc jwt.Keyfunc, v Validator) *authorizator {
+	return &authorizator{keyFunc: keyFunc, validator: v}
 }
 
 // handler - хандлер для проверки авторизации
 func (a *authorizator) handler(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		// Извлекаем ID пользователя из запроса
-		userID, err := a.extractUserID(r)
+		// Извлекаем токен из заголовка запроса.
+		// Если в заголовке пришел Bearer <token>, то извлекается только <token>
+		raw, err := request.AuthorizationHeaderExtractor.ExtractToken(r)
 		if err != nil {
 			_ = render.Render(w, r, errs.ErrResponseUnauthorized)
 			return
 		}
 
-		// Добавляем в контекст запроса ID пользователя
 		ctx := r.Context()
+		if strings.HasPrefix(raw, models.AccessTokenPrefix) {
+			// Проверяем персональный токен
+			t, err := a.validator.AccessTokenValidate(ctx, raw)
+			if err != nil {
+				_ = render.Render(w, r, errs.ErrResponseUnauthorized)
+				return
+			}
+
+			// Добавляем в контекст запроса ID пользователя, роль пользователя и персональный токен
+			ctx = context.WithValue(ctx, ctxUserID, t.UserID)
+			ctx = context.WithValue(ctx, ctxUserRole, models.RoleUser)
+			ctx = context.WithValue(ctx, ctxAccessToken, t)
+			next.ServeHTTP(w, r.WithContext(ctx))
+			return
+		}
+
+		// Извлекаем ID пользователя, ID сессии и роль пользователя из JWT-токена
+		userID, sessionID, role, err := a.extractUserID(r, raw)
+		if err != nil {
+			_ = render.Render(w, r, errs.ErrResponseUnauthorized)
+			return
+		}
+
+		// Добавляем в контекст запроса ID пользователя, ID сессии и роль пользователя
 		ctx = context.WithValue(ctx, ctxUserID, userID)
+		ctx = context.WithValue(ctx, ctxSessionID, sessionID)
+		ctx = context.WithValue(ctx, ctxUserRole, role)
 		next.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
 
-// extractUserID - извлекает ID пользователя из запроса
-func (a *authorizator) extractUserID(r *http.Request) (uint64, error) {
-	// Извлекаем токен из запроса
-	token, err := a.extractJWTToken(r)
+// extractUserID - извлекает ID пользователя, ID сессии и роль пользователя из JWT-токена raw и проверяет, что сессия действительна
+func (a *authorizator) extractUserID(r *http.Request, raw string) (uint64, uint64, models.UserRole, error) {
+	// Проверяем подпись токена
+	token, err := jwt.Parse(raw, a.keyFunc)
 	if err != nil {
-		return 0, err
+		return 0, 0, "", err
 	}
 
 	// Проверяем, что токен валидный
 	if !token.Valid {
-		return 0, fmt.Errorf("invalid token")
+		return 0, 0, "", fmt.Errorf("invalid token")
 	}
 
 	// Получаем claims из токена
 	claims, ok := token.Claims.(jwt.MapClaims)
 	if !ok {
-		return 0, fmt.Errorf("claims extraction failed")
+		return 0, 0, "", fmt.Errorf("claims extraction failed")
 	}
 
 	// Проверяем, что claims содержит число в поле "sub"
 	sub, ok := claims["sub"].(float64)
 	userID := uint64(sub)
 	if !ok || userID <= 0 {
-		return 0, fmt.Errorf("claims does not contain valid sub")
+		return 0, 0, "", fmt.Errorf("claims does not contain valid sub")
 	}
 
 	_, ok = claims["exp"].(float64)
 	if !ok {
-		return 0, fmt.Errorf("claims does not contain valid exp")
+		return 0, 0, "", fmt.Errorf("claims does not contain valid exp")
 	}
 
 	_, ok = claims["nbf"].(float64)
 	if !ok {
-		return 0, fmt.Errorf("claims does not contain valid nbf")
+		return 0, 0, "", fmt.Errorf("claims does not contain valid nbf")
 	}
 
-	return userID, nil
-}
+	iat, ok := claims["iat"].(float64)
+	if !ok {
+		return 0, 0, "", fmt.Errorf("claims does not contain valid iat")
+	}
+
+	// Проверяем, что claims содержит id сессии в поле "jti"
+	jti, ok := claims["jti"].(string)
+	if !ok {
+		return 0, 0, "", fmt.Errorf("claims does not contain valid jti")
+	}
+	sessionID, err := strconv.ParseUint(jti, 10, 64)
+	if err != nil || sessionID == 0 {
+		return 0, 0, "", fmt.Errorf("claims does not contain valid jti")
+	}
 
-// extractJWTToken - извлекает токен из запроса и проверяет алгоритм подписи
-func (a *authorizator) extractJWTToken(r *http.Request) (*jwt.Token, error) {
-	// Получаем JWT из заголовка запроса
-	// AuthorizationHeaderExtractor - извлекает токен из заголовка Authorization
-	// Если в заголовке пришел Bearer <token>, то извлекает только <token>
-	return request.ParseFromRequest(r, request.AuthorizationHeaderExtractor, func(token *jwt.Token) (interface{}, error) {
-		// Проверяем метод подписи токена
-		if token.Method.Alg() != a.alg {
-			return nil, fmt.Errorf("unsupported signing method: %v, want %v", token.Method.Alg(), a.alg)
+	// Проверяем роль пользователя в поле "role"
+	role := models.RoleUser
+	if v, ok := claims["role"]; ok {
+		s, _ := v.(string)
+		role = models.UserRole(s)
+		if !role.Valid() {
+			return 0, 0, "", fmt.Errorf("claims does not contain valid role")
 		}
+	}
 
-		return []byte(a.key), nil
-	})
+	// Проверяем, что сессия и токен не отозваны
+	if err = a.validator.SessionValidate(r.Context(), userID, sessionID, time.Unix(int64(iat), 0)); err != nil {
+		return 0, 0, "", err
+	}
+
+	return userID, sessionID, role, nil
 }
 
 // GetUserID - возвращает userID из контекста
@@ -117,3 +194,30 @@ func GetUserID(ctx context.Context) (uint64, bool) {
 	}
 	return id, true
 }
+
+// GetUserRole - возвращает роль пользователя из контекста
+func GetUserRole(ctx context.Context) (models.UserRole, bool) {
+	role, ok := ctx.Value(ctxUserRole).(models.UserRole)
+	if !ok {
+		return "", false
+	}
+	return role, true
+}
+
+// GetAccessToken - возвращает персональный токен доступа из контекста, если запрос выполнен с персональным токеном
+func GetAccessToken(ctx context.Context) (*models.AccessToken, bool) {
+	t, ok := ctx.Value(ctxAccessToken).(*models.AccessToken)
+	if !ok {
+		return nil, false
+	}
+	return t, true
+}
+
+// GetSessionID - возвращает sessionID из контекста
+func GetSessionID(ctx context.Context) (uint64, bool) {
+	id, ok := ctx.Value(ctxSessionID).(uint64)
+	if !ok {
+		return 0, false
+	}
+	return id, true
+}
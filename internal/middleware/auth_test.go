@@ -18,11 +18,21 @@ import (
 // - [x] Токен содержит невалидное поле sub
 // - [x] Токен не содержит поле exp
 // - [x] Токен не содержит поле nbf
+// - [x] Токен не содержит поле jti
+// - [x] Токен содержит невалидное поле jti
+// - [x] Сессия отозвана
+// - [x] Сессия принадлежит другому пользователю
+// - [x] Токен не содержит поле iat
+// - [x] Токен выпущен до отзыва всех токенов пользователя
+// - [x] Токен содержит невалидное поле role
+// - [x] Успешная авторизация персональным токеном
+// - [x] Невалидный персональный токен
 
 func (suite *middlewareSuite) TestAuthMiddleware() {
 	suite.Run("success", func() {
 		claims := jwt.MapClaims{
 			"sub": 1,
+			"jti": "1",
 			"iat": time.Now().Unix(),
 			"nbf": time.Now().Unix(),
 			"exp": time.Now().Add(1 * time.Hour).Unix(),
@@ -48,6 +58,7 @@ func (suite *middlewareSuite) TestAuthMiddleware() {
 	suite.Run("token signed with wrong key", func() {
 		claims := jwt.MapClaims{
 			"sub": 1,
+			"jti": "1",
 			"iat": time.Now().Unix(),
 			"nbf": time.Now().Unix(),
 			"exp": time.Now().Add(1 * time.Hour).Unix(),
@@ -62,6 +73,7 @@ func (suite *middlewareSuite) TestAuthMiddleware() {
 	suite.Run("token signed with unsupported alg", func() {
 		claims := jwt.MapClaims{
 			"sub": 1,
+			"jti": "1",
 			"iat": time.Now().Unix(),
 			"nbf": time.Now().Unix(),
 			"exp": time.Now().Add(1 * time.Hour).Unix(),
@@ -75,6 +87,7 @@ func (suite *middlewareSuite) TestAuthMiddleware() {
 	suite.Run("token expired", func() {
 		claims := jwt.MapClaims{
 			"sub": 1,
+			"jti": "1",
 			"iat": time.Now().Unix(),
 			"nbf": time.Now().Unix(),
 			"exp": time.Now().Add(-1 * time.Hour).Unix(),
@@ -89,6 +102,7 @@ func (suite *middlewareSuite) TestAuthMiddleware() {
 	suite.Run("token used before time", func() {
 		claims := jwt.MapClaims{
 			"sub": 1,
+			"jti": "1",
 			"iat": time.Now().Unix(),
 			"nbf": time.Now().Add(1 * time.Hour).Unix(),
 			"exp": time.Now().Add(2 * time.Hour).Unix(),
@@ -102,6 +116,7 @@ func (suite *middlewareSuite) TestAuthMiddleware() {
 
 	suite.Run("token without sub claim", func() {
 		claims := jwt.MapClaims{
+			"jti": "1",
 			"iat": time.Now().Unix(),
 			"nbf": time.Now().Unix(),
 			"exp": time.Now().Add(1 * time.Hour).Unix(),
@@ -115,6 +130,7 @@ func (suite *middlewareSuite) TestAuthMiddleware() {
 	suite.Run("token with invalid sub claim", func() {
 		claims := jwt.MapClaims{
 			"sub": "invalid",
+			"jti": "1",
 			"iat": time.Now().Unix(),
 			"nbf": time.Now().Unix(),
 			"exp": time.Now().Add(1 * time.Hour).Unix(),
@@ -128,6 +144,7 @@ func (suite *middlewareSuite) TestAuthMiddleware() {
 	suite.Run("token without exp claim", func() {
 		claims := jwt.MapClaims{
 			"sub": 1,
+			"jti": "1",
 			"iat": time.Now().Unix(),
 			"nbf": time.Now().Unix(),
 		}
@@ -140,6 +157,7 @@ func (suite *middlewareSuite) TestAuthMiddleware() {
 	suite.Run("token without nbf claim", func() {
 		claims := jwt.MapClaims{
 			"sub": 1,
+			"jti": "1",
 			"iat": time.Now().Unix(),
 			"exp": time.Now().Add(1 * time.Hour).Unix(),
 		}
@@ -149,6 +167,114 @@ func (suite *middlewareSuite) TestAuthMiddleware() {
 		suite.Equal(http.StatusUnauthorized, res.StatusCode)
 	})
 
+	suite.Run("token without jti claim", func() {
+		claims := jwt.MapClaims{
+			"sub": 1,
+			"iat": time.Now().Unix(),
+			"nbf": time.Now().Unix(),
+			"exp": time.Now().Add(1 * time.Hour).Unix(),
+		}
+		token := suite.generateJWTToken(claims, "HS256", "test1234567890")
+		res := suite.httpRequest(http.MethodGet, "/private", "", "", token)
+		defer res.Body.Close()
+		suite.Equal(http.StatusUnauthorized, res.StatusCode)
+	})
+
+	suite.Run("token with invalid jti claim", func() {
+		claims := jwt.MapClaims{
+			"sub": 1,
+			"jti": "invalid",
+			"iat": time.Now().Unix(),
+			"nbf": time.Now().Unix(),
+			"exp": time.Now().Add(1 * time.Hour).Unix(),
+		}
+		token := suite.generateJWTToken(claims, "HS256", "test1234567890")
+		res := suite.httpRequest(http.MethodGet, "/private", "", "", token)
+		defer res.Body.Close()
+		suite.Equal(http.StatusUnauthorized, res.StatusCode)
+	})
+
+	suite.Run("revoked session", func() {
+		claims := jwt.MapClaims{
+			"sub": 1,
+			"jti": "3",
+			"iat": time.Now().Unix(),
+			"nbf": time.Now().Unix(),
+			"exp": time.Now().Add(1 * time.Hour).Unix(),
+		}
+		token := suite.generateJWTToken(claims, "HS256", "test1234567890")
+		res := suite.httpRequest(http.MethodGet, "/private", "", "", token)
+		defer res.Body.Close()
+		suite.Equal(http.StatusUnauthorized, res.StatusCode)
+	})
+
+	suite.Run("session belongs to another user", func() {
+		claims := jwt.MapClaims{
+			"sub": 1,
+			"jti": "2",
+			"iat": time.Now().Unix(),
+			"nbf": time.Now().Unix(),
+			"exp": time.Now().Add(1 * time.Hour).Unix(),
+		}
+		token := suite.generateJWTToken(claims, "HS256", "test1234567890")
+		res := suite.httpRequest(http.MethodGet, "/private", "", "", token)
+		defer res.Body.Close()
+		suite.Equal(http.StatusUnauthorized, res.StatusCode)
+	})
+
+	suite.Run("token without iat claim", func() {
+		claims := jwt.MapClaims{
+			"sub": 1,
+			"jti": "1",
+			"nbf": time.Now().Unix(),
+			"exp": time.Now().Add(1 * time.Hour).Unix(),
+		}
+		token := suite.generateJWTToken(claims, "HS256", "test1234567890")
+		res := suite.httpRequest(http.MethodGet, "/private", "", "", token)
+		defer res.Body.Close()
+		suite.Equal(http.StatusUnauthorized, res.StatusCode)
+	})
+
+	suite.Run("token issued before all tokens were revoked", func() {
+		claims := jwt.MapClaims{
+			"sub": 1,
+			"jti": "1",
+			"iat": time.Now().Add(-1 * time.Hour).Unix(),
+			"nbf": time.Now().Add(-1 * time.Hour).Unix(),
+			"exp": time.Now().Add(1 * time.Hour).Unix(),
+		}
+		token := suite.generateJWTToken(claims, "HS256", "test1234567890")
+		res := suite.httpRequest(http.MethodGet, "/private", "", "", token)
+		defer res.Body.Close()
+		suite.Equal(http.StatusUnauthorized, res.StatusCode)
+	})
+
+	suite.Run("token contains invalid role", func() {
+		claims := jwt.MapClaims{
+			"sub":  1,
+			"jti":  "1",
+			"iat":  time.Now().Unix(),
+			"nbf":  time.Now().Unix(),
+			"exp":  time.Now().Add(1 * time.Hour).Unix(),
+			"role": "superuser",
+		}
+		token := suite.generateJWTToken(claims, "HS256", "test1234567890")
+		res := suite.httpRequest(http.MethodGet, "/private", "", "", token)
+		defer res.Body.Close()
+		suite.Equal(http.StatusUnauthorized, res.StatusCode)
+	})
+
+	suite.Run("access token", func() {
+		res := suite.httpRequest(http.MethodGet, "/private", "", "", "gmp_orders")
+		defer res.Body.Close()
+		suite.Equal(http.StatusOK, res.StatusCode)
+	})
+
+	suite.Run("invalid access token", func() {
+		res := suite.httpRequest(http.MethodGet, "/private", "", "", "gmp_invalid")
+		defer res.Body.Close()
+		suite.Equal(http.StatusUnauthorized, res.StatusCode)
+	})
 }
 
 func (suite *middlewareSuite) generateJWTToken(claims jwt.Claims, alg, key string) string {
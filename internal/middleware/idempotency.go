@@ -0,0 +1,139 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"time"
+
+	chimw "github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+
+	"gophermart-loyalty/internal/errs"
+	"gophermart-loyalty/internal/models"
+)
+
+const (
+	// IdempotencyKeyHeader - заголовок запроса с ключом идемпотентности
+	IdempotencyKeyHeader = "Idempotency-Key"
+	// IdempotentReplayedHeader - заголовок ответа, повторенного по ключу идемпотентности
+	IdempotentReplayedHeader = "Idempotent-Replayed"
+	// idempotencyKeyMaxLen - максимальная длина ключа идемпотентности
+	idempotencyKeyMaxLen = 255
+)
+
+// IdempotencyStore - хранит ключи идемпотентности и записанные ответы на запросы.
+type IdempotencyStore interface {
+	IdempotencyKeyReserve(ctx context.Context, userID uint64, key, fingerprint string, ttl time.Duration) (*models.IdempotencyKey, error)
+	IdempotencyKeyComplete(ctx context.Context, k *models.IdempotencyKey) error
+	IdempotencyKeyRelease(ctx context.Context, userID uint64, key string) error
+}
+
+// Idempotency - middleware для повторения ответа на запрос с тем же ключом идемпотентности.
+// Формат заголовка запроса:
+//    Idempotency-Key: <key>
+//
+// Ключ занимается пользователем на время ttl. Ответ на первый запрос с ключом записывается,
+// и на повторный запрос с тем же ключом и тем же методом, путем, параметрами и телом возвращается записанный ответ
+// с заголовком Idempotent-Replayed: true, а сам запрос не выполняется.
+// Запрос с тем же ключом и другими параметрами или телом отклоняется с errs.ErrIdempotencyKeyReused,
+// а запрос, пока первый запрос с ключом еще выполняется, - с errs.ErrIdempotencyKeyInProgress.
+// Ответы с кодом 5xx не записываются, и ключ освобождается, чтобы запрос можно было повторить.
+//
+// Запросы без заголовка и все запросы при нулевом ttl выполняются как обычно.
+// Должен использоваться после Auth.
+func Idempotency(store IdempotencyStore, ttl time.Duration) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			key := r.Header.Get(IdempotencyKeyHeader)
+			if key == "" || ttl <= 0 {
+				next.ServeHTTP(w, r)
+				return
+			}
+			if len(key) > idempotencyKeyMaxLen {
+				_ = render.Render(w, r, errs.NewErrResponse(errs.ErrIdempotencyKeyInvalid))
+				return
+			}
+			userID, ok := GetUserID(r.Context())
+			if !ok {
+				_ = render.Render(w, r, errs.ErrResponseUnauthorized)
+				return
+			}
+
+			// Читаем тело запроса для отпечатка и возвращаем его хандлеру
+			body, err := io.ReadAll(r.Body)
+			if err != nil {
+				_ = render.Render(w, r, errs.ErrResponseBadRequest)
+				return
+			}
+			r.Body = io.NopCloser(bytes.NewReader(body))
+			fingerprint := requestFingerprint(r, body)
+
+			stored, err := store.IdempotencyKeyReserve(r.Context(), userID, key, fingerprint, ttl)
+			if err != nil {
+				_ = render.Render(w, r, errs.NewErrResponse(err))
+				return
+			}
+			if stored != nil {
+				// Повторяем записанный ответ
+				if stored.ContentType != "" {
+					w.Header().Set("Content-Type", stored.ContentType)
+				}
+				w.Header().Set(IdempotentReplayedHeader, "true")
+				w.WriteHeader(stored.StatusCode)
+				_, _ = w.Write(stored.Body)
+				return
+			}
+
+			// Ключ записывается и освобождается без контекста запроса: клиент, не дождавшийся ответа,
+			// закрывает соединение, но записанный ответ нужен именно для его повторного запроса
+			completed := false
+			defer func() {
+				if !completed {
+					_ = store.IdempotencyKeyRelease(context.Background(), userID, key)
+				}
+			}()
+
+			// Выполняем запрос, записывая ответ
+			ww := chimw.NewWrapResponseWriter(w, r.ProtoMajor)
+			buf := &bytes.Buffer{}
+			ww.Tee(buf)
+			next.ServeHTTP(ww, r)
+
+			status := ww.Status()
+			if status == 0 {
+				status = http.StatusOK
+			}
+			if status >= http.StatusInternalServerError {
+				return
+			}
+			// Если ответ не удалось записать, ключ остается занятым до истечения ttl,
+			// чтобы повторный запрос не выполнился еще раз
+			completed = true
+			_ = store.IdempotencyKeyComplete(context.Background(), &models.IdempotencyKey{
+				UserID:      userID,
+				Key:         key,
+				Fingerprint: fingerprint,
+				StatusCode:  status,
+				ContentType: ww.Header().Get("Content-Type"),
+				Body:        buf.Bytes(),
+			})
+		})
+	}
+}
+
+// requestFingerprint - возвращает отпечаток запроса: хэш метода, пути, параметров и тела запроса.
+func requestFingerprint(r *http.Request, body []byte) string {
+	h := sha256.New()
+	h.Write([]byte(r.Method))
+	h.Write([]byte{0})
+	h.Write([]byte(r.URL.Path))
+	h.Write([]byte{0})
+	h.Write([]byte(r.URL.RawQuery))
+	h.Write([]byte{0})
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
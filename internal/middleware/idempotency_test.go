@@ -0,0 +1,129 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"gophermart-loyalty/internal/errs"
+	"gophermart-loyalty/internal/models"
+)
+
+func (suite *middlewareSuite) idempotentRequest(body, key string) *http.Response {
+	target := fmt.Sprintf("%s/idempotent", suite.testServer.URL)
+	req, err := http.NewRequest(http.MethodPost, target, bytes.NewBuffer([]byte(body)))
+	suite.NoError(err)
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", suite.sessionToken()))
+	if key != "" {
+		req.Header.Set(IdempotencyKeyHeader, key)
+	}
+	res, err := http.DefaultClient.Do(req)
+	suite.NoError(err)
+	return res
+}
+
+func (suite *middlewareSuite) TestIdempotency() {
+	suite.Run("request without key", func() {
+		for i := 1; i <= 2; i++ {
+			res := suite.idempotentRequest("order", "")
+			suite.Equal(http.StatusCreated, res.StatusCode)
+			suite.Equal(fmt.Sprintf("order #%d", i), string(suite.getBody(res.Body)))
+			res.Body.Close()
+		}
+	})
+
+	suite.Run("repeated request is replayed", func() {
+		suite.idemCalls = 0
+		for i := 1; i <= 2; i++ {
+			res := suite.idempotentRequest("order", "key-1")
+			suite.Equal(http.StatusCreated, res.StatusCode)
+			suite.Equal("text/plain", res.Header.Get("Content-Type"))
+			suite.Equal("order #1", string(suite.getBody(res.Body)))
+			if i == 2 {
+				suite.Equal("true", res.Header.Get(IdempotentReplayedHeader))
+			} else {
+				suite.Empty(res.Header.Get(IdempotentReplayedHeader))
+			}
+			res.Body.Close()
+		}
+		suite.Equal(1, suite.idemCalls)
+	})
+
+	suite.Run("key reused with another body", func() {
+		res := suite.idempotentRequest("another order", "key-1")
+		defer res.Body.Close()
+		suite.Equal(http.StatusUnprocessableEntity, res.StatusCode)
+	})
+
+	suite.Run("key reused with another query", func() {
+		target := fmt.Sprintf("%s/idempotent?dry_run=true", suite.testServer.URL)
+		req, err := http.NewRequest(http.MethodPost, target, bytes.NewBuffer([]byte("order")))
+		suite.NoError(err)
+		req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", suite.sessionToken()))
+		req.Header.Set(IdempotencyKeyHeader, "key-1")
+		res, err := http.DefaultClient.Do(req)
+		suite.NoError(err)
+		defer res.Body.Close()
+		suite.Equal(http.StatusUnprocessableEntity, res.StatusCode)
+	})
+
+	suite.Run("request in progress", func() {
+		// Первый запрос с ключом еще выполняется: ответ не записан
+		r := &http.Request{Method: http.MethodPost, URL: &url.URL{Path: "/idempotent"}}
+		suite.idemStore.keys["1/key-2"] = &models.IdempotencyKey{UserID: 1, Key: "key-2", Fingerprint: requestFingerprint(r, []byte("order"))}
+		res := suite.idempotentRequest("order", "key-2")
+		defer res.Body.Close()
+		suite.Equal(http.StatusConflict, res.StatusCode)
+	})
+
+	suite.Run("failed request releases key", func() {
+		suite.idemCalls = 0
+		for i := 1; i <= 2; i++ {
+			res := suite.idempotentRequest("fail", "key-3")
+			suite.Equal(http.StatusInternalServerError, res.StatusCode)
+			res.Body.Close()
+		}
+		suite.Equal(2, suite.idemCalls)
+		suite.NotContains(suite.idemStore.keys, "1/key-3")
+	})
+
+	suite.Run("key too long", func() {
+		res := suite.idempotentRequest("order", strings.Repeat("k", idempotencyKeyMaxLen+1))
+		defer res.Body.Close()
+		suite.Equal(http.StatusBadRequest, res.StatusCode)
+	})
+}
+
+// idempotencyStoreStub - заглушка для IdempotencyStore, хранящая ключи в памяти.
+type idempotencyStoreStub struct {
+	keys map[string]*models.IdempotencyKey
+}
+
+func (s *idempotencyStoreStub) IdempotencyKeyReserve(_ context.Context, userID uint64, key, fingerprint string, _ time.Duration) (*models.IdempotencyKey, error) {
+	stored, ok := s.keys[fmt.Sprintf("%d/%s", userID, key)]
+	if !ok {
+		s.keys[fmt.Sprintf("%d/%s", userID, key)] = &models.IdempotencyKey{UserID: userID, Key: key, Fingerprint: fingerprint}
+		return nil, nil
+	}
+	if stored.Fingerprint != fingerprint {
+		return nil, errs.ErrIdempotencyKeyReused
+	}
+	if !stored.Completed() {
+		return nil, errs.ErrIdempotencyKeyInProgress
+	}
+	return stored, nil
+}
+
+func (s *idempotencyStoreStub) IdempotencyKeyComplete(_ context.Context, k *models.IdempotencyKey) error {
+	s.keys[fmt.Sprintf("%d/%s", k.UserID, k.Key)] = k
+	return nil
+}
+
+func (s *idempotencyStoreStub) IdempotencyKeyRelease(_ context.Context, userID uint64, key string) error {
+	delete(s.keys, fmt.Sprintf("%d/%s", userID, key))
+	return nil
+}
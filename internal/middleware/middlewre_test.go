@@ -2,13 +2,20 @@ package middleware
 
 import (
 	"bytes"
+	"context"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/suite"
+
+	"gophermart-loyalty/internal/config"
+	"gophermart-loyalty/internal/jwks"
+	"gophermart-loyalty/internal/models"
 )
 
 func TestMiddlewareSuite(t *testing.T) {
@@ -18,6 +25,8 @@ func TestMiddlewareSuite(t *testing.T) {
 type middlewareSuite struct {
 	suite.Suite
 	testServer *httptest.Server
+	idemStore  *idempotencyStoreStub
+	idemCalls  int
 }
 
 func (suite *middlewareSuite) SetupTest() {
@@ -26,7 +35,40 @@ func (suite *middlewareSuite) SetupTest() {
 		w.WriteHeader(http.StatusOK)
 		w.Write([]byte("Private area"))
 	})
-	r := Auth("HS256", "test1234567890")(mux)
+	mux.Handle("/staff", RequireRole(models.RoleSupport, models.RoleAdmin)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		role, _ := GetUserRole(r.Context())
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(role))
+	})))
+	mux.Handle("/orders", RequireScope(models.ScopeOrdersRead)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})))
+	mux.Handle("/account", RequireSession(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})))
+	suite.idemStore = &idempotencyStoreStub{keys: map[string]*models.IdempotencyKey{}}
+	suite.idemCalls = 0
+	mux.Handle("/idempotent", Idempotency(suite.idemStore, time.Hour)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		suite.idemCalls++
+		body, _ := io.ReadAll(r.Body)
+		if string(body) == "fail" {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "text/plain")
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(fmt.Sprintf("%s #%d", body, suite.idemCalls)))
+	})))
+	keys, err := jwks.NewKeySet(&config.Auth{SigningAlg: "HS256", SigningKey: "test1234567890"})
+	suite.Require().NoError(err)
+	r := Auth(keys.Keyfunc, &validatorStub{
+		sessions:   map[uint64]uint64{1: 1, 2: 2}, // сессии 1 и 2 пользователей 1 и 2 активны, остальные отозваны
+		validAfter: time.Now().Add(-time.Minute),  // токены, выпущенные раньше, отозваны
+		accessTokens: map[string]*models.AccessToken{
+			"gmp_orders":  {ID: 1, UserID: 1, Scopes: []models.AccessTokenScope{models.ScopeOrdersRead}},
+			"gmp_balance": {ID: 2, UserID: 1, Scopes: []models.AccessTokenScope{models.ScopeBalanceRead}},
+		},
+	})(mux)
 	suite.testServer = httptest.NewServer(r)
 }
 
@@ -55,3 +97,31 @@ func (suite *middlewareSuite) getBody(body io.Reader) []byte {
 	suite.NoError(err)
 	return buf.Bytes()
 }
+
+// validatorStub - заглушка для Validator.
+// sessions - активные сессии: id сессии => id пользователя.
+// validAfter - токены, выпущенные раньше этого времени, недействительны.
+// accessTokens - активные персональные токены.
+type validatorStub struct {
+	sessions     map[uint64]uint64
+	validAfter   time.Time
+	accessTokens map[string]*models.AccessToken
+}
+
+func (s *validatorStub) SessionValidate(_ context.Context, userID, sessionID uint64, issuedAt time.Time) error {
+	if id, ok := s.sessions[sessionID]; !ok || id != userID {
+		return errors.New("invalid session")
+	}
+	if issuedAt.Before(s.validAfter) {
+		return errors.New("token revoked")
+	}
+	return nil
+}
+
+func (s *validatorStub) AccessTokenValidate(_ context.Context, token string) (*models.AccessToken, error) {
+	t, ok := s.accessTokens[token]
+	if !ok {
+		return nil, errors.New("invalid access token")
+	}
+	return t, nil
+}
@@ -0,0 +1,61 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+)
+
+func (suite *middlewareSuite) TestRealIP() {
+	trusted, err := ParseTrustedProxies([]string{"10.0.0.0/8", "192.168.1.1"})
+	suite.Require().NoError(err)
+
+	tests := []struct {
+		name       string
+		trusted    bool
+		remoteAddr string
+		xff        string
+		xRealIP    string
+		want       string
+	}{
+		{name: "no proxies configured", remoteAddr: "203.0.113.7:5000", xff: "198.51.100.1", want: "203.0.113.7:5000"},
+		{name: "spoofed header from untrusted peer", trusted: true, remoteAddr: "203.0.113.7:5000", xff: "198.51.100.1", xRealIP: "198.51.100.2", want: "203.0.113.7:5000"},
+		{name: "trusted proxy", trusted: true, remoteAddr: "10.1.2.3:5000", xff: "198.51.100.1", want: "198.51.100.1"},
+		{name: "client prepends fake hop", trusted: true, remoteAddr: "10.1.2.3:5000", xff: "1.2.3.4, 198.51.100.1, 192.168.1.1", want: "198.51.100.1"},
+		{name: "all hops trusted", trusted: true, remoteAddr: "10.1.2.3:5000", xff: "10.0.0.5, 192.168.1.1", want: "10.0.0.5"},
+		{name: "x-real-ip from trusted proxy", trusted: true, remoteAddr: "192.168.1.1:5000", xRealIP: "198.51.100.2", want: "198.51.100.2"},
+		{name: "invalid header", trusted: true, remoteAddr: "10.1.2.3:5000", xff: "not-an-ip", want: "10.1.2.3:5000"},
+	}
+	for _, tt := range tests {
+		suite.Run(tt.name, func() {
+			var nets = trusted
+			if !tt.trusted {
+				nets = nil
+			}
+			var got string
+			h := RealIP(nets)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				got = r.RemoteAddr
+			}))
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.RemoteAddr = tt.remoteAddr
+			if tt.xff != "" {
+				req.Header.Set("X-Forwarded-For", tt.xff)
+			}
+			if tt.xRealIP != "" {
+				req.Header.Set("X-Real-IP", tt.xRealIP)
+			}
+			h.ServeHTTP(httptest.NewRecorder(), req)
+			suite.Equal(tt.want, got)
+		})
+	}
+}
+
+func (suite *middlewareSuite) TestParseTrustedProxies() {
+	nets, err := ParseTrustedProxies([]string{"10.0.0.0/8", " 127.0.0.1 ", "::1", ""})
+	suite.NoError(err)
+	suite.Len(nets, 3)
+
+	_, err = ParseTrustedProxies([]string{"10.0.0.0/33"})
+	suite.Error(err)
+	_, err = ParseTrustedProxies([]string{"proxy.local"})
+	suite.Error(err)
+}
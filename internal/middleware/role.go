@@ -0,0 +1,34 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/go-chi/render"
+
+	"gophermart-loyalty/internal/errs"
+	"gophermart-loyalty/internal/models"
+)
+
+// RequireRole - middleware для проверки роли пользователя.
+// Пропускает запрос, только если пользователь имеет одну из ролей roles.
+// Должен использоваться после Auth.
+func RequireRole(roles ...models.UserRole) func(next http.Handler) http.Handler {
+	allowed := make(map[models.UserRole]struct{}, len(roles))
+	for _, role := range roles {
+		allowed[role] = struct{}{}
+	}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			role, ok := GetUserRole(r.Context())
+			if !ok {
+				_ = render.Render(w, r, errs.ErrResponseUnauthorized)
+				return
+			}
+			if _, ok = allowed[role]; !ok {
+				_ = render.Render(w, r, errs.ErrResponseForbidden)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
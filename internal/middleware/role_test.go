@@ -0,0 +1,51 @@
+package middleware
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+func (suite *middlewareSuite) TestRequireRole() {
+	tokenWithRole := func(role interface{}) string {
+		claims := jwt.MapClaims{
+			"sub": 1,
+			"jti": "1",
+			"iat": time.Now().Unix(),
+			"nbf": time.Now().Unix(),
+			"exp": time.Now().Add(1 * time.Hour).Unix(),
+		}
+		if role != nil {
+			claims["role"] = role
+		}
+		return suite.generateJWTToken(claims, "HS256", "test1234567890")
+	}
+
+	suite.Run("allowed role", func() {
+		for _, role := range []string{"support", "admin"} {
+			res := suite.httpRequest(http.MethodGet, "/staff", "", "", tokenWithRole(role))
+			suite.Equal(http.StatusOK, res.StatusCode)
+			suite.Equal(role, string(suite.getBody(res.Body)))
+			res.Body.Close()
+		}
+	})
+
+	suite.Run("forbidden role", func() {
+		res := suite.httpRequest(http.MethodGet, "/staff", "", "", tokenWithRole("user"))
+		defer res.Body.Close()
+		suite.Equal(http.StatusForbidden, res.StatusCode)
+	})
+
+	suite.Run("token without role has user role", func() {
+		res := suite.httpRequest(http.MethodGet, "/staff", "", "", tokenWithRole(nil))
+		defer res.Body.Close()
+		suite.Equal(http.StatusForbidden, res.StatusCode)
+	})
+
+	suite.Run("unauthorized", func() {
+		res := suite.httpRequest(http.MethodGet, "/staff", "", "", "")
+		defer res.Body.Close()
+		suite.Equal(http.StatusUnauthorized, res.StatusCode)
+	})
+}
@@ -0,0 +1,39 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/go-chi/render"
+
+	"gophermart-loyalty/internal/errs"
+	"gophermart-loyalty/internal/models"
+)
+
+// RequireScope - middleware для проверки области доступа персонального токена.
+// Запрос с персональным токеном пропускается, только если токен имеет область доступа scope.
+// Запрос с JWT-токеном сессии пропускается всегда.
+// Должен использоваться после Auth.
+func RequireScope(scope models.AccessTokenScope) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if t, ok := GetAccessToken(r.Context()); ok && !t.HasScope(scope) {
+				_ = render.Render(w, r, errs.ErrResponseForbidden)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// RequireSession - middleware, которое пропускает только запросы с JWT-токеном сессии.
+// Используется для управления учетной записью: персональный токен не дает к нему доступа.
+// Должен использоваться после Auth.
+func RequireSession(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := GetSessionID(r.Context()); !ok {
+			_ = render.Render(w, r, errs.ErrResponseForbidden)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,53 @@
+package middleware
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+func (suite *middlewareSuite) sessionToken() string {
+	claims := jwt.MapClaims{
+		"sub": 1,
+		"jti": "1",
+		"iat": time.Now().Unix(),
+		"nbf": time.Now().Unix(),
+		"exp": time.Now().Add(1 * time.Hour).Unix(),
+	}
+	return suite.generateJWTToken(claims, "HS256", "test1234567890")
+}
+
+func (suite *middlewareSuite) TestRequireScope() {
+	suite.Run("access token with scope", func() {
+		res := suite.httpRequest(http.MethodGet, "/orders", "", "", "gmp_orders")
+		defer res.Body.Close()
+		suite.Equal(http.StatusOK, res.StatusCode)
+	})
+
+	suite.Run("access token without scope", func() {
+		res := suite.httpRequest(http.MethodGet, "/orders", "", "", "gmp_balance")
+		defer res.Body.Close()
+		suite.Equal(http.StatusForbidden, res.StatusCode)
+	})
+
+	suite.Run("session token", func() {
+		res := suite.httpRequest(http.MethodGet, "/orders", "", "", suite.sessionToken())
+		defer res.Body.Close()
+		suite.Equal(http.StatusOK, res.StatusCode)
+	})
+}
+
+func (suite *middlewareSuite) TestRequireSession() {
+	suite.Run("session token", func() {
+		res := suite.httpRequest(http.MethodGet, "/account", "", "", suite.sessionToken())
+		defer res.Body.Close()
+		suite.Equal(http.StatusOK, res.StatusCode)
+	})
+
+	suite.Run("access token", func() {
+		res := suite.httpRequest(http.MethodGet, "/account", "", "", "gmp_orders")
+		defer res.Body.Close()
+		suite.Equal(http.StatusForbidden, res.StatusCode)
+	})
+}
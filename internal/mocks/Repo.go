@@ -4,7 +4,9 @@ package mocks
 
 import (
 	context "context"
+	decimal "github.com/shopspring/decimal"
 	models "gophermart-loyalty/internal/models"
+	time "time"
 
 	mock "github.com/stretchr/testify/mock"
 
@@ -16,13 +18,735 @@ type Repo struct {
 	mock.Mock
 }
 
+// AccessTokenCreate provides a mock function with given fields: ctx, t, ttlSeconds
+func (_m *Repo) AccessTokenCreate(ctx context.Context, t *models.AccessToken, ttlSeconds int64) error {
+	ret := _m.Called(ctx, t, ttlSeconds)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, *models.AccessToken, int64) error); ok {
+		r0 = rf(ctx, t, ttlSeconds)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// AccessTokenGetByUser provides a mock function with given fields: ctx, userID
+func (_m *Repo) AccessTokenGetByUser(ctx context.Context, userID uint64) ([]*models.AccessToken, error) {
+	ret := _m.Called(ctx, userID)
+
+	var r0 []*models.AccessToken
+	if rf, ok := ret.Get(0).(func(context.Context, uint64) []*models.AccessToken); ok {
+		r0 = rf(ctx, userID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*models.AccessToken)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, uint64) error); ok {
+		r1 = rf(ctx, userID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// AccessTokenRevoke provides a mock function with given fields: ctx, userID, tokenID
+func (_m *Repo) AccessTokenRevoke(ctx context.Context, userID uint64, tokenID uint64) error {
+	ret := _m.Called(ctx, userID, tokenID)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, uint64, uint64) error); ok {
+		r0 = rf(ctx, userID, tokenID)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// AccessTokenUse provides a mock function with given fields: ctx, tokenHash
+func (_m *Repo) AccessTokenUse(ctx context.Context, tokenHash string) (*models.AccessToken, error) {
+	ret := _m.Called(ctx, tokenHash)
+
+	var r0 *models.AccessToken
+	if rf, ok := ret.Get(0).(func(context.Context, string) *models.AccessToken); ok {
+		r0 = rf(ctx, tokenHash)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.AccessToken)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, tokenHash)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// DataExportCreate provides a mock function with given fields: ctx, e
+func (_m *Repo) DataExportCreate(ctx context.Context, e *models.DataExport) error {
+	ret := _m.Called(ctx, e)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, *models.DataExport) error); ok {
+		r0 = rf(ctx, e)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// DataExportDeleteExpired provides a mock function with given fields: ctx
+func (_m *Repo) DataExportDeleteExpired(ctx context.Context) (int64, error) {
+	ret := _m.Called(ctx)
+
+	var r0 int64
+	if rf, ok := ret.Get(0).(func(context.Context) int64); ok {
+		r0 = rf(ctx)
+	} else {
+		r0 = ret.Get(0).(int64)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context) error); ok {
+		r1 = rf(ctx)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// DataExportGet provides a mock function with given fields: ctx, userID, exportID
+func (_m *Repo) DataExportGet(ctx context.Context, userID uint64, exportID uint64) (*models.DataExport, error) {
+	ret := _m.Called(ctx, userID, exportID)
+
+	var r0 *models.DataExport
+	if rf, ok := ret.Get(0).(func(context.Context, uint64, uint64) *models.DataExport); ok {
+		r0 = rf(ctx, userID, exportID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.DataExport)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, uint64, uint64) error); ok {
+		r1 = rf(ctx, userID, exportID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// DataExportProcessFurther provides a mock function with given fields: ctx, ttlSeconds, buildFunc
+func (_m *Repo) DataExportProcessFurther(ctx context.Context, ttlSeconds int64, buildFunc repo.DataExportFunc) (*models.DataExport, error) {
+	ret := _m.Called(ctx, ttlSeconds, buildFunc)
+
+	var r0 *models.DataExport
+	if rf, ok := ret.Get(0).(func(context.Context, int64, repo.DataExportFunc) *models.DataExport); ok {
+		r0 = rf(ctx, ttlSeconds, buildFunc)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.DataExport)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, int64, repo.DataExportFunc) error); ok {
+		r1 = rf(ctx, ttlSeconds, buildFunc)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// IdempotencyKeyComplete provides a mock function with given fields: ctx, k
+func (_m *Repo) IdempotencyKeyComplete(ctx context.Context, k *models.IdempotencyKey) error {
+	ret := _m.Called(ctx, k)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, *models.IdempotencyKey) error); ok {
+		r0 = rf(ctx, k)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// IdempotencyKeyDeleteExpired provides a mock function with given fields: ctx
+func (_m *Repo) IdempotencyKeyDeleteExpired(ctx context.Context) (int64, error) {
+	ret := _m.Called(ctx)
+
+	var r0 int64
+	if rf, ok := ret.Get(0).(func(context.Context) int64); ok {
+		r0 = rf(ctx)
+	} else {
+		r0 = ret.Get(0).(int64)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context) error); ok {
+		r1 = rf(ctx)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// IdempotencyKeyRelease provides a mock function with given fields: ctx, userID, key
+func (_m *Repo) IdempotencyKeyRelease(ctx context.Context, userID uint64, key string) error {
+	ret := _m.Called(ctx, userID, key)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, uint64, string) error); ok {
+		r0 = rf(ctx, userID, key)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// IdempotencyKeyReserve provides a mock function with given fields: ctx, k, ttlSeconds
+func (_m *Repo) IdempotencyKeyReserve(ctx context.Context, k *models.IdempotencyKey, ttlSeconds int64) (*models.IdempotencyKey, error) {
+	ret := _m.Called(ctx, k, ttlSeconds)
+
+	var r0 *models.IdempotencyKey
+	if rf, ok := ret.Get(0).(func(context.Context, *models.IdempotencyKey, int64) *models.IdempotencyKey); ok {
+		r0 = rf(ctx, k, ttlSeconds)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.IdempotencyKey)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, *models.IdempotencyKey, int64) error); ok {
+		r1 = rf(ctx, k, ttlSeconds)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// LoginAttemptRegister provides a mock function with given fields: ctx, key, windowSeconds
+func (_m *Repo) LoginAttemptRegister(ctx context.Context, key string, windowSeconds int64) (*models.LoginFailure, error) {
+	ret := _m.Called(ctx, key, windowSeconds)
+
+	var r0 *models.LoginFailure
+	if rf, ok := ret.Get(0).(func(context.Context, string, int64) *models.LoginFailure); ok {
+		r0 = rf(ctx, key, windowSeconds)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.LoginFailure)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, string, int64) error); ok {
+		r1 = rf(ctx, key, windowSeconds)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// LoginAttemptRelease provides a mock function with given fields: ctx, key
+func (_m *Repo) LoginAttemptRelease(ctx context.Context, key string) error {
+	ret := _m.Called(ctx, key)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) error); ok {
+		r0 = rf(ctx, key)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// LoginChallengeConsume provides a mock function with given fields: ctx, challengeID
+func (_m *Repo) LoginChallengeConsume(ctx context.Context, challengeID uint64) error {
+	ret := _m.Called(ctx, challengeID)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, uint64) error); ok {
+		r0 = rf(ctx, challengeID)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// LoginChallengeCreate provides a mock function with given fields: ctx, c, ttlSeconds
+func (_m *Repo) LoginChallengeCreate(ctx context.Context, c *models.LoginChallenge, ttlSeconds int64) error {
+	ret := _m.Called(ctx, c, ttlSeconds)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, *models.LoginChallenge, int64) error); ok {
+		r0 = rf(ctx, c, ttlSeconds)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// LoginChallengeFail provides a mock function with given fields: ctx, challengeID
+func (_m *Repo) LoginChallengeFail(ctx context.Context, challengeID uint64) error {
+	ret := _m.Called(ctx, challengeID)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, uint64) error); ok {
+		r0 = rf(ctx, challengeID)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// LoginChallengeGet provides a mock function with given fields: ctx, tokenHash, maxAttempts
+func (_m *Repo) LoginChallengeGet(ctx context.Context, tokenHash string, maxAttempts int) (*models.LoginChallenge, error) {
+	ret := _m.Called(ctx, tokenHash, maxAttempts)
+
+	var r0 *models.LoginChallenge
+	if rf, ok := ret.Get(0).(func(context.Context, string, int) *models.LoginChallenge); ok {
+		r0 = rf(ctx, tokenHash, maxAttempts)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.LoginChallenge)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, string, int) error); ok {
+		r1 = rf(ctx, tokenHash, maxAttempts)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// LoginFailureBlock provides a mock function with given fields: ctx, key, blockSeconds
+func (_m *Repo) LoginFailureBlock(ctx context.Context, key string, blockSeconds int64) (*models.LoginFailure, error) {
+	ret := _m.Called(ctx, key, blockSeconds)
+
+	var r0 *models.LoginFailure
+	if rf, ok := ret.Get(0).(func(context.Context, string, int64) *models.LoginFailure); ok {
+		r0 = rf(ctx, key, blockSeconds)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.LoginFailure)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, string, int64) error); ok {
+		r1 = rf(ctx, key, blockSeconds)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// LoginFailureReset provides a mock function with given fields: ctx, key
+func (_m *Repo) LoginFailureReset(ctx context.Context, key string) error {
+	ret := _m.Called(ctx, key)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) error); ok {
+		r0 = rf(ctx, key)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// OperationAdjust provides a mock function with given fields: ctx, op, adj
+func (_m *Repo) OperationAdjust(ctx context.Context, op *models.Operation, adj *models.Adjustment) error {
+	ret := _m.Called(ctx, op, adj)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, *models.Operation, *models.Adjustment) error); ok {
+		r0 = rf(ctx, op, adj)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // OperationCreate provides a mock function with given fields: ctx, op
 func (_m *Repo) OperationCreate(ctx context.Context, op *models.Operation) error {
 	ret := _m.Called(ctx, op)
 
 	var r0 error
-	if rf, ok := ret.Get(0).(func(context.Context, *models.Operation) error); ok {
-		r0 = rf(ctx, op)
+	if rf, ok := ret.Get(0).(func(context.Context, *models.Operation) error); ok {
+		r0 = rf(ctx, op)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// OperationExpirationCandidates provides a mock function with given fields: ctx, afterID, olderThanSeconds, limit
+func (_m *Repo) OperationExpirationCandidates(ctx context.Context, afterID uint64, olderThanSeconds int64, limit int) ([]uint64, error) {
+	ret := _m.Called(ctx, afterID, olderThanSeconds, limit)
+
+	var r0 []uint64
+	if rf, ok := ret.Get(0).(func(context.Context, uint64, int64, int) []uint64); ok {
+		r0 = rf(ctx, afterID, olderThanSeconds, limit)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]uint64)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, uint64, int64, int) error); ok {
+		r1 = rf(ctx, afterID, olderThanSeconds, limit)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// OperationExpire provides a mock function with given fields: ctx, userID, expireFunc
+func (_m *Repo) OperationExpire(ctx context.Context, userID uint64, expireFunc repo.ExpireFunc) (*models.Operation, error) {
+	ret := _m.Called(ctx, userID, expireFunc)
+
+	var r0 *models.Operation
+	if rf, ok := ret.Get(0).(func(context.Context, uint64, repo.ExpireFunc) *models.Operation); ok {
+		r0 = rf(ctx, userID, expireFunc)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.Operation)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, uint64, repo.ExpireFunc) error); ok {
+		r1 = rf(ctx, userID, expireFunc)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// OperationGetByOrder provides a mock function with given fields: ctx, userID, opType, orderNumber
+func (_m *Repo) OperationGetByOrder(ctx context.Context, userID uint64, opType models.OperationType, orderNumber string) (*models.Operation, error) {
+	ret := _m.Called(ctx, userID, opType, orderNumber)
+
+	var r0 *models.Operation
+	if rf, ok := ret.Get(0).(func(context.Context, uint64, models.OperationType, string) *models.Operation); ok {
+		r0 = rf(ctx, userID, opType, orderNumber)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.Operation)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, uint64, models.OperationType, string) error); ok {
+		r1 = rf(ctx, userID, opType, orderNumber)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// OperationGetByType provides a mock function with given fields: ctx, userID, t, q
+func (_m *Repo) OperationGetByType(ctx context.Context, userID uint64, t models.OperationType, q *models.OperationQuery) ([]*models.Operation, error) {
+	ret := _m.Called(ctx, userID, t, q)
+
+	var r0 []*models.Operation
+	if rf, ok := ret.Get(0).(func(context.Context, uint64, models.OperationType, *models.OperationQuery) []*models.Operation); ok {
+		r0 = rf(ctx, userID, t, q)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*models.Operation)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, uint64, models.OperationType, *models.OperationQuery) error); ok {
+		r1 = rf(ctx, userID, t, q)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// OperationHoldCandidates provides a mock function with given fields: ctx, afterID, limit
+func (_m *Repo) OperationHoldCandidates(ctx context.Context, afterID uint64, limit int) ([]uint64, error) {
+	ret := _m.Called(ctx, afterID, limit)
+
+	var r0 []uint64
+	if rf, ok := ret.Get(0).(func(context.Context, uint64, int) []uint64); ok {
+		r0 = rf(ctx, afterID, limit)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]uint64)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, uint64, int) error); ok {
+		r1 = rf(ctx, afterID, limit)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// OperationHoldRelease provides a mock function with given fields: ctx, userID
+func (_m *Repo) OperationHoldRelease(ctx context.Context, userID uint64) (decimal.Decimal, error) {
+	ret := _m.Called(ctx, userID)
+
+	var r0 decimal.Decimal
+	if rf, ok := ret.Get(0).(func(context.Context, uint64) decimal.Decimal); ok {
+		r0 = rf(ctx, userID)
+	} else {
+		r0 = ret.Get(0).(decimal.Decimal)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, uint64) error); ok {
+		r1 = rf(ctx, userID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// OperationStatusHistoryGetByOrder provides a mock function with given fields: ctx, userID, opType, orderNumber
+func (_m *Repo) OperationStatusHistoryGetByOrder(ctx context.Context, userID uint64, opType models.OperationType, orderNumber string) ([]*models.OperationStatusChange, error) {
+	ret := _m.Called(ctx, userID, opType, orderNumber)
+
+	var r0 []*models.OperationStatusChange
+	if rf, ok := ret.Get(0).(func(context.Context, uint64, models.OperationType, string) []*models.OperationStatusChange); ok {
+		r0 = rf(ctx, userID, opType, orderNumber)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*models.OperationStatusChange)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, uint64, models.OperationType, string) error); ok {
+		r1 = rf(ctx, userID, opType, orderNumber)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// OperationTransfer provides a mock function with given fields: ctx, debit, credit, checkFunc
+func (_m *Repo) OperationTransfer(ctx context.Context, debit *models.Operation, credit *models.Operation, checkFunc repo.TransferCheckFunc) error {
+	ret := _m.Called(ctx, debit, credit, checkFunc)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, *models.Operation, *models.Operation, repo.TransferCheckFunc) error); ok {
+		r0 = rf(ctx, debit, credit, checkFunc)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// OperationUpdateByOrder provides a mock function with given fields: ctx, userID, opType, orderNumber, source, updateFunc
+func (_m *Repo) OperationUpdateByOrder(ctx context.Context, userID uint64, opType models.OperationType, orderNumber string, source string, updateFunc repo.UpdateFunc) (*models.Operation, error) {
+	ret := _m.Called(ctx, userID, opType, orderNumber, source, updateFunc)
+
+	var r0 *models.Operation
+	if rf, ok := ret.Get(0).(func(context.Context, uint64, models.OperationType, string, string, repo.UpdateFunc) *models.Operation); ok {
+		r0 = rf(ctx, userID, opType, orderNumber, source, updateFunc)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.Operation)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, uint64, models.OperationType, string, string, repo.UpdateFunc) error); ok {
+		r1 = rf(ctx, userID, opType, orderNumber, source, updateFunc)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// OperationUpdateFurther provides a mock function with given fields: ctx, opType, source, updateFunc
+func (_m *Repo) OperationUpdateFurther(ctx context.Context, opType models.OperationType, source string, updateFunc repo.UpdateFunc) (*models.Operation, error) {
+	ret := _m.Called(ctx, opType, source, updateFunc)
+
+	var r0 *models.Operation
+	if rf, ok := ret.Get(0).(func(context.Context, models.OperationType, string, repo.UpdateFunc) *models.Operation); ok {
+		r0 = rf(ctx, opType, source, updateFunc)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.Operation)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, models.OperationType, string, repo.UpdateFunc) error); ok {
+		r1 = rf(ctx, opType, source, updateFunc)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// OperationWithdraw provides a mock function with given fields: ctx, op, checkFunc
+func (_m *Repo) OperationWithdraw(ctx context.Context, op *models.Operation, checkFunc repo.WithdrawalCheckFunc) error {
+	ret := _m.Called(ctx, op, checkFunc)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, *models.Operation, repo.WithdrawalCheckFunc) error); ok {
+		r0 = rf(ctx, op, checkFunc)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// PasswordResetConsume provides a mock function with given fields: ctx, tokenHash, passHash
+func (_m *Repo) PasswordResetConsume(ctx context.Context, tokenHash string, passHash string) (uint64, error) {
+	ret := _m.Called(ctx, tokenHash, passHash)
+
+	var r0 uint64
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) uint64); ok {
+		r0 = rf(ctx, tokenHash, passHash)
+	} else {
+		r0 = ret.Get(0).(uint64)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, string, string) error); ok {
+		r1 = rf(ctx, tokenHash, passHash)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// PasswordResetCreate provides a mock function with given fields: ctx, pr, ttlSeconds
+func (_m *Repo) PasswordResetCreate(ctx context.Context, pr *models.PasswordReset, ttlSeconds int64) error {
+	ret := _m.Called(ctx, pr, ttlSeconds)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, *models.PasswordReset, int64) error); ok {
+		r0 = rf(ctx, pr, ttlSeconds)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// PromoCreate provides a mock function with given fields: ctx, p
+func (_m *Repo) PromoCreate(ctx context.Context, p *models.Promo) error {
+	ret := _m.Called(ctx, p)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, *models.Promo) error); ok {
+		r0 = rf(ctx, p)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// PromoGetByCode provides a mock function with given fields: ctx, code
+func (_m *Repo) PromoGetByCode(ctx context.Context, code string) (*models.Promo, error) {
+	ret := _m.Called(ctx, code)
+
+	var r0 *models.Promo
+	if rf, ok := ret.Get(0).(func(context.Context, string) *models.Promo); ok {
+		r0 = rf(ctx, code)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.Promo)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, code)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// PromoGetByUser provides a mock function with given fields: ctx, userID
+func (_m *Repo) PromoGetByUser(ctx context.Context, userID uint64) ([]*models.Promo, error) {
+	ret := _m.Called(ctx, userID)
+
+	var r0 []*models.Promo
+	if rf, ok := ret.Get(0).(func(context.Context, uint64) []*models.Promo); ok {
+		r0 = rf(ctx, userID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*models.Promo)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, uint64) error); ok {
+		r1 = rf(ctx, userID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// SessionCreate provides a mock function with given fields: ctx, s, ttlSeconds
+func (_m *Repo) SessionCreate(ctx context.Context, s *models.Session, ttlSeconds int64) error {
+	ret := _m.Called(ctx, s, ttlSeconds)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, *models.Session, int64) error); ok {
+		r0 = rf(ctx, s, ttlSeconds)
 	} else {
 		r0 = ret.Error(0)
 	}
@@ -30,22 +754,22 @@ func (_m *Repo) OperationCreate(ctx context.Context, op *models.Operation) error
 	return r0
 }
 
-// OperationGetByType provides a mock function with given fields: ctx, userID, t
-func (_m *Repo) OperationGetByType(ctx context.Context, userID uint64, t models.OperationType) ([]*models.Operation, error) {
-	ret := _m.Called(ctx, userID, t)
+// SessionGetByID provides a mock function with given fields: ctx, sessionID
+func (_m *Repo) SessionGetByID(ctx context.Context, sessionID uint64) (*models.Session, error) {
+	ret := _m.Called(ctx, sessionID)
 
-	var r0 []*models.Operation
-	if rf, ok := ret.Get(0).(func(context.Context, uint64, models.OperationType) []*models.Operation); ok {
-		r0 = rf(ctx, userID, t)
+	var r0 *models.Session
+	if rf, ok := ret.Get(0).(func(context.Context, uint64) *models.Session); ok {
+		r0 = rf(ctx, sessionID)
 	} else {
 		if ret.Get(0) != nil {
-			r0 = ret.Get(0).([]*models.Operation)
+			r0 = ret.Get(0).(*models.Session)
 		}
 	}
 
 	var r1 error
-	if rf, ok := ret.Get(1).(func(context.Context, uint64, models.OperationType) error); ok {
-		r1 = rf(ctx, userID, t)
+	if rf, ok := ret.Get(1).(func(context.Context, uint64) error); ok {
+		r1 = rf(ctx, sessionID)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -53,22 +777,22 @@ func (_m *Repo) OperationGetByType(ctx context.Context, userID uint64, t models.
 	return r0, r1
 }
 
-// OperationUpdateFurther provides a mock function with given fields: ctx, opType, updateFunc
-func (_m *Repo) OperationUpdateFurther(ctx context.Context, opType models.OperationType, updateFunc repo.UpdateFunc) (*models.Operation, error) {
-	ret := _m.Called(ctx, opType, updateFunc)
+// SessionGetByUser provides a mock function with given fields: ctx, userID
+func (_m *Repo) SessionGetByUser(ctx context.Context, userID uint64) ([]*models.Session, error) {
+	ret := _m.Called(ctx, userID)
 
-	var r0 *models.Operation
-	if rf, ok := ret.Get(0).(func(context.Context, models.OperationType, repo.UpdateFunc) *models.Operation); ok {
-		r0 = rf(ctx, opType, updateFunc)
+	var r0 []*models.Session
+	if rf, ok := ret.Get(0).(func(context.Context, uint64) []*models.Session); ok {
+		r0 = rf(ctx, userID)
 	} else {
 		if ret.Get(0) != nil {
-			r0 = ret.Get(0).(*models.Operation)
+			r0 = ret.Get(0).([]*models.Session)
 		}
 	}
 
 	var r1 error
-	if rf, ok := ret.Get(1).(func(context.Context, models.OperationType, repo.UpdateFunc) error); ok {
-		r1 = rf(ctx, opType, updateFunc)
+	if rf, ok := ret.Get(1).(func(context.Context, uint64) error); ok {
+		r1 = rf(ctx, userID)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -76,13 +800,13 @@ func (_m *Repo) OperationUpdateFurther(ctx context.Context, opType models.Operat
 	return r0, r1
 }
 
-// PromoCreate provides a mock function with given fields: ctx, p
-func (_m *Repo) PromoCreate(ctx context.Context, p *models.Promo) error {
-	ret := _m.Called(ctx, p)
+// SessionRevoke provides a mock function with given fields: ctx, userID, sessionID
+func (_m *Repo) SessionRevoke(ctx context.Context, userID uint64, sessionID uint64) error {
+	ret := _m.Called(ctx, userID, sessionID)
 
 	var r0 error
-	if rf, ok := ret.Get(0).(func(context.Context, *models.Promo) error); ok {
-		r0 = rf(ctx, p)
+	if rf, ok := ret.Get(0).(func(context.Context, uint64, uint64) error); ok {
+		r0 = rf(ctx, userID, sessionID)
 	} else {
 		r0 = ret.Error(0)
 	}
@@ -90,22 +814,36 @@ func (_m *Repo) PromoCreate(ctx context.Context, p *models.Promo) error {
 	return r0
 }
 
-// PromoGetByCode provides a mock function with given fields: ctx, code
-func (_m *Repo) PromoGetByCode(ctx context.Context, code string) (*models.Promo, error) {
-	ret := _m.Called(ctx, code)
+// SessionRevokeAll provides a mock function with given fields: ctx, userID
+func (_m *Repo) SessionRevokeAll(ctx context.Context, userID uint64) error {
+	ret := _m.Called(ctx, userID)
 
-	var r0 *models.Promo
-	if rf, ok := ret.Get(0).(func(context.Context, string) *models.Promo); ok {
-		r0 = rf(ctx, code)
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, uint64) error); ok {
+		r0 = rf(ctx, userID)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// SessionRotate provides a mock function with given fields: ctx, oldHash, newHash, ttlSeconds
+func (_m *Repo) SessionRotate(ctx context.Context, oldHash string, newHash string, ttlSeconds int64) (*models.Session, error) {
+	ret := _m.Called(ctx, oldHash, newHash, ttlSeconds)
+
+	var r0 *models.Session
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, int64) *models.Session); ok {
+		r0 = rf(ctx, oldHash, newHash, ttlSeconds)
 	} else {
 		if ret.Get(0) != nil {
-			r0 = ret.Get(0).(*models.Promo)
+			r0 = ret.Get(0).(*models.Session)
 		}
 	}
 
 	var r1 error
-	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
-		r1 = rf(ctx, code)
+	if rf, ok := ret.Get(1).(func(context.Context, string, string, int64) error); ok {
+		r1 = rf(ctx, oldHash, newHash, ttlSeconds)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -113,19 +851,181 @@ func (_m *Repo) PromoGetByCode(ctx context.Context, code string) (*models.Promo,
 	return r0, r1
 }
 
-// UserBalanceHistoryGetByID provides a mock function with given fields: ctx, userID
-func (_m *Repo) UserBalanceHistoryGetByID(ctx context.Context, userID uint64) ([]*models.Operation, error) {
+// TOTPConfirm provides a mock function with given fields: ctx, userID, step, recoveryHashes
+func (_m *Repo) TOTPConfirm(ctx context.Context, userID uint64, step int64, recoveryHashes []string) error {
+	ret := _m.Called(ctx, userID, step, recoveryHashes)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, uint64, int64, []string) error); ok {
+		r0 = rf(ctx, userID, step, recoveryHashes)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// TOTPDelete provides a mock function with given fields: ctx, userID
+func (_m *Repo) TOTPDelete(ctx context.Context, userID uint64) error {
 	ret := _m.Called(ctx, userID)
 
-	var r0 []*models.Operation
-	if rf, ok := ret.Get(0).(func(context.Context, uint64) []*models.Operation); ok {
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, uint64) error); ok {
+		r0 = rf(ctx, userID)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// TOTPEnroll provides a mock function with given fields: ctx, t
+func (_m *Repo) TOTPEnroll(ctx context.Context, t *models.TOTP) error {
+	ret := _m.Called(ctx, t)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, *models.TOTP) error); ok {
+		r0 = rf(ctx, t)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// TOTPGet provides a mock function with given fields: ctx, userID
+func (_m *Repo) TOTPGet(ctx context.Context, userID uint64) (*models.TOTP, error) {
+	ret := _m.Called(ctx, userID)
+
+	var r0 *models.TOTP
+	if rf, ok := ret.Get(0).(func(context.Context, uint64) *models.TOTP); ok {
 		r0 = rf(ctx, userID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.TOTP)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, uint64) error); ok {
+		r1 = rf(ctx, userID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// TOTPRecoveryCodeUse provides a mock function with given fields: ctx, userID, codeHash
+func (_m *Repo) TOTPRecoveryCodeUse(ctx context.Context, userID uint64, codeHash string) error {
+	ret := _m.Called(ctx, userID, codeHash)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, uint64, string) error); ok {
+		r0 = rf(ctx, userID, codeHash)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// TOTPUseStep provides a mock function with given fields: ctx, userID, step
+func (_m *Repo) TOTPUseStep(ctx context.Context, userID uint64, step int64) error {
+	ret := _m.Called(ctx, userID, step)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, uint64, int64) error); ok {
+		r0 = rf(ctx, userID, step)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// UserBalanceAsOf provides a mock function with given fields: ctx, userID, asOf
+func (_m *Repo) UserBalanceAsOf(ctx context.Context, userID uint64, asOf time.Time) (*models.BalanceSnapshot, error) {
+	ret := _m.Called(ctx, userID, asOf)
+
+	var r0 *models.BalanceSnapshot
+	if rf, ok := ret.Get(0).(func(context.Context, uint64, time.Time) *models.BalanceSnapshot); ok {
+		r0 = rf(ctx, userID, asOf)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.BalanceSnapshot)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, uint64, time.Time) error); ok {
+		r1 = rf(ctx, userID, asOf)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// UserBalanceCheck provides a mock function with given fields: ctx, afterID, limit
+func (_m *Repo) UserBalanceCheck(ctx context.Context, afterID uint64, limit int) ([]*models.BalanceCheck, error) {
+	ret := _m.Called(ctx, afterID, limit)
+
+	var r0 []*models.BalanceCheck
+	if rf, ok := ret.Get(0).(func(context.Context, uint64, int) []*models.BalanceCheck); ok {
+		r0 = rf(ctx, afterID, limit)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*models.BalanceCheck)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, uint64, int) error); ok {
+		r1 = rf(ctx, afterID, limit)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// UserBalanceHistoryGetByID provides a mock function with given fields: ctx, userID, q
+func (_m *Repo) UserBalanceHistoryGetByID(ctx context.Context, userID uint64, q *models.OperationQuery) ([]*models.Operation, error) {
+	ret := _m.Called(ctx, userID, q)
+
+	var r0 []*models.Operation
+	if rf, ok := ret.Get(0).(func(context.Context, uint64, *models.OperationQuery) []*models.Operation); ok {
+		r0 = rf(ctx, userID, q)
 	} else {
 		if ret.Get(0) != nil {
 			r0 = ret.Get(0).([]*models.Operation)
 		}
 	}
 
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, uint64, *models.OperationQuery) error); ok {
+		r1 = rf(ctx, userID, q)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// UserBalanceRepair provides a mock function with given fields: ctx, userID
+func (_m *Repo) UserBalanceRepair(ctx context.Context, userID uint64) (*models.BalanceCheck, error) {
+	ret := _m.Called(ctx, userID)
+
+	var r0 *models.BalanceCheck
+	if rf, ok := ret.Get(0).(func(context.Context, uint64) *models.BalanceCheck); ok {
+		r0 = rf(ctx, userID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.BalanceCheck)
+		}
+	}
+
 	var r1 error
 	if rf, ok := ret.Get(1).(func(context.Context, uint64) error); ok {
 		r1 = rf(ctx, userID)
@@ -150,6 +1050,29 @@ func (_m *Repo) UserCreate(ctx context.Context, u *models.User) error {
 	return r0
 }
 
+// UserDelete provides a mock function with given fields: ctx, userID, description, graceSeconds
+func (_m *Repo) UserDelete(ctx context.Context, userID uint64, description string, graceSeconds int64) (*models.Operation, error) {
+	ret := _m.Called(ctx, userID, description, graceSeconds)
+
+	var r0 *models.Operation
+	if rf, ok := ret.Get(0).(func(context.Context, uint64, string, int64) *models.Operation); ok {
+		r0 = rf(ctx, userID, description, graceSeconds)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.Operation)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, uint64, string, int64) error); ok {
+		r1 = rf(ctx, userID, description, graceSeconds)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // UserGetByID provides a mock function with given fields: ctx, userID
 func (_m *Repo) UserGetByID(ctx context.Context, userID uint64) (*models.User, error) {
 	ret := _m.Called(ctx, userID)
@@ -196,6 +1119,85 @@ func (_m *Repo) UserGetByLogin(ctx context.Context, login string) (*models.User,
 	return r0, r1
 }
 
+// UserLedgerPostingsGet provides a mock function with given fields: ctx, userID, from, to
+func (_m *Repo) UserLedgerPostingsGet(ctx context.Context, userID uint64, from time.Time, to time.Time) ([]*models.LedgerPosting, error) {
+	ret := _m.Called(ctx, userID, from, to)
+
+	var r0 []*models.LedgerPosting
+	if rf, ok := ret.Get(0).(func(context.Context, uint64, time.Time, time.Time) []*models.LedgerPosting); ok {
+		r0 = rf(ctx, userID, from, to)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*models.LedgerPosting)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, uint64, time.Time, time.Time) error); ok {
+		r1 = rf(ctx, userID, from, to)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// UserSetRole provides a mock function with given fields: ctx, userID, role
+func (_m *Repo) UserSetRole(ctx context.Context, userID uint64, role models.UserRole) error {
+	ret := _m.Called(ctx, userID, role)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, uint64, models.UserRole) error); ok {
+		r0 = rf(ctx, userID, role)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// UserSetStatus provides a mock function with given fields: ctx, c
+func (_m *Repo) UserSetStatus(ctx context.Context, c *models.UserStatusChange) error {
+	ret := _m.Called(ctx, c)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, *models.UserStatusChange) error); ok {
+		r0 = rf(ctx, c)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// UserUpdatePassHash provides a mock function with given fields: ctx, userID, passHash
+func (_m *Repo) UserUpdatePassHash(ctx context.Context, userID uint64, passHash string) error {
+	ret := _m.Called(ctx, userID, passHash)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, uint64, string) error); ok {
+		r0 = rf(ctx, userID, passHash)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// UserUpgradePassHash provides a mock function with given fields: ctx, userID, oldHash, newHash
+func (_m *Repo) UserUpgradePassHash(ctx context.Context, userID uint64, oldHash string, newHash string) error {
+	ret := _m.Called(ctx, userID, oldHash, newHash)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, uint64, string, string) error); ok {
+		r0 = rf(ctx, userID, oldHash, newHash)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 type mockConstructorTestingTNewRepo interface {
 	mock.TestingT
 	Cleanup(func())
@@ -0,0 +1,54 @@
+package models
+
+import "time"
+
+// AccessTokenPrefix - префикс персонального токена доступа.
+// Позволяет отличить персональный токен от JWT-токена и найти токен, случайно попавший в код или логи.
+const AccessTokenPrefix = "gmp_"
+
+// AccessTokenScope - область доступа персонального токена.
+type AccessTokenScope string
+
+const (
+	ScopeOrdersRead       AccessTokenScope = "orders:read"       // просмотр загруженных заказов
+	ScopeOrdersWrite      AccessTokenScope = "orders:write"      // загрузка номеров заказов
+	ScopeBalanceRead      AccessTokenScope = "balance:read"      // просмотр баланса и истории операций
+	ScopeWithdrawalsRead  AccessTokenScope = "withdrawals:read"  // просмотр списаний
+	ScopeWithdrawalsWrite AccessTokenScope = "withdrawals:write" // списание баллов
+	ScopePromosWrite      AccessTokenScope = "promos:write"      // зачисление баллов по промо-кодам
+	ScopeTransfersWrite   AccessTokenScope = "transfers:write"   // перевод баллов другим пользователям
+)
+
+// Valid - проверяет, что область доступа поддерживается.
+func (s AccessTokenScope) Valid() bool {
+	switch s {
+	case ScopeOrdersRead, ScopeOrdersWrite, ScopeBalanceRead,
+		ScopeWithdrawalsRead, ScopeWithdrawalsWrite, ScopePromosWrite, ScopeTransfersWrite:
+		return true
+	}
+	return false
+}
+
+// AccessToken - модель персонального токена доступа.
+// Персональный токен позволяет обращаться к API из скриптов без пароля и ограничен областями доступа Scopes.
+type AccessToken struct {
+	ID         uint64
+	UserID     uint64
+	Name       string             // название токена, задается пользователем
+	TokenHash  string             // хэш токена
+	Scopes     []AccessTokenScope // области доступа
+	ExpiresAt  *time.Time         // время истечения токена, если не задано - токен бессрочный
+	LastUsedAt *time.Time         // время последнего использования токена
+	RevokedAt  *time.Time         // время отзыва токена, если токен отозван
+	CreatedAt  time.Time
+}
+
+// HasScope - проверяет, что токен имеет область доступа scope.
+func (t *AccessToken) HasScope(scope AccessTokenScope) bool {
+	for _, s := range t.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,12 @@
+package models
+
+import "time"
+
+// Adjustment - запись журнала корректировок баланса сотрудниками
+type Adjustment struct {
+	OperationID uint64 // id операции корректировки
+	StaffID     uint64 // id сотрудника, выполнившего корректировку
+	Reason      string // причина корректировки, видна только сотрудникам
+	Ticket      string // номер обращения в службу поддержки
+	CreatedAt   time.Time
+}
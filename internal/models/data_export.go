@@ -0,0 +1,42 @@
+package models
+
+import "time"
+
+// DataExport - выгрузка персональных данных пользователя.
+// Выгрузка создается в статусе DataExportNew и формируется в фоне.
+type DataExport struct {
+	ID        uint64
+	UserID    uint64
+	Format    DataExportFormat
+	Status    DataExportStatus
+	Payload   []byte // содержимое файла выгрузки, если выгрузка готова
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	ExpiresAt *time.Time // время удаления готовой выгрузки
+}
+
+// DataExportFormat - формат файла выгрузки
+type DataExportFormat string
+
+const (
+	DataExportJSON DataExportFormat = "json" // все данные в одном JSON-документе
+	DataExportZIP  DataExportFormat = "zip"  // ZIP-архив с JSON-файлом для каждого раздела
+)
+
+// Valid - проверяет, что формат поддерживается.
+func (f DataExportFormat) Valid() bool {
+	switch f {
+	case DataExportJSON, DataExportZIP:
+		return true
+	}
+	return false
+}
+
+// DataExportStatus - статус выгрузки
+type DataExportStatus string
+
+const (
+	DataExportNew    DataExportStatus = "NEW"    // выгрузка ожидает формирования
+	DataExportReady  DataExportStatus = "READY"  // выгрузка сформирована и доступна для скачивания
+	DataExportFailed DataExportStatus = "FAILED" // при формировании выгрузки произошла ошибка
+)
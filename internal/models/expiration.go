@@ -0,0 +1,132 @@
+package models
+
+import (
+	"sort"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// PointsLot - баллы, начисленные одной операцией
+type PointsLot struct {
+	OperationID uint64
+	Type        OperationType
+	Amount      decimal.Decimal // начисленная сумма
+	Remaining   decimal.Decimal // неизрасходованная сумма
+	AccruedAt   time.Time
+	ExpiresAt   *time.Time // время сгорания, nil - баллы не сгорают
+}
+
+// Expired - проверяет, что срок жизни баллов истек к моменту t.
+func (l *PointsLot) Expired(t time.Time) bool {
+	return l.ExpiresAt != nil && !l.ExpiresAt.After(t)
+}
+
+// PointsLots - распределяет списания пользователя по его начислениям и возвращает начисления
+// в порядке их обработки с неизрасходованными остатками.
+//
+// ops - операции пользователя, учитывающиеся в балансе.
+// lifetimes - срок жизни баллов по типам операций начисления, баллы остальных типов не сгорают.
+//
+// Списания расходуют самые старые начисления, обработанные до списания (FIFO).
+// Сгорание баллов расходует самые старые начисления, срок жизни которых истек к моменту сгорания.
+func PointsLots(ops []*Operation, lifetimes map[OperationType]time.Duration) []*PointsLot {
+	var lots []*PointsLot
+	var debits []*Operation
+	for _, op := range ops {
+		effect := op.BalanceEffect()
+		switch {
+		case effect.IsPositive():
+			// Начисление учитывается в балансе с момента обработки,
+			// последующие обновления операции срок жизни баллов не продлевают
+			accruedAt := op.UpdatedAt
+			if op.ProcessedAt != nil {
+				accruedAt = *op.ProcessedAt
+			}
+			lot := &PointsLot{
+				OperationID: op.ID,
+				Type:        op.Type,
+				Amount:      effect,
+				Remaining:   effect,
+				AccruedAt:   accruedAt,
+			}
+			if lifetime := lifetimes[op.Type]; lifetime > 0 {
+				expiresAt := accruedAt.Add(lifetime)
+				lot.ExpiresAt = &expiresAt
+			}
+			lots = append(lots, lot)
+		case effect.IsNegative():
+			// Списание учитывается в балансе с момента создания
+			debits = append(debits, op)
+		}
+	}
+
+	sort.SliceStable(lots, func(i, j int) bool {
+		if lots[i].AccruedAt.Equal(lots[j].AccruedAt) {
+			return lots[i].OperationID < lots[j].OperationID
+		}
+		return lots[i].AccruedAt.Before(lots[j].AccruedAt)
+	})
+	sort.SliceStable(debits, func(i, j int) bool {
+		if debits[i].CreatedAt.Equal(debits[j].CreatedAt) {
+			return debits[i].ID < debits[j].ID
+		}
+		return debits[i].CreatedAt.Before(debits[j].CreatedAt)
+	})
+
+	for _, op := range debits {
+		t := op.CreatedAt
+		rest := op.BalanceEffect().Neg()
+		if op.Type == PointsExpiration {
+			rest = consumeLots(lots, rest, func(l *PointsLot) bool { return l.Expired(t) })
+		} else {
+			rest = consumeLots(lots, rest, func(l *PointsLot) bool { return !l.AccruedAt.After(t) })
+		}
+		// Если подходящих начислений не хватило, расходуем любые оставшиеся
+		consumeLots(lots, rest, func(*PointsLot) bool { return true })
+	}
+	return lots
+}
+
+// PointsExpired - возвращает сумму неизрасходованных баллов, срок жизни которых истек к моменту t.
+func PointsExpired(lots []*PointsLot, t time.Time) decimal.Decimal {
+	total := decimal.Zero
+	for _, l := range lots {
+		if l.Expired(t) {
+			total = total.Add(l.Remaining)
+		}
+	}
+	return total
+}
+
+// PointsExpiring - возвращает начисления с неизрасходованными баллами, которые сгорят после момента t,
+// в порядке сгорания.
+func PointsExpiring(lots []*PointsLot, t time.Time) []*PointsLot {
+	var expiring []*PointsLot
+	for _, l := range lots {
+		if l.ExpiresAt != nil && !l.Expired(t) && l.Remaining.IsPositive() {
+			expiring = append(expiring, l)
+		}
+	}
+	sort.SliceStable(expiring, func(i, j int) bool {
+		return expiring[i].ExpiresAt.Before(*expiring[j].ExpiresAt)
+	})
+	return expiring
+}
+
+// consumeLots - расходует amount баллов из начислений, подходящих под условие match, начиная с самых старых.
+// Возвращает сумму, которую не удалось израсходовать.
+func consumeLots(lots []*PointsLot, amount decimal.Decimal, match func(*PointsLot) bool) decimal.Decimal {
+	for _, l := range lots {
+		if !amount.IsPositive() {
+			break
+		}
+		if !l.Remaining.IsPositive() || !match(l) {
+			continue
+		}
+		spent := decimal.Min(amount, l.Remaining)
+		l.Remaining = l.Remaining.Sub(spent)
+		amount = amount.Sub(spent)
+	}
+	return amount
+}
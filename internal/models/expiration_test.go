@@ -0,0 +1,123 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+func TestPointsLots(t *testing.T) {
+	day := 24 * time.Hour
+	t0 := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+	lifetimes := map[OperationType]time.Duration{
+		OrderAccrual: 365 * day,
+		PromoAccrual: 90 * day,
+	}
+	op := func(id uint64, t OperationType, s OperationStatus, amount int64, at time.Time) *Operation {
+		o := &Operation{ID: id, Type: t, Status: s, Amount: decimal.NewFromInt(amount), CreatedAt: at, UpdatedAt: at}
+		if s == StatusProcessed {
+			o.ProcessedAt = &at
+		}
+		return o
+	}
+	updated := func(o *Operation, at time.Time) *Operation {
+		o.UpdatedAt = at
+		return o
+	}
+
+	tests := []struct {
+		name     string
+		ops      []*Operation
+		at       time.Time
+		expired  int64
+		expiring []int64
+	}{
+		{
+			name: "nothing expired",
+			ops: []*Operation{
+				op(1, OrderAccrual, StatusProcessed, 100, t0),
+				op(2, PromoAccrual, StatusProcessed, 10, t0.Add(day)),
+			},
+			at:       t0.Add(10 * day),
+			expired:  0,
+			expiring: []int64{10, 100},
+		},
+		{
+			name: "oldest accrual spent first",
+			ops: []*Operation{
+				op(1, PromoAccrual, StatusProcessed, 10, t0),
+				op(2, OrderAccrual, StatusProcessed, 100, t0.Add(day)),
+				op(3, OrderWithdrawal, StatusProcessed, -30, t0.Add(2*day)),
+			},
+			at:       t0.Add(100 * day),
+			expired:  0,
+			expiring: []int64{80},
+		},
+		{
+			name: "withdrawal does not spend later accruals",
+			ops: []*Operation{
+				op(1, OrderAccrual, StatusProcessed, 100, t0),
+				op(2, OrderWithdrawal, StatusProcessed, -30, t0.Add(day)),
+				op(3, PromoAccrual, StatusProcessed, 10, t0.Add(2*day)),
+			},
+			at:       t0.Add(100 * day),
+			expired:  10,
+			expiring: []int64{70},
+		},
+		{
+			name: "canceled withdrawal is ignored",
+			ops: []*Operation{
+				op(1, PromoAccrual, StatusProcessed, 10, t0),
+				op(2, OrderWithdrawal, StatusCanceled, -10, t0.Add(day)),
+			},
+			at:      t0.Add(100 * day),
+			expired: 10,
+		},
+		{
+			name: "expired points are burned once",
+			ops: []*Operation{
+				op(1, OrderAccrual, StatusProcessed, 100, t0),
+				op(2, PromoAccrual, StatusProcessed, 10, t0.Add(day)),
+				op(3, PointsExpiration, StatusProcessed, -10, t0.Add(92*day)),
+			},
+			at:       t0.Add(100 * day),
+			expired:  0,
+			expiring: []int64{100},
+		},
+		{
+			name: "later update does not extend lifetime",
+			ops: []*Operation{
+				updated(op(1, PromoAccrual, StatusProcessed, 10, t0), t0.Add(80*day)),
+			},
+			at:      t0.Add(100 * day),
+			expired: 10,
+		},
+		{
+			name: "pending accrual does not expire",
+			ops: []*Operation{
+				op(1, OrderAccrual, StatusNew, 100, t0),
+			},
+			at:      t0.Add(400 * day),
+			expired: 0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lots := PointsLots(tt.ops, lifetimes)
+			if got := PointsExpired(lots, tt.at); !got.Equal(decimal.NewFromInt(tt.expired)) {
+				t.Errorf("PointsExpired() = %v, want %v", got, tt.expired)
+			}
+			expiring := PointsExpiring(lots, tt.at)
+			if len(expiring) != len(tt.expiring) {
+				t.Fatalf("PointsExpiring() returned %d lots, want %d", len(expiring), len(tt.expiring))
+			}
+			for i, l := range expiring {
+				if !l.Remaining.Equal(decimal.NewFromInt(tt.expiring[i])) {
+					t.Errorf("PointsExpiring()[%d].Remaining = %v, want %v", i, l.Remaining, tt.expiring[i])
+				}
+			}
+		})
+	}
+}
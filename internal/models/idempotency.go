@@ -0,0 +1,20 @@
+package models
+
+import "time"
+
+// IdempotencyKey - ключ идемпотентности запроса пользователя и записанный ответ на запрос
+type IdempotencyKey struct {
+	UserID      uint64
+	Key         string
+	Fingerprint string // отпечаток запроса: хэш метода, пути и тела запроса
+	StatusCode  int    // HTTP-код записанного ответа, 0 - запрос еще выполняется
+	ContentType string // Content-Type записанного ответа
+	Body        []byte // тело записанного ответа
+	CreatedAt   time.Time
+	ExpiresAt   time.Time
+}
+
+// Completed - проверяет, что ответ на запрос записан.
+func (k *IdempotencyKey) Completed() bool {
+	return k.StatusCode != 0
+}
@@ -0,0 +1,57 @@
+package models
+
+import (
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// LedgerAccount - код системного счета журнала проводок
+type LedgerAccount string
+
+const (
+	LedgerAccrualSource    LedgerAccount = "accrual_source"    // источник начислений за заказы
+	LedgerPromoBudget      LedgerAccount = "promo_budget"      // бюджет промо-кампаний
+	LedgerWithdrawalSink   LedgerAccount = "withdrawal_sink"   // списания в счет оплаты заказов
+	LedgerClosureSink      LedgerAccount = "closure_sink"      // остатки закрытых счетов
+	LedgerExpirationSink   LedgerAccount = "expiration_sink"   // сгоревшие баллы
+	LedgerTransferClearing LedgerAccount = "transfer_clearing" // транзитный счет переводов между пользователями
+	LedgerAdjustmentFund   LedgerAccount = "adjustment_fund"   // корректировки баланса сотрудниками
+)
+
+// Sink - проверяет, что баллы, переведенные на счет, учитываются в сумме списаний пользователя.
+// Сгоревшие и переведенные другим пользователям баллы пользователь не тратил,
+// поэтому в сумме списаний они не учитываются.
+func (a LedgerAccount) Sink() bool {
+	return a == LedgerWithdrawalSink || a == LedgerClosureSink
+}
+
+// LedgerPosting - проводка по счету пользователя в журнале проводок.
+// Проводки не изменяются: изменение операции записывается новой проводкой на разницу.
+type LedgerPosting struct {
+	ID        uint64
+	Account   LedgerAccount   // системный счет, с которым баллы переведены
+	Amount    decimal.Decimal // сумма, на которую проводка изменила баланс пользователя
+	CreatedAt time.Time
+	Operation *Operation // операция, по которой записана проводка
+}
+
+// BalanceCheck - сверка закэшированного баланса пользователя с журналом проводок
+type BalanceCheck struct {
+	UserID            uint64
+	Balance           decimal.Decimal // закэшированный баланс
+	Withdrawn         decimal.Decimal // закэшированная сумма списаний
+	ExpectedBalance   decimal.Decimal // остаток счета пользователя в журнале проводок
+	ExpectedWithdrawn decimal.Decimal // сумма баллов, переведенных на счета списаний, по журналу проводок
+	Pending           decimal.Decimal // закэшированная сумма удержанных баллов
+	ExpectedPending   decimal.Decimal // сумма удержанных начислений
+	Unposted          int             // число операций, сумма проводок по которым не совпадает с их влиянием на баланс
+	RepairErr         error           // ошибка исправления расхождения, если исправить его не удалось
+}
+
+// Drift - проверяет, что закэшированный баланс расходится с журналом проводок
+// или журнал проводок расходится с операциями.
+func (c *BalanceCheck) Drift() bool {
+	return !c.Balance.Equal(c.ExpectedBalance) || !c.Withdrawn.Equal(c.ExpectedWithdrawn) ||
+		!c.Pending.Equal(c.ExpectedPending) || c.Unposted > 0
+}
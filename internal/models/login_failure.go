@@ -0,0 +1,17 @@
+package models
+
+import "time"
+
+// LoginFailure - счетчик неудачных попыток входа по ключу: логину или IP-адресу.
+// Попытка учитывается в счетчике до проверки пароля и исключается из него, если оказалась удачной.
+type LoginFailure struct {
+	Key          string
+	Failures     int       // число неудачных и еще выполняемых попыток подряд
+	LastFailedAt time.Time // время последней учтенной попытки
+	BlockedUntil time.Time // время, до которого попытки входа по ключу отклоняются
+}
+
+// Blocked - возвращает true, если попытки входа по ключу заблокированы после последней учтенной попытки.
+func (f *LoginFailure) Blocked() bool {
+	return f.BlockedUntil.After(f.LastFailedAt)
+}
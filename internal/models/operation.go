@@ -18,17 +18,82 @@ type Operation struct {
 	UpdatedAt   time.Time
 	OrderNumber *string // номер заказа, если операция связана с заказом
 	PromoID     *uint64 // id промо-кампании, если операция связана с промо-кодом
+	// CounterpartyID - id второго участника, если операция является переводом баллов:
+	// получателя для списания и отправителя для зачисления
+	CounterpartyID *uint64
+	// HoldPeriod - срок удержания баллов, начисленных операцией, отсчитывается от сохранения обработанной операции
+	HoldPeriod time.Duration
+	// HoldUntil - время окончания удержания начисленных баллов.
+	// Пока удержание не снято, баллы учитываются в балансе, но не могут быть потрачены
+	HoldUntil *time.Time
+	// ProcessedAt - время перевода операции в статус PROCESSED, если операция обработана.
+	// В отличие от UpdatedAt не изменяется при последующих обновлениях операции
+	ProcessedAt *time.Time
+	// LastCheckedAt - время последнего опроса внешней системы, обрабатывающей операцию, если операция опрашивалась
+	LastCheckedAt *time.Time
 }
 
 // OperationType - тип операции
 type OperationType string
 
 const (
-	OrderAccrual    OperationType = "order_accrual"
-	OrderWithdrawal OperationType = "order_withdrawal"
-	PromoAccrual    OperationType = "promo_accrual"
+	OrderAccrual      OperationType = "order_accrual"
+	OrderWithdrawal   OperationType = "order_withdrawal"
+	PromoAccrual      OperationType = "promo_accrual"
+	AccountClosure    OperationType = "account_closure"   // списание остатка баланса при удалении учетной записи
+	PointsExpiration  OperationType = "points_expiration" // сгорание баллов, срок жизни которых истек
+	Transfer          OperationType = "transfer"          // перевод баллов другому пользователю
+	BalanceAdjustment OperationType = "adjustment"        // корректировка баланса сотрудником
 )
 
+// OperationTypes - все типы операций
+var OperationTypes = []OperationType{OrderAccrual, OrderWithdrawal, PromoAccrual, AccountClosure, PointsExpiration, Transfer, BalanceAdjustment}
+
+// operationLedgerAccounts - системные счета, с которыми операции обмениваются баллами со счетом пользователя
+var operationLedgerAccounts = map[OperationType]LedgerAccount{
+	OrderAccrual:      LedgerAccrualSource,
+	OrderWithdrawal:   LedgerWithdrawalSink,
+	PromoAccrual:      LedgerPromoBudget,
+	AccountClosure:    LedgerClosureSink,
+	PointsExpiration:  LedgerExpirationSink,
+	Transfer:          LedgerTransferClearing,
+	BalanceAdjustment: LedgerAdjustmentFund,
+}
+
+// Valid - проверяет, что тип операции существует.
+func (t OperationType) Valid() bool {
+	_, ok := operationLedgerAccounts[t]
+	return ok
+}
+
+// LedgerAccount - возвращает системный счет, с которым операция данного типа обменивается баллами
+// со счетом пользователя.
+func (t OperationType) LedgerAccount() (LedgerAccount, bool) {
+	a, ok := operationLedgerAccounts[t]
+	return a, ok
+}
+
+// BalanceEffect - возвращает сумму, на которую операция в текущем статусе изменяет баланс пользователя.
+// Начисления учитываются после обработки, списания - сразу после создания, если они не отклонены и не отменены.
+func (op *Operation) BalanceEffect() decimal.Decimal {
+	switch {
+	case op.Amount.IsPositive() && op.Status == StatusProcessed:
+		return op.Amount
+	case op.Amount.IsNegative() && op.Status != StatusInvalid && op.Status != StatusCanceled:
+		return op.Amount
+	}
+	return decimal.Zero
+}
+
+// Hold - удерживает баллы, начисленные обработанной операцией, в течение period.
+// Списания, необработанные начисления и нулевой срок удержания не изменяют операцию.
+func (op *Operation) Hold(period time.Duration) {
+	if period <= 0 || op.Status != StatusProcessed || !op.Amount.IsPositive() {
+		return
+	}
+	op.HoldPeriod = period
+}
+
 // OperationStatus - статус исполнения операции
 type OperationStatus string
 
@@ -40,8 +105,19 @@ const (
 	StatusCanceled   OperationStatus = "CANCELED"
 )
 
+// Valid - проверяет, что статус операции существует.
+func (s OperationStatus) Valid() bool {
+	_, ok := statusGraph[s]
+	return ok
+}
+
+// Final - проверяет, что статус конечный: из него нет переходов в другие статусы.
+func (s OperationStatus) Final() bool {
+	to, ok := statusGraph[s]
+	return ok && len(to) == 0
+}
+
 // CanTransit - проверяет возможность перехода из статуса from в статус to.
-// В проекте не используется, но может пригодиться в будущем.
 func (s *OperationStatus) CanTransit(to OperationStatus) bool {
 	if *s == to {
 		return true
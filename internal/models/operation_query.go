@@ -0,0 +1,57 @@
+package models
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// OperationQuery - параметры выборки списка операций пользователя.
+// Операции упорядочены по убыванию времени и id, nil выбирает все операции.
+type OperationQuery struct {
+	Limit  int              // Limit - максимальное число операций, 0 - без ограничения
+	After  *OperationCursor // After - курсор: выбираются операции, следующие в списке за ним
+	Status *OperationStatus // Status - фильтр по статусу операции
+	Type   *OperationType   // Type - фильтр по типу операции
+	From   *time.Time       // From - начало периода включительно
+	To     *time.Time       // To - конец периода, не включая
+}
+
+// OperationCursor - позиция в списке операций: время и id последней операции страницы.
+type OperationCursor struct {
+	Time time.Time
+	ID   uint64
+}
+
+// ErrOperationCursorInvalid - курсор не удалось разобрать
+var ErrOperationCursorInvalid = errors.New("invalid operation cursor")
+
+// String - возвращает непрозрачное для клиента представление курсора.
+func (c *OperationCursor) String() string {
+	return base64.RawURLEncoding.EncodeToString([]byte(fmt.Sprintf("%d:%d", c.Time.UnixMicro(), c.ID)))
+}
+
+// ParseOperationCursor - разбирает курсор, полученный OperationCursor.String.
+// Время курсора возвращается в UTC, как и время операций в БД.
+func ParseOperationCursor(s string) (*OperationCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, ErrOperationCursorInvalid
+	}
+	micro, id, ok := strings.Cut(string(data), ":")
+	if !ok {
+		return nil, ErrOperationCursorInvalid
+	}
+	t, err := strconv.ParseInt(micro, 10, 64)
+	if err != nil {
+		return nil, ErrOperationCursorInvalid
+	}
+	c := &OperationCursor{Time: time.UnixMicro(t).UTC()}
+	if c.ID, err = strconv.ParseUint(id, 10, 64); err != nil {
+		return nil, ErrOperationCursorInvalid
+	}
+	return c, nil
+}
@@ -0,0 +1,34 @@
+package models
+
+import (
+	"testing"
+	"time"
+)
+
+func TestOperationCursor(t *testing.T) {
+	c := &OperationCursor{Time: time.Date(2022, 10, 1, 12, 30, 15, 123456000, time.UTC), ID: 42}
+	got, err := ParseOperationCursor(c.String())
+	if err != nil {
+		t.Fatalf("ParseOperationCursor() error = %v", err)
+	}
+	if !got.Time.Equal(c.Time) || got.ID != c.ID {
+		t.Errorf("ParseOperationCursor() = %v, want %v", got, c)
+	}
+
+	tests := []struct {
+		name   string
+		cursor string
+	}{
+		{name: "not base64", cursor: "!!!"},
+		{name: "no separator", cursor: "MTIz"},
+		{name: "invalid time", cursor: "eDo0Mg"},
+		{name: "invalid id", cursor: "MTIzOng"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := ParseOperationCursor(tt.cursor); err == nil {
+				t.Errorf("ParseOperationCursor(%q) expected error", tt.cursor)
+			}
+		})
+	}
+}
@@ -1,6 +1,10 @@
 package models
 
-import "testing"
+import (
+	"testing"
+
+	"github.com/shopspring/decimal"
+)
 
 func TestStatusCanTransit(t *testing.T) {
 	tests := []struct {
@@ -60,3 +64,51 @@ func TestStatusCanTransit(t *testing.T) {
 		})
 	}
 }
+
+func TestStatusFinal(t *testing.T) {
+	tests := []struct {
+		status OperationStatus
+		want   bool
+	}{
+		{status: StatusNew, want: false},
+		{status: StatusProcessing, want: false},
+		{status: StatusInvalid, want: true},
+		{status: StatusProcessed, want: true},
+		{status: StatusCanceled, want: true},
+		{status: "UNKNOWN", want: false},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.status), func(t *testing.T) {
+			if got := tt.status.Final(); got != tt.want {
+				t.Errorf("Final() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestOperationBalanceEffect(t *testing.T) {
+	tests := []struct {
+		name   string
+		amount int64
+		status OperationStatus
+		want   int64
+	}{
+		{name: "new accrual", amount: 100, status: StatusNew, want: 0},
+		{name: "processed accrual", amount: 100, status: StatusProcessed, want: 100},
+		{name: "invalid accrual", amount: 100, status: StatusInvalid, want: 0},
+		{name: "new withdrawal", amount: -10, status: StatusNew, want: -10},
+		{name: "processing withdrawal", amount: -10, status: StatusProcessing, want: -10},
+		{name: "processed withdrawal", amount: -10, status: StatusProcessed, want: -10},
+		{name: "canceled withdrawal", amount: -10, status: StatusCanceled, want: 0},
+		{name: "invalid withdrawal", amount: -10, status: StatusInvalid, want: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			op := &Operation{Amount: decimal.NewFromInt(tt.amount), Status: tt.status}
+			if got := op.BalanceEffect(); !got.Equal(decimal.NewFromInt(tt.want)) {
+				t.Errorf("BalanceEffect() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
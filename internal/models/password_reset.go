@@ -0,0 +1,14 @@
+package models
+
+import "time"
+
+// PasswordReset - модель токена сброса пароля.
+// Токен одноразовый и действует до ExpiresAt.
+type PasswordReset struct {
+	ID        uint64
+	UserID    uint64
+	TokenHash string     // хэш токена сброса пароля
+	ExpiresAt time.Time  // время истечения токена
+	UsedAt    *time.Time // время использования токена, если токен использован или заменен новым
+	CreatedAt time.Time
+}
@@ -0,0 +1,15 @@
+package models
+
+import "time"
+
+// Session - модель сессии пользователя.
+// Сессия создается при аутентификации и продлевается при каждом обновлении refresh-токена.
+type Session struct {
+	ID          uint64
+	UserID      uint64
+	RefreshHash string     // хэш текущего refresh-токена
+	ExpiresAt   time.Time  // время истечения refresh-токена
+	RevokedAt   *time.Time // время отзыва сессии, если сессия отозвана
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+}
@@ -0,0 +1,80 @@
+package models
+
+import (
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// BalanceSnapshot - баланс пользователя на момент времени, рассчитанный по сумме проводок,
+// записанных до этого момента. Удержанные баллы учитываются в балансе.
+type BalanceSnapshot struct {
+	AsOf      time.Time
+	Balance   decimal.Decimal
+	Withdrawn decimal.Decimal // сумма списаний в счет оплаты заказов и остатков закрытых счетов
+}
+
+// Statement - выписка по накопительному счету пользователя за календарный месяц по журналу проводок.
+// Суммы списаний и сгоревших баллов положительные, сальдо переводов и корректировок - со знаком.
+// Отмена списания в следующем месяце попадает в выписку следующего месяца встречной проводкой,
+// поэтому выписка за закрытый месяц не меняется.
+type Statement struct {
+	UserID      uint64
+	From        time.Time // начало месяца включительно
+	To          time.Time // начало следующего месяца, не включая
+	Opening     decimal.Decimal
+	Accruals    decimal.Decimal // начисления за заказы и по промо-кодам
+	Withdrawals decimal.Decimal // списания в счет оплаты заказов и остатки закрытых счетов
+	Expirations decimal.Decimal // сгоревшие баллы
+	Transfers   decimal.Decimal // сальдо переводов другим пользователям и от них
+	Adjustments decimal.Decimal // сальдо корректировок баланса сотрудниками
+	Closing     decimal.Decimal
+	Postings    []*LedgerPosting // проводки месяца в порядке записи
+}
+
+// NewStatement - возвращает выписку за месяц, начинающийся в from, с входящим остатком opening.
+func NewStatement(userID uint64, from time.Time, opening decimal.Decimal) *Statement {
+	return &Statement{
+		UserID:  userID,
+		From:    from,
+		To:      from.AddDate(0, 1, 0),
+		Opening: opening,
+		Closing: opening,
+	}
+}
+
+// Add - добавляет в выписку проводку по счету пользователя и пересчитывает итоги по системному счету проводки.
+func (s *Statement) Add(p *LedgerPosting) {
+	switch p.Account {
+	case LedgerAccrualSource, LedgerPromoBudget:
+		s.Accruals = s.Accruals.Add(p.Amount)
+	case LedgerWithdrawalSink, LedgerClosureSink:
+		s.Withdrawals = s.Withdrawals.Sub(p.Amount)
+	case LedgerExpirationSink:
+		s.Expirations = s.Expirations.Sub(p.Amount)
+	case LedgerTransferClearing:
+		s.Transfers = s.Transfers.Add(p.Amount)
+	case LedgerAdjustmentFund:
+		s.Adjustments = s.Adjustments.Add(p.Amount)
+	}
+	s.Closing = s.Closing.Add(p.Amount)
+	s.Postings = append(s.Postings, p)
+}
+
+// StatementFormat - формат выписки
+type StatementFormat string
+
+const (
+	StatementJSON StatementFormat = "json" // JSON-документ
+	StatementCSV  StatementFormat = "csv"  // таблица операций и итогов в CSV
+	StatementHTML StatementFormat = "html" // страница для печати
+)
+
+// Valid - проверяет, что формат поддерживается.
+func (f StatementFormat) Valid() bool {
+	switch f {
+	case StatementJSON, StatementCSV, StatementHTML:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,63 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+func TestStatement(t *testing.T) {
+	from := time.Date(2022, 2, 1, 0, 0, 0, 0, time.UTC)
+	s := NewStatement(1, from, decimal.NewFromInt(100))
+	if want := time.Date(2022, 3, 1, 0, 0, 0, 0, time.UTC); !s.To.Equal(want) {
+		t.Errorf("NewStatement().To = %v, want %v", s.To, want)
+	}
+
+	for _, p := range []*LedgerPosting{
+		{Account: LedgerAccrualSource, Amount: decimal.NewFromInt(500)},
+		{Account: LedgerPromoBudget, Amount: decimal.NewFromInt(50)},
+		{Account: LedgerWithdrawalSink, Amount: decimal.NewFromInt(-300)},
+		{Account: LedgerWithdrawalSink, Amount: decimal.NewFromInt(-40)},
+		{Account: LedgerWithdrawalSink, Amount: decimal.NewFromInt(40)}, // отмена списания
+		{Account: LedgerExpirationSink, Amount: decimal.NewFromInt(-20)},
+		{Account: LedgerTransferClearing, Amount: decimal.NewFromInt(-30)},
+		{Account: LedgerTransferClearing, Amount: decimal.NewFromInt(10)},
+		{Account: LedgerAdjustmentFund, Amount: decimal.NewFromInt(-5)},
+	} {
+		s.Add(p)
+	}
+
+	tests := []struct {
+		name string
+		got  decimal.Decimal
+		want int64
+	}{
+		{name: "opening", got: s.Opening, want: 100},
+		{name: "accruals", got: s.Accruals, want: 550},
+		{name: "withdrawals", got: s.Withdrawals, want: 300},
+		{name: "expirations", got: s.Expirations, want: 20},
+		{name: "transfers", got: s.Transfers, want: -20},
+		{name: "adjustments", got: s.Adjustments, want: -5},
+		{name: "closing", got: s.Closing, want: 305},
+	}
+	for _, tt := range tests {
+		if !tt.got.Equal(decimal.NewFromInt(tt.want)) {
+			t.Errorf("Statement %s = %s, want %d", tt.name, tt.got, tt.want)
+		}
+	}
+	if len(s.Postings) != 9 {
+		t.Errorf("len(Statement.Postings) = %d, want 9", len(s.Postings))
+	}
+}
+
+func TestStatementFormat_Valid(t *testing.T) {
+	for _, f := range []StatementFormat{StatementJSON, StatementCSV, StatementHTML} {
+		if !f.Valid() {
+			t.Errorf("StatementFormat(%q).Valid() = false, want true", f)
+		}
+	}
+	if StatementFormat("pdf").Valid() {
+		t.Error(`StatementFormat("pdf").Valid() = true, want false`)
+	}
+}
@@ -0,0 +1,25 @@
+package models
+
+import (
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// Источники изменения статуса операции
+const (
+	StatusSourceAccrual = "accrual" // система расчета начислений
+	StatusSourceShop    = "shop"    // магазин
+	StatusSourceUser    = "user"    // пользователь, например при отмене списания
+)
+
+// OperationStatusChange - запись истории изменения статуса операции
+type OperationStatusChange struct {
+	ID          uint64
+	OperationID uint64
+	OldStatus   OperationStatus
+	NewStatus   OperationStatus
+	Amount      decimal.Decimal // сумма операции после изменения
+	Source      string          // источник изменения: название интеграции или пользователь
+	CreatedAt   time.Time
+}
@@ -0,0 +1,29 @@
+package models
+
+import "time"
+
+// TOTP - секрет TOTP пользователя для двухфакторной аутентификации.
+// Двухфакторная аутентификация включена, если секрет подтвержден первым кодом.
+type TOTP struct {
+	UserID       uint64
+	Secret       string     // секрет в кодировке base32
+	ConfirmedAt  *time.Time // время подтверждения секрета, если не задано - секрет ожидает подтверждения
+	LastUsedStep int64      // шаг времени последнего использованного кода, коды этого и предыдущих шагов не принимаются
+	CreatedAt    time.Time
+}
+
+// Enabled - проверяет, что двухфакторная аутентификация включена.
+func (t *TOTP) Enabled() bool {
+	return t.ConfirmedAt != nil
+}
+
+// LoginChallenge - незавершенный вход пользователя, ожидающий второй фактор аутентификации.
+type LoginChallenge struct {
+	ID        uint64
+	UserID    uint64
+	TokenHash string     // хэш токена, который передается клиенту вместо access-токена
+	Attempts  int        // число неудачных попыток ввода кода
+	ExpiresAt time.Time  // время истечения
+	UsedAt    *time.Time // время завершения входа
+	CreatedAt time.Time
+}
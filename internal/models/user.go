@@ -13,6 +13,72 @@ type User struct {
 	PassHash  string
 	Balance   decimal.Decimal
 	Withdrawn decimal.Decimal
+	// Pending - удержанные баллы, которые входят в баланс, но не могут быть потрачены
+	Pending   decimal.Decimal
 	CreatedAt time.Time
 	UpdatedAt time.Time
+	// TokensValidAfter - токены пользователя, выпущенные раньше этого времени, недействительны
+	TokensValidAfter time.Time
+	Role             UserRole
+	// DeletedAt - время удаления учетной записи, удаленная учетная запись обезличена
+	DeletedAt *time.Time
+	Status    UserStatus
+	// BlockedAt - время блокировки учетной записи, если учетная запись заблокирована
+	BlockedAt   *time.Time
+	BlockReason *string
+}
+
+// Available - возвращает сумму баллов, которые пользователь может потратить.
+func (u *User) Available() decimal.Decimal {
+	return u.Balance.Sub(u.Pending)
+}
+
+// Blocked - проверяет, что учетная запись заблокирована.
+func (u *User) Blocked() bool {
+	return u.Status == UserBlocked
+}
+
+// UserStatus - статус учетной записи
+type UserStatus string
+
+const (
+	UserActive  UserStatus = "active"  // учетная запись активна
+	UserBlocked UserStatus = "blocked" // учетная запись заблокирована, вход и списания запрещены
+)
+
+// Valid - проверяет, что статус существует.
+func (s UserStatus) Valid() bool {
+	switch s {
+	case UserActive, UserBlocked:
+		return true
+	}
+	return false
+}
+
+// UserStatusChange - запись истории блокировок учетной записи
+type UserStatusChange struct {
+	ID        uint64
+	UserID    uint64
+	Status    UserStatus
+	Reason    string
+	ChangedBy uint64 // id сотрудника, изменившего статус
+	CreatedAt time.Time
+}
+
+// UserRole - роль пользователя
+type UserRole string
+
+const (
+	RoleUser    UserRole = "user"    // пользователь программы лояльности
+	RoleSupport UserRole = "support" // сотрудник службы поддержки
+	RoleAdmin   UserRole = "admin"   // администратор
+)
+
+// Valid - проверяет, что роль существует.
+func (r UserRole) Valid() bool {
+	switch r {
+	case RoleUser, RoleSupport, RoleAdmin:
+		return true
+	}
+	return false
 }
@@ -0,0 +1,62 @@
+// Package notify - отправка уведомлений пользователям.
+//
+// Реализации предназначены для локального запуска и отладки:
+// уведомления не доставляются пользователю, а пишутся в лог или в файл.
+package notify
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"gophermart-loyalty/internal/logger"
+	"gophermart-loyalty/internal/models"
+)
+
+// LogSender - отправляет уведомления в лог приложения.
+type LogSender struct {
+	log logger.Log
+}
+
+func NewLogSender(log logger.Log) *LogSender {
+	return &LogSender{log: log}
+}
+
+// Send - пишет уведомление в лог.
+func (s *LogSender) Send(ctx context.Context, user *models.User, subject, body string) error {
+	s.log.WithReqID(ctx).Info().
+		Uint64("user_id", user.ID).
+		Str("login", user.Login).
+		Str("subject", subject).
+		Msg(body)
+	return nil
+}
+
+// FileSender - дописывает уведомления в файл.
+type FileSender struct {
+	mu   sync.Mutex
+	path string
+}
+
+func NewFileSender(path string) *FileSender {
+	return &FileSender{path: path}
+}
+
+// Send - дописывает уведомление в конец файла.
+func (s *FileSender) Send(_ context.Context, user *models.User, subject, body string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	f, err := os.OpenFile(s.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	//goland:noinspection GoUnhandledErrorResult
+	defer f.Close()
+
+	_, err = fmt.Fprintf(f, "Date: %s\nTo: %s (id %d)\nSubject: %s\n\n%s\n\n",
+		time.Now().Format(time.RFC3339), user.Login, user.ID, subject, body)
+	return err
+}
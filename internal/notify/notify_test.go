@@ -0,0 +1,37 @@
+package notify
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"gophermart-loyalty/internal/models"
+)
+
+func TestFileSender(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "notify.txt")
+	s := NewFileSender(path)
+	user := &models.User{ID: 1, Login: "user1"}
+
+	if err := s.Send(context.Background(), user, "subject1", "body1"); err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+	if err := s.Send(context.Background(), user, "subject2", "body2"); err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("ReadFile() error = %v", err)
+	}
+	for _, want := range []string{
+		"To: user1 (id 1)\nSubject: subject1\n\nbody1\n",
+		"To: user1 (id 1)\nSubject: subject2\n\nbody2\n",
+	} {
+		if !strings.Contains(string(b), want) {
+			t.Errorf("file content = %q, want to contain %q", b, want)
+		}
+	}
+}
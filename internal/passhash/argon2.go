@@ -0,0 +1,109 @@
+package passhash
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+)
+
+// Параметры argon2id, не задаваемые в конфигурации
+const (
+	argon2SaltLen = 16 // длина соли в байтах
+	argon2KeyLen  = 32 // длина хэша в байтах
+)
+
+// argon2Prefix - префикс хэша argon2id
+const argon2Prefix = "$argon2id$"
+
+// argon2idAlg - алгоритм argon2id (RFC 9106).
+// Формат хэша (PHC string format): $argon2id$v=19$m=<память в КиБ>,t=<итерации>,p=<потоки>$<соль>$<хэш>
+// Соль и хэш кодируются в base64 без выравнивания.
+type argon2idAlg struct {
+	time    uint32 // число итераций
+	memory  uint32 // объем памяти в КиБ
+	threads uint8  // число потоков
+}
+
+// argon2Hash - разобранный хэш argon2id.
+type argon2Hash struct {
+	version int
+	argon2idAlg
+	salt []byte
+	key  []byte
+}
+
+func newArgon2id(time, memory uint32, threads uint8) (*argon2idAlg, error) {
+	if time < 1 || threads < 1 || memory < 8*uint32(threads) {
+		return nil, fmt.Errorf("invalid argon2id parameters: t=%d, m=%d, p=%d", time, memory, threads)
+	}
+	return &argon2idAlg{time: time, memory: memory, threads: threads}, nil
+}
+
+func (a *argon2idAlg) hash(password string) (string, error) {
+	salt := make([]byte, argon2SaltLen)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+	key := argon2.IDKey([]byte(password), salt, a.time, a.memory, a.threads, argon2KeyLen)
+	return fmt.Sprintf("%sv=%d$m=%d,t=%d,p=%d$%s$%s", argon2Prefix, argon2.Version, a.memory, a.time, a.threads,
+		base64.RawStdEncoding.EncodeToString(salt), base64.RawStdEncoding.EncodeToString(key)), nil
+}
+
+func (a *argon2idAlg) recognizes(encoded string) bool {
+	return strings.HasPrefix(encoded, argon2Prefix)
+}
+
+func (a *argon2idAlg) verify(encoded, password string) (bool, error) {
+	h, err := parseArgon2(encoded)
+	if err != nil {
+		return false, err
+	}
+	key := argon2.IDKey([]byte(password), h.salt, h.time, h.memory, h.threads, uint32(len(h.key)))
+	return subtle.ConstantTimeCompare(key, h.key) == 1, nil
+}
+
+func (a *argon2idAlg) current(encoded string) bool {
+	h, err := parseArgon2(encoded)
+	if err != nil {
+		return false
+	}
+	return h.version == argon2.Version && h.argon2idAlg == *a && len(h.key) == argon2KeyLen
+}
+
+// parseArgon2 - разбирает хэш argon2id.
+func parseArgon2(encoded string) (*argon2Hash, error) {
+	parts := strings.Split(encoded, "$")
+	if len(parts) != 6 || parts[1] != "argon2id" {
+		return nil, ErrUnknownHash
+	}
+
+	h := &argon2Hash{}
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &h.version); err != nil {
+		return nil, fmt.Errorf("invalid argon2id version: %w", err)
+	}
+	if h.version != argon2.Version {
+		return nil, fmt.Errorf("unsupported argon2id version %d", h.version)
+	}
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &h.memory, &h.time, &h.threads); err != nil {
+		return nil, fmt.Errorf("invalid argon2id parameters: %w", err)
+	}
+	if h.time < 1 || h.threads < 1 {
+		return nil, fmt.Errorf("invalid argon2id parameters: t=%d, p=%d", h.time, h.threads)
+	}
+
+	var err error
+	if h.salt, err = base64.RawStdEncoding.DecodeString(parts[4]); err != nil {
+		return nil, fmt.Errorf("invalid argon2id salt: %w", err)
+	}
+	if h.key, err = base64.RawStdEncoding.DecodeString(parts[5]); err != nil {
+		return nil, fmt.Errorf("invalid argon2id hash: %w", err)
+	}
+	if len(h.key) == 0 {
+		return nil, fmt.Errorf("empty argon2id hash")
+	}
+	return h, nil
+}
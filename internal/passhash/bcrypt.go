@@ -0,0 +1,52 @@
+package passhash
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// bcryptAlg - алгоритм bcrypt.
+// Формат хэша: $2a$<cost>$<salt и хэш>
+type bcryptAlg struct {
+	cost int
+}
+
+func newBcrypt(cost int) (*bcryptAlg, error) {
+	if cost < bcrypt.MinCost || cost > bcrypt.MaxCost {
+		return nil, fmt.Errorf("bcrypt cost must be between %d and %d", bcrypt.MinCost, bcrypt.MaxCost)
+	}
+	return &bcryptAlg{cost: cost}, nil
+}
+
+func (a *bcryptAlg) hash(password string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), a.cost)
+	if err != nil {
+		return "", err
+	}
+	return string(hash), nil
+}
+
+func (a *bcryptAlg) recognizes(encoded string) bool {
+	return strings.HasPrefix(encoded, "$2a$") || strings.HasPrefix(encoded, "$2b$") || strings.HasPrefix(encoded, "$2y$")
+}
+
+func (a *bcryptAlg) verify(encoded, password string) (bool, error) {
+	err := bcrypt.CompareHashAndPassword([]byte(encoded), []byte(password))
+	if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func (a *bcryptAlg) current(encoded string) bool {
+	if !a.recognizes(encoded) {
+		return false
+	}
+	cost, err := bcrypt.Cost([]byte(encoded))
+	return err == nil && cost == a.cost
+}
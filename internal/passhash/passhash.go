@@ -0,0 +1,85 @@
+// Package passhash - хэширование паролей.
+//
+// Алгоритм и его параметры хранятся в строке хэша, поэтому пароли, захэшированные
+// разными алгоритмами или с разными параметрами, проверяются одинаково.
+// Хэши с параметрами, отличными от текущих, можно прозрачно перехэшировать при успешном входе.
+package passhash
+
+import (
+	"errors"
+	"fmt"
+
+	"gophermart-loyalty/internal/config"
+)
+
+// Поддерживаемые алгоритмы хэширования
+const (
+	AlgArgon2id = "argon2id"
+	AlgBcrypt   = "bcrypt"
+)
+
+// ErrUnknownHash - формат хэша не распознан ни одним из поддерживаемых алгоритмов.
+var ErrUnknownHash = errors.New("unknown password hash format")
+
+// algorithm - алгоритм хэширования паролей.
+type algorithm interface {
+	// hash - возвращает хэш пароля с алгоритмом и параметрами в строке хэша.
+	hash(password string) (string, error)
+	// recognizes - проверяет, что хэш создан этим алгоритмом.
+	recognizes(encoded string) bool
+	// verify - проверяет пароль по хэшу, созданному этим алгоритмом.
+	verify(encoded, password string) (bool, error)
+	// current - проверяет, что хэш создан этим алгоритмом с текущими параметрами.
+	current(encoded string) bool
+}
+
+// Hasher - хэширует пароли текущим алгоритмом и проверяет пароли по хэшам любого поддерживаемого алгоритма.
+type Hasher struct {
+	current    algorithm   // алгоритм для новых хэшей
+	algorithms []algorithm // все поддерживаемые алгоритмы
+}
+
+// New - создает Hasher по конфигурации.
+// Новые хэши создаются алгоритмом cfg.Alg, для остальных алгоритмов используются их параметры из конфигурации.
+func New(cfg *config.Password) (*Hasher, error) {
+	a2, err := newArgon2id(cfg.Argon2Time, cfg.Argon2Memory, cfg.Argon2Threads)
+	if err != nil {
+		return nil, err
+	}
+	bc, err := newBcrypt(cfg.BcryptCost)
+	if err != nil {
+		return nil, err
+	}
+
+	h := &Hasher{algorithms: []algorithm{a2, bc}}
+	switch cfg.Alg {
+	case AlgArgon2id:
+		h.current = a2
+	case AlgBcrypt:
+		h.current = bc
+	default:
+		return nil, fmt.Errorf("unsupported password hash algorithm %q", cfg.Alg)
+	}
+	return h, nil
+}
+
+// Hash - возвращает хэш пароля, созданный текущим алгоритмом.
+func (h *Hasher) Hash(password string) (string, error) {
+	return h.current.hash(password)
+}
+
+// Verify - проверяет пароль по хэшу.
+// Возвращает ErrUnknownHash, если формат хэша не распознан.
+func (h *Hasher) Verify(encoded, password string) (bool, error) {
+	for _, a := range h.algorithms {
+		if a.recognizes(encoded) {
+			return a.verify(encoded, password)
+		}
+	}
+	return false, ErrUnknownHash
+}
+
+// NeedsRehash - проверяет, что хэш создан не текущим алгоритмом или с устаревшими параметрами.
+func (h *Hasher) NeedsRehash(encoded string) bool {
+	return !h.current.current(encoded)
+}
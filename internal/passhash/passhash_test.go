@@ -0,0 +1,144 @@
+package passhash
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+	"golang.org/x/crypto/bcrypt"
+
+	"gophermart-loyalty/internal/config"
+)
+
+func TestHasherSuite(t *testing.T) {
+	suite.Run(t, new(hasherSuite))
+}
+
+type hasherSuite struct {
+	suite.Suite
+	cfg config.Password
+}
+
+func (suite *hasherSuite) SetupTest() {
+	suite.cfg = config.Password{
+		Alg:           AlgArgon2id,
+		BcryptCost:    bcrypt.MinCost,
+		Argon2Time:    1,
+		Argon2Memory:  64,
+		Argon2Threads: 1,
+	}
+}
+
+func (suite *hasherSuite) newHasher(cfg config.Password) *Hasher {
+	h, err := New(&cfg)
+	suite.Require().NoError(err)
+	return h
+}
+
+func (suite *hasherSuite) TestHashVerify() {
+	for _, alg := range []string{AlgArgon2id, AlgBcrypt} {
+		suite.Run(alg, func() {
+			cfg := suite.cfg
+			cfg.Alg = alg
+			h := suite.newHasher(cfg)
+
+			hash, err := h.Hash("password")
+			suite.NoError(err)
+			suite.False(h.NeedsRehash(hash))
+
+			ok, err := h.Verify(hash, "password")
+			suite.NoError(err)
+			suite.True(ok)
+
+			ok, err = h.Verify(hash, "wrong password")
+			suite.NoError(err)
+			suite.False(ok)
+
+			// хэши одного пароля различаются за счет соли
+			other, err := h.Hash("password")
+			suite.NoError(err)
+			suite.NotEqual(hash, other)
+		})
+	}
+}
+
+func (suite *hasherSuite) TestArgon2idFormat() {
+	hash, err := suite.newHasher(suite.cfg).Hash("password")
+	suite.NoError(err)
+	suite.True(strings.HasPrefix(hash, "$argon2id$v=19$m=64,t=1,p=1$"))
+	suite.Len(strings.Split(hash, "$"), 6)
+}
+
+func (suite *hasherSuite) TestVerifyOtherAlgorithm() {
+	bcryptCfg := suite.cfg
+	bcryptCfg.Alg = AlgBcrypt
+	hash, err := suite.newHasher(bcryptCfg).Hash("password")
+	suite.NoError(err)
+
+	// хэш bcrypt проверяется хэшером argon2id и требует перехэширования
+	h := suite.newHasher(suite.cfg)
+	ok, err := h.Verify(hash, "password")
+	suite.NoError(err)
+	suite.True(ok)
+	suite.True(h.NeedsRehash(hash))
+}
+
+func (suite *hasherSuite) TestNeedsRehash() {
+	hash, err := suite.newHasher(suite.cfg).Hash("password")
+	suite.NoError(err)
+
+	suite.Run("argon2id parameters changed", func() {
+		cfg := suite.cfg
+		cfg.Argon2Time = 2
+		suite.True(suite.newHasher(cfg).NeedsRehash(hash))
+		cfg = suite.cfg
+		cfg.Argon2Memory = 128
+		suite.True(suite.newHasher(cfg).NeedsRehash(hash))
+	})
+
+	suite.Run("bcrypt cost changed", func() {
+		cfg := suite.cfg
+		cfg.Alg = AlgBcrypt
+		bcryptHash, err := suite.newHasher(cfg).Hash("password")
+		suite.NoError(err)
+		cfg.BcryptCost = bcrypt.MinCost + 1
+		suite.True(suite.newHasher(cfg).NeedsRehash(bcryptHash))
+	})
+
+	suite.Run("unknown hash", func() {
+		suite.True(suite.newHasher(suite.cfg).NeedsRehash("plain"))
+	})
+}
+
+func (suite *hasherSuite) TestVerifyInvalidHash() {
+	h := suite.newHasher(suite.cfg)
+	for _, hash := range []string{
+		"",
+		"plain",
+		"$argon2id$v=19$m=64,t=1,p=1$salt",
+		"$argon2id$v=18$m=64,t=1,p=1$c2FsdHNhbHRzYWx0c2FsdA$aGFzaA",
+		"$argon2id$v=19$m=64,t=0,p=1$c2FsdHNhbHRzYWx0c2FsdA$aGFzaA",
+		"$argon2id$v=19$m=64,t=1,p=1$!!!$aGFzaA",
+	} {
+		ok, err := h.Verify(hash, "password")
+		suite.Error(err, hash)
+		suite.False(ok, hash)
+	}
+}
+
+func (suite *hasherSuite) TestNewInvalidConfig() {
+	cfg := suite.cfg
+	cfg.Alg = "md5"
+	_, err := New(&cfg)
+	suite.Error(err)
+
+	cfg = suite.cfg
+	cfg.BcryptCost = bcrypt.MaxCost + 1
+	_, err = New(&cfg)
+	suite.Error(err)
+
+	cfg = suite.cfg
+	cfg.Argon2Threads = 0
+	_, err = New(&cfg)
+	suite.Error(err)
+}
@@ -0,0 +1,147 @@
+package repo
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+
+	"gophermart-loyalty/internal/models"
+)
+
+// stmtAccessTokenCreate - создает персональный токен доступа.
+//    $1 - user_id
+//    $2 - name
+//    $3 - token_hash
+//    $4 - scopes через пробел
+//    $5 - время жизни токена в секундах, 0 - бессрочный токен
+// Возвращает id, expires_at, created_at.
+var stmtAccessTokenCreate = registerStatement(`
+	INSERT INTO access_tokens (user_id, name, token_hash, scopes, expires_at)
+	VALUES ($1, $2, $3, $4, CASE WHEN $5::float8 > 0 THEN now() + make_interval(secs => $5::float8) END)
+	RETURNING id, expires_at, created_at
+`)
+
+// AccessTokenCreate - создает персональный токен доступа со временем жизни ttlSeconds.
+// Если ttlSeconds равно 0, токен бессрочный. Время истечения токена отсчитывается от текущего времени БД.
+// Если пользователь не найден, возвращает errs.ErrNotFound.
+func (r *PGXRepo) AccessTokenCreate(ctx context.Context, t *models.AccessToken, ttlSeconds int64) error {
+	err := r.statements[stmtAccessTokenCreate].
+		QueryRowContext(ctx, t.UserID, t.Name, t.TokenHash, joinScopes(t.Scopes), ttlSeconds).
+		Scan(&t.ID, &t.ExpiresAt, &t.CreatedAt)
+	if err != nil {
+		return r.handleError(ctx, err)
+	}
+	return nil
+}
+
+// stmtAccessTokenGetByUser - возвращает неотозванные персональные токены пользователя.
+//    $1 - user_id
+// Возвращает id, user_id, name, token_hash, scopes, expires_at, last_used_at, revoked_at, created_at.
+var stmtAccessTokenGetByUser = registerStatement(`
+	SELECT id, user_id, name, token_hash, scopes, expires_at, last_used_at, revoked_at, created_at FROM access_tokens
+	WHERE user_id = $1 AND revoked_at IS NULL
+	ORDER BY created_at DESC, id DESC
+`)
+
+// AccessTokenGetByUser - возвращает неотозванные персональные токены пользователя, включая истекшие.
+func (r *PGXRepo) AccessTokenGetByUser(ctx context.Context, userID uint64) ([]*models.AccessToken, error) {
+	rows, err := r.statements[stmtAccessTokenGetByUser].QueryContext(ctx, userID)
+	if err != nil {
+		return nil, r.handleError(ctx, err)
+	}
+	//goland:noinspection GoUnhandledErrorResult
+	defer rows.Close()
+
+	var list []*models.AccessToken
+	for rows.Next() {
+		t, err := scanAccessToken(rows)
+		if err != nil {
+			return nil, r.handleError(ctx, err)
+		}
+		list = append(list, t)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, r.handleError(ctx, err)
+	}
+	return list, nil
+}
+
+// stmtAccessTokenUse - отмечает использование активного персонального токена.
+//    $1 - token_hash
+// Возвращает id, user_id, name, token_hash, scopes, expires_at, last_used_at, revoked_at, created_at.
+var stmtAccessTokenUse = registerStatement(`
+	UPDATE access_tokens
+	SET last_used_at = now()
+	WHERE token_hash = $1 AND revoked_at IS NULL AND (expires_at IS NULL OR expires_at > now())
+		AND EXISTS(SELECT 1 FROM users WHERE id = access_tokens.user_id AND status = 'active')
+	RETURNING id, user_id, name, token_hash, scopes, expires_at, last_used_at, revoked_at, created_at
+`)
+
+// AccessTokenUse - возвращает активный персональный токен по хэшу и обновляет время его последнего использования.
+// Если активный токен не найден или учетная запись владельца заблокирована, возвращает errs.ErrNotFound.
+func (r *PGXRepo) AccessTokenUse(ctx context.Context, tokenHash string) (*models.AccessToken, error) {
+	t, err := scanAccessToken(r.statements[stmtAccessTokenUse].QueryRowContext(ctx, tokenHash))
+	if err != nil {
+		return nil, r.handleError(ctx, err)
+	}
+	return t, nil
+}
+
+// stmtAccessTokenRevoke - отзывает персональный токен пользователя.
+//    $1 - user_id
+//    $2 - id токена
+// Возвращает id токена.
+var stmtAccessTokenRevoke = registerStatement(`
+	UPDATE access_tokens
+	SET revoked_at = now()
+	WHERE id = $2 AND user_id = $1 AND revoked_at IS NULL
+	RETURNING id
+`)
+
+// AccessTokenRevoke - отзывает персональный токен tokenID пользователя userID.
+// Если токен не найден, уже отозван или принадлежит другому пользователю, возвращает errs.ErrNotFound.
+func (r *PGXRepo) AccessTokenRevoke(ctx context.Context, userID, tokenID uint64) error {
+	err := r.statements[stmtAccessTokenRevoke].
+		QueryRowContext(ctx, userID, tokenID).
+		Scan(&sql.NullInt64{})
+	if err != nil {
+		return r.handleError(ctx, err)
+	}
+	return nil
+}
+
+// rowScanner - общий интерфейс sql.Row и sql.Rows
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanAccessToken - сканирует персональный токен из строки результата запроса.
+func scanAccessToken(row rowScanner) (*models.AccessToken, error) {
+	t := &models.AccessToken{}
+	var scopes string
+	err := row.Scan(&t.ID, &t.UserID, &t.Name, &t.TokenHash, &scopes, &t.ExpiresAt, &t.LastUsedAt, &t.RevokedAt, &t.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	t.Scopes = splitScopes(scopes)
+	return t, nil
+}
+
+// joinScopes - объединяет области доступа в строку через пробел для хранения в БД.
+func joinScopes(scopes []models.AccessTokenScope) string {
+	s := make([]string, len(scopes))
+	for i, scope := range scopes {
+		s[i] = string(scope)
+	}
+	return strings.Join(s, " ")
+}
+
+// splitScopes - разбирает строку областей доступа, разделенных пробелами.
+func splitScopes(s string) []models.AccessTokenScope {
+	fields := strings.Fields(s)
+	scopes := make([]models.AccessTokenScope, len(fields))
+	for i, f := range fields {
+		scopes[i] = models.AccessTokenScope(f)
+	}
+	return scopes
+}
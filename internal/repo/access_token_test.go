@@ -0,0 +1,80 @@
+package repo
+
+import (
+	"time"
+
+	"gophermart-loyalty/internal/errs"
+	"gophermart-loyalty/internal/models"
+)
+
+func (suite *pgxRepoSuite) TestAccessTokenCreate() {
+	t := &models.AccessToken{
+		UserID:    1,
+		Name:      "script",
+		TokenHash: "hash1",
+		Scopes:    []models.AccessTokenScope{models.ScopeOrdersWrite, models.ScopeBalanceRead},
+	}
+	suite.NoError(suite.repo.AccessTokenCreate(suite.ctx(), t, 3600))
+	suite.NotZero(t.ID)
+	suite.Require().NotNil(t.ExpiresAt)
+	suite.True(t.ExpiresAt.After(t.CreatedAt))
+
+	list, err := suite.repo.AccessTokenGetByUser(suite.ctx(), 1)
+	suite.NoError(err)
+	suite.Require().Len(list, 1)
+	suite.Equal("script", list[0].Name)
+	suite.Equal(t.Scopes, list[0].Scopes)
+	suite.Nil(list[0].LastUsedAt)
+
+	t = &models.AccessToken{UserID: 1, Name: "forever", TokenHash: "hash2"}
+	suite.NoError(suite.repo.AccessTokenCreate(suite.ctx(), t, 0))
+	suite.Nil(t.ExpiresAt)
+
+	err = suite.repo.AccessTokenCreate(suite.ctx(), &models.AccessToken{UserID: 1000, Name: "x", TokenHash: "hash3"}, 0)
+	suite.ErrorIs(err, errs.ErrNotFound)
+}
+
+func (suite *pgxRepoSuite) TestAccessTokenUse() {
+	suite.NoError(suite.repo.AccessTokenCreate(suite.ctx(), &models.AccessToken{UserID: 1, Name: "active", TokenHash: "active"}, 3600))
+	suite.NoError(suite.repo.AccessTokenCreate(suite.ctx(), &models.AccessToken{UserID: 1, Name: "expired", TokenHash: "expired"}, 1))
+	time.Sleep(1100 * time.Millisecond)
+
+	suite.Run("active", func() {
+		t, err := suite.repo.AccessTokenUse(suite.ctx(), "active")
+		suite.NoError(err)
+		suite.Equal(uint64(1), t.UserID)
+		suite.NotNil(t.LastUsedAt)
+	})
+
+	suite.Run("expired", func() {
+		_, err := suite.repo.AccessTokenUse(suite.ctx(), "expired")
+		suite.ErrorIs(err, errs.ErrNotFound)
+	})
+
+	suite.Run("unknown", func() {
+		_, err := suite.repo.AccessTokenUse(suite.ctx(), "unknown")
+		suite.ErrorIs(err, errs.ErrNotFound)
+	})
+}
+
+func (suite *pgxRepoSuite) TestAccessTokenRevoke() {
+	t := &models.AccessToken{UserID: 1, Name: "script", TokenHash: "hash1"}
+	suite.NoError(suite.repo.AccessTokenCreate(suite.ctx(), t, 0))
+
+	suite.Run("token of another user", func() {
+		suite.ErrorIs(suite.repo.AccessTokenRevoke(suite.ctx(), 2, t.ID), errs.ErrNotFound)
+	})
+
+	suite.Run("revoke", func() {
+		suite.NoError(suite.repo.AccessTokenRevoke(suite.ctx(), 1, t.ID))
+		_, err := suite.repo.AccessTokenUse(suite.ctx(), "hash1")
+		suite.ErrorIs(err, errs.ErrNotFound)
+		list, err := suite.repo.AccessTokenGetByUser(suite.ctx(), 1)
+		suite.NoError(err)
+		suite.Empty(list)
+	})
+
+	suite.Run("already revoked", func() {
+		suite.ErrorIs(suite.repo.AccessTokenRevoke(suite.ctx(), 1, t.ID), errs.ErrNotFound)
+	})
+}
@@ -0,0 +1,138 @@
+package repo
+
+import (
+	"context"
+
+	"gophermart-loyalty/internal/models"
+)
+
+// stmtDataExportCreate - создает выгрузку персональных данных пользователя.
+//    $1 - user_id
+//    $2 - format
+// Возвращает id, status, created_at, updated_at выгрузки.
+var stmtDataExportCreate = registerStatement(`
+	INSERT INTO data_exports (user_id, format)
+	VALUES ($1, $2)
+	RETURNING id, status, created_at, updated_at
+`)
+
+// DataExportCreate - создает выгрузку персональных данных пользователя в статусе NEW.
+// Если у пользователя уже есть выгрузка в очереди, возвращает errs.ErrDataExportInProgress.
+func (r *PGXRepo) DataExportCreate(ctx context.Context, e *models.DataExport) error {
+	err := r.statements[stmtDataExportCreate].
+		QueryRowContext(ctx, e.UserID, e.Format).
+		Scan(&e.ID, &e.Status, &e.CreatedAt, &e.UpdatedAt)
+	if err != nil {
+		return r.handleError(ctx, err)
+	}
+	return nil
+}
+
+// stmtDataExportGet - возвращает неистекшую выгрузку пользователя.
+//    $1 - id
+//    $2 - user_id
+// Возвращает id, user_id, format, status, payload, created_at, updated_at, expires_at.
+var stmtDataExportGet = registerStatement(`
+	SELECT id, user_id, format, status, payload, created_at, updated_at, expires_at FROM data_exports
+	WHERE id = $1 AND user_id = $2 AND (expires_at IS NULL OR expires_at > now())
+`)
+
+// DataExportGet - возвращает выгрузку пользователя вместе с содержимым файла.
+// Если выгрузка не найдена, принадлежит другому пользователю или истекла, возвращает errs.ErrNotFound.
+func (r *PGXRepo) DataExportGet(ctx context.Context, userID, exportID uint64) (*models.DataExport, error) {
+	e := &models.DataExport{}
+	err := r.statements[stmtDataExportGet].
+		QueryRowContext(ctx, exportID, userID).
+		Scan(&e.ID, &e.UserID, &e.Format, &e.Status, &e.Payload, &e.CreatedAt, &e.UpdatedAt, &e.ExpiresAt)
+	if err != nil {
+		return nil, r.handleError(ctx, err)
+	}
+	return e, nil
+}
+
+type DataExportFunc func(ctx context.Context, e *models.DataExport) error
+
+// stmtDataExportLockFurther - ищет самую старую выгрузку в очереди
+// и блокирует ее для обновления другими транзакциями.
+// Возвращает id, user_id, format, status, created_at, updated_at выгрузки.
+// ВАЖНО: может вызываться только внутри транзакции.
+var stmtDataExportLockFurther = registerStatement(`
+	SELECT id, user_id, format, status, created_at, updated_at FROM data_exports
+	WHERE status = 'NEW'
+	ORDER BY created_at
+	FOR UPDATE SKIP LOCKED
+	LIMIT 1
+`)
+
+// stmtDataExportUpdate - сохраняет результат формирования выгрузки.
+//    $1 - id
+//    $2 - status
+//    $3 - payload
+//    $4 - время хранения выгрузки в секундах
+// Возвращает updated_at, expires_at.
+// ВАЖНО: может вызываться только внутри транзакции.
+var stmtDataExportUpdate = registerStatement(`
+	UPDATE data_exports
+	SET status = $2, payload = $3, updated_at = now(), expires_at = now() + make_interval(secs => $4)
+	WHERE id = $1
+	RETURNING updated_at, expires_at
+`)
+
+// DataExportProcessFurther - берет самую старую выгрузку в очереди, вызывает для нее коллбэк buildFunc,
+// который заполняет статус и содержимое выгрузки, и сохраняет результат со временем хранения ttlSeconds.
+// Если коллбэк вернул ошибку, выгрузка остается в очереди.
+// Если в очереди нет выгрузок, возвращает errs.ErrNotFound.
+func (r *PGXRepo) DataExportProcessFurther(ctx context.Context, ttlSeconds int64, buildFunc DataExportFunc) (*models.DataExport, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return nil, r.handleError(ctx, err)
+	}
+	//goland:noinspection ALL
+	defer tx.Rollback()
+
+	// Находим выгрузку и блокируем ее
+	e := &models.DataExport{}
+	err = tx.Stmt(r.statements[stmtDataExportLockFurther]).
+		QueryRowContext(ctx).
+		Scan(&e.ID, &e.UserID, &e.Format, &e.Status, &e.CreatedAt, &e.UpdatedAt)
+	if err != nil {
+		return nil, r.handleError(ctx, err)
+	}
+
+	// Вызываем коллбэк для формирования выгрузки
+	if err = buildFunc(ctx, e); err != nil {
+		return nil, err
+	}
+
+	err = tx.Stmt(r.statements[stmtDataExportUpdate]).
+		QueryRowContext(ctx, e.ID, e.Status, e.Payload, ttlSeconds).
+		Scan(&e.UpdatedAt, &e.ExpiresAt)
+	if err != nil {
+		return nil, r.handleError(ctx, err)
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, r.handleError(ctx, err)
+	}
+
+	return e, nil
+}
+
+// stmtDataExportDeleteExpired - удаляет истекшие выгрузки.
+var stmtDataExportDeleteExpired = registerStatement(`
+	DELETE FROM data_exports
+	WHERE expires_at <= now()
+`)
+
+// DataExportDeleteExpired - удаляет истекшие выгрузки. Возвращает число удаленных выгрузок.
+func (r *PGXRepo) DataExportDeleteExpired(ctx context.Context) (int64, error) {
+	res, err := r.statements[stmtDataExportDeleteExpired].ExecContext(ctx)
+	if err != nil {
+		return 0, r.handleError(ctx, err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return 0, r.handleError(ctx, err)
+	}
+	return n, nil
+}
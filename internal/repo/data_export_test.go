@@ -0,0 +1,96 @@
+package repo
+
+import (
+	"context"
+	"time"
+
+	"gophermart-loyalty/internal/errs"
+	"gophermart-loyalty/internal/models"
+)
+
+func (suite *pgxRepoSuite) TestDataExportCreate() {
+	e := &models.DataExport{UserID: 1, Format: models.DataExportJSON}
+	suite.NoError(suite.repo.DataExportCreate(suite.ctx(), e))
+	suite.NotZero(e.ID)
+	suite.Equal(models.DataExportNew, e.Status)
+
+	suite.Run("in progress", func() {
+		err := suite.repo.DataExportCreate(suite.ctx(), &models.DataExport{UserID: 1, Format: models.DataExportZIP})
+		suite.ErrorIs(err, errs.ErrDataExportInProgress)
+	})
+
+	suite.Run("invalid format", func() {
+		err := suite.repo.DataExportCreate(suite.ctx(), &models.DataExport{UserID: 2, Format: "xml"})
+		suite.ErrorIs(err, errs.ErrDataExportFormatInvalid)
+	})
+
+	suite.Run("unknown user", func() {
+		err := suite.repo.DataExportCreate(suite.ctx(), &models.DataExport{UserID: 1000, Format: models.DataExportJSON})
+		suite.ErrorIs(err, errs.ErrNotFound)
+	})
+
+	suite.Run("get", func() {
+		got, err := suite.repo.DataExportGet(suite.ctx(), 1, e.ID)
+		suite.NoError(err)
+		suite.Equal(models.DataExportNew, got.Status)
+		suite.Nil(got.Payload)
+
+		// выгрузка недоступна другим пользователям
+		_, err = suite.repo.DataExportGet(suite.ctx(), 2, e.ID)
+		suite.ErrorIs(err, errs.ErrNotFound)
+	})
+}
+
+func (suite *pgxRepoSuite) TestDataExportProcessFurther() {
+	e := &models.DataExport{UserID: 1, Format: models.DataExportJSON}
+	suite.NoError(suite.repo.DataExportCreate(suite.ctx(), e))
+
+	suite.Run("build error keeps export in queue", func() {
+		_, err := suite.repo.DataExportProcessFurther(suite.ctx(), 3600, func(ctx context.Context, e *models.DataExport) error {
+			return context.Canceled
+		})
+		suite.ErrorIs(err, context.Canceled)
+		got, err := suite.repo.DataExportGet(suite.ctx(), 1, e.ID)
+		suite.NoError(err)
+		suite.Equal(models.DataExportNew, got.Status)
+	})
+
+	suite.Run("ready", func() {
+		processed, err := suite.repo.DataExportProcessFurther(suite.ctx(), 3600, func(ctx context.Context, e *models.DataExport) error {
+			e.Status = models.DataExportReady
+			e.Payload = []byte(`{}`)
+			return nil
+		})
+		suite.NoError(err)
+		suite.Equal(e.ID, processed.ID)
+		suite.NotNil(processed.ExpiresAt)
+
+		got, err := suite.repo.DataExportGet(suite.ctx(), 1, e.ID)
+		suite.NoError(err)
+		suite.Equal(models.DataExportReady, got.Status)
+		suite.Equal([]byte(`{}`), got.Payload)
+	})
+
+	suite.Run("queue is empty", func() {
+		_, err := suite.repo.DataExportProcessFurther(suite.ctx(), 3600, func(ctx context.Context, e *models.DataExport) error {
+			return nil
+		})
+		suite.ErrorIs(err, errs.ErrNotFound)
+	})
+
+	suite.Run("expired", func() {
+		suite.NoError(suite.repo.DataExportCreate(suite.ctx(), &models.DataExport{UserID: 2, Format: models.DataExportZIP}))
+		processed, err := suite.repo.DataExportProcessFurther(suite.ctx(), 1, func(ctx context.Context, e *models.DataExport) error {
+			e.Status = models.DataExportFailed
+			return nil
+		})
+		suite.NoError(err)
+		time.Sleep(1100 * time.Millisecond)
+
+		_, err = suite.repo.DataExportGet(suite.ctx(), 2, processed.ID)
+		suite.ErrorIs(err, errs.ErrNotFound)
+		n, err := suite.repo.DataExportDeleteExpired(suite.ctx())
+		suite.NoError(err)
+		suite.Equal(int64(1), n)
+	})
+}
@@ -16,17 +16,38 @@ var constraintToAppError = map[string]*errs.Error{
 	"balance_not_negative":   errs.ErrUserBalanceNegative,   // общая сумма на счете не может быть отрицательной
 	"withdrawn_not_negative": errs.ErrUserWithdrawnNegative, // общая сумма списаний не может быть отрицательной
 
-	"operation_valid_attrs":    errs.ErrOperationAttrsInvalid,    // аттрибуты операции должны соответствовать типу операции
-	"amount_valid_sign":        errs.ErrOperationAmountInvalid,   // зачисления должны иметь положительные значения, а списания - отрицательные
-	"must_refs_user":           errs.ErrOperationUserNotExists,   // операция должна ссылаться на существующего пользователя
-	"order_belongs_to_user":    errs.ErrOperationOrderNotBelongs, // номер заказа может принадлежать только одному пользователю
-	"order_unique_for_op_type": errs.ErrOperationOrderUsed,       // по заказу возможна 1 операция списания баллов и 1 операция зачисления баллов
-	"must_refs_promo":          errs.ErrNotFound,                 // операция зачисления по промо-кампании должна ссылаться на существующую промо-кампанию
-	"promo_unique_for_user":    errs.ErrOperationPromoUsed,       // пользователь может воспользоваться промо-кампанией не более 1 раза
+	"operation_valid_attrs":    errs.ErrOperationAttrsInvalid,     // аттрибуты операции должны соответствовать типу операции
+	"amount_valid_sign":        errs.ErrOperationAmountInvalid,    // зачисления должны иметь положительные значения, а списания - отрицательные
+	"must_refs_user":           errs.ErrOperationUserNotExists,    // операция должна ссылаться на существующего пользователя
+	"order_belongs_to_user":    errs.ErrOperationOrderNotBelongs,  // номер заказа может принадлежать только одному пользователю
+	"order_unique_for_op_type": errs.ErrOperationOrderUsed,        // по заказу возможна 1 операция списания баллов и 1 операция зачисления баллов
+	"must_refs_promo":          errs.ErrNotFound,                  // операция зачисления по промо-кампании должна ссылаться на существующую промо-кампанию
+	"promo_unique_for_user":    errs.ErrOperationPromoUsed,        // пользователь может воспользоваться промо-кампанией не более 1 раза
+	"must_refs_counterparty":   errs.ErrTransferRecipientNotFound, // перевод должен ссылаться на существующего пользователя
+	"transfer_not_to_self":     errs.ErrTransferSelf,              // нельзя переводить баллы самому себе
 
 	"promo_code_unique":     errs.ErrPromoAlreadyExists,     // промо-кампания должна иметь уникальный код
 	"promo_reward_positive": errs.ErrPromoRewardNotPositive, // вознаграждение за промо-кампанию должно быть положительным
 	"promo_valid_period":    errs.ErrPromoPeriodInvalid,     // дата начала промо-кампании должна быть меньше даты окончания
+
+	"session_refs_user": errs.ErrNotFound, // сессия должна ссылаться на существующего пользователя
+
+	"password_reset_refs_user": errs.ErrNotFound, // токен сброса пароля должен ссылаться на существующего пользователя
+
+	"access_token_refs_user": errs.ErrNotFound, // персональный токен должен ссылаться на существующего пользователя
+
+	"totp_refs_user":            errs.ErrNotFound, // секрет TOTP должен ссылаться на существующего пользователя
+	"login_challenge_refs_user": errs.ErrNotFound, // незавершенный вход должен ссылаться на существующего пользователя
+
+	"data_export_refs_user":      errs.ErrNotFound,                // выгрузка должна ссылаться на существующего пользователя
+	"data_export_valid_format":   errs.ErrDataExportFormatInvalid, // формат выгрузки должен поддерживаться
+	"data_export_pending_unique": errs.ErrDataExportInProgress,    // у пользователя может быть только одна выгрузка в очереди
+
+	"user_status_change_refs_user":  errs.ErrNotFound, // история блокировок должна ссылаться на существующего пользователя
+	"user_status_change_refs_staff": errs.ErrNotFound, // история блокировок должна ссылаться на существующего сотрудника
+
+	"adjustment_refs_staff":  errs.ErrNotFound,   // корректировка должна ссылаться на существующего сотрудника
+	"adjustment_valid_audit": errs.ErrBadRequest, // причина и номер обращения корректировки обязательны
 }
 
 func (r *PGXRepo) handleError(ctx context.Context, err error) error {
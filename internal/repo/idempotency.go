@@ -0,0 +1,143 @@
+package repo
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"gophermart-loyalty/internal/models"
+)
+
+// stmtIdempotencyKeyDeleteExpiredByKey - удаляет истекший ключ идемпотентности пользователя, чтобы его можно было занять заново.
+//    $1 - user_id
+//    $2 - key
+// ВАЖНО: может вызываться только внутри транзакции.
+var stmtIdempotencyKeyDeleteExpiredByKey = registerStatement(`
+	DELETE FROM idempotency_keys
+	WHERE user_id = $1 AND key = $2 AND expires_at <= now()
+`)
+
+// stmtIdempotencyKeyReserve - занимает ключ идемпотентности пользователя, если он свободен.
+// Если ключ занят параллельным запросом, ожидает завершения его транзакции.
+//    $1 - user_id
+//    $2 - key
+//    $3 - fingerprint
+//    $4 - время хранения ключа в секундах
+// Возвращает created_at, expires_at, если ключ занят этим запросом.
+// ВАЖНО: может вызываться только внутри транзакции.
+var stmtIdempotencyKeyReserve = registerStatement(`
+	INSERT INTO idempotency_keys (user_id, key, fingerprint, expires_at)
+	VALUES ($1, $2, $3, now() + make_interval(secs => $4))
+	ON CONFLICT (user_id, key) DO NOTHING
+	RETURNING created_at, expires_at
+`)
+
+// stmtIdempotencyKeyGet - возвращает ключ идемпотентности пользователя и записанный ответ.
+//    $1 - user_id
+//    $2 - key
+// Возвращает fingerprint, status_code, content_type, body, created_at, expires_at.
+var stmtIdempotencyKeyGet = registerStatement(`
+	SELECT fingerprint, coalesce(status_code, 0), content_type, body, created_at, expires_at
+	FROM idempotency_keys
+	WHERE user_id = $1 AND key = $2
+`)
+
+// IdempotencyKeyReserve - занимает ключ идемпотентности k.Key пользователя k.UserID на ttlSeconds секунд.
+// Если ключ занят этим вызовом, возвращает nil. Если ключ уже занят другим запросом и не истек,
+// возвращает сохраненный ключ с записанным ответом, если запрос уже выполнен.
+func (r *PGXRepo) IdempotencyKeyReserve(ctx context.Context, k *models.IdempotencyKey, ttlSeconds int64) (*models.IdempotencyKey, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return nil, r.handleError(ctx, err)
+	}
+	//goland:noinspection ALL
+	defer tx.Rollback()
+
+	// Истекший ключ можно занять заново
+	_, err = tx.Stmt(r.statements[stmtIdempotencyKeyDeleteExpiredByKey]).ExecContext(ctx, k.UserID, k.Key)
+	if err != nil {
+		return nil, r.handleError(ctx, err)
+	}
+
+	err = tx.Stmt(r.statements[stmtIdempotencyKeyReserve]).
+		QueryRowContext(ctx, k.UserID, k.Key, k.Fingerprint, ttlSeconds).
+		Scan(&k.CreatedAt, &k.ExpiresAt)
+	switch {
+	case err == nil:
+		if err = tx.Commit(); err != nil {
+			return nil, r.handleError(ctx, err)
+		}
+		return nil, nil
+	case !errors.Is(err, sql.ErrNoRows):
+		return nil, r.handleError(ctx, err)
+	}
+
+	// Ключ занят другим запросом: возвращаем его вместе с записанным ответом
+	stored := &models.IdempotencyKey{UserID: k.UserID, Key: k.Key}
+	err = tx.Stmt(r.statements[stmtIdempotencyKeyGet]).
+		QueryRowContext(ctx, k.UserID, k.Key).
+		Scan(&stored.Fingerprint, &stored.StatusCode, &stored.ContentType, &stored.Body, &stored.CreatedAt, &stored.ExpiresAt)
+	if err != nil {
+		return nil, r.handleError(ctx, err)
+	}
+	return stored, nil
+}
+
+// stmtIdempotencyKeyComplete - записывает ответ на запрос с ключом идемпотентности.
+//    $1 - user_id
+//    $2 - key
+//    $3 - fingerprint
+//    $4 - status_code
+//    $5 - content_type
+//    $6 - body
+var stmtIdempotencyKeyComplete = registerStatement(`
+	UPDATE idempotency_keys
+	SET status_code = $4, content_type = $5, body = $6
+	WHERE user_id = $1 AND key = $2 AND fingerprint = $3 AND status_code IS NULL
+`)
+
+// IdempotencyKeyComplete - записывает ответ k на запрос с ключом идемпотентности, занятым IdempotencyKeyReserve.
+func (r *PGXRepo) IdempotencyKeyComplete(ctx context.Context, k *models.IdempotencyKey) error {
+	_, err := r.statements[stmtIdempotencyKeyComplete].
+		ExecContext(ctx, k.UserID, k.Key, k.Fingerprint, k.StatusCode, k.ContentType, k.Body)
+	if err != nil {
+		return r.handleError(ctx, err)
+	}
+	return nil
+}
+
+// stmtIdempotencyKeyRelease - освобождает ключ идемпотентности, ответ на запрос с которым не записан.
+//    $1 - user_id
+//    $2 - key
+var stmtIdempotencyKeyRelease = registerStatement(`
+	DELETE FROM idempotency_keys
+	WHERE user_id = $1 AND key = $2 AND status_code IS NULL
+`)
+
+// IdempotencyKeyRelease - освобождает ключ идемпотентности пользователя, если ответ на запрос с ним не записан,
+// чтобы запрос можно было повторить.
+func (r *PGXRepo) IdempotencyKeyRelease(ctx context.Context, userID uint64, key string) error {
+	if _, err := r.statements[stmtIdempotencyKeyRelease].ExecContext(ctx, userID, key); err != nil {
+		return r.handleError(ctx, err)
+	}
+	return nil
+}
+
+// stmtIdempotencyKeyDeleteExpired - удаляет истекшие ключи идемпотентности.
+var stmtIdempotencyKeyDeleteExpired = registerStatement(`
+	DELETE FROM idempotency_keys
+	WHERE expires_at <= now()
+`)
+
+// IdempotencyKeyDeleteExpired - удаляет истекшие ключи идемпотентности. Возвращает число удаленных ключей.
+func (r *PGXRepo) IdempotencyKeyDeleteExpired(ctx context.Context) (int64, error) {
+	res, err := r.statements[stmtIdempotencyKeyDeleteExpired].ExecContext(ctx)
+	if err != nil {
+		return 0, r.handleError(ctx, err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return 0, r.handleError(ctx, err)
+	}
+	return n, nil
+}
@@ -0,0 +1,69 @@
+package repo
+
+import (
+	"gophermart-loyalty/internal/models"
+)
+
+func (suite *pgxRepoSuite) TestIdempotencyKey() {
+	k := &models.IdempotencyKey{UserID: 1, Key: "key-1", Fingerprint: "fp1"}
+
+	suite.Run("reserve", func() {
+		stored, err := suite.repo.IdempotencyKeyReserve(suite.ctx(), k, 3600)
+		suite.NoError(err)
+		suite.Nil(stored)
+		suite.True(k.ExpiresAt.After(k.CreatedAt))
+	})
+
+	suite.Run("reserved key is in progress", func() {
+		stored, err := suite.repo.IdempotencyKeyReserve(suite.ctx(), &models.IdempotencyKey{UserID: 1, Key: "key-1", Fingerprint: "fp2"}, 3600)
+		suite.NoError(err)
+		suite.Require().NotNil(stored)
+		suite.Equal("fp1", stored.Fingerprint)
+		suite.False(stored.Completed())
+	})
+
+	suite.Run("keys are scoped by user", func() {
+		stored, err := suite.repo.IdempotencyKeyReserve(suite.ctx(), &models.IdempotencyKey{UserID: 2, Key: "key-1", Fingerprint: "fp1"}, 3600)
+		suite.NoError(err)
+		suite.Nil(stored)
+	})
+
+	suite.Run("completed response is stored", func() {
+		k.StatusCode = 202
+		k.ContentType = "application/json"
+		k.Body = []byte(`{"ok":true}`)
+		suite.NoError(suite.repo.IdempotencyKeyComplete(suite.ctx(), k))
+		// завершенный ключ не освобождается
+		suite.NoError(suite.repo.IdempotencyKeyRelease(suite.ctx(), 1, "key-1"))
+
+		stored, err := suite.repo.IdempotencyKeyReserve(suite.ctx(), &models.IdempotencyKey{UserID: 1, Key: "key-1", Fingerprint: "fp1"}, 3600)
+		suite.NoError(err)
+		suite.Require().NotNil(stored)
+		suite.Equal(202, stored.StatusCode)
+		suite.Equal("application/json", stored.ContentType)
+		suite.Equal(`{"ok":true}`, string(stored.Body))
+	})
+
+	suite.Run("released key can be reserved again", func() {
+		suite.NoError(suite.repo.IdempotencyKeyRelease(suite.ctx(), 2, "key-1"))
+		stored, err := suite.repo.IdempotencyKeyReserve(suite.ctx(), &models.IdempotencyKey{UserID: 2, Key: "key-1", Fingerprint: "fp2"}, 3600)
+		suite.NoError(err)
+		suite.Nil(stored)
+	})
+
+	suite.Run("expired key can be reserved again", func() {
+		_, err := suite.repo.IdempotencyKeyReserve(suite.ctx(), &models.IdempotencyKey{UserID: 1, Key: "key-2", Fingerprint: "fp1"}, 0)
+		suite.NoError(err)
+		stored, err := suite.repo.IdempotencyKeyReserve(suite.ctx(), &models.IdempotencyKey{UserID: 1, Key: "key-2", Fingerprint: "fp2"}, 3600)
+		suite.NoError(err)
+		suite.Nil(stored)
+	})
+
+	suite.Run("delete expired", func() {
+		_, err := suite.repo.IdempotencyKeyReserve(suite.ctx(), &models.IdempotencyKey{UserID: 3, Key: "key-1", Fingerprint: "fp1"}, 0)
+		suite.NoError(err)
+		n, err := suite.repo.IdempotencyKeyDeleteExpired(suite.ctx())
+		suite.NoError(err)
+		suite.Equal(int64(1), n)
+	})
+}
@@ -2,6 +2,9 @@ package repo
 
 import (
 	"context"
+	"time"
+
+	"github.com/shopspring/decimal"
 
 	"gophermart-loyalty/internal/models"
 )
@@ -11,6 +14,13 @@ type Repo interface {
 	UserRepo
 	OperationRepo
 	PromoRepo
+	SessionRepo
+	PasswordResetRepo
+	LoginFailureRepo
+	AccessTokenRepo
+	TOTPRepo
+	DataExportRepo
+	IdempotencyRepo
 }
 
 type UserRepo interface {
@@ -20,19 +30,70 @@ type UserRepo interface {
 	UserGetByID(ctx context.Context, userID uint64) (*models.User, error)
 	// UserGetByLogin - возвращает пользователя по логину.
 	UserGetByLogin(ctx context.Context, login string) (*models.User, error)
-	// UserBalanceHistoryGetByID - возвращает список операций пользователя, учитывающихся в балансе.
-	UserBalanceHistoryGetByID(ctx context.Context, userID uint64) ([]*models.Operation, error)
+	// UserUpdatePassHash - обновляет хэш пароля пользователя.
+	UserUpdatePassHash(ctx context.Context, userID uint64, passHash string) error
+	// UserUpgradePassHash - заменяет хэш пароля пользователя oldHash на newHash без смены пароля.
+	UserUpgradePassHash(ctx context.Context, userID uint64, oldHash, newHash string) error
+	// UserSetRole - устанавливает роль пользователя.
+	UserSetRole(ctx context.Context, userID uint64, role models.UserRole) error
+	// UserSetStatus - блокирует или разблокирует учетную запись пользователя и записывает изменение в историю.
+	UserSetStatus(ctx context.Context, c *models.UserStatusChange) error
+	// UserDelete - удаляет учетную запись пользователя: списывает остаток баланса операцией закрытия счета
+	// и обезличивает учетную запись, сохраняя операции. Прежний логин нельзя занять в течение graceSeconds.
+	UserDelete(ctx context.Context, userID uint64, description string, graceSeconds int64) (*models.Operation, error)
+	// UserBalanceHistoryGetByID - возвращает список операций пользователя, учитывающихся в балансе,
+	// выбранных по параметрам q по убыванию времени обработки. При q == nil возвращает все операции.
+	UserBalanceHistoryGetByID(ctx context.Context, userID uint64, q *models.OperationQuery) ([]*models.Operation, error)
+	// UserBalanceAsOf - возвращает баланс пользователя на момент asOf по сумме проводок, записанных до этого момента.
+	UserBalanceAsOf(ctx context.Context, userID uint64, asOf time.Time) (*models.BalanceSnapshot, error)
+	// UserLedgerPostingsGet - возвращает проводки по счету пользователя, записанные с from включительно
+	// до to, не включая, в порядке записи.
+	UserLedgerPostingsGet(ctx context.Context, userID uint64, from, to time.Time) ([]*models.LedgerPosting, error)
+	// UserBalanceCheck - сверяет закэшированные баланс и сумму списаний не более limit пользователей
+	// с id больше afterID с журналом проводок.
+	UserBalanceCheck(ctx context.Context, afterID uint64, limit int) ([]*models.BalanceCheck, error)
+	// UserBalanceRepair - исправляет расхождения баланса пользователя с журналом проводок корректирующими проводками.
+	UserBalanceRepair(ctx context.Context, userID uint64) (*models.BalanceCheck, error)
 }
 
 type OperationRepo interface {
-	// OperationCreate - создает операцию и обновляет баланс пользователя.
+	// OperationCreate - создает операцию, записывает проводку и обновляет баланс пользователя.
 	OperationCreate(ctx context.Context, op *models.Operation) error
 	// OperationUpdateFurther - берет самую старую операцию заданного типа,
-	// которая находится не в конечном статусе, вызывает для нее коллбэк updateOp, обновляет операцию
-	// и обновляет баланс пользователя.
-	OperationUpdateFurther(ctx context.Context, opType models.OperationType, updateFunc UpdateFunc) (*models.Operation, error)
-	// OperationGetByType - возвращает список операций пользователя заданного типа.
-	OperationGetByType(ctx context.Context, userID uint64, t models.OperationType) ([]*models.Operation, error)
+	// которая находится не в конечном статусе, вызывает для нее коллбэк updateOp, обновляет операцию,
+	// записывает изменение статуса в историю от имени source, записывает проводку и обновляет баланс пользователя.
+	OperationUpdateFurther(ctx context.Context, opType models.OperationType, source string, updateFunc UpdateFunc) (*models.Operation, error)
+	// OperationWithdraw - создает операцию списания, предварительно проверив коллбэком checkFunc
+	// сумму списаний пользователя за сутки и за месяц под блокировкой пользователя.
+	OperationWithdraw(ctx context.Context, op *models.Operation, checkFunc WithdrawalCheckFunc) error
+	// OperationUpdateByOrder - блокирует операцию пользователя заданного типа по номеру заказа,
+	// вызывает для нее коллбэк updateFunc, обновляет операцию, записывает изменение статуса в историю от имени source,
+	// записывает проводку и обновляет баланс пользователя.
+	OperationUpdateByOrder(ctx context.Context, userID uint64, opType models.OperationType, orderNumber string, source string, updateFunc UpdateFunc) (*models.Operation, error)
+	// OperationGetByOrder - возвращает операцию пользователя заданного типа по номеру заказа.
+	OperationGetByOrder(ctx context.Context, userID uint64, opType models.OperationType, orderNumber string) (*models.Operation, error)
+	// OperationStatusHistoryGetByOrder - возвращает историю изменения статуса операции пользователя
+	// заданного типа по номеру заказа.
+	OperationStatusHistoryGetByOrder(ctx context.Context, userID uint64, opType models.OperationType, orderNumber string) ([]*models.OperationStatusChange, error)
+	// OperationGetByType - возвращает список операций пользователя заданного типа,
+	// выбранных по параметрам q по убыванию времени создания. При q == nil возвращает все операции.
+	OperationGetByType(ctx context.Context, userID uint64, t models.OperationType, q *models.OperationQuery) ([]*models.Operation, error)
+	// OperationExpirationCandidates - возвращает не более limit пользователей с id больше afterID,
+	// у которых могут быть баллы, начисленные раньше чем olderThanSeconds секунд назад.
+	OperationExpirationCandidates(ctx context.Context, afterID uint64, olderThanSeconds int64, limit int) ([]uint64, error)
+	// OperationExpire - блокирует пользователя, вызывает для его операций, учитывающихся в балансе, коллбэк expireFunc
+	// и создает возвращенную коллбэком операцию сгорания баллов.
+	OperationExpire(ctx context.Context, userID uint64, expireFunc ExpireFunc) (*models.Operation, error)
+	// OperationHoldCandidates - возвращает не более limit пользователей с id больше afterID,
+	// у которых есть начисления с истекшим удержанием.
+	OperationHoldCandidates(ctx context.Context, afterID uint64, limit int) ([]uint64, error)
+	// OperationHoldRelease - снимает удержание с начислений пользователя, срок удержания которых истек.
+	OperationHoldRelease(ctx context.Context, userID uint64) (decimal.Decimal, error)
+	// OperationTransfer - переводит баллы между пользователями: в одной транзакции создает операцию списания debit
+	// у отправителя и операцию зачисления credit получателю, если коллбэк checkFunc не вернул ошибку.
+	OperationTransfer(ctx context.Context, debit, credit *models.Operation, checkFunc TransferCheckFunc) error
+	// OperationAdjust - создает операцию корректировки баланса и записывает корректировку в журнал корректировок.
+	OperationAdjust(ctx context.Context, op *models.Operation, adj *models.Adjustment) error
 }
 
 type PromoRepo interface {
@@ -40,4 +101,101 @@ type PromoRepo interface {
 	PromoCreate(ctx context.Context, p *models.Promo) error
 	// PromoGetByCode - возвращает промо-кампанию по ее промо-коду.
 	PromoGetByCode(ctx context.Context, code string) (*models.Promo, error)
+	// PromoGetByUser - возвращает промо-кампании, промо-коды которых использовал пользователь.
+	PromoGetByUser(ctx context.Context, userID uint64) ([]*models.Promo, error)
+}
+
+type SessionRepo interface {
+	// SessionCreate - создает сессию пользователя со сроком жизни ttlSeconds.
+	SessionCreate(ctx context.Context, s *models.Session, ttlSeconds int64) error
+	// SessionGetByID - возвращает сессию по id.
+	SessionGetByID(ctx context.Context, sessionID uint64) (*models.Session, error)
+	// SessionGetByUser - возвращает все сессии пользователя, включая отозванные и истекшие.
+	SessionGetByUser(ctx context.Context, userID uint64) ([]*models.Session, error)
+	// SessionRotate - заменяет refresh-токен активной сессии и продлевает сессию на ttlSeconds.
+	SessionRotate(ctx context.Context, oldHash, newHash string, ttlSeconds int64) (*models.Session, error)
+	// SessionRevoke - отзывает активную сессию пользователя.
+	SessionRevoke(ctx context.Context, userID, sessionID uint64) error
+	// SessionRevokeAll - отзывает все активные сессии и персональные токены пользователя и
+	// делает недействительными все ранее выпущенные токены пользователя.
+	SessionRevokeAll(ctx context.Context, userID uint64) error
+}
+
+type PasswordResetRepo interface {
+	// PasswordResetCreate - создает токен сброса пароля со сроком жизни ttlSeconds.
+	// Ранее выданные и не использованные токены пользователя становятся недействительными.
+	PasswordResetCreate(ctx context.Context, pr *models.PasswordReset, ttlSeconds int64) error
+	// PasswordResetConsume - использует токен сброса пароля, устанавливает новый хэш пароля
+	// и отзывает все сессии и персональные токены пользователя. Возвращает id пользователя.
+	PasswordResetConsume(ctx context.Context, tokenHash, passHash string) (uint64, error)
+}
+
+type LoginFailureRepo interface {
+	// LoginAttemptRegister - учитывает попытку входа по ключу до проверки пароля и возвращает счетчик попыток.
+	// Если попытки входа по ключу заблокированы, попытка не учитывается, и f.Blocked() возвращает true.
+	LoginAttemptRegister(ctx context.Context, key string, windowSeconds int64) (*models.LoginFailure, error)
+	// LoginAttemptRelease - исключает из счетчика по ключу попытку входа, которая не оказалась неудачной.
+	LoginAttemptRelease(ctx context.Context, key string) error
+	// LoginFailureBlock - блокирует попытки входа по ключу на blockSeconds после неудачной попытки.
+	LoginFailureBlock(ctx context.Context, key string, blockSeconds int64) (*models.LoginFailure, error)
+	// LoginFailureReset - сбрасывает счетчик неудачных попыток входа по ключу.
+	LoginFailureReset(ctx context.Context, key string) error
+}
+
+type AccessTokenRepo interface {
+	// AccessTokenCreate - создает персональный токен доступа со временем жизни ttlSeconds, 0 - бессрочный токен.
+	AccessTokenCreate(ctx context.Context, t *models.AccessToken, ttlSeconds int64) error
+	// AccessTokenGetByUser - возвращает неотозванные персональные токены пользователя.
+	AccessTokenGetByUser(ctx context.Context, userID uint64) ([]*models.AccessToken, error)
+	// AccessTokenUse - возвращает активный персональный токен по хэшу и обновляет время его последнего использования.
+	AccessTokenUse(ctx context.Context, tokenHash string) (*models.AccessToken, error)
+	// AccessTokenRevoke - отзывает персональный токен пользователя.
+	AccessTokenRevoke(ctx context.Context, userID, tokenID uint64) error
+}
+
+type TOTPRepo interface {
+	// TOTPGet - возвращает секрет TOTP пользователя.
+	TOTPGet(ctx context.Context, userID uint64) (*models.TOTP, error)
+	// TOTPEnroll - сохраняет новый неподтвержденный секрет TOTP пользователя.
+	TOTPEnroll(ctx context.Context, t *models.TOTP) error
+	// TOTPConfirm - подтверждает секрет TOTP пользователя и сохраняет хэши кодов восстановления.
+	TOTPConfirm(ctx context.Context, userID uint64, step int64, recoveryHashes []string) error
+	// TOTPUseStep - отмечает использование кода TOTP шага времени step, каждый шаг может быть использован один раз.
+	TOTPUseStep(ctx context.Context, userID uint64, step int64) error
+	// TOTPRecoveryCodeUse - использует код восстановления пользователя.
+	TOTPRecoveryCodeUse(ctx context.Context, userID uint64, codeHash string) error
+	// TOTPDelete - отключает двухфакторную аутентификацию пользователя.
+	TOTPDelete(ctx context.Context, userID uint64) error
+	// LoginChallengeCreate - создает незавершенный вход, ожидающий второй фактор, со временем жизни ttlSeconds.
+	LoginChallengeCreate(ctx context.Context, c *models.LoginChallenge, ttlSeconds int64) error
+	// LoginChallengeGet - возвращает действующий незавершенный вход по хэшу токена.
+	LoginChallengeGet(ctx context.Context, tokenHash string, maxAttempts int) (*models.LoginChallenge, error)
+	// LoginChallengeFail - регистрирует неудачную попытку ввода кода для незавершенного входа.
+	LoginChallengeFail(ctx context.Context, challengeID uint64) error
+	// LoginChallengeConsume - помечает незавершенный вход использованным.
+	LoginChallengeConsume(ctx context.Context, challengeID uint64) error
+}
+
+type DataExportRepo interface {
+	// DataExportCreate - создает выгрузку персональных данных пользователя в статусе NEW.
+	DataExportCreate(ctx context.Context, e *models.DataExport) error
+	// DataExportGet - возвращает неистекшую выгрузку пользователя.
+	DataExportGet(ctx context.Context, userID, exportID uint64) (*models.DataExport, error)
+	// DataExportProcessFurther - берет самую старую выгрузку в очереди, вызывает для нее коллбэк buildFunc
+	// и сохраняет результат со временем хранения ttlSeconds.
+	DataExportProcessFurther(ctx context.Context, ttlSeconds int64, buildFunc DataExportFunc) (*models.DataExport, error)
+	// DataExportDeleteExpired - удаляет истекшие выгрузки. Возвращает число удаленных выгрузок.
+	DataExportDeleteExpired(ctx context.Context) (int64, error)
+}
+
+type IdempotencyRepo interface {
+	// IdempotencyKeyReserve - занимает ключ идемпотентности пользователя на ttlSeconds секунд.
+	// Если ключ уже занят и не истек, возвращает сохраненный ключ с записанным ответом.
+	IdempotencyKeyReserve(ctx context.Context, k *models.IdempotencyKey, ttlSeconds int64) (*models.IdempotencyKey, error)
+	// IdempotencyKeyComplete - записывает ответ на запрос с ключом идемпотентности.
+	IdempotencyKeyComplete(ctx context.Context, k *models.IdempotencyKey) error
+	// IdempotencyKeyRelease - освобождает ключ идемпотентности, если ответ на запрос с ним не записан.
+	IdempotencyKeyRelease(ctx context.Context, userID uint64, key string) error
+	// IdempotencyKeyDeleteExpired - удаляет истекшие ключи идемпотентности. Возвращает число удаленных ключей.
+	IdempotencyKeyDeleteExpired(ctx context.Context) (int64, error)
 }
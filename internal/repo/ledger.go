@@ -0,0 +1,168 @@
+package repo
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/shopspring/decimal"
+
+	"gophermart-loyalty/internal/models"
+)
+
+// stmtLedgerPost - записывает проводку между счетом пользователя и системным счетом.
+// Счет пользователя создается при первой проводке.
+//    $1 - id операции
+//    $2 - id пользователя
+//    $3 - код системного счета
+//    $4 - сумма проводки: положительная зачисляется на счет пользователя, отрицательная - списывается с него
+// Возвращает id проводки.
+// ВАЖНО: может вызываться только внутри транзакции и только после вызова PGXRepo.userLockTx.
+var stmtLedgerPost = registerStatement(`
+	WITH
+		user_account AS (
+			INSERT INTO ledger_accounts (user_id) VALUES ($2)
+			ON CONFLICT (user_id) DO UPDATE SET user_id = excluded.user_id
+			RETURNING id
+		),
+		system_account AS (
+			SELECT id FROM ledger_accounts WHERE code = $3
+		)
+	INSERT INTO ledger_postings (operation_id, debit_account_id, credit_account_id, amount)
+	SELECT $1,
+		CASE WHEN $4::numeric > 0 THEN u.id ELSE s.id END,
+		CASE WHEN $4::numeric > 0 THEN s.id ELSE u.id END,
+		abs($4::numeric)
+	FROM user_account u, system_account s
+	RETURNING id
+`)
+
+// ledgerPostTx - записывает проводку по изменению операции op и обновляет баланс пользователя.
+// prevEffect - сумма, на которую операция изменяла баланс до изменения (см. models.Operation.BalanceEffect).
+// Если изменение операции не влияет на баланс, проводка не записывается.
+// Если операция удерживает начисленные баллы (см. models.Operation.HoldUntil), они учитываются в сумме удержанных баллов.
+// Если доступный баланс пользователя становится отрицательным, возвращает errs.ErrUserBalanceNegative.
+// ВАЖНО: может вызываться только внутри транзакции и только после вызова PGXRepo.userLockTx.
+func (r *PGXRepo) ledgerPostTx(ctx context.Context, tx *sql.Tx, op *models.Operation, prevEffect decimal.Decimal) error {
+	delta := op.BalanceEffect().Sub(prevEffect)
+	if delta.IsZero() {
+		return nil
+	}
+
+	account, err := r.ledgerWriteTx(ctx, tx, op, delta)
+	if err != nil {
+		return err
+	}
+
+	// Баллы, переведенные на счет списаний, учитываются в сумме списаний пользователя
+	withdrawnDelta := decimal.Zero
+	if account.Sink() {
+		withdrawnDelta = delta.Neg()
+	}
+	// Удержанные баллы учитываются в балансе, но не могут быть потрачены до снятия удержания
+	pendingDelta := decimal.Zero
+	if op.HoldUntil != nil && delta.IsPositive() {
+		pendingDelta = delta
+	}
+	return r.userUpdateBalanceTx(ctx, tx, op.UserID, delta, withdrawnDelta, pendingDelta)
+}
+
+// ledgerWriteTx - записывает проводку на сумму delta между счетом пользователя и системным счетом операции op
+// без изменения закэшированного баланса пользователя.
+// Возвращает системный счет проводки.
+// ВАЖНО: может вызываться только внутри транзакции и только после блокировки пользователя.
+func (r *PGXRepo) ledgerWriteTx(ctx context.Context, tx *sql.Tx, op *models.Operation, delta decimal.Decimal) (models.LedgerAccount, error) {
+	account, ok := op.Type.LedgerAccount()
+	if !ok {
+		return "", r.handleError(ctx, fmt.Errorf("no ledger account for operation type %s", op.Type))
+	}
+
+	err := tx.Stmt(r.statements[stmtLedgerPost]).
+		QueryRowContext(ctx, op.ID, op.UserID, account, delta).
+		Scan(&sql.NullInt64{})
+	if err != nil {
+		return "", r.handleError(ctx, err)
+	}
+	return account, nil
+}
+
+// ledgerSinkAccounts - коды счетов списаний (см. models.LedgerAccount.Sink)
+const ledgerSinkAccounts = `'withdrawal_sink', 'closure_sink'`
+
+// ledgerUserPostings - проводки по счетам пользователей.
+// Для каждой проводки возвращает id проводки, id пользователя, id операции, код системного счета, время проводки
+// и сумму, на которую проводка изменила баланс пользователя.
+const ledgerUserPostings = `
+	SELECT p.id, ua.user_id, p.operation_id, sa.code, p.created_at,
+		CASE WHEN p.debit_account_id = ua.id THEN p.amount ELSE 0 - p.amount END AS amount
+	FROM ledger_accounts ua
+		JOIN ledger_postings p ON ua.id IN (p.debit_account_id, p.credit_account_id)
+		JOIN ledger_accounts sa
+			ON sa.id = CASE WHEN p.debit_account_id = ua.id THEN p.credit_account_id ELSE p.debit_account_id END
+	WHERE ua.user_id IS NOT NULL
+`
+
+// operationBalanceEffect - сумма, на которую операция в текущем статусе изменяет баланс пользователя
+// (см. models.Operation.BalanceEffect).
+const operationBalanceEffect = `CASE WHEN ` + balanceOperationsCond + ` THEN amount ELSE 0 END`
+
+// stmtLedgerUnposted - возвращает операции пользователя, сумма проводок по которым не совпадает с суммой,
+// на которую операция в текущем статусе изменяет баланс пользователя.
+//    $1 - id пользователя
+// Возвращает id, user_id, op_type, status, amount, hold_until операции и сумму проводок по ней.
+// ВАЖНО: может вызываться только внутри транзакции и только после блокировки пользователя.
+var stmtLedgerUnposted = registerStatement(`
+	SELECT o.id, o.user_id, o.op_type, o.status, o.amount, o.hold_until, coalesce(l.posted, 0)
+	FROM operations o
+		LEFT JOIN (
+			SELECT operation_id, sum(amount) AS posted
+			FROM (` + ledgerUserPostings + `) lp
+			WHERE lp.user_id = $1
+			GROUP BY operation_id
+		) l ON l.operation_id = o.id
+	WHERE o.user_id = $1 AND ` + operationBalanceEffect + ` <> coalesce(l.posted, 0)
+	ORDER BY o.id
+`)
+
+// ledgerCorrectTx - записывает корректирующие проводки по операциям пользователя, сумма проводок по которым
+// не совпадает с их влиянием на баланс. Записанные ранее проводки не изменяются.
+// Закэшированный баланс пользователя не изменяется и не проверяется: он может расходиться с журналом,
+// поэтому после исправления журнала его нужно пересчитать (см. PGXRepo.UserBalanceRepair).
+// Возвращает число исправленных операций.
+// ВАЖНО: может вызываться только внутри транзакции и только после блокировки пользователя.
+func (r *PGXRepo) ledgerCorrectTx(ctx context.Context, tx *sql.Tx, userID uint64) (int, error) {
+	rows, err := tx.Stmt(r.statements[stmtLedgerUnposted]).QueryContext(ctx, userID)
+	if err != nil {
+		return 0, r.handleError(ctx, err)
+	}
+	//goland:noinspection GoUnhandledErrorResult
+	defer rows.Close()
+
+	var (
+		ops    []*models.Operation
+		posted []decimal.Decimal
+	)
+	for rows.Next() {
+		op := &models.Operation{}
+		var p decimal.Decimal
+		if err = rows.Scan(&op.ID, &op.UserID, &op.Type, &op.Status, &op.Amount, &op.HoldUntil, &p); err != nil {
+			return 0, r.handleError(ctx, err)
+		}
+		ops = append(ops, op)
+		posted = append(posted, p)
+	}
+	if err = rows.Err(); err != nil {
+		return 0, r.handleError(ctx, err)
+	}
+	// Проводки записываются в той же транзакции, поэтому выборку нужно закрыть до них
+	if err = rows.Close(); err != nil {
+		return 0, r.handleError(ctx, err)
+	}
+
+	for i, op := range ops {
+		if _, err = r.ledgerWriteTx(ctx, tx, op, op.BalanceEffect().Sub(posted[i])); err != nil {
+			return 0, err
+		}
+	}
+	return len(ops), nil
+}
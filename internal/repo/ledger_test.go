@@ -0,0 +1,95 @@
+package repo
+
+import (
+	"context"
+
+	"github.com/shopspring/decimal"
+
+	"gophermart-loyalty/internal/models"
+)
+
+// ledgerAccountBalance - возвращает остаток счета пользователя userID или системного счета code по журналу проводок.
+func (suite *pgxRepoSuite) ledgerAccountBalance(userID *uint64, code *models.LedgerAccount) decimal.Decimal {
+	var balance decimal.Decimal
+	suite.Require().NoError(suite.repo.db.QueryRowContext(suite.ctx(), `
+		SELECT coalesce(sum(CASE WHEN p.debit_account_id = a.id THEN p.amount ELSE -p.amount END), 0)
+		FROM ledger_accounts a
+			LEFT JOIN ledger_postings p ON a.id IN (p.debit_account_id, p.credit_account_id)
+		WHERE a.user_id = $1 OR a.code = $2
+	`, userID, code).Scan(&balance))
+	return balance
+}
+
+func (suite *pgxRepoSuite) TestLedger() {
+	userID := uint64(1)
+	suite.NoError(suite.repo.OperationCreate(suite.ctx(), testOA(1, "10", 100, models.StatusNew)))
+	suite.NoError(suite.repo.OperationCreate(suite.ctx(), testPA(1, 1, 5, models.StatusProcessed)))
+
+	suite.Run("pending accrual is not posted", func() {
+		suite.Equal("5", suite.ledgerAccountBalance(&userID, nil).String())
+		promo := models.LedgerPromoBudget
+		suite.Equal("-5", suite.ledgerAccountBalance(nil, &promo).String())
+	})
+
+	suite.Run("processed accrual is posted", func() {
+		_, err := suite.repo.OperationUpdateFurther(suite.ctx(), models.OrderAccrual, models.StatusSourceAccrual, func(ctx context.Context, op *models.Operation) error {
+			op.Status = models.StatusProcessed
+			return nil
+		})
+		suite.NoError(err)
+		suite.Equal("105", suite.ledgerAccountBalance(&userID, nil).String())
+	})
+
+	suite.Run("canceled withdrawal is reversed", func() {
+		suite.NoError(suite.repo.OperationCreate(suite.ctx(), testOW(1, "20", -30, models.StatusNew)))
+		suite.Equal("75", suite.ledgerAccountBalance(&userID, nil).String())
+
+		_, err := suite.repo.OperationUpdateFurther(suite.ctx(), models.OrderWithdrawal, models.StatusSourceShop, func(ctx context.Context, op *models.Operation) error {
+			op.Status = models.StatusCanceled
+			return nil
+		})
+		suite.NoError(err)
+		suite.Equal("105", suite.ledgerAccountBalance(&userID, nil).String())
+
+		var count int
+		suite.NoError(suite.repo.db.QueryRowContext(suite.ctx(),
+			`SELECT count(*) FROM ledger_postings p JOIN operations o ON o.id = p.operation_id WHERE o.order_number = '20'`,
+		).Scan(&count))
+		suite.Equal(2, count)
+	})
+
+	suite.Run("projection matches ledger", func() {
+		suite.NoError(suite.repo.OperationCreate(suite.ctx(), testOW(1, "30", -15, models.StatusProcessed)))
+		user, err := suite.repo.UserGetByID(suite.ctx(), 1)
+		suite.NoError(err)
+		suite.Equal("90", user.Balance.String())
+		suite.Equal("15", user.Withdrawn.String())
+		suite.True(user.Balance.Equal(suite.ledgerAccountBalance(&userID, nil)))
+		sink := models.LedgerWithdrawalSink
+		suite.Equal("15", suite.ledgerAccountBalance(nil, &sink).String())
+	})
+
+	suite.Run("postings are balanced", func() {
+		var total decimal.Decimal
+		suite.NoError(suite.repo.db.QueryRowContext(suite.ctx(), `
+			SELECT coalesce(sum(CASE WHEN p.debit_account_id = a.id THEN p.amount ELSE -p.amount END), 0)
+			FROM ledger_accounts a JOIN ledger_postings p ON a.id IN (p.debit_account_id, p.credit_account_id)
+		`).Scan(&total))
+		suite.True(total.IsZero())
+	})
+
+	suite.Run("postings are immutable", func() {
+		_, err := suite.repo.db.ExecContext(suite.ctx(), `UPDATE ledger_postings SET amount = amount + 1`)
+		suite.Error(err)
+		_, err = suite.repo.db.ExecContext(suite.ctx(), `DELETE FROM ledger_postings`)
+		suite.Error(err)
+	})
+
+	suite.Run("account closure is posted", func() {
+		_, err := suite.repo.UserDelete(suite.ctx(), 1, "closure", 3600)
+		suite.NoError(err)
+		suite.True(suite.ledgerAccountBalance(&userID, nil).IsZero())
+		closure := models.LedgerClosureSink
+		suite.Equal("90", suite.ledgerAccountBalance(nil, &closure).String())
+	})
+}
@@ -0,0 +1,98 @@
+package repo
+
+import (
+	"context"
+
+	"gophermart-loyalty/internal/models"
+)
+
+// stmtLoginAttemptRegister - учитывает попытку входа по ключу до проверки пароля.
+// Если попытки входа по ключу заблокированы, счетчик и время последней попытки не изменяются.
+// Если с последней попытки прошло больше windowSeconds, счетчик начинается заново.
+//    $1 - key
+//    $2 - windowSeconds
+// Возвращает key, failures, last_failed_at, blocked_until.
+var stmtLoginAttemptRegister = registerStatement(`
+	INSERT INTO login_failures AS f (key, failures)
+	VALUES ($1, 1)
+	ON CONFLICT (key) DO UPDATE
+	SET failures = CASE
+			WHEN f.blocked_until > now() THEN f.failures
+			WHEN f.last_failed_at < now() - make_interval(secs => $2) THEN 1
+			ELSE f.failures + 1
+		END,
+		last_failed_at = CASE
+			WHEN f.blocked_until > now() THEN f.last_failed_at
+			ELSE now()
+		END
+	RETURNING key, failures, last_failed_at, blocked_until
+`)
+
+// LoginAttemptRegister - учитывает попытку входа по ключу до проверки пароля и возвращает счетчик попыток.
+// Счетчик увеличивается одной вставкой с ON CONFLICT, поэтому параллельные попытки получают разные номера.
+// Если попытки входа по ключу заблокированы, попытка не учитывается, и f.Blocked() возвращает true.
+// Если с последней попытки прошло больше windowSeconds, счетчик начинается заново.
+func (r *PGXRepo) LoginAttemptRegister(ctx context.Context, key string, windowSeconds int64) (*models.LoginFailure, error) {
+	f := &models.LoginFailure{}
+	err := r.statements[stmtLoginAttemptRegister].
+		QueryRowContext(ctx, key, windowSeconds).
+		Scan(&f.Key, &f.Failures, &f.LastFailedAt, &f.BlockedUntil)
+	if err != nil {
+		return nil, r.handleError(ctx, err)
+	}
+	return f, nil
+}
+
+// stmtLoginAttemptRelease - исключает попытку входа из счетчика по ключу.
+//    $1 - key
+var stmtLoginAttemptRelease = registerStatement(`
+	UPDATE login_failures
+	SET failures = GREATEST(failures - 1, 0)
+	WHERE key = $1
+`)
+
+// LoginAttemptRelease - исключает из счетчика по ключу попытку входа, которая не оказалась неудачной.
+func (r *PGXRepo) LoginAttemptRelease(ctx context.Context, key string) error {
+	if _, err := r.statements[stmtLoginAttemptRelease].ExecContext(ctx, key); err != nil {
+		return r.handleError(ctx, err)
+	}
+	return nil
+}
+
+// stmtLoginFailureBlock - блокирует попытки входа по ключу.
+//    $1 - key
+//    $2 - время блокировки в секундах
+// Возвращает key, failures, last_failed_at, blocked_until.
+var stmtLoginFailureBlock = registerStatement(`
+	UPDATE login_failures
+	SET blocked_until = GREATEST(blocked_until, now() + make_interval(secs => $2))
+	WHERE key = $1
+	RETURNING key, failures, last_failed_at, blocked_until
+`)
+
+// LoginFailureBlock - блокирует попытки входа по ключу на blockSeconds после неудачной попытки.
+// Более длительная блокировка, установленная параллельной попыткой, не сокращается.
+func (r *PGXRepo) LoginFailureBlock(ctx context.Context, key string, blockSeconds int64) (*models.LoginFailure, error) {
+	f := &models.LoginFailure{}
+	err := r.statements[stmtLoginFailureBlock].
+		QueryRowContext(ctx, key, blockSeconds).
+		Scan(&f.Key, &f.Failures, &f.LastFailedAt, &f.BlockedUntil)
+	if err != nil {
+		return nil, r.handleError(ctx, err)
+	}
+	return f, nil
+}
+
+// stmtLoginFailureReset - сбрасывает счетчик неудачных попыток входа по ключу.
+//    $1 - key
+var stmtLoginFailureReset = registerStatement(`
+	DELETE FROM login_failures WHERE key = $1
+`)
+
+// LoginFailureReset - сбрасывает счетчик неудачных попыток входа по ключу.
+func (r *PGXRepo) LoginFailureReset(ctx context.Context, key string) error {
+	if _, err := r.statements[stmtLoginFailureReset].ExecContext(ctx, key); err != nil {
+		return r.handleError(ctx, err)
+	}
+	return nil
+}
@@ -0,0 +1,81 @@
+package repo
+
+import (
+	"sort"
+	"sync"
+)
+
+func (suite *pgxRepoSuite) TestLoginAttemptRegister() {
+	suite.Run("attempts counted", func() {
+		for i := 1; i <= 2; i++ {
+			f, err := suite.repo.LoginAttemptRegister(suite.ctx(), "login:oleg", 900)
+			suite.NoError(err)
+			suite.Equal(i, f.Failures)
+			suite.False(f.Blocked())
+		}
+	})
+
+	suite.Run("released attempt not counted", func() {
+		suite.NoError(suite.repo.LoginAttemptRelease(suite.ctx(), "login:oleg"))
+		f, err := suite.repo.LoginAttemptRegister(suite.ctx(), "login:oleg", 900)
+		suite.NoError(err)
+		suite.Equal(2, f.Failures)
+	})
+
+	suite.Run("blocked", func() {
+		f, err := suite.repo.LoginFailureBlock(suite.ctx(), "login:oleg", 3600)
+		suite.NoError(err)
+		suite.Equal(2, f.Failures)
+		suite.True(f.BlockedUntil.After(f.LastFailedAt))
+
+		// Попытка по заблокированному ключу не учитывается
+		f, err = suite.repo.LoginAttemptRegister(suite.ctx(), "login:oleg", 900)
+		suite.NoError(err)
+		suite.Equal(2, f.Failures)
+		suite.True(f.Blocked())
+	})
+
+	suite.Run("block is not shortened", func() {
+		blocked, err := suite.repo.LoginFailureBlock(suite.ctx(), "login:oleg", 0)
+		suite.NoError(err)
+		f, err := suite.repo.LoginAttemptRegister(suite.ctx(), "login:oleg", 900)
+		suite.NoError(err)
+		suite.True(f.Blocked())
+		suite.Equal(blocked.BlockedUntil, f.BlockedUntil)
+	})
+
+	suite.Run("reset", func() {
+		suite.NoError(suite.repo.LoginFailureReset(suite.ctx(), "login:oleg"))
+		f, err := suite.repo.LoginAttemptRegister(suite.ctx(), "login:oleg", 900)
+		suite.NoError(err)
+		suite.Equal(1, f.Failures)
+		suite.False(f.Blocked())
+	})
+
+	suite.Run("counter restarts after window", func() {
+		f, err := suite.repo.LoginAttemptRegister(suite.ctx(), "login:oleg", 0)
+		suite.NoError(err)
+		suite.Equal(1, f.Failures)
+	})
+
+	suite.Run("parallel attempts", func() {
+		// Параллельные попытки получают разные номера в счетчике
+		wg := &sync.WaitGroup{}
+		mu := &sync.Mutex{}
+		var failures []int
+		for i := 0; i < 8; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				f, err := suite.repo.LoginAttemptRegister(suite.ctx(), "ip:10.0.0.1", 900)
+				suite.NoError(err)
+				mu.Lock()
+				failures = append(failures, f.Failures)
+				mu.Unlock()
+			}()
+		}
+		wg.Wait()
+		sort.Ints(failures)
+		suite.Equal([]int{1, 2, 3, 4, 5, 6, 7, 8}, failures)
+	})
+}
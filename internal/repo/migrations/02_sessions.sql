@@ -0,0 +1,26 @@
+--------------------------------------------------------------------------------
+-- +goose Up
+--------------------------------------------------------------------------------
+
+-- Сессии пользователей
+CREATE TABLE IF NOT EXISTS sessions
+(
+    id           INTEGER PRIMARY KEY GENERATED ALWAYS AS IDENTITY,
+    user_id      INTEGER     NOT NULL,
+    refresh_hash VARCHAR(64) NOT NULL,
+    expires_at   TIMESTAMP   NOT NULL,
+    revoked_at   TIMESTAMP            DEFAULT NULL,
+    created_at   TIMESTAMP   NOT NULL DEFAULT now(),
+    updated_at   TIMESTAMP   NOT NULL DEFAULT now(),
+    CONSTRAINT session_refs_user FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE,
+    CONSTRAINT refresh_hash_unique UNIQUE (refresh_hash)
+);
+
+CREATE INDEX IF NOT EXISTS sessions_active_idx ON sessions (user_id)
+    WHERE revoked_at IS NULL;
+
+--------------------------------------------------------------------------------
+-- +goose Down
+--------------------------------------------------------------------------------
+DROP INDEX IF EXISTS sessions_active_idx;
+DROP TABLE IF EXISTS sessions;
@@ -0,0 +1,13 @@
+--------------------------------------------------------------------------------
+-- +goose Up
+--------------------------------------------------------------------------------
+
+-- Токены, выпущенные раньше этого времени, недействительны
+ALTER TABLE users
+    ADD COLUMN IF NOT EXISTS tokens_valid_after TIMESTAMP NOT NULL DEFAULT 'epoch';
+
+--------------------------------------------------------------------------------
+-- +goose Down
+--------------------------------------------------------------------------------
+ALTER TABLE users
+    DROP COLUMN IF EXISTS tokens_valid_after;
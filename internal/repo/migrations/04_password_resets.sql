@@ -0,0 +1,25 @@
+--------------------------------------------------------------------------------
+-- +goose Up
+--------------------------------------------------------------------------------
+
+-- Токены сброса пароля
+CREATE TABLE IF NOT EXISTS password_resets
+(
+    id         INTEGER PRIMARY KEY GENERATED ALWAYS AS IDENTITY,
+    user_id    INTEGER     NOT NULL,
+    token_hash VARCHAR(64) NOT NULL,
+    expires_at TIMESTAMP   NOT NULL,
+    used_at    TIMESTAMP            DEFAULT NULL,
+    created_at TIMESTAMP   NOT NULL DEFAULT now(),
+    CONSTRAINT password_reset_refs_user FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE,
+    CONSTRAINT reset_token_hash_unique UNIQUE (token_hash)
+);
+
+CREATE INDEX IF NOT EXISTS password_resets_unused_idx ON password_resets (user_id)
+    WHERE used_at IS NULL;
+
+--------------------------------------------------------------------------------
+-- +goose Down
+--------------------------------------------------------------------------------
+DROP INDEX IF EXISTS password_resets_unused_idx;
+DROP TABLE IF EXISTS password_resets;
@@ -0,0 +1,29 @@
+--------------------------------------------------------------------------------
+-- +goose Up
+--------------------------------------------------------------------------------
+
+-- Роли пользователей
+-- +goose StatementBegin
+DO
+$$
+    BEGIN
+        IF NOT EXISTS(SELECT 1 FROM pg_type WHERE typname = 'user_role') THEN
+            CREATE TYPE user_role AS ENUM (
+                'user',
+                'support',
+                'admin'
+                );
+        END IF;
+    END
+$$ LANGUAGE plpgsql;
+-- +goose StatementEnd
+
+ALTER TABLE users
+    ADD COLUMN IF NOT EXISTS role user_role NOT NULL DEFAULT 'user';
+
+--------------------------------------------------------------------------------
+-- +goose Down
+--------------------------------------------------------------------------------
+ALTER TABLE users
+    DROP COLUMN IF EXISTS role;
+DROP TYPE IF EXISTS user_role;
@@ -0,0 +1,17 @@
+--------------------------------------------------------------------------------
+-- +goose Up
+--------------------------------------------------------------------------------
+
+-- Неудачные попытки входа по логину и по IP-адресу
+CREATE TABLE IF NOT EXISTS login_failures
+(
+    key            VARCHAR(320) PRIMARY KEY,
+    failures       INTEGER   NOT NULL,
+    last_failed_at TIMESTAMP NOT NULL DEFAULT now(),
+    blocked_until  TIMESTAMP NOT NULL DEFAULT 'epoch'
+);
+
+--------------------------------------------------------------------------------
+-- +goose Down
+--------------------------------------------------------------------------------
+DROP TABLE IF EXISTS login_failures;
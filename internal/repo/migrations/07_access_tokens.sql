@@ -0,0 +1,28 @@
+--------------------------------------------------------------------------------
+-- +goose Up
+--------------------------------------------------------------------------------
+
+-- Персональные токены доступа
+CREATE TABLE IF NOT EXISTS access_tokens
+(
+    id           INTEGER PRIMARY KEY GENERATED ALWAYS AS IDENTITY,
+    user_id      INTEGER      NOT NULL,
+    name         VARCHAR(64)  NOT NULL,
+    token_hash   VARCHAR(64)  NOT NULL,
+    scopes       VARCHAR(256) NOT NULL,
+    expires_at   TIMESTAMP             DEFAULT NULL,
+    last_used_at TIMESTAMP             DEFAULT NULL,
+    revoked_at   TIMESTAMP             DEFAULT NULL,
+    created_at   TIMESTAMP    NOT NULL DEFAULT now(),
+    CONSTRAINT access_token_refs_user FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE,
+    CONSTRAINT access_token_hash_unique UNIQUE (token_hash)
+);
+
+CREATE INDEX IF NOT EXISTS access_tokens_active_idx ON access_tokens (user_id)
+    WHERE revoked_at IS NULL;
+
+--------------------------------------------------------------------------------
+-- +goose Down
+--------------------------------------------------------------------------------
+DROP INDEX IF EXISTS access_tokens_active_idx;
+DROP TABLE IF EXISTS access_tokens;
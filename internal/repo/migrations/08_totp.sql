@@ -0,0 +1,49 @@
+--------------------------------------------------------------------------------
+-- +goose Up
+--------------------------------------------------------------------------------
+
+-- Секреты TOTP для двухфакторной аутентификации
+CREATE TABLE IF NOT EXISTS user_totp
+(
+    user_id        INTEGER PRIMARY KEY,
+    secret         VARCHAR(64) NOT NULL,
+    confirmed_at   TIMESTAMP            DEFAULT NULL,
+    last_used_step BIGINT      NOT NULL DEFAULT 0,
+    created_at     TIMESTAMP   NOT NULL DEFAULT now(),
+    CONSTRAINT totp_refs_user FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+);
+
+-- Коды восстановления для входа без TOTP
+CREATE TABLE IF NOT EXISTS totp_recovery_codes
+(
+    id         INTEGER PRIMARY KEY GENERATED ALWAYS AS IDENTITY,
+    user_id    INTEGER     NOT NULL,
+    code_hash  VARCHAR(64) NOT NULL,
+    used_at    TIMESTAMP            DEFAULT NULL,
+    created_at TIMESTAMP   NOT NULL DEFAULT now(),
+    CONSTRAINT recovery_code_refs_totp FOREIGN KEY (user_id) REFERENCES user_totp (user_id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS totp_recovery_codes_user_idx ON totp_recovery_codes (user_id);
+
+-- Незавершенные входы, ожидающие второй фактор
+CREATE TABLE IF NOT EXISTS login_challenges
+(
+    id         INTEGER PRIMARY KEY GENERATED ALWAYS AS IDENTITY,
+    user_id    INTEGER     NOT NULL,
+    token_hash VARCHAR(64) NOT NULL,
+    attempts   INTEGER     NOT NULL DEFAULT 0,
+    expires_at TIMESTAMP   NOT NULL,
+    used_at    TIMESTAMP            DEFAULT NULL,
+    created_at TIMESTAMP   NOT NULL DEFAULT now(),
+    CONSTRAINT login_challenge_refs_user FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE,
+    CONSTRAINT login_challenge_hash_unique UNIQUE (token_hash)
+);
+
+--------------------------------------------------------------------------------
+-- +goose Down
+--------------------------------------------------------------------------------
+DROP TABLE IF EXISTS login_challenges;
+DROP INDEX IF EXISTS totp_recovery_codes_user_idx;
+DROP TABLE IF EXISTS totp_recovery_codes;
+DROP TABLE IF EXISTS user_totp;
@@ -0,0 +1,79 @@
+-- ALTER TYPE ... ADD VALUE нельзя использовать в той же транзакции, в которой добавлено значение
+-- +goose NO TRANSACTION
+
+--------------------------------------------------------------------------------
+-- +goose Up
+--------------------------------------------------------------------------------
+
+-- Операция закрытия счета при удалении учетной записи
+ALTER TYPE operation_type ADD VALUE IF NOT EXISTS 'account_closure';
+
+-- Время удаления учетной записи, удаленные учетные записи обезличиваются, но не удаляются из БД
+ALTER TABLE users
+    ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMP DEFAULT NULL;
+
+-- Операции сохраняются для учета, поэтому пользователя с операциями нельзя удалить из БД
+ALTER TABLE operations
+    DROP CONSTRAINT IF EXISTS must_refs_user,
+    ADD CONSTRAINT must_refs_user FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE RESTRICT;
+
+ALTER TABLE operations
+    DROP CONSTRAINT IF EXISTS amount_valid_sign,
+    ADD CONSTRAINT amount_valid_sign CHECK (
+            (amount >= 0 AND op_type IN ('order_accrual', 'promo_accrual'))
+            OR
+            (amount <= 0 AND op_type IN ('order_withdrawal', 'account_closure'))
+        );
+
+ALTER TABLE operations
+    DROP CONSTRAINT IF EXISTS operation_valid_attrs,
+    ADD CONSTRAINT operation_valid_attrs CHECK (
+            (op_type = 'order_accrual' AND order_number IS NOT NULL and promo_id IS NULL)
+            OR
+            (op_type = 'order_withdrawal' AND order_number IS NOT NULL AND promo_id IS NULL)
+            OR
+            (op_type = 'promo_accrual' AND order_number IS NULL AND promo_id IS NOT NULL)
+            OR
+            (op_type = 'account_closure' AND order_number IS NULL AND promo_id IS NULL)
+        );
+
+-- Логины удаленных учетных записей, которые нельзя занять до окончания срока.
+-- Хранится только хэш логина.
+CREATE TABLE IF NOT EXISTS deleted_logins
+(
+    login_hash     VARCHAR(64) PRIMARY KEY,
+    reusable_after TIMESTAMP NOT NULL
+);
+
+--------------------------------------------------------------------------------
+-- +goose Down
+--------------------------------------------------------------------------------
+DROP TABLE IF EXISTS deleted_logins;
+
+-- Значение account_closure типа operation_type не удаляется, т.к. PostgreSQL не поддерживает удаление значений перечислений.
+-- Операции закрытия счета сохраняются для учета, поэтому если счета уже закрывались, восстановление ограничений завершится ошибкой.
+
+ALTER TABLE operations
+    DROP CONSTRAINT IF EXISTS operation_valid_attrs,
+    ADD CONSTRAINT operation_valid_attrs CHECK (
+            (op_type = 'order_accrual' AND order_number IS NOT NULL and promo_id IS NULL)
+            OR
+            (op_type = 'order_withdrawal' AND order_number IS NOT NULL AND promo_id IS NULL)
+            OR
+            (op_type = 'promo_accrual' AND order_number IS NULL AND promo_id IS NOT NULL)
+        );
+
+ALTER TABLE operations
+    DROP CONSTRAINT IF EXISTS amount_valid_sign,
+    ADD CONSTRAINT amount_valid_sign CHECK (
+            (amount >= 0 AND op_type IN ('order_accrual', 'promo_accrual'))
+            OR
+            (amount <= 0 AND op_type IN ('order_withdrawal'))
+        );
+
+ALTER TABLE operations
+    DROP CONSTRAINT IF EXISTS must_refs_user,
+    ADD CONSTRAINT must_refs_user FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE;
+
+ALTER TABLE users
+    DROP COLUMN IF EXISTS deleted_at;
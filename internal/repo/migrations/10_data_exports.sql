@@ -0,0 +1,49 @@
+--------------------------------------------------------------------------------
+-- +goose Up
+--------------------------------------------------------------------------------
+
+-- Статусы выгрузки персональных данных
+-- +goose StatementBegin
+DO
+$$
+    BEGIN
+        IF NOT EXISTS(SELECT 1 FROM pg_type WHERE typname = 'data_export_status') THEN
+            CREATE TYPE data_export_status AS ENUM (
+                'NEW',
+                'READY',
+                'FAILED'
+                );
+        END IF;
+    END
+$$ LANGUAGE plpgsql;
+-- +goose StatementEnd
+
+-- Выгрузки персональных данных пользователей
+CREATE TABLE IF NOT EXISTS data_exports
+(
+    id         INTEGER PRIMARY KEY GENERATED ALWAYS AS IDENTITY,
+    user_id    INTEGER            NOT NULL,
+    format     VARCHAR(8)         NOT NULL,
+    status     data_export_status NOT NULL DEFAULT 'NEW',
+    payload    BYTEA                       DEFAULT NULL,
+    created_at TIMESTAMP          NOT NULL DEFAULT now(),
+    updated_at TIMESTAMP          NOT NULL DEFAULT now(),
+    expires_at TIMESTAMP                   DEFAULT NULL,
+    CONSTRAINT data_export_refs_user FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE,
+    CONSTRAINT data_export_valid_format CHECK ( format IN ('json', 'zip') )
+);
+
+-- У пользователя может быть только одна выгрузка в очереди
+CREATE UNIQUE INDEX IF NOT EXISTS data_export_pending_unique ON data_exports (user_id)
+    WHERE status = 'NEW';
+
+CREATE INDEX IF NOT EXISTS data_exports_expires_idx ON data_exports (expires_at)
+    WHERE expires_at IS NOT NULL;
+
+--------------------------------------------------------------------------------
+-- +goose Down
+--------------------------------------------------------------------------------
+DROP INDEX IF EXISTS data_exports_expires_idx;
+DROP INDEX IF EXISTS data_export_pending_unique;
+DROP TABLE IF EXISTS data_exports;
+DROP TYPE IF EXISTS data_export_status;
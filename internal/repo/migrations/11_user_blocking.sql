@@ -0,0 +1,53 @@
+--------------------------------------------------------------------------------
+-- +goose Up
+--------------------------------------------------------------------------------
+
+-- Статусы учетных записей
+-- +goose StatementBegin
+DO
+$$
+    BEGIN
+        IF NOT EXISTS(SELECT 1 FROM pg_type WHERE typname = 'user_status') THEN
+            CREATE TYPE user_status AS ENUM (
+                'active',
+                'blocked'
+                );
+        END IF;
+    END
+$$ LANGUAGE plpgsql;
+-- +goose StatementEnd
+
+-- Заблокированная учетная запись сохраняется, но пользователь не может войти и списывать баллы
+ALTER TABLE users
+    ADD COLUMN IF NOT EXISTS status       user_status NOT NULL DEFAULT 'active',
+    ADD COLUMN IF NOT EXISTS blocked_at   TIMESTAMP            DEFAULT NULL,
+    ADD COLUMN IF NOT EXISTS block_reason TEXT                 DEFAULT NULL,
+    DROP CONSTRAINT IF EXISTS user_blocked_valid,
+    ADD CONSTRAINT user_blocked_valid CHECK ( (status = 'blocked') = (blocked_at IS NOT NULL) );
+
+-- История блокировок и разблокировок учетных записей
+CREATE TABLE IF NOT EXISTS user_status_changes
+(
+    id         INTEGER PRIMARY KEY GENERATED ALWAYS AS IDENTITY,
+    user_id    INTEGER     NOT NULL,
+    status     user_status NOT NULL,
+    reason     TEXT        NOT NULL,
+    changed_by INTEGER     NOT NULL,
+    created_at TIMESTAMP   NOT NULL DEFAULT now(),
+    CONSTRAINT user_status_change_refs_user FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE,
+    CONSTRAINT user_status_change_refs_staff FOREIGN KEY (changed_by) REFERENCES users (id) ON DELETE RESTRICT
+);
+
+CREATE INDEX IF NOT EXISTS user_status_changes_user_idx ON user_status_changes (user_id, created_at);
+
+--------------------------------------------------------------------------------
+-- +goose Down
+--------------------------------------------------------------------------------
+DROP INDEX IF EXISTS user_status_changes_user_idx;
+DROP TABLE IF EXISTS user_status_changes;
+ALTER TABLE users
+    DROP CONSTRAINT IF EXISTS user_blocked_valid,
+    DROP COLUMN IF EXISTS block_reason,
+    DROP COLUMN IF EXISTS blocked_at,
+    DROP COLUMN IF EXISTS status;
+DROP TYPE IF EXISTS user_status;
@@ -0,0 +1,97 @@
+--------------------------------------------------------------------------------
+-- +goose Up
+--------------------------------------------------------------------------------
+
+-- Счета журнала проводок: счет пользователя или системный счет
+CREATE TABLE IF NOT EXISTS ledger_accounts
+(
+    id         INTEGER PRIMARY KEY GENERATED ALWAYS AS IDENTITY,
+    user_id    INTEGER     DEFAULT NULL,
+    code       VARCHAR(32) DEFAULT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT now(),
+    CONSTRAINT ledger_account_refs_user FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE RESTRICT,
+    CONSTRAINT ledger_account_user_unique UNIQUE (user_id),
+    CONSTRAINT ledger_account_code_unique UNIQUE (code),
+    CONSTRAINT ledger_account_valid_owner CHECK ( (user_id IS NULL) <> (code IS NULL) )
+);
+
+-- Системные счета
+INSERT INTO ledger_accounts (code)
+VALUES ('accrual_source'),
+       ('promo_budget'),
+       ('withdrawal_sink'),
+       ('closure_sink')
+ON CONFLICT DO NOTHING;
+
+-- Проводки: каждая проводка переводит amount баллов со счета credit_account_id на счет debit_account_id,
+-- поэтому сумма остатков всех счетов всегда равна нулю.
+-- Остаток счета - сумма проводок по дебету за вычетом суммы проводок по кредиту.
+CREATE TABLE IF NOT EXISTS ledger_postings
+(
+    id                INTEGER PRIMARY KEY GENERATED ALWAYS AS IDENTITY,
+    operation_id      INTEGER        NOT NULL,
+    debit_account_id  INTEGER        NOT NULL,
+    credit_account_id INTEGER        NOT NULL,
+    amount            DECIMAL(16, 4) NOT NULL,
+    created_at        TIMESTAMP      NOT NULL DEFAULT now(),
+    CONSTRAINT ledger_posting_refs_operation FOREIGN KEY (operation_id) REFERENCES operations (id) ON DELETE RESTRICT,
+    CONSTRAINT ledger_posting_refs_debit FOREIGN KEY (debit_account_id) REFERENCES ledger_accounts (id) ON DELETE RESTRICT,
+    CONSTRAINT ledger_posting_refs_credit FOREIGN KEY (credit_account_id) REFERENCES ledger_accounts (id) ON DELETE RESTRICT,
+    CONSTRAINT ledger_posting_valid CHECK ( amount > 0 AND debit_account_id <> credit_account_id )
+);
+
+CREATE INDEX IF NOT EXISTS ledger_postings_operation_idx ON ledger_postings (operation_id);
+CREATE INDEX IF NOT EXISTS ledger_postings_debit_idx ON ledger_postings (debit_account_id, created_at);
+CREATE INDEX IF NOT EXISTS ledger_postings_credit_idx ON ledger_postings (credit_account_id, created_at);
+
+-- Проводки неизменяемы: исправление записывается новой проводкой в обратном направлении
+-- +goose StatementBegin
+CREATE OR REPLACE FUNCTION ledger_postings_immutable() RETURNS trigger AS
+$$
+BEGIN
+    RAISE EXCEPTION 'ledger postings are immutable';
+END
+$$ LANGUAGE plpgsql;
+-- +goose StatementEnd
+
+DROP TRIGGER IF EXISTS ledger_postings_immutable ON ledger_postings;
+CREATE TRIGGER ledger_postings_immutable
+    BEFORE UPDATE OR DELETE
+    ON ledger_postings
+    FOR EACH ROW
+EXECUTE FUNCTION ledger_postings_immutable();
+
+-- Счета существующих пользователей
+INSERT INTO ledger_accounts (user_id)
+SELECT id FROM users
+ON CONFLICT DO NOTHING;
+
+-- Проводки по существующим операциям, учитывающимся в балансе
+INSERT INTO ledger_postings (operation_id, debit_account_id, credit_account_id, amount, created_at)
+SELECT o.id,
+       CASE WHEN o.amount > 0 THEN ua.id ELSE sa.id END,
+       CASE WHEN o.amount > 0 THEN sa.id ELSE ua.id END,
+       abs(o.amount),
+       o.updated_at
+FROM operations o
+         JOIN ledger_accounts ua ON ua.user_id = o.user_id
+         JOIN ledger_accounts sa ON sa.code = CASE o.op_type
+                                                  WHEN 'order_accrual' THEN 'accrual_source'
+                                                  WHEN 'promo_accrual' THEN 'promo_budget'
+                                                  WHEN 'order_withdrawal' THEN 'withdrawal_sink'
+                                                  WHEN 'account_closure' THEN 'closure_sink'
+    END
+WHERE (o.amount > 0 AND o.status = 'PROCESSED')
+   OR (o.amount < 0 AND o.status NOT IN ('INVALID', 'CANCELED'))
+ORDER BY o.id;
+
+--------------------------------------------------------------------------------
+-- +goose Down
+--------------------------------------------------------------------------------
+DROP TRIGGER IF EXISTS ledger_postings_immutable ON ledger_postings;
+DROP FUNCTION IF EXISTS ledger_postings_immutable();
+DROP INDEX IF EXISTS ledger_postings_credit_idx;
+DROP INDEX IF EXISTS ledger_postings_debit_idx;
+DROP INDEX IF EXISTS ledger_postings_operation_idx;
+DROP TABLE IF EXISTS ledger_postings;
+DROP TABLE IF EXISTS ledger_accounts;
@@ -0,0 +1,67 @@
+-- ALTER TYPE ... ADD VALUE нельзя использовать в той же транзакции, в которой добавлено значение
+-- +goose NO TRANSACTION
+
+--------------------------------------------------------------------------------
+-- +goose Up
+--------------------------------------------------------------------------------
+
+-- Операция сгорания баллов, срок жизни которых истек
+ALTER TYPE operation_type ADD VALUE IF NOT EXISTS 'points_expiration';
+
+ALTER TABLE operations
+    DROP CONSTRAINT IF EXISTS amount_valid_sign,
+    ADD CONSTRAINT amount_valid_sign CHECK (
+            (amount >= 0 AND op_type IN ('order_accrual', 'promo_accrual'))
+            OR
+            (amount <= 0 AND op_type IN ('order_withdrawal', 'account_closure', 'points_expiration'))
+        );
+
+ALTER TABLE operations
+    DROP CONSTRAINT IF EXISTS operation_valid_attrs,
+    ADD CONSTRAINT operation_valid_attrs CHECK (
+            (op_type = 'order_accrual' AND order_number IS NOT NULL and promo_id IS NULL)
+            OR
+            (op_type = 'order_withdrawal' AND order_number IS NOT NULL AND promo_id IS NULL)
+            OR
+            (op_type = 'promo_accrual' AND order_number IS NULL AND promo_id IS NOT NULL)
+            OR
+            (op_type IN ('account_closure', 'points_expiration') AND order_number IS NULL AND promo_id IS NULL)
+        );
+
+-- Системный счет сгоревших баллов
+INSERT INTO ledger_accounts (code)
+VALUES ('expiration_sink')
+ON CONFLICT DO NOTHING;
+
+-- Поиск начислений, срок жизни которых истек
+CREATE INDEX IF NOT EXISTS expiring_accruals_idx ON operations (updated_at, user_id)
+    WHERE status = 'PROCESSED' AND amount > 0;
+
+--------------------------------------------------------------------------------
+-- +goose Down
+--------------------------------------------------------------------------------
+DROP INDEX IF EXISTS expiring_accruals_idx;
+
+-- Значение points_expiration типа operation_type не удаляется, т.к. PostgreSQL не поддерживает удаление значений перечислений.
+-- Проводки неизменяемы, поэтому если баллы уже сгорали, восстановление ограничений завершится ошибкой.
+ALTER TABLE operations
+    DROP CONSTRAINT IF EXISTS amount_valid_sign,
+    ADD CONSTRAINT amount_valid_sign CHECK (
+            (amount >= 0 AND op_type IN ('order_accrual', 'promo_accrual'))
+            OR
+            (amount <= 0 AND op_type IN ('order_withdrawal', 'account_closure'))
+        );
+
+ALTER TABLE operations
+    DROP CONSTRAINT IF EXISTS operation_valid_attrs,
+    ADD CONSTRAINT operation_valid_attrs CHECK (
+            (op_type = 'order_accrual' AND order_number IS NOT NULL and promo_id IS NULL)
+            OR
+            (op_type = 'order_withdrawal' AND order_number IS NOT NULL AND promo_id IS NULL)
+            OR
+            (op_type = 'promo_accrual' AND order_number IS NULL AND promo_id IS NOT NULL)
+            OR
+            (op_type = 'account_closure' AND order_number IS NULL AND promo_id IS NULL)
+        );
+
+DELETE FROM ledger_accounts WHERE code = 'expiration_sink';
@@ -0,0 +1,92 @@
+-- ALTER TYPE ... ADD VALUE нельзя использовать в той же транзакции, в которой добавлено значение
+-- +goose NO TRANSACTION
+
+--------------------------------------------------------------------------------
+-- +goose Up
+--------------------------------------------------------------------------------
+
+-- Операция перевода баллов другому пользователю
+ALTER TYPE operation_type ADD VALUE IF NOT EXISTS 'transfer';
+
+-- Второй участник перевода
+ALTER TABLE operations
+    ADD COLUMN IF NOT EXISTS counterparty_id INTEGER DEFAULT NULL;
+
+-- Переводы сохраняются для учета у обоих участников, поэтому второго участника перевода нельзя удалить из БД
+ALTER TABLE operations
+    DROP CONSTRAINT IF EXISTS must_refs_counterparty,
+    ADD CONSTRAINT must_refs_counterparty FOREIGN KEY (counterparty_id) REFERENCES users (id) ON DELETE RESTRICT;
+
+ALTER TABLE operations
+    DROP CONSTRAINT IF EXISTS transfer_not_to_self,
+    ADD CONSTRAINT transfer_not_to_self CHECK ( counterparty_id <> user_id );
+
+-- Перевод списывает баллы у отправителя и зачисляет их получателю
+ALTER TABLE operations
+    DROP CONSTRAINT IF EXISTS amount_valid_sign,
+    ADD CONSTRAINT amount_valid_sign CHECK (
+            (amount >= 0 AND op_type IN ('order_accrual', 'promo_accrual'))
+            OR
+            (amount <= 0 AND op_type IN ('order_withdrawal', 'account_closure', 'points_expiration'))
+            OR
+            (amount <> 0 AND op_type = 'transfer')
+        );
+
+ALTER TABLE operations
+    DROP CONSTRAINT IF EXISTS operation_valid_attrs,
+    ADD CONSTRAINT operation_valid_attrs CHECK (
+            (op_type = 'order_accrual' AND order_number IS NOT NULL and promo_id IS NULL AND counterparty_id IS NULL)
+            OR
+            (op_type = 'order_withdrawal' AND order_number IS NOT NULL AND promo_id IS NULL AND counterparty_id IS NULL)
+            OR
+            (op_type = 'promo_accrual' AND order_number IS NULL AND promo_id IS NOT NULL AND counterparty_id IS NULL)
+            OR
+            (op_type IN ('account_closure', 'points_expiration') AND order_number IS NULL AND promo_id IS NULL AND counterparty_id IS NULL)
+            OR
+            (op_type = 'transfer' AND order_number IS NULL AND promo_id IS NULL AND counterparty_id IS NOT NULL)
+        );
+
+-- Транзитный счет переводов: списание у отправителя и зачисление получателю взаимно погашаются
+INSERT INTO ledger_accounts (code)
+VALUES ('transfer_clearing')
+ON CONFLICT DO NOTHING;
+
+-- Подсчет переводов пользователя за сутки
+CREATE INDEX IF NOT EXISTS transfers_sent_idx ON operations (user_id, created_at)
+    INCLUDE (amount)
+    WHERE op_type = 'transfer' AND amount < 0;
+
+--------------------------------------------------------------------------------
+-- +goose Down
+--------------------------------------------------------------------------------
+DROP INDEX IF EXISTS transfers_sent_idx;
+
+-- Значение transfer типа operation_type не удаляется, т.к. PostgreSQL не поддерживает удаление значений перечислений.
+-- Проводки неизменяемы, поэтому если переводы уже выполнялись, восстановление ограничений завершится ошибкой
+-- до удаления столбца counterparty_id.
+ALTER TABLE operations
+    DROP CONSTRAINT IF EXISTS amount_valid_sign,
+    ADD CONSTRAINT amount_valid_sign CHECK (
+            (amount >= 0 AND op_type IN ('order_accrual', 'promo_accrual'))
+            OR
+            (amount <= 0 AND op_type IN ('order_withdrawal', 'account_closure', 'points_expiration'))
+        );
+
+ALTER TABLE operations
+    DROP CONSTRAINT IF EXISTS operation_valid_attrs,
+    ADD CONSTRAINT operation_valid_attrs CHECK (
+            (op_type = 'order_accrual' AND order_number IS NOT NULL and promo_id IS NULL)
+            OR
+            (op_type = 'order_withdrawal' AND order_number IS NOT NULL AND promo_id IS NULL)
+            OR
+            (op_type = 'promo_accrual' AND order_number IS NULL AND promo_id IS NOT NULL)
+            OR
+            (op_type IN ('account_closure', 'points_expiration') AND order_number IS NULL AND promo_id IS NULL)
+        );
+
+ALTER TABLE operations
+    DROP CONSTRAINT IF EXISTS transfer_not_to_self,
+    DROP CONSTRAINT IF EXISTS must_refs_counterparty,
+    DROP COLUMN IF EXISTS counterparty_id;
+
+DELETE FROM ledger_accounts WHERE code = 'transfer_clearing';
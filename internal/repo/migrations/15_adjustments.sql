@@ -0,0 +1,88 @@
+-- ALTER TYPE ... ADD VALUE нельзя использовать в той же транзакции, в которой добавлено значение
+-- +goose NO TRANSACTION
+
+--------------------------------------------------------------------------------
+-- +goose Up
+--------------------------------------------------------------------------------
+
+-- Корректировка баланса сотрудником
+ALTER TYPE operation_type ADD VALUE IF NOT EXISTS 'adjustment';
+
+-- Корректировка может как зачислять, так и списывать баллы
+ALTER TABLE operations
+    DROP CONSTRAINT IF EXISTS amount_valid_sign,
+    ADD CONSTRAINT amount_valid_sign CHECK (
+            (amount >= 0 AND op_type IN ('order_accrual', 'promo_accrual'))
+            OR
+            (amount <= 0 AND op_type IN ('order_withdrawal', 'account_closure', 'points_expiration'))
+            OR
+            (amount <> 0 AND op_type IN ('transfer', 'adjustment'))
+        );
+
+ALTER TABLE operations
+    DROP CONSTRAINT IF EXISTS operation_valid_attrs,
+    ADD CONSTRAINT operation_valid_attrs CHECK (
+            (op_type = 'order_accrual' AND order_number IS NOT NULL and promo_id IS NULL AND counterparty_id IS NULL)
+            OR
+            (op_type = 'order_withdrawal' AND order_number IS NOT NULL AND promo_id IS NULL AND counterparty_id IS NULL)
+            OR
+            (op_type = 'promo_accrual' AND order_number IS NULL AND promo_id IS NOT NULL AND counterparty_id IS NULL)
+            OR
+            (op_type IN ('account_closure', 'points_expiration', 'adjustment') AND order_number IS NULL AND promo_id IS NULL AND counterparty_id IS NULL)
+            OR
+            (op_type = 'transfer' AND order_number IS NULL AND promo_id IS NULL AND counterparty_id IS NOT NULL)
+        );
+
+-- Системный счет корректировок
+INSERT INTO ledger_accounts (code)
+VALUES ('adjustment_fund')
+ON CONFLICT DO NOTHING;
+
+-- Журнал корректировок: кто, по какой причине и по какому обращению скорректировал баланс
+CREATE TABLE IF NOT EXISTS operation_adjustments
+(
+    operation_id INTEGER PRIMARY KEY,
+    staff_id     INTEGER     NOT NULL,
+    reason       TEXT        NOT NULL,
+    ticket       VARCHAR(64) NOT NULL,
+    created_at   TIMESTAMP   NOT NULL DEFAULT now(),
+    CONSTRAINT adjustment_refs_operation FOREIGN KEY (operation_id) REFERENCES operations (id) ON DELETE RESTRICT,
+    CONSTRAINT adjustment_refs_staff FOREIGN KEY (staff_id) REFERENCES users (id) ON DELETE RESTRICT,
+    CONSTRAINT adjustment_valid_audit CHECK ( reason <> '' AND ticket <> '' )
+);
+
+CREATE INDEX IF NOT EXISTS operation_adjustments_staff_idx ON operation_adjustments (staff_id, created_at);
+
+--------------------------------------------------------------------------------
+-- +goose Down
+--------------------------------------------------------------------------------
+DROP INDEX IF EXISTS operation_adjustments_staff_idx;
+
+-- Значение adjustment типа operation_type не удаляется, т.к. PostgreSQL не поддерживает удаление значений перечислений.
+-- Проводки неизменяемы, поэтому если корректировки уже выполнялись, восстановление ограничений завершится ошибкой.
+ALTER TABLE operations
+    DROP CONSTRAINT IF EXISTS amount_valid_sign,
+    ADD CONSTRAINT amount_valid_sign CHECK (
+            (amount >= 0 AND op_type IN ('order_accrual', 'promo_accrual'))
+            OR
+            (amount <= 0 AND op_type IN ('order_withdrawal', 'account_closure', 'points_expiration'))
+            OR
+            (amount <> 0 AND op_type = 'transfer')
+        );
+
+ALTER TABLE operations
+    DROP CONSTRAINT IF EXISTS operation_valid_attrs,
+    ADD CONSTRAINT operation_valid_attrs CHECK (
+            (op_type = 'order_accrual' AND order_number IS NOT NULL and promo_id IS NULL AND counterparty_id IS NULL)
+            OR
+            (op_type = 'order_withdrawal' AND order_number IS NOT NULL AND promo_id IS NULL AND counterparty_id IS NULL)
+            OR
+            (op_type = 'promo_accrual' AND order_number IS NULL AND promo_id IS NOT NULL AND counterparty_id IS NULL)
+            OR
+            (op_type IN ('account_closure', 'points_expiration') AND order_number IS NULL AND promo_id IS NULL AND counterparty_id IS NULL)
+            OR
+            (op_type = 'transfer' AND order_number IS NULL AND promo_id IS NULL AND counterparty_id IS NOT NULL)
+        );
+
+DROP TABLE IF EXISTS operation_adjustments;
+DELETE FROM ledger_accounts WHERE code = 'adjustment_fund';
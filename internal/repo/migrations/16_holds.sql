@@ -0,0 +1,49 @@
+--------------------------------------------------------------------------------
+-- +goose Up
+--------------------------------------------------------------------------------
+
+-- Время окончания удержания начисленных баллов.
+-- Пока удержание не снято, баллы учитываются в балансе, но не могут быть потрачены
+ALTER TABLE operations
+    ADD COLUMN IF NOT EXISTS hold_until TIMESTAMP DEFAULT NULL;
+
+ALTER TABLE operations
+    DROP CONSTRAINT IF EXISTS hold_only_for_accruals,
+    ADD CONSTRAINT hold_only_for_accruals CHECK (
+            hold_until IS NULL OR (op_type IN ('order_accrual', 'promo_accrual') AND amount > 0)
+        );
+
+-- Поиск начислений, удержание которых истекло
+CREATE INDEX IF NOT EXISTS operations_hold_idx ON operations (hold_until, user_id)
+    WHERE hold_until IS NOT NULL;
+
+-- Сумма удержанных баллов пользователя, входящая в баланс
+ALTER TABLE users
+    ADD COLUMN IF NOT EXISTS pending DECIMAL(16, 4) NOT NULL DEFAULT 0;
+
+-- Потратить можно только доступные баллы: баланс за вычетом удержанных
+ALTER TABLE users
+    DROP CONSTRAINT IF EXISTS balance_not_negative,
+    ADD CONSTRAINT balance_not_negative CHECK ( balance - pending >= 0 );
+
+ALTER TABLE users
+    DROP CONSTRAINT IF EXISTS pending_not_negative,
+    ADD CONSTRAINT pending_not_negative CHECK ( pending >= 0 );
+
+--------------------------------------------------------------------------------
+-- +goose Down
+--------------------------------------------------------------------------------
+DROP INDEX IF EXISTS operations_hold_idx;
+
+ALTER TABLE operations
+    DROP CONSTRAINT IF EXISTS hold_only_for_accruals,
+    DROP COLUMN IF EXISTS hold_until;
+
+-- Удержанные баллы становятся доступными для списания
+ALTER TABLE users
+    DROP CONSTRAINT IF EXISTS pending_not_negative,
+    DROP CONSTRAINT IF EXISTS balance_not_negative,
+    DROP COLUMN IF EXISTS pending;
+
+ALTER TABLE users
+    ADD CONSTRAINT balance_not_negative CHECK ( balance >= 0 );
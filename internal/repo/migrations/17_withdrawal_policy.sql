@@ -0,0 +1,13 @@
+--------------------------------------------------------------------------------
+-- +goose Up
+--------------------------------------------------------------------------------
+
+-- Подсчет списаний пользователя за сутки и за месяц
+CREATE INDEX IF NOT EXISTS withdrawals_sum_idx ON operations (user_id, created_at)
+    INCLUDE (amount)
+    WHERE op_type = 'order_withdrawal' AND status NOT IN ('INVALID', 'CANCELED');
+
+--------------------------------------------------------------------------------
+-- +goose Down
+--------------------------------------------------------------------------------
+DROP INDEX IF EXISTS withdrawals_sum_idx;
@@ -0,0 +1,25 @@
+--------------------------------------------------------------------------------
+-- +goose Up
+--------------------------------------------------------------------------------
+
+-- История изменения статуса и суммы операций: кто и когда перевел операцию из одного статуса в другой
+CREATE TABLE IF NOT EXISTS operation_status_history
+(
+    id           INTEGER PRIMARY KEY GENERATED ALWAYS AS IDENTITY,
+    operation_id INTEGER          NOT NULL,
+    old_status   operation_status NOT NULL,
+    new_status   operation_status NOT NULL,
+    amount       DECIMAL(16, 4)   NOT NULL,
+    source       VARCHAR(32)      NOT NULL,
+    created_at   TIMESTAMP        NOT NULL DEFAULT now(),
+    CONSTRAINT status_history_refs_operation FOREIGN KEY (operation_id) REFERENCES operations (id) ON DELETE RESTRICT,
+    CONSTRAINT status_history_valid_source CHECK ( source <> '' )
+);
+
+CREATE INDEX IF NOT EXISTS operation_status_history_operation_idx ON operation_status_history (operation_id, id);
+
+--------------------------------------------------------------------------------
+-- +goose Down
+--------------------------------------------------------------------------------
+DROP INDEX IF EXISTS operation_status_history_operation_idx;
+DROP TABLE IF EXISTS operation_status_history;
@@ -0,0 +1,28 @@
+--------------------------------------------------------------------------------
+-- +goose Up
+--------------------------------------------------------------------------------
+
+-- Ключи идемпотентности запросов и записанные ответы на запросы.
+-- Пока ответ не записан (status_code IS NULL), запрос с тем же ключом выполняется
+CREATE TABLE IF NOT EXISTS idempotency_keys
+(
+    user_id      INTEGER      NOT NULL,
+    key          VARCHAR(255) NOT NULL,
+    fingerprint  VARCHAR(64)  NOT NULL,
+    status_code  INTEGER               DEFAULT NULL,
+    content_type VARCHAR(255) NOT NULL DEFAULT '',
+    body         BYTEA        NOT NULL DEFAULT '',
+    created_at   TIMESTAMP    NOT NULL DEFAULT now(),
+    expires_at   TIMESTAMP    NOT NULL,
+    PRIMARY KEY (user_id, key),
+    CONSTRAINT idempotency_key_refs_user FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+);
+
+-- Удаление истекших ключей
+CREATE INDEX IF NOT EXISTS idempotency_keys_expires_idx ON idempotency_keys (expires_at);
+
+--------------------------------------------------------------------------------
+-- +goose Down
+--------------------------------------------------------------------------------
+DROP INDEX IF EXISTS idempotency_keys_expires_idx;
+DROP TABLE IF EXISTS idempotency_keys;
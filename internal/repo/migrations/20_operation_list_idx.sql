@@ -0,0 +1,16 @@
+--------------------------------------------------------------------------------
+-- +goose Up
+--------------------------------------------------------------------------------
+
+-- Постраничная выборка операций пользователя заданного типа по убыванию времени создания
+CREATE INDEX IF NOT EXISTS operations_list_idx ON operations (user_id, op_type, created_at DESC, id DESC);
+
+-- Постраничная выборка истории баланса пользователя по убыванию времени обработки
+CREATE INDEX IF NOT EXISTS balance_history_idx ON operations (user_id, updated_at DESC, id DESC)
+    WHERE (status = 'PROCESSED' AND amount >= 0) OR (status NOT IN ('INVALID', 'CANCELED') AND amount < 0);
+
+--------------------------------------------------------------------------------
+-- +goose Down
+--------------------------------------------------------------------------------
+DROP INDEX IF EXISTS balance_history_idx;
+DROP INDEX IF EXISTS operations_list_idx;
@@ -0,0 +1,14 @@
+--------------------------------------------------------------------------------
+-- +goose Up
+--------------------------------------------------------------------------------
+
+-- Время последнего опроса внешней системы, обрабатывающей операцию.
+-- Изменяется только при опросе, в отличие от updated_at, который изменяется при любом обновлении операции
+ALTER TABLE operations
+    ADD COLUMN IF NOT EXISTS last_checked_at TIMESTAMP DEFAULT NULL;
+
+--------------------------------------------------------------------------------
+-- +goose Down
+--------------------------------------------------------------------------------
+ALTER TABLE operations
+    DROP COLUMN IF EXISTS last_checked_at;
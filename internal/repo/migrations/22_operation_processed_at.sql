@@ -0,0 +1,33 @@
+--------------------------------------------------------------------------------
+-- +goose Up
+--------------------------------------------------------------------------------
+
+-- Время обработки операции - перевода в статус PROCESSED.
+-- Не изменяется при последующих обновлениях операции, в отличие от updated_at,
+-- поэтому от него отсчитывается срок жизни начисленных баллов
+ALTER TABLE operations
+    ADD COLUMN IF NOT EXISTS processed_at TIMESTAMP DEFAULT NULL;
+
+-- Время обработки уже обработанных операций берется из истории статусов, а без истории - из updated_at
+UPDATE operations o
+SET processed_at = coalesce(
+        (SELECT min(h.created_at)
+         FROM operation_status_history h
+         WHERE h.operation_id = o.id AND h.new_status = 'PROCESSED'),
+        o.updated_at)
+WHERE o.status = 'PROCESSED';
+
+-- Поиск начислений, срок жизни которых истек
+DROP INDEX IF EXISTS expiring_accruals_idx;
+CREATE INDEX IF NOT EXISTS expiring_accruals_idx ON operations (processed_at, user_id)
+    WHERE status = 'PROCESSED' AND amount > 0;
+
+--------------------------------------------------------------------------------
+-- +goose Down
+--------------------------------------------------------------------------------
+DROP INDEX IF EXISTS expiring_accruals_idx;
+CREATE INDEX IF NOT EXISTS expiring_accruals_idx ON operations (updated_at, user_id)
+    WHERE status = 'PROCESSED' AND amount > 0;
+
+ALTER TABLE operations
+    DROP COLUMN IF EXISTS processed_at;
@@ -5,6 +5,8 @@ import (
 	"database/sql"
 	"errors"
 
+	"github.com/shopspring/decimal"
+
 	"gophermart-loyalty/internal/errs"
 	"gophermart-loyalty/internal/models"
 )
@@ -17,16 +19,22 @@ import (
 //    $5 - description
 //    $6 - order_number
 //    $7 - promo_id
-// Возвращает id, created_at, updated_at операции.
+//    $8 - counterparty_id
+//    $9 - срок удержания начисленных баллов в секундах, если не положительный - баллы не удерживаются
+// Возвращает id, created_at, updated_at, hold_until, processed_at операции.
 // ВАЖНО: может вызываться только внутри транзакции и только после вызова PGXRepo.userLockTx.
-// После вызова необходимо обновить баланс пользователя при помощи PGXRepo.userUpdateBalanceTx.
+// После вызова необходимо записать проводку при помощи PGXRepo.ledgerPostTx.
 var stmtOperationCreate = registerStatement(`
-	INSERT INTO operations (user_id, op_type, status, amount, description, order_number, promo_id)
-	VALUES ($1, $2, $3, $4, $5, $6, $7)
-	RETURNING id, created_at, updated_at
+	INSERT INTO operations (user_id, op_type, status, amount, description, order_number, promo_id, counterparty_id, hold_until,
+		processed_at)
+	VALUES ($1, $2, $3, $4, $5, $6, $7, $8, CASE WHEN $9::float8 > 0 THEN now() + make_interval(secs => $9) END,
+		CASE WHEN $3::operation_status = 'PROCESSED' THEN now() END)
+	RETURNING id, created_at, updated_at, hold_until, processed_at
 `)
 
-// OperationCreate - создает операцию и обновляет баланс пользователя.
+// OperationCreate - создает операцию, записывает проводку и обновляет баланс пользователя.
+// Если учетная запись пользователя заблокирована, списание баллов не создается
+// и возвращается errs.ErrOperationUserBlocked.
 func (r *PGXRepo) OperationCreate(ctx context.Context, op *models.Operation) error {
 
 	tx, err := r.db.Begin()
@@ -37,23 +45,21 @@ func (r *PGXRepo) OperationCreate(ctx context.Context, op *models.Operation) err
 	defer tx.Rollback()
 
 	// Блокируем запись пользователя для обновления
-	if err = r.userLockTx(ctx, tx, op.UserID); err != nil {
+	status, err := r.userLockTx(ctx, tx, op.UserID)
+	if err != nil {
 		if errors.Is(err, errs.ErrNotFound) {
 			err = errs.ErrOperationUserNotExists
 		}
 		return err
 	}
 
-	// Создаем операцию
-	err = tx.Stmt(r.statements[stmtOperationCreate]).
-		QueryRowContext(ctx, op.UserID, op.Type, op.Status, op.Amount, op.Description, op.OrderNumber, op.PromoID).
-		Scan(&op.ID, &op.CreatedAt, &op.UpdatedAt)
-	if err != nil {
-		return r.handleError(ctx, err)
+	// Заблокированный пользователь не может списывать баллы
+	if status == models.UserBlocked && op.Type == models.OrderWithdrawal {
+		return errs.ErrOperationUserBlocked
 	}
 
-	// Обновляем баланс пользователя
-	if err = r.userUpdateBalanceTx(ctx, tx, op.UserID); err != nil {
+	// Создаем операцию, записываем проводку и обновляем баланс пользователя
+	if err = r.operationCreateTx(ctx, tx, op); err != nil {
 		return err
 	}
 
@@ -64,6 +70,18 @@ func (r *PGXRepo) OperationCreate(ctx context.Context, op *models.Operation) err
 	return nil
 }
 
+// operationCreateTx - создает операцию, записывает проводку и обновляет баланс пользователя.
+// ВАЖНО: может вызываться только внутри транзакции и только после вызова PGXRepo.userLockTx.
+func (r *PGXRepo) operationCreateTx(ctx context.Context, tx *sql.Tx, op *models.Operation) error {
+	err := tx.Stmt(r.statements[stmtOperationCreate]).
+		QueryRowContext(ctx, op.UserID, op.Type, op.Status, op.Amount, op.Description, op.OrderNumber, op.PromoID, op.CounterpartyID, op.HoldPeriod.Seconds()).
+		Scan(&op.ID, &op.CreatedAt, &op.UpdatedAt, &op.HoldUntil, &op.ProcessedAt)
+	if err != nil {
+		return r.handleError(ctx, err)
+	}
+	return r.ledgerPostTx(ctx, tx, op, decimal.Zero)
+}
+
 type UpdateFunc func(ctx context.Context, operation *models.Operation) error
 
 // stmtOperationLockFurther - ищет операцию самую старую операцию заданного типа,
@@ -72,7 +90,7 @@ type UpdateFunc func(ctx context.Context, operation *models.Operation) error
 // Возвращает id, user_id, op_type, status, amount, description, order_number, promo_id операции.
 // ВАЖНО: может вызываться только внутри транзакции.
 var stmtOperationLockFurther = registerStatement(`
-		SELECT id, user_id, op_type, status, amount, description, order_number, promo_id, created_at, updated_at
+		SELECT id, user_id, op_type, status, amount, description, order_number, promo_id, counterparty_id, created_at, updated_at
 		FROM operations 
 		WHERE status IN ('NEW', 'PROCESSING') AND op_type = $1
 		ORDER BY updated_at
@@ -80,20 +98,41 @@ var stmtOperationLockFurther = registerStatement(`
 		LIMIT 1
 `)
 
-// stmtOperationUpdate - обновляет status и amount операции.
+// stmtOperationUpdate - обновляет status и amount операции и устанавливает удержание начисленных баллов.
+// При переводе в статус PROCESSED записывает время обработки операции.
+//    $1 - id операции
+//    $2 - status
+//    $3 - amount
+//    $4 - срок удержания начисленных баллов в секундах, если не положительный - удержание не изменяется
+// Возвращает hold_until, processed_at операции.
 // ВАЖНО: может вызываться только внутри транзакции и только после вызова PGXRepo.userLockTx.
-// После вызова необходимо обновить баланс пользователя при помощи PGXRepo.userUpdateBalanceTx.
+// После вызова необходимо записать проводку при помощи PGXRepo.ledgerPostTx.
 var stmtOperationUpdate = registerStatement(`
 	UPDATE operations
-	SET status = $2, amount = $3, updated_at = now()
+	SET status = $2, amount = $3, updated_at = now(),
+		hold_until = CASE WHEN $4::float8 > 0 THEN now() + make_interval(secs => $4) ELSE hold_until END,
+		processed_at = CASE WHEN $2::operation_status = 'PROCESSED' THEN coalesce(processed_at, now()) END
 	WHERE id = $1
-	RETURNING id
+	RETURNING hold_until, processed_at
+`)
+
+// stmtOperationChecked - записывает время опроса внешней системы, обрабатывающей операцию.
+//    $1 - id операции
+// Возвращает last_checked_at операции.
+// ВАЖНО: может вызываться только внутри транзакции и только после блокировки операции.
+var stmtOperationChecked = registerStatement(`
+	UPDATE operations
+	SET last_checked_at = now()
+	WHERE id = $1
+	RETURNING last_checked_at
 `)
 
 // OperationUpdateFurther - берет самую старую операцию заданного типа,
-// которая находится не в конечном статусе, вызывает для нее коллбэк updateOp, обновляет операцию
-// и обновляет баланс пользователя.
-func (r *PGXRepo) OperationUpdateFurther(ctx context.Context, opType models.OperationType, updateFunc UpdateFunc) (*models.Operation, error) {
+// которая находится не в конечном статусе, вызывает для нее коллбэк updateOp, обновляет операцию,
+// записывает изменение статуса в историю от имени source, записывает проводку и обновляет баланс пользователя.
+// Коллбэк опрашивает внешнюю систему, обрабатывающую операцию, поэтому время опроса записывается в last_checked_at.
+// Если коллбэк переводит операцию в недопустимый статус, возвращает errs.ErrOperationStatusTransitInvalid.
+func (r *PGXRepo) OperationUpdateFurther(ctx context.Context, opType models.OperationType, source string, updateFunc UpdateFunc) (*models.Operation, error) {
 
 	tx, err := r.db.Begin()
 	if err != nil {
@@ -115,6 +154,7 @@ func (r *PGXRepo) OperationUpdateFurther(ctx context.Context, opType models.Oper
 			&op.Description,
 			&op.OrderNumber,
 			&op.PromoID,
+			&op.CounterpartyID,
 			&op.CreatedAt,
 			&op.UpdatedAt,
 		)
@@ -122,26 +162,141 @@ func (r *PGXRepo) OperationUpdateFurther(ctx context.Context, opType models.Oper
 		return nil, r.handleError(ctx, err)
 	}
 
+	// Запоминаем операцию до обновления
+	prev := *op
+
 	// Вызываем коллбэк для обновления данных операции
 	if err = updateFunc(ctx, op); err != nil {
 		return nil, err
 	}
 
-	// Блокируем запись пользователя для обновления
-	if err = r.userLockTx(ctx, tx, op.UserID); err != nil {
+	if err = r.operationUpdateTx(ctx, tx, op, &prev, source); err != nil {
 		return nil, err
 	}
 
+	err = tx.Stmt(r.statements[stmtOperationChecked]).
+		QueryRowContext(ctx, op.ID).
+		Scan(&op.LastCheckedAt)
+	if err != nil {
+		return nil, r.handleError(ctx, err)
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, r.handleError(ctx, err)
+	}
+
+	return op, nil
+}
+
+// stmtOperationStatusHistoryAdd - записывает изменение статуса операции в историю.
+//    $1 - id операции
+//    $2 - прежний статус
+//    $3 - новый статус
+//    $4 - сумма операции после изменения
+//    $5 - источник изменения
+// ВАЖНО: может вызываться только внутри транзакции обновления операции.
+var stmtOperationStatusHistoryAdd = registerStatement(`
+	INSERT INTO operation_status_history (operation_id, old_status, new_status, amount, source)
+	VALUES ($1, $2, $3, $4, $5)
+`)
+
+// operationUpdateTx - проверяет переход операции op из статуса prev в новый статус, блокирует пользователя,
+// обновляет операцию, записывает изменение статуса или суммы в историю от имени source,
+// записывает проводку и обновляет баланс пользователя.
+// Если переход статуса недопустим, возвращает errs.ErrOperationStatusTransitInvalid.
+// ВАЖНО: может вызываться только внутри транзакции и только после блокировки операции.
+func (r *PGXRepo) operationUpdateTx(ctx context.Context, tx *sql.Tx, op, prev *models.Operation, source string) error {
+	if !prev.Status.CanTransit(op.Status) {
+		return errs.ErrOperationStatusTransitInvalid
+	}
+
+	// Блокируем запись пользователя для обновления
+	if _, err := r.userLockTx(ctx, tx, op.UserID); err != nil {
+		return err
+	}
+
 	// Обновляем операцию
-	err = tx.Stmt(r.statements[stmtOperationUpdate]).
-		QueryRowContext(ctx, op.ID, op.Status, op.Amount).
-		Scan(&sql.NullInt64{})
+	err := tx.Stmt(r.statements[stmtOperationUpdate]).
+		QueryRowContext(ctx, op.ID, op.Status, op.Amount, op.HoldPeriod.Seconds()).
+		Scan(&op.HoldUntil, &op.ProcessedAt)
+	if err != nil {
+		return r.handleError(ctx, err)
+	}
+
+	// Повторные опросы интеграций без изменения операции в историю не записываются
+	if op.Status != prev.Status || !op.Amount.Equal(prev.Amount) {
+		_, err = tx.Stmt(r.statements[stmtOperationStatusHistoryAdd]).
+			ExecContext(ctx, op.ID, prev.Status, op.Status, op.Amount, source)
+		if err != nil {
+			return r.handleError(ctx, err)
+		}
+	}
+
+	// Записываем проводку и обновляем баланс пользователя
+	return r.ledgerPostTx(ctx, tx, op, prev.BalanceEffect())
+}
+
+// stmtOperationLockByOrder - ищет операцию пользователя заданного типа по номеру заказа
+// и блокирует ее для обновления другими транзакциями.
+// Если операция уже заблокирована, ожидает завершения блокирующей транзакции.
+//     $1 - user_id
+//     $2 - op_type
+//     $3 - order_number
+// Возвращает id, user_id, op_type, status, amount, description, order_number, promo_id операции.
+// ВАЖНО: может вызываться только внутри транзакции.
+var stmtOperationLockByOrder = registerStatement(`
+	SELECT id, user_id, op_type, status, amount, description, order_number, promo_id, counterparty_id, created_at, updated_at
+	FROM operations
+	WHERE user_id = $1 AND op_type = $2 AND order_number = $3
+	FOR UPDATE
+`)
+
+// OperationUpdateByOrder - блокирует операцию пользователя заданного типа по номеру заказа,
+// вызывает для нее коллбэк updateFunc, обновляет операцию, записывает изменение статуса в историю от имени source,
+// записывает проводку и обновляет баланс пользователя.
+// Если операция не найдена, возвращает errs.ErrNotFound.
+// Если коллбэк переводит операцию в недопустимый статус, возвращает errs.ErrOperationStatusTransitInvalid.
+// Операция блокируется раньше пользователя, как и в OperationUpdateFurther, поэтому параллельные обновления
+// не взаимоблокируются: коллбэк всегда получает статус, зафиксированный последней завершенной транзакцией.
+func (r *PGXRepo) OperationUpdateByOrder(ctx context.Context, userID uint64, opType models.OperationType, orderNumber string, source string, updateFunc UpdateFunc) (*models.Operation, error) {
+
+	tx, err := r.db.Begin()
 	if err != nil {
 		return nil, r.handleError(ctx, err)
 	}
+	//goland:noinspection ALL
+	defer tx.Rollback()
 
-	// Обновляем баланс пользователя
-	if err = r.userUpdateBalanceTx(ctx, tx, op.UserID); err != nil {
+	// Находим операцию для обновления и блокируем ее
+	op := &models.Operation{}
+	err = tx.Stmt(r.statements[stmtOperationLockByOrder]).
+		QueryRowContext(ctx, userID, opType, orderNumber).
+		Scan(
+			&op.ID,
+			&op.UserID,
+			&op.Type,
+			&op.Status,
+			&op.Amount,
+			&op.Description,
+			&op.OrderNumber,
+			&op.PromoID,
+			&op.CounterpartyID,
+			&op.CreatedAt,
+			&op.UpdatedAt,
+		)
+	if err != nil {
+		return nil, r.handleError(ctx, err)
+	}
+
+	// Запоминаем операцию до обновления
+	prev := *op
+
+	// Вызываем коллбэк для обновления данных операции
+	if err = updateFunc(ctx, op); err != nil {
+		return nil, err
+	}
+
+	if err = r.operationUpdateTx(ctx, tx, op, &prev, source); err != nil {
 		return nil, err
 	}
 
@@ -152,21 +307,127 @@ func (r *PGXRepo) OperationUpdateFurther(ctx context.Context, opType models.Oper
 	return op, nil
 }
 
-// stmtOperationGetByType - возвращает список операций пользователя заданного типа.
+// stmtOperationGetByOrder - возвращает операцию пользователя заданного типа по номеру заказа.
+//    $1 - user_id
+//    $2 - op_type
+//    $3 - order_number
+// Возвращает id, user_id, op_type, status, amount, description,
+// order_number, promo_id, counterparty_id, created_at, updated_at, hold_until, last_checked_at операции.
+var stmtOperationGetByOrder = registerStatement(`
+	SELECT id, user_id, op_type, status, amount, description, order_number, promo_id, counterparty_id, created_at, updated_at,
+		hold_until, last_checked_at
+	FROM operations
+	WHERE user_id = $1 AND op_type = $2 AND order_number = $3
+`)
+
+// OperationGetByOrder - возвращает операцию пользователя заданного типа по номеру заказа.
+// Если операция не найдена или принадлежит другому пользователю, возвращает errs.ErrNotFound.
+func (r *PGXRepo) OperationGetByOrder(ctx context.Context, userID uint64, opType models.OperationType, orderNumber string) (*models.Operation, error) {
+	op := &models.Operation{}
+	err := r.statements[stmtOperationGetByOrder].
+		QueryRowContext(ctx, userID, opType, orderNumber).
+		Scan(
+			&op.ID,
+			&op.UserID,
+			&op.Type,
+			&op.Status,
+			&op.Amount,
+			&op.Description,
+			&op.OrderNumber,
+			&op.PromoID,
+			&op.CounterpartyID,
+			&op.CreatedAt,
+			&op.UpdatedAt,
+			&op.HoldUntil,
+			&op.LastCheckedAt,
+		)
+	if err != nil {
+		return nil, r.handleError(ctx, err)
+	}
+	return op, nil
+}
+
+// stmtOperationIDByOrder - возвращает id операции пользователя заданного типа по номеру заказа.
+//    $1 - user_id
+//    $2 - op_type
+//    $3 - order_number
+var stmtOperationIDByOrder = registerStatement(`
+	SELECT id
+	FROM operations
+	WHERE user_id = $1 AND op_type = $2 AND order_number = $3
+`)
+
+// stmtOperationStatusHistoryGet - возвращает историю изменения статуса операции в порядке изменений.
+//    $1 - id операции
+// Возвращает id, operation_id, old_status, new_status, amount, source, created_at записей истории.
+var stmtOperationStatusHistoryGet = registerStatement(`
+	SELECT id, operation_id, old_status, new_status, amount, source, created_at
+	FROM operation_status_history
+	WHERE operation_id = $1
+	ORDER BY id
+`)
+
+// OperationStatusHistoryGetByOrder - возвращает историю изменения статуса операции пользователя
+// заданного типа по номеру заказа. Если операция не найдена, возвращает errs.ErrNotFound.
+func (r *PGXRepo) OperationStatusHistoryGetByOrder(ctx context.Context, userID uint64, opType models.OperationType, orderNumber string) ([]*models.OperationStatusChange, error) {
+	var opID uint64
+	err := r.statements[stmtOperationIDByOrder].
+		QueryRowContext(ctx, userID, opType, orderNumber).
+		Scan(&opID)
+	if err != nil {
+		return nil, r.handleError(ctx, err)
+	}
+
+	rows, err := r.statements[stmtOperationStatusHistoryGet].QueryContext(ctx, opID)
+	if err != nil {
+		return nil, r.handleError(ctx, err)
+	}
+	//goland:noinspection GoUnhandledErrorResult
+	defer rows.Close()
+
+	var history []*models.OperationStatusChange
+	for rows.Next() {
+		c := &models.OperationStatusChange{}
+		err = rows.Scan(&c.ID, &c.OperationID, &c.OldStatus, &c.NewStatus, &c.Amount, &c.Source, &c.CreatedAt)
+		if err != nil {
+			return nil, r.handleError(ctx, err)
+		}
+		history = append(history, c)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, r.handleError(ctx, err)
+	}
+	return history, nil
+}
+
+// stmtOperationGetByType - возвращает список операций пользователя заданного типа
+// по убыванию времени создания и id.
 //    $1 - user_id
 //    $2 - op_type
+//    $3 - status или NULL
+//    $4 - начало периода по created_at включительно или NULL
+//    $5 - конец периода по created_at, не включая, или NULL
+//    $6, $7 - created_at и id операции, после которой начинается выборка, или NULL
+//    $8 - максимальное число операций или NULL
 // Возвращает id, user_id, op_type, status, amount, description,
-// order_number, promo_id, created_at, updated_at операции.
+// order_number, promo_id, counterparty_id, created_at, updated_at, processed_at операции.
 var stmtOperationGetByType = registerStatement(`
-	SELECT id, user_id, op_type, status, amount, description, order_number, promo_id, created_at, updated_at
+	SELECT id, user_id, op_type, status, amount, description, order_number, promo_id, counterparty_id, created_at, updated_at,
+		processed_at
 	FROM operations
 	WHERE user_id = $1 AND op_type = $2
-	ORDER BY created_at DESC
+		AND ($3::operation_status IS NULL OR status = $3)
+		AND ($4::timestamp IS NULL OR created_at >= $4)
+		AND ($5::timestamp IS NULL OR created_at < $5)
+		AND ($6::timestamp IS NULL OR (created_at, id) < ($6, $7::integer))
+	ORDER BY created_at DESC, id DESC
+	LIMIT $8
 `)
 
-// OperationGetByType - возвращает список операций пользователя заданного типа.
-func (r *PGXRepo) OperationGetByType(ctx context.Context, userID uint64, t models.OperationType) ([]*models.Operation, error) {
-	rows, err := r.statements[stmtOperationGetByType].QueryContext(ctx, userID, t)
+// OperationGetByType - возвращает список операций пользователя заданного типа, выбранных по параметрам q.
+// Фильтр по типу q.Type не используется.
+func (r *PGXRepo) OperationGetByType(ctx context.Context, userID uint64, t models.OperationType, q *models.OperationQuery) ([]*models.Operation, error) {
+	rows, err := r.statements[stmtOperationGetByType].QueryContext(ctx, operationQueryArgs(userID, t, q)...)
 	if err != nil {
 		return nil, r.handleError(ctx, err)
 	}
@@ -180,6 +441,33 @@ func (r *PGXRepo) OperationGetByType(ctx context.Context, userID uint64, t model
 	return ops, nil
 }
 
+// operationQueryArgs - возвращает параметры запросов списка операций пользователя userID типа t по параметрам q:
+// user_id, op_type, status, начало и конец периода, время и id курсора, максимальное число операций.
+// Незаданные параметры передаются как NULL.
+func operationQueryArgs(userID uint64, t interface{}, q *models.OperationQuery) []interface{} {
+	args := []interface{}{userID, t, nil, nil, nil, nil, 0, nil}
+	if q == nil {
+		return args
+	}
+	if q.Status != nil {
+		args[2] = *q.Status
+	}
+	if q.From != nil {
+		args[3] = q.From.UTC()
+	}
+	if q.To != nil {
+		args[4] = q.To.UTC()
+	}
+	if q.After != nil {
+		args[5] = q.After.Time.UTC()
+		args[6] = q.After.ID
+	}
+	if q.Limit > 0 {
+		args[7] = q.Limit
+	}
+	return args
+}
+
 func (r *PGXRepo) operationScanRows(ctx context.Context, rows *sql.Rows) ([]*models.Operation, error) {
 	var ops []*models.Operation
 	for rows.Next() {
@@ -198,8 +486,10 @@ func (r *PGXRepo) operationScanRows(ctx context.Context, rows *sql.Rows) ([]*mod
 			&op.Description,
 			&op.OrderNumber,
 			&op.PromoID,
+			&op.CounterpartyID,
 			&op.CreatedAt,
 			&op.UpdatedAt,
+			&op.ProcessedAt,
 		); err != nil {
 			return nil, err
 		}
@@ -210,3 +500,378 @@ func (r *PGXRepo) operationScanRows(ctx context.Context, rows *sql.Rows) ([]*mod
 	}
 	return ops, nil
 }
+
+// stmtOperationExpirationCandidates - возвращает неудаленных пользователей с положительным балансом,
+// у которых есть начисления, обработанные раньше заданного срока.
+//    $1 - id пользователя, после которого начинается выборка
+//    $2 - срок в секундах
+//    $3 - максимальное число пользователей
+// Возвращает id пользователей в порядке возрастания.
+var stmtOperationExpirationCandidates = registerStatement(`
+	SELECT id FROM users u
+	WHERE id > $1 AND deleted_at IS NULL AND balance > 0 AND EXISTS(
+		SELECT 1 FROM operations
+		WHERE user_id = u.id AND status = 'PROCESSED' AND amount > 0
+			AND processed_at <= now() - make_interval(secs => $2)
+	)
+	ORDER BY id
+	LIMIT $3
+`)
+
+// OperationExpirationCandidates - возвращает не более limit пользователей с id больше afterID,
+// у которых могут быть баллы, начисленные раньше чем olderThanSeconds секунд назад.
+func (r *PGXRepo) OperationExpirationCandidates(ctx context.Context, afterID uint64, olderThanSeconds int64, limit int) ([]uint64, error) {
+	rows, err := r.statements[stmtOperationExpirationCandidates].QueryContext(ctx, afterID, olderThanSeconds, limit)
+	if err != nil {
+		return nil, r.handleError(ctx, err)
+	}
+	//goland:noinspection GoUnhandledErrorResult
+	defer rows.Close()
+
+	var ids []uint64
+	for rows.Next() {
+		var id uint64
+		if err = rows.Scan(&id); err != nil {
+			return nil, r.handleError(ctx, err)
+		}
+		ids = append(ids, id)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, r.handleError(ctx, err)
+	}
+	return ids, nil
+}
+
+// ExpireFunc - по операциям пользователя, учитывающимся в балансе, возвращает операцию сгорания баллов
+// или nil, если сжигать нечего.
+type ExpireFunc func(ctx context.Context, ops []*models.Operation) (*models.Operation, error)
+
+// OperationExpire - блокирует пользователя, вызывает для его операций, учитывающихся в балансе, коллбэк expireFunc,
+// создает возвращенную коллбэком операцию сгорания баллов, записывает проводку и обновляет баланс пользователя.
+// Возвращает созданную операцию или nil, если коллбэк не вернул операцию.
+// Если пользователь не найден или удален, возвращает errs.ErrNotFound.
+func (r *PGXRepo) OperationExpire(ctx context.Context, userID uint64, expireFunc ExpireFunc) (*models.Operation, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return nil, r.handleError(ctx, err)
+	}
+	//goland:noinspection ALL
+	defer tx.Rollback()
+
+	// Блокируем запись пользователя, чтобы операции не изменились до сгорания баллов
+	if _, err = r.userLockTx(ctx, tx, userID); err != nil {
+		return nil, err
+	}
+
+	ops, err := r.userBalanceHistoryTx(ctx, tx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	op, err := expireFunc(ctx, ops)
+	if err != nil || op == nil {
+		return nil, err
+	}
+
+	// Создаем операцию сгорания баллов, записываем проводку и обновляем баланс пользователя
+	if err = r.operationCreateTx(ctx, tx, op); err != nil {
+		return nil, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, r.handleError(ctx, err)
+	}
+	return op, nil
+}
+
+// stmtOperationHoldCandidates - возвращает пользователей, у которых есть начисления с истекшим удержанием.
+//    $1 - id пользователя, после которого начинается выборка
+//    $2 - максимальное число пользователей
+// Возвращает id пользователей в порядке возрастания.
+var stmtOperationHoldCandidates = registerStatement(`
+	SELECT DISTINCT user_id FROM operations
+	WHERE hold_until <= now() AND user_id > $1
+	ORDER BY user_id
+	LIMIT $2
+`)
+
+// OperationHoldCandidates - возвращает не более limit пользователей с id больше afterID,
+// у которых есть начисления с истекшим удержанием.
+func (r *PGXRepo) OperationHoldCandidates(ctx context.Context, afterID uint64, limit int) ([]uint64, error) {
+	rows, err := r.statements[stmtOperationHoldCandidates].QueryContext(ctx, afterID, limit)
+	if err != nil {
+		return nil, r.handleError(ctx, err)
+	}
+	//goland:noinspection GoUnhandledErrorResult
+	defer rows.Close()
+
+	var ids []uint64
+	for rows.Next() {
+		var id uint64
+		if err = rows.Scan(&id); err != nil {
+			return nil, r.handleError(ctx, err)
+		}
+		ids = append(ids, id)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, r.handleError(ctx, err)
+	}
+	return ids, nil
+}
+
+// stmtOperationHoldRelease - снимает удержание с начислений пользователя.
+//    $1 - id пользователя
+//    $2 - снять удержание со всех начислений, а не только с начислений с истекшим удержанием
+// Возвращает сумму баллов, с которых снято удержание.
+// ВАЖНО: может вызываться только внутри транзакции и только после вызова PGXRepo.userLockTx.
+// После вызова необходимо уменьшить сумму удержанных баллов пользователя при помощи PGXRepo.userUpdateBalanceTx.
+var stmtOperationHoldRelease = registerStatement(`
+	WITH released AS (
+		UPDATE operations
+		SET hold_until = NULL
+		WHERE user_id = $1 AND hold_until IS NOT NULL AND ($2 OR hold_until <= now())
+		RETURNING status, amount
+	)
+	SELECT coalesce(sum(amount) FILTER (WHERE status = 'PROCESSED'), 0) FROM released
+`)
+
+// operationHoldReleaseTx - снимает удержание с начислений пользователя: со всех, если all == true,
+// иначе только с начислений с истекшим удержанием. Удержанные баллы становятся доступными.
+// Возвращает сумму баллов, с которых снято удержание.
+// ВАЖНО: может вызываться только внутри транзакции и только после вызова PGXRepo.userLockTx.
+func (r *PGXRepo) operationHoldReleaseTx(ctx context.Context, tx *sql.Tx, userID uint64, all bool) (decimal.Decimal, error) {
+	var released decimal.Decimal
+	err := tx.Stmt(r.statements[stmtOperationHoldRelease]).
+		QueryRowContext(ctx, userID, all).
+		Scan(&released)
+	if err != nil {
+		return decimal.Zero, r.handleError(ctx, err)
+	}
+	if released.IsZero() {
+		return released, nil
+	}
+	return released, r.userUpdateBalanceTx(ctx, tx, userID, decimal.Zero, decimal.Zero, released.Neg())
+}
+
+// OperationHoldRelease - снимает удержание с начислений пользователя, срок удержания которых истек.
+// Возвращает сумму баллов, которые стали доступны.
+// Если пользователь не найден или удален, возвращает errs.ErrNotFound.
+func (r *PGXRepo) OperationHoldRelease(ctx context.Context, userID uint64) (decimal.Decimal, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return decimal.Zero, r.handleError(ctx, err)
+	}
+	//goland:noinspection ALL
+	defer tx.Rollback()
+
+	// Блокируем запись пользователя для обновления
+	if _, err = r.userLockTx(ctx, tx, userID); err != nil {
+		return decimal.Zero, err
+	}
+
+	released, err := r.operationHoldReleaseTx(ctx, tx, userID, false)
+	if err != nil {
+		return decimal.Zero, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return decimal.Zero, r.handleError(ctx, err)
+	}
+	return released, nil
+}
+
+// stmtOperationWithdrawalsSum - возвращает сумму списаний пользователя за последние сутки и с начала календарного месяца.
+// Отклоненные и отмененные списания не учитываются.
+//    $1 - id пользователя
+// Возвращает сумму списаний за сутки и сумму списаний за месяц.
+// ВАЖНО: может вызываться только внутри транзакции и только после вызова PGXRepo.userLockTx.
+var stmtOperationWithdrawalsSum = registerStatement(`
+	SELECT
+		0 - coalesce(sum(amount) FILTER (WHERE created_at > now() - interval '1 day'), 0),
+		0 - coalesce(sum(amount) FILTER (WHERE created_at >= date_trunc('month', now())), 0)
+	FROM operations
+	WHERE user_id = $1 AND op_type = 'order_withdrawal' AND status NOT IN ('INVALID', 'CANCELED')
+		AND created_at >= least(now() - interval '1 day', date_trunc('month', now()))
+`)
+
+// WithdrawalCheckFunc - проверяет списание по сумме списаний пользователя за последние сутки daily
+// и с начала календарного месяца monthly. Если списание невозможно, возвращает ошибку.
+type WithdrawalCheckFunc func(ctx context.Context, daily, monthly decimal.Decimal) error
+
+// OperationWithdraw - создает операцию списания op, записывает проводку и обновляет баланс пользователя.
+// Перед созданием операции под блокировкой пользователя вызывает коллбэк checkFunc для проверки
+// суммы списаний пользователя за сутки и за месяц.
+// Если пользователь не найден или удален, возвращает errs.ErrOperationUserNotExists.
+// Если учетная запись пользователя заблокирована, возвращает errs.ErrOperationUserBlocked.
+func (r *PGXRepo) OperationWithdraw(ctx context.Context, op *models.Operation, checkFunc WithdrawalCheckFunc) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return r.handleError(ctx, err)
+	}
+	//goland:noinspection ALL
+	defer tx.Rollback()
+
+	// Блокируем запись пользователя, чтобы параллельные списания не превысили ограничения
+	status, err := r.userLockTx(ctx, tx, op.UserID)
+	if err != nil {
+		if errors.Is(err, errs.ErrNotFound) {
+			err = errs.ErrOperationUserNotExists
+		}
+		return err
+	}
+
+	// Заблокированный пользователь не может списывать баллы
+	if status == models.UserBlocked {
+		return errs.ErrOperationUserBlocked
+	}
+
+	var daily, monthly decimal.Decimal
+	err = tx.Stmt(r.statements[stmtOperationWithdrawalsSum]).
+		QueryRowContext(ctx, op.UserID).
+		Scan(&daily, &monthly)
+	if err != nil {
+		return r.handleError(ctx, err)
+	}
+	if err = checkFunc(ctx, daily, monthly); err != nil {
+		return err
+	}
+
+	// Создаем операцию, записываем проводку и обновляем баланс пользователя
+	if err = r.operationCreateTx(ctx, tx, op); err != nil {
+		return err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return r.handleError(ctx, err)
+	}
+	return nil
+}
+
+// stmtOperationTransfersSent - возвращает сумму и количество переводов, отправленных пользователем за последние сутки.
+//    $1 - id пользователя
+// Возвращает сумму и количество переводов.
+// ВАЖНО: может вызываться только внутри транзакции и только после вызова PGXRepo.userLockTx.
+var stmtOperationTransfersSent = registerStatement(`
+	SELECT 0 - coalesce(sum(amount), 0), count(*)
+	FROM operations
+	WHERE user_id = $1 AND op_type = 'transfer' AND amount < 0 AND created_at > now() - interval '1 day'
+`)
+
+// TransferCheckFunc - проверяет перевод по сумме sent и количеству count переводов,
+// отправленных пользователем за последние сутки. Если перевод невозможен, возвращает ошибку.
+type TransferCheckFunc func(ctx context.Context, sent decimal.Decimal, count int) error
+
+// OperationTransfer - переводит баллы между пользователями: создает операцию списания debit у отправителя
+// и операцию зачисления credit получателю, записывает проводки и обновляет балансы обоих пользователей.
+// Перед созданием операций вызывает коллбэк checkFunc для проверки ограничений на переводы.
+// Все действия выполняются в одной транзакции.
+// Если отправитель не найден, возвращает errs.ErrOperationUserNotExists,
+// если получатель не найден или удален - errs.ErrTransferRecipientNotFound,
+// если учетная запись отправителя заблокирована - errs.ErrOperationUserBlocked.
+func (r *PGXRepo) OperationTransfer(ctx context.Context, debit, credit *models.Operation, checkFunc TransferCheckFunc) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return r.handleError(ctx, err)
+	}
+	//goland:noinspection ALL
+	defer tx.Rollback()
+
+	// Блокируем записи обоих пользователей в порядке возрастания id,
+	// чтобы встречные переводы не приводили к взаимной блокировке
+	statuses := make(map[uint64]models.UserStatus, 2)
+	ids := []uint64{debit.UserID, credit.UserID}
+	if ids[0] > ids[1] {
+		ids[0], ids[1] = ids[1], ids[0]
+	}
+	for _, id := range ids {
+		status, err := r.userLockTx(ctx, tx, id)
+		if errors.Is(err, errs.ErrNotFound) && id == debit.UserID {
+			return errs.ErrOperationUserNotExists
+		} else if errors.Is(err, errs.ErrNotFound) {
+			return errs.ErrTransferRecipientNotFound
+		} else if err != nil {
+			return err
+		}
+		statuses[id] = status
+	}
+
+	// Заблокированный пользователь не может переводить баллы
+	if statuses[debit.UserID] == models.UserBlocked {
+		return errs.ErrOperationUserBlocked
+	}
+
+	// Проверяем ограничения на переводы
+	var sent decimal.Decimal
+	var count int
+	err = tx.Stmt(r.statements[stmtOperationTransfersSent]).
+		QueryRowContext(ctx, debit.UserID).
+		Scan(&sent, &count)
+	if err != nil {
+		return r.handleError(ctx, err)
+	}
+	if err = checkFunc(ctx, sent, count); err != nil {
+		return err
+	}
+
+	// Сначала списываем баллы у отправителя, чтобы при нехватке баллов зачисление не создавалось
+	if err = r.operationCreateTx(ctx, tx, debit); err != nil {
+		return err
+	}
+	if err = r.operationCreateTx(ctx, tx, credit); err != nil {
+		return err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return r.handleError(ctx, err)
+	}
+	return nil
+}
+
+// stmtOperationAdjustmentCreate - записывает корректировку баланса в журнал корректировок.
+//    $1 - id операции корректировки
+//    $2 - id сотрудника
+//    $3 - причина корректировки
+//    $4 - номер обращения
+// Возвращает created_at записи журнала.
+// ВАЖНО: может вызываться только внутри транзакции после создания операции корректировки.
+var stmtOperationAdjustmentCreate = registerStatement(`
+	INSERT INTO operation_adjustments (operation_id, staff_id, reason, ticket)
+	VALUES ($1, $2, $3, $4)
+	RETURNING created_at
+`)
+
+// OperationAdjust - создает операцию корректировки баланса op, записывает проводку, обновляет баланс пользователя
+// и записывает корректировку adj в журнал корректировок. Все действия выполняются в одной транзакции.
+// Если пользователь не найден или удален, возвращает errs.ErrNotFound.
+func (r *PGXRepo) OperationAdjust(ctx context.Context, op *models.Operation, adj *models.Adjustment) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return r.handleError(ctx, err)
+	}
+	//goland:noinspection ALL
+	defer tx.Rollback()
+
+	// Блокируем запись пользователя для обновления
+	if _, err = r.userLockTx(ctx, tx, op.UserID); err != nil {
+		return err
+	}
+
+	// Создаем операцию, записываем проводку и обновляем баланс пользователя
+	if err = r.operationCreateTx(ctx, tx, op); err != nil {
+		return err
+	}
+
+	// Записываем корректировку в журнал
+	adj.OperationID = op.ID
+	err = tx.Stmt(r.statements[stmtOperationAdjustmentCreate]).
+		QueryRowContext(ctx, adj.OperationID, adj.StaffID, adj.Reason, adj.Ticket).
+		Scan(&adj.CreatedAt)
+	if err != nil {
+		return r.handleError(ctx, err)
+	}
+
+	if err = tx.Commit(); err != nil {
+		return r.handleError(ctx, err)
+	}
+	return nil
+}
@@ -35,6 +35,17 @@ func (suite *pgxRepoSuite) TestOperationCreate() {
 		suite.NoError(suite.repo.OperationCreate(suite.ctx(), testPA(1, 1, 100, models.StatusProcessed)))
 	})
 
+	suite.Run("processed_at", func() {
+		// Время обработки записывается только для обработанных операций
+		op := testOA(2, "11", 100, models.StatusNew)
+		suite.NoError(suite.repo.OperationCreate(suite.ctx(), op))
+		suite.Nil(op.ProcessedAt)
+		op = testOA(2, "12", 100, models.StatusProcessed)
+		suite.NoError(suite.repo.OperationCreate(suite.ctx(), op))
+		suite.Require().NotNil(op.ProcessedAt)
+		suite.Equal(op.CreatedAt, *op.ProcessedAt)
+	})
+
 	suite.Run("Balance check", func() {
 		u, err := suite.repo.UserGetByID(suite.ctx(), 1)
 		suite.NoError(err)
@@ -143,14 +154,14 @@ func (suite *pgxRepoSuite) TestOperationGetByType() {
 	})
 
 	suite.Run("get OrderAccrual for user 1", func() {
-		ops, err := suite.repo.OperationGetByType(suite.ctx(), 1, models.OrderAccrual)
+		ops, err := suite.repo.OperationGetByType(suite.ctx(), 1, models.OrderAccrual, nil)
 		suite.NoError(err)
 		suite.Len(ops, 1)
 		suite.Equal("10", *ops[0].OrderNumber)
 	})
 
 	suite.Run("get OrderWithdrawal for user 1", func() {
-		ops, err := suite.repo.OperationGetByType(suite.ctx(), 1, models.OrderWithdrawal)
+		ops, err := suite.repo.OperationGetByType(suite.ctx(), 1, models.OrderWithdrawal, nil)
 		suite.NoError(err)
 		suite.Len(ops, 2)
 		suite.Equal("30", *ops[0].OrderNumber)
@@ -158,30 +169,60 @@ func (suite *pgxRepoSuite) TestOperationGetByType() {
 	})
 
 	suite.Run("get PromoAccrual for user 1", func() {
-		ops, err := suite.repo.OperationGetByType(suite.ctx(), 1, models.PromoAccrual)
+		ops, err := suite.repo.OperationGetByType(suite.ctx(), 1, models.PromoAccrual, nil)
 		suite.NoError(err)
 		suite.Len(ops, 1)
 		suite.Equal(uint64(1), *ops[0].PromoID)
 	})
 
 	suite.Run("get OrderAccrual for user 2", func() {
-		ops, err := suite.repo.OperationGetByType(suite.ctx(), 2, models.OrderAccrual)
+		ops, err := suite.repo.OperationGetByType(suite.ctx(), 2, models.OrderAccrual, nil)
 		suite.NoError(err)
 		suite.Equal("40", *ops[0].OrderNumber)
 	})
 
 	suite.Run("get OrderWithdrawal for user 2", func() {
-		ops, err := suite.repo.OperationGetByType(suite.ctx(), 2, models.OrderWithdrawal)
+		ops, err := suite.repo.OperationGetByType(suite.ctx(), 2, models.OrderWithdrawal, nil)
 		suite.NoError(err)
 		suite.Len(ops, 0)
 	})
 
 	suite.Run("get OrderAccrual for user 3", func() {
-		ops, err := suite.repo.OperationGetByType(suite.ctx(), 3, models.OrderAccrual)
+		ops, err := suite.repo.OperationGetByType(suite.ctx(), 3, models.OrderAccrual, nil)
 		suite.NoError(err)
 		suite.Len(ops, 0)
 	})
 
+	suite.Run("pages of OrderWithdrawal for user 1", func() {
+		ops, err := suite.repo.OperationGetByType(suite.ctx(), 1, models.OrderWithdrawal, &models.OperationQuery{Limit: 1})
+		suite.NoError(err)
+		suite.Require().Len(ops, 1)
+		suite.Equal("30", *ops[0].OrderNumber)
+
+		after := &models.OperationCursor{Time: ops[0].CreatedAt, ID: ops[0].ID}
+		ops, err = suite.repo.OperationGetByType(suite.ctx(), 1, models.OrderWithdrawal, &models.OperationQuery{Limit: 1, After: after})
+		suite.NoError(err)
+		suite.Require().Len(ops, 1)
+		suite.Equal("20", *ops[0].OrderNumber)
+
+		after = &models.OperationCursor{Time: ops[0].CreatedAt, ID: ops[0].ID}
+		ops, err = suite.repo.OperationGetByType(suite.ctx(), 1, models.OrderWithdrawal, &models.OperationQuery{Limit: 1, After: after})
+		suite.NoError(err)
+		suite.Len(ops, 0)
+	})
+
+	suite.Run("filter OrderWithdrawal for user 1", func() {
+		status := models.StatusProcessing
+		ops, err := suite.repo.OperationGetByType(suite.ctx(), 1, models.OrderWithdrawal, &models.OperationQuery{Status: &status})
+		suite.NoError(err)
+		suite.Require().Len(ops, 1)
+		suite.Equal("20", *ops[0].OrderNumber)
+
+		from := time.Now().Add(time.Hour)
+		ops, err = suite.repo.OperationGetByType(suite.ctx(), 1, models.OrderWithdrawal, &models.OperationQuery{From: &from})
+		suite.NoError(err)
+		suite.Len(ops, 0)
+	})
 }
 
 func (suite *pgxRepoSuite) TestOperationUpdateFurther() {
@@ -228,7 +269,7 @@ func (suite *pgxRepoSuite) updateWorker(ctx context.Context, wg *sync.WaitGroup,
 			suite.T().Errorf("worker timeout: %d", pid)
 			return
 		default:
-			_, err := suite.repo.OperationUpdateFurther(ctx, models.OrderAccrual, suite.updateFunc)
+			_, err := suite.repo.OperationUpdateFurther(ctx, models.OrderAccrual, models.StatusSourceAccrual, suite.updateFunc)
 			if errors.Is(err, errs.ErrNotFound) {
 				return
 			}
@@ -259,3 +300,505 @@ func (suite *pgxRepoSuite) updateFunc(_ context.Context, op *models.Operation) e
 	}
 	return nil
 }
+
+func (suite *pgxRepoSuite) TestOperationExpire() {
+	suite.NoError(suite.repo.OperationCreate(suite.ctx(), testPA(1, 1, 5, models.StatusProcessed)))
+	suite.NoError(suite.repo.OperationCreate(suite.ctx(), testOA(1, "10", 100, models.StatusProcessed)))
+	suite.NoError(suite.repo.OperationCreate(suite.ctx(), testOA(2, "20", 100, models.StatusProcessed)))
+	_, err := suite.repo.db.ExecContext(suite.ctx(), `UPDATE operations SET processed_at = now() - interval '100 days' WHERE user_id = 1`)
+	suite.Require().NoError(err)
+	// Срок жизни баллов отсчитывается от обработки, а не от последнего обновления операции
+	_, err = suite.repo.db.ExecContext(suite.ctx(), `UPDATE operations SET updated_at = now() - interval '100 days' WHERE user_id = 2`)
+	suite.Require().NoError(err)
+
+	suite.Run("candidates", func() {
+		ids, err := suite.repo.OperationExpirationCandidates(suite.ctx(), 0, 90*24*3600, 10)
+		suite.NoError(err)
+		suite.Equal([]uint64{1}, ids)
+	})
+
+	suite.Run("expire", func() {
+		op, err := suite.repo.OperationExpire(suite.ctx(), 1, func(ctx context.Context, ops []*models.Operation) (*models.Operation, error) {
+			suite.Len(ops, 2)
+			return &models.Operation{
+				UserID:      1,
+				Type:        models.PointsExpiration,
+				Status:      models.StatusProcessed,
+				Amount:      decimal.NewFromInt(-5),
+				Description: "test",
+			}, nil
+		})
+		suite.NoError(err)
+		suite.Require().NotNil(op)
+		suite.NotZero(op.ID)
+
+		// сгоревшие баллы не учитываются в сумме списаний
+		u, err := suite.repo.UserGetByID(suite.ctx(), 1)
+		suite.NoError(err)
+		suite.Equal("100", u.Balance.String())
+		suite.True(u.Withdrawn.IsZero())
+
+		checks, err := suite.repo.UserBalanceCheck(suite.ctx(), 0, 1)
+		suite.NoError(err)
+		suite.Require().Len(checks, 1)
+		suite.False(checks[0].Drift())
+	})
+
+	suite.Run("nothing to expire", func() {
+		op, err := suite.repo.OperationExpire(suite.ctx(), 1, func(ctx context.Context, ops []*models.Operation) (*models.Operation, error) {
+			return nil, nil
+		})
+		suite.NoError(err)
+		suite.Nil(op)
+	})
+
+	suite.Run("balance_not_negative constraint", func() {
+		_, err := suite.repo.OperationExpire(suite.ctx(), 1, func(ctx context.Context, ops []*models.Operation) (*models.Operation, error) {
+			return &models.Operation{UserID: 1, Type: models.PointsExpiration, Status: models.StatusProcessed, Amount: decimal.NewFromInt(-1000), Description: "test"}, nil
+		})
+		suite.ErrorIs(err, errs.ErrUserBalanceNegative)
+	})
+
+	suite.Run("unknown user", func() {
+		_, err := suite.repo.OperationExpire(suite.ctx(), 1000, func(ctx context.Context, ops []*models.Operation) (*models.Operation, error) {
+			return nil, nil
+		})
+		suite.ErrorIs(err, errs.ErrNotFound)
+	})
+}
+
+func (suite *pgxRepoSuite) TestOperationTransfer() {
+	suite.NoError(suite.repo.OperationCreate(suite.ctx(), testOA(1, "10", 100, models.StatusProcessed)))
+	noLimits := func(ctx context.Context, sent decimal.Decimal, count int) error { return nil }
+
+	suite.Run("success", func() {
+		debit, credit := testTransfer(1, 2, 30)
+		suite.NoError(suite.repo.OperationTransfer(suite.ctx(), debit, credit, noLimits))
+		suite.NotZero(debit.ID)
+		suite.NotZero(credit.ID)
+
+		// переведенные баллы не учитываются в сумме списаний
+		u1, err := suite.repo.UserGetByID(suite.ctx(), 1)
+		suite.NoError(err)
+		suite.Equal("70", u1.Balance.String())
+		suite.True(u1.Withdrawn.IsZero())
+		u2, err := suite.repo.UserGetByID(suite.ctx(), 2)
+		suite.NoError(err)
+		suite.Equal("30", u2.Balance.String())
+
+		// перевод отображается в истории обоих пользователей
+		history, err := suite.repo.UserBalanceHistoryGetByID(suite.ctx(), 2, nil)
+		suite.NoError(err)
+		suite.Require().Len(history, 1)
+		suite.Equal(models.Transfer, history[0].Type)
+		suite.Equal(uint64(1), *history[0].CounterpartyID)
+
+		clearing := models.LedgerTransferClearing
+		suite.True(suite.ledgerAccountBalance(nil, &clearing).IsZero())
+
+		checks, err := suite.repo.UserBalanceCheck(suite.ctx(), 0, 2)
+		suite.NoError(err)
+		suite.Require().Len(checks, 2)
+		suite.False(checks[0].Drift())
+		suite.False(checks[1].Drift())
+	})
+
+	suite.Run("transfers sent are counted", func() {
+		debit, credit := testTransfer(1, 3, 10)
+		err := suite.repo.OperationTransfer(suite.ctx(), debit, credit, func(ctx context.Context, sent decimal.Decimal, count int) error {
+			suite.Equal("30", sent.String())
+			suite.Equal(1, count)
+			return errs.ErrTransferDailyCountExceeded
+		})
+		suite.ErrorIs(err, errs.ErrTransferDailyCountExceeded)
+		suite.Zero(debit.ID)
+	})
+
+	suite.Run("balance_not_negative constraint", func() {
+		debit, credit := testTransfer(1, 2, 1000)
+		suite.ErrorIs(suite.repo.OperationTransfer(suite.ctx(), debit, credit, noLimits), errs.ErrUserBalanceNegative)
+		u2, err := suite.repo.UserGetByID(suite.ctx(), 2)
+		suite.NoError(err)
+		suite.Equal("30", u2.Balance.String())
+	})
+
+	suite.Run("transfer_not_to_self constraint", func() {
+		debit, credit := testTransfer(1, 1, 10)
+		suite.ErrorIs(suite.repo.OperationTransfer(suite.ctx(), debit, credit, noLimits), errs.ErrTransferSelf)
+	})
+
+	suite.Run("unknown recipient", func() {
+		debit, credit := testTransfer(1, 1000, 10)
+		suite.ErrorIs(suite.repo.OperationTransfer(suite.ctx(), debit, credit, noLimits), errs.ErrTransferRecipientNotFound)
+	})
+
+	suite.Run("blocked sender", func() {
+		suite.NoError(suite.repo.UserSetStatus(suite.ctx(), &models.UserStatusChange{UserID: 1, Status: models.UserBlocked, Reason: "test", ChangedBy: 2}))
+		debit, credit := testTransfer(1, 2, 10)
+		suite.ErrorIs(suite.repo.OperationTransfer(suite.ctx(), debit, credit, noLimits), errs.ErrOperationUserBlocked)
+	})
+}
+
+func (suite *pgxRepoSuite) TestOperationAdjust() {
+	// testAdjust - возвращает операцию корректировки баланса пользователя u на сумму a и запись журнала
+	testAdjust := func(u uint64, a int) (*models.Operation, *models.Adjustment) {
+		op := &models.Operation{
+			UserID:      u,
+			Type:        models.BalanceAdjustment,
+			Status:      models.StatusProcessed,
+			Amount:      decimal.NewFromInt(int64(a)),
+			Description: "test",
+		}
+		return op, &models.Adjustment{StaffID: 2, Reason: "test", Ticket: "SUP-1"}
+	}
+
+	suite.Run("success", func() {
+		op, adj := testAdjust(1, 50)
+		suite.NoError(suite.repo.OperationAdjust(suite.ctx(), op, adj))
+		suite.NotZero(op.ID)
+		suite.Equal(op.ID, adj.OperationID)
+		suite.False(adj.CreatedAt.IsZero())
+
+		op, adj = testAdjust(1, -20)
+		suite.NoError(suite.repo.OperationAdjust(suite.ctx(), op, adj))
+
+		// корректировка не учитывается в сумме списаний
+		u, err := suite.repo.UserGetByID(suite.ctx(), 1)
+		suite.NoError(err)
+		suite.Equal("30", u.Balance.String())
+		suite.True(u.Withdrawn.IsZero())
+
+		history, err := suite.repo.UserBalanceHistoryGetByID(suite.ctx(), 1, nil)
+		suite.NoError(err)
+		suite.Require().Len(history, 2)
+		suite.Equal(models.BalanceAdjustment, history[0].Type)
+
+		var staffID uint64
+		suite.NoError(suite.repo.db.QueryRowContext(suite.ctx(),
+			`SELECT staff_id FROM operation_adjustments WHERE operation_id = $1`, op.ID).Scan(&staffID))
+		suite.Equal(uint64(2), staffID)
+
+		fund := models.LedgerAdjustmentFund
+		suite.Equal("-30", suite.ledgerAccountBalance(nil, &fund).String())
+	})
+
+	suite.Run("balance_not_negative constraint", func() {
+		op, adj := testAdjust(1, -1000)
+		suite.ErrorIs(suite.repo.OperationAdjust(suite.ctx(), op, adj), errs.ErrUserBalanceNegative)
+	})
+
+	suite.Run("unknown user", func() {
+		op, adj := testAdjust(1000, 10)
+		suite.ErrorIs(suite.repo.OperationAdjust(suite.ctx(), op, adj), errs.ErrNotFound)
+	})
+}
+
+func (suite *pgxRepoSuite) TestOperationUpdateByOrder() {
+	suite.NoError(suite.repo.OperationCreate(suite.ctx(), testOA(1, "10", 1000, models.StatusProcessed)))
+	// cancelFunc - отменяет списание, которое еще не принято в обработку
+	cancelFunc := func(_ context.Context, op *models.Operation) error {
+		if op.Status != models.StatusNew {
+			return errs.ErrWithdrawalNotCancelable
+		}
+		op.Status = models.StatusCanceled
+		return nil
+	}
+
+	suite.Run("cancel withdrawal", func() {
+		suite.NoError(suite.repo.OperationCreate(suite.ctx(), testOW(1, "20", -100, models.StatusNew)))
+		op, err := suite.repo.OperationUpdateByOrder(suite.ctx(), 1, models.OrderWithdrawal, "20", models.StatusSourceUser, cancelFunc)
+		suite.NoError(err)
+		suite.Equal(models.StatusCanceled, op.Status)
+
+		u, err := suite.repo.UserGetByID(suite.ctx(), 1)
+		suite.NoError(err)
+		suite.Equal("1000", u.Balance.String())
+		suite.True(u.Withdrawn.IsZero())
+
+		_, err = suite.repo.OperationUpdateByOrder(suite.ctx(), 1, models.OrderWithdrawal, "20", models.StatusSourceUser, cancelFunc)
+		suite.ErrorIs(err, errs.ErrWithdrawalNotCancelable)
+	})
+
+	suite.Run("status transition is enforced", func() {
+		_, err := suite.repo.OperationUpdateByOrder(suite.ctx(), 1, models.OrderWithdrawal, "20", models.StatusSourceUser, func(_ context.Context, op *models.Operation) error {
+			op.Status = models.StatusNew
+			return nil
+		})
+		suite.ErrorIs(err, errs.ErrOperationStatusTransitInvalid)
+	})
+
+	suite.Run("other user's withdrawal", func() {
+		suite.NoError(suite.repo.OperationCreate(suite.ctx(), testOW(1, "30", -100, models.StatusNew)))
+		_, err := suite.repo.OperationUpdateByOrder(suite.ctx(), 2, models.OrderWithdrawal, "30", models.StatusSourceUser, cancelFunc)
+		suite.ErrorIs(err, errs.ErrNotFound)
+	})
+
+	suite.Run("race with processing", func() {
+		for i := 0; i < 50; i++ {
+			suite.NoError(suite.repo.OperationCreate(suite.ctx(), testOW(1, fmt.Sprintf("1%03d", i), -10, models.StatusNew)))
+		}
+
+		wg := &sync.WaitGroup{}
+		for i := 0; i < 50; i++ {
+			wg.Add(1)
+			go func(n string) {
+				defer wg.Done()
+				_, err := suite.repo.OperationUpdateByOrder(suite.ctx(), 1, models.OrderWithdrawal, n, models.StatusSourceUser, cancelFunc)
+				if !errors.Is(err, errs.ErrWithdrawalNotCancelable) {
+					suite.NoError(err)
+				}
+			}(fmt.Sprintf("1%03d", i))
+		}
+		for i := 0; i < 4; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for {
+					_, err := suite.repo.OperationUpdateFurther(suite.ctx(), models.OrderWithdrawal, models.StatusSourceShop, func(_ context.Context, op *models.Operation) error {
+						op.Status = models.StatusProcessed
+						return nil
+					})
+					if errors.Is(err, errs.ErrNotFound) {
+						return
+					}
+					suite.NoError(err)
+				}
+			}()
+		}
+		wg.Wait()
+
+		// Каждое списание либо отменено, либо обработано, и баланс соответствует операциям
+		checks, err := suite.repo.UserBalanceCheck(suite.ctx(), 0, 1)
+		suite.NoError(err)
+		suite.Require().Len(checks, 1)
+		suite.False(checks[0].Drift())
+
+		ops, err := suite.repo.OperationGetByType(suite.ctx(), 1, models.OrderWithdrawal, nil)
+		suite.NoError(err)
+		processed := decimal.Zero
+		for _, op := range ops {
+			suite.Contains([]models.OperationStatus{models.StatusCanceled, models.StatusProcessed}, op.Status)
+			if op.Status == models.StatusProcessed {
+				processed = processed.Add(op.Amount)
+			}
+		}
+		u, err := suite.repo.UserGetByID(suite.ctx(), 1)
+		suite.NoError(err)
+		suite.Equal(processed.Neg().String(), u.Withdrawn.String())
+	})
+}
+
+func (suite *pgxRepoSuite) TestOperationHold() {
+	// testHeld - возвращает обработанное начисление по заказу n на сумму a, баллы которого удерживаются в течение period
+	testHeld := func(n string, a int, period time.Duration) *models.Operation {
+		op := testOA(1, n, a, models.StatusProcessed)
+		op.HoldPeriod = period
+		return op
+	}
+
+	suite.Run("held points are not spendable", func() {
+		op := testHeld("10", 100, time.Hour)
+		suite.NoError(suite.repo.OperationCreate(suite.ctx(), op))
+		suite.Require().NotNil(op.HoldUntil)
+
+		u, err := suite.repo.UserGetByID(suite.ctx(), 1)
+		suite.NoError(err)
+		suite.Equal("100", u.Balance.String())
+		suite.Equal("100", u.Pending.String())
+		suite.True(u.Available().IsZero())
+
+		suite.ErrorIs(suite.repo.OperationCreate(suite.ctx(), testOW(1, "20", -10, models.StatusNew)), errs.ErrUserBalanceNegative)
+	})
+
+	suite.Run("available points are spendable", func() {
+		suite.NoError(suite.repo.OperationCreate(suite.ctx(), testOA(1, "30", 50, models.StatusProcessed)))
+		suite.NoError(suite.repo.OperationCreate(suite.ctx(), testOW(1, "40", -30, models.StatusNew)))
+		suite.ErrorIs(suite.repo.OperationCreate(suite.ctx(), testOW(1, "50", -30, models.StatusNew)), errs.ErrUserBalanceNegative)
+	})
+
+	suite.Run("hold is set on processing", func() {
+		suite.NoError(suite.repo.OperationCreate(suite.ctx(), testOA(2, "60", 0, models.StatusNew)))
+		op, err := suite.repo.OperationUpdateFurther(suite.ctx(), models.OrderAccrual, models.StatusSourceAccrual, func(_ context.Context, op *models.Operation) error {
+			op.Status = models.StatusProcessed
+			op.Amount = decimal.NewFromInt(40)
+			op.HoldPeriod = time.Hour
+			return nil
+		})
+		suite.NoError(err)
+		suite.NotNil(op.HoldUntil)
+
+		u, err := suite.repo.UserGetByID(suite.ctx(), 2)
+		suite.NoError(err)
+		suite.Equal("40", u.Pending.String())
+	})
+
+	suite.Run("expired hold is released", func() {
+		suite.NoError(suite.repo.OperationCreate(suite.ctx(), testHeld("70", 25, time.Millisecond)))
+		time.Sleep(10 * time.Millisecond)
+
+		ids, err := suite.repo.OperationHoldCandidates(suite.ctx(), 0, 10)
+		suite.NoError(err)
+		suite.Equal([]uint64{1}, ids)
+
+		released, err := suite.repo.OperationHoldRelease(suite.ctx(), 1)
+		suite.NoError(err)
+		suite.Equal("25", released.String())
+
+		u, err := suite.repo.UserGetByID(suite.ctx(), 1)
+		suite.NoError(err)
+		suite.Equal("100", u.Pending.String())
+		suite.Equal("45", u.Available().String())
+
+		checks, err := suite.repo.UserBalanceCheck(suite.ctx(), 0, 2)
+		suite.NoError(err)
+		suite.Require().Len(checks, 2)
+		suite.False(checks[0].Drift())
+		suite.False(checks[1].Drift())
+	})
+
+	suite.Run("account closure releases all holds", func() {
+		op, err := suite.repo.UserDelete(suite.ctx(), 1, "test", 0)
+		suite.NoError(err)
+		suite.Equal("-145", op.Amount.String())
+
+		u, err := suite.repo.UserGetByID(suite.ctx(), 1)
+		suite.NoError(err)
+		suite.True(u.Balance.IsZero())
+		suite.True(u.Pending.IsZero())
+	})
+}
+
+func (suite *pgxRepoSuite) TestOperationWithdraw() {
+	suite.NoError(suite.repo.OperationCreate(suite.ctx(), testOA(1, "10", 100, models.StatusProcessed)))
+	noLimits := func(ctx context.Context, daily, monthly decimal.Decimal) error { return nil }
+
+	suite.Run("success", func() {
+		op := testOW(1, "20", -30, models.StatusNew)
+		suite.NoError(suite.repo.OperationWithdraw(suite.ctx(), op, noLimits))
+		suite.NotZero(op.ID)
+
+		u, err := suite.repo.UserGetByID(suite.ctx(), 1)
+		suite.NoError(err)
+		suite.Equal("70", u.Balance.String())
+		suite.Equal("30", u.Withdrawn.String())
+	})
+
+	suite.Run("withdrawals are summed", func() {
+		suite.NoError(suite.repo.OperationCreate(suite.ctx(), testOW(1, "30", -5, models.StatusInvalid)))
+		op := testOW(1, "40", -10, models.StatusNew)
+		err := suite.repo.OperationWithdraw(suite.ctx(), op, func(ctx context.Context, daily, monthly decimal.Decimal) error {
+			// отклоненные списания не учитываются
+			suite.Equal("30", daily.String())
+			suite.Equal("30", monthly.String())
+			return errs.ErrWithdrawalDailyAmountExceeded
+		})
+		suite.ErrorIs(err, errs.ErrWithdrawalDailyAmountExceeded)
+		suite.Zero(op.ID)
+	})
+
+	suite.Run("balance_not_negative constraint", func() {
+		suite.ErrorIs(suite.repo.OperationWithdraw(suite.ctx(), testOW(1, "50", -1000, models.StatusNew), noLimits), errs.ErrUserBalanceNegative)
+	})
+
+	suite.Run("unknown user", func() {
+		suite.ErrorIs(suite.repo.OperationWithdraw(suite.ctx(), testOW(1000, "60", -10, models.StatusNew), noLimits), errs.ErrOperationUserNotExists)
+	})
+
+	suite.Run("blocked user", func() {
+		suite.NoError(suite.repo.UserSetStatus(suite.ctx(), &models.UserStatusChange{UserID: 1, Status: models.UserBlocked, Reason: "test", ChangedBy: 2}))
+		suite.ErrorIs(suite.repo.OperationWithdraw(suite.ctx(), testOW(1, "70", -10, models.StatusNew), noLimits), errs.ErrOperationUserBlocked)
+	})
+}
+
+func (suite *pgxRepoSuite) TestOperationGetByOrder() {
+	suite.NoError(suite.repo.OperationCreate(suite.ctx(), testOA(1, "10", 100, models.StatusProcessed)))
+
+	suite.Run("success", func() {
+		op, err := suite.repo.OperationGetByOrder(suite.ctx(), 1, models.OrderAccrual, "10")
+		suite.NoError(err)
+		suite.NotZero(op.ID)
+		suite.Equal(models.StatusProcessed, op.Status)
+		suite.Equal("100", op.Amount.String())
+		suite.NotZero(op.CreatedAt)
+		suite.NotZero(op.UpdatedAt)
+		suite.Nil(op.LastCheckedAt)
+	})
+
+	suite.Run("checked by poll", func() {
+		suite.NoError(suite.repo.OperationCreate(suite.ctx(), testOA(1, "20", 0, models.StatusNew)))
+		polled, err := suite.repo.OperationUpdateFurther(suite.ctx(), models.OrderAccrual, models.StatusSourceAccrual, func(_ context.Context, op *models.Operation) error {
+			op.Status = models.StatusProcessing
+			return nil
+		})
+		suite.Require().NoError(err)
+		suite.Require().NotNil(polled.LastCheckedAt)
+
+		op, err := suite.repo.OperationGetByOrder(suite.ctx(), 1, models.OrderAccrual, "20")
+		suite.NoError(err)
+		suite.Require().NotNil(op.LastCheckedAt)
+		suite.True(op.LastCheckedAt.Equal(*polled.LastCheckedAt))
+	})
+
+	suite.Run("other operation type", func() {
+		_, err := suite.repo.OperationGetByOrder(suite.ctx(), 1, models.OrderWithdrawal, "10")
+		suite.ErrorIs(err, errs.ErrNotFound)
+	})
+
+	suite.Run("other user's order", func() {
+		_, err := suite.repo.OperationGetByOrder(suite.ctx(), 2, models.OrderAccrual, "10")
+		suite.ErrorIs(err, errs.ErrNotFound)
+	})
+}
+
+func (suite *pgxRepoSuite) TestOperationStatusHistory() {
+	suite.NoError(suite.repo.OperationCreate(suite.ctx(), testOA(1, "10", 0, models.StatusNew)))
+	// updateTo - возвращает коллбэк, переводящий начисление в статус s с суммой a
+	updateTo := func(s models.OperationStatus, a int) UpdateFunc {
+		return func(_ context.Context, op *models.Operation) error {
+			op.Status = s
+			op.Amount = decimal.NewFromInt(int64(a))
+			return nil
+		}
+	}
+
+	suite.Run("no changes yet", func() {
+		history, err := suite.repo.OperationStatusHistoryGetByOrder(suite.ctx(), 1, models.OrderAccrual, "10")
+		suite.NoError(err)
+		suite.Empty(history)
+	})
+
+	suite.Run("transitions are recorded", func() {
+		_, err := suite.repo.OperationUpdateFurther(suite.ctx(), models.OrderAccrual, models.StatusSourceAccrual, updateTo(models.StatusProcessing, 0))
+		suite.NoError(err)
+		// повторный опрос без изменений не записывается
+		_, err = suite.repo.OperationUpdateFurther(suite.ctx(), models.OrderAccrual, models.StatusSourceAccrual, updateTo(models.StatusProcessing, 0))
+		suite.NoError(err)
+		_, err = suite.repo.OperationUpdateFurther(suite.ctx(), models.OrderAccrual, models.StatusSourceAccrual, updateTo(models.StatusProcessed, 500))
+		suite.NoError(err)
+
+		history, err := suite.repo.OperationStatusHistoryGetByOrder(suite.ctx(), 1, models.OrderAccrual, "10")
+		suite.NoError(err)
+		suite.Require().Len(history, 2)
+		suite.Equal(models.StatusNew, history[0].OldStatus)
+		suite.Equal(models.StatusProcessing, history[0].NewStatus)
+		suite.Equal(models.StatusSourceAccrual, history[0].Source)
+		suite.Equal(models.StatusProcessing, history[1].OldStatus)
+		suite.Equal(models.StatusProcessed, history[1].NewStatus)
+		suite.Equal("500", history[1].Amount.String())
+	})
+
+	suite.Run("invalid transition is rejected", func() {
+		suite.NoError(suite.repo.OperationCreate(suite.ctx(), testOA(1, "20", 0, models.StatusNew)))
+		_, err := suite.repo.OperationUpdateFurther(suite.ctx(), models.OrderAccrual, models.StatusSourceAccrual, updateTo("REGISTERED", 0))
+		suite.ErrorIs(err, errs.ErrOperationStatusTransitInvalid)
+
+		history, err := suite.repo.OperationStatusHistoryGetByOrder(suite.ctx(), 1, models.OrderAccrual, "20")
+		suite.NoError(err)
+		suite.Empty(history)
+	})
+
+	suite.Run("other user's order", func() {
+		_, err := suite.repo.OperationStatusHistoryGetByOrder(suite.ctx(), 2, models.OrderAccrual, "10")
+		suite.ErrorIs(err, errs.ErrNotFound)
+	})
+}
@@ -0,0 +1,82 @@
+package repo
+
+import (
+	"context"
+
+	"gophermart-loyalty/internal/models"
+)
+
+// stmtPasswordResetCreate - создает токен сброса пароля.
+// Ранее выданные и не использованные токены пользователя становятся недействительными.
+//    $1 - user_id
+//    $2 - token_hash
+//    $3 - время жизни токена в секундах
+// Возвращает id, expires_at, created_at токена.
+var stmtPasswordResetCreate = registerStatement(`
+	WITH invalidated AS (
+		UPDATE password_resets
+		SET used_at = now()
+		WHERE user_id = $1 AND used_at IS NULL
+	)
+	INSERT INTO password_resets (user_id, token_hash, expires_at)
+	VALUES ($1, $2, now() + make_interval(secs => $3))
+	RETURNING id, expires_at, created_at
+`)
+
+// PasswordResetCreate - создает токен сброса пароля.
+// Ранее выданные и не использованные токены пользователя становятся недействительными.
+// Время истечения токена отсчитывается от текущего времени БД.
+func (r *PGXRepo) PasswordResetCreate(ctx context.Context, pr *models.PasswordReset, ttlSeconds int64) error {
+	err := r.statements[stmtPasswordResetCreate].
+		QueryRowContext(ctx, pr.UserID, pr.TokenHash, ttlSeconds).
+		Scan(&pr.ID, &pr.ExpiresAt, &pr.CreatedAt)
+	if err != nil {
+		return r.handleError(ctx, err)
+	}
+	return nil
+}
+
+// stmtPasswordResetConsume - использует токен сброса пароля:
+// помечает токен использованным, устанавливает новый хэш пароля,
+// отзывает все сессии и все ранее выпущенные токены пользователя.
+//    $1 - token_hash
+//    $2 - pass_hash
+// Возвращает id пользователя.
+var stmtPasswordResetConsume = registerStatement(`
+	WITH
+		used AS (
+			UPDATE password_resets
+			SET used_at = now()
+			WHERE token_hash = $1 AND used_at IS NULL AND expires_at > now()
+			RETURNING user_id
+		),
+		revoked AS (
+			UPDATE sessions
+			SET revoked_at = now(), updated_at = now()
+			WHERE user_id IN (SELECT user_id FROM used) AND revoked_at IS NULL
+		),
+		revoked_tokens AS (
+			UPDATE access_tokens
+			SET revoked_at = now()
+			WHERE user_id IN (SELECT user_id FROM used) AND revoked_at IS NULL
+		)
+	UPDATE users
+	SET pass_hash = $2, tokens_valid_after = now(), updated_at = now()
+	WHERE id IN (SELECT user_id FROM used)
+	RETURNING id
+`)
+
+// PasswordResetConsume - использует токен сброса пароля и устанавливает новый хэш пароля.
+// Все сессии, персональные токены и все ранее выпущенные токены пользователя становятся недействительными.
+// Поиск и использование токена выполняются одним запросом, поэтому токен может быть использован только один раз.
+// Если действующий токен не найден, возвращает errs.ErrNotFound.
+func (r *PGXRepo) PasswordResetConsume(ctx context.Context, tokenHash, passHash string) (uint64, error) {
+	var userID uint64
+	err := r.statements[stmtPasswordResetConsume].
+		QueryRowContext(ctx, tokenHash, passHash).
+		Scan(&userID)
+	if err != nil {
+		return 0, r.handleError(ctx, err)
+	}
+	return userID, nil
+}
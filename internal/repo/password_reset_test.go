@@ -0,0 +1,61 @@
+package repo
+
+import (
+	"gophermart-loyalty/internal/errs"
+	"gophermart-loyalty/internal/models"
+)
+
+func (suite *pgxRepoSuite) TestPasswordResetCreate() {
+	pr := &models.PasswordReset{UserID: 1, TokenHash: "hash1"}
+	suite.NoError(suite.repo.PasswordResetCreate(suite.ctx(), pr, 3600))
+	suite.NotZero(pr.ID)
+	suite.True(pr.ExpiresAt.After(pr.CreatedAt))
+
+	err := suite.repo.PasswordResetCreate(suite.ctx(), &models.PasswordReset{UserID: 1000, TokenHash: "hash2"}, 3600)
+	suite.ErrorIs(err, errs.ErrNotFound)
+
+	suite.Run("new token invalidates previous one", func() {
+		suite.NoError(suite.repo.PasswordResetCreate(suite.ctx(), &models.PasswordReset{UserID: 1, TokenHash: "hash3"}, 3600))
+		_, err := suite.repo.PasswordResetConsume(suite.ctx(), "hash1", "new-hash")
+		suite.ErrorIs(err, errs.ErrNotFound)
+	})
+}
+
+func (suite *pgxRepoSuite) TestPasswordResetConsume() {
+	suite.NoError(suite.repo.PasswordResetCreate(suite.ctx(), &models.PasswordReset{UserID: 1, TokenHash: "hash1"}, 3600))
+	suite.NoError(suite.repo.PasswordResetCreate(suite.ctx(), &models.PasswordReset{UserID: 2, TokenHash: "expired"}, 0))
+	s := &models.Session{UserID: 1, RefreshHash: "refresh1"}
+	suite.NoError(suite.repo.SessionCreate(suite.ctx(), s, 3600))
+	suite.NoError(suite.repo.AccessTokenCreate(suite.ctx(), &models.AccessToken{UserID: 1, Name: "token1", TokenHash: "token1"}, 3600))
+
+	suite.Run("consume", func() {
+		userID, err := suite.repo.PasswordResetConsume(suite.ctx(), "hash1", "new-hash")
+		suite.NoError(err)
+		suite.Equal(uint64(1), userID)
+
+		u, err := suite.repo.UserGetByID(suite.ctx(), 1)
+		suite.NoError(err)
+		suite.Equal("new-hash", u.PassHash)
+		suite.True(u.TokensValidAfter.After(u.CreatedAt))
+
+		s, err := suite.repo.SessionGetByID(suite.ctx(), s.ID)
+		suite.NoError(err)
+		suite.NotNil(s.RevokedAt)
+
+		_, err = suite.repo.AccessTokenUse(suite.ctx(), "token1")
+		suite.ErrorIs(err, errs.ErrNotFound)
+	})
+
+	suite.Run("token can not be reused", func() {
+		_, err := suite.repo.PasswordResetConsume(suite.ctx(), "hash1", "other-hash")
+		suite.ErrorIs(err, errs.ErrNotFound)
+	})
+
+	suite.Run("expired token", func() {
+		_, err := suite.repo.PasswordResetConsume(suite.ctx(), "expired", "other-hash")
+		suite.ErrorIs(err, errs.ErrNotFound)
+		u, err := suite.repo.UserGetByID(suite.ctx(), 2)
+		suite.NoError(err)
+		suite.Equal("hash2", u.PassHash)
+	})
+}
@@ -50,3 +50,40 @@ func (r *PGXRepo) PromoGetByCode(ctx context.Context, code string) (*models.Prom
 	}
 	return p, nil
 }
+
+// stmtPromoGetByUser - возвращает промо-кампании, промо-коды которых использовал пользователь.
+//    $1 - user_id
+// Возвращает id, code, description, reward, not_before, not_after, created_at.
+var stmtPromoGetByUser = registerStatement(`
+	SELECT p.id, p.code, p.description, p.reward, p.not_before, p.not_after, p.created_at
+	FROM promos p
+	WHERE EXISTS(
+		SELECT 1 FROM operations o
+		WHERE o.user_id = $1 AND o.op_type = 'promo_accrual' AND o.promo_id = p.id
+	)
+	ORDER BY p.id
+`)
+
+// PromoGetByUser - возвращает промо-кампании, промо-коды которых использовал пользователь.
+func (r *PGXRepo) PromoGetByUser(ctx context.Context, userID uint64) ([]*models.Promo, error) {
+	rows, err := r.statements[stmtPromoGetByUser].QueryContext(ctx, userID)
+	if err != nil {
+		return nil, r.handleError(ctx, err)
+	}
+	//goland:noinspection GoUnhandledErrorResult
+	defer rows.Close()
+
+	var list []*models.Promo
+	for rows.Next() {
+		p := &models.Promo{}
+		err = rows.Scan(&p.ID, &p.Code, &p.Description, &p.Reward, &p.NotBefore, &p.NotAfter, &p.CreatedAt)
+		if err != nil {
+			return nil, r.handleError(ctx, err)
+		}
+		list = append(list, p)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, r.handleError(ctx, err)
+	}
+	return list, nil
+}
@@ -4,6 +4,7 @@ import (
 	"time"
 
 	"gophermart-loyalty/internal/errs"
+	"gophermart-loyalty/internal/models"
 )
 
 func (suite *pgxRepoSuite) TestPromoCreate() {
@@ -42,3 +43,18 @@ func (suite *pgxRepoSuite) TestPromoGetByCode() {
 	suite.ErrorIs(err, errs.ErrNotFound)
 	suite.Nil(promo)
 }
+
+func (suite *pgxRepoSuite) TestPromoGetByUser() {
+	p, err := suite.repo.PromoGetByCode(suite.ctx(), "TEST-PROMO")
+	suite.Require().NoError(err)
+	suite.NoError(suite.repo.OperationCreate(suite.ctx(), testPA(1, p.ID, 5, models.StatusProcessed)))
+
+	list, err := suite.repo.PromoGetByUser(suite.ctx(), 1)
+	suite.NoError(err)
+	suite.Require().Len(list, 1)
+	suite.Equal("TEST-PROMO", list[0].Code)
+
+	list, err = suite.repo.PromoGetByUser(suite.ctx(), 2)
+	suite.NoError(err)
+	suite.Empty(list)
+}
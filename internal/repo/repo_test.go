@@ -47,7 +47,7 @@ func (suite *pgxRepoSuite) SetupTest() {
 
 	// Создаем репозиторий
 	var err error
-	suite.repo, err = NewPGXRepo(&config.DB{URI: autotestDSN, RequiredVersion: 1}, suite.log)
+	suite.repo, err = NewPGXRepo(&config.DB{URI: autotestDSN, RequiredVersion: 22}, suite.log)
 	suite.NoError(err)
 
 	// Создаем пользователей
@@ -136,6 +136,26 @@ func testPA(u uint64, p uint64, a int, s models.OperationStatus) *models.Operati
 	}
 }
 
+func testTransfer(from, to uint64, a int) (*models.Operation, *models.Operation) {
+	debit := &models.Operation{
+		UserID:         from,
+		Type:           models.Transfer,
+		Status:         models.StatusProcessed,
+		Amount:         decimal.NewFromInt(int64(-a)),
+		Description:    "test",
+		CounterpartyID: &to,
+	}
+	credit := &models.Operation{
+		UserID:         to,
+		Type:           models.Transfer,
+		Status:         models.StatusProcessed,
+		Amount:         decimal.NewFromInt(int64(a)),
+		Description:    "test",
+		CounterpartyID: &from,
+	}
+	return debit, credit
+}
+
 func testPromo(code string, reward int, notBefore, notAfter time.Time) *models.Promo {
 	return &models.Promo{
 		Code:        code,
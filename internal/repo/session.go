@@ -0,0 +1,170 @@
+package repo
+
+import (
+	"context"
+	"database/sql"
+
+	"gophermart-loyalty/internal/models"
+)
+
+// stmtSessionCreate - создает сессию пользователя.
+//    $1 - user_id
+//    $2 - refresh_hash
+//    $3 - время жизни refresh-токена в секундах
+// Возвращает id, expires_at, created_at, updated_at сессии.
+var stmtSessionCreate = registerStatement(`
+	INSERT INTO sessions (user_id, refresh_hash, expires_at)
+	VALUES ($1, $2, now() + make_interval(secs => $3))
+	RETURNING id, expires_at, created_at, updated_at
+`)
+
+// SessionCreate - создает сессию пользователя.
+// Время истечения сессии отсчитывается от текущего времени БД.
+func (r *PGXRepo) SessionCreate(ctx context.Context, s *models.Session, ttlSeconds int64) error {
+	err := r.statements[stmtSessionCreate].
+		QueryRowContext(ctx, s.UserID, s.RefreshHash, ttlSeconds).
+		Scan(&s.ID, &s.ExpiresAt, &s.CreatedAt, &s.UpdatedAt)
+	if err != nil {
+		return r.handleError(ctx, err)
+	}
+	return nil
+}
+
+// stmtSessionGetByID - возвращает сессию по id.
+//    $1 - id
+// Возвращает id, user_id, refresh_hash, expires_at, revoked_at, created_at, updated_at.
+var stmtSessionGetByID = registerStatement(`
+	SELECT id, user_id, refresh_hash, expires_at, revoked_at, created_at, updated_at FROM sessions
+	WHERE id = $1
+`)
+
+// SessionGetByID - возвращает сессию по id.
+func (r *PGXRepo) SessionGetByID(ctx context.Context, sessionID uint64) (*models.Session, error) {
+	s := &models.Session{}
+	err := r.statements[stmtSessionGetByID].
+		QueryRowContext(ctx, sessionID).
+		Scan(&s.ID, &s.UserID, &s.RefreshHash, &s.ExpiresAt, &s.RevokedAt, &s.CreatedAt, &s.UpdatedAt)
+	if err != nil {
+		return nil, r.handleError(ctx, err)
+	}
+	return s, nil
+}
+
+// stmtSessionRotate - заменяет refresh-токен активной сессии и продлевает сессию.
+//    $1 - хэш текущего refresh-токена
+//    $2 - хэш нового refresh-токена
+//    $3 - время жизни refresh-токена в секундах
+// Возвращает id, user_id, refresh_hash, expires_at, revoked_at, created_at, updated_at.
+var stmtSessionRotate = registerStatement(`
+	UPDATE sessions
+	SET refresh_hash = $2, expires_at = now() + make_interval(secs => $3), updated_at = now()
+	WHERE refresh_hash = $1 AND revoked_at IS NULL AND expires_at > now()
+		AND EXISTS(SELECT 1 FROM users WHERE id = sessions.user_id AND status = 'active')
+	RETURNING id, user_id, refresh_hash, expires_at, revoked_at, created_at, updated_at
+`)
+
+// SessionRotate - заменяет refresh-токен активной сессии и продлевает сессию.
+// Поиск и обновление сессии выполняются одним запросом, поэтому
+// один и тот же refresh-токен может быть использован только один раз.
+// Если активная сессия с таким refresh-токеном не найдена или учетная запись пользователя заблокирована,
+// возвращает errs.ErrNotFound.
+func (r *PGXRepo) SessionRotate(ctx context.Context, oldHash, newHash string, ttlSeconds int64) (*models.Session, error) {
+	s := &models.Session{}
+	err := r.statements[stmtSessionRotate].
+		QueryRowContext(ctx, oldHash, newHash, ttlSeconds).
+		Scan(&s.ID, &s.UserID, &s.RefreshHash, &s.ExpiresAt, &s.RevokedAt, &s.CreatedAt, &s.UpdatedAt)
+	if err != nil {
+		return nil, r.handleError(ctx, err)
+	}
+	return s, nil
+}
+
+// stmtSessionRevoke - отзывает активную сессию пользователя.
+//    $1 - id сессии
+//    $2 - user_id
+// Возвращает id сессии.
+var stmtSessionRevoke = registerStatement(`
+	UPDATE sessions
+	SET revoked_at = now(), updated_at = now()
+	WHERE id = $1 AND user_id = $2 AND revoked_at IS NULL
+	RETURNING id
+`)
+
+// SessionRevoke - отзывает активную сессию пользователя.
+// Если активная сессия не найдена, возвращает errs.ErrNotFound.
+func (r *PGXRepo) SessionRevoke(ctx context.Context, userID, sessionID uint64) error {
+	err := r.statements[stmtSessionRevoke].
+		QueryRowContext(ctx, sessionID, userID).
+		Scan(&sql.NullInt64{})
+	if err != nil {
+		return r.handleError(ctx, err)
+	}
+	return nil
+}
+
+// stmtSessionRevokeAll - отзывает все активные сессии и персональные токены пользователя и
+// делает недействительными все ранее выпущенные токены пользователя.
+//    $1 - user_id
+// Возвращает id пользователя.
+var stmtSessionRevokeAll = registerStatement(`
+	WITH
+		revoked AS (
+			UPDATE sessions
+			SET revoked_at = now(), updated_at = now()
+			WHERE user_id = $1 AND revoked_at IS NULL
+		),
+		revoked_tokens AS (
+			UPDATE access_tokens
+			SET revoked_at = now()
+			WHERE user_id = $1 AND revoked_at IS NULL
+		)
+	UPDATE users
+	SET tokens_valid_after = now(), updated_at = now()
+	WHERE id = $1
+	RETURNING id
+`)
+
+// SessionRevokeAll - отзывает все активные сессии и персональные токены пользователя и
+// делает недействительными все ранее выпущенные токены пользователя.
+func (r *PGXRepo) SessionRevokeAll(ctx context.Context, userID uint64) error {
+	err := r.statements[stmtSessionRevokeAll].
+		QueryRowContext(ctx, userID).
+		Scan(&sql.NullInt64{})
+	if err != nil {
+		return r.handleError(ctx, err)
+	}
+	return nil
+}
+
+// stmtSessionGetByUser - возвращает все сессии пользователя.
+//    $1 - user_id
+// Возвращает id, user_id, refresh_hash, expires_at, revoked_at, created_at, updated_at.
+var stmtSessionGetByUser = registerStatement(`
+	SELECT id, user_id, refresh_hash, expires_at, revoked_at, created_at, updated_at FROM sessions
+	WHERE user_id = $1
+	ORDER BY created_at DESC, id DESC
+`)
+
+// SessionGetByUser - возвращает все сессии пользователя, включая отозванные и истекшие.
+func (r *PGXRepo) SessionGetByUser(ctx context.Context, userID uint64) ([]*models.Session, error) {
+	rows, err := r.statements[stmtSessionGetByUser].QueryContext(ctx, userID)
+	if err != nil {
+		return nil, r.handleError(ctx, err)
+	}
+	//goland:noinspection GoUnhandledErrorResult
+	defer rows.Close()
+
+	var list []*models.Session
+	for rows.Next() {
+		s := &models.Session{}
+		err = rows.Scan(&s.ID, &s.UserID, &s.RefreshHash, &s.ExpiresAt, &s.RevokedAt, &s.CreatedAt, &s.UpdatedAt)
+		if err != nil {
+			return nil, r.handleError(ctx, err)
+		}
+		list = append(list, s)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, r.handleError(ctx, err)
+	}
+	return list, nil
+}
@@ -0,0 +1,118 @@
+package repo
+
+import (
+	"gophermart-loyalty/internal/errs"
+	"gophermart-loyalty/internal/models"
+)
+
+func (suite *pgxRepoSuite) TestSessionCreate() {
+	s := &models.Session{UserID: 1, RefreshHash: "hash1"}
+	suite.NoError(suite.repo.SessionCreate(suite.ctx(), s, 3600))
+	suite.NotZero(s.ID)
+	suite.True(s.ExpiresAt.After(s.CreatedAt))
+
+	s, err := suite.repo.SessionGetByID(suite.ctx(), s.ID)
+	suite.NoError(err)
+	suite.Equal(uint64(1), s.UserID)
+	suite.Equal("hash1", s.RefreshHash)
+	suite.Nil(s.RevokedAt)
+
+	err = suite.repo.SessionCreate(suite.ctx(), &models.Session{UserID: 1000, RefreshHash: "hash2"}, 3600)
+	suite.ErrorIs(err, errs.ErrNotFound)
+
+	_, err = suite.repo.SessionGetByID(suite.ctx(), 1000)
+	suite.ErrorIs(err, errs.ErrNotFound)
+}
+
+func (suite *pgxRepoSuite) TestSessionRotate() {
+	suite.NoError(suite.repo.SessionCreate(suite.ctx(), &models.Session{UserID: 1, RefreshHash: "hash1"}, 3600))
+	suite.NoError(suite.repo.SessionCreate(suite.ctx(), &models.Session{UserID: 2, RefreshHash: "expired"}, 0))
+
+	suite.Run("rotate", func() {
+		s, err := suite.repo.SessionRotate(suite.ctx(), "hash1", "hash2", 3600)
+		suite.NoError(err)
+		suite.Equal(uint64(1), s.UserID)
+		suite.Equal("hash2", s.RefreshHash)
+	})
+
+	suite.Run("old token can not be reused", func() {
+		_, err := suite.repo.SessionRotate(suite.ctx(), "hash1", "hash3", 3600)
+		suite.ErrorIs(err, errs.ErrNotFound)
+	})
+
+	suite.Run("expired session", func() {
+		_, err := suite.repo.SessionRotate(suite.ctx(), "expired", "hash4", 3600)
+		suite.ErrorIs(err, errs.ErrNotFound)
+	})
+}
+
+func (suite *pgxRepoSuite) TestSessionRevoke() {
+	s := &models.Session{UserID: 1, RefreshHash: "hash1"}
+	suite.NoError(suite.repo.SessionCreate(suite.ctx(), s, 3600))
+
+	suite.Run("session of another user", func() {
+		suite.ErrorIs(suite.repo.SessionRevoke(suite.ctx(), 2, s.ID), errs.ErrNotFound)
+	})
+
+	suite.Run("revoke", func() {
+		suite.NoError(suite.repo.SessionRevoke(suite.ctx(), 1, s.ID))
+		s, err := suite.repo.SessionGetByID(suite.ctx(), s.ID)
+		suite.NoError(err)
+		suite.NotNil(s.RevokedAt)
+	})
+
+	suite.Run("already revoked", func() {
+		suite.ErrorIs(suite.repo.SessionRevoke(suite.ctx(), 1, s.ID), errs.ErrNotFound)
+	})
+
+	suite.Run("refresh token of revoked session", func() {
+		_, err := suite.repo.SessionRotate(suite.ctx(), "hash1", "hash2", 3600)
+		suite.ErrorIs(err, errs.ErrNotFound)
+	})
+}
+
+func (suite *pgxRepoSuite) TestSessionRevokeAll() {
+	s1 := &models.Session{UserID: 1, RefreshHash: "hash1"}
+	s2 := &models.Session{UserID: 1, RefreshHash: "hash2"}
+	s3 := &models.Session{UserID: 2, RefreshHash: "hash3"}
+	suite.NoError(suite.repo.SessionCreate(suite.ctx(), s1, 3600))
+	suite.NoError(suite.repo.SessionCreate(suite.ctx(), s2, 3600))
+	suite.NoError(suite.repo.SessionCreate(suite.ctx(), s3, 3600))
+	t1 := &models.AccessToken{UserID: 1, Name: "token1", TokenHash: "token1"}
+	t2 := &models.AccessToken{UserID: 2, Name: "token2", TokenHash: "token2"}
+	suite.NoError(suite.repo.AccessTokenCreate(suite.ctx(), t1, 3600))
+	suite.NoError(suite.repo.AccessTokenCreate(suite.ctx(), t2, 3600))
+
+	suite.NoError(suite.repo.SessionRevokeAll(suite.ctx(), 1))
+
+	for _, id := range []uint64{s1.ID, s2.ID} {
+		s, err := suite.repo.SessionGetByID(suite.ctx(), id)
+		suite.NoError(err)
+		suite.NotNil(s.RevokedAt)
+	}
+	s, err := suite.repo.SessionGetByID(suite.ctx(), s3.ID)
+	suite.NoError(err)
+	suite.Nil(s.RevokedAt)
+
+	_, err = suite.repo.AccessTokenUse(suite.ctx(), "token1")
+	suite.ErrorIs(err, errs.ErrNotFound)
+	_, err = suite.repo.AccessTokenUse(suite.ctx(), "token2")
+	suite.NoError(err)
+
+	u, err := suite.repo.UserGetByID(suite.ctx(), 1)
+	suite.NoError(err)
+	suite.False(u.TokensValidAfter.IsZero())
+
+	suite.ErrorIs(suite.repo.SessionRevokeAll(suite.ctx(), 1000), errs.ErrNotFound)
+}
+
+func (suite *pgxRepoSuite) TestSessionGetByUser() {
+	suite.NoError(suite.repo.SessionCreate(suite.ctx(), &models.Session{UserID: 1, RefreshHash: "hash1"}, 3600))
+	suite.NoError(suite.repo.SessionCreate(suite.ctx(), &models.Session{UserID: 1, RefreshHash: "hash2"}, 3600))
+	suite.NoError(suite.repo.SessionRevokeAll(suite.ctx(), 1))
+
+	list, err := suite.repo.SessionGetByUser(suite.ctx(), 1)
+	suite.NoError(err)
+	suite.Require().Len(list, 2)
+	suite.NotNil(list[0].RevokedAt)
+}
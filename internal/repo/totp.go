@@ -0,0 +1,265 @@
+package repo
+
+import (
+	"context"
+	"database/sql"
+
+	"gophermart-loyalty/internal/models"
+)
+
+// stmtTOTPGet - возвращает секрет TOTP пользователя.
+//    $1 - user_id
+// Возвращает user_id, secret, confirmed_at, last_used_step, created_at.
+var stmtTOTPGet = registerStatement(`
+	SELECT user_id, secret, confirmed_at, last_used_step, created_at FROM user_totp
+	WHERE user_id = $1
+`)
+
+// TOTPGet - возвращает секрет TOTP пользователя.
+// Если пользователь не начинал подключение двухфакторной аутентификации, возвращает errs.ErrNotFound.
+func (r *PGXRepo) TOTPGet(ctx context.Context, userID uint64) (*models.TOTP, error) {
+	t := &models.TOTP{}
+	err := r.statements[stmtTOTPGet].
+		QueryRowContext(ctx, userID).
+		Scan(&t.UserID, &t.Secret, &t.ConfirmedAt, &t.LastUsedStep, &t.CreatedAt)
+	if err != nil {
+		return nil, r.handleError(ctx, err)
+	}
+	return t, nil
+}
+
+// stmtTOTPEnroll - сохраняет новый секрет TOTP пользователя, если двухфакторная аутентификация не включена.
+// Неподтвержденный секрет заменяется новым.
+//    $1 - user_id
+//    $2 - secret
+// Возвращает created_at.
+var stmtTOTPEnroll = registerStatement(`
+	INSERT INTO user_totp (user_id, secret)
+	VALUES ($1, $2)
+	ON CONFLICT (user_id) DO UPDATE
+	SET secret = excluded.secret, last_used_step = 0, created_at = now()
+	WHERE user_totp.confirmed_at IS NULL
+	RETURNING created_at
+`)
+
+// TOTPEnroll - сохраняет новый неподтвержденный секрет TOTP пользователя.
+// Если двухфакторная аутентификация уже включена или пользователь не найден, возвращает errs.ErrNotFound.
+func (r *PGXRepo) TOTPEnroll(ctx context.Context, t *models.TOTP) error {
+	err := r.statements[stmtTOTPEnroll].
+		QueryRowContext(ctx, t.UserID, t.Secret).
+		Scan(&t.CreatedAt)
+	if err != nil {
+		return r.handleError(ctx, err)
+	}
+	t.ConfirmedAt = nil
+	t.LastUsedStep = 0
+	return nil
+}
+
+// stmtTOTPConfirm - подтверждает секрет TOTP пользователя.
+//    $1 - user_id
+//    $2 - шаг времени кода, которым подтвержден секрет
+// Возвращает confirmed_at.
+var stmtTOTPConfirm = registerStatement(`
+	UPDATE user_totp
+	SET confirmed_at = now(), last_used_step = $2
+	WHERE user_id = $1 AND confirmed_at IS NULL
+	RETURNING confirmed_at
+`)
+
+// stmtTOTPRecoveryCodeCreate - создает код восстановления.
+//    $1 - user_id
+//    $2 - code_hash
+var stmtTOTPRecoveryCodeCreate = registerStatement(`
+	INSERT INTO totp_recovery_codes (user_id, code_hash)
+	VALUES ($1, $2)
+`)
+
+// TOTPConfirm - подтверждает секрет TOTP пользователя кодом шага step и сохраняет хэши кодов восстановления.
+// Подтверждение и сохранение кодов восстановления выполняются в одной транзакции.
+// Если неподтвержденный секрет не найден, возвращает errs.ErrNotFound.
+func (r *PGXRepo) TOTPConfirm(ctx context.Context, userID uint64, step int64, recoveryHashes []string) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return r.handleError(ctx, err)
+	}
+	//goland:noinspection ALL
+	defer tx.Rollback()
+
+	err = tx.Stmt(r.statements[stmtTOTPConfirm]).
+		QueryRowContext(ctx, userID, step).
+		Scan(&sql.NullTime{})
+	if err != nil {
+		return r.handleError(ctx, err)
+	}
+
+	stmt := tx.Stmt(r.statements[stmtTOTPRecoveryCodeCreate])
+	for _, hash := range recoveryHashes {
+		if _, err = stmt.ExecContext(ctx, userID, hash); err != nil {
+			return r.handleError(ctx, err)
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return r.handleError(ctx, err)
+	}
+	return nil
+}
+
+// stmtTOTPUseStep - отмечает использование кода TOTP шага времени, если коды этого шага еще не использовались.
+//    $1 - user_id
+//    $2 - шаг времени кода
+// Возвращает user_id.
+var stmtTOTPUseStep = registerStatement(`
+	UPDATE user_totp
+	SET last_used_step = $2
+	WHERE user_id = $1 AND confirmed_at IS NOT NULL AND last_used_step < $2
+	RETURNING user_id
+`)
+
+// TOTPUseStep - отмечает использование кода TOTP шага времени step.
+// Код каждого шага может быть использован только один раз, что защищает от повторного использования перехваченного кода.
+// Если код этого или более позднего шага уже использовался, возвращает errs.ErrNotFound.
+func (r *PGXRepo) TOTPUseStep(ctx context.Context, userID uint64, step int64) error {
+	err := r.statements[stmtTOTPUseStep].
+		QueryRowContext(ctx, userID, step).
+		Scan(&sql.NullInt64{})
+	if err != nil {
+		return r.handleError(ctx, err)
+	}
+	return nil
+}
+
+// stmtTOTPRecoveryCodeUse - использует код восстановления.
+//    $1 - user_id
+//    $2 - code_hash
+// Возвращает id кода восстановления.
+var stmtTOTPRecoveryCodeUse = registerStatement(`
+	UPDATE totp_recovery_codes
+	SET used_at = now()
+	WHERE id = (
+		SELECT id FROM totp_recovery_codes
+		WHERE user_id = $1 AND code_hash = $2 AND used_at IS NULL
+		LIMIT 1
+	) AND used_at IS NULL
+	RETURNING id
+`)
+
+// TOTPRecoveryCodeUse - использует код восстановления пользователя. Каждый код может быть использован только один раз.
+// Если неиспользованный код не найден, возвращает errs.ErrNotFound.
+func (r *PGXRepo) TOTPRecoveryCodeUse(ctx context.Context, userID uint64, codeHash string) error {
+	err := r.statements[stmtTOTPRecoveryCodeUse].
+		QueryRowContext(ctx, userID, codeHash).
+		Scan(&sql.NullInt64{})
+	if err != nil {
+		return r.handleError(ctx, err)
+	}
+	return nil
+}
+
+// stmtTOTPDelete - удаляет секрет TOTP и коды восстановления пользователя.
+//    $1 - user_id
+// Возвращает user_id.
+var stmtTOTPDelete = registerStatement(`
+	DELETE FROM user_totp
+	WHERE user_id = $1
+	RETURNING user_id
+`)
+
+// TOTPDelete - отключает двухфакторную аутентификацию пользователя.
+// Коды восстановления удаляются вместе с секретом.
+// Если секрет не найден, возвращает errs.ErrNotFound.
+func (r *PGXRepo) TOTPDelete(ctx context.Context, userID uint64) error {
+	err := r.statements[stmtTOTPDelete].
+		QueryRowContext(ctx, userID).
+		Scan(&sql.NullInt64{})
+	if err != nil {
+		return r.handleError(ctx, err)
+	}
+	return nil
+}
+
+// stmtLoginChallengeCreate - создает незавершенный вход, ожидающий второй фактор.
+//    $1 - user_id
+//    $2 - token_hash
+//    $3 - время жизни в секундах
+// Возвращает id, expires_at, created_at.
+var stmtLoginChallengeCreate = registerStatement(`
+	INSERT INTO login_challenges (user_id, token_hash, expires_at)
+	VALUES ($1, $2, now() + make_interval(secs => $3))
+	RETURNING id, expires_at, created_at
+`)
+
+// LoginChallengeCreate - создает незавершенный вход со временем жизни ttlSeconds.
+// Время истечения отсчитывается от текущего времени БД.
+// Если пользователь не найден, возвращает errs.ErrNotFound.
+func (r *PGXRepo) LoginChallengeCreate(ctx context.Context, c *models.LoginChallenge, ttlSeconds int64) error {
+	err := r.statements[stmtLoginChallengeCreate].
+		QueryRowContext(ctx, c.UserID, c.TokenHash, ttlSeconds).
+		Scan(&c.ID, &c.ExpiresAt, &c.CreatedAt)
+	if err != nil {
+		return r.handleError(ctx, err)
+	}
+	return nil
+}
+
+// stmtLoginChallengeGet - возвращает действующий незавершенный вход.
+//    $1 - token_hash
+//    $2 - максимальное число неудачных попыток ввода кода
+// Возвращает id, user_id, token_hash, attempts, expires_at, used_at, created_at.
+var stmtLoginChallengeGet = registerStatement(`
+	SELECT id, user_id, token_hash, attempts, expires_at, used_at, created_at FROM login_challenges
+	WHERE token_hash = $1 AND used_at IS NULL AND expires_at > now() AND attempts < $2
+`)
+
+// LoginChallengeGet - возвращает действующий незавершенный вход по хэшу токена.
+// Незавершенный вход действует, пока он не использован, не истек и число неудачных попыток меньше maxAttempts.
+// Если действующий вход не найден, возвращает errs.ErrNotFound.
+func (r *PGXRepo) LoginChallengeGet(ctx context.Context, tokenHash string, maxAttempts int) (*models.LoginChallenge, error) {
+	c := &models.LoginChallenge{}
+	err := r.statements[stmtLoginChallengeGet].
+		QueryRowContext(ctx, tokenHash, maxAttempts).
+		Scan(&c.ID, &c.UserID, &c.TokenHash, &c.Attempts, &c.ExpiresAt, &c.UsedAt, &c.CreatedAt)
+	if err != nil {
+		return nil, r.handleError(ctx, err)
+	}
+	return c, nil
+}
+
+// stmtLoginChallengeFail - увеличивает счетчик неудачных попыток ввода кода.
+//    $1 - id
+var stmtLoginChallengeFail = registerStatement(`
+	UPDATE login_challenges
+	SET attempts = attempts + 1
+	WHERE id = $1
+`)
+
+// LoginChallengeFail - регистрирует неудачную попытку ввода кода для незавершенного входа.
+func (r *PGXRepo) LoginChallengeFail(ctx context.Context, challengeID uint64) error {
+	if _, err := r.statements[stmtLoginChallengeFail].ExecContext(ctx, challengeID); err != nil {
+		return r.handleError(ctx, err)
+	}
+	return nil
+}
+
+// stmtLoginChallengeConsume - завершает вход.
+//    $1 - id
+// Возвращает id.
+var stmtLoginChallengeConsume = registerStatement(`
+	UPDATE login_challenges
+	SET used_at = now()
+	WHERE id = $1 AND used_at IS NULL
+	RETURNING id
+`)
+
+// LoginChallengeConsume - помечает незавершенный вход использованным.
+// Если вход уже завершен, возвращает errs.ErrNotFound, поэтому один вход может быть завершен только один раз.
+func (r *PGXRepo) LoginChallengeConsume(ctx context.Context, challengeID uint64) error {
+	err := r.statements[stmtLoginChallengeConsume].
+		QueryRowContext(ctx, challengeID).
+		Scan(&sql.NullInt64{})
+	if err != nil {
+		return r.handleError(ctx, err)
+	}
+	return nil
+}
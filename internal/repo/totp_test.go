@@ -0,0 +1,96 @@
+package repo
+
+import (
+	"time"
+
+	"gophermart-loyalty/internal/errs"
+	"gophermart-loyalty/internal/models"
+)
+
+func (suite *pgxRepoSuite) TestTOTPEnrollConfirm() {
+	suite.Run("not enrolled", func() {
+		_, err := suite.repo.TOTPGet(suite.ctx(), 1)
+		suite.ErrorIs(err, errs.ErrNotFound)
+	})
+
+	suite.Run("enroll", func() {
+		suite.NoError(suite.repo.TOTPEnroll(suite.ctx(), &models.TOTP{UserID: 1, Secret: "secret1"}))
+		// неподтвержденный секрет заменяется новым
+		suite.NoError(suite.repo.TOTPEnroll(suite.ctx(), &models.TOTP{UserID: 1, Secret: "secret2"}))
+		t, err := suite.repo.TOTPGet(suite.ctx(), 1)
+		suite.NoError(err)
+		suite.Equal("secret2", t.Secret)
+		suite.False(t.Enabled())
+	})
+
+	suite.Run("confirm", func() {
+		suite.NoError(suite.repo.TOTPConfirm(suite.ctx(), 1, 100, []string{"code1", "code2"}))
+		t, err := suite.repo.TOTPGet(suite.ctx(), 1)
+		suite.NoError(err)
+		suite.True(t.Enabled())
+		suite.Equal(int64(100), t.LastUsedStep)
+	})
+
+	suite.Run("confirmed secret can not be replaced", func() {
+		suite.ErrorIs(suite.repo.TOTPEnroll(suite.ctx(), &models.TOTP{UserID: 1, Secret: "secret3"}), errs.ErrNotFound)
+		suite.ErrorIs(suite.repo.TOTPConfirm(suite.ctx(), 1, 101, nil), errs.ErrNotFound)
+	})
+
+	suite.Run("unknown user", func() {
+		suite.ErrorIs(suite.repo.TOTPEnroll(suite.ctx(), &models.TOTP{UserID: 1000, Secret: "secret"}), errs.ErrNotFound)
+	})
+}
+
+func (suite *pgxRepoSuite) TestTOTPUse() {
+	suite.NoError(suite.repo.TOTPEnroll(suite.ctx(), &models.TOTP{UserID: 1, Secret: "secret"}))
+	suite.NoError(suite.repo.TOTPConfirm(suite.ctx(), 1, 100, []string{"code1", "code2"}))
+
+	suite.Run("step", func() {
+		suite.NoError(suite.repo.TOTPUseStep(suite.ctx(), 1, 101))
+		// код того же или более раннего шага не принимается повторно
+		suite.ErrorIs(suite.repo.TOTPUseStep(suite.ctx(), 1, 101), errs.ErrNotFound)
+		suite.ErrorIs(suite.repo.TOTPUseStep(suite.ctx(), 1, 100), errs.ErrNotFound)
+	})
+
+	suite.Run("recovery code", func() {
+		suite.NoError(suite.repo.TOTPRecoveryCodeUse(suite.ctx(), 1, "code1"))
+		suite.ErrorIs(suite.repo.TOTPRecoveryCodeUse(suite.ctx(), 1, "code1"), errs.ErrNotFound)
+		suite.ErrorIs(suite.repo.TOTPRecoveryCodeUse(suite.ctx(), 2, "code2"), errs.ErrNotFound)
+	})
+
+	suite.Run("delete", func() {
+		suite.NoError(suite.repo.TOTPDelete(suite.ctx(), 1))
+		suite.ErrorIs(suite.repo.TOTPRecoveryCodeUse(suite.ctx(), 1, "code2"), errs.ErrNotFound)
+		suite.ErrorIs(suite.repo.TOTPDelete(suite.ctx(), 1), errs.ErrNotFound)
+	})
+}
+
+func (suite *pgxRepoSuite) TestLoginChallenge() {
+	c := &models.LoginChallenge{UserID: 1, TokenHash: "hash1"}
+	suite.NoError(suite.repo.LoginChallengeCreate(suite.ctx(), c, 300))
+	suite.NotZero(c.ID)
+	suite.True(c.ExpiresAt.After(c.CreatedAt))
+
+	suite.Run("attempts", func() {
+		suite.NoError(suite.repo.LoginChallengeFail(suite.ctx(), c.ID))
+		got, err := suite.repo.LoginChallengeGet(suite.ctx(), "hash1", 5)
+		suite.NoError(err)
+		suite.Equal(1, got.Attempts)
+		_, err = suite.repo.LoginChallengeGet(suite.ctx(), "hash1", 1)
+		suite.ErrorIs(err, errs.ErrNotFound)
+	})
+
+	suite.Run("consume", func() {
+		suite.NoError(suite.repo.LoginChallengeConsume(suite.ctx(), c.ID))
+		suite.ErrorIs(suite.repo.LoginChallengeConsume(suite.ctx(), c.ID), errs.ErrNotFound)
+		_, err := suite.repo.LoginChallengeGet(suite.ctx(), "hash1", 5)
+		suite.ErrorIs(err, errs.ErrNotFound)
+	})
+
+	suite.Run("expired", func() {
+		suite.NoError(suite.repo.LoginChallengeCreate(suite.ctx(), &models.LoginChallenge{UserID: 1, TokenHash: "hash2"}, 1))
+		time.Sleep(1100 * time.Millisecond)
+		_, err := suite.repo.LoginChallengeGet(suite.ctx(), "hash2", 5)
+		suite.ErrorIs(err, errs.ErrNotFound)
+	})
+}
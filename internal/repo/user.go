@@ -3,26 +3,38 @@ package repo
 import (
 	"context"
 	"database/sql"
+	"errors"
+	"time"
 
+	"github.com/shopspring/decimal"
+
+	"gophermart-loyalty/internal/errs"
 	"gophermart-loyalty/internal/models"
 )
 
-// stmtUserCreate - создает пользователя.
+// stmtUserCreate - создает пользователя, если логин не принадлежал недавно удаленной учетной записи.
 //    $1 - username
 //    $2 - pass_hash
-// Возвращает id, balance, withdrawn, created_at, updated_at.
+// Возвращает id, balance, withdrawn, created_at, updated_at, tokens_valid_after, role.
 var stmtUserCreate = registerStatement(`
 	INSERT INTO users (username, pass_hash) 
-	VALUES ($1, $2) 
-	RETURNING id, balance, withdrawn, created_at, updated_at
+	SELECT $1, $2
+	WHERE NOT EXISTS (
+		SELECT 1 FROM deleted_logins
+		WHERE login_hash = encode(sha256(convert_to($1, 'UTF8')), 'hex') AND reusable_after > now()
+	)
+	RETURNING id, balance, withdrawn, created_at, updated_at, tokens_valid_after, role
 `)
 
-// UserCreate - создает пользователя по логину и хэшу пароля
+// UserCreate - создает пользователя по логину и хэшу пароля.
+// Если логин занят или принадлежал учетной записи, удаленной меньше срока назад, возвращает errs.ErrUserAlreadyExists.
 func (r *PGXRepo) UserCreate(ctx context.Context, u *models.User) error {
 	err := r.statements[stmtUserCreate].
 		QueryRowContext(ctx, u.Login, u.PassHash).
-		Scan(&u.ID, &u.Balance, &u.Withdrawn, &u.CreatedAt, &u.UpdatedAt)
-	if err != nil {
+		Scan(&u.ID, &u.Balance, &u.Withdrawn, &u.CreatedAt, &u.UpdatedAt, &u.TokensValidAfter, &u.Role)
+	if errors.Is(err, sql.ErrNoRows) {
+		return errs.ErrUserAlreadyExists
+	} else if err != nil {
 		return r.handleError(ctx, err)
 	}
 	return nil
@@ -30,92 +42,102 @@ func (r *PGXRepo) UserCreate(ctx context.Context, u *models.User) error {
 
 // stmtUserGetByID - возвращает пользователя по id.
 //    $1 - id
-// Возвращает id, username, pass_hash, balance, withdrawn, created_at, updated_at.
+// Возвращает id, username, pass_hash, balance, withdrawn, created_at, updated_at, tokens_valid_after, role, deleted_at,
+// status, blocked_at, block_reason, pending.
 var stmtUserGetByID = registerStatement(`
-	SELECT id, username, pass_hash, balance, withdrawn, created_at, updated_at FROM users
+	SELECT id, username, pass_hash, balance, withdrawn, created_at, updated_at, tokens_valid_after, role, deleted_at,
+		status, blocked_at, block_reason, pending
+	FROM users
 	WHERE id = $1
 `)
 
-// UserGetByID - возвращает пользователя по id.
+// UserGetByID - возвращает пользователя по id, в том числе удаленного.
 func (r *PGXRepo) UserGetByID(ctx context.Context, userID uint64) (*models.User, error) {
 	u := &models.User{}
 	err := r.statements[stmtUserGetByID].
 		QueryRowContext(ctx, userID).
-		Scan(&u.ID, &u.Login, &u.PassHash, &u.Balance, &u.Withdrawn, &u.CreatedAt, &u.UpdatedAt)
+		Scan(&u.ID, &u.Login, &u.PassHash, &u.Balance, &u.Withdrawn, &u.CreatedAt, &u.UpdatedAt, &u.TokensValidAfter, &u.Role, &u.DeletedAt,
+			&u.Status, &u.BlockedAt, &u.BlockReason, &u.Pending)
 	if err != nil {
 		return nil, r.handleError(ctx, err)
 	}
 	return u, nil
 }
 
-// stmtUserGetByLogin - возвращает пользователя по логину.
+// stmtUserGetByLogin - возвращает неудаленного пользователя по логину.
 //    $1 - username
-// Возвращает id, username, pass_hash, balance, withdrawn, created_at, updated_at.
+// Возвращает id, username, pass_hash, balance, withdrawn, created_at, updated_at, tokens_valid_after, role,
+// status, blocked_at, block_reason, pending.
 var stmtUserGetByLogin = registerStatement(`
-	SELECT id, username, pass_hash, balance, withdrawn, created_at, updated_at  FROM users
-	WHERE username = $1
+	SELECT id, username, pass_hash, balance, withdrawn, created_at, updated_at, tokens_valid_after, role,
+		status, blocked_at, block_reason, pending
+	FROM users
+	WHERE username = $1 AND deleted_at IS NULL
 `)
 
-// UserGetByLogin - возвращает пользователя по логину.
+// UserGetByLogin - возвращает неудаленного пользователя по логину.
 func (r *PGXRepo) UserGetByLogin(ctx context.Context, login string) (*models.User, error) {
 	u := &models.User{}
 	err := r.statements[stmtUserGetByLogin].
 		QueryRowContext(ctx, login).
-		Scan(&u.ID, &u.Login, &u.PassHash, &u.Balance, &u.Withdrawn, &u.CreatedAt, &u.UpdatedAt)
+		Scan(&u.ID, &u.Login, &u.PassHash, &u.Balance, &u.Withdrawn, &u.CreatedAt, &u.UpdatedAt, &u.TokensValidAfter, &u.Role,
+			&u.Status, &u.BlockedAt, &u.BlockReason, &u.Pending)
 	if err != nil {
 		return nil, r.handleError(ctx, err)
 	}
 	return u, nil
 }
 
-// stmtUserLock - блокирует пользователя для обновления другими транзакциями.
+// stmtUserLock - блокирует неудаленного пользователя для обновления другими транзакциями.
 //    $1 - id пользователя
-// Возвращает id пользователя.
+// Возвращает статус пользователя.
 // ВАЖНО: может вызываться только внутри транзакции.
 var stmtUserLock = registerStatement(`
-	SELECT id FROM users WHERE id = $1 FOR UPDATE 
+	SELECT status FROM users WHERE id = $1 AND deleted_at IS NULL FOR UPDATE 
 `)
 
 // userLockTx - блокирует пользователя для обновления другими транзакциями.
+// Возвращает статус учетной записи пользователя.
+// Если пользователь не найден или удален, возвращает errs.ErrNotFound.
 // ВАЖНО: может вызываться только внутри транзакции
-func (r *PGXRepo) userLockTx(ctx context.Context, tx *sql.Tx, userID uint64) error {
+func (r *PGXRepo) userLockTx(ctx context.Context, tx *sql.Tx, userID uint64) (models.UserStatus, error) {
+	var status models.UserStatus
 	if err := tx.Stmt(r.statements[stmtUserLock]).
 		QueryRowContext(ctx, userID).
-		Scan(&sql.NullInt64{}); err != nil {
-		return r.handleError(ctx, err)
+		Scan(&status); err != nil {
+		return "", r.handleError(ctx, err)
 	}
-	return nil
+	return status, nil
 }
 
-// stmtUserUpdateBalance - обновляет баланс пользователя
+// stmtUserUpdateBalance - изменяет закэшированные баланс, сумму списаний и сумму удержанных баллов пользователя.
 //    $1 - id пользователя
+//    $2 - изменение баланса
+//    $3 - изменение суммы списаний
+//    $4 - изменение суммы удержанных баллов
 // Возвращает id пользователя.
 // ВАЖНО: может вызываться только внутри транзакции и только после вызова PGXRepo.userLockTx
 var stmtUserUpdateBalance = registerStatement(`
-	WITH
-	    total_accrued AS (
-	    	SELECT coalesce(sum(amount), 0) AS val FROM operations
-			WHERE user_id = $1 AND status = 'PROCESSED' AND amount > 0
-		),
-	    total_withdrawn AS (
-	        SELECT coalesce(sum(amount), 0)  AS val  FROM operations
-			WHERE  user_id = $1 AND status NOT IN ('INVALID', 'CANCELED') AND amount < 0
-	    )
 	UPDATE users
 	SET
-	    balance = total_accrued.val + total_withdrawn.val,
-	    withdrawn = 0 - total_withdrawn.val,
+	    balance = balance + $2,
+	    withdrawn = withdrawn + $3,
+	    pending = pending + $4,
 		updated_at = now()
-	FROM  total_accrued, total_withdrawn
 	WHERE id = $1
 	RETURNING id
 `)
 
-// userUpdateBalance - обновляет баланс пользователя.
+// userUpdateBalanceTx - изменяет закэшированные баланс, сумму списаний и сумму удержанных баллов пользователя
+// на balanceDelta, withdrawnDelta и pendingDelta.
+// Баланс является проекцией журнала проводок и изменяется только вместе с записью проводки в PGXRepo.ledgerPostTx
+// или пересчитывается по журналу проводок при сверке в PGXRepo.UserBalanceRepair.
+// Если доступный баланс (баланс за вычетом удержанных баллов) становится отрицательным,
+// возвращает errs.ErrUserBalanceNegative.
 // ВАЖНО: может вызываться только внутри транзакции и только после вызова PGXRepo.userLockTx
-func (r *PGXRepo) userUpdateBalanceTx(ctx context.Context, tx *sql.Tx, userID uint64) error {
+func (r *PGXRepo) userUpdateBalanceTx(ctx context.Context, tx *sql.Tx, userID uint64, balanceDelta, withdrawnDelta, pendingDelta decimal.Decimal) error {
 	err := tx.Stmt(r.statements[stmtUserUpdateBalance]).
-		QueryRowContext(ctx, userID).
+		QueryRowContext(ctx, userID, balanceDelta, withdrawnDelta, pendingDelta).
 		Scan(&sql.NullInt64{})
 	if err != nil {
 		return r.handleError(ctx, err)
@@ -123,24 +145,44 @@ func (r *PGXRepo) userUpdateBalanceTx(ctx context.Context, tx *sql.Tx, userID ui
 	return nil
 }
 
-// stmtUserBalanceHistoryGetByID - возвращает список операций пользователя, учитывающихся в балансе.
+// balanceOperationsCond - условие отбора операций, учитывающихся в балансе пользователя:
+// обработанных начислений и не отклоненных и не отмененных списаний (см. models.Operation.BalanceEffect).
+// Совпадает с условием частичного индекса balance_history_idx.
+const balanceOperationsCond = `(status = 'PROCESSED' AND amount >= 0) OR (status NOT IN ('INVALID', 'CANCELED') AND amount < 0)`
+
+// stmtUserBalanceHistoryGetByID - возвращает список операций пользователя, учитывающихся в балансе,
+// по убыванию времени обработки и id.
 //    $1 - user_id
+//    $2 - op_type или NULL
+//    $3 - status или NULL
+//    $4 - начало периода по updated_at включительно или NULL
+//    $5 - конец периода по updated_at, не включая, или NULL
+//    $6, $7 - updated_at и id операции, после которой начинается выборка, или NULL
+//    $8 - максимальное число операций или NULL
 // Возвращает id, user_id, op_type, status, amount, description,
-// order_number, promo_id, created_at, updated_at операции.
+// order_number, promo_id, counterparty_id, created_at, updated_at, processed_at операции.
 var stmtUserBalanceHistoryGetByID = registerStatement(`
-	SELECT id, user_id, op_type, status, amount, description, order_number, promo_id, created_at, updated_at
+	SELECT id, user_id, op_type, status, amount, description, order_number, promo_id, counterparty_id, created_at, updated_at,
+		processed_at
 	FROM operations
-	WHERE user_id = $1 AND (
-	    (status = 'PROCESSED' AND amount >= 0)
-	    OR 
-	    (status NOT IN ('INVALID', 'CANCELED') AND amount < 0)
-	)
-	ORDER BY updated_at DESC
+	WHERE user_id = $1 AND (` + balanceOperationsCond + `)
+		AND ($2::operation_type IS NULL OR op_type = $2)
+		AND ($3::operation_status IS NULL OR status = $3)
+		AND ($4::timestamp IS NULL OR updated_at >= $4)
+		AND ($5::timestamp IS NULL OR updated_at < $5)
+		AND ($6::timestamp IS NULL OR (updated_at, id) < ($6, $7::integer))
+	ORDER BY updated_at DESC, id DESC
+	LIMIT $8
 `)
 
-// UserBalanceHistoryGetByID - возвращает список операций пользователя, учитывающихся в балансе.
-func (r *PGXRepo) UserBalanceHistoryGetByID(ctx context.Context, userID uint64) ([]*models.Operation, error) {
-	rows, err := r.statements[stmtUserBalanceHistoryGetByID].QueryContext(ctx, userID)
+// UserBalanceHistoryGetByID - возвращает список операций пользователя, учитывающихся в балансе,
+// выбранных по параметрам q.
+func (r *PGXRepo) UserBalanceHistoryGetByID(ctx context.Context, userID uint64, q *models.OperationQuery) ([]*models.Operation, error) {
+	var t interface{}
+	if q != nil && q.Type != nil {
+		t = *q.Type
+	}
+	rows, err := r.statements[stmtUserBalanceHistoryGetByID].QueryContext(ctx, operationQueryArgs(userID, t, q)...)
 	if err != nil {
 		return nil, r.handleError(ctx, err)
 	}
@@ -153,3 +195,528 @@ func (r *PGXRepo) UserBalanceHistoryGetByID(ctx context.Context, userID uint64)
 	}
 	return ops, nil
 }
+
+// userBalanceHistoryTx - возвращает список всех операций пользователя, учитывающихся в балансе.
+// ВАЖНО: может вызываться только внутри транзакции
+func (r *PGXRepo) userBalanceHistoryTx(ctx context.Context, tx *sql.Tx, userID uint64) ([]*models.Operation, error) {
+	rows, err := tx.Stmt(r.statements[stmtUserBalanceHistoryGetByID]).QueryContext(ctx, operationQueryArgs(userID, nil, nil)...)
+	if err != nil {
+		return nil, r.handleError(ctx, err)
+	}
+	//goland:noinspection GoUnhandledErrorResult
+	defer rows.Close()
+
+	ops, err := r.operationScanRows(ctx, rows)
+	if err != nil {
+		return nil, r.handleError(ctx, err)
+	}
+	return ops, nil
+}
+
+// stmtUserBalanceAsOf - возвращает баланс и сумму списаний пользователя по сумме проводок по его счету,
+// записанных до заданного момента. Проводки не изменяются, поэтому баланс на прошедший момент не меняется.
+// В сумме списаний учитываются только баллы, переведенные на счета списаний (см. models.LedgerAccount.Sink).
+//    $1 - user_id
+//    $2 - момент времени, проводки записанные в этот момент и позже не учитываются
+// Возвращает баланс и сумму списаний.
+var stmtUserBalanceAsOf = registerStatement(`
+	SELECT coalesce(sum(lp.amount), 0),
+		0 - coalesce(sum(lp.amount) FILTER (WHERE lp.code IN (` + ledgerSinkAccounts + `)), 0)
+	FROM (` + ledgerUserPostings + `) lp
+	WHERE lp.user_id = $1 AND lp.created_at < $2
+`)
+
+// UserBalanceAsOf - возвращает баланс пользователя на момент asOf по сумме проводок, записанных до этого момента.
+// Изменение операции после asOf записывается новой проводкой и на баланс на момент asOf не влияет.
+func (r *PGXRepo) UserBalanceAsOf(ctx context.Context, userID uint64, asOf time.Time) (*models.BalanceSnapshot, error) {
+	b := &models.BalanceSnapshot{AsOf: asOf}
+	err := r.statements[stmtUserBalanceAsOf].
+		QueryRowContext(ctx, userID, asOf).
+		Scan(&b.Balance, &b.Withdrawn)
+	if err != nil {
+		return nil, r.handleError(ctx, err)
+	}
+	return b, nil
+}
+
+// stmtUserLedgerPostingsGet - возвращает проводки по счету пользователя за период в порядке записи
+// вместе с операциями, по которым они записаны.
+//    $1 - user_id
+//    $2 - начало периода по времени проводки включительно
+//    $3 - конец периода по времени проводки, не включая
+// Возвращает id, код системного счета, сумму и время проводки,
+// id, op_type, status, amount, description, order_number, created_at, updated_at операции.
+var stmtUserLedgerPostingsGet = registerStatement(`
+	SELECT lp.id, lp.code, lp.amount, lp.created_at,
+		o.id, o.op_type, o.status, o.amount, o.description, o.order_number, o.created_at, o.updated_at
+	FROM (` + ledgerUserPostings + `) lp
+		JOIN operations o ON o.id = lp.operation_id
+	WHERE lp.user_id = $1 AND lp.created_at >= $2 AND lp.created_at < $3
+	ORDER BY lp.created_at, lp.id
+`)
+
+// UserLedgerPostingsGet - возвращает проводки по счету пользователя, записанные с from включительно
+// до to, не включая, в порядке записи.
+func (r *PGXRepo) UserLedgerPostingsGet(ctx context.Context, userID uint64, from, to time.Time) ([]*models.LedgerPosting, error) {
+	rows, err := r.statements[stmtUserLedgerPostingsGet].QueryContext(ctx, userID, from, to)
+	if err != nil {
+		return nil, r.handleError(ctx, err)
+	}
+	//goland:noinspection GoUnhandledErrorResult
+	defer rows.Close()
+
+	var postings []*models.LedgerPosting
+	for rows.Next() {
+		p := &models.LedgerPosting{Operation: &models.Operation{UserID: userID}}
+		err = rows.Scan(&p.ID, &p.Account, &p.Amount, &p.CreatedAt,
+			&p.Operation.ID, &p.Operation.Type, &p.Operation.Status, &p.Operation.Amount, &p.Operation.Description,
+			&p.Operation.OrderNumber, &p.Operation.CreatedAt, &p.Operation.UpdatedAt)
+		if err != nil {
+			return nil, r.handleError(ctx, err)
+		}
+		postings = append(postings, p)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, r.handleError(ctx, err)
+	}
+	return postings, nil
+}
+
+// stmtUserBalanceCheck - сверяет закэшированные баланс и сумму списаний пользователей с остатками их счетов
+// в журнале проводок, а сумму удержанных баллов - с суммой удержанных начислений.
+// Кроме того, проверяет, что сумма проводок по каждой операции совпадает с ее влиянием на баланс.
+// В сумме списаний учитываются только баллы, переведенные на счета списаний (см. models.LedgerAccount.Sink).
+// Пользователи выбираются в порядке возрастания id.
+//    $1 - id пользователя, после которого начинается выборка
+//    $2 - максимальное число пользователей
+// Возвращает id, balance, withdrawn, pending пользователя,
+// баланс и сумму списаний по журналу проводок, сумму удержанных начислений
+// и число операций, сумма проводок по которым не совпадает с их влиянием на баланс.
+var stmtUserBalanceCheck = registerStatement(`
+	SELECT u.id, u.balance, u.withdrawn, u.pending,
+		coalesce(l.balance, 0), coalesce(l.withdrawn, 0), coalesce(o.pending, 0), coalesce(o.unposted, 0)
+	FROM (SELECT id, balance, withdrawn, pending FROM users WHERE id > $1 ORDER BY id LIMIT $2) u
+		LEFT JOIN LATERAL (
+			SELECT sum(lp.amount) AS balance,
+				0 - coalesce(sum(lp.amount) FILTER (WHERE lp.code IN (` + ledgerSinkAccounts + `)), 0) AS withdrawn
+			FROM (` + ledgerUserPostings + `) lp
+			WHERE lp.user_id = u.id
+		) l ON true
+		LEFT JOIN LATERAL (
+			SELECT sum(amount) FILTER (WHERE status = 'PROCESSED' AND hold_until IS NOT NULL) AS pending,
+				count(*) FILTER (WHERE ` + operationBalanceEffect + ` <> coalesce(p.posted, 0)) AS unposted
+			FROM operations
+				LEFT JOIN (
+					SELECT lp.operation_id, sum(lp.amount) AS posted
+					FROM (` + ledgerUserPostings + `) lp
+					WHERE lp.user_id = u.id
+					GROUP BY lp.operation_id
+				) p ON p.operation_id = operations.id
+			WHERE operations.user_id = u.id
+		) o ON true
+	ORDER BY u.id
+`)
+
+// UserBalanceCheck - сверяет закэшированные баланс и сумму списаний не более limit пользователей
+// с id больше afterID с журналом проводок.
+// Возвращает результаты сверки в порядке возрастания id пользователя.
+func (r *PGXRepo) UserBalanceCheck(ctx context.Context, afterID uint64, limit int) ([]*models.BalanceCheck, error) {
+	rows, err := r.statements[stmtUserBalanceCheck].QueryContext(ctx, afterID, limit)
+	if err != nil {
+		return nil, r.handleError(ctx, err)
+	}
+	//goland:noinspection GoUnhandledErrorResult
+	defer rows.Close()
+
+	var checks []*models.BalanceCheck
+	for rows.Next() {
+		c := &models.BalanceCheck{}
+		if err = rows.Scan(&c.UserID, &c.Balance, &c.Withdrawn, &c.Pending,
+			&c.ExpectedBalance, &c.ExpectedWithdrawn, &c.ExpectedPending, &c.Unposted); err != nil {
+			return nil, r.handleError(ctx, err)
+		}
+		checks = append(checks, c)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, r.handleError(ctx, err)
+	}
+	return checks, nil
+}
+
+// stmtUserBalanceLock - блокирует пользователя, в том числе удаленного, для обновления другими транзакциями.
+//    $1 - id пользователя
+// Возвращает balance, withdrawn, pending пользователя.
+// ВАЖНО: может вызываться только внутри транзакции.
+var stmtUserBalanceLock = registerStatement(`
+	SELECT balance, withdrawn, pending FROM users WHERE id = $1 FOR UPDATE
+`)
+
+// stmtUserBalanceRebuild - пересчитывает закэшированные баланс и сумму списаний пользователя по остатку
+// его счета в журнале проводок, а сумму удержанных баллов - по сумме удержанных начислений.
+// В сумме списаний учитываются только баллы, переведенные на счета списаний (см. models.LedgerAccount.Sink).
+//    $1 - id пользователя
+// Возвращает balance, withdrawn, pending пользователя после пересчета.
+// ВАЖНО: может вызываться только внутри транзакции и только после вызова stmtUserBalanceLock.
+var stmtUserBalanceRebuild = registerStatement(`
+	WITH
+		ledger AS (
+			SELECT coalesce(sum(lp.amount), 0) AS balance,
+				0 - coalesce(sum(lp.amount) FILTER (WHERE lp.code IN (` + ledgerSinkAccounts + `)), 0) AS withdrawn
+			FROM (` + ledgerUserPostings + `) lp
+			WHERE lp.user_id = $1
+		),
+		held AS (
+			SELECT coalesce(sum(amount), 0) AS pending
+			FROM operations
+			WHERE user_id = $1 AND status = 'PROCESSED' AND hold_until IS NOT NULL
+		)
+	UPDATE users
+	SET balance = ledger.balance, withdrawn = ledger.withdrawn, pending = held.pending, updated_at = now()
+	FROM ledger, held
+	WHERE id = $1
+	RETURNING users.balance, users.withdrawn, users.pending
+`)
+
+// UserBalanceRepair - исправляет расхождения баланса пользователя с журналом проводок.
+// Если сумма проводок по операции не совпадает с ее влиянием на баланс, в журнал записывается
+// корректирующая проводка (записанные проводки не изменяются). Корректирующие проводки не изменяют
+// и не проверяют закэшированный баланс, который может расходиться с журналом: после них закэшированные баланс
+// и сумма списаний пересчитываются по журналу проводок, а не по операциям.
+// Возвращает результат сверки: значения до исправления и значения по журналу проводок, которые были установлены.
+// Если пользователь не найден, возвращает errs.ErrNotFound.
+func (r *PGXRepo) UserBalanceRepair(ctx context.Context, userID uint64) (*models.BalanceCheck, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return nil, r.handleError(ctx, err)
+	}
+	//goland:noinspection ALL
+	defer tx.Rollback()
+
+	// Блокируем запись пользователя, чтобы журнал проводок не изменился до обновления баланса
+	c := &models.BalanceCheck{UserID: userID}
+	if err = tx.Stmt(r.statements[stmtUserBalanceLock]).
+		QueryRowContext(ctx, userID).
+		Scan(&c.Balance, &c.Withdrawn, &c.Pending); err != nil {
+		return nil, r.handleError(ctx, err)
+	}
+
+	// Дописываем недостающие проводки по операциям
+	if c.Unposted, err = r.ledgerCorrectTx(ctx, tx, userID); err != nil {
+		return nil, err
+	}
+
+	err = tx.Stmt(r.statements[stmtUserBalanceRebuild]).
+		QueryRowContext(ctx, userID).
+		Scan(&c.ExpectedBalance, &c.ExpectedWithdrawn, &c.ExpectedPending)
+	if err != nil {
+		return nil, r.handleError(ctx, err)
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, r.handleError(ctx, err)
+	}
+	return c, nil
+}
+
+// stmtUserUpdatePassHash - обновляет хэш пароля пользователя.
+//    $1 - id пользователя
+//    $2 - pass_hash
+// Возвращает id пользователя.
+var stmtUserUpdatePassHash = registerStatement(`
+	UPDATE users
+	SET pass_hash = $2, updated_at = now()
+	WHERE id = $1
+	RETURNING id
+`)
+
+// UserUpdatePassHash - обновляет хэш пароля пользователя.
+// Если пользователь не найден, возвращает errs.ErrNotFound.
+func (r *PGXRepo) UserUpdatePassHash(ctx context.Context, userID uint64, passHash string) error {
+	err := r.statements[stmtUserUpdatePassHash].
+		QueryRowContext(ctx, userID, passHash).
+		Scan(&sql.NullInt64{})
+	if err != nil {
+		return r.handleError(ctx, err)
+	}
+	return nil
+}
+
+// stmtUserUpgradePassHash - заменяет хэш пароля пользователя, если он не изменился.
+//    $1 - id пользователя
+//    $2 - текущий pass_hash
+//    $3 - новый pass_hash
+// Возвращает id пользователя.
+var stmtUserUpgradePassHash = registerStatement(`
+	UPDATE users
+	SET pass_hash = $3, updated_at = now()
+	WHERE id = $1 AND pass_hash = $2
+	RETURNING id
+`)
+
+// UserUpgradePassHash - заменяет хэш пароля пользователя oldHash на newHash без смены пароля.
+// Если пользователь не найден или его хэш пароля отличается от oldHash, возвращает errs.ErrNotFound.
+func (r *PGXRepo) UserUpgradePassHash(ctx context.Context, userID uint64, oldHash, newHash string) error {
+	err := r.statements[stmtUserUpgradePassHash].
+		QueryRowContext(ctx, userID, oldHash, newHash).
+		Scan(&sql.NullInt64{})
+	if err != nil {
+		return r.handleError(ctx, err)
+	}
+	return nil
+}
+
+// stmtUserSetRole - устанавливает роль пользователя и делает недействительными
+// все ранее выпущенные токены пользователя, содержащие прежнюю роль.
+//    $1 - id пользователя
+//    $2 - role
+// Возвращает id пользователя.
+var stmtUserSetRole = registerStatement(`
+	UPDATE users
+	SET role = $2, tokens_valid_after = now(), updated_at = now()
+	WHERE id = $1
+	RETURNING id
+`)
+
+// UserSetRole - устанавливает роль пользователя.
+// Ранее выпущенные токены пользователя становятся недействительными.
+// Если пользователь не найден, возвращает errs.ErrNotFound.
+func (r *PGXRepo) UserSetRole(ctx context.Context, userID uint64, role models.UserRole) error {
+	err := r.statements[stmtUserSetRole].
+		QueryRowContext(ctx, userID, role).
+		Scan(&sql.NullInt64{})
+	if err != nil {
+		return r.handleError(ctx, err)
+	}
+	return nil
+}
+
+// stmtUserSetStatus - устанавливает статус неудаленного пользователя и записывает изменение в историю.
+// При повторной блокировке время блокировки не меняется, обновляется только причина.
+//    $1 - id пользователя
+//    $2 - status
+//    $3 - причина изменения статуса
+//    $4 - id сотрудника, изменившего статус
+// Возвращает id, created_at записи истории.
+var stmtUserSetStatus = registerStatement(`
+	WITH updated AS (
+		UPDATE users
+		SET status = $2::user_status,
+			blocked_at = CASE WHEN $2::user_status = 'blocked' THEN coalesce(blocked_at, now()) END,
+			block_reason = CASE WHEN $2::user_status = 'blocked' THEN $3::text END,
+			updated_at = now()
+		WHERE id = $1 AND deleted_at IS NULL
+		RETURNING id
+	)
+	INSERT INTO user_status_changes (user_id, status, reason, changed_by)
+	SELECT id, $2::user_status, $3::text, $4 FROM updated
+	RETURNING id, created_at
+`)
+
+// UserSetStatus - блокирует или разблокирует учетную запись пользователя и записывает изменение в историю.
+// Если пользователь не найден или удален, возвращает errs.ErrNotFound.
+func (r *PGXRepo) UserSetStatus(ctx context.Context, c *models.UserStatusChange) error {
+	err := r.statements[stmtUserSetStatus].
+		QueryRowContext(ctx, c.UserID, c.Status, c.Reason, c.ChangedBy).
+		Scan(&c.ID, &c.CreatedAt)
+	if err != nil {
+		return r.handleError(ctx, err)
+	}
+	return nil
+}
+
+// stmtUserCancelPending - отменяет операции пользователя, которые находятся не в конечном статусе.
+//    $1 - id пользователя
+// Возвращает id, user_id, op_type, status, amount, description,
+// order_number, promo_id, counterparty_id, created_at, updated_at, processed_at отмененных операций.
+// ВАЖНО: может вызываться только внутри транзакции.
+// После блокировки пользователя по отмененным операциям необходимо записать проводки при помощи PGXRepo.ledgerPostTx.
+var stmtUserCancelPending = registerStatement(`
+	UPDATE operations
+	SET status = 'CANCELED', updated_at = now()
+	WHERE user_id = $1 AND status IN ('NEW', 'PROCESSING')
+	RETURNING id, user_id, op_type, status, amount, description, order_number, promo_id, counterparty_id, created_at, updated_at,
+		processed_at
+`)
+
+// stmtUserCloseBalance - создает операцию закрытия счета на сумму остатка баланса пользователя,
+// если остаток положительный.
+//    $1 - id пользователя
+//    $2 - description
+// Возвращает id, user_id, op_type, status, amount, description,
+// order_number, promo_id, counterparty_id, created_at, updated_at, processed_at операции.
+// ВАЖНО: может вызываться только внутри транзакции и только после вызова PGXRepo.userLockTx.
+// После вызова необходимо записать проводку при помощи PGXRepo.ledgerPostTx.
+var stmtUserCloseBalance = registerStatement(`
+	INSERT INTO operations (user_id, op_type, status, amount, description, processed_at)
+	SELECT id, 'account_closure', 'PROCESSED', 0 - balance, $2, now() FROM users
+	WHERE id = $1 AND balance > 0
+	RETURNING id, user_id, op_type, status, amount, description, order_number, promo_id, counterparty_id, created_at, updated_at,
+		processed_at
+`)
+
+// stmtUserAnonymize - обезличивает учетную запись пользователя:
+// заменяет логин и хэш пароля, резервирует хэш прежнего логина до окончания срока,
+// отзывает сессии, персональные токены, токены сброса пароля и незавершенные входы,
+// удаляет секрет TOTP, выгрузки персональных данных, ключи идемпотентности с записанными ответами
+// и счетчик неудачных попыток входа по логину.
+//    $1 - id пользователя
+//    $2 - срок в секундах, в течение которого прежний логин нельзя занять
+// Возвращает deleted_at.
+// ВАЖНО: может вызываться только внутри транзакции и только после вызова PGXRepo.userLockTx.
+var stmtUserAnonymize = registerStatement(`
+	WITH
+		old AS (
+			SELECT username FROM users WHERE id = $1
+		),
+		reserved AS (
+			INSERT INTO deleted_logins (login_hash, reusable_after)
+			SELECT encode(sha256(convert_to(username, 'UTF8')), 'hex'), now() + make_interval(secs => $2) FROM old
+			ON CONFLICT (login_hash) DO UPDATE SET reusable_after = excluded.reusable_after
+		),
+		failures_reset AS (
+			DELETE FROM login_failures
+			WHERE key IN (SELECT 'login:' || username FROM old)
+		),
+		sessions_revoked AS (
+			UPDATE sessions
+			SET revoked_at = now(), updated_at = now()
+			WHERE user_id = $1 AND revoked_at IS NULL
+		),
+		tokens_revoked AS (
+			UPDATE access_tokens
+			SET revoked_at = now()
+			WHERE user_id = $1 AND revoked_at IS NULL
+		),
+		resets_used AS (
+			UPDATE password_resets
+			SET used_at = now()
+			WHERE user_id = $1 AND used_at IS NULL
+		),
+		challenges_used AS (
+			UPDATE login_challenges
+			SET used_at = now()
+			WHERE user_id = $1 AND used_at IS NULL
+		),
+		totp_deleted AS (
+			DELETE FROM user_totp
+			WHERE user_id = $1
+		),
+		exports_deleted AS (
+			DELETE FROM data_exports
+			WHERE user_id = $1
+		),
+		idempotency_keys_deleted AS (
+			DELETE FROM idempotency_keys
+			WHERE user_id = $1
+		)
+	UPDATE users
+	SET username = '#deleted-' || id, pass_hash = '', role = 'user',
+	    deleted_at = now(), tokens_valid_after = now(), updated_at = now()
+	WHERE id = $1
+	RETURNING deleted_at
+`)
+
+// userCancelPendingTx - отменяет операции пользователя, которые находятся не в конечном статусе.
+// Возвращает отмененные операции.
+// ВАЖНО: может вызываться только внутри транзакции
+func (r *PGXRepo) userCancelPendingTx(ctx context.Context, tx *sql.Tx, userID uint64) ([]*models.Operation, error) {
+	rows, err := tx.Stmt(r.statements[stmtUserCancelPending]).QueryContext(ctx, userID)
+	if err != nil {
+		return nil, r.handleError(ctx, err)
+	}
+	//goland:noinspection GoUnhandledErrorResult
+	defer rows.Close()
+
+	ops, err := r.operationScanRows(ctx, rows)
+	if err != nil {
+		return nil, r.handleError(ctx, err)
+	}
+	return ops, nil
+}
+
+// UserDelete - удаляет учетную запись пользователя с сохранением операций для учета.
+// Незавершенные операции отменяются, остаток баланса списывается операцией закрытия счета с описанием description,
+// учетная запись обезличивается, а прежний логин нельзя занять в течение graceSeconds.
+// Все действия выполняются в одной транзакции.
+// Возвращает операцию закрытия счета или nil, если остаток баланса нулевой.
+// Если пользователь не найден или уже удален, возвращает errs.ErrNotFound.
+func (r *PGXRepo) UserDelete(ctx context.Context, userID uint64, description string, graceSeconds int64) (*models.Operation, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return nil, r.handleError(ctx, err)
+	}
+	//goland:noinspection ALL
+	defer tx.Rollback()
+
+	// Отменяем незавершенные операции до блокировки пользователя, чтобы блокировки операций и пользователя
+	// захватывались в том же порядке, что и в OperationUpdateFurther
+	canceled, err := r.userCancelPendingTx(ctx, tx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Блокируем запись пользователя для обновления
+	if _, err = r.userLockTx(ctx, tx, userID); err != nil {
+		return nil, err
+	}
+
+	// Отменяем операции, созданные до блокировки пользователя
+	more, err := r.userCancelPendingTx(ctx, tx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Снимаем резервирование по отмененным списаниям. До отмены операции находились в статусе NEW или PROCESSING,
+	// которые одинаково учитываются в балансе
+	for _, op := range append(canceled, more...) {
+		prev := *op
+		prev.Status = models.StatusNew
+		if err = r.ledgerPostTx(ctx, tx, op, prev.BalanceEffect()); err != nil {
+			return nil, err
+		}
+	}
+
+	// Снимаем удержание со всех начислений, чтобы списать весь остаток баланса
+	if _, err = r.operationHoldReleaseTx(ctx, tx, userID, true); err != nil {
+		return nil, err
+	}
+
+	// Списываем остаток баланса операцией закрытия счета
+	op := &models.Operation{}
+	err = tx.Stmt(r.statements[stmtUserCloseBalance]).
+		QueryRowContext(ctx, userID, description).
+		Scan(
+			&op.ID,
+			&op.UserID,
+			&op.Type,
+			&op.Status,
+			&op.Amount,
+			&op.Description,
+			&op.OrderNumber,
+			&op.PromoID,
+			&op.CounterpartyID,
+			&op.CreatedAt,
+			&op.UpdatedAt,
+			&op.ProcessedAt,
+		)
+	if errors.Is(err, sql.ErrNoRows) {
+		op = nil
+	} else if err != nil {
+		return nil, r.handleError(ctx, err)
+	} else if err = r.ledgerPostTx(ctx, tx, op, decimal.Zero); err != nil {
+		return nil, err
+	}
+
+	// Обезличиваем учетную запись
+	err = tx.Stmt(r.statements[stmtUserAnonymize]).
+		QueryRowContext(ctx, userID, graceSeconds).
+		Scan(&sql.NullTime{})
+	if err != nil {
+		return nil, r.handleError(ctx, err)
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, r.handleError(ctx, err)
+	}
+	return op, nil
+}
@@ -1,6 +1,11 @@
 package repo
 
 import (
+	"context"
+	"time"
+
+	"github.com/shopspring/decimal"
+
 	"gophermart-loyalty/internal/errs"
 	"gophermart-loyalty/internal/models"
 )
@@ -38,6 +43,96 @@ func (suite *pgxRepoSuite) TestUserGetByID() {
 	suite.ErrorIs(err, errs.ErrNotFound)
 }
 
+func (suite *pgxRepoSuite) TestUserUpdatePassHash() {
+	suite.NoError(suite.repo.UserUpdatePassHash(suite.ctx(), 1, "new-hash"))
+	user, err := suite.repo.UserGetByID(suite.ctx(), 1)
+	suite.NoError(err)
+	suite.Equal("new-hash", user.PassHash)
+	err = suite.repo.UserUpdatePassHash(suite.ctx(), 1000, "new-hash")
+	suite.ErrorIs(err, errs.ErrNotFound)
+}
+
+func (suite *pgxRepoSuite) TestUserUpgradePassHash() {
+	suite.NoError(suite.repo.UserUpdatePassHash(suite.ctx(), 1, "old-hash"))
+	suite.NoError(suite.repo.UserUpgradePassHash(suite.ctx(), 1, "old-hash", "new-hash"))
+	user, err := suite.repo.UserGetByID(suite.ctx(), 1)
+	suite.NoError(err)
+	suite.Equal("new-hash", user.PassHash)
+
+	// хэш изменился с момента проверки пароля
+	err = suite.repo.UserUpgradePassHash(suite.ctx(), 1, "old-hash", "newer-hash")
+	suite.ErrorIs(err, errs.ErrNotFound)
+	user, err = suite.repo.UserGetByID(suite.ctx(), 1)
+	suite.NoError(err)
+	suite.Equal("new-hash", user.PassHash)
+}
+
+func (suite *pgxRepoSuite) TestUserSetRole() {
+	user, err := suite.repo.UserGetByID(suite.ctx(), 1)
+	suite.NoError(err)
+	suite.Equal(models.RoleUser, user.Role)
+
+	suite.NoError(suite.repo.UserSetRole(suite.ctx(), 1, models.RoleAdmin))
+	user, err = suite.repo.UserGetByID(suite.ctx(), 1)
+	suite.NoError(err)
+	suite.Equal(models.RoleAdmin, user.Role)
+	suite.True(user.TokensValidAfter.After(user.CreatedAt))
+
+	err = suite.repo.UserSetRole(suite.ctx(), 1000, models.RoleAdmin)
+	suite.ErrorIs(err, errs.ErrNotFound)
+}
+
+func (suite *pgxRepoSuite) TestUserSetStatus() {
+	block := &models.UserStatusChange{UserID: 2, Status: models.UserBlocked, Reason: "fraud", ChangedBy: 1}
+	suite.NoError(suite.repo.UserSetStatus(suite.ctx(), block))
+	suite.NotZero(block.ID)
+	user, err := suite.repo.UserGetByID(suite.ctx(), 2)
+	suite.NoError(err)
+	suite.True(user.Blocked())
+	suite.Require().NotNil(user.BlockedAt)
+	suite.Require().NotNil(user.BlockReason)
+	suite.Equal("fraud", *user.BlockReason)
+
+	suite.Run("withdrawal refused", func() {
+		suite.NoError(suite.repo.OperationCreate(suite.ctx(), testOA(2, "01", 100, models.StatusProcessed)))
+		err := suite.repo.OperationCreate(suite.ctx(), testOW(2, "02", -10, models.StatusNew))
+		suite.ErrorIs(err, errs.ErrOperationUserBlocked)
+	})
+
+	suite.Run("pending accrual processed", func() {
+		suite.NoError(suite.repo.OperationCreate(suite.ctx(), testOA(2, "03", 0, models.StatusNew)))
+		op, err := suite.repo.OperationUpdateFurther(suite.ctx(), models.OrderAccrual, models.StatusSourceAccrual, func(ctx context.Context, op *models.Operation) error {
+			op.Status = models.StatusProcessed
+			op.Amount = decimal.NewFromInt(50)
+			return nil
+		})
+		suite.NoError(err)
+		suite.Equal(uint64(2), op.UserID)
+		user, err := suite.repo.UserGetByID(suite.ctx(), 2)
+		suite.NoError(err)
+		suite.Equal("150", user.Balance.String())
+	})
+
+	suite.Run("unblock", func() {
+		suite.NoError(suite.repo.UserSetStatus(suite.ctx(), &models.UserStatusChange{
+			UserID: 2, Status: models.UserActive, Reason: "investigation closed", ChangedBy: 1,
+		}))
+		user, err := suite.repo.UserGetByID(suite.ctx(), 2)
+		suite.NoError(err)
+		suite.False(user.Blocked())
+		suite.Nil(user.BlockedAt)
+		suite.Nil(user.BlockReason)
+		suite.NoError(suite.repo.OperationCreate(suite.ctx(), testOW(2, "02", -10, models.StatusNew)))
+	})
+
+	suite.Run("unknown user", func() {
+		err := suite.repo.UserSetStatus(suite.ctx(), &models.UserStatusChange{
+			UserID: 1000, Status: models.UserBlocked, Reason: "fraud", ChangedBy: 1,
+		})
+		suite.ErrorIs(err, errs.ErrNotFound)
+	})
+}
+
 func (suite *pgxRepoSuite) TestUserBalanceHistoryGetByID() {
 
 	suite.Run("populate user 1", func() {
@@ -64,7 +159,7 @@ func (suite *pgxRepoSuite) TestUserBalanceHistoryGetByID() {
 	})
 
 	suite.Run("get balance operations for user 1", func() {
-		ops, err := suite.repo.UserBalanceHistoryGetByID(suite.ctx(), 1)
+		ops, err := suite.repo.UserBalanceHistoryGetByID(suite.ctx(), 1, nil)
 		suite.NoError(err)
 		suite.Len(ops, 5)
 		suite.Equal("30", *ops[0].OrderNumber)
@@ -74,16 +169,256 @@ func (suite *pgxRepoSuite) TestUserBalanceHistoryGetByID() {
 		suite.Equal("30", *ops[4].OrderNumber)
 	})
 
+	suite.Run("filter balance operations for user 1", func() {
+		t := models.OrderWithdrawal
+		ops, err := suite.repo.UserBalanceHistoryGetByID(suite.ctx(), 1, &models.OperationQuery{Type: &t, Limit: 2})
+		suite.NoError(err)
+		suite.Require().Len(ops, 2)
+		suite.Equal("30", *ops[0].OrderNumber)
+		suite.Equal("20", *ops[1].OrderNumber)
+
+		after := &models.OperationCursor{Time: ops[1].UpdatedAt, ID: ops[1].ID}
+		ops, err = suite.repo.UserBalanceHistoryGetByID(suite.ctx(), 1, &models.OperationQuery{Type: &t, Limit: 2, After: after})
+		suite.NoError(err)
+		suite.Require().Len(ops, 1)
+		suite.Equal("10", *ops[0].OrderNumber)
+	})
+
 	suite.Run("get balance operations for user 2", func() {
-		ops, err := suite.repo.UserBalanceHistoryGetByID(suite.ctx(), 2)
+		ops, err := suite.repo.UserBalanceHistoryGetByID(suite.ctx(), 2, nil)
 		suite.NoError(err)
 		suite.Len(ops, 0)
 	})
 
 	suite.Run("get balance operations for user 3", func() {
-		ops, err := suite.repo.UserBalanceHistoryGetByID(suite.ctx(), 3)
+		ops, err := suite.repo.UserBalanceHistoryGetByID(suite.ctx(), 3, nil)
 		suite.NoError(err)
 		suite.Len(ops, 0)
 	})
 
 }
+
+func (suite *pgxRepoSuite) TestUserBalanceAsOf() {
+	suite.NoError(suite.repo.OperationCreate(suite.ctx(), testOA(1, "10", 100, models.StatusProcessed)))
+	suite.NoError(suite.repo.OperationCreate(suite.ctx(), testOA(1, "20", 100, models.StatusProcessing)))
+	suite.NoError(suite.repo.OperationCreate(suite.ctx(), testOW(1, "30", -30, models.StatusNew)))
+	suite.NoError(suite.repo.OperationCreate(suite.ctx(), testOW(1, "40", -10, models.StatusCanceled)))
+
+	history, err := suite.repo.UserBalanceHistoryGetByID(suite.ctx(), 1, nil)
+	suite.Require().NoError(err)
+	suite.Require().Len(history, 2)
+
+	suite.Run("before operations", func() {
+		b, err := suite.repo.UserBalanceAsOf(suite.ctx(), 1, history[1].UpdatedAt)
+		suite.NoError(err)
+		suite.True(b.Balance.IsZero())
+		suite.True(b.Withdrawn.IsZero())
+	})
+
+	suite.Run("after operations", func() {
+		asOf := history[0].UpdatedAt.Add(time.Second)
+		b, err := suite.repo.UserBalanceAsOf(suite.ctx(), 1, asOf)
+		suite.NoError(err)
+		suite.Equal(asOf, b.AsOf)
+		suite.Equal("70", b.Balance.String())
+		suite.Equal("30", b.Withdrawn.String())
+	})
+
+	suite.Run("later cancellation", func() {
+		_, err := suite.repo.OperationUpdateByOrder(suite.ctx(), 1, models.OrderWithdrawal, "30", models.StatusSourceUser, func(_ context.Context, op *models.Operation) error {
+			op.Status = models.StatusCanceled
+			return nil
+		})
+		suite.Require().NoError(err)
+
+		postings, err := suite.repo.UserLedgerPostingsGet(suite.ctx(), 1, history[1].UpdatedAt, history[0].UpdatedAt.Add(time.Hour))
+		suite.Require().NoError(err)
+		suite.Require().Len(postings, 3)
+		suite.Equal(models.LedgerAccrualSource, postings[0].Account)
+		suite.Equal("100", postings[0].Amount.String())
+		suite.Equal(models.LedgerWithdrawalSink, postings[1].Account)
+		suite.Equal("-30", postings[1].Amount.String())
+		suite.Equal("30", postings[2].Amount.String())
+		suite.Equal(models.StatusCanceled, postings[2].Operation.Status)
+		suite.Equal("30", *postings[2].Operation.OrderNumber)
+
+		// отмена записана встречной проводкой и не меняет баланс до нее
+		b, err := suite.repo.UserBalanceAsOf(suite.ctx(), 1, postings[2].CreatedAt)
+		suite.NoError(err)
+		suite.Equal("70", b.Balance.String())
+		suite.Equal("30", b.Withdrawn.String())
+
+		b, err = suite.repo.UserBalanceAsOf(suite.ctx(), 1, postings[2].CreatedAt.Add(time.Second))
+		suite.NoError(err)
+		suite.Equal("100", b.Balance.String())
+		suite.True(b.Withdrawn.IsZero())
+	})
+
+	suite.Run("other user", func() {
+		b, err := suite.repo.UserBalanceAsOf(suite.ctx(), 2, time.Now())
+		suite.NoError(err)
+		suite.True(b.Balance.IsZero())
+	})
+}
+
+func (suite *pgxRepoSuite) TestUserDelete() {
+	suite.NoError(suite.repo.OperationCreate(suite.ctx(), testOA(1, "10", 100, models.StatusProcessed)))
+	suite.NoError(suite.repo.OperationCreate(suite.ctx(), testOA(1, "20", 50, models.StatusNew)))
+	suite.NoError(suite.repo.OperationCreate(suite.ctx(), testOW(1, "30", -10, models.StatusNew)))
+	s := &models.Session{UserID: 1, RefreshHash: "refresh1"}
+	suite.NoError(suite.repo.SessionCreate(suite.ctx(), s, 3600))
+
+	suite.Run("delete", func() {
+		op, err := suite.repo.UserDelete(suite.ctx(), 1, "closure", 3600)
+		suite.NoError(err)
+		suite.Require().NotNil(op)
+		suite.Equal(models.AccountClosure, op.Type)
+		suite.Equal("-100", op.Amount.String())
+
+		user, err := suite.repo.UserGetByID(suite.ctx(), 1)
+		suite.NoError(err)
+		suite.NotNil(user.DeletedAt)
+		suite.NotEqual("user1", user.Login)
+		suite.Empty(user.PassHash)
+		suite.True(user.Balance.IsZero())
+
+		// операции сохраняются, незавершенные операции отменены
+		ops, err := suite.repo.OperationGetByType(suite.ctx(), 1, models.OrderAccrual, nil)
+		suite.NoError(err)
+		suite.Len(ops, 2)
+		ops, err = suite.repo.OperationGetByType(suite.ctx(), 1, models.OrderWithdrawal, nil)
+		suite.NoError(err)
+		suite.Require().Len(ops, 1)
+		suite.Equal(models.StatusCanceled, ops[0].Status)
+
+		session, err := suite.repo.SessionGetByID(suite.ctx(), s.ID)
+		suite.NoError(err)
+		suite.NotNil(session.RevokedAt)
+	})
+
+	suite.Run("login can not be reused", func() {
+		_, err := suite.repo.UserGetByLogin(suite.ctx(), "user1")
+		suite.ErrorIs(err, errs.ErrNotFound)
+		err = suite.repo.UserCreate(suite.ctx(), &models.User{Login: "user1", PassHash: "hash"})
+		suite.ErrorIs(err, errs.ErrUserAlreadyExists)
+	})
+
+	suite.Run("operations of deleted user can not be created", func() {
+		err := suite.repo.OperationCreate(suite.ctx(), testOA(1, "40", 100, models.StatusNew))
+		suite.ErrorIs(err, errs.ErrOperationUserNotExists)
+	})
+
+	suite.Run("already deleted", func() {
+		_, err := suite.repo.UserDelete(suite.ctx(), 1, "closure", 3600)
+		suite.ErrorIs(err, errs.ErrNotFound)
+	})
+
+	suite.Run("zero balance", func() {
+		op, err := suite.repo.UserDelete(suite.ctx(), 2, "closure", 3600)
+		suite.NoError(err)
+		suite.Nil(op)
+	})
+}
+
+func (suite *pgxRepoSuite) TestUserBalanceReconcile() {
+	suite.NoError(suite.repo.OperationCreate(suite.ctx(), testOA(1, "10", 100, models.StatusProcessed)))
+	suite.NoError(suite.repo.OperationCreate(suite.ctx(), testOW(1, "20", -30, models.StatusNew)))
+	suite.NoError(suite.repo.OperationCreate(suite.ctx(), testOA(2, "30", 50, models.StatusProcessed)))
+
+	suite.Run("no drift", func() {
+		checks, err := suite.repo.UserBalanceCheck(suite.ctx(), 0, 10)
+		suite.NoError(err)
+		suite.Require().Len(checks, 3)
+		for _, c := range checks {
+			suite.False(c.Drift())
+		}
+		suite.Equal("70", checks[0].ExpectedBalance.String())
+		suite.Equal("30", checks[0].ExpectedWithdrawn.String())
+	})
+
+	suite.Run("batches", func() {
+		checks, err := suite.repo.UserBalanceCheck(suite.ctx(), 1, 1)
+		suite.NoError(err)
+		suite.Require().Len(checks, 1)
+		suite.Equal(uint64(2), checks[0].UserID)
+	})
+
+	suite.Run("drift", func() {
+		_, err := suite.repo.db.ExecContext(suite.ctx(), `UPDATE users SET balance = 1000, withdrawn = 0 WHERE id = 2`)
+		suite.Require().NoError(err)
+
+		checks, err := suite.repo.UserBalanceCheck(suite.ctx(), 1, 1)
+		suite.NoError(err)
+		suite.Require().Len(checks, 1)
+		suite.True(checks[0].Drift())
+	})
+
+	suite.Run("repair", func() {
+		c, err := suite.repo.UserBalanceRepair(suite.ctx(), 2)
+		suite.NoError(err)
+		suite.Equal("1000", c.Balance.String())
+		suite.Equal("50", c.ExpectedBalance.String())
+
+		user, err := suite.repo.UserGetByID(suite.ctx(), 2)
+		suite.NoError(err)
+		suite.Equal("50", user.Balance.String())
+		suite.True(user.Withdrawn.IsZero())
+	})
+
+	suite.Run("ledger drift", func() {
+		suite.NoError(suite.repo.OperationCreate(suite.ctx(), testOA(3, "40", 20, models.StatusNew)))
+		_, err := suite.repo.db.ExecContext(suite.ctx(), `UPDATE operations SET status = 'PROCESSED' WHERE order_number = '40'`)
+		suite.Require().NoError(err)
+
+		checks, err := suite.repo.UserBalanceCheck(suite.ctx(), 2, 1)
+		suite.NoError(err)
+		suite.Require().Len(checks, 1)
+		suite.True(checks[0].Drift())
+		suite.Equal(1, checks[0].Unposted)
+		suite.True(checks[0].ExpectedBalance.IsZero())
+	})
+
+	suite.Run("repair ledger", func() {
+		c, err := suite.repo.UserBalanceRepair(suite.ctx(), 3)
+		suite.NoError(err)
+		suite.Equal(1, c.Unposted)
+		suite.Equal("20", c.ExpectedBalance.String())
+
+		// недостающая проводка записана в журнал, баланс пересчитан по журналу
+		userID := uint64(3)
+		suite.Equal("20", suite.ledgerAccountBalance(&userID, nil).String())
+		user, err := suite.repo.UserGetByID(suite.ctx(), 3)
+		suite.NoError(err)
+		suite.Equal("20", user.Balance.String())
+
+		checks, err := suite.repo.UserBalanceCheck(suite.ctx(), 2, 1)
+		suite.NoError(err)
+		suite.Require().Len(checks, 1)
+		suite.False(checks[0].Drift())
+	})
+
+	suite.Run("repair negative correction over drifted cache", func() {
+		suite.NoError(suite.repo.OperationCreate(suite.ctx(), testOA(1, "50", 200, models.StatusProcessed)))
+		// проводка на 200 уже записана, а операция и кэш баланса расходятся с журналом:
+		// корректирующая проводка -200 сделала бы кэш отрицательным
+		_, err := suite.repo.db.ExecContext(suite.ctx(), `UPDATE operations SET amount = 0 WHERE order_number = '50'`)
+		suite.Require().NoError(err)
+		_, err = suite.repo.db.ExecContext(suite.ctx(), `UPDATE users SET balance = 100 WHERE id = 1`)
+		suite.Require().NoError(err)
+
+		c, err := suite.repo.UserBalanceRepair(suite.ctx(), 1)
+		suite.NoError(err)
+		suite.Equal(1, c.Unposted)
+		suite.Equal("100", c.Balance.String())
+		suite.Equal("70", c.ExpectedBalance.String())
+
+		user, err := suite.repo.UserGetByID(suite.ctx(), 1)
+		suite.NoError(err)
+		suite.Equal("70", user.Balance.String())
+	})
+
+	suite.Run("unknown user", func() {
+		_, err := suite.repo.UserBalanceRepair(suite.ctx(), 1000)
+		suite.ErrorIs(err, errs.ErrNotFound)
+	})
+}
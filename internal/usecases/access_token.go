@@ -0,0 +1,113 @@
+package usecases
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"gophermart-loyalty/internal/errs"
+	"gophermart-loyalty/internal/models"
+)
+
+// accessTokenLen - длина персонального токена доступа в байтах без префикса
+const accessTokenLen = 32
+
+// accessTokenNameMaxLen - максимальная длина названия персонального токена
+const accessTokenNameMaxLen = 64
+
+// AccessTokenCreate - создает персональный токен доступа пользователя userID с названием name,
+// областями доступа scopes и временем истечения expiresAt. Если expiresAt не задано, токен бессрочный.
+// Возвращает модель токена и сам токен. В репозитории хранится только хэш токена.
+func (u *UseCases) AccessTokenCreate(ctx context.Context, userID uint64, name string, scopes []models.AccessTokenScope, expiresAt *time.Time) (*models.AccessToken, string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" || utf8.RuneCountInString(name) > accessTokenNameMaxLen {
+		return nil, "", errs.ErrAccessTokenNameInvalid
+	}
+
+	// Проверяем области доступа и убираем повторы
+	if len(scopes) == 0 {
+		return nil, "", errs.ErrAccessTokenScopeInvalid
+	}
+	unique := make([]models.AccessTokenScope, 0, len(scopes))
+	seen := make(map[models.AccessTokenScope]struct{}, len(scopes))
+	for _, scope := range scopes {
+		if !scope.Valid() {
+			return nil, "", errs.ErrAccessTokenScopeInvalid
+		}
+		if _, ok := seen[scope]; !ok {
+			seen[scope] = struct{}{}
+			unique = append(unique, scope)
+		}
+	}
+
+	// Время жизни токена отсчитывается от текущего времени БД
+	var ttlSeconds int64
+	if expiresAt != nil {
+		ttlSeconds = int64(time.Until(*expiresAt).Seconds())
+		if ttlSeconds <= 0 {
+			return nil, "", errs.ErrAccessTokenExpiryInvalid
+		}
+	}
+
+	token, _, err := randomToken(accessTokenLen)
+	if err != nil {
+		u.log.WithReqID(ctx).Error().Err(err).Msg("failed to generate access token")
+		return nil, "", errs.ErrInternal
+	}
+	token = models.AccessTokenPrefix + token
+
+	t := &models.AccessToken{
+		UserID:    userID,
+		Name:      name,
+		TokenHash: tokenHash(token),
+		Scopes:    unique,
+	}
+	if err = u.repo.AccessTokenCreate(ctx, t, ttlSeconds); err != nil {
+		u.log.WithReqID(ctx).Error().Err(err).Msg("failed to create access token")
+		return nil, "", err
+	}
+
+	u.log.WithReqID(ctx).Info().Uint64("user_id", userID).Uint64("token_id", t.ID).Msg("access token created")
+	return t, token, nil
+}
+
+// AccessTokenList - возвращает неотозванные персональные токены пользователя userID.
+func (u *UseCases) AccessTokenList(ctx context.Context, userID uint64) ([]*models.AccessToken, error) {
+	list, err := u.repo.AccessTokenGetByUser(ctx, userID)
+	if err != nil {
+		u.log.WithReqID(ctx).Error().Err(err).Msg("failed to get access tokens")
+		return nil, err
+	}
+	return list, nil
+}
+
+// AccessTokenRevoke - отзывает персональный токен tokenID пользователя userID.
+func (u *UseCases) AccessTokenRevoke(ctx context.Context, userID, tokenID uint64) error {
+	err := u.repo.AccessTokenRevoke(ctx, userID, tokenID)
+	if errors.Is(err, errs.ErrNotFound) {
+		return err
+	} else if err != nil {
+		u.log.WithReqID(ctx).Error().Err(err).Msg("failed to revoke access token")
+		return err
+	}
+	u.log.WithReqID(ctx).Info().Uint64("token_id", tokenID).Msg("access token revoked")
+	return nil
+}
+
+// AccessTokenValidate - проверяет персональный токен доступа и отмечает его использование.
+// Возвращает модель токена, если токен существует, не истек и не отозван.
+func (u *UseCases) AccessTokenValidate(ctx context.Context, token string) (*models.AccessToken, error) {
+	if !strings.HasPrefix(token, models.AccessTokenPrefix) {
+		return nil, errs.ErrUnauthorized
+	}
+	t, err := u.repo.AccessTokenUse(ctx, tokenHash(token))
+	if errors.Is(err, errs.ErrNotFound) {
+		return nil, errs.ErrUnauthorized
+	} else if err != nil {
+		u.log.WithReqID(ctx).Error().Err(err).Msg("failed to use access token")
+		return nil, err
+	}
+	return t, nil
+}
@@ -0,0 +1,86 @@
+package usecases
+
+import (
+	"strings"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+
+	"gophermart-loyalty/internal/errs"
+	"gophermart-loyalty/internal/models"
+)
+
+func (suite *useCasesSuite) TestAccessTokenCreate() {
+	scopes := []models.AccessTokenScope{models.ScopeOrdersWrite, models.ScopeBalanceRead, models.ScopeOrdersWrite}
+
+	suite.Run("success", func() {
+		var saved *models.AccessToken
+		suite.repo.On("AccessTokenCreate", mock.Anything, mock.Anything, int64(0)).
+			Run(func(args mock.Arguments) { saved = args.Get(1).(*models.AccessToken) }).
+			Return(nil).Once()
+		t, token, err := suite.useCases.AccessTokenCreate(suite.ctx(), 1, " script ", scopes, nil)
+		suite.NoError(err)
+		suite.True(strings.HasPrefix(token, models.AccessTokenPrefix))
+		suite.Equal("script", t.Name)
+		suite.Equal([]models.AccessTokenScope{models.ScopeOrdersWrite, models.ScopeBalanceRead}, t.Scopes)
+
+		// в репозитории хранится только хэш токена
+		suite.Require().NotNil(saved)
+		suite.Equal(tokenHash(token), saved.TokenHash)
+		suite.NotContains(saved.TokenHash, token)
+	})
+
+	suite.Run("invalid name", func() {
+		_, _, err := suite.useCases.AccessTokenCreate(suite.ctx(), 1, "  ", scopes, nil)
+		suite.ErrorIs(err, errs.ErrAccessTokenNameInvalid)
+		_, _, err = suite.useCases.AccessTokenCreate(suite.ctx(), 1, strings.Repeat("a", 65), scopes, nil)
+		suite.ErrorIs(err, errs.ErrAccessTokenNameInvalid)
+	})
+
+	suite.Run("invalid scopes", func() {
+		_, _, err := suite.useCases.AccessTokenCreate(suite.ctx(), 1, "script", nil, nil)
+		suite.ErrorIs(err, errs.ErrAccessTokenScopeInvalid)
+		_, _, err = suite.useCases.AccessTokenCreate(suite.ctx(), 1, "script", []models.AccessTokenScope{"admin"}, nil)
+		suite.ErrorIs(err, errs.ErrAccessTokenScopeInvalid)
+	})
+
+	suite.Run("expiry in the past", func() {
+		past := time.Now().Add(-time.Minute)
+		_, _, err := suite.useCases.AccessTokenCreate(suite.ctx(), 1, "script", scopes, &past)
+		suite.ErrorIs(err, errs.ErrAccessTokenExpiryInvalid)
+	})
+}
+
+func (suite *useCasesSuite) TestAccessTokenValidate() {
+	suite.Run("success", func() {
+		token := models.AccessTokenPrefix + "token"
+		suite.repo.On("AccessTokenUse", mock.Anything, tokenHash(token)).
+			Return(&models.AccessToken{ID: 1, UserID: 2}, nil).Once()
+		t, err := suite.useCases.AccessTokenValidate(suite.ctx(), token)
+		suite.NoError(err)
+		suite.Equal(uint64(2), t.UserID)
+	})
+
+	suite.Run("revoked or expired", func() {
+		suite.repo.On("AccessTokenUse", mock.Anything, mock.Anything).Return(nil, errs.ErrNotFound).Once()
+		_, err := suite.useCases.AccessTokenValidate(suite.ctx(), models.AccessTokenPrefix+"token")
+		suite.ErrorIs(err, errs.ErrUnauthorized)
+	})
+
+	suite.Run("no prefix", func() {
+		_, err := suite.useCases.AccessTokenValidate(suite.ctx(), "token")
+		suite.ErrorIs(err, errs.ErrUnauthorized)
+	})
+}
+
+func (suite *useCasesSuite) TestAccessTokenRevoke() {
+	suite.Run("success", func() {
+		suite.repo.On("AccessTokenRevoke", mock.Anything, uint64(1), uint64(2)).Return(nil).Once()
+		suite.NoError(suite.useCases.AccessTokenRevoke(suite.ctx(), 1, 2))
+	})
+
+	suite.Run("not found", func() {
+		suite.repo.On("AccessTokenRevoke", mock.Anything, uint64(1), uint64(3)).Return(errs.ErrNotFound).Once()
+		suite.ErrorIs(suite.useCases.AccessTokenRevoke(suite.ctx(), 1, 3), errs.ErrNotFound)
+	})
+}
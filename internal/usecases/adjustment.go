@@ -0,0 +1,69 @@
+package usecases
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/shopspring/decimal"
+
+	"gophermart-loyalty/internal/errs"
+	"gophermart-loyalty/internal/models"
+)
+
+const (
+	// adjustmentTicketMaxLen - максимальная длина номера обращения
+	adjustmentTicketMaxLen = 64
+	// adjustmentDescriptionMaxLen - максимальная длина описания корректировки для пользователя
+	adjustmentDescriptionMaxLen = 256
+)
+
+// BalanceAdjust - корректирует баланс пользователя userID на сумму amount по решению сотрудника staffID.
+// Причина и номер обращения обязательны и сохраняются в журнале корректировок вместе с id сотрудника.
+// Описание description отображается пользователю в истории операций, если не задано - используется описание по умолчанию.
+// Возвращает созданную операцию корректировки.
+func (u *UseCases) BalanceAdjust(ctx context.Context, staffID, userID uint64, amount decimal.Decimal, reason, ticket, description string) (*models.Operation, error) {
+	reason = strings.TrimSpace(reason)
+	ticket = strings.TrimSpace(ticket)
+	description = strings.TrimSpace(description)
+	if reason == "" || ticket == "" || utf8.RuneCountInString(ticket) > adjustmentTicketMaxLen ||
+		utf8.RuneCountInString(description) > adjustmentDescriptionMaxLen {
+		return nil, errs.ErrBadRequest
+	}
+	if amount.IsZero() {
+		return nil, errs.ErrOperationAmountInvalid
+	}
+	// Сотрудник не может корректировать собственный баланс
+	if staffID == userID {
+		return nil, errs.ErrForbidden
+	}
+
+	if description == "" {
+		description = "Корректировка баланса службой поддержки"
+	}
+	op := &models.Operation{
+		UserID:      userID,
+		Type:        models.BalanceAdjustment,
+		Status:      models.StatusProcessed,
+		Amount:      amount,
+		Description: description,
+	}
+	adj := &models.Adjustment{StaffID: staffID, Reason: reason, Ticket: ticket}
+
+	if err := u.repo.OperationAdjust(ctx, op, adj); errors.Is(err, errs.ErrNotFound) {
+		return nil, err
+	} else if err != nil {
+		u.log.WithReqID(ctx).Error().Err(err).Msg("failed to adjust balance")
+		return nil, err
+	}
+	u.log.WithReqID(ctx).Info().
+		Uint64("operation_id", op.ID).
+		Uint64("user_id", userID).
+		Uint64("staff_id", staffID).
+		Str("amount", amount.String()).
+		Str("ticket", ticket).
+		Str("reason", reason).
+		Msg("balance adjusted")
+	return op, nil
+}
@@ -0,0 +1,58 @@
+package usecases
+
+import (
+	"context"
+	"strings"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/mock"
+
+	"gophermart-loyalty/internal/errs"
+	"gophermart-loyalty/internal/models"
+)
+
+func (suite *useCasesSuite) TestBalanceAdjust() {
+	suite.Run("success", func() {
+		suite.repo.On("OperationAdjust", mock.Anything, mock.Anything, mock.Anything).
+			Return(func(_ context.Context, op *models.Operation, adj *models.Adjustment) error {
+				suite.Equal(uint64(2), op.UserID)
+				suite.Equal(models.BalanceAdjustment, op.Type)
+				suite.Equal(models.StatusProcessed, op.Status)
+				suite.Equal("-15", op.Amount.String())
+				suite.Equal("Корректировка баланса службой поддержки", op.Description)
+				suite.Equal(uint64(1), adj.StaffID)
+				suite.Equal("duplicate accrual", adj.Reason)
+				suite.Equal("SUP-1", adj.Ticket)
+				return nil
+			}).Once()
+
+		op, err := suite.useCases.BalanceAdjust(suite.ctx(), 1, 2, decimal.NewFromInt(-15), " duplicate accrual ", "SUP-1", "")
+		suite.NoError(err)
+		suite.NotNil(op)
+	})
+
+	suite.Run("reason and ticket required", func() {
+		_, err := suite.useCases.BalanceAdjust(suite.ctx(), 1, 2, decimal.NewFromInt(10), " ", "SUP-1", "")
+		suite.ErrorIs(err, errs.ErrBadRequest)
+		_, err = suite.useCases.BalanceAdjust(suite.ctx(), 1, 2, decimal.NewFromInt(10), "test", "", "")
+		suite.ErrorIs(err, errs.ErrBadRequest)
+		_, err = suite.useCases.BalanceAdjust(suite.ctx(), 1, 2, decimal.NewFromInt(10), "test", strings.Repeat("1", 65), "")
+		suite.ErrorIs(err, errs.ErrBadRequest)
+	})
+
+	suite.Run("zero amount", func() {
+		_, err := suite.useCases.BalanceAdjust(suite.ctx(), 1, 2, decimal.Zero, "test", "SUP-1", "")
+		suite.ErrorIs(err, errs.ErrOperationAmountInvalid)
+	})
+
+	suite.Run("own balance", func() {
+		_, err := suite.useCases.BalanceAdjust(suite.ctx(), 1, 1, decimal.NewFromInt(10), "test", "SUP-1", "")
+		suite.ErrorIs(err, errs.ErrForbidden)
+	})
+
+	suite.Run("insufficient funds", func() {
+		suite.repo.On("OperationAdjust", mock.Anything, mock.Anything, mock.Anything).Return(errs.ErrUserBalanceNegative).Once()
+		_, err := suite.useCases.BalanceAdjust(suite.ctx(), 1, 2, decimal.NewFromInt(-1000), "test", "SUP-1", "")
+		suite.ErrorIs(err, errs.ErrUserBalanceNegative)
+	})
+}
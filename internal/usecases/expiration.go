@@ -0,0 +1,104 @@
+package usecases
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"gophermart-loyalty/internal/config"
+	"gophermart-loyalty/internal/errs"
+	"gophermart-loyalty/internal/models"
+)
+
+// expirationBatchSize - число пользователей, проверяемых одним запросом
+const expirationBatchSize = 500
+
+// PointsExpire - сжигает неизрасходованные баллы всех пользователей, срок жизни которых истек.
+// Возвращает созданные операции сгорания баллов.
+func (u *UseCases) PointsExpire(ctx context.Context, cfg *config.Expiration) ([]*models.Operation, error) {
+	lifetimes := pointsLifetimes(cfg)
+	minLifetime := time.Duration(0)
+	for _, l := range lifetimes {
+		if minLifetime == 0 || l < minLifetime {
+			minLifetime = l
+		}
+	}
+	// Баллы не сгорают
+	if minLifetime == 0 {
+		return nil, nil
+	}
+
+	var expired []*models.Operation
+	afterID := uint64(0)
+	for {
+		ids, err := u.repo.OperationExpirationCandidates(ctx, afterID, int64(minLifetime.Seconds()), expirationBatchSize)
+		if err != nil {
+			u.log.WithReqID(ctx).Error().Err(err).Msg("failed to get users for points expiration")
+			return nil, err
+		}
+		for _, id := range ids {
+			afterID = id
+			op, err := u.repo.OperationExpire(ctx, id, func(ctx context.Context, ops []*models.Operation) (*models.Operation, error) {
+				return pointsExpirationPrepare(id, ops, lifetimes, time.Now()), nil
+			})
+			if errors.Is(err, errs.ErrNotFound) {
+				// Учетная запись удалена после выборки
+				continue
+			} else if err != nil {
+				u.log.WithReqID(ctx).Error().Err(err).Uint64("user_id", id).Msg("failed to expire points")
+				return nil, err
+			}
+			if op != nil {
+				u.log.WithReqID(ctx).Info().
+					Uint64("user_id", id).
+					Uint64("operation_id", op.ID).
+					Str("amount", op.Amount.String()).
+					Msg("points expired")
+				expired = append(expired, op)
+			}
+		}
+		if len(ids) < expirationBatchSize {
+			return expired, nil
+		}
+	}
+}
+
+// PointsExpiringGet - возвращает начисления пользователя с неизрасходованными баллами,
+// которые сгорят в будущем, в порядке сгорания.
+func (u *UseCases) PointsExpiringGet(ctx context.Context, userID uint64, cfg *config.Expiration) ([]*models.PointsLot, error) {
+	ops, _, err := u.UserBalanceHistoryGetByID(ctx, userID, nil)
+	if err != nil {
+		return nil, err
+	}
+	lots := models.PointsLots(ops, pointsLifetimes(cfg))
+	return models.PointsExpiring(lots, time.Now()), nil
+}
+
+// pointsExpirationPrepare - создает модель операции сгорания баллов пользователя userID, срок жизни которых истек
+// к моменту now. Если сжигать нечего, возвращает nil.
+func pointsExpirationPrepare(userID uint64, ops []*models.Operation, lifetimes map[models.OperationType]time.Duration, now time.Time) *models.Operation {
+	amount := models.PointsExpired(models.PointsLots(ops, lifetimes), now)
+	if !amount.IsPositive() {
+		return nil
+	}
+	return &models.Operation{
+		UserID:      userID,
+		Type:        models.PointsExpiration,
+		Status:      models.StatusProcessed,
+		Amount:      amount.Neg(),
+		Description: "Сгорание баллов, срок жизни которых истек",
+	}
+}
+
+// pointsLifetimes - возвращает срок жизни баллов по типам операций начисления.
+// Типы операций, баллы которых не сгорают, не включаются.
+func pointsLifetimes(cfg *config.Expiration) map[models.OperationType]time.Duration {
+	lifetimes := make(map[models.OperationType]time.Duration)
+	if cfg.OrderAccrual > 0 {
+		lifetimes[models.OrderAccrual] = cfg.OrderAccrual
+	}
+	if cfg.PromoAccrual > 0 {
+		lifetimes[models.PromoAccrual] = cfg.PromoAccrual
+	}
+	return lifetimes
+}
@@ -0,0 +1,78 @@
+package usecases
+
+import (
+	"context"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/mock"
+
+	"gophermart-loyalty/internal/config"
+	"gophermart-loyalty/internal/errs"
+	"gophermart-loyalty/internal/models"
+	"gophermart-loyalty/internal/repo"
+)
+
+func (suite *useCasesSuite) TestPointsExpire() {
+	cfg := &config.Expiration{OrderAccrual: 365 * 24 * time.Hour, PromoAccrual: 90 * 24 * time.Hour}
+	minLifetime := int64(cfg.PromoAccrual.Seconds())
+	accruedAt := time.Now().Add(-100 * 24 * time.Hour)
+	ops := []*models.Operation{
+		{ID: 1, UserID: 1, Type: models.PromoAccrual, Status: models.StatusProcessed, Amount: decimal.NewFromInt(10), CreatedAt: accruedAt, UpdatedAt: accruedAt, ProcessedAt: &accruedAt},
+		{ID: 2, UserID: 1, Type: models.OrderAccrual, Status: models.StatusProcessed, Amount: decimal.NewFromInt(100), CreatedAt: accruedAt, UpdatedAt: accruedAt, ProcessedAt: &accruedAt},
+	}
+	// expire - вызывает коллбэк с операциями ops и возвращает его результат
+	expire := func(ctx context.Context, _ uint64, f repo.ExpireFunc) *models.Operation {
+		op, _ := f(ctx, ops)
+		return op
+	}
+
+	suite.Run("success", func() {
+		suite.repo.On("OperationExpirationCandidates", mock.Anything, uint64(0), minLifetime, expirationBatchSize).
+			Return([]uint64{1, 2}, nil).Once()
+		suite.repo.On("OperationExpire", mock.Anything, uint64(1), mock.Anything).Return(expire, nil).Once()
+		suite.repo.On("OperationExpire", mock.Anything, uint64(2), mock.Anything).Return(nil, errs.ErrNotFound).Once()
+
+		expired, err := suite.useCases.PointsExpire(suite.ctx(), cfg)
+		suite.NoError(err)
+		suite.Require().Len(expired, 1)
+		suite.Equal(models.PointsExpiration, expired[0].Type)
+		suite.Equal(models.StatusProcessed, expired[0].Status)
+		suite.Equal("-10", expired[0].Amount.String())
+	})
+
+	suite.Run("nothing to expire", func() {
+		ops[0].Status = models.StatusInvalid
+		defer func() { ops[0].Status = models.StatusProcessed }()
+		suite.repo.On("OperationExpirationCandidates", mock.Anything, uint64(0), minLifetime, expirationBatchSize).
+			Return([]uint64{1}, nil).Once()
+		suite.repo.On("OperationExpire", mock.Anything, uint64(1), mock.Anything).Return(expire, nil).Once()
+
+		expired, err := suite.useCases.PointsExpire(suite.ctx(), cfg)
+		suite.NoError(err)
+		suite.Empty(expired)
+	})
+
+	suite.Run("expiration disabled", func() {
+		expired, err := suite.useCases.PointsExpire(suite.ctx(), &config.Expiration{})
+		suite.NoError(err)
+		suite.Empty(expired)
+	})
+}
+
+func (suite *useCasesSuite) TestPointsExpiringGet() {
+	cfg := &config.Expiration{PromoAccrual: 90 * 24 * time.Hour}
+	accruedAt := time.Now().Add(-24 * time.Hour)
+	suite.repo.On("UserBalanceHistoryGetByID", mock.Anything, uint64(1), mock.Anything).Return([]*models.Operation{
+		{ID: 1, Type: models.PromoAccrual, Status: models.StatusProcessed, Amount: decimal.NewFromInt(10), CreatedAt: accruedAt, UpdatedAt: accruedAt, ProcessedAt: &accruedAt},
+		{ID: 2, Type: models.OrderAccrual, Status: models.StatusProcessed, Amount: decimal.NewFromInt(100), CreatedAt: accruedAt, UpdatedAt: accruedAt, ProcessedAt: &accruedAt},
+		{ID: 3, Type: models.OrderWithdrawal, Status: models.StatusNew, Amount: decimal.NewFromInt(-4), CreatedAt: time.Now(), UpdatedAt: time.Now()},
+	}, nil).Once()
+
+	lots, err := suite.useCases.PointsExpiringGet(suite.ctx(), 1, cfg)
+	suite.NoError(err)
+	// баллы за заказы не сгорают, списание расходует начисление по промо-коду
+	suite.Require().Len(lots, 1)
+	suite.Equal(uint64(1), lots[0].OperationID)
+	suite.Equal("6", lots[0].Remaining.String())
+}
@@ -0,0 +1,273 @@
+package usecases
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/shopspring/decimal"
+
+	"gophermart-loyalty/internal/errs"
+	"gophermart-loyalty/internal/models"
+)
+
+// exportDocument - содержимое выгрузки персональных данных.
+// Хэши паролей и токенов в выгрузку не попадают.
+type exportDocument struct {
+	Profile        exportProfile       `json:"profile"`
+	Operations     []exportOperation   `json:"operations"`
+	BalanceHistory []exportOperation   `json:"balance_history"`
+	Promos         []exportPromo       `json:"promos"`
+	Sessions       []exportSession     `json:"sessions"`
+	AccessTokens   []exportAccessToken `json:"access_tokens"`
+}
+
+type exportProfile struct {
+	ID        uint64          `json:"id"`
+	Login     string          `json:"login"`
+	Role      models.UserRole `json:"role"`
+	Balance   decimal.Decimal `json:"balance"`
+	Withdrawn decimal.Decimal `json:"withdrawn"`
+	CreatedAt time.Time       `json:"created_at"`
+	UpdatedAt time.Time       `json:"updated_at"`
+}
+
+type exportOperation struct {
+	ID          uint64                 `json:"id"`
+	Type        models.OperationType   `json:"type"`
+	Status      models.OperationStatus `json:"status"`
+	Amount      decimal.Decimal        `json:"amount"`
+	Description string                 `json:"description"`
+	OrderNumber *string                `json:"order_number,omitempty"`
+	PromoID     *uint64                `json:"promo_id,omitempty"`
+	CreatedAt   time.Time              `json:"created_at"`
+	UpdatedAt   time.Time              `json:"updated_at"`
+}
+
+type exportPromo struct {
+	ID          uint64          `json:"id"`
+	Code        string          `json:"code"`
+	Description string          `json:"description"`
+	Reward      decimal.Decimal `json:"reward"`
+}
+
+type exportSession struct {
+	ID        uint64     `json:"id"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+	ExpiresAt time.Time  `json:"expires_at"`
+	RevokedAt *time.Time `json:"revoked_at,omitempty"`
+}
+
+type exportAccessToken struct {
+	ID         uint64                    `json:"id"`
+	Name       string                    `json:"name"`
+	Scopes     []models.AccessTokenScope `json:"scopes"`
+	CreatedAt  time.Time                 `json:"created_at"`
+	ExpiresAt  *time.Time                `json:"expires_at,omitempty"`
+	LastUsedAt *time.Time                `json:"last_used_at,omitempty"`
+}
+
+// DataExportCreate - ставит в очередь выгрузку персональных данных пользователя userID в формате format.
+// Если формат не задан, используется JSON.
+func (u *UseCases) DataExportCreate(ctx context.Context, userID uint64, format models.DataExportFormat) (*models.DataExport, error) {
+	if format == "" {
+		format = models.DataExportJSON
+	}
+	if !format.Valid() {
+		return nil, errs.ErrDataExportFormatInvalid
+	}
+
+	e := &models.DataExport{UserID: userID, Format: format}
+	if err := u.repo.DataExportCreate(ctx, e); errors.Is(err, errs.ErrDataExportInProgress) {
+		return nil, err
+	} else if err != nil {
+		u.log.WithReqID(ctx).Error().Err(err).Msg("failed to create data export")
+		return nil, err
+	}
+
+	u.log.WithReqID(ctx).Info().Uint64("user_id", userID).Uint64("export_id", e.ID).Msg("data export requested")
+	return e, nil
+}
+
+// DataExportGet - возвращает выгрузку exportID пользователя userID.
+func (u *UseCases) DataExportGet(ctx context.Context, userID, exportID uint64) (*models.DataExport, error) {
+	e, err := u.repo.DataExportGet(ctx, userID, exportID)
+	if errors.Is(err, errs.ErrNotFound) {
+		return nil, err
+	} else if err != nil {
+		u.log.WithReqID(ctx).Error().Err(err).Msg("failed to get data export")
+		return nil, err
+	}
+	return e, nil
+}
+
+// DataExportProcessFurther - формирует самую старую выгрузку в очереди и хранит ее в течение ttl.
+// Если данные пользователя собрать не удалось, выгрузка переводится в статус FAILED.
+// Если в очереди нет выгрузок, возвращает errs.ErrNotFound.
+func (u *UseCases) DataExportProcessFurther(ctx context.Context, ttl time.Duration) (*models.DataExport, error) {
+	return u.repo.DataExportProcessFurther(ctx, int64(ttl.Seconds()), func(ctx context.Context, e *models.DataExport) error {
+		payload, err := u.dataExportBuild(ctx, e)
+		if err != nil {
+			// Прерванное формирование повторяется при следующей проверке очереди
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			u.log.Error().Err(err).Uint64("export_id", e.ID).Msg("failed to build data export")
+			e.Status = models.DataExportFailed
+			e.Payload = nil
+			return nil
+		}
+		e.Status = models.DataExportReady
+		e.Payload = payload
+		return nil
+	})
+}
+
+// DataExportDeleteExpired - удаляет истекшие выгрузки. Возвращает число удаленных выгрузок.
+func (u *UseCases) DataExportDeleteExpired(ctx context.Context) (int64, error) {
+	return u.repo.DataExportDeleteExpired(ctx)
+}
+
+// dataExportBuild - собирает данные пользователя и возвращает содержимое файла выгрузки.
+func (u *UseCases) dataExportBuild(ctx context.Context, e *models.DataExport) ([]byte, error) {
+	doc, err := u.dataExportCollect(ctx, e.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	if e.Format == models.DataExportJSON {
+		return json.MarshalIndent(doc, "", "  ")
+	}
+
+	// В архиве каждый раздел выгрузки хранится в отдельном файле
+	buf := &bytes.Buffer{}
+	zw := zip.NewWriter(buf)
+	files := []struct {
+		name string
+		data interface{}
+	}{
+		{"profile.json", doc.Profile},
+		{"operations.json", doc.Operations},
+		{"balance_history.json", doc.BalanceHistory},
+		{"promos.json", doc.Promos},
+		{"sessions.json", doc.Sessions},
+		{"access_tokens.json", doc.AccessTokens},
+	}
+	for _, f := range files {
+		w, err := zw.CreateHeader(&zip.FileHeader{Name: f.name, Method: zip.Deflate, Modified: e.CreatedAt})
+		if err != nil {
+			return nil, err
+		}
+		data, err := json.MarshalIndent(f.data, "", "  ")
+		if err != nil {
+			return nil, err
+		}
+		if _, err = w.Write(data); err != nil {
+			return nil, err
+		}
+	}
+	if err = zw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// dataExportCollect - собирает данные пользователя userID для выгрузки.
+func (u *UseCases) dataExportCollect(ctx context.Context, userID uint64) (*exportDocument, error) {
+	user, err := u.repo.UserGetByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	doc := &exportDocument{
+		Profile: exportProfile{
+			ID:        user.ID,
+			Login:     user.Login,
+			Role:      user.Role,
+			Balance:   user.Balance,
+			Withdrawn: user.Withdrawn,
+			CreatedAt: user.CreatedAt,
+			UpdatedAt: user.UpdatedAt,
+		},
+		Operations:     []exportOperation{},
+		BalanceHistory: []exportOperation{},
+		Promos:         []exportPromo{},
+		Sessions:       []exportSession{},
+		AccessTokens:   []exportAccessToken{},
+	}
+
+	for _, t := range models.OperationTypes {
+		ops, err := u.repo.OperationGetByType(ctx, userID, t, nil)
+		if err != nil && !errors.Is(err, errs.ErrNotFound) {
+			return nil, err
+		}
+		doc.Operations = append(doc.Operations, exportOperations(ops)...)
+	}
+
+	history, err := u.repo.UserBalanceHistoryGetByID(ctx, userID, nil)
+	if err != nil && !errors.Is(err, errs.ErrNotFound) {
+		return nil, err
+	}
+	doc.BalanceHistory = append(doc.BalanceHistory, exportOperations(history)...)
+
+	promos, err := u.repo.PromoGetByUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	for _, p := range promos {
+		doc.Promos = append(doc.Promos, exportPromo{ID: p.ID, Code: p.Code, Description: p.Description, Reward: p.Reward})
+	}
+
+	sessions, err := u.repo.SessionGetByUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	for _, s := range sessions {
+		doc.Sessions = append(doc.Sessions, exportSession{
+			ID:        s.ID,
+			CreatedAt: s.CreatedAt,
+			UpdatedAt: s.UpdatedAt,
+			ExpiresAt: s.ExpiresAt,
+			RevokedAt: s.RevokedAt,
+		})
+	}
+
+	tokens, err := u.repo.AccessTokenGetByUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	for _, t := range tokens {
+		doc.AccessTokens = append(doc.AccessTokens, exportAccessToken{
+			ID:         t.ID,
+			Name:       t.Name,
+			Scopes:     t.Scopes,
+			CreatedAt:  t.CreatedAt,
+			ExpiresAt:  t.ExpiresAt,
+			LastUsedAt: t.LastUsedAt,
+		})
+	}
+
+	return doc, nil
+}
+
+// exportOperations - преобразует операции для выгрузки.
+func exportOperations(ops []*models.Operation) []exportOperation {
+	list := make([]exportOperation, 0, len(ops))
+	for _, op := range ops {
+		list = append(list, exportOperation{
+			ID:          op.ID,
+			Type:        op.Type,
+			Status:      op.Status,
+			Amount:      op.Amount,
+			Description: op.Description,
+			OrderNumber: op.OrderNumber,
+			PromoID:     op.PromoID,
+			CreatedAt:   op.CreatedAt,
+			UpdatedAt:   op.UpdatedAt,
+		})
+	}
+	return list
+}
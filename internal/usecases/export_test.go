@@ -0,0 +1,121 @@
+package usecases
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/mock"
+
+	"gophermart-loyalty/internal/errs"
+	"gophermart-loyalty/internal/models"
+	"gophermart-loyalty/internal/repo"
+)
+
+func (suite *useCasesSuite) TestDataExportCreate() {
+	suite.Run("default format", func() {
+		suite.repo.On("DataExportCreate", mock.Anything, mock.MatchedBy(func(e *models.DataExport) bool {
+			return e.UserID == 1 && e.Format == models.DataExportJSON
+		})).Return(nil).Once()
+		_, err := suite.useCases.DataExportCreate(suite.ctx(), 1, "")
+		suite.NoError(err)
+	})
+
+	suite.Run("invalid format", func() {
+		_, err := suite.useCases.DataExportCreate(suite.ctx(), 1, "xml")
+		suite.ErrorIs(err, errs.ErrDataExportFormatInvalid)
+	})
+}
+
+// mockDataExportProcess - настраивает DataExportProcessFurther так, чтобы коллбэк вызывался для выгрузки e.
+func (suite *useCasesSuite) mockDataExportProcess(e *models.DataExport) {
+	suite.repo.On("DataExportProcessFurther", mock.Anything, int64(3600), mock.Anything).
+		Return(func(ctx context.Context, _ int64, f repo.DataExportFunc) *models.DataExport {
+			if err := f(ctx, e); err != nil {
+				return nil
+			}
+			return e
+		}, func(ctx context.Context, _ int64, f repo.DataExportFunc) error {
+			return nil
+		}).Once()
+}
+
+// mockDataExportCollect - настраивает чтение данных пользователя 1 для выгрузки.
+func (suite *useCasesSuite) mockDataExportCollect() {
+	suite.repo.On("UserGetByID", mock.Anything, uint64(1)).
+		Return(&models.User{ID: 1, Login: "user", PassHash: "secret-hash", Balance: decimal.NewFromInt(10)}, nil).Once()
+	for _, t := range models.OperationTypes {
+		var ops []*models.Operation
+		if t == models.OrderAccrual {
+			ops = []*models.Operation{{ID: 1, UserID: 1, Type: t, Status: models.StatusProcessed, Amount: decimal.NewFromInt(10), OrderNumber: strPtr("12345678903")}}
+		}
+		suite.repo.On("OperationGetByType", mock.Anything, uint64(1), t, mock.Anything).Return(ops, nil).Once()
+	}
+	suite.repo.On("UserBalanceHistoryGetByID", mock.Anything, uint64(1), mock.Anything).Return(nil, errs.ErrNotFound).Once()
+	suite.repo.On("PromoGetByUser", mock.Anything, uint64(1)).Return(nil, nil).Once()
+	suite.repo.On("SessionGetByUser", mock.Anything, uint64(1)).
+		Return([]*models.Session{{ID: 1, UserID: 1, RefreshHash: "refresh-hash"}}, nil).Once()
+	suite.repo.On("AccessTokenGetByUser", mock.Anything, uint64(1)).
+		Return([]*models.AccessToken{{ID: 1, UserID: 1, Name: "script", TokenHash: "token-hash"}}, nil).Once()
+}
+
+func (suite *useCasesSuite) TestDataExportProcessFurther() {
+	suite.Run("json", func() {
+		e := &models.DataExport{ID: 1, UserID: 1, Format: models.DataExportJSON, Status: models.DataExportNew}
+		suite.mockDataExportProcess(e)
+		suite.mockDataExportCollect()
+
+		_, err := suite.useCases.DataExportProcessFurther(suite.ctx(), time.Hour)
+		suite.NoError(err)
+		suite.Equal(models.DataExportReady, e.Status)
+
+		doc := map[string]interface{}{}
+		suite.Require().NoError(json.Unmarshal(e.Payload, &doc))
+		suite.Equal("user", doc["profile"].(map[string]interface{})["login"])
+		suite.Len(doc["operations"], 1)
+		suite.Len(doc["sessions"], 1)
+		suite.Len(doc["access_tokens"], 1)
+		suite.Empty(doc["promos"])
+
+		// хэши паролей и токенов не попадают в выгрузку
+		for _, hash := range []string{"secret-hash", "refresh-hash", "token-hash"} {
+			suite.NotContains(string(e.Payload), hash)
+		}
+	})
+
+	suite.Run("zip", func() {
+		e := &models.DataExport{ID: 2, UserID: 1, Format: models.DataExportZIP, Status: models.DataExportNew}
+		suite.mockDataExportProcess(e)
+		suite.mockDataExportCollect()
+
+		_, err := suite.useCases.DataExportProcessFurther(suite.ctx(), time.Hour)
+		suite.NoError(err)
+		suite.Equal(models.DataExportReady, e.Status)
+
+		zr, err := zip.NewReader(bytes.NewReader(e.Payload), int64(len(e.Payload)))
+		suite.Require().NoError(err)
+		var names []string
+		for _, f := range zr.File {
+			names = append(names, f.Name)
+		}
+		suite.ElementsMatch([]string{
+			"profile.json", "operations.json", "balance_history.json",
+			"promos.json", "sessions.json", "access_tokens.json",
+		}, names)
+	})
+
+	suite.Run("failed", func() {
+		e := &models.DataExport{ID: 3, UserID: 1, Format: models.DataExportJSON, Status: models.DataExportNew}
+		suite.mockDataExportProcess(e)
+		suite.repo.On("UserGetByID", mock.Anything, uint64(1)).Return(nil, errors.New("db error")).Once()
+
+		_, err := suite.useCases.DataExportProcessFurther(suite.ctx(), time.Hour)
+		suite.NoError(err)
+		suite.Equal(models.DataExportFailed, e.Status)
+		suite.Nil(e.Payload)
+	})
+}
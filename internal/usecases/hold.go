@@ -0,0 +1,64 @@
+package usecases
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/shopspring/decimal"
+
+	"gophermart-loyalty/internal/config"
+	"gophermart-loyalty/internal/errs"
+	"gophermart-loyalty/internal/models"
+)
+
+// holdBatchSize - число пользователей, проверяемых одним запросом
+const holdBatchSize = 500
+
+// PointsHold - удерживает баллы, начисленные обработанной операцией op, в течение срока удержания
+// для операций этого типа. Удержанные баллы учитываются в балансе, но не могут быть потрачены до снятия удержания.
+func (u *UseCases) PointsHold(op *models.Operation, cfg *config.Hold) {
+	op.Hold(holdPeriods(cfg)[op.Type])
+}
+
+// PointsRelease - снимает удержание с начислений всех пользователей, срок удержания которых истек.
+// Возвращает сумму баллов, которые стали доступны.
+func (u *UseCases) PointsRelease(ctx context.Context) (decimal.Decimal, error) {
+	total := decimal.Zero
+	afterID := uint64(0)
+	for {
+		ids, err := u.repo.OperationHoldCandidates(ctx, afterID, holdBatchSize)
+		if err != nil {
+			u.log.WithReqID(ctx).Error().Err(err).Msg("failed to get users for points release")
+			return decimal.Zero, err
+		}
+		for _, id := range ids {
+			afterID = id
+			released, err := u.repo.OperationHoldRelease(ctx, id)
+			if errors.Is(err, errs.ErrNotFound) {
+				// Учетная запись удалена после выборки
+				continue
+			} else if err != nil {
+				u.log.WithReqID(ctx).Error().Err(err).Uint64("user_id", id).Msg("failed to release points")
+				return decimal.Zero, err
+			}
+			total = total.Add(released)
+		}
+		if len(ids) < holdBatchSize {
+			return total, nil
+		}
+	}
+}
+
+// holdPeriods - возвращает срок удержания баллов по типам операций начисления.
+// Типы операций, баллы которых не удерживаются, не включаются.
+func holdPeriods(cfg *config.Hold) map[models.OperationType]time.Duration {
+	periods := make(map[models.OperationType]time.Duration)
+	if cfg.OrderAccrual > 0 {
+		periods[models.OrderAccrual] = cfg.OrderAccrual
+	}
+	if cfg.PromoAccrual > 0 {
+		periods[models.PromoAccrual] = cfg.PromoAccrual
+	}
+	return periods
+}
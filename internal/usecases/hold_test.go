@@ -0,0 +1,55 @@
+package usecases
+
+import (
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/mock"
+
+	"gophermart-loyalty/internal/config"
+	"gophermart-loyalty/internal/errs"
+	"gophermart-loyalty/internal/models"
+)
+
+func (suite *useCasesSuite) TestPointsHold() {
+	cfg := &config.Hold{OrderAccrual: 14 * 24 * time.Hour}
+
+	suite.Run("processed order accrual is held", func() {
+		op := &models.Operation{Type: models.OrderAccrual, Status: models.StatusProcessed, Amount: decimal.NewFromInt(100)}
+		suite.useCases.PointsHold(op, cfg)
+		suite.Equal(cfg.OrderAccrual, op.HoldPeriod)
+	})
+
+	suite.Run("promo accrual is not held", func() {
+		op := &models.Operation{Type: models.PromoAccrual, Status: models.StatusProcessed, Amount: decimal.NewFromInt(100)}
+		suite.useCases.PointsHold(op, cfg)
+		suite.Zero(op.HoldPeriod)
+	})
+
+	suite.Run("pending accrual is not held", func() {
+		op := &models.Operation{Type: models.OrderAccrual, Status: models.StatusProcessing}
+		suite.useCases.PointsHold(op, cfg)
+		suite.Zero(op.HoldPeriod)
+	})
+}
+
+func (suite *useCasesSuite) TestPointsRelease() {
+	suite.Run("success", func() {
+		suite.repo.On("OperationHoldCandidates", mock.Anything, uint64(0), holdBatchSize).Return([]uint64{1, 2, 3}, nil).Once()
+		suite.repo.On("OperationHoldRelease", mock.Anything, uint64(1)).Return(decimal.NewFromInt(10), nil).Once()
+		suite.repo.On("OperationHoldRelease", mock.Anything, uint64(2)).Return(decimal.Zero, errs.ErrNotFound).Once()
+		suite.repo.On("OperationHoldRelease", mock.Anything, uint64(3)).Return(decimal.NewFromInt(5), nil).Once()
+
+		released, err := suite.useCases.PointsRelease(suite.ctx())
+		suite.NoError(err)
+		suite.Equal("15", released.String())
+	})
+
+	suite.Run("internal error", func() {
+		suite.repo.On("OperationHoldCandidates", mock.Anything, uint64(0), holdBatchSize).Return([]uint64{1}, nil).Once()
+		suite.repo.On("OperationHoldRelease", mock.Anything, uint64(1)).Return(decimal.Zero, errs.ErrInternal).Once()
+
+		_, err := suite.useCases.PointsRelease(suite.ctx())
+		suite.ErrorIs(err, errs.ErrInternal)
+	})
+}
@@ -0,0 +1,67 @@
+package usecases
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"gophermart-loyalty/internal/errs"
+	"gophermart-loyalty/internal/models"
+)
+
+// IdempotencyKeyReserve - занимает ключ идемпотентности key пользователя userID для запроса
+// с отпечатком fingerprint на время ttl.
+// Если ключ занят этим вызовом, возвращает nil, и запрос нужно выполнить.
+// Если запрос с тем же ключом уже выполнен, возвращает ключ с записанным ответом.
+// Если ключ использовался для запроса с другим отпечатком, возвращает errs.ErrIdempotencyKeyReused.
+// Если запрос с тем же ключом еще выполняется, возвращает errs.ErrIdempotencyKeyInProgress.
+func (u *UseCases) IdempotencyKeyReserve(ctx context.Context, userID uint64, key, fingerprint string, ttl time.Duration) (*models.IdempotencyKey, error) {
+	stored, err := u.repo.IdempotencyKeyReserve(ctx, &models.IdempotencyKey{
+		UserID:      userID,
+		Key:         key,
+		Fingerprint: fingerprint,
+	}, int64(ttl.Seconds()))
+	if errors.Is(err, errs.ErrNotFound) {
+		// Ключ освободили между попыткой занять его и чтением: запрос с ним только что завершился неудачей
+		return nil, errs.ErrIdempotencyKeyInProgress
+	} else if err != nil {
+		u.log.WithReqID(ctx).Error().Err(err).Msg("failed to reserve idempotency key")
+		return nil, err
+	}
+	if stored == nil {
+		return nil, nil
+	}
+
+	if stored.Fingerprint != fingerprint {
+		return nil, errs.ErrIdempotencyKeyReused
+	}
+	if !stored.Completed() {
+		return nil, errs.ErrIdempotencyKeyInProgress
+	}
+	u.log.WithReqID(ctx).Info().Uint64("user_id", userID).Msg("idempotent response replayed")
+	return stored, nil
+}
+
+// IdempotencyKeyComplete - записывает ответ k на запрос с ключом идемпотентности, занятым IdempotencyKeyReserve.
+func (u *UseCases) IdempotencyKeyComplete(ctx context.Context, k *models.IdempotencyKey) error {
+	if err := u.repo.IdempotencyKeyComplete(ctx, k); err != nil {
+		u.log.WithReqID(ctx).Error().Err(err).Msg("failed to complete idempotency key")
+		return err
+	}
+	return nil
+}
+
+// IdempotencyKeyRelease - освобождает ключ идемпотентности, ответ на запрос с которым не записан,
+// чтобы запрос можно было повторить.
+func (u *UseCases) IdempotencyKeyRelease(ctx context.Context, userID uint64, key string) error {
+	if err := u.repo.IdempotencyKeyRelease(ctx, userID, key); err != nil {
+		u.log.WithReqID(ctx).Error().Err(err).Msg("failed to release idempotency key")
+		return err
+	}
+	return nil
+}
+
+// IdempotencyKeyDeleteExpired - удаляет истекшие ключи идемпотентности. Возвращает число удаленных ключей.
+func (u *UseCases) IdempotencyKeyDeleteExpired(ctx context.Context) (int64, error) {
+	return u.repo.IdempotencyKeyDeleteExpired(ctx)
+}
@@ -0,0 +1,52 @@
+package usecases
+
+import (
+	"time"
+
+	"github.com/stretchr/testify/mock"
+
+	"gophermart-loyalty/internal/errs"
+	"gophermart-loyalty/internal/models"
+)
+
+func (suite *useCasesSuite) TestIdempotencyKeyReserve() {
+	suite.Run("reserved", func() {
+		suite.repo.On("IdempotencyKeyReserve", mock.Anything, mock.Anything, int64(3600)).Return(nil, nil).Once()
+
+		stored, err := suite.useCases.IdempotencyKeyReserve(suite.ctx(), 1, "key", "fp", time.Hour)
+		suite.NoError(err)
+		suite.Nil(stored)
+	})
+
+	suite.Run("completed response is replayed", func() {
+		k := &models.IdempotencyKey{UserID: 1, Key: "key", Fingerprint: "fp", StatusCode: 202, Body: []byte("{}")}
+		suite.repo.On("IdempotencyKeyReserve", mock.Anything, mock.Anything, int64(3600)).Return(k, nil).Once()
+
+		stored, err := suite.useCases.IdempotencyKeyReserve(suite.ctx(), 1, "key", "fp", time.Hour)
+		suite.NoError(err)
+		suite.Equal(k, stored)
+	})
+
+	suite.Run("key reused with another request", func() {
+		k := &models.IdempotencyKey{UserID: 1, Key: "key", Fingerprint: "other", StatusCode: 202}
+		suite.repo.On("IdempotencyKeyReserve", mock.Anything, mock.Anything, int64(3600)).Return(k, nil).Once()
+
+		_, err := suite.useCases.IdempotencyKeyReserve(suite.ctx(), 1, "key", "fp", time.Hour)
+		suite.ErrorIs(err, errs.ErrIdempotencyKeyReused)
+	})
+
+	suite.Run("request in progress", func() {
+		k := &models.IdempotencyKey{UserID: 1, Key: "key", Fingerprint: "fp"}
+		suite.repo.On("IdempotencyKeyReserve", mock.Anything, mock.Anything, int64(3600)).Return(k, nil).Once()
+
+		_, err := suite.useCases.IdempotencyKeyReserve(suite.ctx(), 1, "key", "fp", time.Hour)
+		suite.ErrorIs(err, errs.ErrIdempotencyKeyInProgress)
+	})
+
+	suite.Run("key released concurrently", func() {
+		suite.repo.On("IdempotencyKeyReserve", mock.Anything, mock.Anything, int64(3600)).Return(nil, errs.ErrNotFound).Once()
+
+		_, err := suite.useCases.IdempotencyKeyReserve(suite.ctx(), 1, "key", "fp", time.Hour)
+		suite.ErrorIs(err, errs.ErrIdempotencyKeyInProgress)
+	})
+}
@@ -0,0 +1,191 @@
+package usecases
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"gophermart-loyalty/internal/config"
+	"gophermart-loyalty/internal/errs"
+	"gophermart-loyalty/internal/models"
+)
+
+// Префиксы ключей счетчиков неудачных попыток входа
+const (
+	loginFailureKeyLogin = "login:"
+	loginFailureKeyIP    = "ip:"
+)
+
+// UserLogin - проверяет логин и пароль пользователя с защитой от перебора паролей.
+// Попытки входа учитываются отдельно по логину и по IP-адресу ip:
+//   - после cfg.FreeAttempts неудачных попыток по логину следующая попытка возможна только через задержку,
+//     которая удваивается с каждой неудачной попыткой, но не превышает cfg.MaxDelay;
+//   - после cfg.MaxFailures неудачных попыток по логину учетная запись блокируется на cfg.Lockout;
+//   - после cfg.IPMaxFailures неудачных попыток с IP-адреса адрес блокируется на cfg.Lockout.
+// Попытка учитывается в счетчиках до проверки пароля, поэтому параллельные запросы не могут превысить
+// порог блокировки: попытки сверх порога отклоняются без проверки пароля.
+// Успешный вход сбрасывает счетчик по логину. Если у пользователя включена двухфакторная аутентификация,
+// возвращается mfa = true, а счетчик сбрасывается только после ввода кода второго фактора в LoginChallengeVerify.
+// Возвращает пользователя, если логин и пароль верны.
+func (u *UseCases) UserLogin(ctx context.Context, login, password, ip string, cfg *config.LoginThrottle) (user *models.User, mfa bool, err error) {
+	// Учитываем попытку входа, если попытки входа не заблокированы
+	attempts, err := u.loginAttemptsRegister(ctx, login, ip, cfg)
+	if err != nil {
+		return nil, false, err
+	}
+	// Если проверка не завершилась неудачной попыткой, попытка исключается из счетчиков
+	failed := false
+	defer func() {
+		if !failed {
+			u.loginAttemptsRelease(ctx, attempts)
+		}
+	}()
+
+	// Проверяем логин и пароль
+	user, err = u.UserCheckLoginPass(ctx, login, password)
+	if errors.Is(err, errs.ErrUserLoginPassMismatch) {
+		failed = true
+		u.loginFailuresRegister(ctx, attempts, cfg)
+		return nil, false, err
+	} else if err != nil {
+		return nil, false, err
+	}
+
+	// Если включена двухфакторная аутентификация, вход еще не завершен,
+	// и неудачные попытки ввода кода учитываются в том же счетчике по логину
+	mfa, err = u.TOTPEnabled(ctx, user.ID)
+	if err != nil {
+		return nil, false, err
+	}
+	if !mfa {
+		u.loginFailureReset(ctx, login)
+	}
+	return user, mfa, nil
+}
+
+// loginAttempts - попытка входа, учтенная в счетчиках по логину и по IP-адресу.
+type loginAttempts struct {
+	login *models.LoginFailure
+	ip    *models.LoginFailure
+}
+
+// loginAttemptsRegister - учитывает попытку входа по логину login и IP-адресу ip.
+// Если попытки входа заблокированы или порог блокировки уже достигнут параллельными попытками,
+// попытка не учитывается и возвращается errs.ErrUserLocked или errs.ErrLoginThrottled.
+func (u *UseCases) loginAttemptsRegister(ctx context.Context, login, ip string, cfg *config.LoginThrottle) (*loginAttempts, error) {
+	f, err := u.repo.LoginAttemptRegister(ctx, loginFailureKeyLogin+login, int64(cfg.Window.Seconds()))
+	if err != nil {
+		u.log.WithReqID(ctx).Error().Err(err).Msg("failed to register login attempt")
+		return nil, err
+	}
+	if f.Blocked() {
+		if cfg.MaxFailures > 0 && f.Failures >= cfg.MaxFailures {
+			u.log.WithReqID(ctx).Info().Str("login", login).Msg("login attempt for locked user")
+			return nil, errs.ErrUserLocked
+		}
+		u.log.WithReqID(ctx).Info().Str("login", login).Str("ip", ip).Msg("login attempt throttled")
+		return nil, errs.ErrLoginThrottled
+	}
+	attempts := &loginAttempts{login: f}
+
+	f, err = u.repo.LoginAttemptRegister(ctx, loginFailureKeyIP+ip, int64(cfg.Window.Seconds()))
+	if err != nil {
+		u.log.WithReqID(ctx).Error().Err(err).Msg("failed to register login attempt")
+		u.loginAttemptsRelease(ctx, attempts)
+		return nil, err
+	}
+	if f.Blocked() {
+		u.log.WithReqID(ctx).Info().Str("login", login).Str("ip", ip).Msg("login attempt throttled")
+		u.loginAttemptsRelease(ctx, attempts)
+		return nil, errs.ErrLoginThrottled
+	}
+	attempts.ip = f
+
+	// Попытки сверх порога блокировки учтены параллельно с предыдущими попытками,
+	// которые еще выполняются, поэтому отклоняются без проверки пароля
+	if cfg.MaxFailures > 0 && attempts.login.Failures > cfg.MaxFailures ||
+		cfg.IPMaxFailures > 0 && attempts.ip.Failures > cfg.IPMaxFailures {
+		u.log.WithReqID(ctx).Info().Str("login", login).Str("ip", ip).Msg("login attempt throttled")
+		u.loginAttemptsRelease(ctx, attempts)
+		return nil, errs.ErrLoginThrottled
+	}
+	return attempts, nil
+}
+
+// loginAttemptsRelease - исключает попытку входа из счетчиков, если она не оказалась неудачной.
+// Ошибки только логируются: попытка остается учтенной до окончания окна счетчика.
+func (u *UseCases) loginAttemptsRelease(ctx context.Context, attempts *loginAttempts) {
+	for _, f := range []*models.LoginFailure{attempts.login, attempts.ip} {
+		if f == nil {
+			continue
+		}
+		if err := u.repo.LoginAttemptRelease(ctx, f.Key); err != nil {
+			u.log.WithReqID(ctx).Error().Err(err).Msg("failed to release login attempt")
+		}
+	}
+}
+
+// loginFailuresRegister - блокирует попытки входа по логину и по IP-адресу после неудачной попытки.
+// Время блокировки определяется номером попытки в счетчике, полученным при ее учете.
+func (u *UseCases) loginFailuresRegister(ctx context.Context, attempts *loginAttempts, cfg *config.LoginThrottle) {
+	u.loginFailureBlock(ctx, attempts.login.Key, loginBlock(attempts.login.Failures, cfg))
+	u.loginFailureBlock(ctx, attempts.ip.Key, ipBlock(attempts.ip.Failures, cfg))
+}
+
+// loginFailureReset - сбрасывает счетчик неудачных попыток входа по логину login после успешного входа.
+// Счетчик по IP-адресу не сбрасывается, чтобы перебор по многим логинам с одного адреса
+// не обнулялся удачным входом в свою учетную запись.
+func (u *UseCases) loginFailureReset(ctx context.Context, login string) {
+	if err := u.repo.LoginFailureReset(ctx, loginFailureKeyLogin+login); err != nil {
+		u.log.WithReqID(ctx).Error().Err(err).Msg("failed to reset login failures")
+	}
+}
+
+// loginFailureBlock - блокирует попытки входа по ключу key на время block.
+// Ошибки только логируются, чтобы не раскрывать их пользователю вместо ошибки неверного пароля.
+func (u *UseCases) loginFailureBlock(ctx context.Context, key string, block time.Duration) {
+	f, err := u.repo.LoginFailureBlock(ctx, key, int64(block.Seconds()))
+	if err != nil {
+		u.log.WithReqID(ctx).Error().Err(err).Msg("failed to register login failure")
+		return
+	}
+	u.log.WithReqID(ctx).Debug().
+		Str("key", f.Key).
+		Int("failures", f.Failures).
+		Time("blocked_until", f.BlockedUntil).
+		Msg("login failure registered")
+}
+
+// loginBlock - возвращает время блокировки попыток входа по логину после failures неудачных попыток подряд.
+func loginBlock(failures int, cfg *config.LoginThrottle) time.Duration {
+	if cfg.MaxFailures > 0 && failures >= cfg.MaxFailures {
+		return cfg.Lockout
+	}
+	return loginDelay(failures, cfg)
+}
+
+// ipBlock - возвращает время блокировки попыток входа с IP-адреса после failures неудачных попыток подряд.
+func ipBlock(failures int, cfg *config.LoginThrottle) time.Duration {
+	if cfg.IPMaxFailures > 0 && failures >= cfg.IPMaxFailures {
+		return cfg.Lockout
+	}
+	return 0
+}
+
+// loginDelay - возвращает задержку перед следующей попыткой входа после failures неудачных попыток подряд.
+func loginDelay(failures int, cfg *config.LoginThrottle) time.Duration {
+	if cfg.BaseDelay <= 0 || failures <= cfg.FreeAttempts {
+		return 0
+	}
+	delay := cfg.BaseDelay
+	for i := cfg.FreeAttempts + 1; i < failures; i++ {
+		delay *= 2
+		if cfg.MaxDelay > 0 && delay >= cfg.MaxDelay {
+			return cfg.MaxDelay
+		}
+	}
+	if cfg.MaxDelay > 0 && delay > cfg.MaxDelay {
+		return cfg.MaxDelay
+	}
+	return delay
+}
@@ -0,0 +1,144 @@
+package usecases
+
+import (
+	"time"
+
+	"github.com/stretchr/testify/mock"
+	"golang.org/x/crypto/bcrypt"
+
+	"gophermart-loyalty/internal/config"
+	"gophermart-loyalty/internal/errs"
+	"gophermart-loyalty/internal/models"
+)
+
+func (suite *useCasesSuite) TestUserLogin() {
+	hash, err := bcrypt.GenerateFromPassword([]byte("password"), bcrypt.MinCost)
+	suite.Require().NoError(err)
+	user := &models.User{ID: 1, Login: "oleg", PassHash: string(hash)}
+	cfg := &config.LoginThrottle{
+		FreeAttempts:  3,
+		BaseDelay:     time.Second,
+		MaxDelay:      30 * time.Second,
+		MaxFailures:   10,
+		IPMaxFailures: 100,
+		Lockout:       15 * time.Minute,
+		Window:        15 * time.Minute,
+	}
+
+	attempt := func(key string, failures int) *models.LoginFailure {
+		return &models.LoginFailure{Key: key, Failures: failures, LastFailedAt: time.Now()}
+	}
+	blocked := func(key string, failures int) *models.LoginFailure {
+		now := time.Now()
+		return &models.LoginFailure{Key: key, Failures: failures, LastFailedAt: now.Add(-time.Minute), BlockedUntil: now.Add(time.Minute)}
+	}
+
+	suite.Run("success", func() {
+		suite.repo.On("LoginAttemptRegister", mock.Anything, "login:oleg", int64(900)).Return(attempt("login:oleg", 1), nil).Once()
+		suite.repo.On("LoginAttemptRegister", mock.Anything, "ip:10.0.0.1", int64(900)).Return(attempt("ip:10.0.0.1", 1), nil).Once()
+		suite.repo.On("UserGetByLogin", mock.Anything, "oleg").Return(user, nil).Once()
+		suite.repo.On("TOTPGet", mock.Anything, uint64(1)).Return(nil, errs.ErrNotFound).Once()
+		suite.repo.On("LoginFailureReset", mock.Anything, "login:oleg").Return(nil).Once()
+		// удачная попытка исключается из счетчика по IP-адресу
+		suite.repo.On("LoginAttemptRelease", mock.Anything, "login:oleg").Return(nil).Once()
+		suite.repo.On("LoginAttemptRelease", mock.Anything, "ip:10.0.0.1").Return(nil).Once()
+		u, mfa, err := suite.useCases.UserLogin(suite.ctx(), "oleg", "password", "10.0.0.1", cfg)
+		suite.NoError(err)
+		suite.Equal(user, u)
+		suite.False(mfa)
+	})
+
+	suite.Run("second factor required", func() {
+		// счетчик по логину не сбрасывается до ввода кода второго фактора
+		suite.repo.On("LoginAttemptRegister", mock.Anything, "login:oleg", int64(900)).Return(attempt("login:oleg", 1), nil).Once()
+		suite.repo.On("LoginAttemptRegister", mock.Anything, "ip:10.0.0.1", int64(900)).Return(attempt("ip:10.0.0.1", 1), nil).Once()
+		suite.repo.On("UserGetByLogin", mock.Anything, "oleg").Return(user, nil).Once()
+		suite.repo.On("TOTPGet", mock.Anything, uint64(1)).
+			Return(&models.TOTP{UserID: 1, Secret: testTOTPSecret, ConfirmedAt: &time.Time{}}, nil).Once()
+		suite.repo.On("LoginAttemptRelease", mock.Anything, "login:oleg").Return(nil).Once()
+		suite.repo.On("LoginAttemptRelease", mock.Anything, "ip:10.0.0.1").Return(nil).Once()
+		u, mfa, err := suite.useCases.UserLogin(suite.ctx(), "oleg", "password", "10.0.0.1", cfg)
+		suite.NoError(err)
+		suite.Equal(user, u)
+		suite.True(mfa)
+	})
+
+	suite.Run("wrong password", func() {
+		// время блокировки определяется номером попытки в счетчике
+		suite.repo.On("LoginAttemptRegister", mock.Anything, "login:oleg", int64(900)).Return(attempt("login:oleg", 5), nil).Once()
+		suite.repo.On("LoginAttemptRegister", mock.Anything, "ip:10.0.0.1", int64(900)).Return(attempt("ip:10.0.0.1", 100), nil).Once()
+		suite.repo.On("UserGetByLogin", mock.Anything, "oleg").Return(user, nil).Once()
+		suite.repo.On("LoginFailureBlock", mock.Anything, "login:oleg", int64(2)).
+			Return(blocked("login:oleg", 5), nil).Once()
+		suite.repo.On("LoginFailureBlock", mock.Anything, "ip:10.0.0.1", int64(900)).
+			Return(blocked("ip:10.0.0.1", 100), nil).Once()
+		u, _, err := suite.useCases.UserLogin(suite.ctx(), "oleg", "wrong", "10.0.0.1", cfg)
+		suite.ErrorIs(err, errs.ErrUserLoginPassMismatch)
+		suite.Nil(u)
+	})
+
+	suite.Run("unknown user registers failure", func() {
+		suite.repo.On("LoginAttemptRegister", mock.Anything, "login:unknown", int64(900)).Return(attempt("login:unknown", 1), nil).Once()
+		suite.repo.On("LoginAttemptRegister", mock.Anything, "ip:10.0.0.1", int64(900)).Return(attempt("ip:10.0.0.1", 1), nil).Once()
+		suite.repo.On("UserGetByLogin", mock.Anything, "unknown").Return(nil, errs.ErrNotFound).Once()
+		suite.repo.On("LoginFailureBlock", mock.Anything, "login:unknown", int64(0)).
+			Return(attempt("login:unknown", 1), nil).Once()
+		suite.repo.On("LoginFailureBlock", mock.Anything, "ip:10.0.0.1", int64(0)).
+			Return(attempt("ip:10.0.0.1", 1), nil).Once()
+		u, _, err := suite.useCases.UserLogin(suite.ctx(), "unknown", "password", "10.0.0.1", cfg)
+		suite.ErrorIs(err, errs.ErrUserLoginPassMismatch)
+		suite.Nil(u)
+	})
+
+	suite.Run("user locked", func() {
+		suite.repo.On("LoginAttemptRegister", mock.Anything, "login:oleg", int64(900)).Return(blocked("login:oleg", 10), nil).Once()
+		u, _, err := suite.useCases.UserLogin(suite.ctx(), "oleg", "password", "10.0.0.1", cfg)
+		suite.ErrorIs(err, errs.ErrUserLocked)
+		suite.Nil(u)
+	})
+
+	suite.Run("login throttled", func() {
+		suite.repo.On("LoginAttemptRegister", mock.Anything, "login:oleg", int64(900)).Return(blocked("login:oleg", 5), nil).Once()
+		u, _, err := suite.useCases.UserLogin(suite.ctx(), "oleg", "password", "10.0.0.1", cfg)
+		suite.ErrorIs(err, errs.ErrLoginThrottled)
+		suite.Nil(u)
+	})
+
+	suite.Run("ip blocked", func() {
+		suite.repo.On("LoginAttemptRegister", mock.Anything, "login:oleg", int64(900)).Return(attempt("login:oleg", 1), nil).Once()
+		suite.repo.On("LoginAttemptRegister", mock.Anything, "ip:10.0.0.1", int64(900)).Return(blocked("ip:10.0.0.1", 100), nil).Once()
+		suite.repo.On("LoginAttemptRelease", mock.Anything, "login:oleg").Return(nil).Once()
+		u, _, err := suite.useCases.UserLogin(suite.ctx(), "oleg", "password", "10.0.0.1", cfg)
+		suite.ErrorIs(err, errs.ErrLoginThrottled)
+		suite.Nil(u)
+	})
+
+	suite.Run("parallel attempts over lockout threshold", func() {
+		// предыдущие 10 попыток еще выполняются, блокировка еще не установлена
+		suite.repo.On("LoginAttemptRegister", mock.Anything, "login:oleg", int64(900)).Return(attempt("login:oleg", 11), nil).Once()
+		suite.repo.On("LoginAttemptRegister", mock.Anything, "ip:10.0.0.1", int64(900)).Return(attempt("ip:10.0.0.1", 11), nil).Once()
+		suite.repo.On("LoginAttemptRelease", mock.Anything, "login:oleg").Return(nil).Once()
+		suite.repo.On("LoginAttemptRelease", mock.Anything, "ip:10.0.0.1").Return(nil).Once()
+		u, _, err := suite.useCases.UserLogin(suite.ctx(), "oleg", "password", "10.0.0.1", cfg)
+		suite.ErrorIs(err, errs.ErrLoginThrottled)
+		suite.Nil(u)
+	})
+
+	suite.Run("repo error", func() {
+		suite.repo.On("LoginAttemptRegister", mock.Anything, "login:oleg", int64(900)).Return(nil, errs.ErrInternal).Once()
+		u, _, err := suite.useCases.UserLogin(suite.ctx(), "oleg", "password", "10.0.0.1", cfg)
+		suite.ErrorIs(err, errs.ErrInternal)
+		suite.Nil(u)
+	})
+
+	suite.Run("block time", func() {
+		// проверяем время блокировки в зависимости от числа неудачных попыток
+		suite.Equal(time.Duration(0), loginBlock(3, cfg))
+		suite.Equal(time.Second, loginBlock(4, cfg))
+		suite.Equal(4*time.Second, loginBlock(6, cfg))
+		suite.Equal(30*time.Second, loginBlock(9, cfg))
+		suite.Equal(15*time.Minute, loginBlock(10, cfg))
+		suite.Equal(time.Duration(0), ipBlock(99, cfg))
+		suite.Equal(15*time.Minute, ipBlock(100, cfg))
+	})
+}
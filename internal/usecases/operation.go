@@ -8,6 +8,7 @@ import (
 
 	"github.com/shopspring/decimal"
 
+	"gophermart-loyalty/internal/config"
 	"gophermart-loyalty/internal/errs"
 	"gophermart-loyalty/internal/models"
 	"gophermart-loyalty/internal/repo"
@@ -29,12 +30,29 @@ func (u *UseCases) OrderAccrualPrepare(ctx context.Context, userID uint64, order
 	}, nil
 }
 
-// OrderWithdrawalPrepare - создает модель операции списания по заказу.
-func (u *UseCases) OrderWithdrawalPrepare(ctx context.Context, userID uint64, orderNumber string, amount decimal.Decimal) (*models.Operation, error) {
+// OrderWithdrawalPrepare - создает модель операции списания amount баллов по заказу
+// и проверяет ее по правилам списания cfg: минимальной сумме, точности и доле суммы заказа orderTotal, если она известна.
+// Ограничения на сумму списаний за сутки и за месяц проверяются при создании списания в OrderWithdrawalCreate.
+func (u *UseCases) OrderWithdrawalPrepare(ctx context.Context, userID uint64, orderNumber string, amount decimal.Decimal,
+	orderTotal *decimal.Decimal, cfg *config.Withdrawal) (*models.Operation, error) {
 	if err := u.orderNumberValidate(orderNumber); err != nil {
 		u.log.WithReqID(ctx).Error().Err(err).Msg("invalid order number")
 		return nil, err
 	}
+	if !amount.IsPositive() {
+		return nil, errs.ErrOperationAmountInvalid
+	}
+	if !amount.Equal(amount.Truncate(cfg.Precision)) {
+		return nil, errs.ErrWithdrawalPrecisionInvalid
+	}
+	if cfg.MinAmount.IsPositive() && amount.LessThan(cfg.MinAmount) {
+		return nil, errs.ErrWithdrawalBelowMinimum
+	}
+	if orderTotal != nil && cfg.MaxOrderShare.IsPositive() && amount.GreaterThan(orderTotal.Mul(cfg.MaxOrderShare)) {
+		return nil, errs.ErrWithdrawalOrderShareExceeded
+	}
+	amount = amount.Neg()
+
 	return &models.Operation{
 		UserID:      userID,
 		Type:        models.OrderWithdrawal,
@@ -45,6 +63,29 @@ func (u *UseCases) OrderWithdrawalPrepare(ctx context.Context, userID uint64, or
 	}, nil
 }
 
+// OrderWithdrawalCreate - создает операцию списания баллов op.
+// Если списание превышает ограничения cfg на сумму списаний за сутки или за месяц,
+// возвращает errs.ErrWithdrawalDailyAmountExceeded или errs.ErrWithdrawalMonthlyAmountExceeded.
+func (u *UseCases) OrderWithdrawalCreate(ctx context.Context, op *models.Operation, cfg *config.Withdrawal) error {
+	// Ограничения проверяются под блокировкой пользователя, поэтому параллельные списания не могут их превысить
+	checkFunc := func(ctx context.Context, daily, monthly decimal.Decimal) error {
+		if cfg.DailyAmount.IsPositive() && daily.Sub(op.Amount).GreaterThan(cfg.DailyAmount) {
+			return errs.ErrWithdrawalDailyAmountExceeded
+		}
+		if cfg.MonthlyAmount.IsPositive() && monthly.Sub(op.Amount).GreaterThan(cfg.MonthlyAmount) {
+			return errs.ErrWithdrawalMonthlyAmountExceeded
+		}
+		return nil
+	}
+
+	if err := u.repo.OperationWithdraw(ctx, op, checkFunc); err != nil {
+		u.log.WithReqID(ctx).Error().Err(err).Msg("failed to create withdrawal")
+		return err
+	}
+	u.log.WithReqID(ctx).Info().Uint64("operation_id", op.ID).Msg("withdrawal created")
+	return nil
+}
+
 // PromoAccrualPrepare - создает модель операции начисления по промо-коду.
 func (u *UseCases) PromoAccrualPrepare(ctx context.Context, userID uint64, promoCode string) (*models.Operation, error) {
 	// получаем промокод
@@ -80,21 +121,108 @@ func (u *UseCases) OperationCreate(ctx context.Context, op *models.Operation) er
 	return nil
 }
 
-// OperationGetByType - список операций пользователя по заданному типу
-func (u *UseCases) OperationGetByType(ctx context.Context, userID uint64, t models.OperationType) ([]*models.Operation, error) {
-	ops, err := u.repo.OperationGetByType(ctx, userID, t)
+// OperationGetByType - список операций пользователя по заданному типу, выбранных по параметрам q.
+// Если число операций ограничено q.Limit и в списке есть следующие операции, возвращает также курсор следующей страницы.
+func (u *UseCases) OperationGetByType(ctx context.Context, userID uint64, t models.OperationType, q *models.OperationQuery) ([]*models.Operation, *models.OperationCursor, error) {
+	ops, err := u.repo.OperationGetByType(ctx, userID, t, operationPageQuery(q))
 	if errors.Is(err, errs.ErrNotFound) {
-		return []*models.Operation{}, nil
+		return []*models.Operation{}, nil, nil
 	} else if err != nil {
 		u.log.WithReqID(ctx).Error().Err(err).Msg("failed to get operations")
-		return nil, err
+		return nil, nil, err
+	}
+	ops, next := operationPage(ops, q, func(op *models.Operation) time.Time { return op.CreatedAt })
+	return ops, next, nil
+}
+
+// operationPageQuery - возвращает параметры выборки, запрашивающие на одну операцию больше q.Limit,
+// чтобы узнать, есть ли в списке следующие операции.
+func operationPageQuery(q *models.OperationQuery) *models.OperationQuery {
+	if q == nil || q.Limit <= 0 {
+		return q
+	}
+	pq := *q
+	pq.Limit++
+	return &pq
+}
+
+// operationPage - возвращает не более q.Limit операций, выбранных по operationPageQuery(q),
+// и курсор следующей страницы, если операций больше. Курсор строится по времени, по которому упорядочен список.
+func operationPage(ops []*models.Operation, q *models.OperationQuery, key func(op *models.Operation) time.Time) ([]*models.Operation, *models.OperationCursor) {
+	if q == nil || q.Limit <= 0 || len(ops) <= q.Limit {
+		return ops, nil
 	}
-	return ops, nil
+	ops = ops[:q.Limit]
+	last := ops[len(ops)-1]
+	return ops, &models.OperationCursor{Time: key(last), ID: last.ID}
 }
 
 // OperationUpdateFurther - вызывает Repo.OperationUpdateFurther.
-func (u *UseCases) OperationUpdateFurther(ctx context.Context, opType models.OperationType, updateFunc repo.UpdateFunc) (*models.Operation, error) {
-	return u.repo.OperationUpdateFurther(ctx, opType, updateFunc)
+func (u *UseCases) OperationUpdateFurther(ctx context.Context, opType models.OperationType, source string, updateFunc repo.UpdateFunc) (*models.Operation, error) {
+	return u.repo.OperationUpdateFurther(ctx, opType, source, updateFunc)
+}
+
+// OrderOperationGet - возвращает операцию пользователя userID типа t по заказу orderNumber
+// и историю изменения ее статуса, если она записана.
+// Если пользователь не загружал заказ, возвращает errs.ErrNotFound, в том числе если заказ загружен другим пользователем.
+func (u *UseCases) OrderOperationGet(ctx context.Context, userID uint64, t models.OperationType, orderNumber string) (*models.Operation, []*models.OperationStatusChange, error) {
+	if err := u.orderNumberValidate(orderNumber); err != nil {
+		return nil, nil, err
+	}
+	op, err := u.repo.OperationGetByOrder(ctx, userID, t, orderNumber)
+	if errors.Is(err, errs.ErrNotFound) {
+		return nil, nil, err
+	} else if err != nil {
+		u.log.WithReqID(ctx).Error().Err(err).Msg("failed to get order operation")
+		return nil, nil, err
+	}
+	history, err := u.repo.OperationStatusHistoryGetByOrder(ctx, userID, t, orderNumber)
+	if err != nil {
+		u.log.WithReqID(ctx).Error().Err(err).Msg("failed to get order status history")
+		return nil, nil, err
+	}
+	return op, history, nil
+}
+
+// OrderStatusHistoryGet - возвращает историю изменения статуса начисления баллов пользователя userID по заказу orderNumber.
+// Если пользователь не загружал заказ, возвращает errs.ErrNotFound.
+func (u *UseCases) OrderStatusHistoryGet(ctx context.Context, userID uint64, orderNumber string) ([]*models.OperationStatusChange, error) {
+	if err := u.orderNumberValidate(orderNumber); err != nil {
+		return nil, err
+	}
+	history, err := u.repo.OperationStatusHistoryGetByOrder(ctx, userID, models.OrderAccrual, orderNumber)
+	if errors.Is(err, errs.ErrNotFound) {
+		return nil, err
+	} else if err != nil {
+		u.log.WithReqID(ctx).Error().Err(err).Msg("failed to get order status history")
+		return nil, err
+	}
+	return history, nil
+}
+
+// OrderWithdrawalCancel - отменяет списание баллов пользователя userID по заказу orderNumber.
+// Отменить можно только списание в статусе NEW, иначе возвращается errs.ErrWithdrawalNotCancelable.
+// Списанные баллы возвращаются на счет пользователя.
+func (u *UseCases) OrderWithdrawalCancel(ctx context.Context, userID uint64, orderNumber string) (*models.Operation, error) {
+	if err := u.orderNumberValidate(orderNumber); err != nil {
+		return nil, err
+	}
+	// Статус проверяется под блокировкой операции, поэтому отмена не может пересечься с ее обработкой магазином
+	op, err := u.repo.OperationUpdateByOrder(ctx, userID, models.OrderWithdrawal, orderNumber, models.StatusSourceUser, func(ctx context.Context, op *models.Operation) error {
+		if op.Status != models.StatusNew {
+			return errs.ErrWithdrawalNotCancelable
+		}
+		op.Status = models.StatusCanceled
+		return nil
+	})
+	if errors.Is(err, errs.ErrNotFound) || errors.Is(err, errs.ErrWithdrawalNotCancelable) {
+		return nil, err
+	} else if err != nil {
+		u.log.WithReqID(ctx).Error().Err(err).Msg("failed to cancel withdrawal")
+		return nil, err
+	}
+	u.log.WithReqID(ctx).Info().Uint64("operation_id", op.ID).Msg("withdrawal canceled")
+	return op, nil
 }
 
 // orderNumberValidate - валидирует номер заказа.
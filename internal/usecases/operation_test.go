@@ -7,6 +7,7 @@ import (
 	"github.com/shopspring/decimal"
 	"github.com/stretchr/testify/mock"
 
+	"gophermart-loyalty/internal/config"
 	"gophermart-loyalty/internal/errs"
 	"gophermart-loyalty/internal/models"
 	"gophermart-loyalty/internal/repo"
@@ -31,22 +32,91 @@ func (suite *useCasesSuite) TestOrderAccrualPrepare() {
 }
 
 func (suite *useCasesSuite) TestOrderWithdrawalPrepare() {
+	cfg := &config.Withdrawal{
+		MinAmount:     decimal.NewFromInt(10),
+		MaxOrderShare: decimal.RequireFromString("0.5"),
+		Precision:     2,
+	}
+
 	suite.Run("success", func() {
-		op, err := suite.useCases.OrderWithdrawalPrepare(suite.ctx(), 1, "2377225624", decimal.NewFromFloat(100))
+		op, err := suite.useCases.OrderWithdrawalPrepare(suite.ctx(), 1, "2377225624", decimal.NewFromFloat(100), nil, cfg)
 		suite.NoError(err)
 		suite.Equal(uint64(1), op.UserID)
 		suite.Equal(models.OrderWithdrawal, op.Type)
 		suite.NotNil(op.OrderNumber)
 		suite.Equal("2377225624", *op.OrderNumber)
 		suite.Contains(op.Description, "2377225624")
-		suite.Equal(decimal.NewFromFloat(100), op.Amount)
+		suite.Equal(decimal.NewFromFloat(-100), op.Amount)
 	})
 
 	suite.Run("invalid order number", func() {
-		op, err := suite.useCases.OrderWithdrawalPrepare(suite.ctx(), 1, "111", decimal.NewFromFloat(100))
+		op, err := suite.useCases.OrderWithdrawalPrepare(suite.ctx(), 1, "111", decimal.NewFromFloat(100), nil, cfg)
 		suite.ErrorIs(err, errs.ErrOperationOrderNumberInvalid)
 		suite.Nil(op)
 	})
+
+	suite.Run("invalid amount", func() {
+		_, err := suite.useCases.OrderWithdrawalPrepare(suite.ctx(), 1, "2377225624", decimal.NewFromFloat(-100), nil, cfg)
+		suite.ErrorIs(err, errs.ErrOperationAmountInvalid)
+		_, err = suite.useCases.OrderWithdrawalPrepare(suite.ctx(), 1, "2377225624", decimal.Zero, nil, cfg)
+		suite.ErrorIs(err, errs.ErrOperationAmountInvalid)
+	})
+
+	suite.Run("precision", func() {
+		_, err := suite.useCases.OrderWithdrawalPrepare(suite.ctx(), 1, "2377225624", decimal.RequireFromString("10.50"), nil, cfg)
+		suite.NoError(err)
+		_, err = suite.useCases.OrderWithdrawalPrepare(suite.ctx(), 1, "2377225624", decimal.RequireFromString("10.505"), nil, cfg)
+		suite.ErrorIs(err, errs.ErrWithdrawalPrecisionInvalid)
+	})
+
+	suite.Run("below minimum", func() {
+		_, err := suite.useCases.OrderWithdrawalPrepare(suite.ctx(), 1, "2377225624", decimal.RequireFromString("9.99"), nil, cfg)
+		suite.ErrorIs(err, errs.ErrWithdrawalBelowMinimum)
+	})
+
+	suite.Run("order share", func() {
+		total := decimal.NewFromInt(200)
+		_, err := suite.useCases.OrderWithdrawalPrepare(suite.ctx(), 1, "2377225624", decimal.NewFromInt(100), &total, cfg)
+		suite.NoError(err)
+		_, err = suite.useCases.OrderWithdrawalPrepare(suite.ctx(), 1, "2377225624", decimal.RequireFromString("100.01"), &total, cfg)
+		suite.ErrorIs(err, errs.ErrWithdrawalOrderShareExceeded)
+	})
+}
+
+func (suite *useCasesSuite) TestOrderWithdrawalCreate() {
+	cfg := &config.Withdrawal{DailyAmount: decimal.NewFromInt(100), MonthlyAmount: decimal.NewFromInt(500)}
+	op := &models.Operation{UserID: 1, Type: models.OrderWithdrawal, Amount: decimal.NewFromInt(-40)}
+	// withdraw - возвращает результат коллбэка проверки для списаний на сумму daily за сутки и monthly за месяц
+	withdraw := func(daily, monthly int64) func(context.Context, *models.Operation, repo.WithdrawalCheckFunc) error {
+		return func(ctx context.Context, _ *models.Operation, f repo.WithdrawalCheckFunc) error {
+			return f(ctx, decimal.NewFromInt(daily), decimal.NewFromInt(monthly))
+		}
+	}
+
+	suite.Run("success", func() {
+		suite.repo.On("OperationWithdraw", mock.Anything, op, mock.Anything).Return(withdraw(60, 460)).Once()
+		suite.NoError(suite.useCases.OrderWithdrawalCreate(suite.ctx(), op, cfg))
+	})
+
+	suite.Run("daily amount exceeded", func() {
+		suite.repo.On("OperationWithdraw", mock.Anything, op, mock.Anything).Return(withdraw(61, 61)).Once()
+		suite.ErrorIs(suite.useCases.OrderWithdrawalCreate(suite.ctx(), op, cfg), errs.ErrWithdrawalDailyAmountExceeded)
+	})
+
+	suite.Run("monthly amount exceeded", func() {
+		suite.repo.On("OperationWithdraw", mock.Anything, op, mock.Anything).Return(withdraw(0, 461)).Once()
+		suite.ErrorIs(suite.useCases.OrderWithdrawalCreate(suite.ctx(), op, cfg), errs.ErrWithdrawalMonthlyAmountExceeded)
+	})
+
+	suite.Run("limits disabled", func() {
+		suite.repo.On("OperationWithdraw", mock.Anything, op, mock.Anything).Return(withdraw(1000000, 1000000)).Once()
+		suite.NoError(suite.useCases.OrderWithdrawalCreate(suite.ctx(), op, &config.Withdrawal{}))
+	})
+
+	suite.Run("insufficient funds", func() {
+		suite.repo.On("OperationWithdraw", mock.Anything, op, mock.Anything).Return(errs.ErrUserBalanceNegative).Once()
+		suite.ErrorIs(suite.useCases.OrderWithdrawalCreate(suite.ctx(), op, cfg), errs.ErrUserBalanceNegative)
+	})
 }
 
 func (suite *useCasesSuite) TestPromoAccrualPrepare() {
@@ -144,7 +214,7 @@ func (suite *useCasesSuite) TestOperationCreate() {
 
 func (suite *useCasesSuite) TestOperationGetByType() {
 	suite.Run("success", func() {
-		suite.repo.On("OperationGetByType", mock.Anything, uint64(1), models.OrderAccrual).
+		suite.repo.On("OperationGetByType", mock.Anything, uint64(1), models.OrderAccrual, mock.Anything).
 			Return([]*models.Operation{
 				{
 					ID:          1,
@@ -168,16 +238,46 @@ func (suite *useCasesSuite) TestOperationGetByType() {
 				},
 			}, nil).Once()
 
-		ops, err := suite.useCases.OperationGetByType(suite.ctx(), 1, models.OrderAccrual)
+		ops, next, err := suite.useCases.OperationGetByType(suite.ctx(), 1, models.OrderAccrual, nil)
 		suite.NoError(err)
 		suite.Len(ops, 2)
+		suite.Nil(next)
+	})
+
+	suite.Run("next page", func() {
+		created := time.Date(2022, 10, 1, 12, 0, 0, 0, time.UTC)
+		// запрашивается на одну операцию больше, чтобы узнать, есть ли следующая страница
+		suite.repo.On("OperationGetByType", mock.Anything, uint64(1), models.OrderAccrual, mock.MatchedBy(func(q *models.OperationQuery) bool {
+			return q.Limit == 2
+		})).Return([]*models.Operation{
+			{ID: 3, UserID: 1, Type: models.OrderAccrual, Status: models.StatusNew, CreatedAt: created},
+			{ID: 2, UserID: 1, Type: models.OrderAccrual, Status: models.StatusNew, CreatedAt: created.Add(-time.Hour)},
+		}, nil).Once()
+
+		q := &models.OperationQuery{Limit: 1}
+		ops, next, err := suite.useCases.OperationGetByType(suite.ctx(), 1, models.OrderAccrual, q)
+		suite.NoError(err)
+		suite.Require().Len(ops, 1)
+		suite.Equal(uint64(3), ops[0].ID)
+		suite.Equal(&models.OperationCursor{Time: created, ID: 3}, next)
+		suite.Equal(1, q.Limit)
+	})
+
+	suite.Run("last page", func() {
+		suite.repo.On("OperationGetByType", mock.Anything, uint64(1), models.OrderAccrual, mock.Anything).
+			Return([]*models.Operation{{ID: 1, UserID: 1, Type: models.OrderAccrual, Status: models.StatusNew}}, nil).Once()
+
+		ops, next, err := suite.useCases.OperationGetByType(suite.ctx(), 1, models.OrderAccrual, &models.OperationQuery{Limit: 1})
+		suite.NoError(err)
+		suite.Len(ops, 1)
+		suite.Nil(next)
 	})
 
 	suite.Run("not found", func() {
-		suite.repo.On("OperationGetByType", mock.Anything, uint64(1), models.OrderAccrual).
+		suite.repo.On("OperationGetByType", mock.Anything, uint64(1), models.OrderAccrual, mock.Anything).
 			Return(nil, errs.ErrNotFound).Once()
 
-		ops, err := suite.useCases.OperationGetByType(suite.ctx(), 1, models.OrderAccrual)
+		ops, _, err := suite.useCases.OperationGetByType(suite.ctx(), 1, models.OrderAccrual, nil)
 		suite.Nil(err)
 		suite.Equal(0, len(ops))
 	})
@@ -203,22 +303,116 @@ func (suite *useCasesSuite) TestOperationUpdateFurther() {
 			return nil
 		}
 
-		suite.repo.On("OperationUpdateFurther", mock.Anything, models.OrderAccrual, mock.AnythingOfType("repo.UpdateFunc")).
+		suite.repo.On("OperationUpdateFurther", mock.Anything, models.OrderAccrual, models.StatusSourceAccrual, mock.AnythingOfType("repo.UpdateFunc")).
 			Return(&models.Operation{}, nil).Once().
 			Run(func(args mock.Arguments) {
 				updateFunc(suite.ctx(), op)
 			})
 
-		_, err := suite.useCases.OperationUpdateFurther(suite.ctx(), models.OrderAccrual, updateFunc)
+		_, err := suite.useCases.OperationUpdateFurther(suite.ctx(), models.OrderAccrual, models.StatusSourceAccrual, updateFunc)
 		suite.NoError(err)
 		suite.Equal(models.StatusProcessed, op.Status)
 	})
 
 	suite.Run("internal error", func() {
-		suite.repo.On("OperationUpdateFurther", mock.Anything, models.OrderAccrual, mock.AnythingOfType("repo.UpdateFunc")).
+		suite.repo.On("OperationUpdateFurther", mock.Anything, models.OrderAccrual, models.StatusSourceAccrual, mock.AnythingOfType("repo.UpdateFunc")).
 			Return(nil, errs.ErrInternal).Once()
 
-		_, err := suite.useCases.OperationUpdateFurther(suite.ctx(), models.OrderAccrual, nil)
+		_, err := suite.useCases.OperationUpdateFurther(suite.ctx(), models.OrderAccrual, models.StatusSourceAccrual, nil)
 		suite.ErrorIs(err, errs.ErrInternal)
 	})
 }
+
+func (suite *useCasesSuite) TestOrderOperationGet() {
+	suite.Run("success", func() {
+		op := &models.Operation{ID: 1, UserID: 1, Type: models.OrderWithdrawal, Status: models.StatusProcessed, OrderNumber: strPtr("12345678903")}
+		history := []*models.OperationStatusChange{
+			{ID: 1, OperationID: 1, OldStatus: models.StatusNew, NewStatus: models.StatusProcessed, Source: models.StatusSourceShop},
+		}
+		suite.repo.On("OperationGetByOrder", mock.Anything, uint64(1), models.OrderWithdrawal, "12345678903").Return(op, nil).Once()
+		suite.repo.On("OperationStatusHistoryGetByOrder", mock.Anything, uint64(1), models.OrderWithdrawal, "12345678903").
+			Return(history, nil).Once()
+		resOp, resHistory, err := suite.useCases.OrderOperationGet(suite.ctx(), 1, models.OrderWithdrawal, "12345678903")
+		suite.NoError(err)
+		suite.Equal(op, resOp)
+		suite.Equal(history, resHistory)
+	})
+
+	suite.Run("invalid order number", func() {
+		_, _, err := suite.useCases.OrderOperationGet(suite.ctx(), 1, models.OrderAccrual, "12345678904")
+		suite.ErrorIs(err, errs.ErrOperationOrderNumberInvalid)
+	})
+
+	suite.Run("order of another user", func() {
+		suite.repo.On("OperationGetByOrder", mock.Anything, uint64(2), models.OrderAccrual, "12345678903").
+			Return(nil, errs.ErrNotFound).Once()
+		_, _, err := suite.useCases.OrderOperationGet(suite.ctx(), 2, models.OrderAccrual, "12345678903")
+		suite.ErrorIs(err, errs.ErrNotFound)
+	})
+}
+
+func (suite *useCasesSuite) TestOrderStatusHistoryGet() {
+	suite.Run("success", func() {
+		history := []*models.OperationStatusChange{
+			{ID: 1, OperationID: 1, OldStatus: models.StatusNew, NewStatus: models.StatusProcessed, Source: models.StatusSourceAccrual},
+		}
+		suite.repo.On("OperationStatusHistoryGetByOrder", mock.Anything, uint64(1), models.OrderAccrual, "12345678903").
+			Return(history, nil).Once()
+		res, err := suite.useCases.OrderStatusHistoryGet(suite.ctx(), 1, "12345678903")
+		suite.NoError(err)
+		suite.Equal(history, res)
+	})
+
+	suite.Run("invalid order number", func() {
+		_, err := suite.useCases.OrderStatusHistoryGet(suite.ctx(), 1, "12345678904")
+		suite.ErrorIs(err, errs.ErrOperationOrderNumberInvalid)
+	})
+
+	suite.Run("not found", func() {
+		suite.repo.On("OperationStatusHistoryGetByOrder", mock.Anything, uint64(1), models.OrderAccrual, "12345678903").
+			Return(nil, errs.ErrNotFound).Once()
+		_, err := suite.useCases.OrderStatusHistoryGet(suite.ctx(), 1, "12345678903")
+		suite.ErrorIs(err, errs.ErrNotFound)
+	})
+}
+
+func (suite *useCasesSuite) TestOrderWithdrawalCancel() {
+	// cancel - вызывает коллбэк отмены для списания в статусе status,
+	// возвращает списание и ошибку коллбэка
+	cancel := func(status models.OperationStatus) (*models.Operation, error) {
+		op := &models.Operation{ID: 1, UserID: 1, Type: models.OrderWithdrawal, Status: status}
+		var callbackErr error
+		suite.repo.On("OperationUpdateByOrder", mock.Anything, uint64(1), models.OrderWithdrawal, "12345678903", models.StatusSourceUser, mock.AnythingOfType("repo.UpdateFunc")).
+			Return(op, nil).Once().
+			Run(func(args mock.Arguments) {
+				callbackErr = args.Get(5).(repo.UpdateFunc)(suite.ctx(), op)
+			})
+		_, err := suite.useCases.OrderWithdrawalCancel(suite.ctx(), 1, "12345678903")
+		suite.NoError(err)
+		return op, callbackErr
+	}
+
+	suite.Run("success", func() {
+		op, err := cancel(models.StatusNew)
+		suite.NoError(err)
+		suite.Equal(models.StatusCanceled, op.Status)
+	})
+
+	suite.Run("already processing", func() {
+		op, err := cancel(models.StatusProcessing)
+		suite.ErrorIs(err, errs.ErrWithdrawalNotCancelable)
+		suite.Equal(models.StatusProcessing, op.Status)
+	})
+
+	suite.Run("invalid order number", func() {
+		_, err := suite.useCases.OrderWithdrawalCancel(suite.ctx(), 1, "12345678904")
+		suite.ErrorIs(err, errs.ErrOperationOrderNumberInvalid)
+	})
+
+	suite.Run("not found", func() {
+		suite.repo.On("OperationUpdateByOrder", mock.Anything, uint64(1), models.OrderWithdrawal, "12345678903", models.StatusSourceUser, mock.Anything).
+			Return(nil, errs.ErrNotFound).Once()
+		_, err := suite.useCases.OrderWithdrawalCancel(suite.ctx(), 1, "12345678903")
+		suite.ErrorIs(err, errs.ErrNotFound)
+	})
+}
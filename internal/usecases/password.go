@@ -0,0 +1,111 @@
+package usecases
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"gophermart-loyalty/internal/errs"
+	"gophermart-loyalty/internal/models"
+)
+
+// resetTokenLen - длина токена сброса пароля в байтах
+const resetTokenLen = 32
+
+// UserPasswordChange - меняет пароль пользователя userID.
+// Старый пароль проверяется через UserCheckLoginPass.
+func (u *UseCases) UserPasswordChange(ctx context.Context, userID uint64, oldPassword, newPassword string) error {
+	// валидируем новый пароль
+	if !passValidateRe.MatchString(newPassword) {
+		return errs.ErrUserPassInvalid
+	}
+
+	user, err := u.UserGetByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	// Проверяем старый пароль
+	if _, err = u.UserCheckLoginPass(ctx, user.Login, oldPassword); err != nil {
+		return err
+	}
+
+	hash, err := u.passHash(ctx, newPassword)
+	if err != nil {
+		return err
+	}
+	if err = u.repo.UserUpdatePassHash(ctx, userID, hash); err != nil {
+		u.log.WithReqID(ctx).Error().Err(err).Msg("failed to update password")
+		return err
+	}
+
+	u.log.WithReqID(ctx).Info().Uint64("user_id", userID).Msg("password changed")
+	return nil
+}
+
+// PasswordResetRequest - выпускает одноразовый токен сброса пароля со сроком жизни ttl
+// и отправляет его пользователю. Ранее выпущенные токены пользователя становятся недействительными.
+// Если пользователь с таким логином не найден, ничего не делает и не возвращает ошибку,
+// чтобы по ответу нельзя было определить наличие пользователя.
+func (u *UseCases) PasswordResetRequest(ctx context.Context, login string, ttl time.Duration) error {
+	user, err := u.repo.UserGetByLogin(ctx, login)
+	if errors.Is(err, errs.ErrNotFound) {
+		u.log.WithReqID(ctx).Debug().Msg("password reset requested for unknown user")
+		return nil
+	} else if err != nil {
+		u.log.WithReqID(ctx).Error().Err(err).Msg("failed to find user")
+		return err
+	}
+
+	token, hash, err := randomToken(resetTokenLen)
+	if err != nil {
+		u.log.WithReqID(ctx).Error().Err(err).Msg("failed to generate reset token")
+		return errs.ErrInternal
+	}
+
+	pr := &models.PasswordReset{UserID: user.ID, TokenHash: hash}
+	if err = u.repo.PasswordResetCreate(ctx, pr, int64(ttl.Seconds())); err != nil {
+		u.log.WithReqID(ctx).Error().Err(err).Msg("failed to create reset token")
+		return err
+	}
+
+	body := fmt.Sprintf("Password reset token: %s\nThe token can be used once and expires at %s.",
+		token, pr.ExpiresAt.Format(time.RFC3339))
+	if err = u.sender.Send(ctx, user, "Password reset", body); err != nil {
+		u.log.WithReqID(ctx).Error().Err(err).Msg("failed to send reset token")
+		return errs.ErrInternal
+	}
+
+	u.log.WithReqID(ctx).Info().Uint64("user_id", user.ID).Msg("password reset token sent")
+	return nil
+}
+
+// PasswordResetConfirm - устанавливает новый пароль по токену сброса пароля.
+// Токен становится недействительным, все сессии пользователя отзываются.
+func (u *UseCases) PasswordResetConfirm(ctx context.Context, token, newPassword string) error {
+	if token == "" {
+		return errs.ErrPasswordResetTokenInvalid
+	}
+	// валидируем новый пароль
+	if !passValidateRe.MatchString(newPassword) {
+		return errs.ErrUserPassInvalid
+	}
+
+	hash, err := u.passHash(ctx, newPassword)
+	if err != nil {
+		return err
+	}
+
+	userID, err := u.repo.PasswordResetConsume(ctx, tokenHash(token), hash)
+	if errors.Is(err, errs.ErrNotFound) {
+		u.log.WithReqID(ctx).Debug().Msg("valid reset token not found")
+		return errs.ErrPasswordResetTokenInvalid
+	} else if err != nil {
+		u.log.WithReqID(ctx).Error().Err(err).Msg("failed to reset password")
+		return err
+	}
+
+	u.log.WithReqID(ctx).Info().Uint64("user_id", userID).Msg("password reset")
+	return nil
+}
@@ -0,0 +1,109 @@
+package usecases
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+	"golang.org/x/crypto/bcrypt"
+
+	"gophermart-loyalty/internal/errs"
+	"gophermart-loyalty/internal/models"
+)
+
+func (suite *useCasesSuite) TestUserPasswordChange() {
+	hash, err := bcrypt.GenerateFromPassword([]byte("old-password"), bcrypt.MinCost)
+	suite.Require().NoError(err)
+	user := &models.User{ID: 1, Login: "oleg", PassHash: string(hash)}
+
+	suite.Run("success", func() {
+		suite.repo.On("UserGetByID", mock.Anything, uint64(1)).Return(user, nil).Once()
+		suite.repo.On("UserGetByLogin", mock.Anything, "oleg").Return(user, nil).Once()
+		suite.repo.On("UserUpdatePassHash", mock.Anything, uint64(1),
+			mock.MatchedBy(func(h string) bool {
+				return bcrypt.CompareHashAndPassword([]byte(h), []byte("new-password")) == nil
+			})).Return(nil).Once()
+		suite.NoError(suite.useCases.UserPasswordChange(suite.ctx(), 1, "old-password", "new-password"))
+	})
+
+	suite.Run("wrong old password", func() {
+		suite.repo.On("UserGetByID", mock.Anything, uint64(1)).Return(user, nil).Once()
+		suite.repo.On("UserGetByLogin", mock.Anything, "oleg").Return(user, nil).Once()
+		err := suite.useCases.UserPasswordChange(suite.ctx(), 1, "wrong-password", "new-password")
+		suite.ErrorIs(err, errs.ErrUserLoginPassMismatch)
+	})
+
+	suite.Run("invalid new password", func() {
+		err := suite.useCases.UserPasswordChange(suite.ctx(), 1, "old-password", "12345")
+		suite.ErrorIs(err, errs.ErrUserPassInvalid)
+	})
+}
+
+func (suite *useCasesSuite) TestPasswordResetRequest() {
+	user := &models.User{ID: 1, Login: "oleg"}
+
+	suite.Run("success", func() {
+		var hash string
+		suite.repo.On("UserGetByLogin", mock.Anything, "oleg").Return(user, nil).Once()
+		suite.repo.On("PasswordResetCreate", mock.Anything, mock.Anything, int64(3600)).
+			Return(nil).
+			Run(func(args mock.Arguments) {
+				pr := args.Get(1).(*models.PasswordReset)
+				suite.Equal(uint64(1), pr.UserID)
+				hash = pr.TokenHash
+			}).Once()
+		suite.sender.On("Send", mock.Anything, user, "Password reset",
+			mock.MatchedBy(func(body string) bool {
+				// в сообщении передается токен, а в репозитории хранится только его хэш
+				for _, word := range strings.Fields(body) {
+					if tokenHash(word) == hash {
+						return true
+					}
+				}
+				return false
+			})).Return(nil).Once()
+		suite.NoError(suite.useCases.PasswordResetRequest(suite.ctx(), "oleg", time.Hour))
+	})
+
+	suite.Run("unknown user", func() {
+		suite.repo.On("UserGetByLogin", mock.Anything, "unknown").Return(nil, errs.ErrNotFound).Once()
+		suite.NoError(suite.useCases.PasswordResetRequest(suite.ctx(), "unknown", time.Hour))
+	})
+
+	suite.Run("send failed", func() {
+		suite.repo.On("UserGetByLogin", mock.Anything, "oleg").Return(user, nil).Once()
+		suite.repo.On("PasswordResetCreate", mock.Anything, mock.Anything, mock.Anything).Return(nil).Once()
+		suite.sender.On("Send", mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+			Return(errors.New("send failed")).Once()
+		err := suite.useCases.PasswordResetRequest(suite.ctx(), "oleg", time.Hour)
+		suite.ErrorIs(err, errs.ErrInternal)
+	})
+}
+
+func (suite *useCasesSuite) TestPasswordResetConfirm() {
+	suite.Run("success", func() {
+		suite.repo.On("PasswordResetConsume", mock.Anything, tokenHash("reset-token"),
+			mock.MatchedBy(func(h string) bool {
+				return bcrypt.CompareHashAndPassword([]byte(h), []byte("new-password")) == nil
+			})).Return(uint64(1), nil).Once()
+		suite.NoError(suite.useCases.PasswordResetConfirm(suite.ctx(), "reset-token", "new-password"))
+	})
+
+	suite.Run("token not found", func() {
+		suite.repo.On("PasswordResetConsume", mock.Anything, mock.Anything, mock.Anything).
+			Return(uint64(0), errs.ErrNotFound).Once()
+		err := suite.useCases.PasswordResetConfirm(suite.ctx(), "used-token", "new-password")
+		suite.ErrorIs(err, errs.ErrPasswordResetTokenInvalid)
+	})
+
+	suite.Run("empty token", func() {
+		err := suite.useCases.PasswordResetConfirm(suite.ctx(), "", "new-password")
+		suite.ErrorIs(err, errs.ErrPasswordResetTokenInvalid)
+	})
+
+	suite.Run("invalid password", func() {
+		err := suite.useCases.PasswordResetConfirm(suite.ctx(), "reset-token", "12345")
+		suite.ErrorIs(err, errs.ErrUserPassInvalid)
+	})
+}
@@ -0,0 +1,65 @@
+package usecases
+
+import (
+	"context"
+
+	"gophermart-loyalty/internal/models"
+)
+
+// reconcileBatchSize - число пользователей, сверяемых одним запросом
+const reconcileBatchSize = 500
+
+// BalanceReconcile - сверяет закэшированные баланс и сумму списаний всех пользователей с журналом проводок,
+// а журнал проводок - с операциями. Каждое расхождение записывается в лог.
+// Если repair == true, расхождения журнала с операциями исправляются корректирующими проводками,
+// а закэшированный баланс пересчитывается по журналу проводок.
+// Если исправить расхождение пользователя не удалось, ошибка записывается в лог и в результат сверки
+// (models.BalanceCheck.RepairErr), а сверка продолжается.
+// Возвращает результаты сверки пользователей, у которых найдено расхождение.
+func (u *UseCases) BalanceReconcile(ctx context.Context, repair bool) ([]*models.BalanceCheck, error) {
+	var drifts []*models.BalanceCheck
+	afterID := uint64(0)
+	for {
+		checks, err := u.repo.UserBalanceCheck(ctx, afterID, reconcileBatchSize)
+		if err != nil {
+			u.log.WithReqID(ctx).Error().Err(err).Msg("failed to check user balances")
+			return nil, err
+		}
+		for _, c := range checks {
+			afterID = c.UserID
+			if !c.Drift() {
+				continue
+			}
+			// Баланс мог измениться после сверки, поэтому при исправлении сверка выполняется повторно.
+			// UserBalanceRepair возвращает значения до исправления, поэтому расхождения нет, только если оно уже устранено
+			if repair {
+				repaired, err := u.repo.UserBalanceRepair(ctx, c.UserID)
+				if err != nil {
+					u.log.WithReqID(ctx).Error().Err(err).Uint64("user_id", c.UserID).Msg("failed to repair user balance")
+					c.RepairErr = err
+					drifts = append(drifts, c)
+					continue
+				}
+				if !repaired.Drift() {
+					continue
+				}
+				c = repaired
+			}
+			u.log.WithReqID(ctx).Warn().
+				Uint64("user_id", c.UserID).
+				Str("balance", c.Balance.String()).
+				Str("expected_balance", c.ExpectedBalance.String()).
+				Str("withdrawn", c.Withdrawn.String()).
+				Str("expected_withdrawn", c.ExpectedWithdrawn.String()).
+				Str("pending", c.Pending.String()).
+				Str("expected_pending", c.ExpectedPending.String()).
+				Int("unposted", c.Unposted).
+				Bool("repaired", repair).
+				Msg("user balance drift")
+			drifts = append(drifts, c)
+		}
+		if len(checks) < reconcileBatchSize {
+			return drifts, nil
+		}
+	}
+}
@@ -0,0 +1,103 @@
+package usecases
+
+import (
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/mock"
+
+	"gophermart-loyalty/internal/errs"
+	"gophermart-loyalty/internal/models"
+)
+
+func (suite *useCasesSuite) TestBalanceReconcile() {
+	ok := &models.BalanceCheck{
+		UserID:            1,
+		Balance:           decimal.NewFromInt(100),
+		Withdrawn:         decimal.NewFromInt(10),
+		ExpectedBalance:   decimal.NewFromInt(100),
+		ExpectedWithdrawn: decimal.NewFromInt(10),
+	}
+	drift := &models.BalanceCheck{
+		UserID:            2,
+		Balance:           decimal.NewFromInt(50),
+		Withdrawn:         decimal.Zero,
+		ExpectedBalance:   decimal.NewFromInt(40),
+		ExpectedWithdrawn: decimal.NewFromInt(10),
+	}
+
+	suite.Run("report", func() {
+		suite.repo.On("UserBalanceCheck", mock.Anything, uint64(0), reconcileBatchSize).
+			Return([]*models.BalanceCheck{ok, drift}, nil).Once()
+
+		drifts, err := suite.useCases.BalanceReconcile(suite.ctx(), false)
+		suite.NoError(err)
+		suite.Equal([]*models.BalanceCheck{drift}, drifts)
+	})
+
+	suite.Run("ledger drift", func() {
+		unposted := &models.BalanceCheck{UserID: 3, Unposted: 1}
+		suite.repo.On("UserBalanceCheck", mock.Anything, uint64(0), reconcileBatchSize).
+			Return([]*models.BalanceCheck{unposted}, nil).Once()
+		suite.repo.On("UserBalanceRepair", mock.Anything, uint64(3)).Return(unposted, nil).Once()
+
+		drifts, err := suite.useCases.BalanceReconcile(suite.ctx(), true)
+		suite.NoError(err)
+		suite.Equal([]*models.BalanceCheck{unposted}, drifts)
+	})
+
+	suite.Run("repair", func() {
+		suite.repo.On("UserBalanceCheck", mock.Anything, uint64(0), reconcileBatchSize).
+			Return([]*models.BalanceCheck{ok, drift}, nil).Once()
+		suite.repo.On("UserBalanceRepair", mock.Anything, uint64(2)).Return(drift, nil).Once()
+
+		drifts, err := suite.useCases.BalanceReconcile(suite.ctx(), true)
+		suite.NoError(err)
+		suite.Equal([]*models.BalanceCheck{drift}, drifts)
+	})
+
+	suite.Run("repair error", func() {
+		failed := &models.BalanceCheck{UserID: 3, Balance: decimal.NewFromInt(10)}
+		suite.repo.On("UserBalanceCheck", mock.Anything, uint64(0), reconcileBatchSize).
+			Return([]*models.BalanceCheck{failed, drift}, nil).Once()
+		suite.repo.On("UserBalanceRepair", mock.Anything, uint64(3)).Return(nil, errs.ErrUserBalanceNegative).Once()
+		suite.repo.On("UserBalanceRepair", mock.Anything, uint64(2)).Return(drift, nil).Once()
+
+		drifts, err := suite.useCases.BalanceReconcile(suite.ctx(), true)
+		suite.NoError(err)
+		suite.Require().Len(drifts, 2)
+		suite.Equal(uint64(3), drifts[0].UserID)
+		suite.ErrorIs(drifts[0].RepairErr, errs.ErrUserBalanceNegative)
+		suite.Equal(drift, drifts[1])
+	})
+
+	suite.Run("drift resolved before repair", func() {
+		suite.repo.On("UserBalanceCheck", mock.Anything, uint64(0), reconcileBatchSize).
+			Return([]*models.BalanceCheck{drift}, nil).Once()
+		suite.repo.On("UserBalanceRepair", mock.Anything, uint64(2)).Return(ok, nil).Once()
+
+		drifts, err := suite.useCases.BalanceReconcile(suite.ctx(), true)
+		suite.NoError(err)
+		suite.Empty(drifts)
+	})
+
+	suite.Run("next batch", func() {
+		batch := make([]*models.BalanceCheck, reconcileBatchSize)
+		for i := range batch {
+			batch[i] = &models.BalanceCheck{UserID: uint64(i + 1)}
+		}
+		suite.repo.On("UserBalanceCheck", mock.Anything, uint64(0), reconcileBatchSize).Return(batch, nil).Once()
+		suite.repo.On("UserBalanceCheck", mock.Anything, uint64(reconcileBatchSize), reconcileBatchSize).
+			Return([]*models.BalanceCheck{}, nil).Once()
+
+		drifts, err := suite.useCases.BalanceReconcile(suite.ctx(), false)
+		suite.NoError(err)
+		suite.Empty(drifts)
+	})
+
+	suite.Run("repo error", func() {
+		suite.repo.On("UserBalanceCheck", mock.Anything, uint64(0), reconcileBatchSize).
+			Return(nil, errs.ErrInternal).Once()
+
+		_, err := suite.useCases.BalanceReconcile(suite.ctx(), false)
+		suite.ErrorIs(err, errs.ErrInternal)
+	})
+}
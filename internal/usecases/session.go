@@ -0,0 +1,146 @@
+package usecases
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"time"
+
+	"gophermart-loyalty/internal/errs"
+	"gophermart-loyalty/internal/models"
+)
+
+// refreshTokenLen - длина refresh-токена в байтах
+const refreshTokenLen = 32
+
+// SessionCreate - создает сессию пользователя со сроком жизни ttl.
+// Возвращает сессию и refresh-токен. В репозитории хранится только хэш refresh-токена.
+func (u *UseCases) SessionCreate(ctx context.Context, userID uint64, ttl time.Duration) (*models.Session, string, error) {
+	token, hash, err := randomToken(refreshTokenLen)
+	if err != nil {
+		u.log.WithReqID(ctx).Error().Err(err).Msg("failed to generate refresh token")
+		return nil, "", errs.ErrInternal
+	}
+
+	s := &models.Session{UserID: userID, RefreshHash: hash}
+	if err = u.repo.SessionCreate(ctx, s, int64(ttl.Seconds())); err != nil {
+		u.log.WithReqID(ctx).Error().Err(err).Msg("failed to create session")
+		return nil, "", err
+	}
+
+	u.log.WithReqID(ctx).Debug().Uint64("session_id", s.ID).Msg("session created")
+	return s, token, nil
+}
+
+// SessionRefresh - обменивает refresh-токен на новый и продлевает сессию на ttl.
+// Использованный refresh-токен становится недействительным.
+// Возвращает сессию и новый refresh-токен.
+func (u *UseCases) SessionRefresh(ctx context.Context, refreshToken string, ttl time.Duration) (*models.Session, string, error) {
+	if refreshToken == "" {
+		return nil, "", errs.ErrSessionInvalid
+	}
+
+	token, hash, err := randomToken(refreshTokenLen)
+	if err != nil {
+		u.log.WithReqID(ctx).Error().Err(err).Msg("failed to generate refresh token")
+		return nil, "", errs.ErrInternal
+	}
+
+	s, err := u.repo.SessionRotate(ctx, tokenHash(refreshToken), hash, int64(ttl.Seconds()))
+	if errors.Is(err, errs.ErrNotFound) {
+		u.log.WithReqID(ctx).Debug().Msg("active session for refresh token not found")
+		return nil, "", errs.ErrSessionInvalid
+	} else if err != nil {
+		u.log.WithReqID(ctx).Error().Err(err).Msg("failed to rotate session")
+		return nil, "", err
+	}
+
+	u.log.WithReqID(ctx).Debug().Uint64("session_id", s.ID).Msg("session refreshed")
+	return s, token, nil
+}
+
+// SessionValidate - проверяет, что сессия sessionID принадлежит пользователю userID и не отозвана,
+// а токен, выпущенный в момент issuedAt, не был отозван вместе со всеми токенами пользователя.
+// Токены заблокированного пользователя недействительны.
+func (u *UseCases) SessionValidate(ctx context.Context, userID, sessionID uint64, issuedAt time.Time) error {
+	s, err := u.repo.SessionGetByID(ctx, sessionID)
+	if errors.Is(err, errs.ErrNotFound) {
+		return errs.ErrSessionInvalid
+	} else if err != nil {
+		u.log.WithReqID(ctx).Error().Err(err).Msg("failed to get session")
+		return err
+	}
+	if s.UserID != userID || s.RevokedAt != nil {
+		return errs.ErrSessionInvalid
+	}
+
+	user, err := u.repo.UserGetByID(ctx, userID)
+	if errors.Is(err, errs.ErrNotFound) {
+		return errs.ErrSessionInvalid
+	} else if err != nil {
+		u.log.WithReqID(ctx).Error().Err(err).Msg("failed to get user")
+		return err
+	}
+	if user.Blocked() {
+		return errs.ErrUserBlocked
+	}
+	// iat токена имеет точность до секунды, поэтому токен, выпущенный в ту же секунду, что и отзыв токенов,
+	// неотличим от выпущенного до отзыва и тоже отклоняется. Токены сессии, созданной после отзыва,
+	// выпущены после него, поэтому вход в ту же секунду не отклоняется.
+	if !s.CreatedAt.After(user.TokensValidAfter) && issuedAt.Before(ceilSecond(user.TokensValidAfter)) {
+		return errs.ErrSessionInvalid
+	}
+	return nil
+}
+
+// ceilSecond - округляет время вверх до секунды.
+func ceilSecond(t time.Time) time.Time {
+	c := t.Truncate(time.Second)
+	if c.Before(t) {
+		c = c.Add(time.Second)
+	}
+	return c
+}
+
+// SessionRevoke - отзывает сессию sessionID пользователя userID.
+func (u *UseCases) SessionRevoke(ctx context.Context, userID, sessionID uint64) error {
+	err := u.repo.SessionRevoke(ctx, userID, sessionID)
+	if errors.Is(err, errs.ErrNotFound) {
+		return errs.ErrSessionInvalid
+	} else if err != nil {
+		u.log.WithReqID(ctx).Error().Err(err).Msg("failed to revoke session")
+		return err
+	}
+	u.log.WithReqID(ctx).Info().Uint64("session_id", sessionID).Msg("session revoked")
+	return nil
+}
+
+// SessionRevokeAll - отзывает все сессии, персональные токены и все ранее выпущенные токены пользователя userID.
+func (u *UseCases) SessionRevokeAll(ctx context.Context, userID uint64) error {
+	if err := u.repo.SessionRevokeAll(ctx, userID); err != nil {
+		u.log.WithReqID(ctx).Error().Err(err).Msg("failed to revoke all sessions")
+		return err
+	}
+	u.log.WithReqID(ctx).Info().Uint64("user_id", userID).Msg("all sessions revoked")
+	return nil
+}
+
+// randomToken - генерирует случайный токен длиной n байт.
+// Возвращает токен и его хэш.
+func randomToken(n int) (string, string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", err
+	}
+	token := base64.RawURLEncoding.EncodeToString(b)
+	return token, tokenHash(token), nil
+}
+
+// tokenHash - возвращает хэш токена для хранения в репозитории.
+func tokenHash(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
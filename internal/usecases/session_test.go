@@ -0,0 +1,180 @@
+package usecases
+
+import (
+	"time"
+
+	"github.com/stretchr/testify/mock"
+
+	"gophermart-loyalty/internal/errs"
+	"gophermart-loyalty/internal/models"
+)
+
+func (suite *useCasesSuite) TestSessionCreate() {
+	suite.Run("success", func() {
+		var hash string
+		suite.repo.On("SessionCreate", mock.Anything, mock.Anything, int64(3600)).
+			Return(nil).
+			Run(func(args mock.Arguments) {
+				s := args.Get(1).(*models.Session)
+				s.ID = 10
+				hash = s.RefreshHash
+			}).Once()
+		s, token, err := suite.useCases.SessionCreate(suite.ctx(), 1, time.Hour)
+		suite.NoError(err)
+		suite.Equal(uint64(10), s.ID)
+		suite.Equal(uint64(1), s.UserID)
+		suite.NotEmpty(token)
+		suite.Equal(tokenHash(token), hash)
+		suite.NotEqual(token, hash)
+	})
+
+	suite.Run("user not found", func() {
+		suite.repo.On("SessionCreate", mock.Anything, mock.Anything, mock.Anything).
+			Return(errs.ErrNotFound).Once()
+		s, token, err := suite.useCases.SessionCreate(suite.ctx(), 1, time.Hour)
+		suite.ErrorIs(err, errs.ErrNotFound)
+		suite.Nil(s)
+		suite.Empty(token)
+	})
+}
+
+func (suite *useCasesSuite) TestSessionRefresh() {
+	suite.Run("success", func() {
+		suite.repo.On("SessionRotate", mock.Anything, tokenHash("old-token"), mock.Anything, int64(3600)).
+			Return(&models.Session{ID: 10, UserID: 1}, nil).Once()
+		s, token, err := suite.useCases.SessionRefresh(suite.ctx(), "old-token", time.Hour)
+		suite.NoError(err)
+		suite.Equal(uint64(10), s.ID)
+		suite.NotEmpty(token)
+		suite.NotEqual("old-token", token)
+	})
+
+	suite.Run("session not found", func() {
+		suite.repo.On("SessionRotate", mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+			Return(nil, errs.ErrNotFound).Once()
+		s, token, err := suite.useCases.SessionRefresh(suite.ctx(), "old-token", time.Hour)
+		suite.ErrorIs(err, errs.ErrSessionInvalid)
+		suite.Nil(s)
+		suite.Empty(token)
+	})
+
+	suite.Run("empty token", func() {
+		s, _, err := suite.useCases.SessionRefresh(suite.ctx(), "", time.Hour)
+		suite.ErrorIs(err, errs.ErrSessionInvalid)
+		suite.Nil(s)
+	})
+
+	suite.Run("internal error", func() {
+		suite.repo.On("SessionRotate", mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+			Return(nil, errs.ErrInternal).Once()
+		_, _, err := suite.useCases.SessionRefresh(suite.ctx(), "old-token", time.Hour)
+		suite.ErrorIs(err, errs.ErrInternal)
+	})
+}
+
+func (suite *useCasesSuite) TestSessionValidate() {
+	revokedAt := time.Now()
+	issuedAt := time.Now().Truncate(time.Second)
+
+	suite.Run("active session", func() {
+		suite.repo.On("SessionGetByID", mock.Anything, uint64(10)).
+			Return(&models.Session{ID: 10, UserID: 1}, nil).Once()
+		suite.repo.On("UserGetByID", mock.Anything, uint64(1)).
+			Return(&models.User{ID: 1}, nil).Once()
+		suite.NoError(suite.useCases.SessionValidate(suite.ctx(), 1, 10, issuedAt))
+	})
+
+	suite.Run("token issued in the same second as tokens revocation", func() {
+		suite.repo.On("SessionGetByID", mock.Anything, uint64(10)).
+			Return(&models.Session{ID: 10, UserID: 1}, nil).Once()
+		suite.repo.On("UserGetByID", mock.Anything, uint64(1)).
+			Return(&models.User{ID: 1, TokensValidAfter: issuedAt.Add(500 * time.Millisecond)}, nil).Once()
+		suite.ErrorIs(suite.useCases.SessionValidate(suite.ctx(), 1, 10, issuedAt), errs.ErrSessionInvalid)
+	})
+
+	suite.Run("token issued in the second after tokens revocation", func() {
+		suite.repo.On("SessionGetByID", mock.Anything, uint64(10)).
+			Return(&models.Session{ID: 10, UserID: 1}, nil).Once()
+		suite.repo.On("UserGetByID", mock.Anything, uint64(1)).
+			Return(&models.User{ID: 1, TokensValidAfter: issuedAt.Add(-500 * time.Millisecond)}, nil).Once()
+		suite.NoError(suite.useCases.SessionValidate(suite.ctx(), 1, 10, issuedAt))
+	})
+
+	suite.Run("session created after tokens revocation in the same second", func() {
+		suite.repo.On("SessionGetByID", mock.Anything, uint64(11)).
+			Return(&models.Session{ID: 11, UserID: 1, CreatedAt: issuedAt.Add(600 * time.Millisecond)}, nil).Once()
+		suite.repo.On("UserGetByID", mock.Anything, uint64(1)).
+			Return(&models.User{ID: 1, TokensValidAfter: issuedAt.Add(500 * time.Millisecond)}, nil).Once()
+		suite.NoError(suite.useCases.SessionValidate(suite.ctx(), 1, 11, issuedAt))
+	})
+
+	suite.Run("token issued before tokens revocation", func() {
+		suite.repo.On("SessionGetByID", mock.Anything, uint64(10)).
+			Return(&models.Session{ID: 10, UserID: 1}, nil).Once()
+		suite.repo.On("UserGetByID", mock.Anything, uint64(1)).
+			Return(&models.User{ID: 1, TokensValidAfter: issuedAt.Add(time.Second)}, nil).Once()
+		suite.ErrorIs(suite.useCases.SessionValidate(suite.ctx(), 1, 10, issuedAt), errs.ErrSessionInvalid)
+	})
+
+	suite.Run("blocked user", func() {
+		suite.repo.On("SessionGetByID", mock.Anything, uint64(10)).
+			Return(&models.Session{ID: 10, UserID: 1}, nil).Once()
+		suite.repo.On("UserGetByID", mock.Anything, uint64(1)).
+			Return(&models.User{ID: 1, Status: models.UserBlocked}, nil).Once()
+		suite.ErrorIs(suite.useCases.SessionValidate(suite.ctx(), 1, 10, issuedAt), errs.ErrUserBlocked)
+	})
+
+	suite.Run("revoked session", func() {
+		suite.repo.On("SessionGetByID", mock.Anything, uint64(10)).
+			Return(&models.Session{ID: 10, UserID: 1, RevokedAt: &revokedAt}, nil).Once()
+		suite.ErrorIs(suite.useCases.SessionValidate(suite.ctx(), 1, 10, issuedAt), errs.ErrSessionInvalid)
+	})
+
+	suite.Run("session of another user", func() {
+		suite.repo.On("SessionGetByID", mock.Anything, uint64(10)).
+			Return(&models.Session{ID: 10, UserID: 2}, nil).Once()
+		suite.ErrorIs(suite.useCases.SessionValidate(suite.ctx(), 1, 10, issuedAt), errs.ErrSessionInvalid)
+	})
+
+	suite.Run("session not found", func() {
+		suite.repo.On("SessionGetByID", mock.Anything, uint64(10)).
+			Return(nil, errs.ErrNotFound).Once()
+		suite.ErrorIs(suite.useCases.SessionValidate(suite.ctx(), 1, 10, issuedAt), errs.ErrSessionInvalid)
+	})
+
+	suite.Run("user not found", func() {
+		suite.repo.On("SessionGetByID", mock.Anything, uint64(10)).
+			Return(&models.Session{ID: 10, UserID: 1}, nil).Once()
+		suite.repo.On("UserGetByID", mock.Anything, uint64(1)).
+			Return(nil, errs.ErrNotFound).Once()
+		suite.ErrorIs(suite.useCases.SessionValidate(suite.ctx(), 1, 10, issuedAt), errs.ErrSessionInvalid)
+	})
+}
+
+func (suite *useCasesSuite) TestSessionRevoke() {
+	suite.Run("success", func() {
+		suite.repo.On("SessionRevoke", mock.Anything, uint64(1), uint64(10)).
+			Return(nil).Once()
+		suite.NoError(suite.useCases.SessionRevoke(suite.ctx(), 1, 10))
+	})
+
+	suite.Run("session already revoked", func() {
+		suite.repo.On("SessionRevoke", mock.Anything, uint64(1), uint64(10)).
+			Return(errs.ErrNotFound).Once()
+		suite.ErrorIs(suite.useCases.SessionRevoke(suite.ctx(), 1, 10), errs.ErrSessionInvalid)
+	})
+}
+
+func (suite *useCasesSuite) TestSessionRevokeAll() {
+	suite.Run("success", func() {
+		suite.repo.On("SessionRevokeAll", mock.Anything, uint64(1)).
+			Return(nil).Once()
+		suite.NoError(suite.useCases.SessionRevokeAll(suite.ctx(), 1))
+	})
+
+	suite.Run("internal error", func() {
+		suite.repo.On("SessionRevokeAll", mock.Anything, uint64(1)).
+			Return(errs.ErrInternal).Once()
+		suite.ErrorIs(suite.useCases.SessionRevokeAll(suite.ctx(), 1), errs.ErrInternal)
+	})
+}
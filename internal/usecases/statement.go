@@ -0,0 +1,39 @@
+package usecases
+
+import (
+	"context"
+	"time"
+
+	"gophermart-loyalty/internal/errs"
+	"gophermart-loyalty/internal/models"
+)
+
+// StatementGet - возвращает выписку по накопительному счету пользователя за календарный месяц (UTC),
+// содержащий момент month. Входящий остаток рассчитывается по проводкам, записанным до начала месяца,
+// а в выписку попадают проводки, записанные в течение месяца. Проводки не изменяются,
+// поэтому выписка за закрытый месяц не меняется при последующих изменениях операций.
+// Месяц, который еще не начался, отклоняется с errs.ErrStatementPeriodInvalid.
+func (u *UseCases) StatementGet(ctx context.Context, userID uint64, month time.Time) (*models.Statement, error) {
+	month = month.UTC()
+	from := time.Date(month.Year(), month.Month(), 1, 0, 0, 0, 0, time.UTC)
+	if from.After(time.Now()) {
+		return nil, errs.ErrStatementPeriodInvalid
+	}
+
+	opening, err := u.repo.UserBalanceAsOf(ctx, userID, from)
+	if err != nil {
+		u.log.WithReqID(ctx).Error().Err(err).Msg("failed to get statement opening balance")
+		return nil, err
+	}
+	s := models.NewStatement(userID, from, opening.Balance)
+
+	postings, err := u.repo.UserLedgerPostingsGet(ctx, userID, s.From, s.To)
+	if err != nil {
+		u.log.WithReqID(ctx).Error().Err(err).Msg("failed to get statement postings")
+		return nil, err
+	}
+	for _, p := range postings {
+		s.Add(p)
+	}
+	return s, nil
+}
@@ -0,0 +1,66 @@
+package usecases
+
+import (
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/mock"
+
+	"gophermart-loyalty/internal/errs"
+	"gophermart-loyalty/internal/models"
+)
+
+func (suite *useCasesSuite) TestStatementGet() {
+	from := time.Date(2022, 10, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2022, 11, 1, 0, 0, 0, 0, time.UTC)
+
+	suite.Run("success", func() {
+		suite.repo.On("UserBalanceAsOf", mock.Anything, uint64(1), from).
+			Return(&models.BalanceSnapshot{AsOf: from, Balance: decimal.NewFromInt(100)}, nil).Once()
+		withdrawal := &models.Operation{ID: 2, UserID: 1, Type: models.OrderWithdrawal, Status: models.StatusCanceled, Amount: decimal.NewFromInt(-50)}
+		postings := []*models.LedgerPosting{
+			{ID: 1, Account: models.LedgerAccrualSource, Amount: decimal.NewFromInt(500), CreatedAt: from.Add(24 * time.Hour),
+				Operation: &models.Operation{ID: 1, UserID: 1, Type: models.OrderAccrual, Status: models.StatusProcessed, Amount: decimal.NewFromInt(500)}},
+			{ID: 2, Account: models.LedgerWithdrawalSink, Amount: decimal.NewFromInt(-50), CreatedAt: from.Add(48 * time.Hour), Operation: withdrawal},
+			{ID: 3, Account: models.LedgerWithdrawalSink, Amount: decimal.NewFromInt(50), CreatedAt: from.Add(60 * time.Hour), Operation: withdrawal},
+			{ID: 4, Account: models.LedgerExpirationSink, Amount: decimal.NewFromInt(-10), CreatedAt: from.Add(72 * time.Hour),
+				Operation: &models.Operation{ID: 3, UserID: 1, Type: models.PointsExpiration, Status: models.StatusProcessed, Amount: decimal.NewFromInt(-10)}},
+		}
+		suite.repo.On("UserLedgerPostingsGet", mock.Anything, uint64(1), from, to).Return(postings, nil).Once()
+
+		s, err := suite.useCases.StatementGet(suite.ctx(), 1, from.Add(10*24*time.Hour))
+		suite.NoError(err)
+		suite.Equal(from, s.From)
+		suite.Equal(to, s.To)
+		suite.Equal("100", s.Opening.String())
+		suite.Equal("500", s.Accruals.String())
+		suite.Equal("0", s.Withdrawals.String())
+		suite.Equal("10", s.Expirations.String())
+		suite.Equal("590", s.Closing.String())
+		suite.Require().Len(s.Postings, 4)
+		suite.Equal(uint64(1), s.Postings[0].ID)
+		suite.Equal(uint64(4), s.Postings[3].ID)
+	})
+
+	suite.Run("no postings", func() {
+		suite.repo.On("UserBalanceAsOf", mock.Anything, uint64(2), from).
+			Return(&models.BalanceSnapshot{AsOf: from, Balance: decimal.NewFromInt(100)}, nil).Once()
+		suite.repo.On("UserLedgerPostingsGet", mock.Anything, uint64(2), from, to).Return(nil, nil).Once()
+
+		s, err := suite.useCases.StatementGet(suite.ctx(), 2, from)
+		suite.NoError(err)
+		suite.Equal("100", s.Closing.String())
+		suite.Empty(s.Postings)
+	})
+
+	suite.Run("future month", func() {
+		_, err := suite.useCases.StatementGet(suite.ctx(), 1, time.Now().AddDate(0, 1, 0))
+		suite.ErrorIs(err, errs.ErrStatementPeriodInvalid)
+	})
+
+	suite.Run("internal error", func() {
+		suite.repo.On("UserBalanceAsOf", mock.Anything, uint64(3), from).Return(nil, errs.ErrInternal).Once()
+		_, err := suite.useCases.StatementGet(suite.ctx(), 3, from)
+		suite.ErrorIs(err, errs.ErrInternal)
+	})
+}
@@ -0,0 +1,285 @@
+package usecases
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base32"
+	"errors"
+	"strings"
+	"time"
+
+	"gophermart-loyalty/internal/config"
+	"gophermart-loyalty/internal/errs"
+	"gophermart-loyalty/internal/models"
+	"gophermart-loyalty/pkg/totp"
+)
+
+const (
+	// totpSkew - допустимое расхождение часов клиента и сервера в шагах TOTP
+	totpSkew = 1
+	// recoveryCodeCount - число кодов восстановления, выдаваемых при включении двухфакторной аутентификации
+	recoveryCodeCount = 10
+	// recoveryCodeLen - длина кода восстановления в байтах
+	recoveryCodeLen = 5
+	// loginChallengeTokenLen - длина токена незавершенного входа в байтах
+	loginChallengeTokenLen = 32
+	// loginChallengeMaxAttempts - число попыток ввода кода для одного незавершенного входа
+	loginChallengeMaxAttempts = 5
+)
+
+// recoveryCodeEncoding - кодировка кодов восстановления, удобная для ручного ввода
+var recoveryCodeEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// TOTPEnroll - начинает подключение двухфакторной аутентификации пользователя userID.
+// Генерирует новый секрет, который нужно подтвердить кодом через TOTPConfirm.
+// Возвращает секрет и URI otpauth:// для приложения-аутентификатора.
+func (u *UseCases) TOTPEnroll(ctx context.Context, userID uint64, issuer string) (string, string, error) {
+	user, err := u.UserGetByID(ctx, userID)
+	if err != nil {
+		return "", "", err
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		u.log.WithReqID(ctx).Error().Err(err).Msg("failed to generate totp secret")
+		return "", "", errs.ErrInternal
+	}
+
+	// Секрет сохраняется, только если двухфакторная аутентификация еще не включена
+	err = u.repo.TOTPEnroll(ctx, &models.TOTP{UserID: userID, Secret: secret})
+	if errors.Is(err, errs.ErrNotFound) {
+		return "", "", errs.ErrTOTPAlreadyEnabled
+	} else if err != nil {
+		u.log.WithReqID(ctx).Error().Err(err).Msg("failed to enroll totp")
+		return "", "", err
+	}
+
+	u.log.WithReqID(ctx).Info().Uint64("user_id", userID).Msg("totp enrollment started")
+	return secret, totp.ProvisioningURI(issuer, user.Login, secret), nil
+}
+
+// TOTPConfirm - включает двухфакторную аутентификацию пользователя userID,
+// если код подходит к секрету, полученному в TOTPEnroll.
+// Возвращает одноразовые коды восстановления. В репозитории хранятся только их хэши.
+func (u *UseCases) TOTPConfirm(ctx context.Context, userID uint64, code string) ([]string, error) {
+	t, err := u.repo.TOTPGet(ctx, userID)
+	if errors.Is(err, errs.ErrNotFound) {
+		return nil, errs.ErrTOTPNotEnrolled
+	} else if err != nil {
+		u.log.WithReqID(ctx).Error().Err(err).Msg("failed to get totp")
+		return nil, err
+	}
+	if t.Enabled() {
+		return nil, errs.ErrTOTPAlreadyEnabled
+	}
+
+	step, ok, err := totp.Validate(t.Secret, code, time.Now(), totpSkew)
+	if err != nil {
+		u.log.WithReqID(ctx).Error().Err(err).Msg("failed to validate totp code")
+		return nil, errs.ErrInternal
+	}
+	if !ok {
+		return nil, errs.ErrTOTPCodeInvalid
+	}
+
+	codes := make([]string, recoveryCodeCount)
+	hashes := make([]string, recoveryCodeCount)
+	for i := range codes {
+		if codes[i], err = recoveryCode(); err != nil {
+			u.log.WithReqID(ctx).Error().Err(err).Msg("failed to generate recovery code")
+			return nil, errs.ErrInternal
+		}
+		hashes[i] = recoveryCodeHash(codes[i])
+	}
+
+	err = u.repo.TOTPConfirm(ctx, userID, step, hashes)
+	if errors.Is(err, errs.ErrNotFound) {
+		// Секрет подтвержден или удален параллельным запросом
+		return nil, errs.ErrTOTPNotEnrolled
+	} else if err != nil {
+		u.log.WithReqID(ctx).Error().Err(err).Msg("failed to confirm totp")
+		return nil, err
+	}
+
+	u.log.WithReqID(ctx).Info().Uint64("user_id", userID).Msg("totp enabled")
+	return codes, nil
+}
+
+// TOTPDisable - отключает двухфакторную аутентификацию пользователя userID.
+// Для отключения требуется текущий пароль пользователя.
+func (u *UseCases) TOTPDisable(ctx context.Context, userID uint64, password string) error {
+	user, err := u.UserGetByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if _, err = u.UserCheckLoginPass(ctx, user.Login, password); err != nil {
+		return err
+	}
+
+	err = u.repo.TOTPDelete(ctx, userID)
+	if errors.Is(err, errs.ErrNotFound) {
+		return errs.ErrTOTPNotEnrolled
+	} else if err != nil {
+		u.log.WithReqID(ctx).Error().Err(err).Msg("failed to delete totp")
+		return err
+	}
+
+	u.log.WithReqID(ctx).Info().Uint64("user_id", userID).Msg("totp disabled")
+	return nil
+}
+
+// TOTPEnabled - проверяет, что у пользователя userID включена двухфакторная аутентификация.
+func (u *UseCases) TOTPEnabled(ctx context.Context, userID uint64) (bool, error) {
+	t, err := u.repo.TOTPGet(ctx, userID)
+	if errors.Is(err, errs.ErrNotFound) {
+		return false, nil
+	} else if err != nil {
+		u.log.WithReqID(ctx).Error().Err(err).Msg("failed to get totp")
+		return false, err
+	}
+	return t.Enabled(), nil
+}
+
+// LoginChallengeCreate - создает незавершенный вход пользователя userID со временем жизни ttl.
+// Вход завершается через LoginChallengeVerify после ввода кода второго фактора.
+// Возвращает незавершенный вход и его токен. В репозитории хранится только хэш токена.
+func (u *UseCases) LoginChallengeCreate(ctx context.Context, userID uint64, ttl time.Duration) (*models.LoginChallenge, string, error) {
+	token, hash, err := randomToken(loginChallengeTokenLen)
+	if err != nil {
+		u.log.WithReqID(ctx).Error().Err(err).Msg("failed to generate login challenge")
+		return nil, "", errs.ErrInternal
+	}
+
+	c := &models.LoginChallenge{UserID: userID, TokenHash: hash}
+	if err = u.repo.LoginChallengeCreate(ctx, c, int64(ttl.Seconds())); err != nil {
+		u.log.WithReqID(ctx).Error().Err(err).Msg("failed to create login challenge")
+		return nil, "", err
+	}
+
+	u.log.WithReqID(ctx).Debug().Uint64("user_id", userID).Msg("login challenge created")
+	return c, token, nil
+}
+
+// LoginChallengeVerify - завершает вход по токену незавершенного входа и коду второго фактора.
+// Вместо кода TOTP может быть передан неиспользованный код восстановления.
+// Каждый код TOTP и каждый код восстановления принимаются только один раз.
+// Неверный код учитывается в счетчиках неудачных попыток входа по логину и по IP-адресу ip так же,
+// как неверный пароль в UserLogin, поэтому перебор кодов через новые незавершенные входы ограничен
+// блокировкой учетной записи. Счетчик по логину сбрасывается только после успешного ввода кода.
+// Возвращает пользователя, для которого нужно создать сессию.
+func (u *UseCases) LoginChallengeVerify(ctx context.Context, challenge, code, ip string, cfg *config.LoginThrottle) (*models.User, error) {
+	c, err := u.repo.LoginChallengeGet(ctx, tokenHash(challenge), loginChallengeMaxAttempts)
+	if errors.Is(err, errs.ErrNotFound) {
+		return nil, errs.ErrLoginChallengeInvalid
+	} else if err != nil {
+		u.log.WithReqID(ctx).Error().Err(err).Msg("failed to get login challenge")
+		return nil, err
+	}
+
+	user, err := u.UserGetByID(ctx, c.UserID)
+	if err != nil {
+		return nil, err
+	}
+	attempts, err := u.loginAttemptsRegister(ctx, user.Login, ip, cfg)
+	if err != nil {
+		return nil, err
+	}
+	failed := false
+	defer func() {
+		if !failed {
+			u.loginAttemptsRelease(ctx, attempts)
+		}
+	}()
+
+	t, err := u.repo.TOTPGet(ctx, c.UserID)
+	if errors.Is(err, errs.ErrNotFound) {
+		return nil, errs.ErrLoginChallengeInvalid
+	} else if err != nil {
+		u.log.WithReqID(ctx).Error().Err(err).Msg("failed to get totp")
+		return nil, err
+	}
+	if !t.Enabled() {
+		return nil, errs.ErrLoginChallengeInvalid
+	}
+
+	// Проверяем код и регистрируем неудачную попытку
+	if err = u.totpCheck(ctx, t, code); errors.Is(err, errs.ErrTOTPCodeInvalid) {
+		if err := u.repo.LoginChallengeFail(ctx, c.ID); err != nil {
+			u.log.WithReqID(ctx).Error().Err(err).Msg("failed to register login challenge failure")
+			return nil, err
+		}
+		failed = true
+		u.loginFailuresRegister(ctx, attempts, cfg)
+		u.log.WithReqID(ctx).Info().Uint64("user_id", c.UserID).Msg("invalid second factor code")
+		return nil, err
+	} else if err != nil {
+		return nil, err
+	}
+
+	err = u.repo.LoginChallengeConsume(ctx, c.ID)
+	if errors.Is(err, errs.ErrNotFound) {
+		return nil, errs.ErrLoginChallengeInvalid
+	} else if err != nil {
+		u.log.WithReqID(ctx).Error().Err(err).Msg("failed to consume login challenge")
+		return nil, err
+	}
+
+	// Учетная запись могла быть заблокирована после ввода пароля
+	if user.Blocked() {
+		return nil, errs.ErrUserBlocked
+	}
+	u.loginFailureReset(ctx, user.Login)
+	return user, nil
+}
+
+// totpCheck - проверяет код TOTP или код восстановления и отмечает его использованным.
+// Если код неверный или уже использован, возвращает errs.ErrTOTPCodeInvalid.
+func (u *UseCases) totpCheck(ctx context.Context, t *models.TOTP, code string) error {
+	code = strings.TrimSpace(code)
+
+	// Код TOTP состоит из Digits цифр, код восстановления длиннее
+	if len(code) == totp.Digits && strings.Trim(code, "0123456789") == "" {
+		step, ok, err := totp.Validate(t.Secret, code, time.Now(), totpSkew)
+		if err != nil {
+			u.log.WithReqID(ctx).Error().Err(err).Msg("failed to validate totp code")
+			return errs.ErrInternal
+		}
+		if !ok {
+			return errs.ErrTOTPCodeInvalid
+		}
+		err = u.repo.TOTPUseStep(ctx, t.UserID, step)
+		if errors.Is(err, errs.ErrNotFound) {
+			return errs.ErrTOTPCodeInvalid
+		} else if err != nil {
+			u.log.WithReqID(ctx).Error().Err(err).Msg("failed to use totp step")
+			return err
+		}
+		return nil
+	}
+
+	err := u.repo.TOTPRecoveryCodeUse(ctx, t.UserID, recoveryCodeHash(code))
+	if errors.Is(err, errs.ErrNotFound) {
+		return errs.ErrTOTPCodeInvalid
+	} else if err != nil {
+		u.log.WithReqID(ctx).Error().Err(err).Msg("failed to use recovery code")
+		return err
+	}
+	u.log.WithReqID(ctx).Info().Uint64("user_id", t.UserID).Msg("recovery code used")
+	return nil
+}
+
+// recoveryCode - генерирует код восстановления вида xxxx-xxxx.
+func recoveryCode() (string, error) {
+	b := make([]byte, recoveryCodeLen)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	s := strings.ToLower(recoveryCodeEncoding.EncodeToString(b))
+	return s[:4] + "-" + s[4:], nil
+}
+
+// recoveryCodeHash - возвращает хэш кода восстановления без учета регистра и разделителей.
+func recoveryCodeHash(code string) string {
+	code = strings.ToLower(strings.NewReplacer("-", "", " ", "").Replace(code))
+	return tokenHash(code)
+}
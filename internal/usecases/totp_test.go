@@ -0,0 +1,150 @@
+package usecases
+
+import (
+	"time"
+
+	"github.com/stretchr/testify/mock"
+
+	"gophermart-loyalty/internal/config"
+	"gophermart-loyalty/internal/errs"
+	"gophermart-loyalty/internal/models"
+	"gophermart-loyalty/pkg/totp"
+)
+
+const testTOTPSecret = "JBSWY3DPEHPK3PXP"
+
+func (suite *useCasesSuite) TestTOTPConfirm() {
+	suite.Run("success", func() {
+		step := totp.Step(time.Now())
+		code, err := totp.Code(testTOTPSecret, step)
+		suite.Require().NoError(err)
+
+		var hashes []string
+		suite.repo.On("TOTPGet", mock.Anything, uint64(1)).
+			Return(&models.TOTP{UserID: 1, Secret: testTOTPSecret}, nil).Once()
+		suite.repo.On("TOTPConfirm", mock.Anything, uint64(1), step, mock.Anything).
+			Run(func(args mock.Arguments) { hashes = args.Get(3).([]string) }).
+			Return(nil).Once()
+
+		codes, err := suite.useCases.TOTPConfirm(suite.ctx(), 1, code)
+		suite.NoError(err)
+		suite.Len(codes, recoveryCodeCount)
+
+		// в репозитории хранятся только хэши кодов восстановления
+		suite.Require().Len(hashes, recoveryCodeCount)
+		for i, c := range codes {
+			suite.Regexp(`^[a-z2-7]{4}-[a-z2-7]{4}$`, c)
+			suite.Equal(recoveryCodeHash(c), hashes[i])
+		}
+	})
+
+	suite.Run("already enabled", func() {
+		suite.repo.On("TOTPGet", mock.Anything, uint64(1)).
+			Return(&models.TOTP{UserID: 1, Secret: testTOTPSecret, ConfirmedAt: &time.Time{}}, nil).Once()
+		_, err := suite.useCases.TOTPConfirm(suite.ctx(), 1, "123456")
+		suite.ErrorIs(err, errs.ErrTOTPAlreadyEnabled)
+	})
+
+	suite.Run("invalid code", func() {
+		suite.repo.On("TOTPGet", mock.Anything, uint64(1)).
+			Return(&models.TOTP{UserID: 1, Secret: testTOTPSecret}, nil).Once()
+		_, err := suite.useCases.TOTPConfirm(suite.ctx(), 1, "abcdef")
+		suite.ErrorIs(err, errs.ErrTOTPCodeInvalid)
+	})
+}
+
+func (suite *useCasesSuite) TestLoginChallengeVerify() {
+	enabled := &models.TOTP{UserID: 1, Secret: testTOTPSecret, ConfirmedAt: &time.Time{}}
+	challenge := &models.LoginChallenge{ID: 3, UserID: 1}
+	user := &models.User{ID: 1, Login: "oleg"}
+	cfg := &config.LoginThrottle{MaxFailures: 10, IPMaxFailures: 100, Lockout: 15 * time.Minute, Window: 15 * time.Minute}
+
+	suite.Run("replayed code", func() {
+		step := totp.Step(time.Now())
+		code, err := totp.Code(testTOTPSecret, step)
+		suite.Require().NoError(err)
+
+		suite.repo.On("LoginChallengeGet", mock.Anything, tokenHash("challenge"), loginChallengeMaxAttempts).
+			Return(challenge, nil).Once()
+		suite.repo.On("UserGetByID", mock.Anything, uint64(1)).Return(user, nil).Once()
+		suite.repo.On("LoginAttemptRegister", mock.Anything, "login:oleg", int64(900)).
+			Return(&models.LoginFailure{Key: "login:oleg", Failures: 1, LastFailedAt: time.Now()}, nil).Once()
+		suite.repo.On("LoginAttemptRegister", mock.Anything, "ip:10.0.0.1", int64(900)).
+			Return(&models.LoginFailure{Key: "ip:10.0.0.1", Failures: 1, LastFailedAt: time.Now()}, nil).Once()
+		suite.repo.On("TOTPGet", mock.Anything, uint64(1)).Return(enabled, nil).Once()
+		suite.repo.On("TOTPUseStep", mock.Anything, uint64(1), step).Return(errs.ErrNotFound).Once()
+		suite.repo.On("LoginChallengeFail", mock.Anything, uint64(3)).Return(nil).Once()
+		suite.repo.On("LoginFailureBlock", mock.Anything, "login:oleg", int64(0)).
+			Return(&models.LoginFailure{Key: "login:oleg", Failures: 1}, nil).Once()
+		suite.repo.On("LoginFailureBlock", mock.Anything, "ip:10.0.0.1", int64(0)).
+			Return(&models.LoginFailure{Key: "ip:10.0.0.1", Failures: 1}, nil).Once()
+
+		_, err = suite.useCases.LoginChallengeVerify(suite.ctx(), "challenge", code, "10.0.0.1", cfg)
+		suite.ErrorIs(err, errs.ErrTOTPCodeInvalid)
+	})
+
+	suite.Run("recovery code is normalized", func() {
+		suite.repo.On("LoginChallengeGet", mock.Anything, tokenHash("challenge"), loginChallengeMaxAttempts).
+			Return(challenge, nil).Once()
+		suite.repo.On("UserGetByID", mock.Anything, uint64(1)).Return(user, nil).Once()
+		suite.repo.On("LoginAttemptRegister", mock.Anything, "login:oleg", int64(900)).
+			Return(&models.LoginFailure{Key: "login:oleg", Failures: 1, LastFailedAt: time.Now()}, nil).Once()
+		suite.repo.On("LoginAttemptRegister", mock.Anything, "ip:10.0.0.1", int64(900)).
+			Return(&models.LoginFailure{Key: "ip:10.0.0.1", Failures: 1, LastFailedAt: time.Now()}, nil).Once()
+		suite.repo.On("LoginAttemptRelease", mock.Anything, "login:oleg").Return(nil).Once()
+		suite.repo.On("LoginAttemptRelease", mock.Anything, "ip:10.0.0.1").Return(nil).Once()
+		suite.repo.On("TOTPGet", mock.Anything, uint64(1)).Return(enabled, nil).Once()
+		suite.repo.On("TOTPRecoveryCodeUse", mock.Anything, uint64(1), recoveryCodeHash("abcd-efgh")).Return(nil).Once()
+		suite.repo.On("LoginChallengeConsume", mock.Anything, uint64(3)).Return(nil).Once()
+		suite.repo.On("LoginFailureReset", mock.Anything, "login:oleg").Return(nil).Once()
+
+		u, err := suite.useCases.LoginChallengeVerify(suite.ctx(), "challenge", " ABCD EFGH ", "10.0.0.1", cfg)
+		suite.NoError(err)
+		suite.Equal(uint64(1), u.ID)
+	})
+
+	suite.Run("login locked after failed codes", func() {
+		suite.repo.On("LoginChallengeGet", mock.Anything, tokenHash("challenge"), loginChallengeMaxAttempts).
+			Return(challenge, nil).Once()
+		suite.repo.On("UserGetByID", mock.Anything, uint64(1)).Return(user, nil).Once()
+		suite.repo.On("LoginAttemptRegister", mock.Anything, "login:oleg", int64(900)).
+			Return(&models.LoginFailure{Key: "login:oleg", Failures: 10, LastFailedAt: time.Now(), BlockedUntil: time.Now().Add(time.Hour)}, nil).Once()
+
+		_, err := suite.useCases.LoginChallengeVerify(suite.ctx(), "challenge", "abcd-efgh", "10.0.0.1", cfg)
+		suite.ErrorIs(err, errs.ErrUserLocked)
+	})
+
+	suite.Run("two-factor authentication disabled", func() {
+		suite.repo.On("LoginChallengeGet", mock.Anything, tokenHash("challenge"), loginChallengeMaxAttempts).
+			Return(challenge, nil).Once()
+		suite.repo.On("UserGetByID", mock.Anything, uint64(1)).Return(user, nil).Once()
+		suite.repo.On("LoginAttemptRegister", mock.Anything, "login:oleg", int64(900)).
+			Return(&models.LoginFailure{Key: "login:oleg", Failures: 1, LastFailedAt: time.Now()}, nil).Once()
+		suite.repo.On("LoginAttemptRegister", mock.Anything, "ip:10.0.0.1", int64(900)).
+			Return(&models.LoginFailure{Key: "ip:10.0.0.1", Failures: 1, LastFailedAt: time.Now()}, nil).Once()
+		suite.repo.On("LoginAttemptRelease", mock.Anything, "login:oleg").Return(nil).Once()
+		suite.repo.On("LoginAttemptRelease", mock.Anything, "ip:10.0.0.1").Return(nil).Once()
+		suite.repo.On("TOTPGet", mock.Anything, uint64(1)).Return(nil, errs.ErrNotFound).Once()
+
+		_, err := suite.useCases.LoginChallengeVerify(suite.ctx(), "challenge", "123456", "10.0.0.1", cfg)
+		suite.ErrorIs(err, errs.ErrLoginChallengeInvalid)
+	})
+
+	suite.Run("challenge already consumed", func() {
+		suite.repo.On("LoginChallengeGet", mock.Anything, tokenHash("challenge"), loginChallengeMaxAttempts).
+			Return(challenge, nil).Once()
+		suite.repo.On("UserGetByID", mock.Anything, uint64(1)).Return(user, nil).Once()
+		suite.repo.On("LoginAttemptRegister", mock.Anything, "login:oleg", int64(900)).
+			Return(&models.LoginFailure{Key: "login:oleg", Failures: 1, LastFailedAt: time.Now()}, nil).Once()
+		suite.repo.On("LoginAttemptRegister", mock.Anything, "ip:10.0.0.1", int64(900)).
+			Return(&models.LoginFailure{Key: "ip:10.0.0.1", Failures: 1, LastFailedAt: time.Now()}, nil).Once()
+		suite.repo.On("LoginAttemptRelease", mock.Anything, "login:oleg").Return(nil).Once()
+		suite.repo.On("LoginAttemptRelease", mock.Anything, "ip:10.0.0.1").Return(nil).Once()
+		suite.repo.On("TOTPGet", mock.Anything, uint64(1)).Return(enabled, nil).Once()
+		suite.repo.On("TOTPRecoveryCodeUse", mock.Anything, uint64(1), mock.Anything).Return(nil).Once()
+		suite.repo.On("LoginChallengeConsume", mock.Anything, uint64(3)).Return(errs.ErrNotFound).Once()
+
+		_, err := suite.useCases.LoginChallengeVerify(suite.ctx(), "challenge", "abcd-efgh", "10.0.0.1", cfg)
+		suite.ErrorIs(err, errs.ErrLoginChallengeInvalid)
+	})
+}
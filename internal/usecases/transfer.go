@@ -0,0 +1,83 @@
+package usecases
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/shopspring/decimal"
+
+	"gophermart-loyalty/internal/config"
+	"gophermart-loyalty/internal/errs"
+	"gophermart-loyalty/internal/models"
+)
+
+// TransferPrepare - создает модели операций перевода amount баллов от пользователя userID
+// пользователю с логином recipientLogin: списание у отправителя и зачисление получателю.
+func (u *UseCases) TransferPrepare(ctx context.Context, userID uint64, recipientLogin string, amount decimal.Decimal) (*models.Operation, *models.Operation, error) {
+	if recipientLogin == "" {
+		return nil, nil, errs.ErrBadRequest
+	}
+	if !amount.IsPositive() {
+		return nil, nil, errs.ErrOperationAmountInvalid
+	}
+
+	sender, err := u.UserGetByID(ctx, userID)
+	if err != nil {
+		return nil, nil, err
+	}
+	recipient, err := u.repo.UserGetByLogin(ctx, recipientLogin)
+	if errors.Is(err, errs.ErrNotFound) {
+		return nil, nil, errs.ErrTransferRecipientNotFound
+	} else if err != nil {
+		u.log.WithReqID(ctx).Error().Err(err).Msg("failed to get transfer recipient")
+		return nil, nil, err
+	}
+	if recipient.ID == sender.ID {
+		return nil, nil, errs.ErrTransferSelf
+	}
+
+	debit := &models.Operation{
+		UserID:         sender.ID,
+		Type:           models.Transfer,
+		Status:         models.StatusProcessed,
+		Amount:         amount.Neg(),
+		CounterpartyID: &recipient.ID,
+		Description:    fmt.Sprintf("Перевод баллов пользователю %s", recipient.Login),
+	}
+	credit := &models.Operation{
+		UserID:         recipient.ID,
+		Type:           models.Transfer,
+		Status:         models.StatusProcessed,
+		Amount:         amount,
+		CounterpartyID: &sender.ID,
+		Description:    fmt.Sprintf("Перевод баллов от пользователя %s", sender.Login),
+	}
+	return debit, credit, nil
+}
+
+// TransferCreate - переводит баллы между пользователями: создает операции списания debit и зачисления credit.
+// Если перевод превышает ограничения на сумму или количество переводов за сутки,
+// возвращает errs.ErrTransferDailyAmountExceeded или errs.ErrTransferDailyCountExceeded.
+func (u *UseCases) TransferCreate(ctx context.Context, debit, credit *models.Operation, cfg *config.Transfer) error {
+	// Ограничения проверяются под блокировкой отправителя, поэтому параллельные переводы не могут их превысить
+	checkFunc := func(ctx context.Context, sent decimal.Decimal, count int) error {
+		if cfg.DailyCount > 0 && count >= cfg.DailyCount {
+			return errs.ErrTransferDailyCountExceeded
+		}
+		if cfg.DailyAmount.IsPositive() && sent.Sub(debit.Amount).GreaterThan(cfg.DailyAmount) {
+			return errs.ErrTransferDailyAmountExceeded
+		}
+		return nil
+	}
+
+	if err := u.repo.OperationTransfer(ctx, debit, credit, checkFunc); err != nil {
+		u.log.WithReqID(ctx).Error().Err(err).Msg("failed to create transfer")
+		return err
+	}
+	u.log.WithReqID(ctx).Info().
+		Uint64("debit_operation_id", debit.ID).
+		Uint64("credit_operation_id", credit.ID).
+		Msg("transfer created")
+	return nil
+}
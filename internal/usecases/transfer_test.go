@@ -0,0 +1,98 @@
+package usecases
+
+import (
+	"context"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/mock"
+
+	"gophermart-loyalty/internal/config"
+	"gophermart-loyalty/internal/errs"
+	"gophermart-loyalty/internal/models"
+	"gophermart-loyalty/internal/repo"
+)
+
+func (suite *useCasesSuite) TestTransferPrepare() {
+	suite.Run("success", func() {
+		suite.repo.On("UserGetByID", mock.Anything, uint64(1)).Return(&models.User{ID: 1, Login: "alice"}, nil).Once()
+		suite.repo.On("UserGetByLogin", mock.Anything, "bob").Return(&models.User{ID: 2, Login: "bob"}, nil).Once()
+
+		debit, credit, err := suite.useCases.TransferPrepare(suite.ctx(), 1, "bob", decimal.NewFromInt(50))
+		suite.NoError(err)
+		suite.Equal(uint64(1), debit.UserID)
+		suite.Equal(models.Transfer, debit.Type)
+		suite.Equal(models.StatusProcessed, debit.Status)
+		suite.Equal("-50", debit.Amount.String())
+		suite.Equal(uint64(2), *debit.CounterpartyID)
+		suite.Equal("Перевод баллов пользователю bob", debit.Description)
+		suite.Equal(uint64(2), credit.UserID)
+		suite.Equal("50", credit.Amount.String())
+		suite.Equal(uint64(1), *credit.CounterpartyID)
+		suite.Equal("Перевод баллов от пользователя alice", credit.Description)
+	})
+
+	suite.Run("recipient not found", func() {
+		suite.repo.On("UserGetByID", mock.Anything, uint64(1)).Return(&models.User{ID: 1, Login: "alice"}, nil).Once()
+		suite.repo.On("UserGetByLogin", mock.Anything, "nobody").Return(nil, errs.ErrNotFound).Once()
+
+		_, _, err := suite.useCases.TransferPrepare(suite.ctx(), 1, "nobody", decimal.NewFromInt(50))
+		suite.ErrorIs(err, errs.ErrTransferRecipientNotFound)
+	})
+
+	suite.Run("transfer to self", func() {
+		suite.repo.On("UserGetByID", mock.Anything, uint64(1)).Return(&models.User{ID: 1, Login: "alice"}, nil).Once()
+		suite.repo.On("UserGetByLogin", mock.Anything, "alice").Return(&models.User{ID: 1, Login: "alice"}, nil).Once()
+
+		_, _, err := suite.useCases.TransferPrepare(suite.ctx(), 1, "alice", decimal.NewFromInt(50))
+		suite.ErrorIs(err, errs.ErrTransferSelf)
+	})
+
+	suite.Run("invalid amount", func() {
+		_, _, err := suite.useCases.TransferPrepare(suite.ctx(), 1, "bob", decimal.NewFromInt(-50))
+		suite.ErrorIs(err, errs.ErrOperationAmountInvalid)
+		_, _, err = suite.useCases.TransferPrepare(suite.ctx(), 1, "bob", decimal.Zero)
+		suite.ErrorIs(err, errs.ErrOperationAmountInvalid)
+	})
+
+	suite.Run("empty login", func() {
+		_, _, err := suite.useCases.TransferPrepare(suite.ctx(), 1, "", decimal.NewFromInt(50))
+		suite.ErrorIs(err, errs.ErrBadRequest)
+	})
+}
+
+func (suite *useCasesSuite) TestTransferCreate() {
+	cfg := &config.Transfer{DailyAmount: decimal.NewFromInt(100), DailyCount: 3}
+	debit := &models.Operation{UserID: 1, Type: models.Transfer, Amount: decimal.NewFromInt(-40)}
+	credit := &models.Operation{UserID: 2, Type: models.Transfer, Amount: decimal.NewFromInt(40)}
+	// transfer - возвращает результат коллбэка проверки для переводов на сумму sent и в количестве count за сутки
+	transfer := func(sent int64, count int) func(context.Context, *models.Operation, *models.Operation, repo.TransferCheckFunc) error {
+		return func(ctx context.Context, _, _ *models.Operation, f repo.TransferCheckFunc) error {
+			return f(ctx, decimal.NewFromInt(sent), count)
+		}
+	}
+
+	suite.Run("success", func() {
+		suite.repo.On("OperationTransfer", mock.Anything, debit, credit, mock.Anything).Return(transfer(60, 2)).Once()
+		suite.NoError(suite.useCases.TransferCreate(suite.ctx(), debit, credit, cfg))
+	})
+
+	suite.Run("daily amount exceeded", func() {
+		suite.repo.On("OperationTransfer", mock.Anything, debit, credit, mock.Anything).Return(transfer(61, 1)).Once()
+		suite.ErrorIs(suite.useCases.TransferCreate(suite.ctx(), debit, credit, cfg), errs.ErrTransferDailyAmountExceeded)
+	})
+
+	suite.Run("daily count exceeded", func() {
+		suite.repo.On("OperationTransfer", mock.Anything, debit, credit, mock.Anything).Return(transfer(0, 3)).Once()
+		suite.ErrorIs(suite.useCases.TransferCreate(suite.ctx(), debit, credit, cfg), errs.ErrTransferDailyCountExceeded)
+	})
+
+	suite.Run("limits disabled", func() {
+		suite.repo.On("OperationTransfer", mock.Anything, debit, credit, mock.Anything).Return(transfer(1000000, 1000)).Once()
+		suite.NoError(suite.useCases.TransferCreate(suite.ctx(), debit, credit, &config.Transfer{}))
+	})
+
+	suite.Run("insufficient funds", func() {
+		suite.repo.On("OperationTransfer", mock.Anything, debit, credit, mock.Anything).Return(errs.ErrUserBalanceNegative).Once()
+		suite.ErrorIs(suite.useCases.TransferCreate(suite.ctx(), debit, credit, cfg), errs.ErrUserBalanceNegative)
+	})
+}
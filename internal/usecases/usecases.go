@@ -1,19 +1,46 @@
 package usecases
 
 import (
+	"context"
+	"sync"
+
 	"gophermart-loyalty/internal/logger"
+	"gophermart-loyalty/internal/models"
 	"gophermart-loyalty/internal/repo"
 )
 
+// Sender - отправитель уведомлений пользователям.
+type Sender interface {
+	// Send - отправляет пользователю сообщение с темой subject и текстом body.
+	Send(ctx context.Context, user *models.User, subject, body string) error
+}
+
+// PasswordHasher - хэширование паролей.
+type PasswordHasher interface {
+	// Hash - возвращает хэш пароля, созданный текущим алгоритмом.
+	Hash(password string) (string, error)
+	// Verify - проверяет пароль по хэшу.
+	Verify(hash, password string) (bool, error)
+	// NeedsRehash - проверяет, что хэш создан не текущим алгоритмом или с устаревшими параметрами.
+	NeedsRehash(hash string) bool
+}
+
 // UseCases - набор бизнес-логики.
 type UseCases struct {
-	repo repo.Repo
-	log  logger.Log
+	repo   repo.Repo
+	hasher PasswordHasher
+	sender Sender
+	log    logger.Log
+
+	dummyHashOnce sync.Once // dummyHashOnce - однократное создание dummyHash
+	dummyHash     string    // dummyHash - хэш для проверки пароля несуществующего пользователя
 }
 
-func NewUseCases(repo repo.Repo, log logger.Log) *UseCases {
+func NewUseCases(repo repo.Repo, hasher PasswordHasher, sender Sender, log logger.Log) *UseCases {
 	return &UseCases{
-		repo: repo,
-		log:  log,
+		repo:   repo,
+		hasher: hasher,
+		sender: sender,
+		log:    log,
 	}
 }
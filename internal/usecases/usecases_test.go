@@ -7,9 +7,12 @@ import (
 	"github.com/go-chi/chi/v5/middleware"
 	"github.com/rs/zerolog"
 	"github.com/stretchr/testify/suite"
+	"golang.org/x/crypto/bcrypt"
 
+	"gophermart-loyalty/internal/config"
 	"gophermart-loyalty/internal/logger"
 	"gophermart-loyalty/internal/mocks"
+	"gophermart-loyalty/internal/passhash"
 )
 
 func TestUseCasesSuite(t *testing.T) {
@@ -20,6 +23,7 @@ type useCasesSuite struct {
 	suite.Suite
 	log      logger.Log
 	repo     *mocks.Repo
+	sender   *mocks.Sender
 	useCases *UseCases
 }
 
@@ -29,7 +33,16 @@ func (suite *useCasesSuite) SetupSuite() {
 
 func (suite *useCasesSuite) SetupTest() {
 	suite.repo = mocks.NewRepo(suite.T())
-	suite.useCases = NewUseCases(suite.repo, suite.log)
+	suite.sender = mocks.NewSender(suite.T())
+	hasher, err := passhash.New(&config.Password{
+		Alg:           passhash.AlgBcrypt,
+		BcryptCost:    bcrypt.MinCost,
+		Argon2Time:    1,
+		Argon2Memory:  64,
+		Argon2Threads: 1,
+	})
+	suite.Require().NoError(err)
+	suite.useCases = NewUseCases(suite.repo, hasher, suite.sender, suite.log)
 }
 
 func (suite *useCasesSuite) ctx() context.Context {
@@ -4,8 +4,8 @@ import (
 	"context"
 	"errors"
 	"regexp"
-
-	"golang.org/x/crypto/bcrypt"
+	"strings"
+	"time"
 
 	"gophermart-loyalty/internal/errs"
 	"gophermart-loyalty/internal/models"
@@ -27,16 +27,16 @@ func (u *UseCases) UserCreate(ctx context.Context, login, password string) (*mod
 	}
 
 	// Создаем хэш пароля
-	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	hash, err := u.passHash(ctx, password)
 	if err != nil {
-		u.log.WithReqID(ctx).Error().Err(err).Msg("failed to hash password")
-		return nil, errs.ErrInternal
+		return nil, err
 	}
 
 	// Создаем пользователя
 	user := &models.User{
 		Login:    login,
-		PassHash: string(hash),
+		PassHash: hash,
+		Role:     models.RoleUser,
 	}
 
 	// Сохраняем пользователя
@@ -51,20 +51,43 @@ func (u *UseCases) UserCreate(ctx context.Context, login, password string) (*mod
 }
 
 // UserCheckLoginPass - проверяет логин и пароль пользователя.
+// Если учетная запись заблокирована, возвращает errs.ErrUserBlocked.
+// Если хэш пароля создан устаревшим алгоритмом или с устаревшими параметрами, пароль перехэшируется.
 // Возвращает пользователя, если логин и пароль верны.
 func (u *UseCases) UserCheckLoginPass(ctx context.Context, login, password string) (*models.User, error) {
 	// Ищем пользователя по логину
 	user, err := u.repo.UserGetByLogin(ctx, login)
-	if err != nil {
-		u.log.WithReqID(ctx).Error().Err(err).Msg("failed to find user")
+	if errors.Is(err, errs.ErrNotFound) {
+		// Проверяем пароль по фиктивному хэшу, чтобы время ответа для несуществующего
+		// пользователя не отличалось от времени ответа при неверном пароле
+		_, _ = u.hasher.Verify(u.dummyPassHash(ctx), password)
 		return nil, errs.ErrUserLoginPassMismatch
+	} else if err != nil {
+		u.log.WithReqID(ctx).Error().Err(err).Msg("failed to find user")
+		return nil, err
 	}
-	// Сравниваем хэши паролей
-	err = bcrypt.CompareHashAndPassword([]byte(user.PassHash), []byte(password))
+	// Проверяем пароль по хэшу
+	ok, err := u.hasher.Verify(user.PassHash, password)
 	if err != nil {
+		u.log.WithReqID(ctx).Error().Err(err).Uint64("user_id", user.ID).Msg("failed to verify password")
+		return nil, errs.ErrUserLoginPassMismatch
+	}
+	if !ok {
 		return nil, errs.ErrUserLoginPassMismatch
 	}
 	u.log.Debug().Msg("user found, password matched")
+
+	// Заблокированный пользователь не может войти. Статус проверяется после пароля,
+	// чтобы не раскрывать его без знания пароля
+	if user.Blocked() {
+		u.log.WithReqID(ctx).Info().Uint64("user_id", user.ID).Msg("login attempt for blocked user")
+		return nil, errs.ErrUserBlocked
+	}
+
+	// Перехэшируем пароль текущим алгоритмом
+	if u.hasher.NeedsRehash(user.PassHash) {
+		u.passRehash(ctx, user, password)
+	}
 	return user, nil
 }
 
@@ -79,14 +102,147 @@ func (u *UseCases) UserGetByID(ctx context.Context, userID uint64) (*models.User
 	return user, nil
 }
 
-// UserBalanceHistoryGetByID - возвращает список операций пользователя, учитывающихся в балансе.
-func (u *UseCases) UserBalanceHistoryGetByID(ctx context.Context, userID uint64) ([]*models.Operation, error) {
-	list, err := u.repo.UserBalanceHistoryGetByID(ctx, userID)
+// UserSetRole - устанавливает роль пользователя userID.
+// Ранее выпущенные токены пользователя становятся недействительными,
+// новые токены, полученные по refresh-токену, будут содержать новую роль.
+func (u *UseCases) UserSetRole(ctx context.Context, userID uint64, role models.UserRole) error {
+	if !role.Valid() {
+		return errs.ErrUserRoleInvalid
+	}
+	if err := u.repo.UserSetRole(ctx, userID, role); err != nil {
+		u.log.WithReqID(ctx).Error().Err(err).Msg("failed to set user role")
+		return err
+	}
+	u.log.WithReqID(ctx).Info().Uint64("user_id", userID).Str("role", string(role)).Msg("user role changed")
+	return nil
+}
+
+// UserSetStatus - блокирует или разблокирует учетную запись пользователя userID по решению сотрудника staffID.
+// Заблокированный пользователь не может войти и списывать баллы, но его начисления продолжают обрабатываться.
+// Причина изменения статуса обязательна и сохраняется в истории вместе с id сотрудника.
+func (u *UseCases) UserSetStatus(ctx context.Context, staffID, userID uint64, status models.UserStatus, reason string) error {
+	reason = strings.TrimSpace(reason)
+	if !status.Valid() || reason == "" {
+		return errs.ErrBadRequest
+	}
+	if staffID == userID {
+		return errs.ErrForbidden
+	}
+
+	c := &models.UserStatusChange{UserID: userID, Status: status, Reason: reason, ChangedBy: staffID}
+	if err := u.repo.UserSetStatus(ctx, c); errors.Is(err, errs.ErrNotFound) {
+		return err
+	} else if err != nil {
+		u.log.WithReqID(ctx).Error().Err(err).Msg("failed to set user status")
+		return err
+	}
+	u.log.WithReqID(ctx).Info().
+		Uint64("user_id", userID).
+		Uint64("staff_id", staffID).
+		Str("status", string(status)).
+		Str("reason", reason).
+		Msg("user status changed")
+	return nil
+}
+
+// accountClosureDescription - описание операции закрытия счета при удалении учетной записи
+const accountClosureDescription = "Account closure: remaining balance forfeited"
+
+// UserDelete - удаляет учетную запись пользователя userID после проверки пароля.
+// Остаток баланса списывается операцией закрытия счета, операции сохраняются для учета,
+// а логин и учетные данные обезличиваются. Прежний логин нельзя занять в течение grace.
+func (u *UseCases) UserDelete(ctx context.Context, userID uint64, password string, grace time.Duration) error {
+	user, err := u.UserGetByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if _, err = u.UserCheckLoginPass(ctx, user.Login, password); err != nil {
+		return err
+	}
+
+	op, err := u.repo.UserDelete(ctx, userID, accountClosureDescription, int64(grace.Seconds()))
+	if err != nil {
+		u.log.WithReqID(ctx).Error().Err(err).Msg("failed to delete user")
+		return err
+	}
+
+	log := u.log.WithReqID(ctx).Info().Uint64("user_id", userID)
+	if op != nil {
+		log = log.Uint64("closure_op_id", op.ID).Str("forfeited", op.Amount.Neg().String())
+	}
+	log.Msg("user deleted")
+	return nil
+}
+
+// UserBalanceHistoryGetByID - возвращает список операций пользователя, учитывающихся в балансе, выбранных по параметрам q.
+// Если число операций ограничено q.Limit и в списке есть следующие операции, возвращает также курсор следующей страницы.
+func (u *UseCases) UserBalanceHistoryGetByID(ctx context.Context, userID uint64, q *models.OperationQuery) ([]*models.Operation, *models.OperationCursor, error) {
+	list, err := u.repo.UserBalanceHistoryGetByID(ctx, userID, operationPageQuery(q))
 	if errors.Is(err, errs.ErrNotFound) {
-		return nil, nil
+		return nil, nil, nil
 	} else if err != nil {
 		u.log.WithReqID(ctx).Error().Err(err).Msg("failed to get balance history")
+		return nil, nil, err
+	}
+	list, next := operationPage(list, q, func(op *models.Operation) time.Time { return op.UpdatedAt })
+	return list, next, nil
+}
+
+// UserBalanceAsOf - возвращает баланс пользователя на момент asOf по сумме проводок, записанных до этого момента.
+// Момент в будущем отклоняется с errs.ErrBalanceAsOfInvalid.
+func (u *UseCases) UserBalanceAsOf(ctx context.Context, userID uint64, asOf time.Time) (*models.BalanceSnapshot, error) {
+	if asOf.After(time.Now()) {
+		return nil, errs.ErrBalanceAsOfInvalid
+	}
+	b, err := u.repo.UserBalanceAsOf(ctx, userID, asOf)
+	if err != nil {
+		u.log.WithReqID(ctx).Error().Err(err).Msg("failed to get balance as of")
 		return nil, err
 	}
-	return list, nil
+	return b, nil
+}
+
+// passHash - возвращает хэш пароля для хранения в репозитории.
+func (u *UseCases) passHash(ctx context.Context, password string) (string, error) {
+	hash, err := u.hasher.Hash(password)
+	if err != nil {
+		u.log.WithReqID(ctx).Error().Err(err).Msg("failed to hash password")
+		return "", errs.ErrInternal
+	}
+	return hash, nil
+}
+
+// passRehash - заменяет хэш пароля пользователя на хэш, созданный текущим алгоритмом.
+// Хэш заменяется, только если он не изменился с момента проверки пароля, чтобы не затереть
+// пароль, измененный параллельно. Ошибки только логируются: вход выполняется и со старым хэшем.
+func (u *UseCases) passRehash(ctx context.Context, user *models.User, password string) {
+	hash, err := u.passHash(ctx, password)
+	if err != nil {
+		return
+	}
+	err = u.repo.UserUpgradePassHash(ctx, user.ID, user.PassHash, hash)
+	if errors.Is(err, errs.ErrNotFound) {
+		u.log.WithReqID(ctx).Debug().Uint64("user_id", user.ID).Msg("password hash changed concurrently, rehash skipped")
+		return
+	} else if err != nil {
+		u.log.WithReqID(ctx).Error().Err(err).Uint64("user_id", user.ID).Msg("failed to rehash password")
+		return
+	}
+	user.PassHash = hash
+	u.log.WithReqID(ctx).Info().Uint64("user_id", user.ID).Msg("password rehashed")
+}
+
+// dummyPassHash - возвращает хэш случайного пароля, созданный текущим алгоритмом.
+// Используется для проверки пароля несуществующего пользователя.
+func (u *UseCases) dummyPassHash(ctx context.Context) string {
+	u.dummyHashOnce.Do(func() {
+		password, _, err := randomToken(refreshTokenLen)
+		if err == nil {
+			u.dummyHash, err = u.hasher.Hash(password)
+		}
+		if err != nil {
+			u.log.WithReqID(ctx).Error().Err(err).Msg("failed to create dummy password hash")
+		}
+	})
+	return u.dummyHash
 }
@@ -81,7 +81,7 @@ func (suite *useCasesSuite) TestUserCreate() {
 
 func (suite *useCasesSuite) TestUserCheckLoginPass() {
 	password := "Qwerty123456!"
-	passhash, _ := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	passhash, _ := bcrypt.GenerateFromPassword([]byte(password), bcrypt.MinCost)
 
 	suite.Run("success", func() {
 		suite.repo.On("UserGetByLogin", mock.Anything, "oleg").
@@ -114,6 +114,65 @@ func (suite *useCasesSuite) TestUserCheckLoginPass() {
 		suite.ErrorIs(err, errs.ErrUserLoginPassMismatch)
 		suite.Nil(user)
 	})
+
+	suite.Run("user blocked", func() {
+		suite.repo.On("UserGetByLogin", mock.Anything, "oleg").
+			Return(&models.User{
+				ID:       1,
+				Login:    "oleg",
+				PassHash: string(passhash),
+				Status:   models.UserBlocked,
+			}, nil).Once()
+		user, err := suite.useCases.UserCheckLoginPass(suite.ctx(), "oleg", password)
+		suite.ErrorIs(err, errs.ErrUserBlocked)
+		suite.Nil(user)
+	})
+
+	suite.Run("outdated hash rehashed", func() {
+		oldHash, _ := bcrypt.GenerateFromPassword([]byte(password), bcrypt.MinCost+1)
+		suite.repo.On("UserGetByLogin", mock.Anything, "oleg").
+			Return(&models.User{
+				ID:       1,
+				Login:    "oleg",
+				PassHash: string(oldHash),
+			}, nil).Once()
+		suite.repo.On("UserUpgradePassHash", mock.Anything, uint64(1), string(oldHash),
+			mock.MatchedBy(func(h string) bool {
+				cost, err := bcrypt.Cost([]byte(h))
+				return err == nil && cost == bcrypt.MinCost &&
+					bcrypt.CompareHashAndPassword([]byte(h), []byte(password)) == nil
+			})).Return(nil).Once()
+		user, err := suite.useCases.UserCheckLoginPass(suite.ctx(), "oleg", password)
+		suite.NoError(err)
+		suite.NotEqual(string(oldHash), user.PassHash)
+	})
+
+	suite.Run("hash changed concurrently", func() {
+		oldHash, _ := bcrypt.GenerateFromPassword([]byte(password), bcrypt.MinCost+1)
+		suite.repo.On("UserGetByLogin", mock.Anything, "oleg").
+			Return(&models.User{
+				ID:       1,
+				Login:    "oleg",
+				PassHash: string(oldHash),
+			}, nil).Once()
+		suite.repo.On("UserUpgradePassHash", mock.Anything, uint64(1), string(oldHash), mock.Anything).
+			Return(errs.ErrNotFound).Once()
+		user, err := suite.useCases.UserCheckLoginPass(suite.ctx(), "oleg", password)
+		suite.NoError(err)
+		suite.Equal(string(oldHash), user.PassHash)
+	})
+
+	suite.Run("unknown hash format", func() {
+		suite.repo.On("UserGetByLogin", mock.Anything, "oleg").
+			Return(&models.User{
+				ID:       1,
+				Login:    "oleg",
+				PassHash: "plain",
+			}, nil).Once()
+		user, err := suite.useCases.UserCheckLoginPass(suite.ctx(), "oleg", "plain")
+		suite.ErrorIs(err, errs.ErrUserLoginPassMismatch)
+		suite.Nil(user)
+	})
 }
 
 func (suite *useCasesSuite) TestUserGetByID() {
@@ -151,27 +210,127 @@ func (suite *useCasesSuite) TestUserBalanceHistoryGetByID() {
 				UpdatedAt:   time.Now(),
 			},
 		}
-		suite.repo.On("UserBalanceHistoryGetByID", mock.Anything, uint64(1)).
+		suite.repo.On("UserBalanceHistoryGetByID", mock.Anything, uint64(1), mock.Anything).
 			Return(ops, nil).Once()
-		history, err := suite.useCases.UserBalanceHistoryGetByID(suite.ctx(), uint64(1))
+		history, _, err := suite.useCases.UserBalanceHistoryGetByID(suite.ctx(), uint64(1), nil)
 		suite.NoError(err)
 		suite.Equal(ops, history)
 	})
 
+	suite.Run("next page is ordered by processing time", func() {
+		processed := time.Date(2022, 10, 1, 12, 0, 0, 0, time.UTC)
+		ops := []*models.Operation{
+			{ID: 5, UserID: 1, Type: models.OrderAccrual, Status: models.StatusProcessed, UpdatedAt: processed},
+			{ID: 4, UserID: 1, Type: models.OrderAccrual, Status: models.StatusProcessed, UpdatedAt: processed.Add(-time.Hour)},
+		}
+		suite.repo.On("UserBalanceHistoryGetByID", mock.Anything, uint64(1), &models.OperationQuery{Limit: 2}).
+			Return(ops, nil).Once()
+		history, next, err := suite.useCases.UserBalanceHistoryGetByID(suite.ctx(), uint64(1), &models.OperationQuery{Limit: 1})
+		suite.NoError(err)
+		suite.Equal(ops[:1], history)
+		suite.Equal(&models.OperationCursor{Time: processed, ID: 5}, next)
+	})
+
 	suite.Run("no operations", func() {
-		suite.repo.On("UserBalanceHistoryGetByID", mock.Anything, uint64(1)).
+		suite.repo.On("UserBalanceHistoryGetByID", mock.Anything, uint64(1), mock.Anything).
 			Return(nil, errs.ErrNotFound).Once()
-		history, err := suite.useCases.UserBalanceHistoryGetByID(suite.ctx(), uint64(1))
+		history, _, err := suite.useCases.UserBalanceHistoryGetByID(suite.ctx(), uint64(1), nil)
 		suite.NoError(err)
 		suite.Nil(history)
 	})
 
 	suite.Run("internal error", func() {
-		suite.repo.On("UserBalanceHistoryGetByID", mock.Anything, uint64(1)).
+		suite.repo.On("UserBalanceHistoryGetByID", mock.Anything, uint64(1), mock.Anything).
 			Return(nil, errs.ErrInternal).Once()
-		history, err := suite.useCases.UserBalanceHistoryGetByID(suite.ctx(), uint64(1))
+		history, _, err := suite.useCases.UserBalanceHistoryGetByID(suite.ctx(), uint64(1), nil)
 		suite.ErrorIs(err, errs.ErrInternal)
 		suite.Nil(history)
 	})
 
 }
+
+func (suite *useCasesSuite) TestUserBalanceAsOf() {
+	suite.Run("success", func() {
+		asOf := time.Date(2022, 10, 1, 0, 0, 0, 0, time.UTC)
+		b := &models.BalanceSnapshot{AsOf: asOf, Balance: decimal.NewFromInt(100), Withdrawn: decimal.NewFromInt(30)}
+		suite.repo.On("UserBalanceAsOf", mock.Anything, uint64(1), asOf).Return(b, nil).Once()
+		res, err := suite.useCases.UserBalanceAsOf(suite.ctx(), 1, asOf)
+		suite.NoError(err)
+		suite.Equal(b, res)
+	})
+
+	suite.Run("future", func() {
+		_, err := suite.useCases.UserBalanceAsOf(suite.ctx(), 1, time.Now().Add(time.Hour))
+		suite.ErrorIs(err, errs.ErrBalanceAsOfInvalid)
+	})
+}
+
+func (suite *useCasesSuite) TestUserSetRole() {
+	suite.Run("success", func() {
+		suite.repo.On("UserSetRole", mock.Anything, uint64(1), models.RoleAdmin).Return(nil).Once()
+		suite.NoError(suite.useCases.UserSetRole(suite.ctx(), 1, models.RoleAdmin))
+	})
+
+	suite.Run("user not found", func() {
+		suite.repo.On("UserSetRole", mock.Anything, uint64(1000), models.RoleSupport).Return(errs.ErrNotFound).Once()
+		suite.ErrorIs(suite.useCases.UserSetRole(suite.ctx(), 1000, models.RoleSupport), errs.ErrNotFound)
+	})
+
+	suite.Run("invalid role", func() {
+		suite.ErrorIs(suite.useCases.UserSetRole(suite.ctx(), 1, "superuser"), errs.ErrUserRoleInvalid)
+	})
+}
+
+func (suite *useCasesSuite) TestUserSetStatus() {
+	suite.Run("block", func() {
+		suite.repo.On("UserSetStatus", mock.Anything, &models.UserStatusChange{
+			UserID:    2,
+			Status:    models.UserBlocked,
+			Reason:    "fraud",
+			ChangedBy: 1,
+		}).Return(nil).Once()
+		suite.NoError(suite.useCases.UserSetStatus(suite.ctx(), 1, 2, models.UserBlocked, " fraud "))
+	})
+
+	suite.Run("reason required", func() {
+		suite.ErrorIs(suite.useCases.UserSetStatus(suite.ctx(), 1, 2, models.UserBlocked, "  "), errs.ErrBadRequest)
+	})
+
+	suite.Run("invalid status", func() {
+		suite.ErrorIs(suite.useCases.UserSetStatus(suite.ctx(), 1, 2, "frozen", "fraud"), errs.ErrBadRequest)
+	})
+
+	suite.Run("staff can not block themselves", func() {
+		suite.ErrorIs(suite.useCases.UserSetStatus(suite.ctx(), 1, 1, models.UserBlocked, "test"), errs.ErrForbidden)
+	})
+}
+
+func (suite *useCasesSuite) TestUserDelete() {
+	passHash, err := suite.useCases.passHash(suite.ctx(), "password")
+	suite.Require().NoError(err)
+	user := &models.User{ID: 1, Login: "user", PassHash: passHash}
+
+	suite.Run("success", func() {
+		suite.repo.On("UserGetByID", mock.Anything, uint64(1)).Return(user, nil).Once()
+		suite.repo.On("UserGetByLogin", mock.Anything, "user").Return(user, nil).Once()
+		suite.repo.On("UserDelete", mock.Anything, uint64(1), accountClosureDescription, int64(3600)).
+			Return(nil, nil).Once()
+		suite.NoError(suite.useCases.UserDelete(suite.ctx(), 1, "password", time.Hour))
+	})
+
+	suite.Run("wrong password", func() {
+		suite.repo.On("UserGetByID", mock.Anything, uint64(1)).Return(user, nil).Once()
+		suite.repo.On("UserGetByLogin", mock.Anything, "user").Return(user, nil).Once()
+		err := suite.useCases.UserDelete(suite.ctx(), 1, "wrong", time.Hour)
+		suite.ErrorIs(err, errs.ErrUserLoginPassMismatch)
+	})
+
+	suite.Run("repo error", func() {
+		suite.repo.On("UserGetByID", mock.Anything, uint64(1)).Return(user, nil).Once()
+		suite.repo.On("UserGetByLogin", mock.Anything, "user").Return(user, nil).Once()
+		suite.repo.On("UserDelete", mock.Anything, uint64(1), mock.Anything, mock.Anything).
+			Return(nil, errs.ErrInternal).Once()
+		err := suite.useCases.UserDelete(suite.ctx(), 1, "password", time.Hour)
+		suite.ErrorIs(err, errs.ErrInternal)
+	})
+}
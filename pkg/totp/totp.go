@@ -0,0 +1,99 @@
+// Package totp - одноразовые пароли на основе времени (TOTP, RFC 6238).
+//
+// Используются параметры, которые поддерживают все распространенные приложения-аутентификаторы:
+// HMAC-SHA1, 6 цифр, шаг 30 секунд.
+package totp
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/binary"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	Digits    = 6                // Digits - число цифр в коде
+	Period    = 30 * time.Second // Period - шаг времени
+	SecretLen = 20               // SecretLen - длина секрета в байтах
+)
+
+// encoding - base32 без выравнивания, в котором секрет передается в приложение-аутентификатор
+var encoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// GenerateSecret - генерирует случайный секрет в кодировке base32.
+func GenerateSecret() (string, error) {
+	b := make([]byte, SecretLen)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return encoding.EncodeToString(b), nil
+}
+
+// Step - возвращает номер шага времени t.
+func Step(t time.Time) int64 {
+	return t.Unix() / int64(Period/time.Second)
+}
+
+// Code - возвращает код для секрета secret на шаге step.
+func Code(secret string, step int64) (string, error) {
+	key, err := encoding.DecodeString(strings.ToUpper(secret))
+	if err != nil {
+		return "", fmt.Errorf("invalid secret: %w", err)
+	}
+
+	var msg [8]byte
+	binary.BigEndian.PutUint64(msg[:], uint64(step))
+	mac := hmac.New(sha1.New, key)
+	mac.Write(msg[:])
+	sum := mac.Sum(nil)
+
+	// Динамическое усечение (RFC 4226, раздел 5.3)
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+
+	mod := uint32(1)
+	for i := 0; i < Digits; i++ {
+		mod *= 10
+	}
+	return fmt.Sprintf("%0*d", Digits, value%mod), nil
+}
+
+// Validate - проверяет код для секрета secret в момент t.
+// Допускается расхождение часов на skew шагов в обе стороны.
+// Возвращает номер шага, которому соответствует код, чтобы вызывающий мог запретить повторное использование кода.
+func Validate(secret, code string, t time.Time, skew int) (int64, bool, error) {
+	if len(code) != Digits {
+		return 0, false, nil
+	}
+	current := Step(t)
+	for i := -skew; i <= skew; i++ {
+		step := current + int64(i)
+		expected, err := Code(secret, step)
+		if err != nil {
+			return 0, false, err
+		}
+		if subtle.ConstantTimeCompare([]byte(expected), []byte(code)) == 1 {
+			return step, true, nil
+		}
+	}
+	return 0, false, nil
+}
+
+// ProvisioningURI - возвращает URI otpauth:// для добавления секрета в приложение-аутентификатор,
+// обычно передается пользователю в виде QR-кода.
+func ProvisioningURI(issuer, account, secret string) string {
+	label := url.PathEscape(issuer + ":" + account)
+	q := url.Values{}
+	q.Set("secret", secret)
+	q.Set("issuer", issuer)
+	q.Set("algorithm", "SHA1")
+	q.Set("digits", fmt.Sprint(Digits))
+	q.Set("period", fmt.Sprint(int(Period/time.Second)))
+	return "otpauth://totp/" + label + "?" + q.Encode()
+}
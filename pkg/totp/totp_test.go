@@ -0,0 +1,92 @@
+package totp
+
+import (
+	"encoding/base32"
+	"strings"
+	"testing"
+	"time"
+)
+
+// rfcSecret - секрет из тестовых векторов RFC 6238 для HMAC-SHA1
+var rfcSecret = base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString([]byte("12345678901234567890"))
+
+func TestCode(t *testing.T) {
+	// Тестовые векторы RFC 6238, приложение B (последние 6 цифр 8-значных кодов)
+	tests := []struct {
+		name string
+		unix int64
+		want string
+	}{
+		{"59", 59, "287082"},
+		{"1111111109", 1111111109, "081804"},
+		{"1111111111", 1111111111, "050471"},
+		{"1234567890", 1234567890, "005924"},
+		{"2000000000", 2000000000, "279037"},
+		{"20000000000", 20000000000, "353130"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Code(rfcSecret, Step(time.Unix(tt.unix, 0)))
+			if err != nil {
+				t.Fatalf("Code() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Code() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidate(t *testing.T) {
+	now := time.Unix(1111111111, 0)
+	tests := []struct {
+		name     string
+		code     string
+		skew     int
+		wantOK   bool
+		wantStep int64
+	}{
+		{"current step", "050471", 1, true, Step(now)},
+		{"previous step within skew", "081804", 1, true, Step(now) - 1},
+		{"previous step without skew", "081804", 0, false, 0},
+		{"wrong code", "000000", 1, false, 0},
+		{"wrong length", "50471", 1, false, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			step, ok, err := Validate(rfcSecret, tt.code, now, tt.skew)
+			if err != nil {
+				t.Fatalf("Validate() error = %v", err)
+			}
+			if ok != tt.wantOK || step != tt.wantStep {
+				t.Errorf("Validate() = %v, %v, want %v, %v", step, ok, tt.wantStep, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestGenerateSecret(t *testing.T) {
+	s1, err := GenerateSecret()
+	if err != nil {
+		t.Fatalf("GenerateSecret() error = %v", err)
+	}
+	s2, _ := GenerateSecret()
+	if s1 == s2 {
+		t.Errorf("GenerateSecret() returned the same secret twice")
+	}
+	if _, err = Code(s1, 1); err != nil {
+		t.Errorf("Code() error = %v", err)
+	}
+}
+
+func TestProvisioningURI(t *testing.T) {
+	uri := ProvisioningURI("Gophermart", "user 1", "SECRET")
+	if !strings.HasPrefix(uri, "otpauth://totp/Gophermart:user%201?") {
+		t.Errorf("ProvisioningURI() = %v", uri)
+	}
+	for _, want := range []string{"secret=SECRET", "issuer=Gophermart", "digits=6", "period=30"} {
+		if !strings.Contains(uri, want) {
+			t.Errorf("ProvisioningURI() = %v, want %v", uri, want)
+		}
+	}
+}